@@ -0,0 +1,379 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/alerts"
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+	"github.com/mlrf/mlrf-api/internal/audit"
+	"github.com/mlrf/mlrf-api/internal/backtest"
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/drift"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	mlrfmiddleware "github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/remote"
+	"github.com/mlrf/mlrf-api/internal/shapclient"
+	"github.com/mlrf/mlrf-api/internal/slo"
+	"github.com/mlrf/mlrf-api/internal/tracing"
+	"github.com/mlrf/mlrf-api/internal/watch"
+)
+
+// The functions below build each component's configuration from the loaded
+// config.Config. Settings are validated by config.Load, so parse errors are ignored.
+
+// remoteConfig returns the object store download configuration.
+func remoteConfig(cfg *config.Config) remote.Config {
+	c := remote.DefaultConfig()
+	if cfg.Remote.CacheDir != "" {
+		c.CacheDir = cfg.Remote.CacheDir
+	}
+	c.Timeout = cfg.Remote.FetchTimeout
+	if cfg.Remote.AWSRegion != "" {
+		c.S3Region = cfg.Remote.AWSRegion
+	} else if cfg.Remote.AWSDefaultRegion != "" {
+		c.S3Region = cfg.Remote.AWSDefaultRegion
+	}
+	c.S3Endpoint = cfg.Remote.AWSEndpointURLS3
+	c.AWSAccessKeyID = cfg.Remote.AWSAccessKeyID
+	c.AWSSecretKey = cfg.Remote.AWSSecretAccessKey
+	c.AWSSessionToken = cfg.Remote.AWSSessionToken
+	if cfg.Remote.GCSEndpoint != "" {
+		c.GCSEndpoint = cfg.Remote.GCSEndpoint
+	}
+	c.GCSToken = cfg.Remote.GCSAccessToken
+	return c
+}
+
+// sessionConfig returns the ONNX session configuration.
+func sessionConfig(cfg *config.Config) inference.SessionConfig {
+	return inference.SessionConfig{
+		MaxBatchSize: cfg.Model.ONNXMaxBatchSize,
+		LibPath:      cfg.Model.ONNXLibPath,
+	}
+}
+
+// backendConfig returns the feature backend configuration, reading the DuckDB
+// default table from featurePath.
+func backendConfig(cfg *config.Config, featurePath string) features.BackendConfig {
+	c := features.DefaultBackendConfig(cfg.Features.Backend, featurePath)
+	if cfg.Features.DBDriver != "" {
+		c.Driver = cfg.Features.DBDriver
+	}
+	c.DSN = cfg.Features.DBDSN
+	if cfg.Features.DBTable != "" {
+		c.Table = cfg.Features.DBTable
+	}
+	c.CacheSize = cfg.Features.DBCacheSize
+	c.QueryTimeout = cfg.Features.DBTimeout
+	return c
+}
+
+// qualityConfig returns the feature quality thresholds.
+func qualityConfig(cfg *config.Config) features.QualityConfig {
+	return features.QualityConfig{
+		Mode:           cfg.Features.QualityMode,
+		MaxNullRate:    cfg.Features.QualityMaxNullRate,
+		MaxOutlierRate: cfg.Features.QualityMaxOutlierRate,
+	}
+}
+
+// breakerConfig returns the SHAP client circuit breaker configuration.
+func breakerConfig(cfg *config.Config) shapclient.BreakerConfig {
+	return shapclient.BreakerConfig{
+		FailureThreshold: cfg.Data.SHAPBreakerThreshold,
+		OpenDuration:     cfg.Data.SHAPBreakerOpenDuration,
+		HalfOpenProbes:   cfg.Data.SHAPBreakerHalfOpenProbes,
+	}
+}
+
+// retryConfig returns the SHAP client retry configuration.
+func retryConfig(cfg *config.Config) shapclient.RetryConfig {
+	return shapclient.RetryConfig{
+		MaxRetries: cfg.Data.SHAPRetries,
+		BaseDelay:  cfg.Data.SHAPRetryBaseDelay,
+		MaxDelay:   cfg.Data.SHAPRetryMaxDelay,
+		Hedge:      cfg.Data.SHAPHedge,
+	}
+}
+
+// labelConfig returns the prediction metric label bounds.
+func labelConfig(cfg *config.Config) metrics.LabelConfig {
+	return metrics.LabelConfig{
+		FamilyLabel:      cfg.Metrics.FamilyLabel,
+		MaxFamilies:      cfg.Metrics.MaxFamilies,
+		MaxModelVersions: cfg.Metrics.MaxModelVersions,
+	}
+}
+
+// tracingConfig returns the OpenTelemetry configuration. Without an endpoint traces
+// go to the local collector's port for the protocol.
+func tracingConfig(cfg *config.Config) tracing.Config {
+	endpoint := cfg.Tracing.Endpoint
+	if endpoint == "" {
+		endpoint = tracing.DefaultEndpoint(cfg.Tracing.Protocol)
+	}
+	return tracing.Config{
+		Enabled:      cfg.Tracing.Enabled,
+		Endpoint:     endpoint,
+		Protocol:     cfg.Tracing.Protocol,
+		Sampler:      cfg.Tracing.Sampler,
+		SampleRate:   cfg.Tracing.SampleRate,
+		RateLimit:    cfg.Tracing.RateLimit,
+		TailSampling: cfg.Tracing.TailSampling,
+		TailLatency:  cfg.Tracing.TailLatency,
+		ServiceName:  cfg.Tracing.ServiceName,
+		Environment:  cfg.Server.Environment,
+	}
+}
+
+// shadowConfig returns the shadow-mode configuration.
+func shadowConfig(cfg *config.Config) inference.ShadowConfig {
+	return inference.ShadowConfig{
+		Challenger: cfg.Model.ShadowModel,
+		Workers:    cfg.Model.ShadowWorkers,
+		QueueSize:  cfg.Model.ShadowQueueSize,
+		LogSize:    cfg.Model.ShadowLogSize,
+	}
+}
+
+// quantileConfig returns the quantile model paths.
+func quantileConfig(cfg *config.Config) inference.QuantileConfig {
+	return inference.QuantileConfig{
+		P10Path: cfg.Model.QuantileP10Path,
+		P50Path: cfg.Model.QuantileP50Path,
+		P90Path: cfg.Model.QuantileP90Path,
+	}
+}
+
+// guardConfig returns the prediction sanity guard configuration.
+func guardConfig(cfg *config.Config) inference.GuardConfig {
+	return inference.GuardConfig{
+		Min:    cfg.Predictions.Min,
+		Max:    cfg.Predictions.Max,
+		Policy: cfg.Predictions.NaNPolicy,
+	}
+}
+
+// jobsConfig returns the async job configuration.
+func jobsConfig(cfg *config.Config) jobs.Config {
+	return jobs.Config{
+		Workers:   cfg.Predictions.JobWorkers,
+		QueueSize: cfg.Predictions.JobQueueSize,
+		MaxItems:  cfg.Predictions.JobMaxItems,
+		ResultTTL: cfg.Predictions.JobResultTTL,
+	}
+}
+
+// actualsConfig returns the actuals store configuration.
+func actualsConfig(cfg *config.Config) actuals.Config {
+	return actuals.Config{
+		Driver:     cfg.Data.ActualsDriver,
+		DSN:        cfg.Data.ActualsDSN,
+		ImportPath: cfg.Data.ActualsPath,
+		MaxBatch:   cfg.Data.ActualsMaxBatch,
+	}
+}
+
+// alertsConfig returns the accuracy alert configuration.
+func alertsConfig(cfg *config.Config) alerts.Config {
+	return alerts.Config{
+		Interval:       cfg.Alerts.CheckInterval,
+		WindowDays:     cfg.Alerts.WindowDays,
+		MAPEThreshold:  cfg.Alerts.MAPEThreshold,
+		MinSamples:     cfg.Alerts.MinSamples,
+		WebhookURL:     cfg.Alerts.WebhookURL,
+		WebhookTimeout: cfg.Alerts.WebhookTimeout,
+	}
+}
+
+// anomalyConfig returns the anomaly detection configuration.
+func anomalyConfig(cfg *config.Config) anomaly.Config {
+	return anomaly.Config{
+		Method:       cfg.Alerts.AnomalyMethod,
+		BaselineDays: cfg.Alerts.AnomalyBaselineDays,
+		MinBaseline:  cfg.Alerts.AnomalyMinBaseline,
+		ZThreshold:   cfg.Alerts.AnomalyZThreshold,
+		CriticalZ:    cfg.Alerts.AnomalyCriticalZ,
+		WindowDays:   cfg.Alerts.AnomalyWindowDays,
+	}
+}
+
+// auditConfig returns the admin audit store configuration.
+func auditConfig(cfg *config.Config) audit.Config {
+	return audit.Config{
+		Path:          cfg.Server.AdminAuditPath,
+		MaxParamBytes: cfg.Server.AdminAuditParams,
+	}
+}
+
+// sloConfig returns the SLO tracking configuration.
+func sloConfig(cfg *config.Config) slo.Config {
+	c := slo.DefaultConfig()
+	c.Routes = slo.ParseRoutes(cfg.SLO.Routes)
+	c.AvailabilityTarget = cfg.SLO.AvailabilityTarget
+	c.LatencyTarget = cfg.SLO.LatencyTarget
+	c.LatencyThreshold = cfg.SLO.LatencyThreshold
+	c.LatencyThresholds, _ = slo.ParseThresholds(cfg.SLO.LatencyThresholds)
+	c.Window = cfg.SLO.Window
+	return c
+}
+
+// predLogConfig returns the prediction log configuration.
+func predLogConfig(cfg *config.Config) predlog.Config {
+	return predlog.Config{
+		Enabled:        cfg.PredictionLog.Enabled,
+		Dir:            cfg.PredictionLog.Dir,
+		BufferSize:     cfg.PredictionLog.Buffer,
+		BatchSize:      cfg.PredictionLog.BatchSize,
+		FlushInterval:  cfg.PredictionLog.FlushInterval,
+		MaxRowsPerFile: cfg.PredictionLog.MaxRows,
+		RotateInterval: cfg.PredictionLog.RotateInterval,
+	}
+}
+
+// liveConfig returns the live update hub configuration.
+func liveConfig(cfg *config.Config) live.Config {
+	return live.Config{
+		MaxSubscriptions: cfg.Predictions.WSMaxSubscriptions,
+		SendBuffer:       cfg.Predictions.WSSendBuffer,
+	}
+}
+
+// hierarchyCacheConfig returns the hierarchy cache configuration.
+func hierarchyCacheConfig(cfg *config.Config) cache.HierarchyConfig {
+	return cache.HierarchyConfig{
+		TTL:        cfg.Cache.HierarchyCacheTTL,
+		MaxEntries: cfg.Cache.HierarchyCacheMaxEntries,
+	}
+}
+
+// driftConfig returns the feature drift configuration.
+func driftConfig(cfg *config.Config) drift.Config {
+	return drift.Config{
+		ReferencePath: cfg.Features.DriftReferencePath,
+		WindowDays:    cfg.Features.DriftWindowDays,
+		PSIThreshold:  cfg.Features.DriftPSIThreshold,
+		KSThreshold:   cfg.Features.DriftKSThreshold,
+	}
+}
+
+// backtestConfig returns the backtest configuration.
+func backtestConfig(cfg *config.Config) backtest.Config {
+	return backtest.Config{
+		MaxDays:         cfg.Cache.BacktestMaxDays,
+		CacheTTL:        cfg.Cache.BacktestCacheTTL,
+		CacheMaxEntries: cfg.Cache.BacktestCacheMaxEntries,
+	}
+}
+
+// watchConfig returns the file watcher configuration.
+func watchConfig(cfg *config.Config) watch.Config {
+	return watch.Config{
+		Enabled:  cfg.Features.WatchFiles,
+		Interval: cfg.Features.WatchInterval,
+		Debounce: cfg.Features.WatchDebounce,
+	}
+}
+
+// refreshConfig returns the scheduled feature refresh configuration.
+func refreshConfig(cfg *config.Config) refresh.Config {
+	loc, err := time.LoadLocation(cfg.Features.RefreshTZ)
+	if err != nil {
+		loc = time.UTC
+	}
+	return refresh.Config{
+		Cron:     cfg.Features.RefreshCron,
+		Location: loc,
+		Warm:     cfg.Features.RefreshWarm,
+		Timeout:  cfg.Features.RefreshTimeout,
+	}
+}
+
+// ingestConfig returns the streamed feature consumer configuration.
+func ingestConfig(cfg *config.Config) ingest.Config {
+	return ingest.Config{
+		Broker:         cfg.Ingest.Broker,
+		URL:            cfg.Ingest.URL,
+		Topic:          cfg.Ingest.Topic,
+		Start:          cfg.Ingest.Start,
+		CheckpointPath: cfg.Ingest.CheckpointPath,
+		BatchSize:      cfg.Ingest.BatchSize,
+		FlushInterval:  cfg.Ingest.FlushInterval,
+
+		InvalidateInterval: cfg.Ingest.InvalidateInterval,
+	}
+}
+
+// proxyConfig returns the proxies whose forwarding headers are trusted.
+func proxyConfig(cfg *config.Config) mlrfmiddleware.ProxyConfig {
+	proxies, _ := mlrfmiddleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	return mlrfmiddleware.ProxyConfig{TrustedProxies: proxies}
+}
+
+// requestLoggerConfig returns the access log configuration.
+func requestLoggerConfig(cfg *config.Config) mlrfmiddleware.RequestLoggerConfig {
+	return mlrfmiddleware.RequestLoggerConfig{
+		SampleRate: cfg.Server.LogSampleRate,
+		File:       cfg.Server.AccessLogFile,
+		MaxSizeMB:  cfg.Server.AccessLogMaxSize,
+		MaxBackups: cfg.Server.AccessLogBackups,
+		AuditFile:  cfg.Server.AuditLogFile,
+	}
+}
+
+// timeoutConfig returns the request timeouts.
+func timeoutConfig(cfg *config.Config) mlrfmiddleware.TimeoutConfig {
+	c := mlrfmiddleware.DefaultTimeoutConfig()
+	c.Default = cfg.Server.RequestTimeout
+	c.Routes, _ = mlrfmiddleware.ParseTimeouts(cfg.Server.RequestTimeouts)
+	return c
+}
+
+// rateLimiterConfig returns the rate limits.
+func rateLimiterConfig(cfg *config.Config) mlrfmiddleware.RateLimiterConfig {
+	c := mlrfmiddleware.DefaultRateLimiterConfig()
+	c.RequestsPerSecond = cfg.Server.RateLimitRPS
+	c.BurstSize = cfg.Server.RateLimitBurst
+	c.ClassLimits, _ = mlrfmiddleware.ParseClassLimits(cfg.Server.RateLimitClasses)
+	return c
+}
+
+// bodyLimitConfig returns the request body size limits.
+func bodyLimitConfig(cfg *config.Config) mlrfmiddleware.BodyLimitConfig {
+	c := mlrfmiddleware.DefaultBodyLimitConfig()
+	c.MaxBytes = int64(cfg.Server.MaxBodyBytes)
+	c.BulkMaxBytes = int64(cfg.Server.MaxBulkBodyBytes)
+	return c
+}
+
+// concurrencyConfig returns the load shedding configuration.
+func concurrencyConfig(cfg *config.Config) mlrfmiddleware.ConcurrencyConfig {
+	c := mlrfmiddleware.DefaultConcurrencyConfig()
+	c.Limit = cfg.Server.ConcurrencyLimit
+	c.MaxQueue = cfg.Server.ConcurrencyQueue
+	c.MaxWait = cfg.Server.ConcurrencyWait
+	return c
+}
+
+// versionConfig returns the API version configuration.
+func versionConfig(cfg *config.Config) mlrfmiddleware.VersionConfig {
+	c := mlrfmiddleware.DefaultVersionConfig()
+	if cfg.Server.LegacySunset != "" {
+		c.Sunset, _ = time.Parse("2006-01-02", cfg.Server.LegacySunset)
+	}
+	return c
+}
+
+// apiKeyConfig returns where the API key store loads keys from.
+func apiKeyConfig(cfg *config.Config) mlrfmiddleware.KeyStoreConfig {
+	return mlrfmiddleware.KeyStoreConfig{Key: cfg.Server.APIKey, File: cfg.Server.APIKeysFile, AdminKey: cfg.Server.AdminAPIKey}
+}
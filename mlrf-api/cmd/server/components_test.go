@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/alerts"
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+	"github.com/mlrf/mlrf-api/internal/audit"
+	"github.com/mlrf/mlrf-api/internal/backtest"
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/drift"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	mlrfmiddleware "github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/remote"
+	"github.com/mlrf/mlrf-api/internal/shapclient"
+	"github.com/mlrf/mlrf-api/internal/slo"
+	"github.com/mlrf/mlrf-api/internal/tracing"
+	"github.com/mlrf/mlrf-api/internal/watch"
+)
+
+// TestComponentDefaults checks that the configuration defaults match the defaults of
+// each component, so a setting left unset behaves as the component documents.
+func TestComponentDefaults(t *testing.T) {
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"remote", remoteConfig(cfg), remote.DefaultConfig()},
+		{"session", sessionConfig(cfg), inference.DefaultSessionConfig()},
+		{"backend", backendConfig(cfg, "f.parquet"), features.DefaultBackendConfig(features.BackendMemory, "f.parquet")},
+		{"quality", qualityConfig(cfg), features.DefaultQualityConfig()},
+		{"breaker", breakerConfig(cfg), shapclient.DefaultBreakerConfig()},
+		{"retry", retryConfig(cfg), shapclient.DefaultRetryConfig()},
+		{"labels", labelConfig(cfg), metrics.DefaultLabelConfig()},
+		{"tracing", tracingConfig(cfg), tracing.DefaultConfig()},
+		{"shadow", shadowConfig(cfg), inference.DefaultShadowConfig()},
+		{"quantile", quantileConfig(cfg), inference.DefaultQuantileConfig()},
+		{"guard", guardConfig(cfg), inference.DefaultGuardConfig()},
+		{"jobs", jobsConfig(cfg), jobs.DefaultConfig()},
+		{"actuals", actualsConfig(cfg), actuals.DefaultConfig()},
+		{"alerts", alertsConfig(cfg), alerts.DefaultConfig()},
+		{"anomaly", anomalyConfig(cfg), anomaly.DefaultConfig()},
+		{"audit", auditConfig(cfg), audit.DefaultConfig()},
+		{"slo", sloConfig(cfg), slo.DefaultConfig()},
+		{"predlog", predLogConfig(cfg), predlog.DefaultConfig()},
+		{"live", liveConfig(cfg), live.DefaultConfig()},
+		{"hierarchy cache", hierarchyCacheConfig(cfg), cache.DefaultHierarchyConfig()},
+		{"drift", driftConfig(cfg), drift.DefaultConfig()},
+		{"backtest", backtestConfig(cfg), backtest.DefaultConfig()},
+		{"watch", watchConfig(cfg), watch.DefaultConfig()},
+		{"refresh", refreshConfig(cfg), refresh.DefaultConfig()},
+		{"ingest", ingestConfig(cfg), ingest.DefaultConfig()},
+		{"proxy", proxyConfig(cfg), mlrfmiddleware.DefaultProxyConfig()},
+		{"request logger", requestLoggerConfig(cfg), mlrfmiddleware.DefaultRequestLoggerConfig()},
+		{"timeout", timeoutConfig(cfg), mlrfmiddleware.DefaultTimeoutConfig()},
+		{"rate limiter", rateLimiterConfig(cfg), mlrfmiddleware.DefaultRateLimiterConfig()},
+		{"body limit", bodyLimitConfig(cfg), mlrfmiddleware.DefaultBodyLimitConfig()},
+		{"concurrency", concurrencyConfig(cfg), mlrfmiddleware.DefaultConcurrencyConfig()},
+		{"version", versionConfig(cfg), mlrfmiddleware.DefaultVersionConfig()},
+		{"api keys", apiKeyConfig(cfg), mlrfmiddleware.DefaultKeyStoreConfig()},
+	} {
+		if !reflect.DeepEqual(tc.got, tc.want) {
+			t.Errorf("%s: configuration defaults %+v differ from the component's %+v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestComponentOverrides(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_PROTOCOL", "grpc")
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("ALERT_MAPE_THRESHOLD", "15.5")
+	t.Setenv("ANOMALY_WINDOW_DAYS", "30")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+	t.Setenv("FEATURE_BACKEND", features.BackendDuckDB)
+	t.Setenv("FEATURE_REFRESH_TZ", "America/Guayaquil")
+	t.Setenv("REQUEST_TIMEOUTS", "/explain=20s")
+	t.Setenv("RATE_LIMIT_CLASSES", "predict=50/100")
+	t.Setenv("API_LEGACY_SUNSET", "2027-06-30")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := tracingConfig(cfg); c.Endpoint != "localhost:4317" || c.Environment != "production" {
+		t.Errorf("expected the gRPC collector port and the production environment, got %+v", c)
+	}
+	if c := alertsConfig(cfg); c.MAPEThreshold != 15.5 {
+		t.Errorf("expected MAPE threshold 15.5, got %v", c.MAPEThreshold)
+	}
+	if c := anomalyConfig(cfg); c.WindowDays != 30 {
+		t.Errorf("expected a 30-day anomaly window, got %d", c.WindowDays)
+	}
+	if c := remoteConfig(cfg); c.S3Region != "eu-west-1" {
+		t.Errorf("expected AWS_DEFAULT_REGION as the S3 region, got %q", c.S3Region)
+	}
+	if c := backendConfig(cfg, "data/f.parquet"); c.Driver != "duckdb" || c.Table != "read_parquet('data/f.parquet')" {
+		t.Errorf("unexpected duckdb config %+v", c)
+	}
+	if c := refreshConfig(cfg); c.Location.String() != "America/Guayaquil" {
+		t.Errorf("expected the refresh time zone, got %s", c.Location)
+	}
+	if c := timeoutConfig(cfg); len(c.Routes) != 1 || c.Routes["/explain"] != 20*time.Second {
+		t.Errorf("expected only the /explain timeout, got %v", c.Routes)
+	}
+	if c := rateLimiterConfig(cfg); len(c.ClassLimits) != 1 || c.ClassLimits[mlrfmiddleware.ClassPredict] != (mlrfmiddleware.ClassLimit{RequestsPerSecond: 50, BurstSize: 100}) {
+		t.Errorf("expected only the predict limit of 50/100, got %v", c.ClassLimits)
+	}
+	if c := versionConfig(cfg); c.Sunset.Format("2006-01-02") != "2027-06-30" {
+		t.Errorf("expected sunset 2027-06-30, got %v", c.Sunset)
+	}
+}
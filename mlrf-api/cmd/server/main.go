@@ -3,9 +3,12 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -15,13 +18,29 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/alerts"
+	"github.com/mlrf/mlrf-api/internal/audit"
+	"github.com/mlrf/mlrf-api/internal/backtest"
 	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/drift"
 	"github.com/mlrf/mlrf-api/internal/features"
 	"github.com/mlrf/mlrf-api/internal/handlers"
+	"github.com/mlrf/mlrf-api/internal/history"
 	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/metrics"
 	mlrfmiddleware "github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/remote"
 	"github.com/mlrf/mlrf-api/internal/shapclient"
+	"github.com/mlrf/mlrf-api/internal/slo"
 	"github.com/mlrf/mlrf-api/internal/tracing"
+	"github.com/mlrf/mlrf-api/internal/watch"
 )
 
 func main() {
@@ -29,41 +48,55 @@ func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
-	// Get configuration from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Load configuration: CONFIG_FILE (optional TOML) with environment overrides
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid configuration")
 	}
-
-	modelPath := os.Getenv("MODEL_PATH")
-	if modelPath == "" {
-		modelPath = "models/lightgbm_model.onnx"
+	if cfg.Server.LogFormat == "json" {
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 	}
-
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "redis://localhost:6379"
+	setLogLevel(cfg.Server.LogLevel)
+	if cfg.File() != "" {
+		log.Info().Str("file", cfg.File()).Msg("Configuration file loaded")
 	}
 
-	featurePath := os.Getenv("FEATURE_PATH")
-	if featurePath == "" {
-		featurePath = "data/features/feature_matrix.parquet"
+	port := strconv.Itoa(cfg.Server.Port)
+	modelPath := cfg.Model.Path
+	redisURL := cfg.Cache.RedisURL
+	featurePath := cfg.Features.Path
+	shapServiceAddr := cfg.Data.SHAPServiceAddr
+
+	// Forecast horizons accepted by the prediction endpoints (validated by config.Load)
+	if horizons, err := handlers.ParseHorizons(cfg.Predictions.Horizons); err == nil {
+		handlers.SetValidHorizons(horizons)
 	}
 
-	shapServiceAddr := os.Getenv("SHAP_SERVICE_ADDR")
-	if shapServiceAddr == "" {
-		shapServiceAddr = "localhost:50051"
+	// Download s3:// and gs:// model and feature files into the local cache
+	fetcher := remote.NewFetcher(remoteConfig(cfg))
+	modelSource := remote.Source{URL: modelPath, SHA256: cfg.Model.SHA256}
+	featureSource := remote.Source{URL: featurePath, SHA256: cfg.Features.SHA256}
+	if local, fetchErr := fetcher.Fetch(context.Background(), modelSource); fetchErr != nil {
+		log.Warn().Err(fetchErr).Str("url", modelPath).Msg("Failed to fetch model")
+	} else {
+		modelPath = local
+	}
+	if local, fetchErr := fetcher.Fetch(context.Background(), featureSource); fetchErr != nil {
+		log.Warn().Err(fetchErr).Str("url", featurePath).Msg("Failed to fetch feature file")
+	} else {
+		featurePath = local
 	}
 
-	// Initialize ONNX Runtime
-	var onnxSession *inference.ONNXSession
-	var err error
+	// Initialize ONNX Runtime (wrapped for hot reload via /admin/reload-model)
+	var onnxSession *inference.ReloadableSession
+	defer inference.DestroyEnvironment()
 
 	// Check if model file exists before trying to load
 	if _, statErr := os.Stat(modelPath); statErr == nil {
-		onnxSession, err = inference.NewONNXSession(modelPath)
+		onnxSession, err = inference.NewReloadableSession(modelPath, sessionConfig(cfg))
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to load ONNX model, running without inference")
+			onnxSession = nil
 		} else {
 			log.Info().Str("model", modelPath).Msg("ONNX model loaded")
 			defer onnxSession.Close()
@@ -72,12 +105,41 @@ func main() {
 		log.Warn().Str("model", modelPath).Msg("Model file not found, running without inference")
 	}
 
+	// Load model registry from manifest (optional - enables per-request model selection)
+	manifestPath := cfg.Model.ManifestPath
+	var registry *inference.Registry
+	if _, statErr := os.Stat(manifestPath); statErr == nil {
+		manifest, err := inference.LoadManifest(manifestPath)
+		if err == nil {
+			registry, err = inference.NewRegistryFromManifest(manifest, sessionConfig(cfg))
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("manifest", manifestPath).Msg("Failed to load model registry, serving single model")
+			registry = nil
+		} else {
+			log.Info().
+				Str("manifest", manifestPath).
+				Str("champion", registry.Champion()).
+				Int("models", len(registry.Models())).
+				Msg("Model registry loaded")
+			defer registry.Close()
+		}
+	}
+
 	// Initialize Redis cache
 	var redisCache *cache.RedisCache
+	// Validated with the configuration
+	ttlRules, _ := cache.ParseTTLRules(cfg.Cache.TTLPolicy)
 	cacheCfg := cache.Config{
-		URL:      redisURL,
-		MaxLocal: 10000,
-		TTL:      time.Hour,
+		URL:           redisURL,
+		MaxLocalBytes: int64(cfg.Cache.LocalMaxBytes),
+		TTL:           cfg.Cache.TTL,
+		NegativeTTL:   cfg.Cache.NegativeTTL,
+		TTLRules:      ttlRules,
+		TTLJitter:     cfg.Cache.TTLJitter,
+		EarlyExpiry:   cfg.Cache.EarlyExpiry,
+		ExplainTTL:    cfg.Cache.ExplainTTL,
+		Compression:   cfg.Cache.Compression,
 	}
 	redisCache, err = cache.NewRedisCache(cacheCfg)
 	if err != nil {
@@ -88,10 +150,19 @@ func main() {
 		defer redisCache.Close()
 	}
 
-	// Initialize feature store
+	// Initialize feature store (FEATURE_BACKEND=duckdb|sqlite queries a database on demand)
 	var featureStore *features.Store
-	if _, statErr := os.Stat(featurePath); statErr == nil {
-		featureStore, err = features.NewStore(featurePath)
+	backendCfg := backendConfig(cfg, featurePath)
+	if backendCfg.Backend != features.BackendMemory {
+		featureStore, err = features.NewSQLStore(backendCfg)
+		if err != nil {
+			log.Warn().Err(err).Str("backend", backendCfg.Backend).Msg("Failed to open feature database, using zero features")
+			featureStore = nil
+		} else {
+			featureStore.SetQualityConfig(qualityConfig(cfg))
+		}
+	} else if _, statErr := os.Stat(featurePath); statErr == nil {
+		featureStore, err = features.NewStoreWithQuality(featurePath, qualityConfig(cfg))
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to load feature store, using zero features")
 		} else {
@@ -113,11 +184,16 @@ func main() {
 		shapClient = nil
 	} else {
 		log.Info().Str("addr", shapServiceAddr).Msg("SHAP service connected")
+		shapClient.SetBreaker(shapclient.NewBreaker(breakerConfig(cfg)))
+		shapClient.SetRetry(retryConfig(cfg))
 		defer shapClient.Close()
 	}
 
+	// Bound the model_version and family labels of the prediction metrics
+	metrics.SetLabelConfig(labelConfig(cfg))
+
 	// Initialize OpenTelemetry tracing
-	tracingCfg := tracing.DefaultConfig()
+	tracingCfg := tracingConfig(cfg)
 	tracerProvider, err := tracing.NewTracerProvider(tracingCfg)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to initialize tracing, running without distributed tracing")
@@ -131,67 +207,576 @@ func main() {
 		}()
 	}
 
-	// Create handlers
-	h := handlers.NewHandlers(onnxSession, redisCache, featureStore, shapClient)
+	// Create handlers (avoid wrapping a nil session in a non-nil interface)
+	var model inference.Inferencer
+	if onnxSession != nil {
+		model = onnxSession
+	}
+	if registry != nil {
+		// Champion from the manifest replaces the MODEL_PATH session as the default model,
+		// following /admin/models/promote
+		if champion, _, ok := registry.Get(""); ok {
+			model = registry.Default()
+			if reloadable, ok := champion.(*inference.ReloadableSession); ok {
+				onnxSession = reloadable
+			}
+		}
+	}
+	h := handlers.NewHandlers(model, redisCache, featureStore, shapClient)
+	h.SetRemoteSources(fetcher, modelSource, featureSource)
+	h.SetConfig(cfg)
+	if onnxSession != nil {
+		// Known inputs recorded at export; a failing model keeps /health/ready failing
+		if err := onnxSession.SetSelfTestPath(cfg.Model.SelfTestPath); err != nil {
+			log.Error().Err(err).Str("selftest", cfg.Model.SelfTestPath).Msg("Model self-test failed, marking not ready")
+		} else if info := onnxSession.Info(); info.SelfTest != nil {
+			log.Info().Int("cases", info.SelfTest.Cases).Msg("Model self-test passed")
+		}
+		h.SetModelReloader(onnxSession)
+	}
+	if registry != nil {
+		h.SetModelRegistry(registry)
+		// Reloads replace the model of the champion at the time
+		h.SetModelReloader(registry.Default())
+	}
+	// Cached predictions are scoped to the loaded model and features
+	h.RotateCacheNamespace()
+	// Hot series are warmed into the cache now and after every reload
+	if warmCfg, err := handlers.ParseCacheWarmConfig(cfg.Cache); err != nil {
+		log.Warn().Err(err).Msg("Invalid cache warm configuration, warming disabled")
+	} else if warmCfg.Enabled() {
+		h.SetCacheWarm(warmCfg)
+		log.Info().
+			Int("series", len(warmCfg.Series)).
+			Int("top_k", warmCfg.TopK).
+			Str("date", warmCfg.Date).
+			Msg("Prediction cache warming enabled")
+	}
 
-	// Load prediction intervals for confidence bands
-	intervalsPath := os.Getenv("INTERVALS_PATH")
-	if intervalsPath == "" {
-		intervalsPath = "models/prediction_intervals.json"
+	// Shadow-mode challenger evaluation (optional - controlled by SHADOW_MODEL env var)
+	shadowCfg := shadowConfig(cfg)
+	if shadowCfg.Challenger != "" {
+		var challenger inference.Inferencer
+		var challengerKey string
+		var ok bool
+		if registry != nil {
+			challenger, challengerKey, ok = registry.Get(shadowCfg.Challenger)
+		}
+		if !ok {
+			log.Warn().Str("challenger", shadowCfg.Challenger).Msg("Shadow model not found in registry, shadow mode disabled")
+		} else {
+			shadowRunner := inference.NewShadowRunner(challengerKey, challenger, shadowCfg)
+			defer shadowRunner.Close()
+			h.SetShadowRunner(shadowRunner)
+			log.Info().
+				Str("challenger", challengerKey).
+				Int("workers", shadowCfg.Workers).
+				Int("queue_size", shadowCfg.QueueSize).
+				Int("log_size", shadowCfg.LogSize).
+				Msg("Shadow mode enabled")
+		}
 	}
+
+	// Quantile models for P10/P50/P90 forecasts (optional - controlled by QUANTILE_*_MODEL_PATH env vars)
+	quantileCfg := quantileConfig(cfg)
+	if quantileCfg.Enabled() {
+		quantiles, err := inference.LoadQuantileEnsemble(quantileCfg, sessionConfig(cfg))
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load quantile models, using static prediction intervals")
+		} else {
+			defer quantiles.Close()
+			h.SetQuantileEnsemble(quantiles)
+			log.Info().
+				Str("p10", quantileCfg.P10Path).
+				Str("p50", quantileCfg.P50Path).
+				Str("p90", quantileCfg.P90Path).
+				Msg("Quantile ensemble loaded")
+		}
+	}
+
+	// Sanity guard on model output (PREDICTION_MIN, PREDICTION_MAX, PREDICTION_NAN_POLICY)
+	guardCfg := guardConfig(cfg)
+	h.SetPredictionGuard(inference.NewGuard(guardCfg))
+	log.Info().
+		Float64("min", guardCfg.Min).
+		Float64("max", guardCfg.Max).
+		Str("nan_policy", guardCfg.Policy).
+		Msg("Prediction guard enabled")
+
+	// Async batch prediction jobs
+	jobCfg := jobsConfig(cfg)
+	jobManager := jobs.NewManager(jobCfg)
+	defer jobManager.Close()
+	h.SetJobManager(jobManager)
+	log.Info().
+		Int("workers", jobCfg.Workers).
+		Int("max_items", jobCfg.MaxItems).
+		Dur("result_ttl", jobCfg.ResultTTL).
+		Msg("Job manager started")
+
+	// Actuals ingestion and live accuracy tracking
+	actualsCfg := actualsConfig(cfg)
+	actualsStore, err := actuals.Open(actualsCfg)
+	if err != nil {
+		log.Warn().Err(err).Str("driver", actualsCfg.Driver).Msg("Actuals store unavailable, POST /actuals disabled")
+	} else {
+		defer actualsStore.Close()
+		h.SetActualsStore(actualsStore, actualsCfg.MaxBatch)
+		records, _ := actualsStore.Len()
+		log.Info().
+			Str("driver", actualsCfg.Driver).
+			Int("records", records).
+			Msg("Actuals store connected")
+
+		// Accuracy drift alerts over the submitted actuals
+		alertCfg := alertsConfig(cfg)
+		alertMonitor := alerts.NewMonitor(alertCfg, actualsStore)
+		alertMonitor.Start()
+		defer alertMonitor.Close()
+		h.SetAlertMonitor(alertMonitor)
+		log.Info().
+			Dur("interval", alertCfg.Interval).
+			Float64("mape_threshold", alertCfg.MAPEThreshold).
+			Bool("webhook", alertCfg.WebhookURL != "").
+			Msg("Accuracy alert monitor started")
+
+		// Anomalies of the submitted actuals against their forecast
+		anomalyCfg := anomalyConfig(cfg)
+		h.SetAnomalyConfig(anomalyCfg)
+		log.Info().
+			Str("method", anomalyCfg.Method).
+			Float64("z_threshold", anomalyCfg.ZThreshold).
+			Float64("critical_z", anomalyCfg.CriticalZ).
+			Msg("Anomaly detection enabled")
+	}
+
+	// Append-only audit trail of /admin calls, queried on /admin/audit
+	auditCfg := auditConfig(cfg)
+	auditStore, err := audit.Open(auditCfg)
+	if err != nil {
+		log.Warn().Err(err).Str("path", auditCfg.Path).Msg("Admin audit store unavailable, /admin calls not recorded")
+	} else {
+		defer auditStore.Close()
+		h.SetAuditStore(auditStore)
+		log.Info().
+			Str("path", auditCfg.Path).
+			Int("entries", auditStore.Len()).
+			Msg("Admin audit store loaded")
+	}
+
+	// Per-route availability and latency objectives
+	sloCfg := sloConfig(cfg)
+	sloTracker := slo.NewTracker(sloCfg)
+	sloTracker.Start()
+	defer sloTracker.Close()
+	h.SetSLOTracker(sloTracker)
+	log.Info().
+		Strs("routes", sloCfg.Routes).
+		Dur("window", sloCfg.Window).
+		Msg("SLO tracking started")
+
+	// Prediction logging for auditing and drift monitoring
+	predLogCfg := predLogConfig(cfg)
+	if predLogCfg.Enabled {
+		predLogger, err := predlog.NewLogger(predLogCfg)
+		if err != nil {
+			log.Warn().Err(err).Str("dir", predLogCfg.Dir).Msg("Prediction logging disabled")
+		} else {
+			defer predLogger.Close()
+			h.SetPredictionLogger(predLogger)
+			log.Info().
+				Str("dir", predLogCfg.Dir).
+				Int("batch_size", predLogCfg.BatchSize).
+				Dur("flush_interval", predLogCfg.FlushInterval).
+				Msg("Prediction logging enabled")
+		}
+	}
+
+	// Live prediction updates over WebSocket
+	liveCfg := liveConfig(cfg)
+	h.SetLiveHub(live.NewHub(liveCfg))
+	log.Info().
+		Int("max_subscriptions", liveCfg.MaxSubscriptions).
+		Int("send_buffer", liveCfg.SendBuffer).
+		Msg("Live update hub started")
+
+	// Load prediction intervals for confidence bands
+	intervalsPath := cfg.Model.IntervalsPath
 	if err := h.LoadPredictionIntervals(intervalsPath); err != nil {
 		log.Warn().Str("path", intervalsPath).Msg("Running without prediction intervals")
 	}
 
+	// Load the serving model's training metrics for /model
+	if err := h.LoadTrainingMetrics(cfg.Model.TrainingMetrics); err != nil {
+		log.Warn().Str("path", cfg.Model.TrainingMetrics).Msg("Running without training metrics")
+	}
+
+	// Load the model comparison for /model-metrics
+	if err := h.LoadModelMetrics(cfg.Model.ModelMetrics); err != nil {
+		log.Warn().Str("path", cfg.Model.ModelMetrics).Msg("Running without model metrics")
+	}
+
+	// Load forecast error covariance for MinT hierarchy reconciliation
+	covariancePath := cfg.Data.ReconciliationCovariancePath
+	if err := h.LoadReconciliationCovariance(covariancePath); err != nil {
+		log.Warn().Str("path", covariancePath).Msg("Running without MinT reconciliation")
+	}
+
+	// Load the LightGBM model dump for native TreeSHAP (optional - /explain then needs the SHAP service)
+	treeSHAPPath := cfg.Data.TreeSHAPModelPath
+	if err := h.LoadTreeSHAP(treeSHAPPath); err != nil {
+		log.Warn().Str("path", treeSHAPPath).Msg("Running without native TreeSHAP")
+	}
+
+	// Load waterfalls precomputed by training (optional - only served to /explain with the offline engine)
+	shapDataPath := cfg.Data.SHAPDataPath
+	if err := h.LoadOfflineExplanations(shapDataPath); err != nil {
+		log.Warn().Str("path", shapDataPath).Msg("Running without offline SHAP waterfalls")
+	}
+
+	// Load precomputed global feature importance (optional - /explain/global then computes it)
+	importancePath := cfg.Data.GlobalImportancePath
+	if err := h.LoadGlobalImportance(importancePath); err != nil {
+		log.Warn().Str("path", importancePath).Msg("Running without precomputed global feature importance")
+	}
+
+	// Load hierarchy definition (optional - falls back to the tree in HIERARCHY_DATA_PATH)
+	hierarchyPath := cfg.Data.HierarchyDefinitionPath
+	if err := h.LoadHierarchyDefinition(hierarchyPath); err != nil {
+		log.Warn().Str("path", hierarchyPath).Msg("Running without hierarchy definition")
+	}
+
+	// Load the holiday calendar for is_holiday of days rolled forward past the feature matrix
+	if err := h.LoadHolidayCalendar(cfg.Data.HolidaysPath); err != nil {
+		log.Warn().Str("path", cfg.Data.HolidaysPath).Msg("Running without holiday calendar")
+	}
+
+	// Load supplied future values of oil_price and onpromotion, e.g. finance's oil price forecast
+	if err := h.LoadRegressorOverrides(cfg.Data.RegressorOverridesPath); err != nil {
+		log.Warn().Str("path", cfg.Data.RegressorOverridesPath).Msg("Running without regressor overrides")
+	}
+
+	// Load the daily sales history served by /historical, from HISTORICAL_DB_TABLE of
+	// the feature database when set, otherwise from HISTORICAL_DATA_PATH
+	if table := cfg.Data.HistoricalDBTable; table != "" {
+		if err := loadSQLHistory(h, backendCfg, table); err != nil {
+			log.Warn().Err(err).Str("table", table).Msg("Running without sales history")
+		}
+	} else if err := h.LoadHistory(cfg.Data.HistoricalDataPath); err != nil {
+		log.Warn().Str("path", cfg.Data.HistoricalDataPath).Msg("Running without sales history")
+	}
+
+	// Cache assembled hierarchy trees (shared via Redis when available)
+	hierarchyCacheCfg := hierarchyCacheConfig(cfg)
+	h.SetHierarchyCache(cache.NewHierarchyCache(redisCache, hierarchyCacheCfg))
+	log.Info().
+		Dur("ttl", hierarchyCacheCfg.TTL).
+		Bool("redis", redisCache != nil).
+		Msg("Hierarchy cache enabled")
+
+	// Feature drift monitoring against training distributions
+	driftCfg := driftConfig(cfg)
+	if ref, err := drift.LoadReference(driftCfg.ReferencePath); err != nil {
+		log.Warn().Err(err).Str("path", driftCfg.ReferencePath).Msg("Feature drift reference unavailable, /monitoring/feature-drift disabled")
+	} else {
+		h.SetFeatureDrift(ref, driftCfg)
+		log.Info().
+			Str("path", driftCfg.ReferencePath).
+			Int("features", len(ref.Features)).
+			Msg("Loaded feature drift reference")
+	}
+
+	// Cache backtest results for the dashboard
+	backtestCfg := backtestConfig(cfg)
+	h.SetBacktestCache(backtest.NewCache(backtestCfg), backtestCfg.MaxDays)
+
+	// Reload changed feature, model and interval files (optional - controlled by WATCH_FILES env var)
+	watchCfg := watchConfig(cfg)
+	if watchCfg.Enabled {
+		var targets []watch.Target
+		if featureStore != nil && !remote.IsRemote(featureSource.URL) {
+			targets = append(targets, watch.Target{Name: "features", Path: featurePath, Reload: func(ctx context.Context) error {
+				_, err := h.RefreshFeatures(ctx)
+				return err
+			}})
+		}
+		if onnxSession != nil && !remote.IsRemote(modelSource.URL) {
+			targets = append(targets, watch.Target{Name: "model", Path: modelPath, Reload: func(ctx context.Context) error {
+				_, err := h.RefreshModel(ctx)
+				return err
+			}})
+		}
+		targets = append(targets, watch.Target{Name: "intervals", Path: intervalsPath, Reload: h.RefreshIntervals})
+
+		watcher := watch.New(watchCfg, targets...)
+		watcher.Start()
+		defer watcher.Close()
+		log.Info().
+			Int("files", len(targets)).
+			Dur("interval", watchCfg.Interval).
+			Dur("debounce", watchCfg.Debounce).
+			Msg("File watcher started")
+	}
+
+	// Scheduled feature refresh
+	refreshCfg := refreshConfig(cfg)
+	if refreshCfg.Cron != "" {
+		scheduler, err := refresh.NewScheduler(refreshCfg, func(ctx context.Context) (int, error) {
+			if _, err := h.RefreshFeatures(ctx); err != nil {
+				return 0, err
+			}
+			if !refreshCfg.Warm {
+				return 0, nil
+			}
+			return h.WarmPredictionCache(ctx)
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Invalid FEATURE_REFRESH_CRON, scheduled refresh disabled")
+		} else {
+			scheduler.Start()
+			defer scheduler.Close()
+			h.SetRefreshScheduler(scheduler)
+			log.Info().
+				Str("schedule", refreshCfg.Cron).
+				Str("timezone", refreshCfg.Location.String()).
+				Bool("warm", refreshCfg.Warm).
+				Time("next_run", scheduler.Status().NextRun).
+				Msg("Scheduled feature refresh enabled")
+		}
+	}
+
+	// Feature rows streamed over Kafka or NATS, applied to the feature store as they arrive
+	ingestCfg := ingestConfig(cfg)
+	if ingestCfg.Broker != "" {
+		src, checkpoint, err := ingest.Open(ingestCfg)
+		if err != nil {
+			log.Warn().Err(err).Str("broker", ingestCfg.Broker).Msg("Feature ingestion unavailable")
+		} else {
+			consumer := ingest.NewConsumer(ingestCfg, src, checkpoint, h.ApplyFeatureRows, h.FeaturesAppended)
+			consumer.Start()
+			defer consumer.Close()
+			h.SetIngestConsumer(consumer)
+			log.Info().
+				Str("broker", ingestCfg.Broker).
+				Str("topic", ingestCfg.Topic).
+				Interface("offsets", checkpoint.Offsets).
+				Msg("Feature ingestion started")
+		}
+	}
+
 	// Setup router
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	// Request ID for handlers and the X-Request-ID response header
+	r.Use(mlrfmiddleware.RequestID)
+	// Client IP for rate limiting and logs; forwarding headers only honoured from TRUSTED_PROXIES
+	r.Use(mlrfmiddleware.RealIP(proxyConfig(cfg)))
+	// Structured access logging (successful requests sampled by LOG_SAMPLE_RATE), to
+	// rotating ACCESS_LOG_FILE and AUDIT_LOG_FILE files if set
+	requestLoggerCfg := requestLoggerConfig(cfg)
+	requestLogger, err := mlrfmiddleware.NewRequestLogger(requestLoggerCfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open access log")
+	}
+	defer requestLogger.Close()
+	if requestLoggerCfg.File != "" || requestLoggerCfg.AuditFile != "" {
+		log.Info().
+			Str("access_log", requestLoggerCfg.File).
+			Str("audit_log", requestLoggerCfg.AuditFile).
+			Msg("Access log files opened")
+	}
+	r.Use(requestLogger.Middleware)
+	// In-flight request count awaited by /admin/drain (probes and WebSockets excluded)
+	inFlight := mlrfmiddleware.NewInFlightTracker([]string{"/health", "/health/live", "/health/ready", "/metrics/prometheus", "/ws", "/admin/drain"})
+	r.Use(inFlight.Middleware)
+	h.SetInFlightCounter(inFlight)
+	// Panic recovery with a logged stack and a standard INTERNAL_ERROR response
+	r.Use(mlrfmiddleware.Recoverer)
+	// Request timeouts by route (REQUEST_TIMEOUT, REQUEST_TIMEOUTS), propagated to inference,
+	// cache and SHAP calls; streaming responses and WebSockets manage their own per-write deadlines
+	timeoutCfg := timeoutConfig(cfg)
+	log.Info().
+		Dur("default", timeoutCfg.Default).
+		Interface("routes", timeoutCfg.Routes).
+		Msg("Request timeouts configured")
+	r.Use(mlrfmiddleware.Timeout(timeoutCfg))
 
 	// OpenTelemetry tracing middleware (skip health and metrics endpoints for efficiency)
-	r.Use(mlrfmiddleware.TracingMiddlewareWithFilter(tracerProvider, []string{"/health", "/metrics/prometheus"}))
+	r.Use(mlrfmiddleware.TracingMiddlewareWithFilter(tracerProvider, []string{"/health", "/health/live", "/health/ready", "/metrics/prometheus"}))
 
-	// CORS middleware for dashboard (configurable via CORS_ORIGINS env var)
-	corsConfig := mlrfmiddleware.NewCORSConfig()
+	// CORS middleware for dashboard (CORS_ORIGINS)
+	corsConfig := mlrfmiddleware.NewCORSConfig(mlrfmiddleware.ParseCORSOrigins(cfg.Server.CORSOrigins))
 	log.Info().Strs("origins", corsConfig.AllowedOrigins).Msg("CORS configuration loaded")
-	r.Use(mlrfmiddleware.CORS(corsConfig))
+	corsPolicy := mlrfmiddleware.NewCORSPolicy(corsConfig)
+	r.Use(corsPolicy.Middleware)
+	h.SetLiveOrigins(corsPolicy.AllowsOrigin)
 
-	// Rate limiting middleware (100 req/sec default, configurable via RATE_LIMIT_RPS/BURST)
-	rateLimitCfg := mlrfmiddleware.DefaultRateLimiterConfig()
+	// API keys (optional - controlled by API_KEY and API_KEYS_FILE)
+	keyStore, err := mlrfmiddleware.NewKeyStore(apiKeyConfig(cfg))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load API keys")
+	}
+	if keyStore.Enabled() {
+		log.Info().Int("keys", len(keyStore.Keys())).Msg("API key authentication enabled")
+	}
+	if auditLogger, ok := requestLogger.AuditLogger(); ok {
+		keyStore.SetAuditLogger(auditLogger)
+	}
+
+	// Rate limiting middleware (100 req/sec default, configurable via RATE_LIMIT_RPS/BURST),
+	// per API key for requests with a valid key, which may also have a monthly quota, and
+	// per endpoint class with the RATE_LIMIT_CLASSES limits
+	rateLimitCfg := rateLimiterConfig(cfg)
 	rateLimiter := mlrfmiddleware.NewRateLimiter(rateLimitCfg)
+	rateLimiter.SetKeyStore(keyStore)
+	if redisCache != nil {
+		// Monthly quotas are shared by every replica through Redis
+		rateLimiter.SetQuotaCounter(redisCache)
+	}
 	log.Info().
 		Float64("rps", rateLimitCfg.RequestsPerSecond).
 		Int("burst", rateLimitCfg.BurstSize).
+		Interface("class_limits", rateLimitCfg.ClassLimits).
+		Bool("shared_quotas", redisCache != nil).
 		Msg("Rate limiter initialized")
 	r.Use(rateLimiter.Middleware)
 
-	// API Key authentication middleware (optional - controlled by API_KEY env var)
-	r.Use(mlrfmiddleware.APIKeyAuth)
+	// API Key authentication middleware
+	r.Use(keyStore.Middleware)
+
+	// Request body size limits (413 before oversized bodies are read)
+	r.Use(mlrfmiddleware.BodyLimit(bodyLimitConfig(cfg)))
 
 	// Prometheus metrics middleware (must be after auth to capture authenticated requests)
 	r.Use(mlrfmiddleware.PrometheusMetrics)
+	r.Use(mlrfmiddleware.SLO(sloTracker))
+
+	// Load shedding: bounded concurrency for inference-heavy routes, 503 OVERLOADED once
+	// the wait for a slot exceeds CONCURRENCY_MAX_WAIT or CONCURRENCY_MAX_QUEUE requests
+	concurrencyCfg := concurrencyConfig(cfg)
+	if concurrencyCfg.Limit > 0 {
+		log.Info().
+			Int("limit", concurrencyCfg.Limit).
+			Int("max_queue", concurrencyCfg.MaxQueue).
+			Dur("max_wait", concurrencyCfg.MaxWait).
+			Msg("Concurrency limiter enabled")
+	}
+	r.Use(mlrfmiddleware.NewConcurrencyLimiter(concurrencyCfg).Middleware)
 
-	// Routes
+	// Operational routes (unversioned)
 	r.Get("/health", h.Health)
-	r.Post("/predict", h.Predict)
-	r.Post("/predict/simple", h.PredictSimple)
-	r.Post("/predict/batch", h.PredictBatch)
-	r.Post("/explain", h.Explain)
-	r.Get("/hierarchy", h.Hierarchy)
+	r.Get("/health/live", h.Liveness)
+	r.Get("/health/ready", h.Readiness)
 	r.Get("/metrics", h.Metrics)
-	r.Get("/model-metrics", h.ModelMetrics)
-	r.Get("/accuracy", h.Accuracy)
-	r.Post("/whatif", h.WhatIf)
-	r.Post("/historical", h.Historical)
+	r.Get("/slo", h.SLO)
 	r.Handle("/metrics/prometheus", promhttp.Handler())
+	r.Get("/openapi.json", h.OpenAPI)
+	r.Get("/docs", h.Docs)
+	r.Get("/ws", h.Live)
+	r.Post("/ws/token", keyStore.WebSocketTokenHandler)
+
+	// API routes, mounted under /v1 and (deprecated) at the unversioned legacy paths
+	apiRoutes := func(r chi.Router) {
+		r.Post("/predict", h.Predict)
+		r.Post("/predict/simple", h.PredictSimple)
+		r.Post("/predict/batch", h.PredictBatch)
+		r.Post("/predict/stream", h.PredictStream)
+		r.Post("/predict/aggregate", h.PredictAggregate)
+		r.Post("/predict/replay", h.PredictReplay)
+		r.With(keyStore.RequireScope(mlrfmiddleware.ScopeWrite)).Post("/predict/jobs", h.SubmitPredictJob)
+		r.Get("/jobs/{id}", h.GetJob)
+		r.Get("/jobs/{id}/result", h.GetJobResult)
+		r.Post("/forecast", h.Forecast)
+		r.Post("/explain", h.Explain)
+		r.Get("/explain/global", h.GlobalImportance)
+		r.Get("/hierarchy", h.Hierarchy)
+		r.Get("/hierarchy/validate", h.ValidateHierarchy)
+		r.Get("/hierarchy/{nodeID}/children", h.HierarchyChildren)
+		r.Get("/model", h.Model)
+		r.Get("/model-metrics", h.ModelMetrics)
+		r.Get("/models/comparison", h.ModelComparison)
+		r.Get("/accuracy", h.Accuracy)
+		r.Get("/accuracy/calibration", h.Calibration)
+		r.With(keyStore.RequireScope(mlrfmiddleware.ScopeWrite)).Post("/actuals", h.SubmitActuals)
+		r.Post("/backtest", h.Backtest)
+		r.Get("/alerts", h.Alerts)
+		r.Get("/monitoring/feature-drift", h.FeatureDrift)
+		r.Get("/monitoring/anomalies", h.Anomalies)
+		r.Post("/whatif", h.WhatIf)
+		r.Post("/whatif/sweep", h.WhatIfSweep)
+		r.Post("/promotions/impact", h.PromotionsImpact)
+		r.Get("/features/schema", h.FeatureSchema)
+		r.Get("/families", h.Families)
+		r.Get("/stores", h.Stores)
+		r.Get("/calendar/holidays", h.Holidays)
+		r.Post("/historical", h.Historical)
+		r.Get("/series", h.Series)
+		r.Post("/insights/top-movers", h.TopMovers)
+	}
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(mlrfmiddleware.Version("v1"))
+		apiRoutes(r)
+	})
+
+	versionCfg := versionConfig(cfg)
+	r.Group(func(r chi.Router) {
+		r.Use(mlrfmiddleware.LegacyVersion(versionCfg))
+		apiRoutes(r)
+	})
+	log.Info().
+		Strs("versions", versionCfg.Supported).
+		Time("legacy_sunset", versionCfg.Sunset).
+		Msg("API versioning configured")
+
+	// Admin routes (require the admin scope: ADMIN_API_KEY or a key listing it), each
+	// call recorded in the audit store
+	r.Group(func(r chi.Router) {
+		if auditStore != nil {
+			r.Use(auditStore.Middleware)
+		}
+		r.Use(keyStore.RequireScope(mlrfmiddleware.ScopeAdmin))
+		r.Post("/admin/reload-features", h.ReloadFeatures)
+		r.Post("/admin/append-features", h.AppendFeatures)
+		r.Post("/admin/reload-model", h.ReloadModel)
+		r.Post("/admin/models/promote", h.PromoteModel)
+		r.Post("/admin/reload-hierarchy", h.ReloadHierarchy)
+		r.Post("/admin/reload-intervals", h.ReloadIntervals)
+		r.Post("/admin/reload-all", h.ReloadAll)
+		r.Post("/admin/cache/flush", h.FlushCache)
+		r.Get("/admin/feature-quality", h.FeatureQuality)
+		r.Get("/admin/config", h.AdminConfig)
+		r.Post("/admin/config/reload", h.ReloadConfig)
+		r.Get("/admin/log-level", h.GetLogLevel)
+		r.Put("/admin/log-level", h.UpdateLogLevel)
+		r.Post("/admin/drain", h.StartDrain)
+		r.Get("/admin/drain", h.GetDrainStatus)
+		r.Get("/admin/audit", h.AdminAudit)
+	})
+
+	// Rate limits, CORS origins, API keys and log sampling follow configuration reloads; the
+	// handlers apply the log level, cache TTL and feature staleness threshold themselves
+	h.OnConfigReload(func(cfg *config.Config) {
+		classLimits, _ := mlrfmiddleware.ParseClassLimits(cfg.Server.RateLimitClasses)
+		rateLimiter.SetLimits(cfg.Server.RateLimitRPS, cfg.Server.RateLimitBurst, classLimits)
+		if err := keyStore.Load(apiKeyConfig(cfg)); err != nil {
+			log.Error().Err(err).Msg("Failed to reload API keys, keeping the current keys")
+		}
+		corsPolicy.SetOrigins(mlrfmiddleware.ParseCORSOrigins(cfg.Server.CORSOrigins))
+		requestLogger.SetSampleRate(cfg.Server.LogSampleRate)
+	})
 
-	// Admin routes (protected by ADMIN_API_KEY)
-	r.Post("/admin/reload-features", h.ReloadFeatures)
+	// SIGHUP reloads the configuration like POST /admin/config/reload
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := h.RefreshConfig(); err != nil {
+				log.Error().Err(err).Msg("Configuration reload failed, keeping the current configuration")
+			}
+		}
+	}()
 
 	// Start server
 	srv := &http.Server{
@@ -226,3 +811,30 @@ func main() {
 
 	log.Info().Msg("Server stopped")
 }
+
+// setLogLevel sets the global log level (validated by config.Load).
+func setLogLevel(level string) {
+	if lvl, err := zerolog.ParseLevel(level); err == nil {
+		zerolog.SetGlobalLevel(lvl)
+	}
+}
+
+// loadSQLHistory loads the daily sales history from a table of the FEATURE_BACKEND
+// database, using its driver and DSN, and reads it again the same way on /admin/reload-all.
+func loadSQLHistory(h *handlers.Handlers, backendCfg features.BackendConfig, table string) error {
+	if backendCfg.Backend == features.BackendMemory {
+		return fmt.Errorf("HISTORICAL_DB_TABLE needs FEATURE_BACKEND=%s or %s", features.BackendDuckDB, features.BackendSQLite)
+	}
+	h.SetHistoryLoader(func(ctx context.Context) (*history.Store, error) {
+		db, err := sql.Open(backendCfg.Driver, backendCfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return history.LoadSQL(ctx, db, table, backendCfg.Backend == features.BackendDuckDB)
+	})
+	_, err := h.RefreshHistory(context.Background())
+	return err
+}
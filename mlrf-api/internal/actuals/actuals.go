@@ -0,0 +1,306 @@
+// Package actuals stores realized sales submitted by downstream systems, paired
+// with the prediction that was served for the same series and date, so forecast
+// accuracy can be tracked live.
+//
+// Records are persisted in a SQL table keyed by (store, family, date); a later
+// record for the same key replaces the earlier one. The sqlite driver is linked in;
+// Postgres works with a "postgres" or "pgx" driver linked into cmd/server, which
+// lets every replica share the submitted actuals.
+package actuals
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers "sqlite" (pure Go)
+)
+
+// Config holds actuals store configuration.
+type Config struct {
+	Driver     string // database/sql driver: sqlite, or postgres/pgx when linked
+	DSN        string // Data source name; for sqlite, the database file
+	ImportPath string // JSON lines file of a previous release, imported into an empty table
+	MaxBatch   int    // Maximum actuals per submission
+}
+
+// DefaultConfig returns the default actuals configuration.
+func DefaultConfig() Config {
+	return Config{
+		Driver:     "sqlite",
+		DSN:        "data/actuals.db",
+		ImportPath: "data/actuals.jsonl",
+		MaxBatch:   10000,
+	}
+}
+
+// Record is a realized sales value and, when one could be produced, the
+// prediction for the same series and date.
+type Record struct {
+	StoreNbr   int       `json:"store_nbr"`
+	Family     string    `json:"family"`
+	Date       string    `json:"date"`
+	Actual     float64   `json:"actual"`
+	Prediction *float64  `json:"prediction,omitempty"`
+	Horizon    int       `json:"horizon,omitempty"`
+	Model      string    `json:"model,omitempty"` // Model version that produced the prediction
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Filter selects records. Zero values match everything; dates are inclusive.
+type Filter struct {
+	StoreNbr  int
+	Family    string
+	StartDate string
+	EndDate   string
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS actuals (
+	store_nbr   INTEGER NOT NULL,
+	family      TEXT NOT NULL,
+	date        TEXT NOT NULL,
+	actual      DOUBLE PRECISION NOT NULL,
+	prediction  DOUBLE PRECISION,
+	horizon     INTEGER NOT NULL,
+	model       TEXT NOT NULL,
+	received_at TEXT NOT NULL,
+	PRIMARY KEY (store_nbr, family, date)
+)`
+
+const upsert = `INSERT INTO actuals (store_nbr, family, date, actual, prediction, horizon, model, received_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (store_nbr, family, date) DO UPDATE SET
+	actual = excluded.actual, prediction = excluded.prediction, horizon = excluded.horizon,
+	model = excluded.model, received_at = excluded.received_at`
+
+// Store persists actuals in a SQL database. Safe for concurrent use.
+type Store struct {
+	db       *sql.DB
+	numbered bool // The driver takes $1, $2... placeholders instead of ?
+}
+
+// Open connects to the database, creating the actuals table if needed. With sqlite,
+// the database file's directory is created too. When the table is empty and
+// cfg.ImportPath exists, its records are imported.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Driver == "sqlite" && cfg.DSN != ":memory:" && !strings.HasPrefix(cfg.DSN, "file:") {
+		if err := os.MkdirAll(filepath.Dir(cfg.DSN), 0o755); err != nil {
+			return nil, fmt.Errorf("create actuals directory: %w", err)
+		}
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open actuals database (is the %q driver linked?): %w", cfg.Driver, err)
+	}
+	if cfg.Driver == "sqlite" {
+		// SQLite serializes writers; one connection avoids "database is locked"
+		db.SetMaxOpenConns(1)
+	}
+	s := &Store{db: db, numbered: cfg.Driver == "postgres" || cfg.Driver == "pgx"}
+	if _, err := db.Exec(createTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create actuals table: %w", err)
+	}
+	if cfg.ImportPath != "" {
+		if err := s.importFile(cfg.ImportPath); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// importFile adds the records of a JSON lines file when the table is empty. A
+// missing file is not an error.
+func (s *Store) importFile(path string) error {
+	if n, err := s.Len(); err != nil || n > 0 {
+		return err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open actuals import file: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("actuals import file line %d: %w", line, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read actuals import file: %w", err)
+	}
+	// Later lines replace earlier ones, as when the file was replayed
+	return s.Add(records)
+}
+
+// rebind rewrites ? placeholders for drivers that number them.
+func (s *Store) rebind(query string) string {
+	if !s.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Add persists records in one transaction, replacing any stored for the same series
+// and date. Within a batch, the last record for a key wins.
+func (s *Store) Add(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin actuals transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(s.rebind(upsert))
+	if err != nil {
+		return fmt.Errorf("prepare actuals insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		_, err := stmt.Exec(r.StoreNbr, r.Family, r.Date, r.Actual, r.Prediction, r.Horizon, r.Model,
+			r.ReceivedAt.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("write actual: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit actuals: %w", err)
+	}
+	return nil
+}
+
+// List returns the matching records ordered by date, store and family.
+func (s *Store) List(f Filter) ([]Record, error) {
+	query := `SELECT store_nbr, family, date, actual, prediction, horizon, model, received_at FROM actuals WHERE 1 = 1`
+	var args []interface{}
+	if f.StoreNbr != 0 {
+		query += ` AND store_nbr = ?`
+		args = append(args, f.StoreNbr)
+	}
+	if f.Family != "" {
+		query += ` AND family = ?`
+		args = append(args, f.Family)
+	}
+	if f.StartDate != "" {
+		query += ` AND date >= ?`
+		args = append(args, f.StartDate)
+	}
+	if f.EndDate != "" {
+		query += ` AND date <= ?`
+		args = append(args, f.EndDate)
+	}
+	query += ` ORDER BY date, store_nbr, family`
+
+	rows, err := s.db.Query(s.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query actuals: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var pred sql.NullFloat64
+		var received string
+		if err := rows.Scan(&r.StoreNbr, &r.Family, &r.Date, &r.Actual, &pred, &r.Horizon, &r.Model, &received); err != nil {
+			return nil, fmt.Errorf("scan actual: %w", err)
+		}
+		if pred.Valid {
+			r.Prediction = &pred.Float64
+		}
+		r.ReceivedAt, _ = time.Parse(time.RFC3339Nano, received)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read actuals: %w", err)
+	}
+	return records, nil
+}
+
+// Len returns the number of stored records.
+func (s *Store) Len() (int, error) {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM actuals`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count actuals: %w", err)
+	}
+	return n, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Metrics are accuracy statistics over records that have a prediction.
+type Metrics struct {
+	Count   int     // Records with a prediction
+	MAPE    float64 // Mean absolute percentage error, over records with a non-zero actual
+	RMSLE   float64 // Root mean squared log error (negative predictions count as zero)
+	Bias    float64 // Mean of prediction - actual
+	BiasPct float64 // Sum of prediction - actual as a percentage of total actuals
+}
+
+// Evaluate computes accuracy metrics, skipping records without a prediction.
+func Evaluate(records []Record) Metrics {
+	var m Metrics
+	var apeSum, sqLogSum, errSum, actualSum float64
+	apeCount := 0
+	for _, r := range records {
+		if r.Prediction == nil {
+			continue
+		}
+		pred := *r.Prediction
+		m.Count++
+		errSum += pred - r.Actual
+		actualSum += r.Actual
+		d := math.Log1p(math.Max(pred, 0)) - math.Log1p(r.Actual)
+		sqLogSum += d * d
+		if r.Actual != 0 {
+			apeSum += math.Abs(pred-r.Actual) / math.Abs(r.Actual) * 100
+			apeCount++
+		}
+	}
+	if m.Count == 0 {
+		return m
+	}
+	m.RMSLE = math.Sqrt(sqLogSum / float64(m.Count))
+	m.Bias = errSum / float64(m.Count)
+	if apeCount > 0 {
+		m.MAPE = apeSum / float64(apeCount)
+	}
+	if actualSum != 0 {
+		m.BiasPct = errSum / actualSum * 100
+	}
+	return m
+}
@@ -0,0 +1,140 @@
+package actuals
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestStorePersistsAndReplaces(t *testing.T) {
+	cfg := Config{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "nested", "actuals.db")}
+	s, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	err = s.Add([]Record{
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-02", Actual: 10},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-02", Actual: 20, Prediction: ptr(18), Model: "v1"},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Actual: 30},
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// Resubmission replaces the earlier value
+	if err := s.Add([]Record{{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-02", Actual: 12}}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	if n, err := s.Len(); err != nil || n != 3 {
+		t.Fatalf("expected 3 records after reopening, got %d (%v)", n, err)
+	}
+	records, err := s.List(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Date != "2017-08-01" || records[1].StoreNbr != 1 || records[2].Actual != 12 {
+		t.Errorf("unexpected order or values: %+v", records)
+	}
+	if records[1].Prediction == nil || *records[1].Prediction != 18 || records[1].Model != "v1" {
+		t.Errorf("expected the prediction to be persisted: %+v", records[1])
+	}
+	if records[0].Prediction != nil {
+		t.Errorf("expected no prediction, got %v", *records[0].Prediction)
+	}
+
+	if got, _ := s.List(Filter{StoreNbr: 1, StartDate: "2017-08-02"}); len(got) != 1 || got[0].Actual != 20 {
+		t.Errorf("unexpected filtered records: %+v", got)
+	}
+}
+
+func TestOpenImportsLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "actuals.jsonl")
+	os.WriteFile(legacy, []byte(`{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-01", "actual": 5}
+{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-01", "actual": 7, "prediction": 6}
+`), 0o644)
+
+	cfg := Config{Driver: "sqlite", DSN: filepath.Join(dir, "actuals.db"), ImportPath: legacy}
+	s, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	records, _ := s.List(Filter{})
+	if len(records) != 1 || records[0].Actual != 7 || records[0].Prediction == nil {
+		t.Fatalf("expected the later line to win, got %+v", records)
+	}
+
+	// A non-empty table is not re-imported
+	s.Add([]Record{{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Actual: 9}})
+	s.Close()
+	s, err = Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if records, _ := s.List(Filter{}); records[0].Actual != 9 {
+		t.Errorf("expected the stored value to be kept, got %+v", records)
+	}
+}
+
+func TestOpenCorruptImportFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "actuals.jsonl")
+	os.WriteFile(legacy, []byte("{\"store_nbr\": 1}\nnot json\n"), 0o644)
+
+	if _, err := Open(Config{Driver: "sqlite", DSN: filepath.Join(dir, "actuals.db"), ImportPath: legacy}); err == nil {
+		t.Error("expected error for corrupt line")
+	}
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	if _, err := Open(Config{Driver: "nope", DSN: "x"}); err == nil {
+		t.Error("expected error for an unregistered driver")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	s := &Store{numbered: true}
+	if got := s.rebind("a = ? AND b = ?"); got != "a = $1 AND b = $2" {
+		t.Errorf("unexpected query %q", got)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	m := Evaluate([]Record{
+		{Actual: 100, Prediction: ptr(110)},
+		{Actual: 50, Prediction: ptr(40)},
+		{Actual: 0, Prediction: ptr(0)},
+		{Actual: 70}, // no prediction
+	})
+
+	if m.Count != 3 {
+		t.Errorf("expected 3 evaluated records, got %d", m.Count)
+	}
+	// (10% + 20%) / 2; the zero actual is excluded
+	if math.Abs(m.MAPE-15) > 1e-9 {
+		t.Errorf("expected MAPE 15, got %f", m.MAPE)
+	}
+	if math.Abs(m.Bias-0) > 1e-9 || math.Abs(m.BiasPct) > 1e-9 {
+		t.Errorf("expected zero bias, got %f / %f%%", m.Bias, m.BiasPct)
+	}
+	want := math.Sqrt((math.Pow(math.Log(111)-math.Log(101), 2) + math.Pow(math.Log(41)-math.Log(51), 2)) / 3)
+	if math.Abs(m.RMSLE-want) > 1e-9 {
+		t.Errorf("expected RMSLE %f, got %f", want, m.RMSLE)
+	}
+
+	if empty := Evaluate(nil); empty.Count != 0 || empty.RMSLE != 0 {
+		t.Errorf("expected zero metrics, got %+v", empty)
+	}
+}
@@ -0,0 +1,306 @@
+// Package alerts monitors forecast accuracy against submitted actuals and notifies
+// a webhook when a family's error breaches a threshold.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// State is the lifecycle state of an alert.
+type State string
+
+// Alert states.
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Config holds drift alert configuration.
+type Config struct {
+	Interval       time.Duration // Time between accuracy checks
+	WindowDays     int           // Days of actuals evaluated, ending at the latest actual
+	MAPEThreshold  float64       // Family MAPE (percent) above which an alert fires
+	MinSamples     int           // Paired actuals a family needs before it is evaluated
+	WebhookURL     string        // Notified on fire and resolve; empty disables notifications
+	WebhookTimeout time.Duration // Timeout for a webhook request
+}
+
+// DefaultConfig returns the default alert configuration, without a webhook.
+func DefaultConfig() Config {
+	return Config{
+		Interval:       15 * time.Minute,
+		WindowDays:     7,
+		MAPEThreshold:  20,
+		MinSamples:     10,
+		WebhookTimeout: 10 * time.Second,
+	}
+}
+
+// Alert is the accuracy alert state of one family.
+type Alert struct {
+	Family      string     `json:"family"`
+	State       State      `json:"state"`
+	MAPE        float64    `json:"mape"` // Latest evaluated MAPE
+	Threshold   float64    `json:"threshold"`
+	Samples     int        `json:"samples"`
+	WindowStart string     `json:"window_start"`
+	WindowEnd   string     `json:"window_end"`
+	FiredAt     time.Time  `json:"fired_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Monitor periodically evaluates per-family MAPE over recent actuals and tracks
+// alert state. Safe for concurrent use.
+type Monitor struct {
+	cfg    Config
+	store  *actuals.Store
+	client *http.Client
+
+	mu        sync.RWMutex
+	alerts    map[string]*Alert
+	lastCheck time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor over the actuals store. Call Start to begin periodic checks.
+func NewMonitor(cfg Config, store *actuals.Store) *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Monitor{
+		cfg:    cfg,
+		store:  store,
+		client: &http.Client{Timeout: cfg.WebhookTimeout},
+		alerts: make(map[string]*Alert),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start runs a check immediately and then every Interval until Close.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Close stops periodic checks and waits for an in-flight check to finish.
+func (m *Monitor) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Config returns the monitor's configuration.
+func (m *Monitor) Config() Config {
+	return m.cfg
+}
+
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	m.Check(m.ctx)
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(m.ctx)
+		}
+	}
+}
+
+// Check evaluates each family's MAPE over the last WindowDays of actuals with a
+// paired prediction, fires or resolves alerts and notifies the webhook of every
+// transition. Families with fewer than MinSamples records keep their state.
+// Returns the alerts that changed state.
+func (m *Monitor) Check(ctx context.Context) []Alert {
+	now := time.Now().UTC()
+	records, err := m.store.List(actuals.Filter{})
+	if err != nil {
+		log.Error().Err(err).Msg("Accuracy alert check skipped")
+		return nil
+	}
+	byFamily, start, end := recentByFamily(records, m.cfg.WindowDays)
+
+	var changed []Alert
+	m.mu.Lock()
+	for family, recs := range byFamily {
+		eval := actuals.Evaluate(recs)
+		if eval.Count < m.cfg.MinSamples {
+			continue
+		}
+		if a := m.update(family, eval, start, end, now); a != nil {
+			changed = append(changed, *a)
+		}
+	}
+	m.lastCheck = now
+	metrics.SetAlertsFiring(m.firingLocked())
+	m.mu.Unlock()
+
+	for _, a := range changed {
+		log.Warn().
+			Str("family", a.Family).
+			Str("state", string(a.State)).
+			Float64("mape", a.MAPE).
+			Float64("threshold", a.Threshold).
+			Msg("Accuracy alert state changed")
+		m.notify(ctx, a)
+	}
+	return changed
+}
+
+// recentByFamily groups records with a prediction from the last days ending at the
+// latest record's date (records are sorted by date) and returns that window.
+func recentByFamily(records []actuals.Record, days int) (map[string][]actuals.Record, string, string) {
+	byFamily := make(map[string][]actuals.Record)
+	if len(records) == 0 {
+		return byFamily, "", ""
+	}
+
+	end := records[len(records)-1].Date
+	start := end
+	if d, err := time.Parse("2006-01-02", end); err == nil {
+		start = d.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	}
+	for _, r := range records {
+		if r.Date >= start && r.Prediction != nil {
+			byFamily[r.Family] = append(byFamily[r.Family], r)
+		}
+	}
+	return byFamily, start, end
+}
+
+// update applies an evaluation to a family's alert and returns a copy when its state changed.
+// Must be called with mu held.
+func (m *Monitor) update(family string, eval actuals.Metrics, start, end string, now time.Time) *Alert {
+	breached := eval.MAPE > m.cfg.MAPEThreshold
+	a, exists := m.alerts[family]
+	if !exists {
+		if !breached {
+			return nil
+		}
+		a = &Alert{Family: family}
+		m.alerts[family] = a
+	}
+
+	a.MAPE = eval.MAPE
+	a.Threshold = m.cfg.MAPEThreshold
+	a.Samples = eval.Count
+	a.WindowStart, a.WindowEnd = start, end
+	a.UpdatedAt = now
+
+	switch {
+	case breached && a.State != StateFiring:
+		a.State = StateFiring
+		a.FiredAt = now
+		a.ResolvedAt = nil
+	case !breached && a.State == StateFiring:
+		a.State = StateResolved
+		resolved := now
+		a.ResolvedAt = &resolved
+	default:
+		return nil
+	}
+	c := *a
+	return &c
+}
+
+// firingLocked counts firing alerts. Must be called with mu held.
+func (m *Monitor) firingLocked() int {
+	n := 0
+	for _, a := range m.alerts {
+		if a.State == StateFiring {
+			n++
+		}
+	}
+	return n
+}
+
+// Alerts returns every alert, firing first, then by family.
+func (m *Monitor) Alerts() []Alert {
+	m.mu.RLock()
+	list := make([]Alert, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		list = append(list, *a)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].State == StateFiring) != (list[j].State == StateFiring) {
+			return list[i].State == StateFiring
+		}
+		return list[i].Family < list[j].Family
+	})
+	return list
+}
+
+// LastCheck returns when accuracy was last evaluated, or the zero time before the first check.
+func (m *Monitor) LastCheck() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastCheck
+}
+
+// webhookPayload is posted to the webhook. Text makes it a valid Slack incoming
+// webhook message; other receivers can use the structured alert.
+type webhookPayload struct {
+	Text  string `json:"text"`
+	Alert Alert  `json:"alert"`
+}
+
+// notify posts an alert transition to the webhook, if configured.
+func (m *Monitor) notify(ctx context.Context, a Alert) {
+	if m.cfg.WebhookURL == "" {
+		return
+	}
+
+	var text string
+	if a.State == StateFiring {
+		text = fmt.Sprintf(":rotating_light: Forecast accuracy alert: %s MAPE %.1f%% exceeds %.1f%% (%d actuals, %s to %s)",
+			a.Family, a.MAPE, a.Threshold, a.Samples, a.WindowStart, a.WindowEnd)
+	} else {
+		text = fmt.Sprintf(":white_check_mark: Forecast accuracy recovered: %s MAPE %.1f%% is within %.1f%% (%d actuals, %s to %s)",
+			a.Family, a.MAPE, a.Threshold, a.Samples, a.WindowStart, a.WindowEnd)
+	}
+	body, err := json.Marshal(webhookPayload{Text: text, Alert: a})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal alert notification")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create alert notification")
+		metrics.RecordAlertNotification("failed")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Str("family", a.Family).Msg("alert webhook failed")
+		metrics.RecordAlertNotification("failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Int("status", resp.StatusCode).Str("family", a.Family).Msg("alert webhook rejected notification")
+		metrics.RecordAlertNotification("failed")
+		return
+	}
+	metrics.RecordAlertNotification("sent")
+}
@@ -0,0 +1,174 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+)
+
+func newTestStore(t *testing.T) *actuals.Store {
+	t.Helper()
+	s, err := actuals.Open(actuals.Config{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "actuals.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// addDays stores one paired actual per day for a family, each off by errPct percent.
+func addDays(t *testing.T, s *actuals.Store, family string, first time.Time, days int, errPct float64) {
+	t.Helper()
+	var records []actuals.Record
+	for i := 0; i < days; i++ {
+		pred := 100 * (1 + errPct/100)
+		records = append(records, actuals.Record{
+			StoreNbr:   1,
+			Family:     family,
+			Date:       first.AddDate(0, 0, i).Format("2006-01-02"),
+			Actual:     100,
+			Prediction: &pred,
+		})
+	}
+	if err := s.Add(records); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads []webhookPayload
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var p webhookPayload
+	json.NewDecoder(r.Body).Decode(&p)
+	w.mu.Lock()
+	w.payloads = append(w.payloads, p)
+	w.mu.Unlock()
+}
+
+func TestMonitorFiresAndResolves(t *testing.T) {
+	store := newTestStore(t)
+	hook := &webhookRecorder{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	m := NewMonitor(Config{
+		Interval:       time.Hour,
+		WindowDays:     3,
+		MAPEThreshold:  20,
+		MinSamples:     3,
+		WebhookURL:     srv.URL,
+		WebhookTimeout: time.Second,
+	}, store)
+
+	aug1 := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	addDays(t, store, "DAIRY", aug1, 3, 50)
+	addDays(t, store, "BEVERAGES", aug1, 3, 5)
+	addDays(t, store, "EGGS", aug1, 2, 90) // below MinSamples
+
+	changed := m.Check(context.Background())
+	if len(changed) != 1 || changed[0].Family != "DAIRY" || changed[0].State != StateFiring {
+		t.Fatalf("expected DAIRY to fire, got %+v", changed)
+	}
+	if changed[0].WindowStart != "2017-08-01" || changed[0].WindowEnd != "2017-08-03" {
+		t.Errorf("unexpected window: %s to %s", changed[0].WindowStart, changed[0].WindowEnd)
+	}
+
+	// Unchanged state does not notify again
+	if changed := m.Check(context.Background()); len(changed) != 0 {
+		t.Errorf("expected no transitions on recheck, got %+v", changed)
+	}
+
+	// Newer accurate actuals move the window past the breach
+	addDays(t, store, "DAIRY", aug1.AddDate(0, 0, 3), 3, 1)
+	changed = m.Check(context.Background())
+	if len(changed) != 1 || changed[0].State != StateResolved || changed[0].ResolvedAt == nil {
+		t.Fatalf("expected DAIRY to resolve, got %+v", changed)
+	}
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if len(hook.payloads) != 2 {
+		t.Fatalf("expected 2 webhook notifications, got %d", len(hook.payloads))
+	}
+	if !strings.Contains(hook.payloads[0].Text, "DAIRY") || hook.payloads[0].Alert.State != StateFiring {
+		t.Errorf("unexpected firing notification: %+v", hook.payloads[0])
+	}
+	if hook.payloads[1].Alert.State != StateResolved {
+		t.Errorf("unexpected resolve notification: %+v", hook.payloads[1])
+	}
+
+	alerts := m.Alerts()
+	if len(alerts) != 1 || alerts[0].Family != "DAIRY" {
+		t.Errorf("expected only DAIRY to have alert state, got %+v", alerts)
+	}
+	if m.LastCheck().IsZero() {
+		t.Error("expected LastCheck to be set")
+	}
+}
+
+func TestMonitorAlertOrder(t *testing.T) {
+	store := newTestStore(t)
+	m := NewMonitor(Config{Interval: time.Hour, WindowDays: 7, MAPEThreshold: 20, MinSamples: 1}, store)
+
+	aug1 := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	addDays(t, store, "EGGS", aug1, 1, 50)
+	addDays(t, store, "DAIRY", aug1, 1, 50)
+	addDays(t, store, "BEVERAGES", aug1, 1, 50)
+	m.Check(context.Background())
+
+	// Resolve BEVERAGES by replacing its actual with an accurate one
+	addDays(t, store, "BEVERAGES", aug1, 1, 0)
+	m.Check(context.Background())
+
+	alerts := m.Alerts()
+	var order []string
+	for _, a := range alerts {
+		order = append(order, a.Family+":"+string(a.State))
+	}
+	want := "DAIRY:firing,EGGS:firing,BEVERAGES:resolved"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("expected order %s, got %s", want, got)
+	}
+}
+
+func TestMonitorStartClose(t *testing.T) {
+	store := newTestStore(t)
+	addDays(t, store, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), 1, 50)
+
+	m := NewMonitor(Config{Interval: time.Hour, WindowDays: 7, MAPEThreshold: 20, MinSamples: 1}, store)
+	m.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.LastCheck().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected an initial check on Start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.Close()
+
+	if alerts := m.Alerts(); len(alerts) != 1 || alerts[0].State != StateFiring {
+		t.Errorf("expected DAIRY firing after initial check, got %+v", alerts)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Interval != 15*time.Minute || cfg.WindowDays != 7 || cfg.MAPEThreshold != 20 || cfg.MinSamples != 10 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.WebhookURL != "" {
+		t.Errorf("expected no webhook by default, got %q", cfg.WebhookURL)
+	}
+}
@@ -0,0 +1,308 @@
+// Package anomaly flags days where realized sales of a series deviate from its
+// forecast: outside the forecast's confidence interval, or by a robust z-score of the
+// forecast residual against the series' recent residuals.
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+// Detection methods.
+const (
+	MethodAuto     = "auto"     // Interval where the series has intervals, z-score otherwise
+	MethodInterval = "interval" // Outside the 80% (warning) or 95% (critical) forecast interval
+	MethodZScore   = "zscore"   // Robust z-score of the residual against the series' baseline
+)
+
+// Severities, from least to most severe.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Directions of a deviation.
+const (
+	DirectionAbove = "above"
+	DirectionBelow = "below"
+)
+
+// z95 is the normal quantile of the two-sided 95% interval.
+const z95 = 1.96
+
+// MaxWindowDays caps the days checked at once.
+const MaxWindowDays = 90
+
+// madScale turns a median absolute deviation into a normal standard deviation.
+const madScale = 1.4826
+
+// Config holds anomaly detection configuration.
+type Config struct {
+	Method       string  // MethodAuto, MethodInterval or MethodZScore
+	BaselineDays int     // Days of residuals before the window a z-score is measured against
+	MinBaseline  int     // Residuals a series needs for a z-score
+	ZThreshold   float64 // |z| at or above which a day is a warning
+	CriticalZ    float64 // |z| at or above which a day is critical
+	WindowDays   int     // Days checked by default, ending at the latest actual
+}
+
+// DefaultConfig returns the default anomaly detection configuration.
+func DefaultConfig() Config {
+	return Config{
+		Method:       MethodAuto,
+		BaselineDays: 56,
+		MinBaseline:  7,
+		ZThreshold:   3.5,
+		CriticalZ:    5,
+		WindowDays:   1,
+	}
+}
+
+// Series identifies a (store, family) series.
+type Series struct {
+	StoreNbr int
+	Family   string
+}
+
+// Observation is the realized sales of a series on a date and its forecast. Upper95
+// is zero when the forecast has no interval.
+type Observation struct {
+	StoreNbr   int
+	Family     string
+	Date       string
+	Actual     float64
+	Prediction float64
+	Lower80    float64
+	Upper80    float64
+	Lower95    float64
+	Upper95    float64
+}
+
+// Anomaly is an observation that deviates from its forecast.
+type Anomaly struct {
+	StoreNbr     int     `json:"store_nbr"`
+	Family       string  `json:"family"`
+	Date         string  `json:"date"`
+	Actual       float64 `json:"actual"`
+	Prediction   float64 `json:"prediction"`
+	Lower        float64 `json:"lower"` // Expected range: the 95% interval, or the critical z-score band
+	Upper        float64 `json:"upper"`
+	Deviation    float64 `json:"deviation"`               // Actual - prediction
+	DeviationPct float64 `json:"deviation_pct,omitempty"` // Deviation as a percentage of the prediction
+	Score        float64 `json:"score"`                   // |z|: deviation in standard deviations, for ranking
+	Direction    string  `json:"direction"`               // above or below the forecast
+	Severity     string  `json:"severity"`
+	Method       string  `json:"method"` // interval or zscore
+}
+
+// Result is the outcome of checking a window of observations.
+type Result struct {
+	Checked   int       // Observations scored
+	Unscored  int       // Observations without an interval or enough baseline for their method
+	Anomalies []Anomaly // Most severe first, then by score
+}
+
+// Detect checks each observation with the configured method and returns the anomalies
+// ranked by severity and score. baseline holds each series' residuals (actual -
+// prediction) before the window, for z-scores.
+func Detect(window []Observation, baseline map[Series][]float64, cfg Config) Result {
+	var res Result
+	scales := make(map[Series]robustScale)
+	for _, o := range window {
+		method := cfg.Method
+		if method == MethodAuto {
+			method = MethodInterval
+			if o.Upper95 == 0 {
+				method = MethodZScore
+			}
+		}
+
+		var a Anomaly
+		var flagged bool
+		switch method {
+		case MethodInterval:
+			if o.Upper95 == 0 {
+				res.Unscored++
+				continue
+			}
+			a, flagged = interval(o)
+		default:
+			s := Series{StoreNbr: o.StoreNbr, Family: o.Family}
+			scale, ok := scales[s]
+			if !ok {
+				scale = newRobustScale(baseline[s], cfg.MinBaseline)
+				scales[s] = scale
+			}
+			if scale.sigma == 0 {
+				res.Unscored++
+				continue
+			}
+			a, flagged = zscore(o, scale, cfg)
+		}
+		res.Checked++
+		if flagged {
+			res.Anomalies = append(res.Anomalies, a)
+		}
+	}
+
+	sort.SliceStable(res.Anomalies, func(i, j int) bool {
+		a, b := res.Anomalies[i], res.Anomalies[j]
+		if a.Severity != b.Severity {
+			return a.Severity == SeverityCritical
+		}
+		return a.Score > b.Score
+	})
+	return res
+}
+
+// interval flags an observation outside its 80% interval, critical outside the 95%
+// one. The score measures the deviation in standard deviations implied by the 95%
+// interval on the side of the deviation.
+func interval(o Observation) (Anomaly, bool) {
+	a := newAnomaly(o, MethodInterval)
+	a.Lower, a.Upper = o.Lower95, o.Upper95
+
+	halfWidth := o.Upper95 - o.Prediction
+	if a.Direction == DirectionBelow {
+		halfWidth = o.Prediction - o.Lower95
+	}
+	if halfWidth > 0 {
+		a.Score = math.Abs(a.Deviation) / (halfWidth / z95)
+	}
+
+	switch {
+	case o.Actual > o.Upper95 || o.Actual < o.Lower95:
+		a.Severity = SeverityCritical
+	case o.Actual > o.Upper80 || o.Actual < o.Lower80:
+		a.Severity = SeverityWarning
+	default:
+		return a, false
+	}
+	return a, true
+}
+
+// zscore flags an observation whose residual is ZThreshold or more robust standard
+// deviations from the series' median residual, critical at CriticalZ.
+func zscore(o Observation, scale robustScale, cfg Config) (Anomaly, bool) {
+	a := newAnomaly(o, MethodZScore)
+	center := o.Prediction + scale.median
+	a.Lower = math.Max(center-cfg.CriticalZ*scale.sigma, 0)
+	a.Upper = center + cfg.CriticalZ*scale.sigma
+
+	z := (o.Actual - center) / scale.sigma
+	a.Score = math.Abs(z)
+	switch {
+	case a.Score >= cfg.CriticalZ:
+		a.Severity = SeverityCritical
+	case a.Score >= cfg.ZThreshold:
+		a.Severity = SeverityWarning
+	default:
+		return a, false
+	}
+	return a, true
+}
+
+// newAnomaly fills in the deviation of an observation.
+func newAnomaly(o Observation, method string) Anomaly {
+	a := Anomaly{
+		StoreNbr:   o.StoreNbr,
+		Family:     o.Family,
+		Date:       o.Date,
+		Actual:     o.Actual,
+		Prediction: o.Prediction,
+		Deviation:  o.Actual - o.Prediction,
+		Direction:  DirectionAbove,
+		Method:     method,
+	}
+	if a.Deviation < 0 {
+		a.Direction = DirectionBelow
+	}
+	if o.Prediction != 0 {
+		a.DeviationPct = a.Deviation / o.Prediction * 100
+	}
+	return a
+}
+
+// robustScale is the median and robust standard deviation of a series' residuals.
+// A zero sigma means the series can't be scored.
+type robustScale struct {
+	median float64
+	sigma  float64
+}
+
+// newRobustScale estimates the spread of residuals from their median absolute
+// deviation, falling back to the mean absolute deviation when more than half the
+// residuals are equal.
+func newRobustScale(residuals []float64, minCount int) robustScale {
+	if len(residuals) < max(minCount, 2) {
+		return robustScale{}
+	}
+	med := median(residuals)
+	dev := make([]float64, len(residuals))
+	var sum float64
+	for i, r := range residuals {
+		dev[i] = math.Abs(r - med)
+		sum += dev[i]
+	}
+	sigma := madScale * median(dev)
+	if sigma == 0 {
+		sigma = math.Sqrt(math.Pi/2) * sum / float64(len(dev))
+	}
+	return robustScale{median: med, sigma: sigma}
+}
+
+// median returns the median of values, without reordering them.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// StoreSummary rolls up the anomalies of one store.
+type StoreSummary struct {
+	StoreNbr  int     `json:"store_nbr"`
+	Anomalies int     `json:"anomalies"`
+	Critical  int     `json:"critical"`
+	Deviation float64 `json:"deviation"` // Net actual - prediction over its anomalies
+	MaxScore  float64 `json:"max_score"`
+}
+
+// ByStore summarizes anomalies per store, stores with the most critical anomalies
+// first, then the most anomalies and the highest score.
+func ByStore(anomalies []Anomaly) []StoreSummary {
+	index := make(map[int]int)
+	var stores []StoreSummary
+	for _, a := range anomalies {
+		i, ok := index[a.StoreNbr]
+		if !ok {
+			i = len(stores)
+			index[a.StoreNbr] = i
+			stores = append(stores, StoreSummary{StoreNbr: a.StoreNbr})
+		}
+		s := &stores[i]
+		s.Anomalies++
+		if a.Severity == SeverityCritical {
+			s.Critical++
+		}
+		s.Deviation += a.Deviation
+		s.MaxScore = math.Max(s.MaxScore, a.Score)
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		a, b := stores[i], stores[j]
+		if a.Critical != b.Critical {
+			return a.Critical > b.Critical
+		}
+		if a.Anomalies != b.Anomalies {
+			return a.Anomalies > b.Anomalies
+		}
+		if a.MaxScore != b.MaxScore {
+			return a.MaxScore > b.MaxScore
+		}
+		return a.StoreNbr < b.StoreNbr
+	})
+	return stores
+}
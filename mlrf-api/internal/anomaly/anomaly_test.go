@@ -0,0 +1,107 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+)
+
+// banded returns an observation with a forecast of 100 and intervals of +/-10 (80%)
+// and +/-20 (95%).
+func banded(storeNbr int, actual float64) Observation {
+	return Observation{StoreNbr: storeNbr, Family: "DAIRY", Date: "2017-08-15", Actual: actual, Prediction: 100,
+		Lower80: 90, Upper80: 110, Lower95: 80, Upper95: 120}
+}
+
+func TestDetectInterval(t *testing.T) {
+	cfg := Config{Method: MethodInterval}
+	res := Detect([]Observation{
+		banded(1, 105), // Within the 80% interval
+		banded(2, 115), // Warning above
+		banded(3, 50),  // Critical below
+		banded(4, 125), // Critical above, closer than store 3
+		{StoreNbr: 5, Family: "DAIRY", Date: "2017-08-15", Actual: 1000, Prediction: 100}, // No interval
+	}, nil, cfg)
+
+	if res.Checked != 4 || res.Unscored != 1 || len(res.Anomalies) != 3 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	want := []struct {
+		store     int
+		severity  string
+		direction string
+	}{{3, SeverityCritical, DirectionBelow}, {4, SeverityCritical, DirectionAbove}, {2, SeverityWarning, DirectionAbove}}
+	for i, w := range want {
+		a := res.Anomalies[i]
+		if a.StoreNbr != w.store || a.Severity != w.severity || a.Direction != w.direction || a.Method != MethodInterval {
+			t.Errorf("anomaly %d: expected store %d %s %s, got %+v", i, w.store, w.severity, w.direction, a)
+		}
+	}
+	// 50 below a forecast of 100 whose 95% interval spans 20: 50 / (20/1.96) sigmas
+	if a := res.Anomalies[0]; math.Abs(a.Score-4.9) > 1e-9 || a.Deviation != -50 || a.DeviationPct != -50 || a.Lower != 80 || a.Upper != 120 {
+		t.Errorf("unexpected critical anomaly %+v", a)
+	}
+}
+
+func TestDetectZScore(t *testing.T) {
+	cfg := Config{Method: MethodAuto, MinBaseline: 5, ZThreshold: 3.5, CriticalZ: 5}
+	baseline := map[Series][]float64{
+		{StoreNbr: 1, Family: "DAIRY"}: {-2, -1, 0, 1, 2, 0, 0}, // Median 0, MAD 1
+		{StoreNbr: 2, Family: "DAIRY"}: {5, 5, 5, 5, 5, 5, 8},   // Median 5, MAD 0
+		{StoreNbr: 3, Family: "DAIRY"}: {1, 2},
+	}
+	obs := func(store int, actual float64) Observation {
+		return Observation{StoreNbr: store, Family: "DAIRY", Date: "2017-08-15", Actual: actual, Prediction: 100}
+	}
+	res := Detect([]Observation{obs(1, 104), obs(1, 106), obs(1, 95), obs(1, 92), obs(2, 105), obs(3, 500), banded(4, 150)}, baseline, cfg)
+
+	// Store 3 lacks baseline; store 4 has intervals so auto checks those
+	if res.Checked != 6 || res.Unscored != 1 || len(res.Anomalies) != 3 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	// 8 below the forecast is 8/1.4826 robust sigmas, above the 4.9 of store 4
+	if a := res.Anomalies[0]; a.Actual != 92 || a.Severity != SeverityCritical || a.Method != MethodZScore || math.Abs(a.Score-8/madScale) > 1e-9 {
+		t.Errorf("expected a critical z-score below, got %+v", a)
+	}
+	if a := res.Anomalies[1]; a.StoreNbr != 4 || a.Method != MethodInterval {
+		t.Errorf("expected the interval anomaly of store 4, got %+v", a)
+	}
+	if a := res.Anomalies[2]; a.Actual != 106 || a.Severity != SeverityWarning || a.Upper != 100+5*madScale {
+		t.Errorf("expected a warning for 106, got %+v", a)
+	}
+	// 95 and 104 are within 3.5 sigmas, as is store 2's constant residual of 5
+}
+
+func TestRobustScale(t *testing.T) {
+	if s := newRobustScale([]float64{1, 2, 3}, 4); s.sigma != 0 {
+		t.Errorf("expected too few residuals to be unscored, got %+v", s)
+	}
+	if s := newRobustScale([]float64{3, 3, 3, 3}, 2); s.sigma != 0 {
+		t.Errorf("expected constant residuals to be unscored, got %+v", s)
+	}
+	// MAD of 0, mean absolute deviation of 1
+	if s := newRobustScale([]float64{0, 0, 0, 4}, 2); s.median != 0 || math.Abs(s.sigma-math.Sqrt(math.Pi/2)) > 1e-9 {
+		t.Errorf("unexpected fallback scale %+v", s)
+	}
+}
+
+func TestByStore(t *testing.T) {
+	stores := ByStore([]Anomaly{
+		{StoreNbr: 1, Severity: SeverityWarning, Deviation: 10, Score: 4},
+		{StoreNbr: 1, Severity: SeverityWarning, Deviation: -5, Score: 3},
+		{StoreNbr: 2, Severity: SeverityCritical, Deviation: -50, Score: 6},
+		{StoreNbr: 3, Severity: SeverityWarning, Deviation: 1, Score: 5},
+	})
+	if len(stores) != 3 || stores[0].StoreNbr != 2 || stores[1].StoreNbr != 1 || stores[2].StoreNbr != 3 {
+		t.Fatalf("unexpected order %+v", stores)
+	}
+	if s := stores[1]; s.Anomalies != 2 || s.Critical != 0 || s.Deviation != 5 || s.MaxScore != 4 {
+		t.Errorf("unexpected store 1 summary %+v", s)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Method != MethodAuto || cfg.ZThreshold != 3.5 || cfg.CriticalZ != 5 || cfg.WindowDays != 1 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
@@ -0,0 +1,278 @@
+// Package audit records administrative operations: who called which /admin endpoint,
+// when, with which parameters and with what outcome.
+//
+// Entries are persisted to an append-only JSON lines file and replayed on open, so
+// the trail survives restarts. Entries are never modified or removed by the server.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// Outcomes of an audited call.
+const (
+	OutcomeSuccess = "success" // 2xx or 3xx
+	OutcomeDenied  = "denied"  // 401 or 403
+	OutcomeFailure = "failure" // Any other error status
+)
+
+// Config holds audit store configuration.
+type Config struct {
+	Path          string // JSON lines file the entries are appended to
+	MaxParamBytes int    // Request bodies up to this size are recorded with the entry
+}
+
+// DefaultConfig returns the default audit configuration.
+func DefaultConfig() Config {
+	return Config{
+		Path:          "data/admin_audit.jsonl",
+		MaxParamBytes: 4096,
+	}
+}
+
+// Entry is one administrative call.
+type Entry struct {
+	ID         int64             `json:"id"` // Position in the audit file, from 1
+	Time       time.Time         `json:"time"`
+	APIKey     string            `json:"api_key,omitempty"` // Name of the calling key; empty without authentication
+	Owner      string            `json:"owner,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Query      map[string]string `json:"query,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`       // JSON request body up to MaxParamBytes
+	BodyBytes  int64             `json:"body_bytes,omitempty"` // Size of a body that isn't recorded
+	Status     int               `json:"status"`
+	Outcome    string            `json:"outcome"`
+	Result     string            `json:"result,omitempty"` // "status" of the response, e.g. reloaded or unchanged
+	Code       string            `json:"code,omitempty"`   // Error code of a failed call
+	Error      string            `json:"error,omitempty"`
+	DurationMs float64           `json:"duration_ms"`
+}
+
+// Filter selects entries. Zero values match everything; Since is inclusive, Until
+// exclusive, and Path matches the path and the paths below it.
+type Filter struct {
+	APIKey  string
+	Path    string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+}
+
+func (f Filter) match(e Entry) bool {
+	return (f.APIKey == "" || e.APIKey == f.APIKey) &&
+		(f.Path == "" || e.Path == f.Path || strings.HasPrefix(e.Path, strings.TrimSuffix(f.Path, "/")+"/")) &&
+		(f.Outcome == "" || e.Outcome == f.Outcome) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+// Store holds the audit trail in memory, backed by an append-only file. Safe for
+// concurrent use.
+type Store struct {
+	cfg     Config
+	mu      sync.RWMutex
+	file    *os.File
+	entries []Entry
+}
+
+// Open loads the entries in cfg.Path, creating the file if needed, and appends new
+// entries to it.
+func Open(cfg Config) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+
+	s := &Store{cfg: cfg, file: file}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("audit file line %d: %w", line, err)
+		}
+		s.entries = append(s.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+	return s, nil
+}
+
+// Append assigns e the next ID and persists it. The entry is only kept in memory
+// once written.
+func (s *Store) Append(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = int64(len(s.entries)) + 1
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, fmt.Errorf("marshal audit entry: %w", err)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return e, fmt.Errorf("write audit entry: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return e, fmt.Errorf("sync audit entry: %w", err)
+	}
+	s.entries = append(s.entries, e)
+	return e, nil
+}
+
+// List returns the matching entries, newest first, and how many matched before
+// limit was applied (0 = no limit).
+func (s *Store) List(f Filter, limit int) ([]Entry, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []Entry{}
+	matched := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if !f.match(s.entries[i]) {
+			continue
+		}
+		matched++
+		if limit == 0 || len(entries) < limit {
+			entries = append(entries, s.entries[i])
+		}
+	}
+	return entries, matched
+}
+
+// Len returns the number of stored entries.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Close closes the backing file.
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+// maxResponseCapture bounds the response bytes kept to read its status and error.
+const maxResponseCapture = 16 * 1024
+
+// Middleware records every request it serves in the store, with the API key that
+// authenticated it, its query and JSON body (bodies over MaxParamBytes are recorded
+// by size only) and the status, result and error of the response. The request is
+// served even when the entry can't be written; the failure is logged.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		e := Entry{
+			Time:       start.UTC(),
+			RequestID:  chimiddleware.GetReqID(r.Context()),
+			RemoteAddr: middleware.ClientIP(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+		}
+		if key, ok := middleware.APIKeyFromContext(r.Context()); ok {
+			e.APIKey, e.Owner = key.Name, key.Owner
+		}
+		for name, values := range r.URL.Query() {
+			if e.Query == nil {
+				e.Query = make(map[string]string)
+			}
+			e.Query[name] = strings.Join(values, ",")
+		}
+		s.readBody(r, &e)
+
+		var captured bytes.Buffer
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&limitedWriter{buf: &captured, max: maxResponseCapture})
+		next.ServeHTTP(ww, r)
+
+		e.Status = ww.Status()
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		switch {
+		case e.Status < 400:
+			e.Outcome = OutcomeSuccess
+		case e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden:
+			e.Outcome = OutcomeDenied
+		default:
+			e.Outcome = OutcomeFailure
+		}
+		var resp struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+			Code   string `json:"code"`
+		}
+		if json.Unmarshal(captured.Bytes(), &resp) == nil {
+			e.Result, e.Error, e.Code = resp.Status, resp.Error, resp.Code
+		}
+		e.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+		if _, err := s.Append(e); err != nil {
+			log.Error().Err(err).
+				Str("path", e.Path).
+				Str("api_key", e.APIKey).
+				Int("status", e.Status).
+				Msg("Failed to write admin audit entry")
+		}
+	})
+}
+
+// readBody records the request body in e when it is JSON of at most MaxParamBytes,
+// otherwise its size, leaving the body readable by the handler.
+func (s *Store) readBody(r *http.Request, e *Entry) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(s.cfg.MaxParamBytes)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil || len(head) == 0 {
+		return
+	}
+	if len(head) <= s.cfg.MaxParamBytes && json.Valid(head) {
+		e.Body = json.RawMessage(bytes.TrimSpace(head))
+		return
+	}
+	e.BodyBytes = r.ContentLength
+	if e.BodyBytes <= 0 {
+		e.BodyBytes = int64(len(head))
+	}
+}
+
+// limitedWriter keeps the first max bytes written to it and discards the rest.
+type limitedWriter struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if room := l.max - l.buf.Len(); room > 0 {
+		l.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/middleware"
+)
+
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(Config{Path: path, MaxParamBytes: 64})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStorePersistsAndFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.jsonl")
+	s := openStore(t, path)
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{Time: start, APIKey: "ops", Path: "/admin/reload-model", Outcome: OutcomeSuccess},
+		{Time: start.Add(time.Minute), APIKey: "ci", Path: "/admin/models/promote", Outcome: OutcomeFailure},
+		{Time: start.Add(2 * time.Minute), APIKey: "ops", Path: "/admin/models", Outcome: OutcomeSuccess},
+	} {
+		got, err := s.Append(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != int64(i+1) {
+			t.Errorf("expected ID %d, got %d", i+1, got.ID)
+		}
+	}
+	s.Close()
+
+	s = openStore(t, path)
+	if s.Len() != 3 {
+		t.Fatalf("expected 3 entries after replay, got %d", s.Len())
+	}
+	entries, matched := s.List(Filter{}, 2)
+	if matched != 3 || len(entries) != 2 || entries[0].ID != 3 || entries[1].ID != 2 {
+		t.Errorf("expected the two newest of 3, got %d %+v", matched, entries)
+	}
+	// A path matches the paths below it, not those sharing its prefix
+	if entries, _ := s.List(Filter{Path: "/admin/models"}, 0); len(entries) != 2 {
+		t.Errorf("expected /admin/models and /admin/models/promote, got %+v", entries)
+	}
+	if entries, _ := s.List(Filter{Path: "/admin/reload"}, 0); len(entries) != 0 {
+		t.Errorf("expected no match for a partial segment, got %+v", entries)
+	}
+	entries, _ = s.List(Filter{APIKey: "ops", Since: start.Add(time.Minute)}, 0)
+	if len(entries) != 1 || entries[0].ID != 3 {
+		t.Errorf("unexpected filtered entries %+v", entries)
+	}
+	if entries, _ := s.List(Filter{Outcome: OutcomeFailure, Until: start.Add(time.Minute)}, 0); len(entries) != 0 {
+		t.Errorf("expected until to be exclusive, got %+v", entries)
+	}
+
+	// New entries continue the numbering
+	if e, err := s.Append(Entry{Path: "/admin/drain"}); err != nil || e.ID != 4 {
+		t.Errorf("expected ID 4, got %d %v", e.ID, err)
+	}
+}
+
+func TestOpenCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	os.WriteFile(path, []byte("{\"id\": 1}\nnot json\n"), 0o600)
+
+	if _, err := Open(Config{Path: path}); err == nil {
+		t.Error("expected error for corrupt line")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s := openStore(t, filepath.Join(t.TempDir(), "audit.jsonl"))
+	keys, err := middleware.NewKeyStore(middleware.KeyStoreConfig{AdminKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handlerBody string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/models/promote", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		handlerBody = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"promoted","message":"ok"}`))
+	})
+	mux.HandleFunc("/admin/append-features", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"bad rows","code":"INVALID_REQUEST"}`))
+	})
+	handler := keys.Middleware(s.Middleware(keys.RequireScope(middleware.ScopeAdmin)(mux)))
+
+	serve := func(path, key, body string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.AdminKeyHeader, key)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("/admin/models/promote?dry_run=true", "secret", "{\n  \"model\": \"lightgbm@2\"\n}")
+	serve("/admin/append-features", "secret", strings.Repeat("x", 100))
+	serve("/admin/models/promote", "", `{}`)
+
+	if handlerBody != "{\n  \"model\": \"lightgbm@2\"\n}" {
+		t.Errorf("expected the handler to read the whole body, got %q", handlerBody)
+	}
+	entries, _ := s.List(Filter{}, 0)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	denied, failed, promoted := entries[0], entries[1], entries[2]
+
+	if promoted.APIKey != "admin" || promoted.Status != http.StatusOK || promoted.Outcome != OutcomeSuccess || promoted.Result != "promoted" {
+		t.Errorf("unexpected success entry %+v", promoted)
+	}
+	if promoted.Query["dry_run"] != "true" {
+		t.Errorf("expected the query to be recorded, got %+v", promoted.Query)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(promoted.Body, &body); err != nil || body["model"] != "lightgbm@2" {
+		t.Errorf("expected the JSON body to be recorded, got %s", promoted.Body)
+	}
+
+	if failed.Outcome != OutcomeFailure || failed.Code != "INVALID_REQUEST" || failed.Error != "bad rows" {
+		t.Errorf("unexpected failure entry %+v", failed)
+	}
+	if failed.Body != nil || failed.BodyBytes != 100 {
+		t.Errorf("expected only the size of a large body, got %s %d", failed.Body, failed.BodyBytes)
+	}
+
+	if denied.Outcome != OutcomeDenied || denied.Status != http.StatusUnauthorized || denied.APIKey != "" {
+		t.Errorf("unexpected denied entry %+v", denied)
+	}
+}
@@ -0,0 +1,252 @@
+// Package backtest scores historical forecasts against realized sales over rolling
+// origins and summarizes their accuracy and interval coverage.
+package backtest
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config holds backtest configuration.
+type Config struct {
+	MaxDays         int           // Longest date range a single backtest may cover
+	CacheTTL        time.Duration // How long a result is served from cache
+	CacheMaxEntries int           // Maximum results kept in process
+}
+
+// DefaultConfig returns the default backtest configuration.
+func DefaultConfig() Config {
+	return Config{
+		MaxDays:         366,
+		CacheTTL:        6 * time.Hour,
+		CacheMaxEntries: 50,
+	}
+}
+
+// Interval is a prediction's 80% and 95% confidence bands.
+type Interval struct {
+	Lower80, Upper80 float64
+	Lower95, Upper95 float64
+}
+
+// Point is one replayed prediction paired with the realized sales.
+type Point struct {
+	Fold       int // Index of the rolling origin the date belongs to
+	StoreNbr   int
+	Family     string
+	Date       string
+	Actual     float64
+	Prediction float64
+	Interval   *Interval // nil when no intervals were available
+}
+
+// Metrics are accuracy statistics over a set of points.
+type Metrics struct {
+	Count      int      `json:"count"`
+	RMSLE      float64  `json:"rmsle"`                 // Root mean squared log error (negative predictions count as zero)
+	MAPE       float64  `json:"mape"`                  // Mean absolute percentage error over non-zero actuals
+	Coverage80 *float64 `json:"coverage_80,omitempty"` // Fraction of actuals inside the 80% band; nil without intervals
+	Coverage95 *float64 `json:"coverage_95,omitempty"` // Fraction of actuals inside the 95% band; nil without intervals
+}
+
+// FoldMetrics are the metrics of one rolling origin, covering dates from Origin to End.
+type FoldMetrics struct {
+	Fold   int    `json:"fold"`
+	Origin string `json:"origin"`
+	End    string `json:"end"`
+	Metrics
+}
+
+// SeriesMetrics are the metrics of one (store, family) series across all folds.
+type SeriesMetrics struct {
+	StoreNbr int    `json:"store_nbr"`
+	Family   string `json:"family"`
+	Metrics
+}
+
+// Result summarizes a backtest overall, per fold and per series.
+type Result struct {
+	Overall Metrics         `json:"overall"`
+	Folds   []FoldMetrics   `json:"folds"`
+	Series  []SeriesMetrics `json:"series"` // Ordered by store, then family
+}
+
+// Window is the date range covered by one rolling origin.
+type Window struct {
+	Origin time.Time
+	End    time.Time
+}
+
+// Windows splits start..end (inclusive) into consecutive folds of step days.
+// The last fold is cut short at end.
+func Windows(start, end time.Time, step int) []Window {
+	if step <= 0 {
+		step = 1
+	}
+	var windows []Window
+	for origin := start; !origin.After(end); origin = origin.AddDate(0, 0, step) {
+		w := Window{Origin: origin, End: origin.AddDate(0, 0, step-1)}
+		if w.End.After(end) {
+			w.End = end
+		}
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// Evaluate computes metrics over points.
+func Evaluate(points []Point) Metrics {
+	var m Metrics
+	var sqLogSum, apeSum float64
+	var apeCount, intervalCount, in80, in95 int
+	for _, p := range points {
+		m.Count++
+		d := math.Log1p(math.Max(p.Prediction, 0)) - math.Log1p(math.Max(p.Actual, 0))
+		sqLogSum += d * d
+		if p.Actual != 0 {
+			apeSum += math.Abs(p.Prediction-p.Actual) / math.Abs(p.Actual) * 100
+			apeCount++
+		}
+		if iv := p.Interval; iv != nil {
+			intervalCount++
+			if p.Actual >= iv.Lower80 && p.Actual <= iv.Upper80 {
+				in80++
+			}
+			if p.Actual >= iv.Lower95 && p.Actual <= iv.Upper95 {
+				in95++
+			}
+		}
+	}
+	if m.Count == 0 {
+		return m
+	}
+	m.RMSLE = math.Sqrt(sqLogSum / float64(m.Count))
+	if apeCount > 0 {
+		m.MAPE = apeSum / float64(apeCount)
+	}
+	if intervalCount > 0 {
+		c80 := float64(in80) / float64(intervalCount)
+		c95 := float64(in95) / float64(intervalCount)
+		m.Coverage80, m.Coverage95 = &c80, &c95
+	}
+	return m
+}
+
+// Summarize evaluates points overall, per window and per series. Every window
+// is reported, with a zero count when none of its dates had actuals.
+func Summarize(points []Point, windows []Window) Result {
+	byFold := make([][]Point, len(windows))
+	type seriesKey struct {
+		storeNbr int
+		family   string
+	}
+	bySeries := make(map[seriesKey][]Point)
+	for _, p := range points {
+		if p.Fold >= 0 && p.Fold < len(windows) {
+			byFold[p.Fold] = append(byFold[p.Fold], p)
+		}
+		k := seriesKey{p.StoreNbr, p.Family}
+		bySeries[k] = append(bySeries[k], p)
+	}
+
+	res := Result{
+		Overall: Evaluate(points),
+		Folds:   make([]FoldMetrics, len(windows)),
+		Series:  make([]SeriesMetrics, 0, len(bySeries)),
+	}
+	for i, w := range windows {
+		res.Folds[i] = FoldMetrics{
+			Fold:    i,
+			Origin:  w.Origin.Format("2006-01-02"),
+			End:     w.End.Format("2006-01-02"),
+			Metrics: Evaluate(byFold[i]),
+		}
+	}
+	for k, pts := range bySeries {
+		res.Series = append(res.Series, SeriesMetrics{StoreNbr: k.storeNbr, Family: k.family, Metrics: Evaluate(pts)})
+	}
+	sort.Slice(res.Series, func(i, j int) bool {
+		if res.Series[i].StoreNbr != res.Series[j].StoreNbr {
+			return res.Series[i].StoreNbr < res.Series[j].StoreNbr
+		}
+		return res.Series[i].Family < res.Series[j].Family
+	})
+	return res
+}
+
+// Cache keeps backtest results in process for repeated dashboard requests.
+// Safe for concurrent use.
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	result    *Result
+	expiresAt time.Time
+}
+
+// NewCache creates a result cache from the config's TTL and size.
+func NewCache(cfg Config) *Cache {
+	return &Cache{
+		ttl:        cfg.CacheTTL,
+		maxEntries: cfg.CacheMaxEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// Get returns an unexpired result.
+func (c *Cache) Get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+// Set stores a result, evicting expired entries and then an arbitrary entry when at capacity.
+func (c *Cache) Set(key string, r *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{result: r, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Invalidate drops every cached result. Called when the model, features or actuals change.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached results.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
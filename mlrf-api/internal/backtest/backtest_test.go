@@ -0,0 +1,122 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestWindows(t *testing.T) {
+	windows := Windows(date("2017-08-01"), date("2017-08-10"), 4)
+	want := [][2]string{{"2017-08-01", "2017-08-04"}, {"2017-08-05", "2017-08-08"}, {"2017-08-09", "2017-08-10"}}
+	if len(windows) != len(want) {
+		t.Fatalf("expected %d windows, got %d", len(want), len(windows))
+	}
+	for i, w := range windows {
+		if got := w.Origin.Format("2006-01-02"); got != want[i][0] {
+			t.Errorf("window %d: expected origin %s, got %s", i, want[i][0], got)
+		}
+		if got := w.End.Format("2006-01-02"); got != want[i][1] {
+			t.Errorf("window %d: expected end %s, got %s", i, want[i][1], got)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	points := []Point{
+		{Actual: 100, Prediction: 110, Interval: &Interval{Lower80: 95, Upper80: 120, Lower95: 90, Upper95: 130}},
+		{Actual: 100, Prediction: 90, Interval: &Interval{Lower80: 80, Upper80: 95, Lower95: 70, Upper95: 105}},
+		{Actual: 0, Prediction: 5},
+	}
+	m := Evaluate(points)
+
+	if m.Count != 3 {
+		t.Errorf("expected count 3, got %d", m.Count)
+	}
+	// Zero actuals are excluded from MAPE
+	if math.Abs(m.MAPE-10) > 1e-9 {
+		t.Errorf("expected MAPE 10, got %v", m.MAPE)
+	}
+	wantRMSLE := math.Sqrt((math.Pow(math.Log1p(110)-math.Log1p(100), 2) +
+		math.Pow(math.Log1p(90)-math.Log1p(100), 2) +
+		math.Pow(math.Log1p(5), 2)) / 3)
+	if math.Abs(m.RMSLE-wantRMSLE) > 1e-9 {
+		t.Errorf("expected RMSLE %v, got %v", wantRMSLE, m.RMSLE)
+	}
+	// Coverage only counts points with intervals
+	if m.Coverage80 == nil || *m.Coverage80 != 0.5 {
+		t.Errorf("expected 80%% coverage 0.5, got %v", m.Coverage80)
+	}
+	if m.Coverage95 == nil || *m.Coverage95 != 1 {
+		t.Errorf("expected 95%% coverage 1, got %v", m.Coverage95)
+	}
+
+	if m := Evaluate([]Point{{Actual: 1, Prediction: 1}}); m.Coverage80 != nil {
+		t.Error("expected no coverage without intervals")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	windows := Windows(date("2017-08-01"), date("2017-08-04"), 2)
+	points := []Point{
+		{Fold: 0, StoreNbr: 2, Family: "DAIRY", Actual: 10, Prediction: 10},
+		{Fold: 0, StoreNbr: 1, Family: "DAIRY", Actual: 10, Prediction: 12},
+		{Fold: 0, StoreNbr: 1, Family: "DAIRY", Actual: 10, Prediction: 8},
+	}
+	res := Summarize(points, windows)
+
+	if res.Overall.Count != 3 {
+		t.Errorf("expected 3 points overall, got %d", res.Overall.Count)
+	}
+	if len(res.Folds) != 2 || res.Folds[0].Count != 3 || res.Folds[1].Count != 0 {
+		t.Errorf("unexpected folds: %+v", res.Folds)
+	}
+	if res.Folds[1].Origin != "2017-08-03" || res.Folds[1].End != "2017-08-04" {
+		t.Errorf("unexpected second fold range: %+v", res.Folds[1])
+	}
+	if len(res.Series) != 2 || res.Series[0].StoreNbr != 1 || res.Series[0].Count != 2 {
+		t.Errorf("expected series ordered by store with counts, got %+v", res.Series)
+	}
+	if math.Abs(res.Series[0].MAPE-20) > 1e-9 {
+		t.Errorf("expected store 1 MAPE 20, got %v", res.Series[0].MAPE)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(Config{CacheTTL: time.Hour, CacheMaxEntries: 2})
+	c.Set("a", &Result{})
+	c.Set("b", &Result{})
+	c.Set("c", &Result{})
+	if c.Len() != 2 {
+		t.Errorf("expected capacity to cap entries at 2, got %d", c.Len())
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("expected the newest entry to be cached")
+	}
+
+	c.Invalidate()
+	if c.Len() != 0 {
+		t.Errorf("expected empty cache after Invalidate, got %d", c.Len())
+	}
+
+	expired := NewCache(Config{CacheTTL: -time.Second, CacheMaxEntries: 2})
+	expired.Set("a", &Result{})
+	if _, ok := expired.Get("a"); ok {
+		t.Error("expected expired entry to miss")
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.MaxDays != 366 {
+		t.Errorf("expected MaxDays 366, got %d", cfg.MaxDays)
+	}
+	if cfg.CacheTTL != 6*time.Hour {
+		t.Errorf("expected a 6h TTL, got %v", cfg.CacheTTL)
+	}
+}
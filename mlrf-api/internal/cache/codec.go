@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Envelope versions: the first byte of a cached prediction. A new version is added
+// whenever the encoding of PredictionResult changes incompatibly; values of unknown
+// versions read as misses, so instances on either side of a rollout never serve each
+// other's entries wrongly.
+const (
+	envelopeProto       byte = 1 // Protobuf wire format
+	envelopeProtoSnappy byte = 2 // Snappy-compressed protobuf wire format
+
+	// Values cached as plain JSON before envelopes start with '{'
+	envelopeLegacyJSON byte = '{'
+)
+
+// Compression of cached predictions.
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+)
+
+// errUnknownEnvelope is returned when decoding a value of an unknown envelope version.
+var errUnknownEnvelope = errors.New("unknown cache envelope version")
+
+// Field numbers of PredictionResult in the protobuf wire format. Numbers are never
+// reused; new fields take new numbers and are skipped by older decoders.
+const (
+	fieldStoreNbr      protowire.Number = 1
+	fieldFamily        protowire.Number = 2
+	fieldDate          protowire.Number = 3
+	fieldHorizon       protowire.Number = 4
+	fieldPrediction    protowire.Number = 5
+	fieldCachedAt      protowire.Number = 6 // Unix nanoseconds
+	fieldExpiresAt     protowire.Number = 7 // Unix nanoseconds
+	fieldFeatureSource protowire.Number = 8
+	fieldNoData        protowire.Number = 9
+	fieldGuard         protowire.Number = 10
+)
+
+// encodePrediction encodes a prediction in a versioned envelope, compressed with snappy
+// when compress is set and it makes the value smaller.
+func encodePrediction(result *PredictionResult, compress bool) []byte {
+	b := []byte{envelopeProto}
+	b = appendVarintField(b, fieldStoreNbr, uint64(result.StoreNbr))
+	b = appendStringField(b, fieldFamily, result.Family)
+	b = appendStringField(b, fieldDate, result.Date)
+	b = appendVarintField(b, fieldHorizon, uint64(result.Horizon))
+	if result.Prediction != 0 {
+		b = protowire.AppendTag(b, fieldPrediction, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, math.Float32bits(result.Prediction))
+	}
+	b = appendVarintField(b, fieldCachedAt, unixNano(result.CachedAt))
+	b = appendVarintField(b, fieldExpiresAt, unixNano(result.ExpiresAt))
+	b = appendStringField(b, fieldFeatureSource, result.FeatureSource)
+	if result.NoData {
+		b = appendVarintField(b, fieldNoData, 1)
+	}
+	b = appendStringField(b, fieldGuard, result.Guard)
+
+	if compress {
+		if c := snappy.Encode(nil, b[1:]); len(c)+1 < len(b) {
+			return append([]byte{envelopeProtoSnappy}, c...)
+		}
+	}
+	return b
+}
+
+// decodePrediction decodes a prediction of any known envelope version, or cached as
+// plain JSON.
+func decodePrediction(data []byte) (*PredictionResult, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty cache value")
+	}
+	var result PredictionResult
+	switch data[0] {
+	case envelopeLegacyJSON:
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	case envelopeProto:
+		data = data[1:]
+	case envelopeProtoSnappy:
+		var err error
+		if data, err = snappy.Decode(nil, data[1:]); err != nil {
+			return nil, fmt.Errorf("snappy: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w %d", errUnknownEnvelope, data[0])
+	}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case fieldStoreNbr:
+				result.StoreNbr = int(v)
+			case fieldHorizon:
+				result.Horizon = int(v)
+			case fieldCachedAt:
+				result.CachedAt = fromUnixNano(v)
+			case fieldExpiresAt:
+				result.ExpiresAt = fromUnixNano(v)
+			case fieldNoData:
+				result.NoData = v != 0
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case fieldFamily:
+				result.Family = string(v)
+			case fieldDate:
+				result.Date = string(v)
+			case fieldFeatureSource:
+				result.FeatureSource = string(v)
+			case fieldGuard:
+				result.Guard = string(v)
+			}
+		case typ == protowire.Fixed32Type && num == fieldPrediction:
+			v, n := protowire.ConsumeFixed32(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			result.Prediction = math.Float32frombits(v)
+		default:
+			// A field added by a newer version
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return &result, nil
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// unixNano returns t in Unix nanoseconds, or 0 for the zero time.
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func fromUnixNano(v uint64) time.Time {
+	return time.Unix(0, int64(v))
+}
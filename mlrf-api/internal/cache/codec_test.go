@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPredictionCodec(t *testing.T) {
+	cachedAt := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	result := &PredictionResult{
+		StoreNbr:      44,
+		Family:        "GROCERY I",
+		Date:          "2017-08-01",
+		Horizon:       90,
+		Prediction:    1234.5,
+		CachedAt:      cachedAt,
+		ExpiresAt:     cachedAt.Add(time.Hour),
+		FeatureSource: "exact",
+		Guard:         "clamped",
+	}
+
+	for _, compress := range []bool{false, true} {
+		data := encodePrediction(result, compress)
+		if data[0] != envelopeProto {
+			// Small values are left uncompressed
+			t.Errorf("compress=%v: expected envelope %d, got %d", compress, envelopeProto, data[0])
+		}
+		got, err := decodePrediction(data)
+		if err != nil {
+			t.Fatalf("compress=%v: unexpected error: %v", compress, err)
+		}
+		if got.StoreNbr != 44 || got.Family != "GROCERY I" || got.Date != "2017-08-01" || got.Horizon != 90 ||
+			got.Prediction != 1234.5 || got.FeatureSource != "exact" || got.Guard != "clamped" || !got.CachedAt.Equal(cachedAt) || !got.ExpiresAt.Equal(result.ExpiresAt) {
+			t.Errorf("compress=%v: unexpected round trip %+v", compress, got)
+		}
+	}
+
+	jsonData, _ := json.Marshal(result)
+	if data := encodePrediction(result, false); len(data) >= len(jsonData)/2 {
+		t.Errorf("expected the encoding to be at most half of JSON's %d bytes, got %d", len(jsonData), len(data))
+	}
+
+	// Values that shrink are compressed
+	long := &PredictionResult{Family: strings.Repeat("GROCERY I ", 50), NoData: true}
+	data := encodePrediction(long, true)
+	if data[0] != envelopeProtoSnappy {
+		t.Fatalf("expected a compressed envelope, got %d", data[0])
+	}
+	if got, err := decodePrediction(data); err != nil || got.Family != long.Family || !got.NoData {
+		t.Errorf("unexpected compressed round trip %+v %v", got, err)
+	}
+}
+
+func TestPredictionCodecVersions(t *testing.T) {
+	// Values cached as JSON before envelopes still read
+	got, err := decodePrediction([]byte(`{"store_nbr":1,"family":"DAIRY","prediction":7}`))
+	if err != nil || got.StoreNbr != 1 || got.Family != "DAIRY" || got.Prediction != 7 {
+		t.Errorf("unexpected legacy JSON decode %+v %v", got, err)
+	}
+
+	// Unknown versions are rejected rather than misread
+	if _, err := decodePrediction([]byte{99, 1, 2, 3}); !errors.Is(err, errUnknownEnvelope) {
+		t.Errorf("expected an unknown envelope error, got %v", err)
+	}
+	if _, err := decodePrediction(nil); err == nil {
+		t.Error("expected an error for an empty value")
+	}
+
+	// Fields added by a newer encoder are skipped
+	data := encodePrediction(&PredictionResult{StoreNbr: 3}, false)
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+	data = appendVarintField(data, fieldHorizon, 30)
+	if got, err := decodePrediction(data); err != nil || got.StoreNbr != 3 || got.Horizon != 30 {
+		t.Errorf("unexpected decode with an unknown field %+v %v", got, err)
+	}
+
+	// Truncated values are errors
+	if _, err := decodePrediction(encodePrediction(&PredictionResult{Family: "DAIRY"}, false)[:4]); err == nil {
+		t.Error("expected an error for a truncated value")
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hierarchyKeyPrefix prefixes every hierarchy cache key, so entries can be invalidated together.
+const hierarchyKeyPrefix = "hier:v1:"
+
+// HierarchyConfig configures the hierarchy tree cache.
+type HierarchyConfig struct {
+	TTL        time.Duration // How long an assembled tree is served
+	MaxEntries int           // Maximum trees kept in process
+}
+
+// DefaultHierarchyConfig returns the default hierarchy cache configuration.
+func DefaultHierarchyConfig() HierarchyConfig {
+	return HierarchyConfig{
+		TTL:        time.Hour,
+		MaxEntries: 100,
+	}
+}
+
+// HierarchyCache caches assembled hierarchy trees as JSON, in process and, when a
+// RedisCache is given, in Redis so replicas share them. Safe for concurrent use.
+type HierarchyCache struct {
+	redis *RedisCache
+	cfg   HierarchyConfig
+
+	mu    sync.Mutex
+	local map[string]hierarchyEntry
+}
+
+type hierarchyEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewHierarchyCache creates a hierarchy cache. r may be nil for an in-process cache only.
+func NewHierarchyCache(r *RedisCache, cfg HierarchyConfig) *HierarchyCache {
+	return &HierarchyCache{
+		redis: r,
+		cfg:   cfg,
+		local: make(map[string]hierarchyEntry),
+	}
+}
+
+// HierarchyCacheKey creates the cache key for a tree assembled for a date,
+// reconciliation method ("" for none) and horizon.
+func HierarchyCacheKey(date, method string, horizon int) string {
+	return fmt.Sprintf("%s%s:%s:%d", hierarchyKeyPrefix, date, method, horizon)
+}
+
+// Get returns a cached tree. Checks the local cache first, then Redis.
+func (c *HierarchyCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	entry, ok := c.local[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(c.local, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.data, true
+	}
+
+	if c.redis == nil {
+		return nil, false
+	}
+	data, err := c.redis.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	c.setLocal(key, data)
+	return data, true
+}
+
+// Set stores a tree locally and in Redis.
+func (c *HierarchyCache) Set(ctx context.Context, key string, data []byte) error {
+	c.setLocal(key, data)
+	if c.redis == nil {
+		return nil
+	}
+	if err := c.redis.client.Set(ctx, key, data, c.cfg.TTL).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+// Invalidate drops every cached tree, locally and in Redis. Called when the feature
+// store, model or hierarchy definition is reloaded.
+func (c *HierarchyCache) Invalidate(ctx context.Context) error {
+	c.mu.Lock()
+	c.local = make(map[string]hierarchyEntry)
+	c.mu.Unlock()
+
+	if c.redis == nil {
+		return nil
+	}
+
+	var keys []string
+	iter := c.redis.client.Scan(ctx, 0, hierarchyKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("redis scan failed: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := c.redis.client.Del(ctx, keys...).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("redis del failed: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of trees cached in process.
+func (c *HierarchyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.local)
+}
+
+// setLocal stores a tree in process, evicting expired entries and then an
+// arbitrary entry when at capacity.
+func (c *HierarchyCache) setLocal(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.local) >= c.cfg.MaxEntries {
+		now := time.Now()
+		for k, e := range c.local {
+			if now.After(e.expiresAt) {
+				delete(c.local, k)
+			}
+		}
+		for k := range c.local {
+			if len(c.local) < c.cfg.MaxEntries {
+				break
+			}
+			delete(c.local, k)
+		}
+	}
+
+	c.local[key] = hierarchyEntry{data: data, expiresAt: time.Now().Add(c.cfg.TTL)}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHierarchyCacheKey(t *testing.T) {
+	if key := HierarchyCacheKey("2017-08-01", "mint", 30); key != "hier:v1:2017-08-01:mint:30" {
+		t.Errorf("unexpected key %q", key)
+	}
+	if HierarchyCacheKey("2017-08-01", "", 30) == HierarchyCacheKey("2017-08-02", "", 30) {
+		t.Error("keys must differ by date")
+	}
+}
+
+func TestHierarchyCacheLocal(t *testing.T) {
+	ctx := context.Background()
+	c := NewHierarchyCache(nil, HierarchyConfig{TTL: time.Hour, MaxEntries: 2})
+
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	if err := c.Set(ctx, "a", []byte("tree")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get(ctx, "a"); !ok || string(data) != "tree" {
+		t.Errorf("expected hit, got %q %v", data, ok)
+	}
+
+	// Capacity is enforced
+	c.Set(ctx, "b", []byte("b"))
+	c.Set(ctx, "c", []byte("c"))
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries at capacity, got %d", c.Len())
+	}
+
+	if err := c.Invalidate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected empty cache after invalidate, got %d", c.Len())
+	}
+}
+
+func TestHierarchyCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewHierarchyCache(nil, HierarchyConfig{TTL: time.Millisecond, MaxEntries: 10})
+
+	c.Set(ctx, "a", []byte("tree"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("expected expired entry to miss")
+	}
+}
+
+func TestDefaultHierarchyConfig(t *testing.T) {
+	cfg := DefaultHierarchyConfig()
+	if cfg.TTL != time.Hour || cfg.MaxEntries != 100 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
@@ -1,13 +1,20 @@
-// Package cache provides Redis caching with TinyLFU local cache layer.
+// Package cache provides Redis caching with a TinyLFU local cache layer.
 package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -19,34 +26,55 @@ type PredictionResult struct {
 	Horizon    int       `json:"horizon"`
 	Prediction float32   `json:"prediction"`
 	CachedAt   time.Time `json:"cached_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// NoData records that the series has no features, without a prediction, so strict
+	// requests are rejected without looking the features up again. Only stored under
+	// GenerateNoDataKey
+	NoData bool `json:"no_data,omitempty"`
+
+	// FeatureSource is how the features were looked up (exact, aggregated, zeros or
+	// rolled_forward); empty for predictions on client-supplied features
+	FeatureSource string `json:"feature_source,omitempty"`
+
+	// Guard is the action the prediction guard took on the model output (clamped or
+	// zeroed); empty if the model's prediction was served as is
+	Guard string `json:"guard,omitempty"`
 }
 
 // RedisCache wraps Redis client with local caching.
 type RedisCache struct {
 	client     *redis.Client
-	localCache map[string]*cacheEntry
-	maxLocal   int
-	ttl        time.Duration
-}
-
-type cacheEntry struct {
-	result    *PredictionResult
-	expiresAt time.Time
+	local      *LocalCache
+	namespace  atomic.Pointer[string]    // Model and feature versions predictions are cached under
+	policy     atomic.Pointer[TTLPolicy] // TTL of predictions; changed by SetTTL and SetTTLPolicy
+	explainTTL time.Duration
+	compress   bool // Snappy-compress cached predictions
 }
 
 // Config holds Redis connection configuration.
 type Config struct {
-	URL      string
-	MaxLocal int           // Maximum local cache entries (TinyLFU-like behavior)
-	TTL      time.Duration // Cache TTL
+	URL           string
+	MaxLocalBytes int64                    // Maximum size of the in-process TinyLFU cache in front of Redis
+	TTL           time.Duration            // Cache TTL
+	NegativeTTL   time.Duration            // Cache TTL of "no data" predictions
+	TTLRules      map[string]time.Duration // Cache TTL by endpoint and horizon, overriding TTL
+	TTLJitter     float64                  // Fraction of TTLs shortened at random
+	EarlyExpiry   float64                  // Probabilistic early expiration window, as a fraction of TTLs
+	ExplainTTL    time.Duration            // Cache TTL of SHAP explanations
+	Compression   string                   // Compression of cached predictions: none or snappy
 }
 
 // DefaultConfig returns sensible defaults for cache configuration.
 func DefaultConfig() Config {
 	return Config{
-		URL:      "redis://localhost:6379",
-		MaxLocal: 10000,
-		TTL:      time.Hour,
+		URL:           "redis://localhost:6379",
+		MaxLocalBytes: 16 << 20,
+		TTL:           time.Hour,
+		NegativeTTL:   DefaultNegativeTTL,
+		TTLJitter:     0.1,
+		EarlyExpiry:   0.05,
+		ExplainTTL:    24 * time.Hour,
 	}
 }
 
@@ -69,31 +97,152 @@ func NewRedisCache(cfg Config) (*RedisCache, error) {
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("redis connection failed: %w", err)
 	}
+	return NewRedisCacheWithClient(client, cfg), nil
+}
 
-	return &RedisCache{
+// NewRedisCacheWithClient creates a cache over an existing Redis client, without
+// checking the connection. cfg.URL is ignored.
+func NewRedisCacheWithClient(client *redis.Client, cfg Config) *RedisCache {
+	rc := &RedisCache{
 		client:     client,
-		localCache: make(map[string]*cacheEntry),
-		maxLocal:   cfg.MaxLocal,
-		ttl:        cfg.TTL,
-	}, nil
+		explainTTL: cfg.ExplainTTL,
+		compress:   cfg.Compression == CompressionSnappy,
+	}
+	if cfg.MaxLocalBytes <= 0 {
+		cfg.MaxLocalBytes = DefaultConfig().MaxLocalBytes
+	}
+	rc.local = NewLocalCache(cfg.MaxLocalBytes)
+	if rc.explainTTL <= 0 {
+		rc.explainTTL = DefaultConfig().ExplainTTL
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = DefaultConfig().NegativeTTL
+	}
+	rc.SetTTLPolicy(TTLPolicy{
+		Default:     cfg.TTL,
+		Negative:    cfg.NegativeTTL,
+		Rules:       cfg.TTLRules,
+		Jitter:      cfg.TTLJitter,
+		EarlyExpiry: cfg.EarlyExpiry,
+	})
+	return rc
+}
+
+// SetTTL changes the default TTL of predictions cached from now on, keeping the
+// negative TTL and rules.
+func (r *RedisCache) SetTTL(ttl time.Duration) {
+	p := r.TTLPolicy()
+	p.Default = ttl
+	r.SetTTLPolicy(p)
+}
+
+// SetTTLPolicy changes the TTLs of predictions cached from now on.
+func (r *RedisCache) SetTTLPolicy(p TTLPolicy) {
+	if p.Negative <= 0 {
+		p.Negative = DefaultNegativeTTL
+	}
+	r.policy.Store(&p)
+}
+
+// TTLPolicy returns the TTLs of newly cached predictions.
+func (r *RedisCache) TTLPolicy() TTLPolicy {
+	if p := r.policy.Load(); p != nil {
+		return *p
+	}
+	return TTLPolicy{Negative: DefaultNegativeTTL}
 }
 
+// TTL returns the default TTL of newly cached predictions.
+func (r *RedisCache) TTL() time.Duration {
+	return r.TTLPolicy().Default
+}
+
+// Key prefixes of the entries removed by Flush.
+const (
+	predictionKeyPrefix  = "pred:"
+	explanationKeyPrefix = "explain:"
+	quotaKeyPrefix       = "quota:v1:"
+)
+
 // GenerateCacheKey creates a deterministic cache key for predictions.
 func GenerateCacheKey(storeNbr int, family string, date string, horizon int) string {
 	return fmt.Sprintf("pred:v1:%d:%s:%s:%d", storeNbr, family, date, horizon)
 }
 
+// GenerateNoDataKey creates the cache key recording that a series has no features.
+// It is kept apart from the prediction key so readers of predictions never mistake
+// it for a forecast of zero.
+func GenerateNoDataKey(storeNbr int, family string, date string, horizon int) string {
+	return fmt.Sprintf("pred:nodata:v1:%d:%s:%s:%d", storeNbr, family, date, horizon)
+}
+
+// GenerateModelCacheKey creates a cache key scoped to a specific registered model.
+// Used when a request explicitly selects a non-default model.
+func GenerateModelCacheKey(model string, storeNbr int, family string, date string, horizon int) string {
+	return fmt.Sprintf("pred:v1:%s:%d:%s:%s:%d", model, storeNbr, family, date, horizon)
+}
+
+// GenerateExplainCacheKey creates a cache key for the SHAP explanation of a feature
+// vector. SHAP values are deterministic for a model and its input, so the key hashes
+// only the model version and the features.
+func GenerateExplainCacheKey(modelVersion string, features []float32) string {
+	sum := sha256.New()
+	sum.Write([]byte(modelVersion))
+	sum.Write([]byte{0})
+	binary.Write(sum, binary.LittleEndian, features)
+	return "explain:v1:" + hex.EncodeToString(sum.Sum(nil))
+}
+
+// SetNamespace scopes prediction keys to a model and feature version, so predictions
+// cached before a model or feature reload are no longer served. Predictions cached in
+// process under the previous namespace are dropped. Returns the namespace.
+func (r *RedisCache) SetNamespace(modelVersion, featureVersion string) string {
+	sum := sha256.Sum256([]byte(modelVersion + "\x00" + featureVersion))
+	ns := hex.EncodeToString(sum[:6])
+	if old := r.namespace.Swap(&ns); old == nil || *old != ns {
+		r.local.Clear()
+	}
+	return ns
+}
+
+// Namespace returns the namespace of prediction keys, empty until SetNamespace.
+func (r *RedisCache) Namespace() string {
+	if ns := r.namespace.Load(); ns != nil {
+		return *ns
+	}
+	return ""
+}
+
+// namespaced returns a prediction key in the current namespace: "pred:v1:..." becomes
+// "pred:<namespace>:v1:...".
+func (r *RedisCache) namespaced(key string) string {
+	ns := r.Namespace()
+	if ns == "" || !strings.HasPrefix(key, predictionKeyPrefix) {
+		return key
+	}
+	return predictionKeyPrefix + ns + ":" + key[len(predictionKeyPrefix):]
+}
+
 // GetPrediction retrieves a cached prediction.
 // Checks local cache first, then Redis.
 func (r *RedisCache) GetPrediction(ctx context.Context, key string) (*PredictionResult, error) {
+	ctx, span := tracing.Start(ctx, "cache.get")
+	defer span.End()
+	result, err := r.getPrediction(ctx, key)
+	span.SetAttributes(tracing.AttrCacheHit.Bool(err == nil))
+	return result, err
+}
+
+func (r *RedisCache) getPrediction(ctx context.Context, key string) (*PredictionResult, error) {
+	key = r.namespaced(key)
+
 	// Check local cache first
-	if entry, ok := r.localCache[key]; ok {
-		if time.Now().Before(entry.expiresAt) {
-			metrics.RecordCacheHit()
-			return entry.result, nil
+	if result, ok := r.local.Get(key); ok {
+		if r.expiresEarly(result) {
+			return nil, fmt.Errorf("cache miss")
 		}
-		// Expired, remove from local cache
-		delete(r.localCache, key)
+		metrics.RecordCacheHit()
+		return result, nil
 	}
 
 	// Check Redis
@@ -106,64 +255,243 @@ func (r *RedisCache) GetPrediction(ctx context.Context, key string) (*Prediction
 		return nil, fmt.Errorf("redis get failed: %w", err)
 	}
 
+	result, err := decodePrediction(data)
+	if err != nil {
+		// Written by an incompatible version: a miss rather than a wrong prediction
+		metrics.RecordCacheMiss()
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	// Store in local cache
+	r.setLocal(key, result)
+	if r.expiresEarly(result) {
+		return nil, fmt.Errorf("cache miss")
+	}
+
 	// Redis hit (but local miss)
 	metrics.RecordCacheHit()
 
-	var result PredictionResult
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("unmarshal failed: %w", err)
+	return result, nil
+}
+
+// GetPredictions retrieves multiple cached predictions in one round trip.
+// Checks local cache first, then fetches the remaining keys with a single Redis MGET.
+// Returns the hits keyed by cache key; missing keys are absent from the map.
+// On a Redis error the local hits are still returned alongside the error.
+func (r *RedisCache) GetPredictions(ctx context.Context, keys []string) (map[string]*PredictionResult, error) {
+	ctx, span := tracing.Start(ctx, "cache.get_batch", tracing.AttrBatchSize.Int(len(keys)))
+	hits, err := r.getPredictions(ctx, keys)
+	span.SetAttributes(tracing.AttrCacheHits.Int(len(hits)))
+	tracing.End(span, err)
+	return hits, err
+}
+
+func (r *RedisCache) getPredictions(ctx context.Context, keys []string) (map[string]*PredictionResult, error) {
+	hits := make(map[string]*PredictionResult, len(keys))
+	var remote, remoteKeys []string
+
+	for _, key := range keys {
+		nsKey := r.namespaced(key)
+		if result, ok := r.local.Get(nsKey); ok {
+			if !r.expiresEarly(result) {
+				metrics.RecordCacheHit()
+				hits[key] = result
+			}
+			continue
+		}
+		remote = append(remote, nsKey)
+		remoteKeys = append(remoteKeys, key)
 	}
 
-	// Store in local cache
-	r.setLocal(key, &result)
+	if len(remote) == 0 {
+		return hits, nil
+	}
+
+	values, err := r.client.MGet(ctx, remote...).Result()
+	if err != nil {
+		return hits, fmt.Errorf("redis mget failed: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			metrics.RecordCacheMiss()
+			continue
+		}
 
-	return &result, nil
+		result, err := decodePrediction([]byte(data))
+		if err != nil {
+			metrics.RecordCacheMiss()
+			continue
+		}
+
+		r.setLocal(remote[i], result)
+		if r.expiresEarly(result) {
+			continue
+		}
+		metrics.RecordCacheHit()
+		hits[remoteKeys[i]] = result
+	}
+
+	return hits, nil
 }
 
-// SetPrediction stores a prediction in both local and Redis cache.
-func (r *RedisCache) SetPrediction(ctx context.Context, key string, result *PredictionResult) error {
+// SetPrediction stores a prediction computed by endpoint in both local and Redis
+// cache, for the TTL the policy gives it.
+func (r *RedisCache) SetPrediction(ctx context.Context, endpoint, key string, result *PredictionResult) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.set")
+	defer func() { tracing.End(span, err) }()
+
+	policy := r.TTLPolicy()
+	ttl := policy.jittered(policy.For(endpoint, result), rand.Float64())
 	result.CachedAt = time.Now()
+	result.ExpiresAt = result.CachedAt.Add(ttl)
+	key = r.namespaced(key)
 
 	// Store in local cache
 	r.setLocal(key, result)
 
 	// Store in Redis
-	data, err := json.Marshal(result)
+	data := encodePrediction(result, r.compress)
+	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+
+	return nil
+}
+
+// SetPredictions stores multiple predictions computed by endpoint in local and Redis
+// cache using one pipelined round trip.
+func (r *RedisCache) SetPredictions(ctx context.Context, endpoint string, results map[string]*PredictionResult) (err error) {
+	if len(results) == 0 {
+		return nil
+	}
+	ctx, span := tracing.Start(ctx, "cache.set_batch", tracing.AttrBatchSize.Int(len(results)))
+	defer func() { tracing.End(span, err) }()
+
+	policy := r.TTLPolicy()
+	now := time.Now()
+	pipe := r.client.Pipeline()
+	for key, result := range results {
+		ttl := policy.jittered(policy.For(endpoint, result), rand.Float64())
+		result.CachedAt = now
+		result.ExpiresAt = now.Add(ttl)
+		key = r.namespaced(key)
+		r.setLocal(key, result)
+
+		pipe.Set(ctx, key, encodePrediction(result, r.compress), ttl)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis pipeline set failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetExplanation retrieves a cached SHAP explanation into v.
+// Explanations are only cached in Redis: they are large and rarely requested twice
+// from the same instance.
+func (r *RedisCache) GetExplanation(ctx context.Context, key string, v interface{}) error {
+	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
-		return fmt.Errorf("marshal failed: %w", err)
+		if err == redis.Nil {
+			return fmt.Errorf("cache miss")
+		}
+		return fmt.Errorf("redis get failed: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal failed: %w", err)
 	}
+	return nil
+}
 
-	if err := r.client.Set(ctx, key, data, r.ttl).Err(); err != nil {
+// SetExplanation stores a SHAP explanation in Redis for the explanation TTL.
+func (r *RedisCache) SetExplanation(ctx context.Context, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal failed: %w", err)
+	}
+	if err := r.client.Set(ctx, key, data, r.explainTTL).Err(); err != nil {
 		return fmt.Errorf("redis set failed: %w", err)
 	}
-
 	return nil
 }
 
-// setLocal stores an entry in the local cache with simple eviction.
+// expiresEarly reports whether a hit is served as a miss to refresh the entry before
+// it expires, recording the miss. See TTLPolicy.EarlyExpiry.
+func (r *RedisCache) expiresEarly(result *PredictionResult) bool {
+	policy := r.TTLPolicy()
+	if !policy.expiresEarly(result, time.Now(), 1-rand.Float64()) {
+		return false
+	}
+	metrics.RecordCacheMiss()
+	metrics.RecordCacheEarlyExpiration()
+	return true
+}
+
+// setLocal stores an entry in the local cache until it expires in Redis, if it is
+// admitted. Entries cached without an expiry are kept for the default TTL.
 func (r *RedisCache) setLocal(key string, result *PredictionResult) {
-	// Simple eviction: if at capacity, remove oldest entries
-	if len(r.localCache) >= r.maxLocal {
-		// Remove ~10% of entries (oldest by cached_at)
-		var oldest []string
-		cutoff := time.Now().Add(-r.ttl / 2)
-		for k, v := range r.localCache {
-			if v.result.CachedAt.Before(cutoff) {
-				oldest = append(oldest, k)
-			}
-			if len(oldest) >= r.maxLocal/10 {
-				break
+	ttl := r.TTL()
+	if !result.ExpiresAt.IsZero() {
+		ttl = time.Until(result.ExpiresAt)
+	}
+	if ttl > 0 {
+		r.local.Set(key, result, ttl)
+	}
+}
+
+// Flush removes every cached prediction and SHAP explanation, in process and in Redis
+// whatever their namespace, and returns the number of Redis keys deleted.
+func (r *RedisCache) Flush(ctx context.Context) (int, error) {
+	r.local.Clear()
+
+	deleted := 0
+	for _, prefix := range []string{predictionKeyPrefix, explanationKeyPrefix} {
+		iter := r.client.Scan(ctx, 0, prefix+"*", 1000).Iterator()
+		var keys []string
+		for iter.Next(ctx) {
+			if keys = append(keys, iter.Val()); len(keys) == 1000 {
+				n, err := r.client.Del(ctx, keys...).Result()
+				deleted += int(n)
+				if err != nil {
+					return deleted, fmt.Errorf("redis del failed: %w", err)
+				}
+				keys = keys[:0]
 			}
 		}
-		for _, k := range oldest {
-			delete(r.localCache, k)
+		if err := iter.Err(); err != nil {
+			return deleted, fmt.Errorf("redis scan failed: %w", err)
+		}
+		if len(keys) > 0 {
+			n, err := r.client.Del(ctx, keys...).Result()
+			deleted += int(n)
+			if err != nil {
+				return deleted, fmt.Errorf("redis del failed: %w", err)
+			}
 		}
 	}
+	return deleted, nil
+}
 
-	r.localCache[key] = &cacheEntry{
-		result:    result,
-		expiresAt: time.Now().Add(r.ttl),
+// IncrQuota counts a request of an API key in the month starting at month and returns
+// the requests counted in it so far, across every replica sharing Redis. Counts expire
+// a day after the month ends and survive Flush.
+func (r *RedisCache) IncrQuota(ctx context.Context, name string, month time.Time) (int64, error) {
+	key := quotaKeyPrefix + name + ":" + month.Format("2006-01")
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireAt(ctx, key, month.AddDate(0, 1, 1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis quota count failed: %w", err)
 	}
+	return incr.Val(), nil
+}
+
+// Ping checks that Redis is reachable.
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
 // Close closes the Redis connection.
@@ -173,9 +501,27 @@ func (r *RedisCache) Close() error {
 
 // Stats returns cache statistics.
 func (r *RedisCache) Stats() map[string]interface{} {
+	local := r.local.Stats()
+	policy := r.TTLPolicy()
+	rules := make(map[string]float64, len(policy.Rules))
+	for key, ttl := range policy.Rules {
+		rules[key] = ttl.Seconds()
+	}
 	return map[string]interface{}{
-		"local_entries": len(r.localCache),
-		"max_local":     r.maxLocal,
-		"ttl_seconds":   r.ttl.Seconds(),
+		"local_entries":        local.Entries,
+		"local_bytes":          local.Cost,
+		"max_local_bytes":      local.MaxCost,
+		"local_hits":           local.Hits,
+		"local_misses":         local.Misses,
+		"local_hit_ratio":      local.HitRatio,
+		"local_rejected":       local.Rejected,
+		"local_evicted":        local.Evicted,
+		"namespace":            r.Namespace(),
+		"ttl_seconds":          policy.Default.Seconds(),
+		"negative_ttl_seconds": policy.Negative.Seconds(),
+		"ttl_rules_seconds":    rules,
+		"ttl_jitter":           policy.Jitter,
+		"early_expiry":         policy.EarlyExpiry,
+		"explain_ttl_seconds":  r.explainTTL.Seconds(),
 	}
 }
@@ -1,7 +1,12 @@
 package cache
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func TestGenerateCacheKey(t *testing.T) {
@@ -26,6 +31,33 @@ func TestGenerateCacheKey(t *testing.T) {
 	}
 }
 
+func TestGenerateModelCacheKey(t *testing.T) {
+	key := GenerateModelCacheKey("challenger@2", 1, "GROCERY I", "2017-08-01", 90)
+	if key != "pred:v1:challenger@2:1:GROCERY I:2017-08-01:90" {
+		t.Errorf("unexpected key %q", key)
+	}
+	if key == GenerateCacheKey(1, "GROCERY I", "2017-08-01", 90) {
+		t.Error("model-scoped key must differ from default key")
+	}
+}
+
+func TestGenerateExplainCacheKey(t *testing.T) {
+	features := []float32{1, 2.5, 0}
+	key := GenerateExplainCacheKey("v3", features)
+	if !strings.HasPrefix(key, "explain:v1:") {
+		t.Errorf("unexpected key %q", key)
+	}
+	if key != GenerateExplainCacheKey("v3", []float32{1, 2.5, 0}) {
+		t.Error("expected the same key for the same model and features")
+	}
+	if key == GenerateExplainCacheKey("v4", features) {
+		t.Error("expected a new model version to change the key")
+	}
+	if key == GenerateExplainCacheKey("v3", []float32{1, 2.5, 0.001}) {
+		t.Error("expected different features to change the key")
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -33,11 +65,99 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("expected default URL")
 	}
 
-	if cfg.MaxLocal <= 0 {
-		t.Error("expected positive MaxLocal")
+	if cfg.MaxLocalBytes <= 0 {
+		t.Error("expected positive MaxLocalBytes")
 	}
 
 	if cfg.TTL <= 0 {
 		t.Error("expected positive TTL")
 	}
+
+	if cfg.ExplainTTL <= 0 {
+		t.Error("expected positive ExplainTTL")
+	}
+}
+
+func TestGetPredictionsLocalHits(t *testing.T) {
+	// Unreachable Redis: local hits must still be returned alongside the MGET error
+	r := &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		local:  NewLocalCache(1 << 20),
+	}
+	r.SetTTL(time.Hour)
+	defer r.Close()
+
+	r.setLocal("a", &PredictionResult{Prediction: 1, CachedAt: time.Now()})
+	r.local.Set("expired", &PredictionResult{Prediction: 2}, -time.Minute)
+
+	hits, err := r.GetPredictions(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatalf("expected no Redis call when all keys hit locally, got %v", err)
+	}
+	if len(hits) != 1 || hits["a"].Prediction != 1 {
+		t.Errorf("unexpected hits: %v", hits)
+	}
+
+	hits, err = r.GetPredictions(context.Background(), []string{"a", "expired", "b"})
+	if err == nil {
+		t.Error("expected error from unreachable Redis")
+	}
+	if len(hits) != 1 || hits["a"] == nil {
+		t.Errorf("expected local hit to survive Redis error, got %v", hits)
+	}
+	if r.local.Len() != 1 {
+		t.Error("expected expired local entry to be evicted")
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	r := &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		local:  NewLocalCache(1 << 20),
+	}
+	r.SetTTL(time.Hour)
+	defer r.Close()
+
+	key := GenerateCacheKey(1, "GROCERY I", "2017-08-01", 30)
+	if r.namespaced(key) != key {
+		t.Error("expected keys to be unchanged without a namespace")
+	}
+
+	ns := r.SetNamespace("1700000000", "1690000000+0")
+	if want := "pred:" + ns + ":v1:1:GROCERY I:2017-08-01:30"; r.namespaced(key) != want {
+		t.Errorf("expected %q, got %q", want, r.namespaced(key))
+	}
+	if r.namespaced("explain:v1:abc") != "explain:v1:abc" {
+		t.Error("expected explanation keys to be unchanged")
+	}
+
+	r.SetPrediction(context.Background(), EndpointSimple, key, &PredictionResult{Prediction: 1})
+	if hits, _ := r.GetPredictions(context.Background(), []string{key}); hits[key] == nil {
+		t.Fatal("expected a local hit under the caller's key")
+	}
+
+	// The same versions keep the namespace and its entries
+	if r.SetNamespace("1700000000", "1690000000+0") != ns || r.local.Len() != 1 {
+		t.Error("expected the same versions to keep the namespace")
+	}
+
+	// A reload moves to a new namespace, dropping local entries
+	if r.SetNamespace("1700000000", "1690000000+1") == ns {
+		t.Error("expected a new feature version to change the namespace")
+	}
+	if r.local.Len() != 0 {
+		t.Error("expected local entries of the old namespace to be dropped")
+	}
+}
+
+func TestIncrQuotaUnreachable(t *testing.T) {
+	r := &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		local:  NewLocalCache(1 << 20),
+	}
+	defer r.Close()
+
+	if _, err := r.IncrQuota(context.Background(), "trial", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("expected error from unreachable Redis")
+	}
 }
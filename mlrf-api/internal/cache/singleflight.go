@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errFlightPanicked is returned to callers sharing a call whose function panicked.
+var errFlightPanicked = errors.New("shared call panicked")
+
+// Group de-duplicates concurrent calls with the same key, in the manner of
+// golang.org/x/sync/singleflight: while a call for a key is in flight, further calls
+// for the key wait for it and share its result instead of running their own. The zero
+// value is ready to use.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flight[T]
+}
+
+// flight is a call in flight or completed.
+type flight[T any] struct {
+	done  chan struct{}
+	dups  int // Callers waiting on the call
+	value T
+	err   error
+}
+
+// Do runs fn for key unless a call for key is already in flight, in which case it
+// waits for that call and returns its result. shared reports whether the result came
+// from another caller's call. A waiting caller gives up with ctx.Err() once ctx is
+// done, leaving the call running for the others; fn itself isn't passed ctx, so a
+// call shared between requests should run under a context of its own.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func() (T, error)) (value T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight[T])
+	}
+	if f, ok := g.calls[key]; ok {
+		f.dups++
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, f.err, true
+		case <-ctx.Done():
+			g.mu.Lock()
+			f.dups--
+			g.mu.Unlock()
+			return value, ctx.Err(), true
+		}
+	}
+	f := &flight[T]{done: make(chan struct{}), err: errFlightPanicked}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = fn()
+	return f.value, f.err, false
+}
+
+// Waiters returns the number of callers waiting on the call in flight for key.
+func (g *Group[T]) Waiters(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.calls[key]; ok {
+		return f.dups
+	}
+	return 0
+}
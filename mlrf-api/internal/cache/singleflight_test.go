@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until n callers are waiting on the call in flight for key.
+func waitForWaiters[T any](t *testing.T, g *Group[T], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Waiters(key) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, g.Waiters(key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var g Group[int]
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	const n = 10
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	do := func() {
+		defer wg.Done()
+		v, err, shared := g.Do(context.Background(), "a", func() (int, error) {
+			calls.Add(1)
+			close(started)
+			<-release
+			return 42, nil
+		})
+		if v != 42 || err != nil {
+			t.Errorf("expected 42, got %d %v", v, err)
+		}
+		if shared {
+			sharedCount.Add(1)
+		}
+	}
+	wg.Add(1)
+	go do()
+	<-started
+	for i := 1; i < n; i++ {
+		wg.Add(1)
+		go do()
+	}
+	waitForWaiters(t, &g, "a", n-1)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 || sharedCount.Load() != n-1 {
+		t.Errorf("expected one call shared by %d callers, got %d calls and %d shared", n-1, calls.Load(), sharedCount.Load())
+	}
+
+	// Completed calls are not remembered, and errors are returned
+	boom := errors.New("boom")
+	if _, err, shared := g.Do(context.Background(), "a", func() (int, error) { return 0, boom }); err != boom || shared {
+		t.Errorf("expected a new call failing, got %v shared=%v", err, shared)
+	}
+}
+
+func TestGroupWaiterContext(t *testing.T) {
+	var g Group[int]
+	started, release := make(chan struct{}), make(chan struct{})
+	leader := make(chan int)
+	go func() {
+		v, _, _ := g.Do(context.Background(), "a", func() (int, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		leader <- v
+	}()
+	<-started
+
+	// A waiter whose context ends gives up without cancelling the call
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err, _ := g.Do(ctx, "a", func() (int, error) { return 1, nil })
+		done <- err
+	}()
+	waitForWaiters(t, &g, "a", 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if n := g.Waiters("a"); n != 0 {
+		t.Errorf("expected the waiter to leave, got %d waiters", n)
+	}
+
+	close(release)
+	if v := <-leader; v != 42 {
+		t.Errorf("expected the call to complete with 42, got %d", v)
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	var g Group[int]
+	started, done := make(chan struct{}), make(chan error)
+	go func() {
+		defer func() { recover() }()
+		g.Do(context.Background(), "a", func() (int, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			panic("boom")
+		})
+	}()
+	<-started
+	go func() {
+		_, err, _ := g.Do(context.Background(), "a", func() (int, error) { return 1, nil })
+		done <- err
+	}()
+
+	// A caller sharing a panicking call gets an error rather than blocking forever
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error, got none")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("shared call never returned")
+	}
+}
@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// evictionSamples is the number of entries sampled for the least frequently used
+// victim when the local cache is full, as in ristretto.
+const evictionSamples = 5
+
+// LocalCache is an in-process prediction cache bounded by cost, in the manner of
+// ristretto: a count-min sketch estimates how often each key is accessed (TinyLFU), a
+// new entry only displaces entries it is accessed more often than, and the victim is
+// the least frequently used of a random sample. Safe for concurrent use.
+type LocalCache struct {
+	mu      sync.Mutex
+	items   map[string]*localItem
+	keys    []string // Keys in items, for sampling eviction candidates
+	sketch  *cmSketch
+	cost    int64
+	maxCost int64
+	rng     *rand.Rand
+	now     func() time.Time
+
+	hits, misses, rejected, evicted uint64
+}
+
+type localItem struct {
+	result    *PredictionResult
+	cost      int64
+	expiresAt time.Time
+	index     int // Position in LocalCache.keys
+}
+
+// LocalCacheStats are the counters of a LocalCache.
+type LocalCacheStats struct {
+	Entries  int     `json:"entries"`
+	Cost     int64   `json:"cost"`
+	MaxCost  int64   `json:"max_cost"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+	Rejected uint64  `json:"rejected"` // New entries not admitted over more frequently used ones
+	Evicted  uint64  `json:"evicted"`  // Entries evicted to make room
+}
+
+// NewLocalCache creates a local cache holding entries costing up to maxCost in total.
+func NewLocalCache(maxCost int64) *LocalCache {
+	// Track the frequency of about ten times the keys expected to fit
+	counters := maxCost / predictionCost(&PredictionResult{}, "pred:v1:0:GROCERY I:2017-08-01:1") * 10
+	return &LocalCache{
+		items:   make(map[string]*localItem),
+		sketch:  newCMSketch(counters),
+		maxCost: maxCost,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:     time.Now,
+	}
+}
+
+// predictionCost approximates the memory used by a cached prediction, in bytes.
+func predictionCost(result *PredictionResult, key string) int64 {
+	const overhead = 160 // Entry, item and map bucket
+	return int64(overhead + len(key) + len(result.Family) + len(result.Date) + len(result.FeatureSource))
+}
+
+// Get returns an unexpired entry, recording the access.
+func (c *LocalCache) Get(key string) (*PredictionResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sketch.increment(key)
+	item, ok := c.items[key]
+	if ok && !c.now().Before(item.expiresAt) {
+		c.remove(key, item)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return item.result, true
+}
+
+// Set stores an entry for ttl, evicting less frequently used entries to make room.
+// It returns false when the entry is not admitted: it costs more than the whole cache,
+// or it is accessed less often than the entries it would displace.
+func (c *LocalCache) Set(key string, result *PredictionResult, ttl time.Duration) bool {
+	cost := predictionCost(result, key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sketch.increment(key)
+	expiresAt := c.now().Add(ttl)
+	if item, ok := c.items[key]; ok {
+		c.cost += cost - item.cost
+		item.result, item.cost, item.expiresAt = result, cost, expiresAt
+		c.evict(key, 0, true)
+		return true
+	}
+
+	if cost > c.maxCost || !c.evict(key, cost, false) {
+		c.rejected++
+		return false
+	}
+	c.items[key] = &localItem{result: result, cost: cost, expiresAt: expiresAt, index: len(c.keys)}
+	c.keys = append(c.keys, key)
+	c.cost += cost
+	return true
+}
+
+// evict removes entries until cost more fits, sampling a victim among entries other
+// than key each time. Expired victims are always removed; others only when key is
+// accessed at least as often, or force is set for an updated key. Returns false if key
+// is not admitted. Callers hold c.mu.
+func (c *LocalCache) evict(key string, cost int64, force bool) bool {
+	now := c.now()
+	incoming := c.sketch.estimate(key)
+	for c.cost+cost > c.maxCost {
+		var victim string
+		var victimItem *localItem
+		victimFreq := -1
+		for i := 0; i < evictionSamples && i < len(c.keys); i++ {
+			candidate := c.keys[c.rng.Intn(len(c.keys))]
+			if candidate == key {
+				continue
+			}
+			item := c.items[candidate]
+			if !now.Before(item.expiresAt) {
+				victim, victimItem, victimFreq = candidate, item, -1
+				break
+			}
+			if freq := c.sketch.estimate(candidate); victimItem == nil || freq < victimFreq {
+				victim, victimItem, victimFreq = candidate, item, freq
+			}
+		}
+		if victimItem == nil {
+			// Only key itself was sampled; it is over budget after an update
+			if item, ok := c.items[key]; ok && len(c.keys) == 1 {
+				c.remove(key, item)
+				c.evicted++
+				return true
+			}
+			continue
+		}
+		if !force && incoming < victimFreq {
+			return false
+		}
+		c.remove(victim, victimItem)
+		c.evicted++
+	}
+	return true
+}
+
+// remove deletes an entry. Callers hold c.mu.
+func (c *LocalCache) remove(key string, item *localItem) {
+	last := len(c.keys) - 1
+	c.keys[item.index] = c.keys[last]
+	c.items[c.keys[last]].index = item.index
+	c.keys = c.keys[:last]
+	delete(c.items, key)
+	c.cost -= item.cost
+}
+
+// Clear removes every entry, keeping the access frequencies and counters.
+func (c *LocalCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]*localItem)
+	c.keys = nil
+	c.cost = 0
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *LocalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats returns the cache counters.
+func (c *LocalCache) Stats() LocalCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := LocalCacheStats{
+		Entries:  len(c.items),
+		Cost:     c.cost,
+		MaxCost:  c.maxCost,
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Rejected: c.rejected,
+		Evicted:  c.evicted,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRatio = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// cmSketchDepth is the number of rows of the count-min sketch.
+const cmSketchDepth = 4
+
+// cmSketch is a count-min sketch of 4-bit saturating access counters. After as many
+// increments as ten times its width, every counter is halved, so the frequencies favour
+// recent accesses.
+type cmSketch struct {
+	seed       maphash.Seed
+	rows       [cmSketchDepth][]uint8
+	mask       uint64
+	increments int64
+	resetAt    int64
+}
+
+func newCMSketch(counters int64) *cmSketch {
+	width := int64(1024)
+	for width < counters {
+		width <<= 1
+	}
+	s := &cmSketch{seed: maphash.MakeSeed(), mask: uint64(width - 1), resetAt: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter of key in row i, by double hashing.
+func (s *cmSketch) index(h uint64, i int) uint64 {
+	lo, hi := h&0xffffffff, h>>32
+	return (lo + uint64(i)*hi) & s.mask
+}
+
+func (s *cmSketch) increment(key string) {
+	h := maphash.String(s.seed, key)
+	for i := range s.rows {
+		if j := s.index(h, i); s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	if s.increments++; s.increments >= s.resetAt {
+		s.halve()
+	}
+}
+
+func (s *cmSketch) estimate(key string) int {
+	h := maphash.String(s.seed, key)
+	lowest := uint8(15)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < lowest {
+			lowest = v
+		}
+	}
+	return int(lowest)
+}
+
+func (s *cmSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.increments /= 2
+}
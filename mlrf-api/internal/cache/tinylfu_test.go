@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalCache(t *testing.T) {
+	c := NewLocalCache(1 << 20)
+	now := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	c.Set("a", &PredictionResult{Prediction: 1}, time.Minute)
+	if result, ok := c.Get("a"); !ok || result.Prediction != 1 {
+		t.Fatalf("expected a hit, got %v %v", result, ok)
+	}
+
+	// Updates replace the entry without changing the count
+	c.Set("a", &PredictionResult{Prediction: 2}, time.Minute)
+	if result, _ := c.Get("a"); result.Prediction != 2 || c.Len() != 1 {
+		t.Errorf("expected the updated entry, got %v with %d entries", result, c.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected the entry to expire")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.HitRatio != 0.5 || stats.Entries != 0 || stats.Cost != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLocalCacheAdmission(t *testing.T) {
+	entry := &PredictionResult{Family: "GROCERY I", Date: "2017-08-01"}
+	cost := predictionCost(entry, "hot-0")
+	c := NewLocalCache(10 * cost)
+
+	// Ten frequently used keys fill the cache
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("hot-%d", i)
+		for j := 0; j < 5; j++ {
+			c.Get(key)
+		}
+		if !c.Set(key, entry, time.Hour) {
+			t.Fatalf("expected %s to be admitted into a cache with room", key)
+		}
+	}
+
+	// A scan of keys used once doesn't displace them
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("cold-%d", i), entry, time.Hour)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot-%d", i)); !ok {
+			t.Errorf("expected hot-%d to survive a scan", i)
+		}
+	}
+	stats := c.Stats()
+	if stats.Rejected == 0 || stats.Cost > stats.MaxCost {
+		t.Errorf("expected rejections within the cost budget, got %+v", stats)
+	}
+
+	// A key used more often than the cached ones is admitted by evicting one
+	for j := 0; j < 10; j++ {
+		c.Get("new")
+	}
+	if !c.Set("new", entry, time.Hour) || c.Len() != 10 {
+		t.Errorf("expected a frequently used key to displace one entry, got %d entries", c.Len())
+	}
+
+	// Expired entries are evicted first, whatever their frequency
+	now := time.Now().Add(2 * time.Hour)
+	c.now = func() time.Time { return now }
+	if !c.Set("cold-again", entry, time.Hour) {
+		t.Error("expected a key to be admitted over expired entries")
+	}
+}
+
+func TestLocalCacheConcurrent(t *testing.T) {
+	c := NewLocalCache(64 * predictionCost(&PredictionResult{}, "key-00"))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key-%02d", (g*7+i)%100)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, &PredictionResult{Prediction: float32(i)}, time.Hour)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if stats := c.Stats(); stats.Cost > stats.MaxCost || stats.Hits+stats.Misses != 8000 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCMSketch(t *testing.T) {
+	s := newCMSketch(1024)
+	for i := 0; i < 20; i++ {
+		s.increment("a")
+	}
+	s.increment("b")
+	if got := s.estimate("a"); got != 15 {
+		t.Errorf("expected the counter to saturate at 15, got %d", got)
+	}
+	if got := s.estimate("b"); got != 1 {
+		t.Errorf("expected 1, got %d", got)
+	}
+	s.halve()
+	if s.estimate("a") != 7 || s.estimate("b") != 0 {
+		t.Errorf("expected halved counters, got %d and %d", s.estimate("a"), s.estimate("b"))
+	}
+}
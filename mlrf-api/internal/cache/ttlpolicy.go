@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endpoints caching predictions, as named in TTL policy rules.
+const (
+	EndpointPredict  = "predict"
+	EndpointSimple   = "predict_simple"
+	EndpointBatch    = "predict_batch"
+	EndpointInsights = "insights"
+)
+
+// DefaultNegativeTTL is the TTL of "no data" predictions: those of series without
+// features, made on zeros or not made at all.
+const DefaultNegativeTTL = time.Minute
+
+// TTLPolicy decides how long a prediction is cached, by the endpoint that computed it
+// and its horizon.
+type TTLPolicy struct {
+	Default  time.Duration            // Predictions without a rule
+	Negative time.Duration            // "No data" predictions, whatever the rules
+	Rules    map[string]time.Duration // By "endpoint" or "endpoint:horizon"
+
+	// Jitter shortens each TTL by a random fraction up to Jitter, so entries written
+	// together, as by a cache warm, don't all expire together.
+	Jitter float64
+
+	// EarlyExpiry serves hits as misses at random before entries expire, so one
+	// request recomputes a hot entry before every request misses it at once
+	// (probabilistic early expiration, or XFetch). The chance of a hit expiring early
+	// is e^(-remaining / (EarlyExpiry * TTL)): 37% with EarlyExpiry of the TTL left,
+	// 5% with three times as much. 0 disables it.
+	EarlyExpiry float64
+}
+
+// For returns the TTL of a prediction: the negative TTL for "no data" results, else
+// the rule for the endpoint and horizon, the rule for the endpoint, or the default.
+func (p *TTLPolicy) For(endpoint string, result *PredictionResult) time.Duration {
+	if result.NoData || result.FeatureSource == "zeros" {
+		return p.Negative
+	}
+	if ttl, ok := p.Rules[endpoint+":"+strconv.Itoa(result.Horizon)]; ok {
+		return ttl
+	}
+	if ttl, ok := p.Rules[endpoint]; ok {
+		return ttl
+	}
+	return p.Default
+}
+
+// jittered shortens ttl by the fraction u of the policy's jitter; u is uniform in [0, 1).
+func (p *TTLPolicy) jittered(ttl time.Duration, u float64) time.Duration {
+	if p.Jitter <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(float64(ttl)*p.Jitter*u)
+}
+
+// expiresEarly reports whether a hit on result at now is served as a miss; u is
+// uniform in (0, 1]. Entries cached without an expiry never expire early.
+func (p *TTLPolicy) expiresEarly(result *PredictionResult, now time.Time, u float64) bool {
+	if p.EarlyExpiry <= 0 || result.ExpiresAt.IsZero() || result.CachedAt.IsZero() {
+		return false
+	}
+	window := float64(result.ExpiresAt.Sub(result.CachedAt)) * p.EarlyExpiry
+	return float64(result.ExpiresAt.Sub(now)) <= -window*math.Log(u)
+}
+
+// ParseTTLRules parses a comma-separated list of TTL rules, such as
+// "predict_simple=2h,predict_simple:90=6h,predict_batch=30m". A rule for an endpoint
+// and horizon takes precedence over one for the endpoint.
+func ParseTTLRules(s string) (map[string]time.Duration, error) {
+	rules := make(map[string]time.Duration)
+	if strings.TrimSpace(s) == "" {
+		return rules, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid TTL rule %q: must be endpoint[:horizon]=duration", field)
+		}
+		key = strings.TrimSpace(key)
+		endpoint, horizon, hasHorizon := strings.Cut(key, ":")
+		switch endpoint {
+		case EndpointPredict, EndpointSimple, EndpointBatch, EndpointInsights:
+		default:
+			return nil, fmt.Errorf("invalid TTL rule %q: endpoint must be %s, %s, %s or %s",
+				field, EndpointPredict, EndpointSimple, EndpointBatch, EndpointInsights)
+		}
+		if hasHorizon {
+			if h, err := strconv.Atoi(horizon); err != nil || h < 1 {
+				return nil, fmt.Errorf("invalid TTL rule %q: horizon must be a positive number of days", field)
+			}
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid TTL rule %q: TTL must be a positive duration", field)
+		}
+		rules[key] = ttl
+	}
+	return rules, nil
+}
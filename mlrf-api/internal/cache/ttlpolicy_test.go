@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTTLPolicy(t *testing.T) {
+	rules, err := ParseTTLRules("predict_simple=2h, predict_simple:90=6h,predict_batch=30m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := &TTLPolicy{Default: time.Hour, Negative: time.Minute, Rules: rules}
+
+	tests := []struct {
+		endpoint string
+		result   PredictionResult
+		want     time.Duration
+	}{
+		{EndpointSimple, PredictionResult{Horizon: 90}, 6 * time.Hour},
+		{EndpointSimple, PredictionResult{Horizon: 30}, 2 * time.Hour},
+		{EndpointBatch, PredictionResult{Horizon: 90}, 30 * time.Minute},
+		{EndpointPredict, PredictionResult{Horizon: 90}, time.Hour},
+		{EndpointSimple, PredictionResult{Horizon: 90, FeatureSource: "zeros"}, time.Minute},
+		{EndpointSimple, PredictionResult{Horizon: 90, NoData: true}, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.For(tt.endpoint, &tt.result); got != tt.want {
+			t.Errorf("%s %+v: expected %v, got %v", tt.endpoint, tt.result, tt.want, got)
+		}
+	}
+
+	if rules, err := ParseTTLRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("expected no rules, got %v %v", rules, err)
+	}
+	for _, invalid := range []string{"predict_simple", "explain=1h", "predict_simple:x=1h", "predict_simple=-1m", "predict_simple=soon"} {
+		if _, err := ParseTTLRules(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSetPredictionTTL(t *testing.T) {
+	r := &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		local:  NewLocalCache(1 << 20),
+	}
+	r.SetTTLPolicy(TTLPolicy{Default: time.Hour, Rules: map[string]time.Duration{EndpointBatch: 10 * time.Minute}})
+	defer r.Close()
+
+	// Redis is unreachable; the entry is still cached in process
+	noData := &PredictionResult{NoData: true}
+	r.SetPrediction(context.Background(), EndpointSimple, "pred:v1:a", noData)
+	if ttl := noData.ExpiresAt.Sub(noData.CachedAt); ttl != DefaultNegativeTTL {
+		t.Errorf("expected the default negative TTL, got %v", ttl)
+	}
+	if hit, err := r.GetPrediction(context.Background(), "pred:v1:a"); err != nil || !hit.NoData {
+		t.Errorf("expected a local no data hit, got %+v %v", hit, err)
+	}
+
+	batch := map[string]*PredictionResult{"pred:v1:b": {Prediction: 1}}
+	r.SetPredictions(context.Background(), EndpointBatch, batch)
+	if ttl := batch["pred:v1:b"].ExpiresAt.Sub(batch["pred:v1:b"].CachedAt); ttl != 10*time.Minute {
+		t.Errorf("expected the batch rule, got %v", ttl)
+	}
+
+	// SetTTL keeps the rules
+	r.SetTTL(2 * time.Hour)
+	if p := r.TTLPolicy(); p.Default != 2*time.Hour || p.Rules[EndpointBatch] != 10*time.Minute {
+		t.Errorf("unexpected policy %+v", p)
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	p := &TTLPolicy{Jitter: 0.1}
+	if got := p.jittered(time.Hour, 0); got != time.Hour {
+		t.Errorf("expected no shortening for u=0, got %v", got)
+	}
+	if got := p.jittered(time.Hour, 0.5); got != 57*time.Minute {
+		t.Errorf("expected 57m, got %v", got)
+	}
+	if got := (&TTLPolicy{}).jittered(time.Hour, 0.5); got != time.Hour {
+		t.Errorf("expected no jitter when disabled, got %v", got)
+	}
+}
+
+func TestTTLEarlyExpiry(t *testing.T) {
+	p := &TTLPolicy{EarlyExpiry: 0.1}
+	cachedAt := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	result := &PredictionResult{CachedAt: cachedAt, ExpiresAt: cachedAt.Add(time.Hour)}
+
+	// The window is 6 minutes: with 6 minutes left, hits expire early with u <= 1/e
+	now := result.ExpiresAt.Add(-6 * time.Minute)
+	if !p.expiresEarly(result, now, 0.3) || p.expiresEarly(result, now, 0.4) {
+		t.Error("expected early expiry for u below 1/e only")
+	}
+	// Fresh entries practically never expire early; expired ones always do
+	if p.expiresEarly(result, cachedAt, 0.001) {
+		t.Error("expected a fresh entry to be served")
+	}
+	if !p.expiresEarly(result, result.ExpiresAt, 1) {
+		t.Error("expected an entry at its expiry to expire")
+	}
+
+	if (&TTLPolicy{}).expiresEarly(result, now, 0.001) {
+		t.Error("expected no early expiry when disabled")
+	}
+	if p.expiresEarly(&PredictionResult{}, now, 0.001) {
+		t.Error("expected no early expiry for an entry without an expiry")
+	}
+}
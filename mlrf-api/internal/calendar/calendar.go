@@ -0,0 +1,179 @@
+// Package calendar loads the holiday calendar used to set the is_holiday feature
+// of dates past the feature matrix, from the Kaggle holidays_events.csv or a JSON
+// list of holidays.
+package calendar
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of holiday dates.
+const DateFormat = "2006-01-02"
+
+// LocaleNational is the locale of country-wide holidays, the only ones the training
+// pipeline flags in is_holiday.
+const LocaleNational = "National"
+
+// Holiday is one event of the calendar, following the holidays_events.csv columns.
+type Holiday struct {
+	Date        string `json:"date"`
+	Type        string `json:"type,omitempty"`   // Holiday, Transfer, Additional, Bridge, Work Day or Event
+	Locale      string `json:"locale,omitempty"` // National, Regional or Local; empty means National
+	LocaleName  string `json:"locale_name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Transferred bool   `json:"transferred,omitempty"` // Celebrated on another date, listed as a Transfer
+}
+
+// National reports whether the holiday is flagged in is_holiday.
+func (h Holiday) National() bool {
+	return h.Locale == LocaleNational
+}
+
+// File is the JSON form of a holiday calendar.
+type File struct {
+	Holidays []Holiday `json:"holidays"`
+}
+
+// Calendar is a validated holiday calendar, ordered by date.
+type Calendar struct {
+	holidays []Holiday
+	national map[string]bool // dates with a national holiday
+}
+
+// Load reads a holiday calendar: a .json file with "holidays", or otherwise a CSV
+// file with the holidays_events.csv header.
+func Load(path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var holidays []Holiday
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var file File
+		if err := json.NewDecoder(f).Decode(&file); err != nil {
+			return nil, fmt.Errorf("parse holiday calendar: %w", err)
+		}
+		for i := range file.Holidays {
+			if file.Holidays[i].Locale == "" {
+				file.Holidays[i].Locale = LocaleNational
+			}
+		}
+		holidays = file.Holidays
+	} else if holidays, err = ParseCSV(f); err != nil {
+		return nil, err
+	}
+	return New(holidays)
+}
+
+// ParseCSV reads holidays in the holidays_events.csv format. The date column is
+// required; type, locale, locale_name, description and transferred are optional.
+func ParseCSV(r io.Reader) ([]Holiday, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read holiday calendar header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	if _, ok := cols["date"]; !ok {
+		return nil, errors.New("holiday calendar has no date column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var holidays []Holiday
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read holiday calendar: %w", err)
+		}
+		h := Holiday{
+			Date:        field(record, "date"),
+			Type:        field(record, "type"),
+			Locale:      field(record, "locale"),
+			LocaleName:  field(record, "locale_name"),
+			Description: field(record, "description"),
+		}
+		if h.Locale == "" {
+			h.Locale = LocaleNational
+		}
+		if t := field(record, "transferred"); t != "" {
+			if h.Transferred, err = strconv.ParseBool(t); err != nil {
+				return nil, fmt.Errorf("holiday calendar line %d: invalid transferred %q", line, t)
+			}
+		}
+		holidays = append(holidays, h)
+	}
+	return holidays, nil
+}
+
+// New validates holidays and returns their calendar.
+func New(holidays []Holiday) (*Calendar, error) {
+	if len(holidays) == 0 {
+		return nil, errors.New("holiday calendar has no holidays")
+	}
+	c := &Calendar{
+		holidays: make([]Holiday, len(holidays)),
+		national: make(map[string]bool),
+	}
+	copy(c.holidays, holidays)
+	for i, h := range c.holidays {
+		if _, err := time.Parse(DateFormat, h.Date); err != nil {
+			return nil, fmt.Errorf("holiday %d: invalid date %q", i, h.Date)
+		}
+		if h.National() {
+			c.national[h.Date] = true
+		}
+	}
+	sort.SliceStable(c.holidays, func(i, j int) bool { return c.holidays[i].Date < c.holidays[j].Date })
+	return c, nil
+}
+
+// IsHoliday reports whether date has a national holiday. As in the training
+// pipeline, every national event counts, transferred holidays and work days included.
+func (c *Calendar) IsHoliday(date time.Time) bool {
+	return c.national[date.Format(DateFormat)]
+}
+
+// Between returns the holidays from from to to, inclusive, in date order.
+func (c *Calendar) Between(from, to time.Time) []Holiday {
+	lo, hi := from.Format(DateFormat), to.Format(DateFormat)
+	start := sort.Search(len(c.holidays), func(i int) bool { return c.holidays[i].Date >= lo })
+	end := sort.Search(len(c.holidays), func(i int) bool { return c.holidays[i].Date > hi })
+	if start >= end {
+		return []Holiday{}
+	}
+	return append([]Holiday(nil), c.holidays[start:end]...)
+}
+
+// Span returns the dates of the first and last holiday. Dates after the last one
+// are never holidays, so the calendar should reach past the forecast horizon.
+func (c *Calendar) Span() (first, last string) {
+	return c.holidays[0].Date, c.holidays[len(c.holidays)-1].Date
+}
+
+// Len returns the number of holidays.
+func (c *Calendar) Len() int {
+	return len(c.holidays)
+}
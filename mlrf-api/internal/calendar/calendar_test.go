@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHolidaysCSV = `date,type,locale,locale_name,description,transferred
+2017-08-10,Holiday,National,Ecuador,Primer Grito de Independencia,True
+2017-08-11,Transfer,National,Ecuador,Traslado Primer Grito de Independencia,False
+2017-08-15,Holiday,Local,Riobamba,Fundacion de Riobamba,False
+2017-12-25,Holiday,National,Ecuador,Navidad,False
+2017-08-24,Holiday,Local,Ambato,Fundacion de Ambato,False
+`
+
+func day(s string) time.Time {
+	d, _ := time.Parse(DateFormat, s)
+	return d
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCSV(t *testing.T) {
+	c, err := Load(writeFile(t, "holidays_events.csv", testHolidaysCSV))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Len() != 5 {
+		t.Fatalf("expected 5 holidays, got %d", c.Len())
+	}
+
+	// National events count, transferred ones included; local ones don't
+	for date, want := range map[string]bool{
+		"2017-08-10": true,
+		"2017-08-11": true,
+		"2017-08-15": false,
+		"2017-08-16": false,
+		"2017-12-25": true,
+	} {
+		if got := c.IsHoliday(day(date)); got != want {
+			t.Errorf("IsHoliday(%s) = %v, want %v", date, got, want)
+		}
+	}
+
+	holidays := c.Between(day("2017-08-11"), day("2017-08-24"))
+	if len(holidays) != 3 || holidays[0].Type != "Transfer" || holidays[2].LocaleName != "Ambato" {
+		t.Errorf("unexpected holidays in range %+v", holidays)
+	}
+	if first, last := c.Span(); first != "2017-08-10" || last != "2017-12-25" {
+		t.Errorf("unexpected span %s to %s", first, last)
+	}
+	if len(c.Between(day("2018-01-01"), day("2018-12-31"))) != 0 {
+		t.Error("expected no holidays past the calendar")
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	c, err := Load(writeFile(t, "holidays.json", `{"holidays":[
+		{"date":"2018-01-01","description":"Primer dia del ano"},
+		{"date":"2018-02-12","type":"Holiday","locale":"Regional","locale_name":"Cotopaxi"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !c.IsHoliday(day("2018-01-01")) || c.IsHoliday(day("2018-02-12")) {
+		t.Error("expected holidays without a locale to be national")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty.csv":     "date,type\n",
+		"nodate.csv":    "day,type\n2017-01-01,Holiday\n",
+		"baddate.csv":   "date\n01/01/2017\n",
+		"transfer.csv":  "date,transferred\n2017-01-01,maybe\n",
+		"invalid.json":  `{"holidays":`,
+		"baddate.json":  `{"holidays":[{"date":"2017-13-01"}]}`,
+		"nothing.json":  `{"holidays":[]}`,
+		"truncated.csv": "",
+	} {
+		if _, err := Load(writeFile(t, name, data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := ParseCSV(strings.NewReader("date\n2017-01-01\n")); err != nil {
+		t.Errorf("expected a date-only calendar to parse, got %v", err)
+	}
+}
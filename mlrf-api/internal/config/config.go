@@ -0,0 +1,646 @@
+// Package config loads the server configuration from an optional TOML file and
+// environment variable overrides into one typed Config, validated at startup.
+//
+// Every setting keeps the environment variable it has always been read from, and
+// environment variables take precedence over the file. Components don't read the
+// environment themselves: the server builds their configuration from the Config.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/slo"
+	"github.com/mlrf/mlrf-api/internal/tracing"
+	"github.com/rs/zerolog"
+)
+
+// Config is the effective server configuration. Field tags name the TOML key within
+// its section, the environment variable and the default ("" leaves the default to
+// the component). Secret settings are redacted by Redacted, and settings tagged
+// reload can be changed at runtime by Reload without restarting the server.
+type Config struct {
+	Server        ServerConfig        `toml:"server"`
+	Model         ModelConfig         `toml:"model"`
+	Predictions   PredictionsConfig   `toml:"predictions"`
+	Features      FeaturesConfig      `toml:"features"`
+	Cache         CacheConfig         `toml:"cache"`
+	Data          DataConfig          `toml:"data"`
+	Alerts        AlertsConfig        `toml:"alerts"`
+	SLO           SLOConfig           `toml:"slo"`
+	Metrics       MetricsConfig       `toml:"metrics"`
+	PredictionLog PredictionLogConfig `toml:"prediction_log"`
+	Ingest        IngestConfig        `toml:"ingest"`
+	Remote        RemoteConfig        `toml:"remote"`
+	Tracing       TracingConfig       `toml:"tracing"`
+	Health        HealthConfig        `toml:"health"`
+
+	file    string
+	sources map[string]string // Env var -> SourceDefault, SourceFile or SourceEnv
+	raw     map[string]string // Env var -> value read from the file
+}
+
+// ServerConfig configures the HTTP server and its middleware.
+type ServerConfig struct {
+	Port             int           `toml:"port" env:"PORT" default:"8080"`
+	Environment      string        `toml:"environment" env:"ENVIRONMENT" default:"development"`
+	CORSOrigins      string        `toml:"cors_origins" env:"CORS_ORIGINS" reload:"true"`
+	RateLimitRPS     float64       `toml:"rate_limit_rps" env:"RATE_LIMIT_RPS" default:"100" reload:"true"`
+	RateLimitBurst   int           `toml:"rate_limit_burst" env:"RATE_LIMIT_BURST" default:"200" reload:"true"`
+	RateLimitClasses string        `toml:"rate_limit_classes" env:"RATE_LIMIT_CLASSES" default:"batch=10/20,explain=10/20,admin=5/10" reload:"true"`
+	TrustedProxies   string        `toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	RequestTimeout   time.Duration `toml:"request_timeout" env:"REQUEST_TIMEOUT" default:"30s"`
+	RequestTimeouts  string        `toml:"request_timeouts" env:"REQUEST_TIMEOUTS" default:"/predict=1s,/predict/simple=1s,/backtest=5m"`
+	ConcurrencyLimit int           `toml:"concurrency_limit" env:"CONCURRENCY_LIMIT" default:"32"`
+	ConcurrencyQueue int           `toml:"concurrency_max_queue" env:"CONCURRENCY_MAX_QUEUE" default:"64"`
+	ConcurrencyWait  time.Duration `toml:"concurrency_max_wait" env:"CONCURRENCY_MAX_WAIT" default:"250ms"`
+	LogLevel         string        `toml:"log_level" env:"LOG_LEVEL" default:"info" reload:"true"`
+	LogFormat        string        `toml:"log_format" env:"LOG_FORMAT" default:"console"`
+	LogSampleRate    float64       `toml:"log_sample_rate" env:"LOG_SAMPLE_RATE" default:"1" reload:"true"`
+	AccessLogFile    string        `toml:"access_log_file" env:"ACCESS_LOG_FILE"`
+	AccessLogMaxSize int           `toml:"access_log_max_size_mb" env:"ACCESS_LOG_MAX_SIZE_MB" default:"100"`
+	AccessLogBackups int           `toml:"access_log_max_backups" env:"ACCESS_LOG_MAX_BACKUPS" default:"5"`
+	AuditLogFile     string        `toml:"audit_log_file" env:"AUDIT_LOG_FILE"`
+	AdminAuditPath   string        `toml:"admin_audit_path" env:"ADMIN_AUDIT_PATH" default:"data/admin_audit.jsonl"`
+	AdminAuditParams int           `toml:"admin_audit_max_param_bytes" env:"ADMIN_AUDIT_MAX_PARAM_BYTES" default:"4096"`
+	LegacySunset     string        `toml:"legacy_sunset" env:"API_LEGACY_SUNSET"`
+	DrainTimeout     time.Duration `toml:"drain_timeout" env:"DRAIN_TIMEOUT" default:"5m"`
+	MaxBodyBytes     int           `toml:"max_body_bytes" env:"MAX_BODY_BYTES" default:"1048576"`
+	MaxBulkBodyBytes int           `toml:"max_bulk_body_bytes" env:"MAX_BULK_BODY_BYTES" default:"33554432"`
+	StrictJSON       bool          `toml:"strict_json" env:"STRICT_JSON"`
+	MockFallbacks    string        `toml:"mock_fallbacks" env:"MOCK_FALLBACKS" default:"allow"`
+	APIKey           string        `toml:"api_key" env:"API_KEY" secret:"true" reload:"true"`
+	APIKeysFile      string        `toml:"api_keys_file" env:"API_KEYS_FILE" reload:"true"`
+	AdminAPIKey      string        `toml:"admin_api_key" env:"ADMIN_API_KEY" secret:"true" reload:"true"`
+}
+
+// ModelConfig configures the ONNX models and prediction intervals.
+type ModelConfig struct {
+	Path             string `toml:"path" env:"MODEL_PATH" default:"models/lightgbm_model.onnx"`
+	SHA256           string `toml:"sha256" env:"MODEL_SHA256"`
+	ManifestPath     string `toml:"manifest_path" env:"MODEL_MANIFEST_PATH" default:"models/manifest.json"`
+	ShadowModel      string `toml:"shadow_model" env:"SHADOW_MODEL"`
+	ShadowWorkers    int    `toml:"shadow_workers" env:"SHADOW_WORKERS" default:"2"`
+	ShadowQueueSize  int    `toml:"shadow_queue_size" env:"SHADOW_QUEUE_SIZE" default:"1000"`
+	ShadowLogSize    int    `toml:"shadow_log_size" env:"SHADOW_LOG_SIZE" default:"100000"`
+	QuantileP10Path  string `toml:"quantile_p10_path" env:"QUANTILE_P10_MODEL_PATH"`
+	QuantileP50Path  string `toml:"quantile_p50_path" env:"QUANTILE_P50_MODEL_PATH"`
+	QuantileP90Path  string `toml:"quantile_p90_path" env:"QUANTILE_P90_MODEL_PATH"`
+	ONNXLibPath      string `toml:"onnx_lib_path" env:"ONNX_LIB_PATH" default:"libonnxruntime.so"`
+	ONNXMaxBatchSize int    `toml:"onnx_max_batch_size" env:"ONNX_MAX_BATCH_SIZE" default:"256"`
+	IntervalsPath    string `toml:"intervals_path" env:"INTERVALS_PATH" default:"models/prediction_intervals.json"`
+	SelfTestPath     string `toml:"selftest_path" env:"MODEL_SELFTEST_PATH" default:"models/model_selftest.json"`
+	TrainingMetrics  string `toml:"training_metrics_path" env:"TRAINING_METRICS_PATH" default:"models/metrics.json"`
+	ModelMetrics     string `toml:"model_metrics_path" env:"MODEL_METRICS_PATH" default:"models/model_metrics.json"`
+}
+
+// PredictionsConfig configures request limits, async jobs, live updates and the
+// sanity guard on model output.
+type PredictionsConfig struct {
+	Horizons           string        `toml:"horizons" env:"FORECAST_HORIZONS" default:"15,30,60,90"`
+	MaxBatchSize       int           `toml:"max_batch_size" env:"MAX_BATCH_SIZE" default:"100"`
+	StreamMaxBatchSize int           `toml:"stream_max_batch_size" env:"STREAM_MAX_BATCH_SIZE" default:"50000"`
+	JobWorkers         int           `toml:"job_workers" env:"JOB_WORKERS" default:"2"`
+	JobQueueSize       int           `toml:"job_queue_size" env:"JOB_QUEUE_SIZE" default:"100"`
+	JobMaxItems        int           `toml:"job_max_items" env:"JOB_MAX_ITEMS" default:"50000"`
+	JobResultTTL       time.Duration `toml:"job_result_ttl" env:"JOB_RESULT_TTL" default:"1h"`
+	WSMaxSubscriptions int           `toml:"ws_max_subscriptions" env:"WS_MAX_SUBSCRIPTIONS" default:"100"`
+	WSSendBuffer       int           `toml:"ws_send_buffer" env:"WS_SEND_BUFFER" default:"256"`
+	Min                float64       `toml:"min" env:"PREDICTION_MIN" default:"0"`
+	Max                float64       `toml:"max" env:"PREDICTION_MAX" default:"1e7"`
+	NaNPolicy          string        `toml:"nan_policy" env:"PREDICTION_NAN_POLICY" default:"clamp"`
+}
+
+// FeaturesConfig configures the feature store, its quality and drift checks and reloads.
+type FeaturesConfig struct {
+	Path                  string        `toml:"path" env:"FEATURE_PATH" default:"data/features/feature_matrix.parquet"`
+	SHA256                string        `toml:"sha256" env:"FEATURE_SHA256"`
+	Backend               string        `toml:"backend" env:"FEATURE_BACKEND" default:"memory"`
+	StalenessThreshold    time.Duration `toml:"staleness_threshold" env:"FEATURE_STALENESS_THRESHOLD" default:"24h" reload:"true"`
+	DBDSN                 string        `toml:"db_dsn" env:"FEATURE_DB_DSN" secret:"true"`
+	DBDriver              string        `toml:"db_driver" env:"FEATURE_DB_DRIVER"`
+	DBTable               string        `toml:"db_table" env:"FEATURE_DB_TABLE"`
+	DBCacheSize           int           `toml:"db_cache_size" env:"FEATURE_DB_CACHE_SIZE" default:"100000"`
+	DBTimeout             time.Duration `toml:"db_timeout" env:"FEATURE_DB_TIMEOUT" default:"2s"`
+	QualityMode           string        `toml:"quality_mode" env:"FEATURE_QUALITY_MODE" default:"reject"`
+	QualityMaxNullRate    float64       `toml:"quality_max_null_rate" env:"FEATURE_QUALITY_MAX_NULL_RATE" default:"0.01"`
+	QualityMaxOutlierRate float64       `toml:"quality_max_outlier_rate" env:"FEATURE_QUALITY_MAX_OUTLIER_RATE" default:"0.01"`
+	DriftReferencePath    string        `toml:"drift_reference_path" env:"FEATURE_DRIFT_REFERENCE_PATH" default:"models/feature_reference.json"`
+	DriftWindowDays       int           `toml:"drift_window_days" env:"FEATURE_DRIFT_WINDOW_DAYS" default:"28"`
+	DriftPSIThreshold     float64       `toml:"drift_psi_threshold" env:"FEATURE_DRIFT_PSI_THRESHOLD" default:"0.2"`
+	DriftKSThreshold      float64       `toml:"drift_ks_threshold" env:"FEATURE_DRIFT_KS_THRESHOLD" default:"0.1"`
+	RefreshCron           string        `toml:"refresh_cron" env:"FEATURE_REFRESH_CRON"`
+	RefreshTZ             string        `toml:"refresh_tz" env:"FEATURE_REFRESH_TZ" default:"UTC"`
+	RefreshWarm           bool          `toml:"refresh_warm" env:"FEATURE_REFRESH_WARM" default:"true"`
+	RefreshTimeout        time.Duration `toml:"refresh_timeout" env:"FEATURE_REFRESH_TIMEOUT" default:"30m"`
+	WatchFiles            bool          `toml:"watch_files" env:"WATCH_FILES" default:"false"`
+	WatchInterval         time.Duration `toml:"watch_interval" env:"WATCH_INTERVAL" default:"5s"`
+	WatchDebounce         time.Duration `toml:"watch_debounce" env:"WATCH_DEBOUNCE" default:"10s"`
+}
+
+// CacheConfig configures Redis and the in-process result caches.
+type CacheConfig struct {
+	RedisURL                 string        `toml:"redis_url" env:"REDIS_URL" default:"redis://localhost:6379" secret:"url"`
+	TTL                      time.Duration `toml:"ttl" env:"CACHE_TTL" default:"1h" reload:"true"`
+	NegativeTTL              time.Duration `toml:"negative_ttl" env:"CACHE_NEGATIVE_TTL" default:"1m" reload:"true"`
+	TTLPolicy                string        `toml:"ttl_policy" env:"CACHE_TTL_POLICY" reload:"true"`
+	TTLJitter                float64       `toml:"ttl_jitter" env:"CACHE_TTL_JITTER" default:"0.1" reload:"true"`
+	EarlyExpiry              float64       `toml:"early_expiry" env:"CACHE_EARLY_EXPIRY" default:"0.05" reload:"true"`
+	ExplainTTL               time.Duration `toml:"explain_ttl" env:"EXPLAIN_CACHE_TTL" default:"24h"`
+	LocalMaxBytes            int           `toml:"local_max_bytes" env:"LOCAL_CACHE_MAX_BYTES" default:"16777216"`
+	Compression              string        `toml:"compression" env:"CACHE_COMPRESSION" default:"none"`
+	WarmSeries               string        `toml:"warm_series" env:"CACHE_WARM_SERIES"`
+	WarmTopK                 int           `toml:"warm_top_k" env:"CACHE_WARM_TOP_K"`
+	WarmHorizons             string        `toml:"warm_horizons" env:"CACHE_WARM_HORIZONS"`
+	WarmDate                 string        `toml:"warm_date" env:"CACHE_WARM_DATE" default:"2017-08-01"`
+	HierarchyCacheTTL        time.Duration `toml:"hierarchy_cache_ttl" env:"HIERARCHY_CACHE_TTL" default:"1h"`
+	HierarchyCacheMaxEntries int           `toml:"hierarchy_cache_max_entries" env:"HIERARCHY_CACHE_MAX_ENTRIES" default:"100"`
+	BacktestMaxDays          int           `toml:"backtest_max_days" env:"BACKTEST_MAX_DAYS" default:"366"`
+	BacktestCacheTTL         time.Duration `toml:"backtest_cache_ttl" env:"BACKTEST_CACHE_TTL" default:"6h"`
+	BacktestCacheMaxEntries  int           `toml:"backtest_cache_max_entries" env:"BACKTEST_CACHE_MAX_ENTRIES" default:"50"`
+}
+
+// DataConfig configures auxiliary data files and services.
+type DataConfig struct {
+	HistoricalDataPath           string        `toml:"historical_data_path" env:"HISTORICAL_DATA_PATH" default:"data/processed/train_preprocessed.parquet"`
+	HistoricalDBTable            string        `toml:"historical_db_table" env:"HISTORICAL_DB_TABLE"`
+	HierarchyDataPath            string        `toml:"hierarchy_data_path" env:"HIERARCHY_DATA_PATH" default:"models/hierarchy_data.json"`
+	HierarchyDefinitionPath      string        `toml:"hierarchy_definition_path" env:"HIERARCHY_DEFINITION_PATH" default:"models/hierarchy.json"`
+	ReconciliationCovariancePath string        `toml:"reconciliation_covariance_path" env:"RECONCILIATION_COVARIANCE_PATH" default:"models/reconciliation_covariance.json"`
+	HolidaysPath                 string        `toml:"holidays_path" env:"HOLIDAYS_PATH" default:"data/raw/holidays_events.csv"`
+	RegressorOverridesPath       string        `toml:"regressor_overrides_path" env:"REGRESSOR_OVERRIDES_PATH" default:"data/regressor_overrides.json"`
+	ActualsDriver                string        `toml:"actuals_driver" env:"ACTUALS_DRIVER" default:"sqlite"`
+	ActualsDSN                   string        `toml:"actuals_dsn" env:"ACTUALS_DSN" default:"data/actuals.db" secret:"true"`
+	ActualsPath                  string        `toml:"actuals_path" env:"ACTUALS_PATH" default:"data/actuals.jsonl"`
+	ActualsMaxBatch              int           `toml:"actuals_max_batch" env:"ACTUALS_MAX_BATCH" default:"10000"`
+	SHAPServiceAddr              string        `toml:"shap_service_addr" env:"SHAP_SERVICE_ADDR" default:"localhost:50051"`
+	SHAPDataPath                 string        `toml:"shap_data_path" env:"SHAP_DATA_PATH" default:"models/shap_waterfall.json"`
+	TreeSHAPModelPath            string        `toml:"treeshap_model_path" env:"TREESHAP_MODEL_PATH" default:"models/lightgbm_model.json"`
+	GlobalImportancePath         string        `toml:"global_importance_path" env:"GLOBAL_IMPORTANCE_PATH" default:"models/global_importance.json"`
+	GlobalImportanceSample       int           `toml:"global_importance_sample_per_family" env:"GLOBAL_IMPORTANCE_SAMPLE_PER_FAMILY" default:"10"`
+	SHAPBreakerThreshold         int           `toml:"shap_breaker_threshold" env:"SHAP_BREAKER_THRESHOLD" default:"5"`
+	SHAPBreakerOpenDuration      time.Duration `toml:"shap_breaker_open_duration" env:"SHAP_BREAKER_OPEN_DURATION" default:"30s"`
+	SHAPBreakerHalfOpenProbes    int           `toml:"shap_breaker_half_open_probes" env:"SHAP_BREAKER_HALF_OPEN_PROBES" default:"1"`
+	SHAPRetries                  int           `toml:"shap_retries" env:"SHAP_RETRIES" default:"2"`
+	SHAPRetryBaseDelay           time.Duration `toml:"shap_retry_base_delay" env:"SHAP_RETRY_BASE_DELAY" default:"50ms"`
+	SHAPRetryMaxDelay            time.Duration `toml:"shap_retry_max_delay" env:"SHAP_RETRY_MAX_DELAY" default:"1s"`
+	SHAPHedge                    bool          `toml:"shap_hedge" env:"SHAP_HEDGE" default:"false"`
+}
+
+// AlertsConfig configures forecast accuracy alerts and anomaly detection.
+type AlertsConfig struct {
+	CheckInterval  time.Duration `toml:"check_interval" env:"ALERT_CHECK_INTERVAL" default:"15m"`
+	WindowDays     int           `toml:"window_days" env:"ALERT_WINDOW_DAYS" default:"7"`
+	MinSamples     int           `toml:"min_samples" env:"ALERT_MIN_SAMPLES" default:"10"`
+	MAPEThreshold  float64       `toml:"mape_threshold" env:"ALERT_MAPE_THRESHOLD" default:"20"`
+	WebhookURL     string        `toml:"webhook_url" env:"ALERT_WEBHOOK_URL" secret:"true"`
+	WebhookTimeout time.Duration `toml:"webhook_timeout" env:"ALERT_WEBHOOK_TIMEOUT" default:"10s"`
+
+	AnomalyMethod       string  `toml:"anomaly_method" env:"ANOMALY_METHOD" default:"auto"`
+	AnomalyBaselineDays int     `toml:"anomaly_baseline_days" env:"ANOMALY_BASELINE_DAYS" default:"56"`
+	AnomalyMinBaseline  int     `toml:"anomaly_min_baseline" env:"ANOMALY_MIN_BASELINE" default:"7"`
+	AnomalyZThreshold   float64 `toml:"anomaly_z_threshold" env:"ANOMALY_Z_THRESHOLD" default:"3.5"`
+	AnomalyCriticalZ    float64 `toml:"anomaly_critical_z" env:"ANOMALY_CRITICAL_Z" default:"5"`
+	AnomalyWindowDays   int     `toml:"anomaly_window_days" env:"ANOMALY_WINDOW_DAYS" default:"1"`
+}
+
+// SLOConfig configures the per-route service level objectives.
+type SLOConfig struct {
+	Routes             string        `toml:"routes" env:"SLO_ROUTES" default:"/predict,/predict/simple,/predict/batch,/forecast,/explain,/hierarchy"`
+	AvailabilityTarget float64       `toml:"availability_target" env:"SLO_AVAILABILITY_TARGET" default:"0.999"`
+	LatencyTarget      float64       `toml:"latency_target" env:"SLO_LATENCY_TARGET" default:"0.99"`
+	LatencyThreshold   time.Duration `toml:"latency_threshold" env:"SLO_LATENCY_THRESHOLD" default:"250ms"`
+	LatencyThresholds  string        `toml:"latency_thresholds" env:"SLO_LATENCY_THRESHOLDS" default:"/predict/batch=1s,/forecast=1s,/explain=2s"`
+	Window             time.Duration `toml:"window" env:"SLO_WINDOW" default:"24h"`
+}
+
+// MetricsConfig bounds the cardinality of the prediction metric labels.
+type MetricsConfig struct {
+	FamilyLabel      bool `toml:"family_label" env:"METRICS_FAMILY_LABEL" default:"true"`
+	MaxFamilies      int  `toml:"max_families" env:"METRICS_MAX_FAMILIES" default:"50"`
+	MaxModelVersions int  `toml:"max_model_versions" env:"METRICS_MAX_MODEL_VERSIONS" default:"10"`
+}
+
+// PredictionLogConfig configures the Parquet prediction log.
+type PredictionLogConfig struct {
+	Enabled        bool          `toml:"enabled" env:"PREDICTION_LOG_ENABLED" default:"false"`
+	Dir            string        `toml:"dir" env:"PREDICTION_LOG_DIR" default:"data/prediction_logs"`
+	Buffer         int           `toml:"buffer" env:"PREDICTION_LOG_BUFFER" default:"10000"`
+	BatchSize      int           `toml:"batch_size" env:"PREDICTION_LOG_BATCH_SIZE" default:"500"`
+	FlushInterval  time.Duration `toml:"flush_interval" env:"PREDICTION_LOG_FLUSH_INTERVAL" default:"5s"`
+	MaxRows        int           `toml:"max_rows" env:"PREDICTION_LOG_MAX_ROWS" default:"1000000"`
+	RotateInterval time.Duration `toml:"rotate_interval" env:"PREDICTION_LOG_ROTATE_INTERVAL" default:"1h"`
+}
+
+// IngestConfig configures the streamed feature consumer.
+type IngestConfig struct {
+	Broker         string        `toml:"broker" env:"INGEST_BROKER"`
+	URL            string        `toml:"url" env:"INGEST_URL" secret:"url"`
+	Topic          string        `toml:"topic" env:"INGEST_TOPIC" default:"mlrf.features"`
+	Start          string        `toml:"start" env:"INGEST_START" default:"earliest"`
+	CheckpointPath string        `toml:"checkpoint_path" env:"INGEST_CHECKPOINT_PATH" default:"data/ingest_checkpoint.json"`
+	BatchSize      int           `toml:"batch_size" env:"INGEST_BATCH_SIZE" default:"1000"`
+	FlushInterval  time.Duration `toml:"flush_interval" env:"INGEST_FLUSH_INTERVAL" default:"5s"`
+
+	InvalidateInterval time.Duration `toml:"invalidate_interval" env:"INGEST_INVALIDATE_INTERVAL" default:"1m"`
+}
+
+// RemoteConfig configures downloads of s3:// and gs:// model and feature files.
+type RemoteConfig struct {
+	CacheDir           string        `toml:"cache_dir" env:"REMOTE_CACHE_DIR"`
+	FetchTimeout       time.Duration `toml:"fetch_timeout" env:"REMOTE_FETCH_TIMEOUT" default:"10m"`
+	AWSRegion          string        `toml:"aws_region" env:"AWS_REGION"`
+	AWSDefaultRegion   string        `toml:"aws_default_region" env:"AWS_DEFAULT_REGION"`
+	AWSAccessKeyID     string        `toml:"aws_access_key_id" env:"AWS_ACCESS_KEY_ID" secret:"true"`
+	AWSSecretAccessKey string        `toml:"aws_secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+	AWSSessionToken    string        `toml:"aws_session_token" env:"AWS_SESSION_TOKEN" secret:"true"`
+	AWSEndpointURLS3   string        `toml:"aws_endpoint_url_s3" env:"AWS_ENDPOINT_URL_S3"`
+	GCSAccessToken     string        `toml:"gcs_access_token" env:"GCS_ACCESS_TOKEN" secret:"true"`
+	GCSEndpoint        string        `toml:"gcs_endpoint" env:"GCS_ENDPOINT"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	Enabled      bool          `toml:"enabled" env:"OTEL_ENABLED" default:"true"`
+	Endpoint     string        `toml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"` // localhost:4318, or localhost:4317 over gRPC
+	Protocol     string        `toml:"protocol" env:"OTEL_EXPORTER_PROTOCOL" default:"http"`
+	ServiceName  string        `toml:"service_name" env:"OTEL_SERVICE_NAME" default:"mlrf-api"`
+	Sampler      string        `toml:"sampler" env:"OTEL_TRACES_SAMPLER" default:"traceidratio"`
+	SampleRate   float64       `toml:"sample_rate" env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+	RateLimit    float64       `toml:"rate_limit" env:"OTEL_TRACES_RATE_LIMIT" default:"10"`
+	TailSampling bool          `toml:"tail_sampling" env:"OTEL_TRACES_TAIL_SAMPLING" default:"false"`
+	TailLatency  time.Duration `toml:"tail_latency" env:"OTEL_TRACES_TAIL_LATENCY" default:"500ms"`
+}
+
+// HealthConfig configures the checks /health/ready requires.
+type HealthConfig struct {
+	RequireModel    bool          `toml:"require_model" env:"READY_REQUIRE_MODEL" default:"true"`
+	RequireFeatures bool          `toml:"require_features" env:"READY_REQUIRE_FEATURES" default:"true"`
+	RequireRedis    bool          `toml:"require_redis" env:"READY_REQUIRE_REDIS" default:"true"`
+	RedisTimeout    time.Duration `toml:"redis_timeout" env:"READY_REDIS_TIMEOUT" default:"500ms"`
+}
+
+// Sources of a setting's value, from lowest to highest precedence.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Load reads the configuration: defaults, then the TOML file at path (if path is not
+// empty), then environment variables. The result is validated.
+func Load(path string) (*Config, error) {
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return load(path, file, os.LookupEnv)
+}
+
+// Reload reads the configuration file and environment again, returning the new
+// configuration; c is unchanged, so an invalid file leaves the running configuration
+// in place.
+func (c *Config) Reload() (*Config, error) {
+	file, err := readFile(c.file)
+	if err != nil {
+		return nil, err
+	}
+	return load(c.file, file, os.LookupEnv)
+}
+
+// readFile parses the TOML file at path, or returns no values if path is empty.
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	file, err := parseTOML(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// load builds a Config from parsed file values and an environment lookup.
+func load(path string, file map[string]string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := &Config{file: path, sources: make(map[string]string), raw: make(map[string]string)}
+	known := make(map[string]bool)
+
+	var errs []string
+	cfg.each(func(section string, f field) {
+		key := section + "." + f.toml
+		known[key] = true
+
+		val, source := f.def, SourceDefault
+		if v, ok := file[key]; ok {
+			val, source = v, SourceFile
+			cfg.raw[f.env] = v
+		}
+		if v, ok := lookupEnv(f.env); ok && v != "" {
+			val, source = v, SourceEnv
+		}
+		cfg.sources[f.env] = source
+		if err := f.set(val); err != nil {
+			errs = append(errs, fmt.Sprintf("%s (%s, from %s): %v", key, f.env, source, err))
+		}
+	})
+
+	var unknown []string
+	for key := range file {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Sprintf("%s: unknown setting", key))
+	}
+
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return cfg, nil
+}
+
+// validate checks settings a type alone doesn't constrain.
+func (c *Config) validate() []string {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535")
+	if _, err := zerolog.ParseLevel(c.Server.LogLevel); err != nil || c.Server.LogLevel == "" {
+		check(false, "server.log_level must be trace, debug, info, warn, error, fatal, panic or disabled")
+	}
+	check(c.Server.LogFormat == "console" || c.Server.LogFormat == "json", "server.log_format must be console or json")
+	check(c.Server.LogSampleRate <= 1, "server.log_sample_rate must be between 0 and 1")
+	check(c.Server.AccessLogMaxSize > 0, "server.access_log_max_size_mb must be positive")
+	if _, err := middleware.ParseClassLimits(c.Server.RateLimitClasses); err != nil {
+		check(false, "server.rate_limit_classes: %v", err)
+	}
+	if _, err := middleware.ParseTrustedProxies(c.Server.TrustedProxies); err != nil {
+		check(false, "server.trusted_proxies: %v", err)
+	}
+	check(c.Server.RequestTimeout > 0, "server.request_timeout must be positive")
+	if _, err := middleware.ParseTimeouts(c.Server.RequestTimeouts); err != nil {
+		check(false, "server.request_timeouts: %v", err)
+	}
+	check(c.Server.ConcurrencyLimit >= 0, "server.concurrency_limit must not be negative")
+	check(c.Server.ConcurrencyQueue >= 0, "server.concurrency_max_queue must not be negative")
+	check(c.Server.ConcurrencyWait >= 0, "server.concurrency_max_wait must not be negative")
+	check(c.Server.MockFallbacks == "allow" || c.Server.MockFallbacks == "deny", "server.mock_fallbacks must be allow or deny")
+	if c.Server.LegacySunset != "" {
+		_, err := time.Parse("2006-01-02", c.Server.LegacySunset)
+		check(err == nil, "server.legacy_sunset must be a YYYY-MM-DD date")
+	}
+
+	for _, h := range strings.Split(c.Predictions.Horizons, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(h))
+		check(err == nil && days >= 1 && days <= 365, "predictions.horizons: %q is not a number of days between 1 and 365", strings.TrimSpace(h))
+	}
+	check(c.Predictions.Min <= c.Predictions.Max, "predictions.min must not exceed predictions.max")
+	switch c.Predictions.NaNPolicy {
+	case "clamp", "zero", "error":
+	default:
+		check(false, "predictions.nan_policy must be clamp, zero or error")
+	}
+
+	switch c.Features.Backend {
+	case "memory", "duckdb", "sqlite":
+	default:
+		check(false, "features.backend must be memory, duckdb or sqlite")
+	}
+	check(c.Features.QualityMode == "reject" || c.Features.QualityMode == "degrade", "features.quality_mode must be reject or degrade")
+	check(c.Features.QualityMaxNullRate >= 0 && c.Features.QualityMaxNullRate <= 1, "features.quality_max_null_rate must be between 0 and 1")
+	check(c.Features.QualityMaxOutlierRate >= 0 && c.Features.QualityMaxOutlierRate <= 1, "features.quality_max_outlier_rate must be between 0 and 1")
+	if c.Features.RefreshCron != "" {
+		_, err := refresh.ParseSchedule(c.Features.RefreshCron)
+		check(err == nil, "features.refresh_cron: %v", err)
+	}
+	_, err := time.LoadLocation(c.Features.RefreshTZ)
+	check(err == nil, "features.refresh_tz: unknown time zone %q", c.Features.RefreshTZ)
+
+	switch c.Alerts.AnomalyMethod {
+	case anomaly.MethodAuto, anomaly.MethodInterval, anomaly.MethodZScore:
+	default:
+		check(false, "alerts.anomaly_method must be %s, %s or %s", anomaly.MethodAuto, anomaly.MethodInterval, anomaly.MethodZScore)
+	}
+	check(c.Alerts.AnomalyZThreshold > 0, "alerts.anomaly_z_threshold must be positive")
+	check(c.Alerts.AnomalyCriticalZ >= c.Alerts.AnomalyZThreshold, "alerts.anomaly_critical_z must not be below alerts.anomaly_z_threshold")
+	check(c.Alerts.AnomalyMinBaseline >= 2, "alerts.anomaly_min_baseline must be at least 2")
+	check(c.Alerts.AnomalyWindowDays <= anomaly.MaxWindowDays, "alerts.anomaly_window_days must be at most %d", anomaly.MaxWindowDays)
+
+	check(c.SLO.AvailabilityTarget > 0 && c.SLO.AvailabilityTarget < 1, "slo.availability_target must be between 0 and 1, exclusive")
+	check(c.SLO.LatencyTarget > 0 && c.SLO.LatencyTarget < 1, "slo.latency_target must be between 0 and 1, exclusive")
+	_, err = slo.ParseThresholds(c.SLO.LatencyThresholds)
+	check(err == nil, "slo.latency_thresholds: %v", err)
+	check(c.SLO.Window >= time.Minute, "slo.window must be at least 1m")
+
+	check(c.Metrics.MaxFamilies > 0, "metrics.max_families must be positive")
+	check(c.Metrics.MaxModelVersions > 0, "metrics.max_model_versions must be positive")
+
+	check(c.Tracing.Protocol == tracing.ProtocolHTTP || c.Tracing.Protocol == tracing.ProtocolGRPC, "tracing.protocol must be http or grpc")
+	switch c.Tracing.Sampler {
+	case tracing.SamplerAlwaysOn, tracing.SamplerRatio, tracing.SamplerParentBasedRatio,
+		tracing.SamplerRateLimited, tracing.SamplerParentBasedRateLimited:
+	default:
+		check(false, "tracing.sampler must be %s, %s, %s, %s or %s", tracing.SamplerAlwaysOn, tracing.SamplerRatio,
+			tracing.SamplerParentBasedRatio, tracing.SamplerRateLimited, tracing.SamplerParentBasedRateLimited)
+	}
+	check(c.Tracing.SampleRate <= 1, "tracing.sample_rate must be between 0 and 1")
+	check(c.Tracing.RateLimit > 0, "tracing.rate_limit must be positive")
+
+	switch c.Ingest.Broker {
+	case "", ingest.BrokerKafka, ingest.BrokerNATS:
+	default:
+		check(false, "ingest.broker must be %s or %s", ingest.BrokerKafka, ingest.BrokerNATS)
+	}
+	check(c.Ingest.Broker == "" || c.Ingest.URL != "", "ingest.url is required with ingest.broker")
+	check(c.Ingest.Start == ingest.StartEarliest || c.Ingest.Start == ingest.StartLatest, "ingest.start must be %s or %s", ingest.StartEarliest, ingest.StartLatest)
+	check(c.Ingest.InvalidateInterval >= 0, "ingest.invalidate_interval must not be negative")
+
+	check(c.Data.SHAPBreakerHalfOpenProbes >= 1, "data.shap_breaker_half_open_probes must be at least 1")
+
+	check(c.Cache.Compression == cache.CompressionNone || c.Cache.Compression == cache.CompressionSnappy, "cache.compression must be none or snappy")
+	check(c.Cache.TTLJitter <= 1, "cache.ttl_jitter must be between 0 and 1")
+	check(c.Cache.EarlyExpiry <= 1, "cache.early_expiry must be between 0 and 1")
+	_, err = cache.ParseTTLRules(c.Cache.TTLPolicy)
+	check(err == nil, "cache.ttl_policy: %v", err)
+	_, err = time.Parse("2006-01-02", c.Cache.WarmDate)
+	check(err == nil, "cache.warm_date must be a YYYY-MM-DD date")
+
+	if c.Cache.RedisURL != "" {
+		_, err := url.Parse(c.Cache.RedisURL)
+		check(err == nil, "cache.redis_url is not a URL")
+	}
+	return errs
+}
+
+// Change is a setting whose value differs between two configurations.
+type Change struct {
+	Key        string `json:"key"` // section.key
+	Env        string `json:"env"`
+	Reloadable bool   `json:"reloadable"` // false if it takes effect only after a restart
+}
+
+// Changes returns the settings whose values differ in next, in declaration order.
+func (c *Config) Changes(next *Config) []Change {
+	prev := make(map[string]interface{})
+	c.each(func(section string, f field) {
+		prev[f.env] = f.value.Interface()
+	})
+
+	var changes []Change
+	next.each(func(section string, f field) {
+		if reflect.DeepEqual(prev[f.env], f.value.Interface()) {
+			return
+		}
+		changes = append(changes, Change{Key: section + "." + f.toml, Env: f.env, Reloadable: f.reload})
+	})
+	return changes
+}
+
+// File returns the path of the configuration file, or "" if none was loaded.
+func (c *Config) File() string {
+	return c.file
+}
+
+// Setting is one effective setting as reported by Redacted.
+type Setting struct {
+	Env    string      `json:"env"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"` // default, file or env
+}
+
+// Redacted returns every setting by section and key with secrets masked: secret
+// values read "[REDACTED]" when set, and passwords are removed from secret URLs.
+func (c *Config) Redacted() map[string]map[string]Setting {
+	out := make(map[string]map[string]Setting)
+	c.each(func(section string, f field) {
+		if out[section] == nil {
+			out[section] = make(map[string]Setting)
+		}
+		out[section][f.toml] = Setting{Env: f.env, Value: f.redacted(), Source: c.sources[f.env]}
+	})
+	return out
+}
+
+// field is one setting of a Config section.
+type field struct {
+	value  reflect.Value
+	toml   string
+	env    string
+	def    string
+	secret string
+	reload bool
+}
+
+// each calls fn for every setting in declaration order.
+func (c *Config) each(fn func(section string, f field)) {
+	root := reflect.ValueOf(c).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		sf := root.Type().Field(i)
+		section := sf.Tag.Get("toml")
+		if section == "" {
+			continue
+		}
+		sv := root.Field(i)
+		for j := 0; j < sv.NumField(); j++ {
+			tag := sv.Type().Field(j).Tag
+			fn(section, field{
+				value:  sv.Field(j),
+				toml:   tag.Get("toml"),
+				env:    tag.Get("env"),
+				def:    tag.Get("default"),
+				secret: tag.Get("secret"),
+				reload: tag.Get("reload") == "true",
+			})
+		}
+	}
+}
+
+// set parses s into the field. Numbers and durations must be positive; an int without
+// a default may be unset, leaving it zero.
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case bool:
+		if s == "" {
+			f.value.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		f.value.SetBool(b)
+	case int:
+		if s == "" {
+			f.value.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%q is not a positive integer", s)
+		}
+		f.value.SetInt(int64(n))
+	case float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil || x < 0 {
+			return fmt.Errorf("%q is not a non-negative number", s)
+		}
+		f.value.SetFloat(x)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%q is not a positive duration", s)
+		}
+		f.value.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// redacted returns the field's value for display, masking secrets.
+func (f field) redacted() interface{} {
+	v := f.value.Interface()
+	switch f.secret {
+	case "true":
+		if s, _ := v.(string); s != "" {
+			return "[REDACTED]"
+		}
+	case "url":
+		if s, _ := v.(string); s != "" {
+			if u, err := url.Parse(s); err == nil {
+				return u.Redacted()
+			}
+			return "[REDACTED]"
+		}
+	}
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by configuration files: [section] tables
+// and key = value pairs, where a value is a basic "string", a number or a boolean.
+// Comments start with #. It returns values by "section.key" as strings.
+func parseTOML(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", n+1)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if !isBareKey(section) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", n+1, section)
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		if !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", n+1, key)
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: %s must be inside a [section]", n+1, key)
+		}
+
+		val, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n+1, key, err)
+		}
+		full := section + "." + key
+		if _, dup := values[full]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", n+1, full)
+		}
+		values[full] = val
+	}
+	return values, nil
+}
+
+// parseValue decodes a quoted string, or returns a bare number or boolean as written.
+func parseValue(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"`) {
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	}
+	if raw == "true" || raw == "false" {
+		return raw, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+	return "", fmt.Errorf("invalid value %q (quote strings and durations)", raw)
+}
+
+// stripComment removes a # comment that isn't inside a quoted string.
+func stripComment(line string) string {
+	inString, escaped := false, false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inString:
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case r == '#' && !inString:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isBareKey reports whether s is a non-empty key of letters, digits, _ and -.
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
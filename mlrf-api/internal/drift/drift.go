@@ -0,0 +1,191 @@
+// Package drift compares recent feature distributions against a training reference
+// using the population stability index (PSI) and the Kolmogorov-Smirnov statistic.
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// psiEpsilon replaces empty bin proportions so PSI stays finite.
+const psiEpsilon = 1e-4
+
+// Config holds feature drift configuration.
+type Config struct {
+	ReferencePath string  // Training reference distributions
+	WindowDays    int     // Recent days of the feature store compared by default
+	PSIThreshold  float64 // PSI above which a feature is flagged
+	KSThreshold   float64 // KS statistic above which a feature is flagged
+}
+
+// DefaultConfig returns the default feature drift configuration.
+func DefaultConfig() Config {
+	return Config{
+		ReferencePath: "models/feature_reference.json",
+		WindowDays:    28,
+		PSIThreshold:  0.2,
+		KSThreshold:   0.1,
+	}
+}
+
+// FeatureReference is the training distribution of one feature as a histogram.
+// Edges are the k-1 interior cut points of k bins: bin 0 holds values below Edges[0],
+// bin i values in [Edges[i-1], Edges[i]) and the last bin values at or above the
+// last edge. Proportions holds the training share of each bin.
+type FeatureReference struct {
+	Name        string    `json:"name"`
+	Edges       []float64 `json:"edges"`
+	Proportions []float64 `json:"proportions"`
+	Mean        float64   `json:"mean"`
+	Std         float64   `json:"std"`
+}
+
+// Reference holds training distributions for the model's features.
+type Reference struct {
+	Source   string             `json:"source,omitempty"` // e.g. the training window
+	Features []FeatureReference `json:"features"`
+
+	byName map[string]*FeatureReference
+}
+
+// LoadReference reads and validates a reference JSON file.
+func LoadReference(path string) (*Reference, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReference(data)
+}
+
+// ParseReference parses and validates reference JSON.
+func ParseReference(data []byte) (*Reference, error) {
+	var ref Reference
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("parse feature reference: %w", err)
+	}
+	if len(ref.Features) == 0 {
+		return nil, errors.New("feature reference has no features")
+	}
+
+	ref.byName = make(map[string]*FeatureReference, len(ref.Features))
+	for i := range ref.Features {
+		f := &ref.Features[i]
+		if f.Name == "" {
+			return nil, fmt.Errorf("feature reference %d has no name", i)
+		}
+		if _, dup := ref.byName[f.Name]; dup {
+			return nil, fmt.Errorf("duplicate feature reference %q", f.Name)
+		}
+		if len(f.Proportions) != len(f.Edges)+1 {
+			return nil, fmt.Errorf("feature %q has %d proportions for %d edges (want edges+1)", f.Name, len(f.Proportions), len(f.Edges))
+		}
+		for j := 1; j < len(f.Edges); j++ {
+			if f.Edges[j] <= f.Edges[j-1] {
+				return nil, fmt.Errorf("feature %q edges must be strictly increasing", f.Name)
+			}
+		}
+		var sum float64
+		for _, p := range f.Proportions {
+			if p < 0 {
+				return nil, fmt.Errorf("feature %q has a negative proportion", f.Name)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 0.01 {
+			return nil, fmt.Errorf("feature %q proportions sum to %.4f, want 1", f.Name, sum)
+		}
+		ref.byName[f.Name] = f
+	}
+	return &ref, nil
+}
+
+// Feature returns the reference for a feature name.
+func (r *Reference) Feature(name string) (*FeatureReference, bool) {
+	f, ok := r.byName[name]
+	return f, ok
+}
+
+// FeatureDrift is the drift of one feature between the reference and a window.
+type FeatureDrift struct {
+	Name          string  `json:"name"`
+	PSI           float64 `json:"psi"`
+	KS            float64 `json:"ks"` // Max CDF difference at the reference bin edges
+	Mean          float64 `json:"mean"`
+	ReferenceMean float64 `json:"reference_mean"`
+	Drifted       bool    `json:"drifted"`
+}
+
+// Compare computes the drift of each named feature over rows, where rows[i][j] is
+// feature names[j]. Features without a reference are skipped.
+func Compare(ref *Reference, names []string, rows [][]float32, psiThreshold, ksThreshold float64) []FeatureDrift {
+	results := make([]FeatureDrift, 0, len(names))
+	for j, name := range names {
+		fr, ok := ref.Feature(name)
+		if !ok {
+			continue
+		}
+
+		counts := make([]float64, len(fr.Proportions))
+		var sum float64
+		for _, row := range rows {
+			if j >= len(row) {
+				continue
+			}
+			v := float64(row[j])
+			counts[bin(fr.Edges, v)]++
+			sum += v
+		}
+
+		d := FeatureDrift{Name: name, ReferenceMean: fr.Mean}
+		if len(rows) > 0 {
+			d.Mean = sum / float64(len(rows))
+			for i := range counts {
+				counts[i] /= float64(len(rows))
+			}
+			d.PSI = psi(fr.Proportions, counts)
+			d.KS = ks(fr.Proportions, counts)
+		}
+		d.Drifted = d.PSI > psiThreshold || d.KS > ksThreshold
+		results = append(results, d)
+	}
+	return results
+}
+
+// bin returns the histogram bin of v for the given interior edges.
+func bin(edges []float64, v float64) int {
+	lo, hi := 0, len(edges)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if v >= edges[mid] {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// psi is the population stability index: sum((actual - expected) * ln(actual / expected)).
+func psi(expected, actual []float64) float64 {
+	var total float64
+	for i := range expected {
+		e := math.Max(expected[i], psiEpsilon)
+		a := math.Max(actual[i], psiEpsilon)
+		total += (a - e) * math.Log(a/e)
+	}
+	return total
+}
+
+// ks is the largest difference between the cumulative bin proportions.
+func ks(expected, actual []float64) float64 {
+	var ce, ca, maxDiff float64
+	for i := range expected {
+		ce += expected[i]
+		ca += actual[i]
+		maxDiff = math.Max(maxDiff, math.Abs(ce-ca))
+	}
+	return maxDiff
+}
@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testReference = `{
+	"source": "train 2013-01-01 to 2017-06-30",
+	"features": [
+		{"name": "oil_price", "edges": [40, 50], "proportions": [0.25, 0.5, 0.25], "mean": 45},
+		{"name": "onpromotion", "edges": [1], "proportions": [0.5, 0.5], "mean": 1}
+	]
+}`
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference([]byte(testReference))
+	if err != nil {
+		t.Fatalf("ParseReference failed: %v", err)
+	}
+	if f, ok := ref.Feature("oil_price"); !ok || f.Mean != 45 {
+		t.Errorf("expected oil_price reference, got %+v", f)
+	}
+
+	invalid := []struct {
+		name, json, want string
+	}{
+		{"no features", `{"features": []}`, "no features"},
+		{"bins", `{"features": [{"name": "a", "edges": [1], "proportions": [1]}]}`, "want edges+1"},
+		{"sum", `{"features": [{"name": "a", "edges": [1], "proportions": [0.2, 0.2]}]}`, "sum to"},
+		{"order", `{"features": [{"name": "a", "edges": [2, 1], "proportions": [0.2, 0.4, 0.4]}]}`, "increasing"},
+		{"duplicate", `{"features": [{"name": "a", "proportions": [1]}, {"name": "a", "proportions": [1]}]}`, "duplicate"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseReference([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reference.json")
+	if err := os.WriteFile(path, []byte(testReference), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := LoadReference(path)
+	if err != nil {
+		t.Fatalf("LoadReference failed: %v", err)
+	}
+	if len(ref.Features) != 2 {
+		t.Errorf("expected 2 features, got %d", len(ref.Features))
+	}
+	if _, err := LoadReference(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ref, err := ParseReference([]byte(testReference))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"oil_price", "onpromotion", "cluster"}
+
+	// oil_price matches the reference exactly; onpromotion moved entirely into the upper bin
+	rows := [][]float32{
+		{35, 1, 0},
+		{45, 1, 0},
+		{48, 2, 0},
+		{60, 3, 0},
+	}
+	results := Compare(ref, names, rows, 0.2, 0.1)
+	if len(results) != 2 {
+		t.Fatalf("expected features without a reference to be skipped, got %+v", results)
+	}
+
+	oil := results[0]
+	if oil.PSI > 1e-9 || oil.KS > 1e-9 || oil.Drifted {
+		t.Errorf("expected no drift for oil_price, got %+v", oil)
+	}
+	if oil.Mean != 47 || oil.ReferenceMean != 45 {
+		t.Errorf("unexpected oil_price means: %+v", oil)
+	}
+
+	promo := results[1]
+	wantPSI := (psiEpsilon-0.5)*math.Log(psiEpsilon/0.5) + (1-0.5)*math.Log(1/0.5)
+	if math.Abs(promo.PSI-wantPSI) > 1e-9 {
+		t.Errorf("expected PSI %v, got %v", wantPSI, promo.PSI)
+	}
+	if math.Abs(promo.KS-0.5) > 1e-9 || !promo.Drifted {
+		t.Errorf("expected KS 0.5 and drift for onpromotion, got %+v", promo)
+	}
+}
+
+func TestBin(t *testing.T) {
+	edges := []float64{10, 20}
+	for v, want := range map[float64]int{5: 0, 10: 1, 15: 1, 20: 2, 25: 2} {
+		if got := bin(edges, v); got != want {
+			t.Errorf("bin(%v) = %d, want %d", v, got, want)
+		}
+	}
+}
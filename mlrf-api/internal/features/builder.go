@@ -0,0 +1,325 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// MaxRollForwardDays bounds how far past the feature matrix features are rolled forward.
+const MaxRollForwardDays = 365
+
+// ErrRollForwardLimit is returned when a date lies more than MaxRollForwardDays past a series.
+var ErrRollForwardLimit = errors.New("date too far beyond feature matrix")
+
+// maxLagWindow is the longest lag/rolling window used by the model (days).
+const maxLagWindow = 90
+
+// Positions in the feature vector, looked up in the shared schema.
+var (
+	idxYear       = featureIndex("year")
+	idxMonth      = featureIndex("month")
+	idxDay        = featureIndex("day")
+	idxDayOfWeek  = featureIndex("dayofweek")
+	idxDayOfYear  = featureIndex("dayofyear")
+	idxIsMidMonth = featureIndex("is_mid_month")
+	idxIsLeapYear = featureIndex("is_leap_year")
+	idxIsHoliday  = featureIndex("is_holiday")
+	idxCluster    = featureIndex("cluster")
+	idxSalesLag1  = featureIndex("sales_lag_1")
+)
+
+// lagFeatures maps feature positions to lag periods in days.
+var lagFeatures = []struct{ idx, days int }{
+	{featureIndex("sales_lag_1"), 1},
+	{featureIndex("sales_lag_7"), 7},
+	{featureIndex("sales_lag_14"), 14},
+	{featureIndex("sales_lag_28"), 28},
+	{featureIndex("sales_lag_90"), 90},
+}
+
+// rollingFeatures maps feature positions to rolling window sizes in days.
+var rollingFeatures = []struct{ meanIdx, stdIdx, window int }{
+	{featureIndex("sales_rolling_mean_7"), featureIndex("sales_rolling_std_7"), 7},
+	{featureIndex("sales_rolling_mean_14"), featureIndex("sales_rolling_std_14"), 14},
+	{featureIndex("sales_rolling_mean_28"), featureIndex("sales_rolling_std_28"), 28},
+	{featureIndex("sales_rolling_mean_90"), featureIndex("sales_rolling_std_90"), 90},
+}
+
+// featureIndex returns the position of a schema column, panicking on an unknown name
+// so a renamed column fails at startup rather than indexing the wrong feature.
+func featureIndex(name string) int {
+	i, ok := schema.Features.Index(name)
+	if !ok {
+		panic("features: no schema column " + name)
+	}
+	return i
+}
+
+// Predictor runs model inference on a feature vector.
+type Predictor func(features []float32) (float32, error)
+
+// Adjustment returns a modified copy of the feature vector of a series day, for
+// scenarios such as what-if analysis over a forecast window.
+type Adjustment func(features []float32) []float32
+
+// HolidayCalendar reports the holidays flagged in the is_holiday feature.
+type HolidayCalendar interface {
+	IsHoliday(date time.Time) bool
+}
+
+// FeatureBuilder computes features for dates beyond the loaded feature matrix.
+// It rolls forward from the last known window of a series, feeding prior model
+// predictions back in as sales so lags and rolling statistics stay realistic.
+type FeatureBuilder struct {
+	store      *Store
+	adjust     Adjustment
+	holidays   HolidayCalendar
+	regressors []*Regressors // applied in order, so later values win
+}
+
+// NewFeatureBuilder creates a builder backed by store. A nil store is allowed;
+// series then start from zero features.
+func NewFeatureBuilder(store *Store) *FeatureBuilder {
+	return &FeatureBuilder{store: store}
+}
+
+// WithAdjustment returns a builder that applies adjust to the features of every day
+// Series returns before predicting it. Days rolled through to reach start are not
+// adjusted; the adjusted predictions feed the lags of the following days.
+func (b *FeatureBuilder) WithAdjustment(adjust Adjustment) *FeatureBuilder {
+	c := *b
+	c.adjust = adjust
+	return &c
+}
+
+// WithHolidays returns a builder that sets is_holiday of rolled-forward days from
+// holidays rather than keeping the value of the last known row. Feature matrix rows
+// are returned as they are.
+func (b *FeatureBuilder) WithHolidays(holidays HolidayCalendar) *FeatureBuilder {
+	c := *b
+	c.holidays = holidays
+	return &c
+}
+
+// WithRegressors returns a builder that sets the supplied regressor values of every
+// day it builds, known or rolled forward, before any adjustment. Values of r take
+// precedence over those of regressors added before it. A nil r is ignored.
+func (b *FeatureBuilder) WithRegressors(r *Regressors) *FeatureBuilder {
+	c := *b
+	if r != nil {
+		c.regressors = append(append([]*Regressors(nil), b.regressors...), r)
+	}
+	return &c
+}
+
+// Build returns features for a single (store, family, date).
+// Dates present in the feature matrix are returned as-is (rolled=false).
+// Dates after the series' last known date are rolled forward (rolled=true).
+func (b *FeatureBuilder) Build(storeNbr int, family, date string, predict Predictor) ([]float32, bool, error) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	if b.store != nil {
+		if f, ok := b.store.exact(storeNbr, family, d); ok {
+			return b.applyRegressors(f, storeNbr, family, d), false, nil
+		}
+	}
+
+	feats, _, err := b.Series(storeNbr, family, d, 1, predict)
+	if err != nil {
+		return nil, false, err
+	}
+	return feats[0], true, nil
+}
+
+// Series returns feature vectors and predictions for days consecutive days from start.
+// Known dates use feature matrix rows; later dates are rolled forward from the last
+// known row, with every intermediate day predicted to extend the sales history.
+func (b *FeatureBuilder) Series(storeNbr int, family string, start time.Time, days int, predict Predictor) ([][]float32, []float32, error) {
+	if days <= 0 {
+		return nil, nil, nil
+	}
+
+	template, lastDate, sales, known := b.seed(storeNbr, family, start)
+	if gap := dayNumber(start) - dayNumber(lastDate); gap > MaxRollForwardDays {
+		return nil, nil, fmt.Errorf("%w: %s is %d days past %s (max %d)", ErrRollForwardLimit,
+			start.Format("2006-01-02"), gap, lastDate.Format("2006-01-02"), MaxRollForwardDays)
+	}
+
+	// Begin at the last known date so its prediction seeds the rolled history
+	d := start
+	if known && start.After(lastDate) {
+		d = lastDate
+	}
+	end := start.AddDate(0, 0, days-1)
+
+	features := make([][]float32, 0, days)
+	predictions := make([]float32, 0, days)
+	for ; !d.After(end); d = d.AddDate(0, 0, 1) {
+		var f []float32
+		if b.store != nil {
+			f, _ = b.store.exact(storeNbr, family, d)
+		}
+		if f == nil {
+			f = rollFeatures(template, d, sales)
+			if b.holidays != nil {
+				f[idxIsHoliday] = boolToFloat(b.holidays.IsHoliday(d))
+			}
+		}
+		f = b.applyRegressors(f, storeNbr, family, d)
+		if b.adjust != nil && !d.Before(start) {
+			f = b.adjust(f)
+		}
+
+		pred, err := predict(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("predict %s: %w", d.Format("2006-01-02"), err)
+		}
+
+		// Actual sales take precedence over predictions in the history
+		if _, ok := sales[dayNumber(d)]; !ok {
+			sales[dayNumber(d)] = pred
+		}
+
+		if !d.Before(start) {
+			features = append(features, f)
+			predictions = append(predictions, pred)
+		}
+	}
+	return features, predictions, nil
+}
+
+// applyRegressors returns f with the supplied regressor values of the series on date.
+func (b *FeatureBuilder) applyRegressors(f []float32, storeNbr int, family string, date time.Time) []float32 {
+	for _, r := range b.regressors {
+		f = r.Apply(f, storeNbr, family, date)
+	}
+	return f
+}
+
+// seed returns the template row, last known date and actual sales history for a series.
+// Unknown series (known=false) start from zero features on the day before start.
+func (b *FeatureBuilder) seed(storeNbr int, family string, start time.Time) (template []float32, lastDate time.Time, sales map[int]float32, known bool) {
+	sales = make(map[int]float32)
+	if b.store != nil {
+		lastDate, known = b.store.LastDate(storeNbr, family)
+	}
+	if !known {
+		return make([]float32, NumFeatures), start.AddDate(0, 0, -1), sales, false
+	}
+	template, _ = b.store.exact(storeNbr, family, lastDate)
+
+	// Sales on day d-1 is sales_lag_1 of day d; lag_k of the last row reaches further back
+	for i := 0; i <= maxLagWindow; i++ {
+		d := lastDate.AddDate(0, 0, -i)
+		row, ok := b.store.exact(storeNbr, family, d)
+		if !ok {
+			continue
+		}
+		sales[dayNumber(d)-1] = row[idxSalesLag1]
+		if i == 0 {
+			for _, lag := range lagFeatures {
+				if _, ok := sales[dayNumber(d)-lag.days]; !ok {
+					sales[dayNumber(d)-lag.days] = row[lag.idx]
+				}
+			}
+		}
+	}
+	return template, lastDate, sales, true
+}
+
+// rollFeatures derives features for date from template using the sales history.
+// Lags and rolling windows missing history keep the template value.
+func rollFeatures(template []float32, date time.Time, sales map[int]float32) []float32 {
+	f := make([]float32, len(template))
+	copy(f, template)
+	SetCalendarFeatures(f, date)
+
+	day := dayNumber(date)
+	for _, lag := range lagFeatures {
+		if v, ok := sales[day-lag.days]; ok {
+			f[lag.idx] = v
+		}
+	}
+
+	for _, roll := range rollingFeatures {
+		values := make([]float64, 0, roll.window)
+		for k := 1; k <= roll.window; k++ {
+			if v, ok := sales[day-k]; ok {
+				values = append(values, float64(v))
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		mean, std := meanStd(values)
+		f[roll.meanIdx] = float32(mean)
+		if len(values) > 1 {
+			f[roll.stdIdx] = float32(std)
+		}
+	}
+	return f
+}
+
+// SetCalendarFeatures writes the date-derived features for date into f.
+// Day of week follows the pipeline convention (Monday=1 ... Sunday=7).
+func SetCalendarFeatures(f []float32, date time.Time) {
+	dow := int(date.Weekday())
+	if dow == 0 {
+		dow = 7
+	}
+	year := date.Year()
+	isLeap := year%4 == 0 && (year%100 != 0 || year%400 == 0)
+
+	f[idxYear] = float32(year)
+	f[idxMonth] = float32(date.Month())
+	f[idxDay] = float32(date.Day())
+	f[idxDayOfWeek] = float32(dow)
+	f[idxDayOfYear] = float32(date.YearDay())
+	f[idxIsMidMonth] = boolToFloat(date.Day() == 15)
+	f[idxIsLeapYear] = boolToFloat(isLeap)
+}
+
+// meanStd returns the mean and sample standard deviation (ddof=1) of values.
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// dayNumber returns the number of days since the Unix epoch for date.
+func dayNumber(date time.Time) int {
+	y, m, d := date.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// boolToFloat converts a boolean flag to a 0/1 feature value.
+func boolToFloat(b bool) float32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// exact returns the indexed feature vector for a specific date, without fallback.
+func (s *Store) exact(storeNbr int, family string, date time.Time) ([]float32, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.source().Features(storeNbr, family, date)
+}
@@ -0,0 +1,215 @@
+package features
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// newSeriesStore builds a store with one series for 2017-08-01..2017-08-10
+// where actual sales on day d of August are d*10.
+func newSeriesStore() *Store {
+	s := &Store{cols: newColumns(10), loaded: true}
+	for day := 1; day <= 10; day++ {
+		date := time.Date(2017, 8, day, 0, 0, 0, 0, time.UTC)
+		f := make([]float32, NumFeatures)
+		SetCalendarFeatures(f, date)
+		f[idxSalesLag1] = float32((day - 1) * 10)
+		f[11] = 13 // cluster
+		s.cols.put(1, "GROCERY I", date, f)
+	}
+	return s
+}
+
+func constantPredictor(value float32, calls *int) Predictor {
+	return func([]float32) (float32, error) {
+		*calls++
+		return value, nil
+	}
+}
+
+func TestBuildKnownDate(t *testing.T) {
+	b := NewFeatureBuilder(newSeriesStore())
+	calls := 0
+
+	f, rolled, err := b.Build(1, "GROCERY I", "2017-08-05", constantPredictor(500, &calls))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rolled {
+		t.Error("expected rolled=false for indexed date")
+	}
+	if f[idxSalesLag1] != 40 {
+		t.Errorf("expected sales_lag_1=40, got %v", f[idxSalesLag1])
+	}
+	if calls != 0 {
+		t.Errorf("expected no inference for indexed date, got %d calls", calls)
+	}
+}
+
+func TestBuildRollsForward(t *testing.T) {
+	b := NewFeatureBuilder(newSeriesStore())
+	calls := 0
+
+	f, rolled, err := b.Build(1, "GROCERY I", "2017-08-11", constantPredictor(500, &calls))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rolled {
+		t.Error("expected rolled=true for out-of-sample date")
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 inference calls (last known day + target), got %d", calls)
+	}
+
+	if f[idxDay] != 11 || f[idxDayOfWeek] != 5 {
+		t.Errorf("expected day=11 dayofweek=5 (Friday), got %v %v", f[idxDay], f[idxDayOfWeek])
+	}
+	if f[idxSalesLag1] != 500 {
+		t.Errorf("expected sales_lag_1 from prediction of 2017-08-10, got %v", f[idxSalesLag1])
+	}
+	if f[13] != 40 {
+		t.Errorf("expected sales_lag_7 = actual sales of 2017-08-04 (40), got %v", f[13])
+	}
+	if f[11] != 13 {
+		t.Errorf("expected static features carried over, got cluster %v", f[11])
+	}
+
+	// Rolling 7-day window: actual 40..90 plus predicted 500
+	values := []float64{40, 50, 60, 70, 80, 90, 500}
+	mean, std := meanStd(values)
+	if math.Abs(float64(f[17])-mean) > 1e-3 {
+		t.Errorf("expected rolling_mean_7 %v, got %v", mean, f[17])
+	}
+	if math.Abs(float64(f[21])-std) > 1e-3 {
+		t.Errorf("expected rolling_std_7 %v, got %v", std, f[21])
+	}
+}
+
+func TestSeriesUnknownSeries(t *testing.T) {
+	b := NewFeatureBuilder(nil)
+	calls := 0
+	start := time.Date(2017, 8, 16, 0, 0, 0, 0, time.UTC)
+
+	features, predictions, err := b.Series(1, "GROCERY I", start, 3, constantPredictor(100, &calls))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(features) != 3 || len(predictions) != 3 || calls != 3 {
+		t.Fatalf("expected 3 features, predictions and calls, got %d %d %d", len(features), len(predictions), calls)
+	}
+	if features[0][idxSalesLag1] != 0 {
+		t.Errorf("expected zero sales_lag_1 on first day, got %v", features[0][idxSalesLag1])
+	}
+	if features[2][idxSalesLag1] != 100 || features[2][idxDay] != 18 {
+		t.Errorf("expected day 18 with sales_lag_1=100, got %v %v", features[2][idxDay], features[2][idxSalesLag1])
+	}
+}
+
+func TestSeriesRollForwardLimit(t *testing.T) {
+	b := NewFeatureBuilder(newSeriesStore())
+	calls := 0
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _, err := b.Series(1, "GROCERY I", start, 1, constantPredictor(1, &calls))
+	if !errors.Is(err, ErrRollForwardLimit) {
+		t.Errorf("expected ErrRollForwardLimit, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no inference, got %d calls", calls)
+	}
+}
+
+func TestSeriesPredictorError(t *testing.T) {
+	b := NewFeatureBuilder(newSeriesStore())
+	failing := func([]float32) (float32, error) { return 0, errors.New("boom") }
+
+	_, _, err := b.Series(1, "GROCERY I", time.Date(2017, 8, 12, 0, 0, 0, 0, time.UTC), 3, failing)
+	if err == nil {
+		t.Error("expected predictor error to propagate")
+	}
+}
+
+func TestSetCalendarFeatures(t *testing.T) {
+	f := make([]float32, NumFeatures)
+	SetCalendarFeatures(f, time.Date(2016, 5, 15, 0, 0, 0, 0, time.UTC)) // Sunday
+
+	if f[idxYear] != 2016 || f[idxMonth] != 5 || f[idxDay] != 15 {
+		t.Errorf("unexpected date fields: %v", f[:3])
+	}
+	if f[idxDayOfWeek] != 7 {
+		t.Errorf("expected dayofweek 7 (Sunday), got %v", f[idxDayOfWeek])
+	}
+	if f[idxDayOfYear] != 136 {
+		t.Errorf("expected dayofyear 136, got %v", f[idxDayOfYear])
+	}
+	if f[idxIsMidMonth] != 1 || f[idxIsLeapYear] != 1 {
+		t.Errorf("expected is_mid_month=1 and is_leap_year=1, got %v %v", f[idxIsMidMonth], f[idxIsLeapYear])
+	}
+}
+
+func TestSeriesWithAdjustment(t *testing.T) {
+	b := NewFeatureBuilder(newSeriesStore())
+	var predicted []float32
+	predict := func(f []float32) (float32, error) {
+		predicted = append(predicted, f[11])
+		return f[11] * 10, nil
+	}
+	double := func(f []float32) []float32 {
+		adjusted := append([]float32(nil), f...)
+		adjusted[11] *= 2 // cluster
+		return adjusted
+	}
+
+	features, predictions, err := b.WithAdjustment(double).Series(1, "GROCERY I", time.Date(2017, 8, 12, 0, 0, 0, 0, time.UTC), 2, predict)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 2017-08-10 and 08-11 lead in unadjusted; 08-12 and 08-13 are adjusted
+	if len(predicted) != 4 || predicted[0] != 13 || predicted[1] != 13 || predicted[2] != 26 || predicted[3] != 26 {
+		t.Errorf("expected only the returned days adjusted, got %v", predicted)
+	}
+	if features[0][11] != 26 || predictions[0] != 260 {
+		t.Errorf("expected adjusted features and predictions, got %v %v", features[0][11], predictions[0])
+	}
+	// The adjusted prediction of 08-12 is the sales_lag_1 of 08-13
+	if features[1][idxSalesLag1] != 260 {
+		t.Errorf("expected sales_lag_1 260 from the adjusted prediction, got %v", features[1][idxSalesLag1])
+	}
+	if b.adjust != nil {
+		t.Error("expected WithAdjustment to leave the original builder unchanged")
+	}
+}
+
+// holidaySet is a HolidayCalendar of the listed dates.
+type holidaySet map[string]bool
+
+func (s holidaySet) IsHoliday(date time.Time) bool { return s[date.Format("2006-01-02")] }
+
+func TestSeriesWithHolidays(t *testing.T) {
+	store := newSeriesStore()
+	last, _ := store.exact(1, "GROCERY I", time.Date(2017, 8, 10, 0, 0, 0, 0, time.UTC))
+	last[idxIsHoliday] = 1
+	calls := 0
+	start := time.Date(2017, 8, 10, 0, 0, 0, 0, time.UTC)
+
+	// Without a calendar the last known row's flag is carried forward
+	features, _, err := NewFeatureBuilder(store).Series(1, "GROCERY I", start, 3, constantPredictor(100, &calls))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if features[1][idxIsHoliday] != 1 || features[2][idxIsHoliday] != 1 {
+		t.Errorf("expected the frozen is_holiday 1, got %v %v", features[1][idxIsHoliday], features[2][idxIsHoliday])
+	}
+
+	b := NewFeatureBuilder(store).WithHolidays(holidaySet{"2017-08-11": true})
+	features, _, err = b.WithAdjustment(func(f []float32) []float32 { return f }).Series(1, "GROCERY I", start, 3, constantPredictor(100, &calls))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The known 08-10 row is kept; rolled days follow the calendar
+	if features[0][idxIsHoliday] != 1 || features[1][idxIsHoliday] != 1 || features[2][idxIsHoliday] != 0 {
+		t.Errorf("expected is_holiday 1, 1, 0, got %v %v %v", features[0][idxIsHoliday], features[1][idxIsHoliday], features[2][idxIsHoliday])
+	}
+}
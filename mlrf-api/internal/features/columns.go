@@ -0,0 +1,278 @@
+package features
+
+import (
+	"sort"
+	"time"
+)
+
+// columns is the in-memory FeatureProvider built by Store.Load, a feature index in a
+// columnar layout. Every feature vector lives in one contiguous float32 slice, and
+// each (store, family) series maps its dates to row numbers by day ordinal, so no
+// per-row key strings or slice headers are allocated. Rows are immutable once
+// written: replacing a date appends a new row and repoints the series, so vectors
+// already handed to callers never change. Once replaced rows make up a large share
+// of values, the live rows are copied to a new slice (see compact), leaving the old
+// one to callers still holding its vectors and then to the garbage collector.
+type columns struct {
+	values []float32 // Row r holds values[r*NumFeatures : (r+1)*NumFeatures]
+	rows   int       // Rows written to values, including replaced ones
+	live   int       // Distinct (store, family, date) rows
+
+	familyIDs map[string]uint32
+	families  []string
+	series    map[seriesKey]*seriesColumn
+	clusters  map[int]int // store_nbr -> store cluster
+}
+
+// seriesKey identifies a series by store number and interned family ID.
+type seriesKey struct {
+	store  int32
+	family uint32
+}
+
+// seriesColumn indexes the rows of one series.
+type seriesColumn struct {
+	first int32   // Day ordinal of days[0]
+	days  []int32 // Row number for each day from first; -1 where the series has no row
+	last  int32   // Day ordinal of the newest row
+
+	agg  []float32 // Average feature vector (fallback for unknown dates)
+	aggN int       // Rows averaged into agg
+}
+
+// newColumns creates an empty index with room for capacity rows.
+func newColumns(capacity int) *columns {
+	return &columns{
+		values:    make([]float32, 0, capacity*NumFeatures),
+		familyIDs: make(map[string]uint32),
+		series:    make(map[seriesKey]*seriesColumn),
+		clusters:  make(map[int]int),
+	}
+}
+
+// dayOrdinal returns the number of days between the Unix epoch and date's calendar day.
+func dayOrdinal(date time.Time) int32 {
+	y, m, d := date.Date()
+	return int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// ordinalDate converts a day ordinal back to a UTC date.
+func ordinalDate(ord int32) time.Time {
+	return time.Unix(int64(ord)*86400, 0).UTC()
+}
+
+// key returns the series key of (storeNbr, family), interning the family if intern is set.
+func (c *columns) key(storeNbr int, family string, intern bool) (seriesKey, bool) {
+	id, ok := c.familyIDs[family]
+	if !ok {
+		if !intern {
+			return seriesKey{}, false
+		}
+		id = uint32(len(c.families))
+		c.familyIDs[family] = id
+		c.families = append(c.families, family)
+	}
+	return seriesKey{store: int32(storeNbr), family: id}, true
+}
+
+// lookup returns the series column of (storeNbr, family).
+func (c *columns) lookup(storeNbr int, family string) (*seriesColumn, bool) {
+	k, ok := c.key(storeNbr, family, false)
+	if !ok {
+		return nil, false
+	}
+	sc, ok := c.series[k]
+	return sc, ok
+}
+
+// row returns the feature vector of row r, capped so appends by callers cannot
+// overwrite the next row.
+func (c *columns) row(r int32) []float32 {
+	start := int(r) * NumFeatures
+	return c.values[start : start+NumFeatures : start+NumFeatures]
+}
+
+// put stores the feature vector of (storeNbr, family, date) and returns the vector it
+// replaced, if any.
+func (c *columns) put(storeNbr int, family string, date time.Time, features []float32) ([]float32, bool) {
+	k, _ := c.key(storeNbr, family, true)
+	ord := dayOrdinal(date)
+	sc, ok := c.series[k]
+	if !ok {
+		sc = &seriesColumn{first: ord, last: ord}
+		c.series[k] = sc
+	}
+	i := sc.slot(ord)
+
+	r := int32(c.rows)
+	c.values = append(c.values, features[:NumFeatures]...)
+	c.rows++
+
+	var old []float32
+	replaced := sc.days[i] >= 0
+	if replaced {
+		old = c.row(sc.days[i])
+	} else {
+		c.live++
+	}
+	sc.days[i] = r
+	if ord > sc.last {
+		sc.last = ord
+	}
+	if dead := c.rows - c.live; dead > compactMinRows && dead > c.live/compactRatio {
+		c.compact()
+	}
+	return old, replaced
+}
+
+// Replaced rows are reclaimed once there are more than compactMinRows of them and
+// they exceed 1/compactRatio of the live rows, so repeatedly updating the same dates
+// (e.g. from a stream) keeps memory bounded at a copy amortized over the updates.
+const (
+	compactMinRows = 4096
+	compactRatio   = 4
+)
+
+// compact copies the live rows to a new values slice and renumbers the series.
+func (c *columns) compact() {
+	values := make([]float32, 0, (c.live+c.live/compactRatio)*NumFeatures)
+	for _, sc := range c.series {
+		for i, r := range sc.days {
+			if r >= 0 {
+				sc.days[i] = int32(len(values) / NumFeatures)
+				values = append(values, c.row(r)...)
+			}
+		}
+	}
+	c.values, c.rows = values, c.live
+}
+
+// slot returns the index of ord in days, growing days to cover it.
+func (sc *seriesColumn) slot(ord int32) int {
+	if len(sc.days) == 0 {
+		sc.first = ord
+		sc.days = []int32{-1}
+		return 0
+	}
+	if ord < sc.first {
+		grown := make([]int32, int(sc.first-ord)+len(sc.days))
+		for i := range grown[:sc.first-ord] {
+			grown[i] = -1
+		}
+		copy(grown[sc.first-ord:], sc.days)
+		sc.days = grown
+		sc.first = ord
+	}
+	for int(ord-sc.first) >= len(sc.days) {
+		sc.days = append(sc.days, -1)
+	}
+	return int(ord - sc.first)
+}
+
+// Features returns the feature vector of (storeNbr, family, date).
+func (c *columns) Features(storeNbr int, family string, date time.Time) ([]float32, bool) {
+	sc, ok := c.lookup(storeNbr, family)
+	if !ok {
+		return nil, false
+	}
+	i := int(dayOrdinal(date) - sc.first)
+	if i < 0 || i >= len(sc.days) || sc.days[i] < 0 {
+		return nil, false
+	}
+	return c.row(sc.days[i]), true
+}
+
+// Aggregate returns the average feature vector of a series.
+func (c *columns) Aggregate(storeNbr int, family string) ([]float32, bool) {
+	avg, _, ok := c.aggregate(storeNbr, family)
+	return avg, ok
+}
+
+// aggregate returns the average feature vector of a series and the rows behind it.
+func (c *columns) aggregate(storeNbr int, family string) ([]float32, int, bool) {
+	sc, ok := c.lookup(storeNbr, family)
+	if !ok || sc.agg == nil {
+		return nil, 0, false
+	}
+	return sc.agg, sc.aggN, true
+}
+
+// setAggregate sets the average feature vector of an existing series.
+func (c *columns) setAggregate(storeNbr int, family string, avg []float32, n int) {
+	if sc, ok := c.lookup(storeNbr, family); ok {
+		sc.agg, sc.aggN = avg, n
+	}
+}
+
+// LastDate returns the newest date of a series.
+func (c *columns) LastDate(storeNbr int, family string) (time.Time, bool) {
+	sc, ok := c.lookup(storeNbr, family)
+	if !ok || len(sc.days) == 0 {
+		return time.Time{}, false
+	}
+	return ordinalDate(sc.last), true
+}
+
+// Window returns the vectors of every series for dates from start to end inclusive.
+func (c *columns) Window(start, end time.Time) [][]float32 {
+	from, to := dayOrdinal(start), dayOrdinal(end)
+	var rows [][]float32
+	for _, sc := range c.series {
+		lo := max(int(from-sc.first), 0)
+		hi := min(int(to-sc.first), len(sc.days)-1)
+		for i := lo; i <= hi; i++ {
+			if sc.days[i] >= 0 {
+				rows = append(rows, c.row(sc.days[i]))
+			}
+		}
+	}
+	return rows
+}
+
+// each calls fn for every series in store, then family order.
+func (c *columns) each(fn func(storeNbr int, family string, sc *seriesColumn)) {
+	keys := make([]seriesKey, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].store != keys[j].store {
+			return keys[i].store < keys[j].store
+		}
+		return c.families[keys[i].family] < c.families[keys[j].family]
+	})
+	for _, k := range keys {
+		fn(int(k.store), c.families[k.family], c.series[k])
+	}
+}
+
+// Series returns every series, ordered by store then family.
+func (c *columns) Series() []Series {
+	series := make([]Series, 0, len(c.series))
+	c.each(func(storeNbr int, family string, _ *seriesColumn) {
+		series = append(series, Series{StoreNbr: storeNbr, Family: family})
+	})
+	return series
+}
+
+// Cluster returns the cluster of a store.
+func (c *columns) Cluster(storeNbr int) (int, bool) {
+	cluster, ok := c.clusters[storeNbr]
+	return cluster, ok
+}
+
+// Size returns the number of distinct (store, family, date) rows.
+func (c *columns) Size() int {
+	return c.live
+}
+
+// AggregatedSize returns the number of series with an average vector.
+func (c *columns) AggregatedSize() int {
+	n := 0
+	for _, sc := range c.series {
+		if sc.agg != nil {
+			n++
+		}
+	}
+	return n
+}
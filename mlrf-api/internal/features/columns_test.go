@@ -0,0 +1,97 @@
+package features
+
+import (
+	"testing"
+	"time"
+)
+
+func TestColumnsPutGet(t *testing.T) {
+	c := newColumns(0)
+	aug1 := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	vector := func(v float32) []float32 {
+		f := make([]float32, NumFeatures)
+		f[0] = v
+		return f
+	}
+
+	// Out-of-order dates and a gap
+	c.put(1, "DAIRY", aug1.AddDate(0, 0, 5), vector(6))
+	c.put(1, "DAIRY", aug1, vector(1))
+	c.put(1, "DAIRY", aug1.AddDate(0, 0, 2), vector(3))
+
+	for day, want := range map[int]float32{0: 1, 2: 3, 5: 6} {
+		f, ok := c.Features(1, "DAIRY", aug1.AddDate(0, 0, day))
+		if !ok || f[0] != want {
+			t.Errorf("day %d: expected %v, got %v (ok=%v)", day, want, f, ok)
+		}
+	}
+	for _, day := range []int{-1, 1, 3, 6} {
+		if _, ok := c.Features(1, "DAIRY", aug1.AddDate(0, 0, day)); ok {
+			t.Errorf("day %d: expected no row", day)
+		}
+	}
+	if _, ok := c.Features(2, "DAIRY", aug1); ok {
+		t.Error("expected no row for an unknown series")
+	}
+	if last, _ := c.LastDate(1, "DAIRY"); !last.Equal(aug1.AddDate(0, 0, 5)) {
+		t.Errorf("expected last date 2017-08-06, got %s", last)
+	}
+
+	// Replacing a date leaves vectors already returned unchanged
+	before, _ := c.Features(1, "DAIRY", aug1)
+	old, replaced := c.put(1, "DAIRY", aug1, vector(10))
+	if !replaced || old[0] != 1 || before[0] != 1 {
+		t.Errorf("expected the replaced vector to be preserved, got old=%v before=%v", old, before)
+	}
+	if f, _ := c.Features(1, "DAIRY", aug1); f[0] != 10 {
+		t.Errorf("expected the replacement, got %v", f)
+	}
+	if c.live != 3 {
+		t.Errorf("expected 3 live rows, got %d", c.live)
+	}
+
+	// Returned vectors are capped so appends cannot reach the next row
+	if f, _ := c.Features(1, "DAIRY", aug1.AddDate(0, 0, 2)); cap(f) != NumFeatures {
+		t.Errorf("expected capacity %d, got %d", NumFeatures, cap(f))
+	}
+
+	if rows := c.Window(aug1.AddDate(0, 0, 1), aug1.AddDate(0, 0, 5)); len(rows) != 2 {
+		t.Errorf("expected 2 rows in window, got %d", len(rows))
+	}
+}
+
+func TestColumnsCompactsReplacedRows(t *testing.T) {
+	c := newColumns(0)
+	aug1 := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	vector := func(v float32) []float32 {
+		f := make([]float32, NumFeatures)
+		f[0] = v
+		return f
+	}
+	c.put(1, "DAIRY", aug1, vector(1))
+	c.put(2, "EGGS", aug1, vector(2))
+	held, _ := c.Features(1, "DAIRY", aug1)
+
+	// Upserting the same keys over and over keeps the row count flat
+	for i := 0; i < 10*compactMinRows; i++ {
+		c.put(1, "DAIRY", aug1, vector(float32(i)))
+		c.put(2, "EGGS", aug1.AddDate(0, 0, i%3), vector(float32(-i)))
+		if c.rows > c.live+compactMinRows+1 {
+			t.Fatalf("after %d upserts: %d rows for %d live", i, c.rows, c.live)
+		}
+	}
+	if len(c.values) != c.rows*NumFeatures || c.live != 4 {
+		t.Errorf("expected %d values for 4 live rows, got %d values, %d live", c.rows*NumFeatures, len(c.values), c.live)
+	}
+
+	last := float32(10*compactMinRows - 1)
+	if f, _ := c.Features(1, "DAIRY", aug1); f[0] != last {
+		t.Errorf("expected the latest vector %v, got %v", last, f[0])
+	}
+	if f, _ := c.Features(2, "EGGS", aug1.AddDate(0, 0, 2)); f[0] != -(last - 1) {
+		t.Errorf("expected the latest vector %v, got %v", -(last - 1), f[0])
+	}
+	if held[0] != 1 {
+		t.Errorf("expected a vector returned before compaction to be unchanged, got %v", held[0])
+	}
+}
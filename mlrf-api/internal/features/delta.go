@@ -0,0 +1,227 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+)
+
+// AppendResult summarizes a delta file merged into the store.
+type AppendResult struct {
+	Source      string `json:"source"`
+	Rows        int    `json:"rows"`     // Rows in the delta
+	Added       int    `json:"added"`    // New (store, family, date) rows
+	Replaced    int    `json:"replaced"` // Rows that overwrote an existing date
+	DataDateMin string `json:"data_date_min"`
+	DataDateMax string `json:"data_date_max"`
+}
+
+// deltaRow is one parsed row of a delta file.
+type deltaRow struct {
+	series   Series
+	cluster  int
+	date     time.Time
+	features []float32
+}
+
+// ErrNotLoaded is returned by appends before the store's first full load.
+var ErrNotLoaded = errors.New("feature store not loaded; a full load must precede appends")
+
+// Append merges a delta parquet file (typically only new dates) into the loaded data
+// without rereading the full feature matrix. See AppendReader.
+func (s *Store) Append(parquetPath string) (AppendResult, error) {
+	file, err := os.Open(parquetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return AppendResult{}, fmt.Errorf("feature file not found: %s", parquetPath)
+		}
+		return AppendResult{}, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return AppendResult{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return s.AppendReader(file, stat.Size(), parquetPath)
+}
+
+// AppendReader merges a delta parquet file of size bytes read from r; source names it
+// in logs and reports. Rows for a (store, family, date) already present replace it.
+// The delta is read and validated in full before anything is merged, so a malformed
+// or rejected delta leaves the store unchanged. Aggregated fallbacks, last dates and
+// metadata are updated to include the delta.
+func (s *Store) AppendReader(r io.ReaderAt, size int64, source string) (AppendResult, error) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	start := time.Now()
+
+	if err := s.appendable(); err != nil {
+		return AppendResult{}, err
+	}
+	pf, err := openParquet(r, size, source)
+	if err != nil {
+		return AppendResult{}, err
+	}
+	reader := parquet.NewReader(pf)
+	defer reader.Close()
+
+	check := newQualityCheck(pf)
+	var rows []deltaRow
+	for {
+		var row FeatureRow
+		if err := reader.Read(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return AppendResult{}, fmt.Errorf("failed to read feature row %d: %w", len(rows), err)
+		}
+		rows = append(rows, check.deltaRow(&row))
+	}
+	return s.merge(rows, check, source, start)
+}
+
+// AppendRows merges feature rows, e.g. streamed by a pipeline, into the loaded data
+// like AppendReader; source names them in logs and reports. The rows pass the same
+// quality checks as a delta file, so a rejected batch leaves the store unchanged.
+func (s *Store) AppendRows(featureRows []FeatureRow, source string) (AppendResult, error) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	start := time.Now()
+
+	if err := s.appendable(); err != nil {
+		return AppendResult{}, err
+	}
+	check := newQualityCheck(nil)
+	rows := make([]deltaRow, len(featureRows))
+	for i := range featureRows {
+		rows[i] = check.deltaRow(&featureRows[i])
+	}
+	return s.merge(rows, check, source, start)
+}
+
+// appendable returns why rows can't be appended to the store, if they can't.
+func (s *Store) appendable() error {
+	if !s.IsLoaded() {
+		return ErrNotLoaded
+	}
+	s.mu.RLock()
+	provider, backend := s.provider, s.metadata.Backend
+	s.mu.RUnlock()
+	if provider != nil {
+		return fmt.Errorf("appends are not supported by the %s feature backend", backend)
+	}
+	return nil
+}
+
+// deltaRow converts a row to merge, counting its problem values.
+func (c *qualityCheck) deltaRow(row *FeatureRow) deltaRow {
+	features := rowToFeatures(row)
+	c.observe(features)
+	return deltaRow{
+		series:   Series{StoreNbr: int(row.StoreNbr), Family: row.Family},
+		cluster:  int(row.Cluster),
+		date:     row.Date,
+		features: features,
+	}
+}
+
+// merge applies checked delta rows read since start. The caller holds loadMu.
+func (s *Store) merge(rows []deltaRow, check *qualityCheck, source string, start time.Time) (AppendResult, error) {
+	s.mu.RLock()
+	qualityCfg := s.qualityCfg
+	s.mu.RUnlock()
+	report := check.report(qualityCfg, source, len(rows))
+	if err := s.rejectOnQuality(report); err != nil {
+		return AppendResult{}, err
+	}
+
+	res := AppendResult{Source: source, Rows: len(rows)}
+	var minDate, maxDate time.Time
+	for i, row := range rows {
+		if i == 0 || row.date.Before(minDate) {
+			minDate = row.date
+		}
+		if i == 0 || row.date.After(maxDate) {
+			maxDate = row.date
+		}
+	}
+	res.DataDateMin = minDate.Format("2006-01-02")
+	res.DataDateMax = maxDate.Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Change in each series' feature sums and row count, applied to its average below
+	sumDelta := make(map[Series][]float64)
+	countDelta := make(map[Series]int)
+	for _, row := range rows {
+		if _, ok := sumDelta[row.series]; !ok {
+			sumDelta[row.series] = make([]float64, NumFeatures)
+		}
+		old, replaced := s.cols.put(row.series.StoreNbr, row.series.Family, row.date, row.features)
+		for i, f := range row.features {
+			sumDelta[row.series][i] += float64(f)
+			if replaced {
+				sumDelta[row.series][i] -= float64(old[i])
+			}
+		}
+		if replaced {
+			res.Replaced++
+		} else {
+			res.Added++
+			countDelta[row.series]++
+		}
+		s.cols.clusters[row.series.StoreNbr] = row.cluster
+	}
+
+	// Aggregated vectors are replaced rather than updated in place; callers may hold them
+	for series, delta := range sumDelta {
+		prev, n, _ := s.cols.aggregate(series.StoreNbr, series.Family)
+		total := n + countDelta[series]
+		if total == 0 {
+			continue
+		}
+		avg := make([]float32, NumFeatures)
+		for i := range avg {
+			var sum float64
+			if prev != nil {
+				sum = float64(prev[i]) * float64(n)
+			}
+			avg[i] = float32((sum + delta[i]) / float64(total))
+		}
+		s.cols.setAggregate(series.StoreNbr, series.Family, avg, total)
+	}
+
+	if report.Status == QualityDegraded {
+		s.quality = &report
+	}
+	if len(rows) > 0 {
+		if res.DataDateMin < s.metadata.DataDateMin {
+			s.metadata.DataDateMin = res.DataDateMin
+		}
+		if res.DataDateMax > s.metadata.DataDateMax {
+			s.metadata.DataDateMax = res.DataDateMax
+		}
+	}
+	s.metadata.RowCount += res.Added
+	s.metadata.Appends++
+	s.metadata.LoadedAt = time.Now()
+	s.metadata.Version = deltaVersion(s.metadata.Version, rows)
+
+	log.Info().
+		Str("source", source).
+		Int("rows", res.Rows).
+		Int("added", res.Added).
+		Int("replaced", res.Replaced).
+		Str("data_range", fmt.Sprintf("%s to %s", s.metadata.DataDateMin, s.metadata.DataDateMax)).
+		Dur("duration", time.Since(start)).
+		Msg("Feature delta appended")
+
+	return res, nil
+}
@@ -0,0 +1,151 @@
+package features
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestAppend(t *testing.T) {
+	aug14 := time.Date(2017, 8, 14, 0, 0, 0, 0, time.UTC)
+	aug15 := aug14.AddDate(0, 0, 1)
+	aug16 := aug15.AddDate(0, 0, 1)
+	full := writeFeatureFile(t, []FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug14, SalesLag1: 10},
+		{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 20},
+	})
+	s, err := NewStore(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := s.GetMetadata().Version
+
+	delta := writeFeatureFile(t, []FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 30}, // replaces 20
+		{StoreNbr: 1, Family: "DAIRY", Date: aug16, SalesLag1: 50},
+		{StoreNbr: 2, Family: "EGGS", Date: aug16, SalesLag1: 7, Cluster: 3},
+	})
+	res, err := s.Append(delta)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if res.Rows != 3 || res.Added != 2 || res.Replaced != 1 || res.DataDateMax != "2017-08-16" {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	if f, ok := s.Lookup(1, "DAIRY", aug15); !ok || f[idxSalesLag1] != 30 {
+		t.Errorf("expected the replaced row, got %v", f)
+	}
+	if f, ok := s.Lookup(2, "EGGS", aug16); !ok || f[idxSalesLag1] != 7 {
+		t.Errorf("expected the new series, got %v", f)
+	}
+	if last, _ := s.LastDate(1, "DAIRY"); !last.Equal(aug16) {
+		t.Errorf("expected last date 2017-08-16, got %s", last)
+	}
+	if cluster, ok := s.Cluster(2); !ok || cluster != 3 {
+		t.Errorf("expected cluster 3 for store 2, got %d", cluster)
+	}
+
+	// The fallback averages the merged rows: (10 + 30 + 50) / 3
+	if f, _ := s.GetFeatures(1, "DAIRY", "2017-09-01"); f[idxSalesLag1] != 30 {
+		t.Errorf("expected aggregated sales_lag_1 30, got %v", f[idxSalesLag1])
+	}
+
+	meta := s.GetMetadata()
+	if meta.RowCount != 4 || meta.DataDateMax != "2017-08-16" || meta.Appends != 1 {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if meta.Version == version {
+		t.Error("expected the version to change after an append")
+	}
+
+	// Another replica loading the same files reaches the same versions
+	replica, err := NewStore(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := replica.GetMetadata().Version; v != version {
+		t.Errorf("expected the same file to have version %s, got %s", version, v)
+	}
+	if _, err := replica.Append(delta); err != nil {
+		t.Fatal(err)
+	}
+	if v := replica.GetMetadata().Version; v != meta.Version {
+		t.Errorf("expected the same delta to give version %s, got %s", meta.Version, v)
+	}
+}
+
+func TestAppendReaderRejects(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	s, err := NewStore(writeFeatureFile(t, []FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: date}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetQualityConfig(QualityConfig{Mode: QualityModeReject, MaxNullRate: 0.01, MaxOutlierRate: 0.01})
+
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, []FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date.AddDate(0, 0, 1), SalesLag1: -3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.AppendReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "upload")
+	var qerr *QualityError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a QualityError, got %v", err)
+	}
+	if s.Size() != 1 || s.GetMetadata().Appends != 0 {
+		t.Errorf("expected a rejected delta to leave the store unchanged, got %d rows", s.Size())
+	}
+
+	garbage := []byte("not parquet")
+	if _, err := s.AppendReader(bytes.NewReader(garbage), int64(len(garbage)), "upload"); err == nil {
+		t.Error("expected an error for a malformed delta")
+	}
+
+	if _, err := (&Store{}).Append(os.DevNull); err == nil {
+		t.Error("expected an error appending to an unloaded store")
+	}
+}
+
+func TestAppendRows(t *testing.T) {
+	aug15 := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	aug16 := aug15.AddDate(0, 0, 1)
+	s := &Store{cols: newColumns(0), qualityCfg: QualityConfig{Mode: QualityModeReject, MaxNullRate: 0.01, MaxOutlierRate: 0.01}}
+	if _, err := s.AppendRows([]FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: aug16}}, "stream"); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("expected ErrNotLoaded before a load, got %v", err)
+	}
+
+	s, err := NewStore(writeFeatureFile(t, []FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 10}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetQualityConfig(QualityConfig{Mode: QualityModeReject, MaxNullRate: 0.01, MaxOutlierRate: 0.01})
+	res, err := s.AppendRows([]FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 12},
+		{StoreNbr: 1, Family: "DAIRY", Date: aug16, SalesLag1: 14},
+	}, "stream")
+	if err != nil {
+		t.Fatalf("AppendRows failed: %v", err)
+	}
+	if res.Source != "stream" || res.Added != 1 || res.Replaced != 1 || res.DataDateMax != "2017-08-16" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if f, ok := s.Lookup(1, "DAIRY", aug16); !ok || f[idxSalesLag1] != 14 {
+		t.Errorf("expected the streamed row, got %v", f)
+	}
+
+	// Rows failing the quality checks are rejected as a whole
+	_, err = s.AppendRows([]FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: aug16.AddDate(0, 0, 1), SalesLag1: -3}}, "stream")
+	var qerr *QualityError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a quality error, got %v", err)
+	}
+	if last, _ := s.LastDate(1, "DAIRY"); !last.Equal(aug16) {
+		t.Errorf("expected the rejected row not to be merged, got last date %s", last)
+	}
+}
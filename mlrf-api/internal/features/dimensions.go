@@ -0,0 +1,75 @@
+package features
+
+import "sort"
+
+var (
+	idxFamilyEncoded = featureIndex("family_encoded")
+	idxTypeEncoded   = featureIndex("type_encoded")
+)
+
+// Family is a product family in the feature matrix.
+type Family struct {
+	Name   string `json:"family"`
+	ID     int    `json:"family_id"` // family_encoded value the model was trained with
+	Stores int    `json:"stores"`    // Stores selling the family
+}
+
+// StoreInfo is a store in the feature matrix and its metadata.
+type StoreInfo struct {
+	StoreNbr int `json:"store_nbr"`
+	Cluster  int `json:"cluster"`
+	Type     int `json:"type_id"` // type_encoded value the model was trained with
+	Families int `json:"families"`
+}
+
+// Dimensions returns the families and stores of the loaded feature matrix, ordered by
+// name and store number. Encoded IDs and store metadata come from each series' newest
+// row, so they match the features the model is scored on.
+func (s *Store) Dimensions() ([]Family, []StoreInfo) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	families := make(map[string]*Family)
+	stores := make(map[int]*StoreInfo)
+	src := s.source()
+	for _, series := range src.Series() {
+		var row []float32
+		if last, ok := src.LastDate(series.StoreNbr, series.Family); ok {
+			row, _ = src.Features(series.StoreNbr, series.Family, last)
+		}
+
+		f, ok := families[series.Family]
+		if !ok {
+			f = &Family{Name: series.Family}
+			if row != nil {
+				f.ID = int(row[idxFamilyEncoded])
+			}
+			families[series.Family] = f
+		}
+		f.Stores++
+
+		st, ok := stores[series.StoreNbr]
+		if !ok {
+			st = &StoreInfo{StoreNbr: series.StoreNbr}
+			st.Cluster, _ = src.Cluster(series.StoreNbr)
+			if row != nil {
+				st.Type = int(row[idxTypeEncoded])
+			}
+			stores[series.StoreNbr] = st
+		}
+		st.Families++
+	}
+
+	familyList := make([]Family, 0, len(families))
+	for _, f := range families {
+		familyList = append(familyList, *f)
+	}
+	sort.Slice(familyList, func(i, j int) bool { return familyList[i].Name < familyList[j].Name })
+
+	storeList := make([]StoreInfo, 0, len(stores))
+	for _, st := range stores {
+		storeList = append(storeList, *st)
+	}
+	sort.Slice(storeList, func(i, j int) bool { return storeList[i].StoreNbr < storeList[j].StoreNbr })
+	return familyList, storeList
+}
@@ -0,0 +1,9 @@
+package features
+
+// database/sql drivers of the SQL feature backends, linked so FEATURE_BACKEND=duckdb
+// and FEATURE_BACKEND=sqlite work without a custom build. The history source opens
+// its database through the same drivers.
+import (
+	_ "github.com/marcboeker/go-duckdb" // Registers "duckdb" (cgo)
+	_ "modernc.org/sqlite"              // Registers "sqlite" (pure Go)
+)
@@ -0,0 +1,72 @@
+package features
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache keeps the most recently used lookups of an on-demand provider. Misses are
+// cached too, so repeated requests for an unknown series do not reach the database.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Front is the most recently used
+	items map[string]*list.Element
+}
+
+// lruEntry is one cached lookup result.
+type lruEntry struct {
+	key      string
+	features []float32
+	ok       bool
+}
+
+// newLRUCache creates a cache holding up to size entries; size 0 disables it.
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns a cached lookup result and whether key was cached.
+func (c *lruCache) get(key string) (features []float32, ok, cached bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, found := c.items[key]
+	if !found {
+		return nil, false, false
+	}
+	c.order.MoveToFront(el)
+	e := el.Value.(*lruEntry)
+	return e.features, e.ok, true
+}
+
+// add caches a lookup result, evicting the least recently used entry when full.
+func (c *lruCache) add(key string, features []float32, ok bool) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.items[key]; found {
+		e := el.Value.(*lruEntry)
+		e.features, e.ok = features, ok
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, features: features, ok: ok})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// len returns the number of cached entries.
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
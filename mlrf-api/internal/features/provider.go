@@ -0,0 +1,68 @@
+package features
+
+import (
+	"fmt"
+	"time"
+)
+
+// FeatureProvider serves the feature vectors behind a Store. The default is the
+// in-memory columnar index built by Load; NewSQLStore uses a sqlProvider that queries
+// a database on demand for matrices that do not fit in RAM.
+type FeatureProvider interface {
+	// Features returns the vector indexed for (storeNbr, family, date).
+	Features(storeNbr int, family string, date time.Time) ([]float32, bool)
+	// Aggregate returns the average vector of a series.
+	Aggregate(storeNbr int, family string) ([]float32, bool)
+	// LastDate returns the newest date of a series.
+	LastDate(storeNbr int, family string) (time.Time, bool)
+	// Window returns the vectors of every series for dates from start to end inclusive.
+	Window(start, end time.Time) [][]float32
+	// Series returns every series, ordered by store then family.
+	Series() []Series
+	// Cluster returns the cluster of a store.
+	Cluster(storeNbr int) (int, bool)
+	// Size returns the number of distinct (store, family, date) rows.
+	Size() int
+	// AggregatedSize returns the number of series with an average vector.
+	AggregatedSize() int
+}
+
+// Feature backends selectable with FEATURE_BACKEND.
+const (
+	BackendMemory = "memory" // Load the parquet file into the columnar index
+	BackendDuckDB = "duckdb" // Query DuckDB, by default over the parquet file itself
+	BackendSQLite = "sqlite" // Query a SQLite table holding the feature matrix
+)
+
+// BackendConfig selects and configures the feature backend.
+type BackendConfig struct {
+	Backend      string        // BackendMemory, BackendDuckDB or BackendSQLite
+	Driver       string        // database/sql driver name; defaults to "duckdb" or "sqlite"
+	DSN          string        // Data source name passed to sql.Open
+	Table        string        // Table or table expression holding the feature matrix
+	CacheSize    int           // Vectors kept in the LRU hot cache
+	QueryTimeout time.Duration // Timeout of each on-demand query
+}
+
+// DefaultBackendConfig returns the default configuration of backend. With DuckDB the
+// table is read_parquet over featurePath, so no import step is needed; with SQLite it
+// is "features".
+func DefaultBackendConfig(backend, featurePath string) BackendConfig {
+	cfg := BackendConfig{
+		Backend:      BackendMemory,
+		CacheSize:    100000,
+		QueryTimeout: 2 * time.Second,
+	}
+
+	switch backend {
+	case BackendDuckDB:
+		cfg.Backend = backend
+		cfg.Driver = "duckdb"
+		cfg.Table = fmt.Sprintf("read_parquet('%s')", featurePath)
+	case BackendSQLite:
+		cfg.Backend = backend
+		cfg.Driver = "sqlite"
+		cfg.Table = "features"
+	}
+	return cfg
+}
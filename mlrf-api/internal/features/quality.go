@@ -0,0 +1,160 @@
+package features
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Quality modes decide what Load does when a check exceeds its thresholds.
+const (
+	QualityModeReject  = "reject"  // Fail the load and keep serving the previous data
+	QualityModeDegrade = "degrade" // Load anyway and report the store as degraded
+)
+
+// Quality statuses of a load.
+const (
+	QualityPassed   = "passed"
+	QualityDegraded = "degraded"
+	QualityRejected = "rejected"
+)
+
+// QualityConfig holds feature data quality thresholds.
+type QualityConfig struct {
+	Mode           string  // QualityModeReject or QualityModeDegrade
+	MaxNullRate    float64 // Share of a column's rows that may be null, NaN or infinite
+	MaxOutlierRate float64 // Share of a column's rows that may fall outside its plausible range
+}
+
+// DefaultQualityConfig returns the default quality thresholds.
+func DefaultQualityConfig() QualityConfig {
+	return QualityConfig{
+		Mode:           QualityModeReject,
+		MaxNullRate:    0.01,
+		MaxOutlierRate: 0.01,
+	}
+}
+
+// ColumnQuality counts the problem values found in one column.
+type ColumnQuality struct {
+	Name     string `json:"name"`
+	Nulls    int    `json:"nulls"`
+	NaN      int    `json:"nan"`      // NaN or infinite
+	Outliers int    `json:"outliers"` // Outside the column's plausible range
+}
+
+// QualityReport is the result of validating a feature file.
+type QualityReport struct {
+	CheckedAt  time.Time       `json:"checked_at"`
+	FilePath   string          `json:"file_path"`
+	Rows       int             `json:"rows"`
+	Status     string          `json:"status"` // passed, degraded or rejected
+	Violations []string        `json:"violations,omitempty"`
+	Columns    []ColumnQuality `json:"columns"`
+}
+
+// QualityError is returned by Load when a file fails validation in reject mode.
+type QualityError struct {
+	Report QualityReport
+}
+
+func (e *QualityError) Error() string {
+	return "feature quality check failed: " + strings.Join(e.Report.Violations, "; ")
+}
+
+// keyColumns identify a row; they are only checked for nulls.
+var keyColumns = []string{"store_nbr", "family", "date"}
+
+// qualityCheck accumulates per-column counts while a file is read.
+type qualityCheck struct {
+	nulls    map[string]int
+	nan      []int
+	outliers []int
+}
+
+// newQualityCheck starts a check, taking null counts from the file's column statistics
+// since nulls are read back as zero values. f is nil for rows not read from a file.
+func newQualityCheck(f *parquet.File) *qualityCheck {
+	c := &qualityCheck{
+		nulls:    make(map[string]int),
+		nan:      make([]int, NumFeatures),
+		outliers: make([]int, NumFeatures),
+	}
+	if f == nil {
+		return c
+	}
+	for _, rg := range f.Metadata().RowGroups {
+		for _, col := range rg.Columns {
+			if len(col.MetaData.PathInSchema) > 0 {
+				c.nulls[col.MetaData.PathInSchema[0]] += int(col.MetaData.Statistics.NullCount)
+			}
+		}
+	}
+	return c
+}
+
+// observe checks one row's feature vector.
+func (c *qualityCheck) observe(features []float32) {
+	for i, f := range features {
+		v := float64(f)
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			c.nan[i]++
+		case v < schema.Features.Column(i).Min || v > schema.Features.Column(i).Max:
+			c.outliers[i]++
+		}
+	}
+}
+
+// report compares the counts against the thresholds.
+func (c *qualityCheck) report(cfg QualityConfig, path string, rows int) QualityReport {
+	rep := QualityReport{
+		CheckedAt: time.Now(),
+		FilePath:  path,
+		Rows:      rows,
+		Status:    QualityPassed,
+	}
+	if rows == 0 {
+		rep.Violations = append(rep.Violations, "no rows")
+	}
+
+	rate := func(n int) float64 {
+		if rows == 0 {
+			return 0
+		}
+		return float64(n) / float64(rows)
+	}
+	for _, name := range keyColumns {
+		col := ColumnQuality{Name: name, Nulls: c.nulls[name]}
+		if col.Nulls > 0 {
+			rep.Violations = append(rep.Violations, fmt.Sprintf("%s: %d nulls", name, col.Nulls))
+		}
+		rep.Columns = append(rep.Columns, col)
+	}
+	for i, qc := range schema.Features.Columns() {
+		col := ColumnQuality{Name: qc.Name, Nulls: c.nulls[qc.Name], NaN: c.nan[i], Outliers: c.outliers[i]}
+		invalid := col.NaN
+		if !qc.Optional {
+			invalid += col.Nulls
+		}
+		if r := rate(invalid); r > cfg.MaxNullRate {
+			rep.Violations = append(rep.Violations, fmt.Sprintf("%s: %.2f%% null or NaN exceeds %.2f%%", qc.Name, 100*r, 100*cfg.MaxNullRate))
+		}
+		if r := rate(col.Outliers); r > cfg.MaxOutlierRate {
+			rep.Violations = append(rep.Violations, fmt.Sprintf("%s: %.2f%% outside [%g, %g] exceeds %.2f%%", qc.Name, 100*r, qc.Min, qc.Max, 100*cfg.MaxOutlierRate))
+		}
+		rep.Columns = append(rep.Columns, col)
+	}
+
+	if len(rep.Violations) > 0 {
+		rep.Status = QualityRejected
+		if cfg.Mode == QualityModeDegrade {
+			rep.Status = QualityDegraded
+		}
+	}
+	return rep
+}
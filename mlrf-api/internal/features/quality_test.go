@@ -0,0 +1,178 @@
+package features
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func writeFeatureFile[T any](t *testing.T, rows []T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "features.parquet")
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatalf("failed to write parquet: %v", err)
+	}
+	return path
+}
+
+// nullableOilRow writes a feature file whose oil_price column may be null.
+type nullableOilRow struct {
+	StoreNbr int32     `parquet:"store_nbr"`
+	Family   string    `parquet:"family"`
+	Date     time.Time `parquet:"date"`
+
+	// Numeric features
+	Year           int32    `parquet:"year"`
+	Month          int32    `parquet:"month"`
+	Day            int32    `parquet:"day"`
+	DayOfWeek      int32    `parquet:"dayofweek"`
+	DayOfYear      int32    `parquet:"dayofyear"`
+	IsMidMonth     int32    `parquet:"is_mid_month"`
+	IsLeapYear     int32    `parquet:"is_leap_year"`
+	OilPrice       *float64 `parquet:"oil_price,optional"`
+	IsHoliday      int32    `parquet:"is_holiday"`
+	OnPromotion    int32    `parquet:"onpromotion"`
+	PromoRolling7  float64  `parquet:"promo_rolling_7"`
+	Cluster        int32    `parquet:"cluster"`
+	SalesLag1      float64  `parquet:"sales_lag_1"`
+	SalesLag7      float64  `parquet:"sales_lag_7"`
+	SalesLag14     float64  `parquet:"sales_lag_14"`
+	SalesLag28     float64  `parquet:"sales_lag_28"`
+	SalesLag90     float64  `parquet:"sales_lag_90"`
+	SalesRolMean7  float64  `parquet:"sales_rolling_mean_7"`
+	SalesRolMean14 float64  `parquet:"sales_rolling_mean_14"`
+	SalesRolMean28 float64  `parquet:"sales_rolling_mean_28"`
+	SalesRolMean90 float64  `parquet:"sales_rolling_mean_90"`
+	SalesRolStd7   float64  `parquet:"sales_rolling_std_7"`
+	SalesRolStd14  float64  `parquet:"sales_rolling_std_14"`
+	SalesRolStd28  float64  `parquet:"sales_rolling_std_28"`
+	SalesRolStd90  float64  `parquet:"sales_rolling_std_90"`
+
+	// Categorical features (encoded as int for model)
+	FamilyEncoded int32 `parquet:"family_encoded,optional"`
+	TypeEncoded   int32 `parquet:"type_encoded,optional"`
+}
+
+func columnQuality(t *testing.T, rep *QualityReport, name string) ColumnQuality {
+	t.Helper()
+	for _, c := range rep.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("column %s missing from report", name)
+	return ColumnQuality{}
+}
+
+func TestLoadQualityReject(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	good := writeFeatureFile(t, []FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, OilPrice: 47, SalesLag1: 10},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, OilPrice: 47, SalesLag1: 20},
+	})
+	bad := writeFeatureFile(t, []FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, OilPrice: math.NaN(), SalesLag1: 10},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, OilPrice: 47, SalesLag1: -5},
+		{StoreNbr: 3, Family: "DAIRY", Date: date, OilPrice: 47, SalesLag1: 30},
+	})
+
+	s, err := NewStore(good)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	s.SetQualityConfig(QualityConfig{Mode: QualityModeReject, MaxNullRate: 0.01, MaxOutlierRate: 0.01})
+
+	err = s.Load(bad)
+	var qerr *QualityError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a QualityError, got %v", err)
+	}
+	if len(qerr.Report.Violations) != 2 {
+		t.Errorf("expected oil_price and sales_lag_1 violations, got %v", qerr.Report.Violations)
+	}
+
+	// The previous data keeps serving
+	if s.Size() != 2 || s.FilePath() != good {
+		t.Errorf("expected the rejected load to keep the previous data, got %d rows from %s", s.Size(), s.FilePath())
+	}
+	current, rejected := s.Quality()
+	if current == nil || current.Status != QualityPassed {
+		t.Errorf("expected the served data to have passed, got %+v", current)
+	}
+	if rejected == nil || rejected.Status != QualityRejected || rejected.FilePath != bad {
+		t.Fatalf("expected the rejected report, got %+v", rejected)
+	}
+	if c := columnQuality(t, rejected, "oil_price"); c.NaN != 1 || c.Outliers != 0 {
+		t.Errorf("unexpected oil_price counts %+v", c)
+	}
+	if c := columnQuality(t, rejected, "sales_lag_1"); c.Outliers != 1 || c.NaN != 0 {
+		t.Errorf("unexpected sales_lag_1 counts %+v", c)
+	}
+
+	// A successful load clears the rejected report
+	if err := s.Load(good); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if _, rejected := s.Quality(); rejected != nil {
+		t.Errorf("expected the rejected report to clear, got %+v", rejected)
+	}
+}
+
+func TestLoadQualityDegrade(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	oil := 47.0
+	path := writeFeatureFile(t, []nullableOilRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, OilPrice: &oil},
+		{StoreNbr: 2, Family: "DAIRY", Date: date},
+	})
+
+	cfg := DefaultQualityConfig()
+	cfg.Mode = QualityModeDegrade
+	s, err := NewStoreWithQuality(path, cfg)
+	if err != nil {
+		t.Fatalf("expected degraded load to succeed, got %v", err)
+	}
+	current, _ := s.Quality()
+	if current == nil || current.Status != QualityDegraded {
+		t.Fatalf("expected degraded report, got %+v", current)
+	}
+	if c := columnQuality(t, current, "oil_price"); c.Nulls != 1 {
+		t.Errorf("expected 1 null oil price, got %+v", c)
+	}
+	if s.Size() != 2 {
+		t.Errorf("expected both rows loaded, got %d", s.Size())
+	}
+}
+
+func TestQualityThresholds(t *testing.T) {
+	check := &qualityCheck{
+		nulls:    map[string]int{},
+		nan:      make([]int, NumFeatures),
+		outliers: make([]int, NumFeatures),
+	}
+	features := make([]float32, NumFeatures)
+	features[1] = 13 // month
+	check.observe(features)
+
+	cfg := QualityConfig{Mode: QualityModeReject, MaxOutlierRate: 0.1}
+	if rep := check.report(cfg, "f", 100); rep.Status != QualityPassed {
+		t.Errorf("expected 1%% outliers to pass a 10%% threshold, got %v", rep.Violations)
+	}
+	if rep := check.report(cfg, "f", 5); rep.Status != QualityRejected || len(rep.Violations) != 1 {
+		t.Errorf("expected 20%% outliers to be rejected, got %+v", rep)
+	}
+}
+
+func TestDefaultQualityConfig(t *testing.T) {
+	cfg := DefaultQualityConfig()
+	if cfg.Mode != QualityModeReject {
+		t.Errorf("expected reject mode, got %q", cfg.Mode)
+	}
+	if cfg.MaxNullRate != 0.01 || cfg.MaxOutlierRate != 0.01 {
+		t.Errorf("expected 1%% thresholds, got %+v", cfg)
+	}
+}
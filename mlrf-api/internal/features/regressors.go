@@ -0,0 +1,168 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// RegressorFeatures are the exogenous features whose future values can be supplied,
+// in place of the value frozen in the last known row of a series.
+var RegressorFeatures = []string{"oil_price", "onpromotion"}
+
+// ErrInvalidRegressor is returned for a value of an unknown regressor, a negative or
+// non-finite value, or an invalid date.
+var ErrInvalidRegressor = errors.New("invalid regressor")
+
+// RegressorValue is one supplied value of a regressor, on date or every day from
+// start_date to end_date, for every series or those of store_nbr and/or family.
+type RegressorValue struct {
+	Feature   string  `json:"feature"`
+	StoreNbr  int     `json:"store_nbr,omitempty"` // 0 for every store
+	Family    string  `json:"family,omitempty"`    // "" for every family
+	Date      string  `json:"date,omitempty"`
+	StartDate string  `json:"start_date,omitempty"`
+	EndDate   string  `json:"end_date,omitempty"`
+	Value     float32 `json:"value"`
+}
+
+// RegressorsFile is the JSON form of a regressor overrides file.
+type RegressorsFile struct {
+	Regressors []RegressorValue `json:"regressors"`
+}
+
+// regressorKey identifies a value; zero storeNbr and empty family match every series.
+type regressorKey struct {
+	col      int
+	storeNbr int
+	family   string
+	day      int
+}
+
+// Regressors are supplied values of exogenous features by date. A value for a
+// series takes precedence over one for its store, then its family, then every series.
+type Regressors struct {
+	values map[regressorKey]float32
+}
+
+// NewRegressors returns an empty set of regressor values.
+func NewRegressors() *Regressors {
+	return &Regressors{values: make(map[regressorKey]float32)}
+}
+
+// LoadRegressors reads a regressor overrides file with a "regressors" list.
+func LoadRegressors(path string) (*Regressors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file RegressorsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse regressors: %w", err)
+	}
+	r := NewRegressors()
+	for i, v := range file.Regressors {
+		if err := r.Add(v); err != nil {
+			return nil, fmt.Errorf("regressors[%d]: %w", i, err)
+		}
+	}
+	return r, nil
+}
+
+// Add sets v on its date or date range, replacing an earlier value for the same days.
+func (r *Regressors) Add(v RegressorValue) error {
+	if v.Date != "" && (v.StartDate != "" || v.EndDate != "") {
+		return fmt.Errorf("%w: set date or start_date and end_date, not both", ErrInvalidRegressor)
+	}
+	start, end := v.Date, v.Date
+	if v.Date == "" {
+		start, end = v.StartDate, v.EndDate
+	}
+	from, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return fmt.Errorf("%w: invalid date %q", ErrInvalidRegressor, start)
+	}
+	to, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return fmt.Errorf("%w: invalid end_date %q", ErrInvalidRegressor, end)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("%w: end_date %s is before start_date %s", ErrInvalidRegressor, end, start)
+	}
+	if dayNumber(to)-dayNumber(from) > MaxRollForwardDays {
+		return fmt.Errorf("%w: a range spans at most %d days", ErrInvalidRegressor, MaxRollForwardDays)
+	}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if err := r.Set(v.Feature, v.StoreNbr, v.Family, d, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set sets the value of feature on date, for every series when storeNbr is 0 and
+// family is empty, or for those of the store and/or family given.
+func (r *Regressors) Set(feature string, storeNbr int, family string, date time.Time, value float32) error {
+	col, ok := regressorIndex(feature)
+	if !ok {
+		return fmt.Errorf("%w: %q is not a regressor, expected one of %v", ErrInvalidRegressor, feature, RegressorFeatures)
+	}
+	if value < 0 || math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+		return fmt.Errorf("%w: %s must be a non-negative number", ErrInvalidRegressor, feature)
+	}
+	r.values[regressorKey{col: col, storeNbr: storeNbr, family: family, day: dayNumber(date)}] = value
+	return nil
+}
+
+// Len returns the number of values set, a range counting once per day.
+func (r *Regressors) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.values)
+}
+
+// Apply returns f with the regressor values of the series on date. f is returned as
+// is when no value is set, and copied otherwise, so feature matrix rows are never
+// modified in place.
+func (r *Regressors) Apply(f []float32, storeNbr int, family string, date time.Time) []float32 {
+	if r.Len() == 0 {
+		return f
+	}
+	day := dayNumber(date)
+	copied := false
+	for _, name := range RegressorFeatures {
+		col, _ := regressorIndex(name)
+		for _, k := range []regressorKey{
+			{col, storeNbr, family, day},
+			{col, storeNbr, "", day},
+			{col, 0, family, day},
+			{col, 0, "", day},
+		} {
+			v, ok := r.values[k]
+			if !ok {
+				continue
+			}
+			if !copied {
+				f = append([]float32(nil), f...)
+				copied = true
+			}
+			f[col] = v
+			break
+		}
+	}
+	return f
+}
+
+// regressorIndex returns the feature position of a regressor.
+func regressorIndex(feature string) (int, bool) {
+	for _, name := range RegressorFeatures {
+		if name == feature {
+			return featureIndex(name), true
+		}
+	}
+	return 0, false
+}
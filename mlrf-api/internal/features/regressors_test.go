@@ -0,0 +1,112 @@
+package features
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegressorsApply(t *testing.T) {
+	r := NewRegressors()
+	oil, promo := featureIndex("oil_price"), featureIndex("onpromotion")
+	for _, v := range []RegressorValue{
+		{Feature: "oil_price", StartDate: "2017-08-16", EndDate: "2017-08-20", Value: 50},
+		{Feature: "oil_price", Family: "DAIRY", Date: "2017-08-17", Value: 51},
+		{Feature: "oil_price", StoreNbr: 1, Date: "2017-08-17", Value: 52},
+		{Feature: "onpromotion", StoreNbr: 1, Family: "DAIRY", Date: "2017-08-17", Value: 3},
+	} {
+		if err := r.Add(v); err != nil {
+			t.Fatalf("Add(%+v) failed: %v", v, err)
+		}
+	}
+	if r.Len() != 8 {
+		t.Errorf("expected 8 values, got %d", r.Len())
+	}
+
+	base := make([]float32, NumFeatures)
+	base[oil], base[promo] = 40, 1
+	day := func(s string) time.Time { d, _ := time.Parse("2006-01-02", s); return d }
+
+	if f := r.Apply(base, 1, "DAIRY", day("2017-08-15")); &f[0] != &base[0] {
+		t.Error("expected the row returned as is without values")
+	}
+	f := r.Apply(base, 2, "BEVERAGES", day("2017-08-16"))
+	if f[oil] != 50 || f[promo] != 1 || base[oil] != 40 {
+		t.Errorf("expected oil 50 on a copy, got %v (base %v)", f[oil], base[oil])
+	}
+	// The store's value beats the family's, the series' onpromotion applies alone
+	if f := r.Apply(base, 1, "DAIRY", day("2017-08-17")); f[oil] != 52 || f[promo] != 3 {
+		t.Errorf("expected oil 52 and onpromotion 3, got %v %v", f[oil], f[promo])
+	}
+	if f := r.Apply(base, 2, "DAIRY", day("2017-08-17")); f[oil] != 51 || f[promo] != 1 {
+		t.Errorf("expected the DAIRY oil price 51, got %v %v", f[oil], f[promo])
+	}
+}
+
+func TestRegressorsInvalid(t *testing.T) {
+	for _, v := range []RegressorValue{
+		{Feature: "sales_lag_1", Date: "2017-08-16", Value: 1},
+		{Feature: "oil_price", Date: "2017-08-16", Value: -1},
+		{Feature: "oil_price", Date: "16/08/2017", Value: 1},
+		{Feature: "oil_price", Value: 1},
+		{Feature: "oil_price", StartDate: "2017-08-20", EndDate: "2017-08-16", Value: 1},
+		{Feature: "oil_price", Date: "2017-08-16", StartDate: "2017-08-16", Value: 1},
+		{Feature: "oil_price", StartDate: "2017-01-01", EndDate: "2019-01-01", Value: 1},
+	} {
+		if err := NewRegressors().Add(v); !errors.Is(err, ErrInvalidRegressor) {
+			t.Errorf("Add(%+v): expected ErrInvalidRegressor, got %v", v, err)
+		}
+	}
+}
+
+func TestLoadRegressors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regressors.json")
+	if err := os.WriteFile(path, []byte(`{"regressors":[{"feature":"oil_price","start_date":"2017-08-16","end_date":"2017-08-31","value":48.5}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadRegressors(path)
+	if err != nil || r.Len() != 16 {
+		t.Fatalf("expected 16 days of oil prices, got %v, %v", r.Len(), err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"regressors":[{"feature":"transactions","date":"2017-08-16","value":1}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRegressors(path); !errors.Is(err, ErrInvalidRegressor) {
+		t.Errorf("expected ErrInvalidRegressor, got %v", err)
+	}
+}
+
+func TestSeriesWithRegressors(t *testing.T) {
+	store := newSeriesStore()
+	oil := featureIndex("oil_price")
+	file, request := NewRegressors(), NewRegressors()
+	file.Add(RegressorValue{Feature: "oil_price", StartDate: "2017-08-10", EndDate: "2017-08-12", Value: 45})
+	request.Add(RegressorValue{Feature: "oil_price", Date: "2017-08-12", Value: 60})
+
+	var predicted []float32
+	predict := func(f []float32) (float32, error) {
+		predicted = append(predicted, f[oil])
+		return f[oil], nil
+	}
+	b := NewFeatureBuilder(store).WithRegressors(file).WithRegressors(nil).WithRegressors(request)
+	features, _, err := b.Series(1, "GROCERY I", time.Date(2017, 8, 11, 0, 0, 0, 0, time.UTC), 3, predict)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The known 08-10 row leads in with the file's value; the request's wins on 08-12
+	if len(predicted) != 4 || predicted[0] != 45 || features[0][oil] != 45 || features[1][oil] != 60 || features[2][oil] != 0 {
+		t.Errorf("unexpected oil prices, predicted %v", predicted)
+	}
+	known, _ := store.exact(1, "GROCERY I", time.Date(2017, 8, 10, 0, 0, 0, 0, time.UTC))
+	if known[oil] != 0 {
+		t.Error("expected the feature matrix row left unchanged")
+	}
+
+	f, rolled, err := b.Build(1, "GROCERY I", "2017-08-10", predict)
+	if err != nil || rolled || f[oil] != 45 {
+		t.Errorf("expected the known row with oil 45, got %v %v %v", f[oil], rolled, err)
+	}
+}
@@ -0,0 +1,340 @@
+package features
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// sqlProvider is a FeatureProvider that queries the feature matrix from a database on
+// demand. Only the series list, last dates and store clusters are held in memory;
+// vectors are fetched per lookup and kept in an LRU hot cache. A provider is immutable
+// apart from its cache: Load builds a new one over the same database and swaps it in.
+type sqlProvider struct {
+	db      *sql.DB
+	cfg     BackendConfig
+	dayExpr string // SQL expression rendering the date column as YYYY-MM-DD
+	columns string // Quoted feature columns in schema order, nulls read as zero
+	cache   *lruCache
+
+	series    []Series
+	lastDates map[Series]time.Time
+	clusters  map[int]int
+	rows      int
+	minDate   string
+	maxDate   string
+	version   string // Hash of the series index
+}
+
+// NewSQLStore creates a feature store backed by a DuckDB or SQLite database, opened
+// with the database/sql driver named by cfg.Driver. The duckdb and sqlite drivers are
+// linked in; another driver needs a blank import in cmd/server. The table needs the
+// parquet columns: store_nbr, family, date and the model features.
+func NewSQLStore(cfg BackendConfig) (*Store, error) {
+	if cfg.Backend != BackendDuckDB && cfg.Backend != BackendSQLite {
+		return nil, fmt.Errorf("unsupported feature backend: %q", cfg.Backend)
+	}
+	if cfg.Table == "" {
+		return nil, errors.New("feature table not configured (FEATURE_DB_TABLE)")
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database (is the %q driver linked?): %w", cfg.Backend, cfg.Driver, err)
+	}
+
+	s := &Store{
+		stalenessThreshold: DefaultStalenessThreshold,
+		qualityCfg:         DefaultQualityConfig(),
+	}
+	if err := s.loadSQL(db, cfg); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadSQL reads the series index from the database and swaps in a provider over it,
+// dropping every cached vector.
+func (s *Store) loadSQL(db *sql.DB, cfg BackendConfig) error {
+	start := time.Now()
+	p, err := newSQLProvider(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.provider = p
+	s.cols = nil
+	s.quality = nil
+	s.rejectedQuality = nil
+	s.metadata = Metadata{
+		LoadedAt:    time.Now(),
+		FilePath:    cfg.Table,
+		RowCount:    p.rows,
+		DataDateMin: p.minDate,
+		DataDateMax: p.maxDate,
+		Version:     p.version,
+		Backend:     cfg.Backend,
+	}
+	s.loaded = true
+
+	log.Info().
+		Str("backend", cfg.Backend).
+		Str("table", cfg.Table).
+		Int("rows", p.rows).
+		Int("series", len(p.series)).
+		Int("cache_size", cfg.CacheSize).
+		Str("data_range", fmt.Sprintf("%s to %s", p.minDate, p.maxDate)).
+		Dur("duration", time.Since(start)).
+		Msg("Feature store connected")
+
+	return nil
+}
+
+// newSQLProvider builds a provider, querying the series index up front.
+func newSQLProvider(db *sql.DB, cfg BackendConfig) (*sqlProvider, error) {
+	p := &sqlProvider{
+		db:        db,
+		cfg:       cfg,
+		dayExpr:   `date("date")`,
+		cache:     newLRUCache(cfg.CacheSize),
+		lastDates: make(map[Series]time.Time),
+		clusters:  make(map[int]int),
+	}
+	if cfg.Backend == BackendDuckDB {
+		p.dayExpr = `CAST(CAST("date" AS DATE) AS VARCHAR)`
+	}
+	cols := make([]string, NumFeatures)
+	for i, name := range schema.Features.Names() {
+		cols[i] = fmt.Sprintf(`COALESCE("%s", 0)`, name)
+	}
+	p.columns = strings.Join(cols, ", ")
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	if err := validateTableSchema(ctx, db, cfg.Table); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT "store_nbr", "family", MIN(%[1]s), MAX(%[1]s), COUNT(*) FROM %[2]s GROUP BY "store_nbr", "family" ORDER BY "store_nbr", "family"`,
+		p.dayExpr, cfg.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature series: %w", err)
+	}
+	defer rows.Close()
+	version := sha256.New()
+	for rows.Next() {
+		var series Series
+		var first, last string
+		var n int
+		if err := rows.Scan(&series.StoreNbr, &series.Family, &first, &last, &n); err != nil {
+			return nil, fmt.Errorf("failed to read feature series: %w", err)
+		}
+		writeSeries(version, series)
+		fmt.Fprintf(version, "%s:%s:%d;", first, last, n)
+		lastDate, err := time.Parse("2006-01-02", last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q for store %d %s: %w", last, series.StoreNbr, series.Family, err)
+		}
+		p.series = append(p.series, series)
+		p.lastDates[series] = lastDate
+		p.rows += n
+		if p.minDate == "" || first < p.minDate {
+			p.minDate = first
+		}
+		if last > p.maxDate {
+			p.maxDate = last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feature series: %w", err)
+	}
+	p.version = sumVersion(version)
+
+	clusters, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT "store_nbr", MAX("cluster") FROM %s GROUP BY "store_nbr"`, cfg.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store clusters: %w", err)
+	}
+	defer clusters.Close()
+	for clusters.Next() {
+		var storeNbr int
+		var cluster sql.NullInt64
+		if err := clusters.Scan(&storeNbr, &cluster); err != nil {
+			return nil, fmt.Errorf("failed to read store clusters: %w", err)
+		}
+		p.clusters[storeNbr] = int(cluster.Int64)
+	}
+	if err := clusters.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read store clusters: %w", err)
+	}
+
+	return p, nil
+}
+
+// validateTableSchema fails fast on a table that lacks a key column or a feature of
+// the model's schema. Column types aren't checked since SQLite columns are untyped.
+func validateTableSchema(ctx context.Context, db *sql.DB, table string) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 0`, table))
+	if err != nil {
+		return fmt.Errorf("failed to query feature table columns: %w", err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read feature table columns: %w", err)
+	}
+	columns := make([]schema.SourceColumn, len(names))
+	for i, name := range names {
+		columns[i] = schema.SourceColumn{Name: name, Numeric: true}
+	}
+	return schema.Features.Validate(table, columns, keyColumns...)
+}
+
+// context returns a context bounded by the query timeout.
+func (p *sqlProvider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), p.cfg.QueryTimeout)
+}
+
+// queryVector runs a query returning the feature columns, caching the first row under key.
+func (p *sqlProvider) queryVector(key, query string, args ...any) ([]float32, bool) {
+	if features, ok, cached := p.cache.get(key); cached {
+		return features, ok
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+	features, err := scanVector(p.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			// Not cached, so the next lookup retries
+			log.Warn().Err(err).Str("key", key).Msg("Feature query failed")
+			return nil, false
+		}
+		p.cache.add(key, nil, false)
+		return nil, false
+	}
+	p.cache.add(key, features, true)
+	return features, true
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanVector reads one row of feature columns. Nulls, including the all-null row of an
+// aggregate over no rows, are reported as sql.ErrNoRows.
+func scanVector(row scanner) ([]float32, error) {
+	values := make([]sql.NullFloat64, NumFeatures)
+	dest := make([]any, NumFeatures)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if !values[0].Valid {
+		return nil, sql.ErrNoRows
+	}
+	features := make([]float32, NumFeatures)
+	for i, v := range values {
+		features[i] = float32(v.Float64)
+	}
+	return features, nil
+}
+
+// Features returns the feature vector of (storeNbr, family, date).
+func (p *sqlProvider) Features(storeNbr int, family string, date time.Time) ([]float32, bool) {
+	day := date.Format("2006-01-02")
+	return p.queryVector(
+		fmt.Sprintf("row:%d:%s:%s", storeNbr, family, day),
+		fmt.Sprintf(`SELECT %s FROM %s WHERE "store_nbr" = ? AND "family" = ? AND %s = ? LIMIT 1`,
+			p.columns, p.cfg.Table, p.dayExpr),
+		storeNbr, family, day)
+}
+
+// Aggregate returns the average feature vector of a series.
+func (p *sqlProvider) Aggregate(storeNbr int, family string) ([]float32, bool) {
+	if _, ok := p.lastDates[Series{StoreNbr: storeNbr, Family: family}]; !ok {
+		return nil, false
+	}
+	avg := make([]string, NumFeatures)
+	for i, name := range schema.Features.Names() {
+		avg[i] = fmt.Sprintf(`AVG(COALESCE("%s", 0))`, name)
+	}
+	return p.queryVector(
+		fmt.Sprintf("agg:%d:%s", storeNbr, family),
+		fmt.Sprintf(`SELECT %s FROM %s WHERE "store_nbr" = ? AND "family" = ?`,
+			strings.Join(avg, ", "), p.cfg.Table),
+		storeNbr, family)
+}
+
+// LastDate returns the newest date of a series.
+func (p *sqlProvider) LastDate(storeNbr int, family string) (time.Time, bool) {
+	last, ok := p.lastDates[Series{StoreNbr: storeNbr, Family: family}]
+	return last, ok
+}
+
+// Window returns the vectors of every series for dates from start to end inclusive.
+// Windows are read straight from the database and not cached.
+func (p *sqlProvider) Window(start, end time.Time) [][]float32 {
+	ctx, cancel := p.context()
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT %s FROM %s WHERE %s BETWEEN ? AND ?`, p.columns, p.cfg.Table, p.dayExpr),
+		start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		log.Warn().Err(err).Msg("Feature window query failed")
+		return nil
+	}
+	defer rows.Close()
+
+	var window [][]float32
+	for rows.Next() {
+		features, err := scanVector(rows)
+		if err != nil {
+			log.Warn().Err(err).Msg("Feature window query failed")
+			return nil
+		}
+		window = append(window, features)
+	}
+	if err := rows.Err(); err != nil {
+		log.Warn().Err(err).Msg("Feature window query failed")
+		return nil
+	}
+	return window
+}
+
+// Series returns every series, ordered by store then family.
+func (p *sqlProvider) Series() []Series {
+	return append([]Series(nil), p.series...)
+}
+
+// Cluster returns the cluster of a store.
+func (p *sqlProvider) Cluster(storeNbr int) (int, bool) {
+	cluster, ok := p.clusters[storeNbr]
+	return cluster, ok
+}
+
+// Size returns the number of rows in the feature table.
+func (p *sqlProvider) Size() int {
+	return p.rows
+}
+
+// AggregatedSize returns the number of series; averages are computed on demand.
+func (p *sqlProvider) AggregatedSize() int {
+	return len(p.series)
+}
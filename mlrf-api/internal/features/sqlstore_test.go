@@ -0,0 +1,405 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// fakeFeatureDB answers the queries of sqlProvider from rows held in memory.
+type fakeFeatureDB struct {
+	mu      sync.Mutex
+	rows    []fakeFeatureRow
+	queries int
+	drop    string // Column left out of the table schema
+}
+
+type fakeFeatureRow struct {
+	store    int64
+	family   string
+	day      string
+	features []float32
+}
+
+var (
+	fakeDBOnce sync.Once
+	fakeDB     = &fakeFeatureDB{}
+)
+
+func (d *fakeFeatureDB) Open(string) (driver.Conn, error) { return d, nil }
+
+func (d *fakeFeatureDB) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (d *fakeFeatureDB) Close() error { return nil }
+
+func (d *fakeFeatureDB) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (d *fakeFeatureDB) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries++
+
+	vector := func(f []float32) []driver.Value {
+		row := make([]driver.Value, NumFeatures)
+		for i, v := range f {
+			row[i] = float64(v)
+		}
+		return row
+	}
+	out := &fakeRows{}
+	switch {
+	case strings.Contains(query, "LIMIT 0"):
+		for _, name := range append(append([]string{}, keyColumns...), schema.Features.Names()...) {
+			if name != d.drop {
+				out.names = append(out.names, name)
+			}
+		}
+	case strings.Contains(query, `GROUP BY "store_nbr", "family"`):
+		out.cols = 5
+		type series struct {
+			store  int64
+			family string
+		}
+		index := map[series]int{}
+		for _, r := range d.rows {
+			k := series{r.store, r.family}
+			i, ok := index[k]
+			if !ok {
+				i = len(out.rows)
+				index[k] = i
+				out.rows = append(out.rows, []driver.Value{r.store, r.family, r.day, r.day, int64(0)})
+			}
+			row := out.rows[i]
+			row[2] = min(row[2].(string), r.day)
+			row[3] = max(row[3].(string), r.day)
+			row[4] = row[4].(int64) + 1
+		}
+	case strings.Contains(query, `MAX("cluster")`):
+		out.cols = 2
+		seen := map[int64]bool{}
+		for _, r := range d.rows {
+			if !seen[r.store] {
+				seen[r.store] = true
+				out.rows = append(out.rows, []driver.Value{r.store, int64(r.features[idxCluster])})
+			}
+		}
+	case strings.Contains(query, "AVG("):
+		out.cols = NumFeatures
+		sum := make([]float32, NumFeatures)
+		n := 0
+		for _, r := range d.rows {
+			if r.store == args[0].Value.(int64) && r.family == args[1].Value.(string) {
+				for i, v := range r.features {
+					sum[i] += v
+				}
+				n++
+			}
+		}
+		if n == 0 {
+			out.rows = append(out.rows, make([]driver.Value, NumFeatures))
+			break
+		}
+		for i := range sum {
+			sum[i] /= float32(n)
+		}
+		out.rows = append(out.rows, vector(sum))
+	case strings.Contains(query, "LIMIT 1"):
+		out.cols = NumFeatures
+		for _, r := range d.rows {
+			if r.store == args[0].Value.(int64) && r.family == args[1].Value.(string) && r.day == args[2].Value.(string) {
+				out.rows = append(out.rows, vector(r.features))
+			}
+		}
+	case strings.Contains(query, "BETWEEN"):
+		out.cols = NumFeatures
+		for _, r := range d.rows {
+			if r.day >= args[0].Value.(string) && r.day <= args[1].Value.(string) {
+				out.rows = append(out.rows, vector(r.features))
+			}
+		}
+	default:
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return out, nil
+}
+
+func (d *fakeFeatureDB) queryCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.queries
+}
+
+type fakeRows struct {
+	cols  int
+	names []string
+	rows  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if r.names != nil {
+		return r.names
+	}
+	return make([]string, r.cols)
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// newFakeSQLStore opens a SQLite-mode store over rows served by the fake driver.
+func newFakeSQLStore(t *testing.T, rows []fakeFeatureRow) *Store {
+	t.Helper()
+	fakeDBOnce.Do(func() { sql.Register("fakefeatures", fakeDB) })
+	fakeDB.mu.Lock()
+	fakeDB.rows, fakeDB.queries, fakeDB.drop = rows, 0, ""
+	fakeDB.mu.Unlock()
+
+	s, err := NewSQLStore(BackendConfig{
+		Backend:      BackendSQLite,
+		Driver:       "fakefeatures",
+		Table:        "features",
+		CacheSize:    2,
+		QueryTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSQLStore failed: %v", err)
+	}
+	return s
+}
+
+func TestSQLStore(t *testing.T) {
+	vector := func(lag, cluster float32) []float32 {
+		f := make([]float32, NumFeatures)
+		f[idxSalesLag1] = lag
+		f[idxCluster] = cluster
+		return f
+	}
+	s := newFakeSQLStore(t, []fakeFeatureRow{
+		{1, "DAIRY", "2017-08-01", vector(10, 4)},
+		{1, "DAIRY", "2017-08-02", vector(20, 4)},
+		{2, "EGGS", "2017-08-02", vector(5, 7)},
+	})
+
+	meta := s.GetMetadata()
+	if meta.Backend != BackendSQLite || meta.RowCount != 3 || meta.DataDateMin != "2017-08-01" || meta.DataDateMax != "2017-08-02" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if s.Size() != 3 || len(s.Series()) != 2 {
+		t.Errorf("expected 3 rows in 2 series, got %d in %v", s.Size(), s.Series())
+	}
+	if last, ok := s.LastDate(1, "DAIRY"); !ok || last.Format("2006-01-02") != "2017-08-02" {
+		t.Errorf("expected last date 2017-08-02, got %s", last)
+	}
+	if cluster, ok := s.Cluster(2); !ok || cluster != 7 {
+		t.Errorf("expected cluster 7 for store 2, got %d", cluster)
+	}
+
+	// Exact lookups go to the database once, then hit the cache
+	queries := fakeDB.queryCount()
+	for i := 0; i < 3; i++ {
+		f, ok := s.GetFeatures(1, "DAIRY", "2017-08-02")
+		if !ok || f[idxSalesLag1] != 20 {
+			t.Fatalf("expected sales_lag_1 20, got %v", f)
+		}
+	}
+	if n := fakeDB.queryCount() - queries; n != 1 {
+		t.Errorf("expected 1 query for repeated lookups, got %d", n)
+	}
+
+	// Unknown dates fall back to the series average
+	if f, ok := s.GetFeatures(1, "DAIRY", "2017-09-01"); !ok || f[idxSalesLag1] != 15 {
+		t.Errorf("expected aggregated sales_lag_1 15, got %v", f)
+	}
+	if _, ok := s.GetFeatures(3, "BREAD", "2017-08-01"); ok {
+		t.Error("expected no features for an unknown series")
+	}
+
+	if rows := s.Window("2017-08-02", "2017-08-02"); len(rows) != 2 {
+		t.Errorf("expected 2 rows in window, got %d", len(rows))
+	}
+	if _, err := s.Append("delta.parquet"); err == nil {
+		t.Error("expected appends to be rejected by the SQL backend")
+	}
+
+	// Load rereads the index and drops cached vectors
+	fakeDB.mu.Lock()
+	fakeDB.rows = append(fakeDB.rows, fakeFeatureRow{1, "DAIRY", "2017-08-03", vector(30, 4)})
+	fakeDB.mu.Unlock()
+	if err := s.Load(""); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if last, _ := s.LastDate(1, "DAIRY"); last.Format("2006-01-02") != "2017-08-03" {
+		t.Errorf("expected last date 2017-08-03 after reload, got %s", last)
+	}
+	if f, _ := s.GetFeatures(1, "DAIRY", "2017-09-01"); f[idxSalesLag1] != 20 {
+		t.Errorf("expected the reloaded average 20, got %v", f[idxSalesLag1])
+	}
+}
+
+// writeFeatureDB creates a features table in a new SQLite or DuckDB database file and
+// returns its DSN.
+func writeFeatureDB(t *testing.T, backend string, rows []fakeFeatureRow) string {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "features."+backend)
+	db, err := sql.Open(map[string]string{BackendSQLite: "sqlite", BackendDuckDB: "duckdb"}[backend], dsn)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", backend, err)
+	}
+	defer db.Close()
+
+	columns := []string{`"store_nbr" INTEGER`, `"family" VARCHAR`, `"date" DATE`}
+	placeholders := []string{"?", "?", "?"}
+	for _, name := range schema.Features.Names() {
+		columns = append(columns, fmt.Sprintf(`"%s" DOUBLE`, name))
+		placeholders = append(placeholders, "?")
+	}
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE features (%s)", strings.Join(columns, ", "))); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	insert := fmt.Sprintf("INSERT INTO features VALUES (%s)", strings.Join(placeholders, ", "))
+	for _, r := range rows {
+		args := []any{r.store, r.family, r.day}
+		for _, v := range r.features {
+			args = append(args, float64(v))
+		}
+		if _, err := db.Exec(insert, args...); err != nil {
+			t.Fatalf("failed to insert row: %v", err)
+		}
+	}
+	return dsn
+}
+
+func TestSQLStoreDrivers(t *testing.T) {
+	vector := func(lag, cluster float32) []float32 {
+		f := make([]float32, NumFeatures)
+		f[idxSalesLag1] = lag
+		f[idxCluster] = cluster
+		return f
+	}
+	rows := []fakeFeatureRow{
+		{1, "DAIRY", "2017-08-01", vector(10, 4)},
+		{1, "DAIRY", "2017-08-02", vector(20, 4)},
+		{2, "EGGS", "2017-08-02", vector(5, 7)},
+	}
+
+	for _, backend := range []string{BackendSQLite, BackendDuckDB} {
+		t.Run(backend, func(t *testing.T) {
+			cfg := DefaultBackendConfig(backend, "")
+			cfg.DSN = writeFeatureDB(t, backend, rows)
+			cfg.Table = "features"
+			s, err := NewSQLStore(cfg)
+			if err != nil {
+				t.Fatalf("NewSQLStore failed: %v", err)
+			}
+
+			if meta := s.GetMetadata(); meta.RowCount != 3 || meta.DataDateMin != "2017-08-01" || meta.DataDateMax != "2017-08-02" {
+				t.Errorf("unexpected metadata %+v", meta)
+			}
+			if last, ok := s.LastDate(1, "DAIRY"); !ok || last.Format("2006-01-02") != "2017-08-02" {
+				t.Errorf("expected last date 2017-08-02, got %s", last)
+			}
+			if cluster, ok := s.Cluster(2); !ok || cluster != 7 {
+				t.Errorf("expected cluster 7 for store 2, got %d", cluster)
+			}
+			if f, ok := s.GetFeatures(1, "DAIRY", "2017-08-02"); !ok || f[idxSalesLag1] != 20 {
+				t.Errorf("expected sales_lag_1 20, got %v", f)
+			}
+			if f, ok := s.GetFeatures(1, "DAIRY", "2017-09-01"); !ok || f[idxSalesLag1] != 15 {
+				t.Errorf("expected aggregated sales_lag_1 15, got %v", f)
+			}
+			if window := s.Window("2017-08-02", "2017-08-02"); len(window) != 2 {
+				t.Errorf("expected 2 rows in window, got %d", len(window))
+			}
+		})
+	}
+}
+
+func TestSQLStoreSchemaMismatch(t *testing.T) {
+	fakeDBOnce.Do(func() { sql.Register("fakefeatures", fakeDB) })
+	fakeDB.mu.Lock()
+	fakeDB.rows, fakeDB.drop = nil, "sales_lag_7"
+	fakeDB.mu.Unlock()
+	defer func() {
+		fakeDB.mu.Lock()
+		fakeDB.drop = ""
+		fakeDB.mu.Unlock()
+	}()
+
+	_, err := NewSQLStore(BackendConfig{
+		Backend:      BackendSQLite,
+		Driver:       "fakefeatures",
+		Table:        "features",
+		CacheSize:    2,
+		QueryTimeout: time.Second,
+	})
+	var mismatch *schema.MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a schema mismatch, got %v", err)
+	}
+	if len(mismatch.Missing) != 1 || mismatch.Missing[0] != "sales_lag_7" {
+		t.Errorf("expected sales_lag_7 missing, got %v", mismatch.Missing)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+	c.add("a", []float32{1}, true)
+	c.add("b", nil, false)
+	c.get("a") // b is now the least recently used
+	c.add("c", []float32{3}, true)
+
+	if _, _, cached := c.get("b"); cached {
+		t.Error("expected b to be evicted")
+	}
+	if f, ok, cached := c.get("a"); !cached || !ok || f[0] != 1 {
+		t.Errorf("expected a to be cached, got %v %v %v", f, ok, cached)
+	}
+	if c.len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.len())
+	}
+
+	disabled := newLRUCache(0)
+	disabled.add("a", []float32{1}, true)
+	if disabled.len() != 0 {
+		t.Error("expected a zero-size cache to hold nothing")
+	}
+}
+
+func TestDefaultBackendConfig(t *testing.T) {
+	if cfg := DefaultBackendConfig("", "f.parquet"); cfg.Backend != BackendMemory {
+		t.Errorf("expected the memory backend by default, got %q", cfg.Backend)
+	}
+
+	cfg := DefaultBackendConfig(BackendDuckDB, "data/features.parquet")
+	if cfg.Driver != "duckdb" || cfg.Table != "read_parquet('data/features.parquet')" {
+		t.Errorf("unexpected duckdb config %+v", cfg)
+	}
+	if cfg.CacheSize != 100000 {
+		t.Errorf("expected the default cache size, got %d", cfg.CacheSize)
+	}
+
+	if _, err := NewSQLStore(BackendConfig{Backend: BackendDuckDB, Driver: "missing", Table: "t"}); err == nil {
+		t.Error("expected an error for an unregistered driver")
+	}
+}
@@ -3,17 +3,20 @@ package features
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/mlrf/mlrf-api/internal/schema"
 	"github.com/parquet-go/parquet-go"
 	"github.com/rs/zerolog/log"
 )
 
 // NumFeatures is the number of features expected by the model.
-const NumFeatures = 27
+const NumFeatures = schema.NumFeatures
 
 // DefaultStalenessThreshold is the default max age before features are considered stale.
 var DefaultStalenessThreshold = 24 * time.Hour
@@ -26,16 +29,19 @@ type Metadata struct {
 	RowCount    int       `json:"row_count"`
 	DataDateMin string    `json:"data_date_min"`
 	DataDateMax string    `json:"data_date_max"`
-	Version     string    `json:"version"`
+	Version     string    `json:"version"`           // Hash of the data, the same wherever it is loaded
+	Appends     int       `json:"appends,omitempty"` // Delta files merged since the full load
+	Backend     string    `json:"backend"`           // BackendMemory, BackendDuckDB or BackendSQLite
 }
 
 // Store provides fast feature lookup by (store_nbr, family, date).
 type Store struct {
-	// index maps "storeNbr_family_date" -> feature vector
-	index map[string][]float32
+	// cols indexes feature vectors, per-series average vectors (fallback), the
+	// last date of each series and store clusters
+	cols *columns
 
-	// aggregated maps "storeNbr_family" -> average feature vector (fallback)
-	aggregated map[string][]float32
+	// provider serves lookups instead of cols when a database backend is configured
+	provider FeatureProvider
 
 	// metadata tracks freshness information
 	metadata Metadata
@@ -43,54 +49,72 @@ type Store struct {
 	// stalenessThreshold defines how old data can be before considered stale
 	stalenessThreshold time.Duration
 
+	// qualityCfg sets the validation thresholds applied on Load
+	qualityCfg QualityConfig
+
+	// quality is the validation report of the loaded data; rejectedQuality is the
+	// report of a file rejected since then, if any
+	quality         *QualityReport
+	rejectedQuality *QualityReport
+
 	mu     sync.RWMutex
 	loaded bool
+
+	// loadMu serializes loads so only one new index is built at a time
+	loadMu sync.Mutex
 }
 
-// FeatureRow represents a row from the feature matrix parquet file.
+// FeatureRow represents a row from the feature matrix parquet file, or a streamed
+// feature update in JSON with the same column names.
 type FeatureRow struct {
-	StoreNbr int32     `parquet:"store_nbr"`
-	Family   string    `parquet:"family"`
-	Date     time.Time `parquet:"date"`
+	StoreNbr int32     `parquet:"store_nbr" json:"store_nbr"`
+	Family   string    `parquet:"family" json:"family"`
+	Date     time.Time `parquet:"date" json:"date"`
 
 	// Numeric features
-	Year           int32   `parquet:"year"`
-	Month          int32   `parquet:"month"`
-	Day            int32   `parquet:"day"`
-	DayOfWeek      int32   `parquet:"dayofweek"`
-	DayOfYear      int32   `parquet:"dayofyear"`
-	IsMidMonth     int32   `parquet:"is_mid_month"`
-	IsLeapYear     int32   `parquet:"is_leap_year"`
-	OilPrice       float64 `parquet:"oil_price"`
-	IsHoliday      int32   `parquet:"is_holiday"`
-	OnPromotion    int32   `parquet:"onpromotion"`
-	PromoRolling7  float64 `parquet:"promo_rolling_7"`
-	Cluster        int32   `parquet:"cluster"`
-	SalesLag1      float64 `parquet:"sales_lag_1"`
-	SalesLag7      float64 `parquet:"sales_lag_7"`
-	SalesLag14     float64 `parquet:"sales_lag_14"`
-	SalesLag28     float64 `parquet:"sales_lag_28"`
-	SalesLag90     float64 `parquet:"sales_lag_90"`
-	SalesRolMean7  float64 `parquet:"sales_rolling_mean_7"`
-	SalesRolMean14 float64 `parquet:"sales_rolling_mean_14"`
-	SalesRolMean28 float64 `parquet:"sales_rolling_mean_28"`
-	SalesRolMean90 float64 `parquet:"sales_rolling_mean_90"`
-	SalesRolStd7   float64 `parquet:"sales_rolling_std_7"`
-	SalesRolStd14  float64 `parquet:"sales_rolling_std_14"`
-	SalesRolStd28  float64 `parquet:"sales_rolling_std_28"`
-	SalesRolStd90  float64 `parquet:"sales_rolling_std_90"`
+	Year           int32   `parquet:"year" json:"year"`
+	Month          int32   `parquet:"month" json:"month"`
+	Day            int32   `parquet:"day" json:"day"`
+	DayOfWeek      int32   `parquet:"dayofweek" json:"dayofweek"`
+	DayOfYear      int32   `parquet:"dayofyear" json:"dayofyear"`
+	IsMidMonth     int32   `parquet:"is_mid_month" json:"is_mid_month"`
+	IsLeapYear     int32   `parquet:"is_leap_year" json:"is_leap_year"`
+	OilPrice       float64 `parquet:"oil_price" json:"oil_price"`
+	IsHoliday      int32   `parquet:"is_holiday" json:"is_holiday"`
+	OnPromotion    int32   `parquet:"onpromotion" json:"onpromotion"`
+	PromoRolling7  float64 `parquet:"promo_rolling_7" json:"promo_rolling_7"`
+	Cluster        int32   `parquet:"cluster" json:"cluster"`
+	SalesLag1      float64 `parquet:"sales_lag_1" json:"sales_lag_1"`
+	SalesLag7      float64 `parquet:"sales_lag_7" json:"sales_lag_7"`
+	SalesLag14     float64 `parquet:"sales_lag_14" json:"sales_lag_14"`
+	SalesLag28     float64 `parquet:"sales_lag_28" json:"sales_lag_28"`
+	SalesLag90     float64 `parquet:"sales_lag_90" json:"sales_lag_90"`
+	SalesRolMean7  float64 `parquet:"sales_rolling_mean_7" json:"sales_rolling_mean_7"`
+	SalesRolMean14 float64 `parquet:"sales_rolling_mean_14" json:"sales_rolling_mean_14"`
+	SalesRolMean28 float64 `parquet:"sales_rolling_mean_28" json:"sales_rolling_mean_28"`
+	SalesRolMean90 float64 `parquet:"sales_rolling_mean_90" json:"sales_rolling_mean_90"`
+	SalesRolStd7   float64 `parquet:"sales_rolling_std_7" json:"sales_rolling_std_7"`
+	SalesRolStd14  float64 `parquet:"sales_rolling_std_14" json:"sales_rolling_std_14"`
+	SalesRolStd28  float64 `parquet:"sales_rolling_std_28" json:"sales_rolling_std_28"`
+	SalesRolStd90  float64 `parquet:"sales_rolling_std_90" json:"sales_rolling_std_90"`
 
 	// Categorical features (encoded as int for model)
-	FamilyEncoded int32 `parquet:"family_encoded,optional"`
-	TypeEncoded   int32 `parquet:"type_encoded,optional"`
+	FamilyEncoded int32 `parquet:"family_encoded,optional" json:"family_encoded"`
+	TypeEncoded   int32 `parquet:"type_encoded,optional" json:"type_encoded"`
 }
 
 // NewStore creates a new feature store from a parquet file.
 func NewStore(parquetPath string) (*Store, error) {
+	return NewStoreWithQuality(parquetPath, DefaultQualityConfig())
+}
+
+// NewStoreWithQuality creates a new feature store from a parquet file, validating it
+// and later reloads with the quality thresholds of cfg.
+func NewStoreWithQuality(parquetPath string, cfg QualityConfig) (*Store, error) {
 	s := &Store{
-		index:              make(map[string][]float32),
-		aggregated:         make(map[string][]float32),
+		cols:               newColumns(0),
 		stalenessThreshold: DefaultStalenessThreshold,
+		qualityCfg:         cfg,
 	}
 
 	if err := s.Load(parquetPath); err != nil {
@@ -106,10 +130,78 @@ func (s *Store) SetStalenessThreshold(d time.Duration) {
 	s.stalenessThreshold = d
 }
 
-// Load reads the parquet file and builds the in-memory index.
+// StalenessThreshold returns the max age before features are considered stale.
+func (s *Store) StalenessThreshold() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stalenessThreshold
+}
+
+// SetQualityConfig sets the validation thresholds applied by subsequent loads.
+func (s *Store) SetQualityConfig(cfg QualityConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.qualityCfg = cfg
+}
+
+// Quality returns the validation report of the loaded data and the report of a file
+// rejected since it was loaded, if any. Both are nil before the first load.
+func (s *Store) Quality() (current, rejected *QualityReport) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.quality, s.rejectedQuality
+}
+
+// Snapshot is the data a Store serves, captured so a load can be undone.
+type Snapshot struct {
+	cols     *columns
+	provider FeatureProvider
+	metadata Metadata
+	quality  *QualityReport
+	loaded   bool
+}
+
+// Snapshot captures the data the store serves. It shares the index rather than
+// copying it, so holding one keeps the index in memory until it is released.
+func (s *Store) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Snapshot{cols: s.cols, provider: s.provider, metadata: s.metadata, quality: s.quality, loaded: s.loaded}
+}
+
+// Restore serves the data of snap again, dropping whatever was loaded or appended
+// since it was taken.
+func (s *Store) Restore(snap Snapshot) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cols = snap.cols
+	s.provider = snap.provider
+	s.metadata = snap.metadata
+	s.quality = snap.quality
+	s.rejectedQuality = nil
+	s.loaded = snap.loaded
+}
+
+// Load reads the parquet file and builds the in-memory index. The new index is built
+// off to the side and swapped in only after the whole file has been read and validated,
+// so lookups during a reload see either the previous data or the new data in full. In
+// reject mode a file that fails validation returns a *QualityError and the previously
+// loaded data keeps serving. A database-backed store ignores parquetPath and rereads
+// its series index instead, clearing the hot cache.
 func (s *Store) Load(parquetPath string) error {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
 	start := time.Now()
 
+	s.mu.RLock()
+	p, ok := s.provider.(*sqlProvider)
+	s.mu.RUnlock()
+	if ok {
+		return s.loadSQL(p.db, p.cfg)
+	}
+
 	// Check if file exists
 	if _, err := os.Stat(parquetPath); os.IsNotExist(err) {
 		return fmt.Errorf("feature file not found: %s", parquetPath)
@@ -128,20 +220,27 @@ func (s *Store) Load(parquetPath string) error {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
 
+	version, err := contentVersion(io.NewSectionReader(file, 0, stat.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to read parquet file: %w", err)
+	}
+
+	pf, err := openParquet(file, stat.Size(), parquetPath)
+	if err != nil {
+		return err
+	}
+
 	// Create parquet reader (schema inferred from FeatureRow struct tags)
-	reader := parquet.NewReader(file)
+	reader := parquet.NewReader(pf)
 	defer reader.Close()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Clear existing data for reload
-	s.index = make(map[string][]float32)
-	s.aggregated = make(map[string][]float32)
+	s.mu.RLock()
+	qualityCfg := s.qualityCfg
+	s.mu.RUnlock()
+	check := newQualityCheck(pf)
 
-	// Track aggregation data for fallback
-	aggSum := make(map[string][]float64)
-	aggCount := make(map[string]int)
+	// Build a fresh index so a rejected file leaves the current data in place
+	cols := newColumns(int(pf.NumRows()))
 
 	// Track date range for metadata
 	var minDate, maxDate time.Time
@@ -150,9 +249,12 @@ func (s *Store) Load(parquetPath string) error {
 	rowCount := 0
 	for {
 		var row FeatureRow
-		err := reader.Read(&row)
-		if err != nil {
-			break // End of file or error
+		if err := reader.Read(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			// A partial read never replaces the current data
+			return fmt.Errorf("failed to read feature row %d: %w", rowCount, err)
 		}
 
 		// Track date range
@@ -169,23 +271,11 @@ func (s *Store) Load(parquetPath string) error {
 			}
 		}
 
-		// Build key (format date as YYYY-MM-DD)
-		dateStr := row.Date.Format("2006-01-02")
-		key := fmt.Sprintf("%d_%s_%s", row.StoreNbr, row.Family, dateStr)
-		aggKey := fmt.Sprintf("%d_%s", row.StoreNbr, row.Family)
-
 		// Extract features as float32 array
 		features := rowToFeatures(&row)
-		s.index[key] = features
-
-		// Accumulate for aggregated fallback
-		if _, ok := aggSum[aggKey]; !ok {
-			aggSum[aggKey] = make([]float64, NumFeatures)
-		}
-		for i, f := range features {
-			aggSum[aggKey][i] += float64(f)
-		}
-		aggCount[aggKey]++
+		check.observe(features)
+		cols.put(int(row.StoreNbr), row.Family, row.Date, features)
+		cols.clusters[int(row.StoreNbr)] = int(row.Cluster)
 
 		rowCount++
 		if rowCount%500000 == 0 {
@@ -193,15 +283,38 @@ func (s *Store) Load(parquetPath string) error {
 		}
 	}
 
+	report := check.report(qualityCfg, parquetPath, rowCount)
+	if err := s.rejectOnQuality(report); err != nil {
+		return err
+	}
+
 	// Compute aggregated averages
-	for key, sum := range aggSum {
-		count := float64(aggCount[key])
+	sum := make([]float64, NumFeatures)
+	cols.each(func(_ int, _ string, sc *seriesColumn) {
+		clear(sum)
+		n := 0
+		for _, r := range sc.days {
+			if r < 0 {
+				continue
+			}
+			for i, f := range cols.row(r) {
+				sum[i] += float64(f)
+			}
+			n++
+		}
 		avg := make([]float32, NumFeatures)
 		for i, v := range sum {
-			avg[i] = float32(v / count)
+			avg[i] = float32(v / float64(n))
 		}
-		s.aggregated[key] = avg
-	}
+		sc.agg, sc.aggN = avg, n
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cols = cols
+	s.quality = &report
+	s.rejectedQuality = nil
 
 	// Update metadata
 	s.metadata = Metadata{
@@ -211,22 +324,84 @@ func (s *Store) Load(parquetPath string) error {
 		RowCount:    rowCount,
 		DataDateMin: minDate.Format("2006-01-02"),
 		DataDateMax: maxDate.Format("2006-01-02"),
-		Version:     fmt.Sprintf("%d", stat.ModTime().Unix()),
+		Version:     version,
+		Backend:     BackendMemory,
 	}
 
 	s.loaded = true
 	log.Info().
 		Int("rows", rowCount).
-		Int("indexed", len(s.index)).
-		Int("aggregated", len(s.aggregated)).
+		Int("indexed", s.cols.live).
+		Int("aggregated", s.cols.AggregatedSize()).
 		Int64("file_size_mb", stat.Size()/(1024*1024)).
 		Str("data_range", fmt.Sprintf("%s to %s", s.metadata.DataDateMin, s.metadata.DataDateMax)).
+		Str("quality", report.Status).
 		Dur("duration", time.Since(start)).
 		Msg("Feature store loaded")
 
 	return nil
 }
 
+// openParquet opens a parquet file, reporting malformed files as errors.
+func openParquet(r io.ReaderAt, size int64, source string) (*parquet.File, error) {
+	pf, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	if err := validateParquetSchema(pf, source); err != nil {
+		return nil, err
+	}
+	return pf, nil
+}
+
+// validateParquetSchema fails fast on a file that lacks a key column or a feature of
+// the model's schema, which would otherwise read back as zeros in every row.
+func validateParquetSchema(pf *parquet.File, source string) error {
+	fields := pf.Schema().Fields()
+	columns := make([]schema.SourceColumn, len(fields))
+	for i, f := range fields {
+		columns[i] = schema.SourceColumn{Name: f.Name(), Numeric: f.Leaf() && isNumericKind(f.Type().Kind())}
+	}
+	return schema.Features.Validate(source, columns, keyColumns...)
+}
+
+// isNumericKind reports whether a parquet physical type reads into a feature value.
+func isNumericKind(k parquet.Kind) bool {
+	switch k {
+	case parquet.Boolean, parquet.Int32, parquet.Int64, parquet.Float, parquet.Double:
+		return true
+	}
+	return false
+}
+
+// rejectOnQuality logs a failed quality check and, when the file is rejected, records
+// its report and returns a *QualityError.
+func (s *Store) rejectOnQuality(report QualityReport) error {
+	if report.Status == QualityPassed {
+		return nil
+	}
+	log.Warn().
+		Str("path", report.FilePath).
+		Str("status", report.Status).
+		Strs("violations", report.Violations).
+		Msg("Feature quality check failed")
+	if report.Status != QualityRejected {
+		return nil
+	}
+	s.mu.Lock()
+	s.rejectedQuality = &report
+	s.mu.Unlock()
+	return &QualityError{Report: report}
+}
+
+// source returns the provider serving lookups. Callers must hold s.mu.
+func (s *Store) source() FeatureProvider {
+	if s.provider != nil {
+		return s.provider
+	}
+	return s.cols
+}
+
 // rowToFeatures converts a FeatureRow to a float32 array for model input.
 func rowToFeatures(row *FeatureRow) []float32 {
 	return []float32{
@@ -266,27 +441,75 @@ func rowToFeatures(row *FeatureRow) []float32 {
 	}
 }
 
+// FeatureSource reports which lookup supplied a feature vector.
+type FeatureSource string
+
+// Feature sources, from most to least faithful.
+const (
+	SourceExact         FeatureSource = "exact"          // The row for the requested date
+	SourceRolledForward FeatureSource = "rolled_forward" // Built past the series' last date by a FeatureBuilder
+	SourceAggregated    FeatureSource = "aggregated"     // The series' average row
+	SourceZeros         FeatureSource = "zeros"          // Unknown series; every feature is zero
+)
+
 // GetFeatures returns features for a specific (store, family, date) combination.
 // Falls back to aggregated features if exact date not found, then to zeros.
+// ok is false only when zeros are returned.
 func (s *Store) GetFeatures(storeNbr int, family, date string) ([]float32, bool) {
+	features, source := s.ResolveFeatures(storeNbr, family, date)
+	return features, source != SourceZeros
+}
+
+// ResolveFeatures is GetFeatures reporting which fallback supplied the features.
+func (s *Store) ResolveFeatures(storeNbr int, family, date string) ([]float32, FeatureSource) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.resolve(storeNbr, family, date)
+}
+
+// FeatureKey identifies a (store, family, date) feature lookup.
+type FeatureKey struct {
+	StoreNbr int
+	Family   string
+	Date     string
+}
+
+// FeatureResult is the outcome of one lookup in GetFeaturesBatch.
+type FeatureResult struct {
+	Features []float32
+	Source   FeatureSource
+}
 
+// GetFeaturesBatch resolves many keys with the fallbacks of ResolveFeatures under a
+// single read lock, so large batches don't contend with a reload per key. Results are
+// in key order, and a reload can't swap the feature matrix part way through a batch.
+func (s *Store) GetFeaturesBatch(keys []FeatureKey) []FeatureResult {
+	results := make([]FeatureResult, len(keys))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i, k := range keys {
+		results[i].Features, results[i].Source = s.resolve(k.StoreNbr, k.Family, k.Date)
+	}
+	return results
+}
+
+// resolve looks up features with the exact, aggregated, zeros fallback. Callers hold s.mu.
+func (s *Store) resolve(storeNbr int, family, date string) ([]float32, FeatureSource) {
 	// Try exact match first
-	key := fmt.Sprintf("%d_%s_%s", storeNbr, family, date)
-	if features, ok := s.index[key]; ok {
-		return features, true
+	if d, err := time.Parse("2006-01-02", date); err == nil {
+		if features, ok := s.source().Features(storeNbr, family, d); ok {
+			return features, SourceExact
+		}
 	}
 
 	// Try aggregated features (average for store+family)
-	aggKey := fmt.Sprintf("%d_%s", storeNbr, family)
-	if features, ok := s.aggregated[aggKey]; ok {
+	if features, ok := s.source().Aggregate(storeNbr, family); ok {
 		log.Debug().
 			Int("store", storeNbr).
 			Str("family", family).
 			Str("date", date).
 			Msg("Using aggregated features")
-		return features, true
+		return features, SourceAggregated
 	}
 
 	// Return zeros as last resort
@@ -295,7 +518,75 @@ func (s *Store) GetFeatures(storeNbr int, family, date string) ([]float32, bool)
 		Str("family", family).
 		Str("date", date).
 		Msg("No features found, using zeros")
-	return make([]float32, NumFeatures), false
+	return make([]float32, NumFeatures), SourceZeros
+}
+
+// LastDate returns the newest date in the feature matrix for a (store, family) series.
+func (s *Store) LastDate(storeNbr int, family string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.source().LastDate(storeNbr, family)
+}
+
+// Lookup returns the feature vector indexed for a date, without the aggregated fallback
+// of GetFeatures.
+func (s *Store) Lookup(storeNbr int, family string, date time.Time) ([]float32, bool) {
+	return s.exact(storeNbr, family, date)
+}
+
+// Actual returns the realized sales of a series on date. The feature matrix records
+// them as sales_lag_1 of the following day, so none is known for a series' last date.
+func (s *Store) Actual(storeNbr int, family string, date time.Time) (float32, bool) {
+	next, ok := s.exact(storeNbr, family, date.AddDate(0, 0, 1))
+	if !ok {
+		return 0, false
+	}
+	return next[idxSalesLag1], true
+}
+
+// Window returns the feature vectors of every series for dates from start to end
+// (inclusive, YYYY-MM-DD), in no particular order.
+func (s *Store) Window(start, end string) [][]float32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return nil
+	}
+	to, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return nil
+	}
+	return s.source().Window(from, to)
+}
+
+// Series identifies a (store, family) time series.
+type Series struct {
+	StoreNbr int    `json:"store_nbr"`
+	Family   string `json:"family"`
+}
+
+// Series returns every (store, family) series in the feature matrix, ordered by store then family.
+func (s *Store) Series() []Series {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.source().Series()
+}
+
+// Cluster returns the cluster of a store.
+func (s *Store) Cluster(storeNbr int) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.source().Cluster(storeNbr)
+}
+
+// HasStore reports whether a store has any series in the feature matrix.
+func (s *Store) HasStore(storeNbr int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.source().Cluster(storeNbr)
+	return ok
 }
 
 // IsLoaded returns whether the feature store has been loaded.
@@ -309,14 +600,14 @@ func (s *Store) IsLoaded() bool {
 func (s *Store) Size() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.index)
+	return s.source().Size()
 }
 
 // AggregatedSize returns the number of aggregated feature vectors.
 func (s *Store) AggregatedSize() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.aggregated)
+	return s.source().AggregatedSize()
 }
 
 // hash64 computes a simple hash for cache key generation.
@@ -1,17 +1,21 @@
 package features
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
 )
 
 func TestGetFeaturesWithNoData(t *testing.T) {
 	// Create empty store (without loading from file)
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     true,
-	}
+	s := &Store{cols: newColumns(0), loaded: true}
 
 	// Should return zeros when no data
 	features, found := s.GetFeatures(1, "GROCERY I", "2017-08-01")
@@ -33,18 +37,14 @@ func TestGetFeaturesWithNoData(t *testing.T) {
 }
 
 func TestGetFeaturesWithExactMatch(t *testing.T) {
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     true,
-	}
+	s := &Store{cols: newColumns(0), loaded: true}
 
 	// Add test data
 	testFeatures := make([]float32, NumFeatures)
 	testFeatures[0] = 2017 // year
 	testFeatures[1] = 8    // month
 	testFeatures[2] = 1    // day
-	s.index["1_GROCERY I_2017-08-01"] = testFeatures
+	s.cols.put(1, "GROCERY I", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), testFeatures)
 
 	// Should find exact match
 	features, found := s.GetFeatures(1, "GROCERY I", "2017-08-01")
@@ -63,16 +63,13 @@ func TestGetFeaturesWithExactMatch(t *testing.T) {
 }
 
 func TestGetFeaturesWithAggregatedFallback(t *testing.T) {
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     true,
-	}
+	s := &Store{cols: newColumns(0), loaded: true}
 
 	// Add aggregated data only (no exact match)
+	s.cols.put(1, "GROCERY I", time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
 	aggFeatures := make([]float32, NumFeatures)
 	aggFeatures[0] = 2016.5 // average year
-	s.aggregated["1_GROCERY I"] = aggFeatures
+	s.cols.setAggregate(1, "GROCERY I", aggFeatures, 1)
 
 	// Should fall back to aggregated features
 	features, found := s.GetFeatures(1, "GROCERY I", "2017-08-01")
@@ -86,12 +83,62 @@ func TestGetFeaturesWithAggregatedFallback(t *testing.T) {
 	}
 }
 
-func TestIsLoaded(t *testing.T) {
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     false,
+func TestResolveFeatures(t *testing.T) {
+	s := &Store{cols: newColumns(0), loaded: true}
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
+	s.cols.setAggregate(1, "DAIRY", make([]float32, NumFeatures), 1)
+
+	for _, tc := range []struct {
+		store int
+		date  string
+		want  FeatureSource
+	}{
+		{1, "2017-08-01", SourceExact},
+		{1, "2017-08-02", SourceAggregated},
+		{1, "not a date", SourceAggregated},
+		{2, "2017-08-01", SourceZeros},
+	} {
+		if _, source := s.ResolveFeatures(tc.store, "DAIRY", tc.date); source != tc.want {
+			t.Errorf("store %d on %s: expected %s, got %s", tc.store, tc.date, tc.want, source)
+		}
 	}
+}
+
+func TestGetFeaturesBatch(t *testing.T) {
+	s := &Store{cols: newColumns(0), loaded: true}
+	exact := make([]float32, NumFeatures)
+	exact[0] = 2017
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), exact)
+	s.cols.setAggregate(1, "DAIRY", make([]float32, NumFeatures), 1)
+
+	keys := []FeatureKey{
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-01"},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01"},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-02"},
+	}
+	results := s.GetFeaturesBatch(keys)
+	if len(results) != len(keys) {
+		t.Fatalf("expected %d results, got %d", len(keys), len(results))
+	}
+	for i, want := range []FeatureSource{SourceZeros, SourceExact, SourceAggregated} {
+		if results[i].Source != want {
+			t.Errorf("key %d: expected %s, got %s", i, want, results[i].Source)
+		}
+		if len(results[i].Features) != NumFeatures {
+			t.Errorf("key %d: expected %d features, got %d", i, NumFeatures, len(results[i].Features))
+		}
+	}
+	if results[1].Features[0] != 2017 {
+		t.Errorf("expected exact row, got year %v", results[1].Features[0])
+	}
+
+	if got := s.GetFeaturesBatch(nil); len(got) != 0 {
+		t.Errorf("expected no results for no keys, got %d", len(got))
+	}
+}
+
+func TestIsLoaded(t *testing.T) {
+	s := &Store{cols: newColumns(0), loaded: false}
 
 	if s.IsLoaded() {
 		t.Error("expected IsLoaded()=false")
@@ -183,18 +230,14 @@ func TestRowToFeatures(t *testing.T) {
 }
 
 func TestSize(t *testing.T) {
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     true,
-	}
+	s := &Store{cols: newColumns(0), loaded: true}
 
 	if s.Size() != 0 {
 		t.Errorf("expected size=0, got %d", s.Size())
 	}
 
-	s.index["key1"] = make([]float32, NumFeatures)
-	s.index["key2"] = make([]float32, NumFeatures)
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
 
 	if s.Size() != 2 {
 		t.Errorf("expected size=2, got %d", s.Size())
@@ -202,19 +245,227 @@ func TestSize(t *testing.T) {
 }
 
 func TestAggregatedSize(t *testing.T) {
-	s := &Store{
-		index:      make(map[string][]float32),
-		aggregated: make(map[string][]float32),
-		loaded:     true,
-	}
+	s := &Store{cols: newColumns(0), loaded: true}
 
 	if s.AggregatedSize() != 0 {
 		t.Errorf("expected aggregated size=0, got %d", s.AggregatedSize())
 	}
 
-	s.aggregated["key1"] = make([]float32, NumFeatures)
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
+	s.cols.setAggregate(1, "DAIRY", make([]float32, NumFeatures), 1)
 
 	if s.AggregatedSize() != 1 {
 		t.Errorf("expected aggregated size=1, got %d", s.AggregatedSize())
 	}
 }
+
+func TestSeries(t *testing.T) {
+	s := &Store{cols: newColumns(4), loaded: true}
+	date := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	for _, k := range []Series{
+		{StoreNbr: 2, Family: "DAIRY"},
+		{StoreNbr: 10, Family: "AUTOMOTIVE"},
+		{StoreNbr: 2, Family: "BREAD/BAKERY"},
+		{StoreNbr: 1, Family: "LIQUOR,WINE,BEER"},
+	} {
+		s.cols.put(k.StoreNbr, k.Family, date, make([]float32, NumFeatures))
+	}
+
+	want := []Series{
+		{StoreNbr: 1, Family: "LIQUOR,WINE,BEER"},
+		{StoreNbr: 2, Family: "BREAD/BAKERY"},
+		{StoreNbr: 2, Family: "DAIRY"},
+		{StoreNbr: 10, Family: "AUTOMOTIVE"},
+	}
+	got := s.Series()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d series, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("series[%d]: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestActual(t *testing.T) {
+	next := make([]float32, NumFeatures)
+	next[idxSalesLag1] = 42
+	s := &Store{cols: newColumns(2), loaded: true}
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), make([]float32, NumFeatures))
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC), next)
+
+	sales, ok := s.Actual(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC))
+	if !ok || sales != 42 {
+		t.Errorf("expected actual 42 from the next day's lag, got %v (ok=%v)", sales, ok)
+	}
+	if _, ok := s.Actual(1, "DAIRY", time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected no actual for the series' last date")
+	}
+	if _, ok := s.Lookup(1, "DAIRY", time.Date(2017, 8, 3, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected Lookup not to fall back for an unknown date")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	s := &Store{cols: newColumns(4), loaded: true}
+	vector := func(v float32) []float32 {
+		f := make([]float32, NumFeatures)
+		f[0] = v
+		return f
+	}
+	s.cols.put(1, "DAIRY", time.Date(2017, 7, 31, 0, 0, 0, 0, time.UTC), vector(1))
+	s.cols.put(1, "DAIRY", time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), vector(2))
+	s.cols.put(2, "DAIRY", time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC), vector(3))
+	s.cols.put(2, "DAIRY", time.Date(2017, 8, 3, 0, 0, 0, 0, time.UTC), vector(4))
+
+	rows := s.Window("2017-08-01", "2017-08-02")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows in window, got %d", len(rows))
+	}
+	for _, r := range rows {
+		if r[0] != 2 && r[0] != 3 {
+			t.Errorf("unexpected row %v in window", r)
+		}
+	}
+}
+
+func TestLoadSwapsAtomically(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	rows := make([]FeatureRow, 2000)
+	for i := range rows {
+		rows[i] = FeatureRow{StoreNbr: int32(i), Family: "DAIRY", Date: date, SalesLag1: 1}
+	}
+	path := writeFeatureFile(t, rows)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers must never observe a cleared or partially built index during reloads
+	done := make(chan struct{})
+	failures := make(chan string, 1)
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			if err := s.Load(path); err != nil {
+				failures <- err.Error()
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			select {
+			case msg := <-failures:
+				t.Fatalf("reload failed: %s", msg)
+			default:
+			}
+			return
+		default:
+		}
+		if n := s.Size(); n != len(rows) {
+			t.Fatalf("observed %d indexed rows during reload, want %d", n, len(rows))
+		}
+		if _, ok := s.Lookup(1999, "DAIRY", date); !ok {
+			t.Fatal("lookup failed during reload")
+		}
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	s, err := NewStore(writeFeatureFile(t, []FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: date}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := s.Snapshot()
+	version := s.GetMetadata().Version
+
+	if err := s.Load(writeFeatureFile(t, []FeatureRow{{StoreNbr: 2, Family: "DAIRY", Date: date}})); err != nil {
+		t.Fatal(err)
+	}
+	s.Restore(snap)
+
+	if _, ok := s.Lookup(1, "DAIRY", date); !ok || s.HasStore(2) {
+		t.Error("expected the snapshot's rows to serve again")
+	}
+	if s.GetMetadata().Version != version {
+		t.Errorf("expected version %s, got %s", version, s.GetMetadata().Version)
+	}
+}
+
+func TestLoadCorruptFileKeepsData(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	path := writeFeatureFile(t, []FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: date}})
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.parquet")
+	if err := os.WriteFile(corrupt, []byte("not a parquet file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(corrupt); err == nil {
+		t.Fatal("expected an error for a corrupt file")
+	}
+	if s.Size() != 1 || s.FilePath() != path {
+		t.Errorf("expected the failed load to keep the previous data, got %d rows from %s", s.Size(), s.FilePath())
+	}
+}
+
+func TestRowToFeaturesMatchesSchema(t *testing.T) {
+	// rowToFeatures reads FeatureRow fields in declaration order, so the feature
+	// columns of FeatureRow must follow the schema
+	var names []string
+	rt := reflect.TypeOf(FeatureRow{})
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("parquet"), ",")
+		if !slices.Contains(keyColumns, name) {
+			names = append(names, name)
+		}
+	}
+	if !slices.Equal(names, schema.Features.Names()) {
+		t.Errorf("FeatureRow columns %v don't match the schema %v", names, schema.Features.Names())
+	}
+
+	// Each value lands at its schema index
+	row := &FeatureRow{OilPrice: 46.57, Cluster: 14, SalesLag7: 95.3}
+	features := rowToFeatures(row)
+	for name, want := range map[string]float32{"oil_price": 46.57, "cluster": 14, "sales_lag_7": 95.3} {
+		i, _ := schema.Features.Index(name)
+		if features[i] != want {
+			t.Errorf("expected %s=%g at index %d, got %g", name, want, i, features[i])
+		}
+	}
+}
+
+func TestLoadSchemaMismatchKeepsData(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	path := writeFeatureFile(t, []FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: date}})
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type partialRow struct {
+		StoreNbr int32     `parquet:"store_nbr"`
+		Family   string    `parquet:"family"`
+		Date     time.Time `parquet:"date"`
+		OilPrice string    `parquet:"oil_price"`
+	}
+	partial := writeFeatureFile(t, []partialRow{{StoreNbr: 2, Family: "DAIRY", Date: date, OilPrice: "46.57"}})
+	err = s.Load(partial)
+	var mismatch *schema.MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a schema mismatch, got %v", err)
+	}
+	if len(mismatch.Missing) != NumFeatures-3 || !slices.Equal(mismatch.WrongType, []string{"oil_price"}) {
+		t.Errorf("unexpected mismatch %+v", mismatch)
+	}
+	if s.Size() != 1 || s.FilePath() != path {
+		t.Errorf("expected the failed load to keep the previous data, got %d rows from %s", s.Size(), s.FilePath())
+	}
+}
@@ -0,0 +1,61 @@
+package features
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"math"
+)
+
+// Feature versions are derived from the data rather than from when it was loaded, so
+// replicas and restarts serving the same features report the same version and share
+// the prediction cache namespaced by it.
+
+// versionLength is the number of hex digits of a version.
+const versionLength = 16
+
+// contentVersion returns the version of a feature file read from r: a hash of its
+// content.
+func contentVersion(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return sumVersion(h), nil
+}
+
+// deltaVersion returns the version of the data at version prev after merging rows.
+func deltaVersion(prev string, rows []deltaRow) string {
+	h := sha256.New()
+	io.WriteString(h, prev)
+	var buf [8]byte
+	for _, row := range rows {
+		writeSeries(h, row.series)
+		binary.LittleEndian.PutUint64(buf[:], uint64(row.date.Unix()))
+		h.Write(buf[:])
+		binary.LittleEndian.PutUint64(buf[:], uint64(row.cluster))
+		h.Write(buf[:])
+		for _, f := range row.features {
+			binary.LittleEndian.PutUint32(buf[:4], math.Float32bits(f))
+			h.Write(buf[:4])
+		}
+	}
+	return sumVersion(h)
+}
+
+// writeSeries writes a series key to h, the family length-prefixed.
+func writeSeries(h hash.Hash, series Series) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(series.StoreNbr))
+	h.Write(buf[:])
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(series.Family)))
+	h.Write(buf[:])
+	io.WriteString(h, series.Family)
+}
+
+// sumVersion formats the hash written to h as a version.
+func sumVersion(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))[:versionLength]
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// accuracyDataPath holds validation-set accuracy exported by mlrf-ml.
+const accuracyDataPath = "models/accuracy_data.json"
+
 // AccuracyDataPoint represents a single data point with actual vs predicted values.
 type AccuracyDataPoint struct {
 	Date      string  `json:"date"`
@@ -25,12 +28,20 @@ type AccuracySummary struct {
 	MeanError     float32 `json:"mean_error"`
 	MeanMAPE      float32 `json:"mean_mape"`
 	Correlation   float32 `json:"correlation"`
+
+	// Set for live accuracy from submitted actuals
+	Series  int     `json:"series,omitempty"`   // Series-date records evaluated
+	RMSLE   float32 `json:"rmsle,omitempty"`    // Root mean squared log error
+	Bias    float32 `json:"bias,omitempty"`     // Mean prediction - actual per record
+	BiasPct float32 `json:"bias_pct,omitempty"` // Total prediction - actual as % of total actuals
 }
 
 // AccuracyResponse is the response format for the /accuracy endpoint.
 type AccuracyResponse struct {
 	Data    []AccuracyDataPoint `json:"data"`
 	Summary AccuracySummary     `json:"summary"`
+	Source  string              `json:"source,omitempty"`  // "actuals" when computed live from POST /actuals
+	IsMock  bool                `json:"is_mock,omitempty"` // Sample data, without accuracy_data.json or actuals
 }
 
 // mockAccuracyData returns sample accuracy data when the real data file is not available.
@@ -55,7 +66,8 @@ func mockAccuracyData() AccuracyResponse {
 	}
 
 	return AccuracyResponse{
-		Data: data,
+		Data:   data,
+		IsMock: true,
 		Summary: AccuracySummary{
 			DataPoints:    len(data),
 			MeanActual:    924000,
@@ -68,16 +80,47 @@ func mockAccuracyData() AccuracyResponse {
 }
 
 // Accuracy handles requests for model accuracy data (predicted vs actual).
-// Returns aggregated daily accuracy metrics from the validation set.
+// When actuals have been submitted via POST /actuals, returns live daily accuracy
+// metrics (see liveAccuracy). Otherwise returns aggregated daily accuracy metrics
+// from the validation set, or flagged sample data without either (see allowMock).
+// Supports If-None-Match (see notModified).
 func (h *Handlers) Accuracy(w http.ResponseWriter, r *http.Request) {
+	var fileVersion string
+	if info, err := os.Stat(accuracyDataPath); err == nil {
+		fileVersion = info.ModTime().String()
+	}
+	if h.notModified(w, r, fileVersion) {
+		return
+	}
+
+	if h.actuals != nil {
+		live, ok, verr, err := h.liveAccuracy(r)
+		if verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Live accuracy unavailable")
+			WriteServiceUnavailable(w, r, "actuals store unavailable", CodeActualsUnavailable)
+			return
+		}
+		if ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(live)
+			return
+		}
+	}
+
 	// Try to load accuracy data from file
-	data, err := os.ReadFile("models/accuracy_data.json")
+	data, err := os.ReadFile(accuracyDataPath)
 	if err != nil {
 		log.Debug().Err(err).Msg("Could not load accuracy_data.json, using mock data")
 
 		// Return mock data if file doesn't exist
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(mockAccuracyData())
+		if h.allowMock(w, r, "accuracy") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(mockAccuracyData())
+		}
 		return
 	}
 
@@ -87,8 +130,10 @@ func (h *Handlers) Accuracy(w http.ResponseWriter, r *http.Request) {
 		log.Warn().Err(err).Msg("Could not parse accuracy_data.json")
 
 		// Return mock data if parsing fails
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(mockAccuracyData())
+		if h.allowMock(w, r, "accuracy") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(mockAccuracyData())
+		}
 		return
 	}
 
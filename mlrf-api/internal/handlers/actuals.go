@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/rs/zerolog/log"
+)
+
+// ActualInput is a realized sales value for one series and date.
+type ActualInput struct {
+	StoreNbr int     `json:"store_nbr"`
+	Family   string  `json:"family"`
+	Date     string  `json:"date"`
+	Sales    float64 `json:"sales"`
+}
+
+// ActualsRequest submits realized sales.
+type ActualsRequest struct {
+	Actuals []ActualInput `json:"actuals"`
+	Horizon int           `json:"horizon,omitempty"` // Pair only with predictions served for this horizon (default any)
+}
+
+// ActualsResponse reports how many actuals were stored.
+type ActualsResponse struct {
+	Accepted       int     `json:"accepted"`
+	WithPrediction int     `json:"with_prediction"` // Actuals paired with a served prediction for accuracy tracking
+	LatencyMs      float64 `json:"latency_ms"`
+}
+
+// SetActualsStore enables POST /actuals and live accuracy on /accuracy.
+func (h *Handlers) SetActualsStore(s *actuals.Store, maxBatch int) {
+	h.actuals = s
+	h.actualsMax = maxBatch
+}
+
+// SubmitActuals stores realized sales for (store, family, date) tuples. Each actual is
+// paired with the latest prediction served for its series and date, as found in the
+// prediction log, so /accuracy can compute live error metrics. Actuals without a logged
+// prediction, or submitted while prediction logging is disabled, are stored unpaired.
+// Resubmitting a tuple replaces the stored actual.
+func (h *Handlers) SubmitActuals(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if h.actuals == nil {
+		WriteServiceUnavailable(w, r, "actuals tracking not enabled", CodeActualsUnavailable)
+		return
+	}
+
+	var req ActualsRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Actuals) == 0 {
+		WriteBadRequest(w, r, "actuals array is empty", "EMPTY_BATCH")
+		return
+	}
+	if len(req.Actuals) > h.actualsMax {
+		WriteBadRequest(w, r, fmt.Sprintf("actuals exceed maximum of %d", h.actualsMax), CodeBatchTooLarge)
+		return
+	}
+	if req.Horizon != 0 {
+		if err := ValidateHorizon(req.Horizon); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+	for i, a := range req.Actuals {
+		if err := h.validateActual(a); err != nil {
+			WriteBadRequest(w, r, fmt.Sprintf("actual[%d]: %s", i, err.Message), err.Code)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	records := make([]actuals.Record, len(req.Actuals))
+	for i, a := range req.Actuals {
+		records[i] = actuals.Record{
+			StoreNbr:   a.StoreNbr,
+			Family:     a.Family,
+			Date:       a.Date,
+			Actual:     a.Sales,
+			ReceivedAt: now,
+		}
+	}
+
+	// Pair with the served predictions; actuals are stored even when none was logged
+	withPrediction := 0
+	if h.predLog != nil {
+		keys := make([]predlog.SeriesKey, len(req.Actuals))
+		for i, a := range req.Actuals {
+			keys[i] = predlog.SeriesKey{StoreNbr: int32(a.StoreNbr), Family: a.Family, Date: a.Date}
+		}
+		served, err := h.predLog.FindSeries(int32(req.Horizon), keys)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to pair actuals with served predictions")
+		}
+		for i, key := range keys {
+			e, ok := served[key]
+			if !ok {
+				continue
+			}
+			p := float64(e.Prediction)
+			records[i].Prediction = &p
+			records[i].Horizon = int(e.Horizon)
+			records[i].Model = e.ModelVersion
+			withPrediction++
+		}
+	}
+
+	if err := h.actuals.Add(records); err != nil {
+		log.Error().Err(err).Msg("failed to store actuals")
+		WriteInternalError(w, r, "failed to store actuals", CodeInternalError)
+		return
+	}
+
+	h.invalidateBacktests()
+
+	log.Info().
+		Int("accepted", len(records)).
+		Int("with_prediction", withPrediction).
+		Msg("Actuals stored")
+
+	resp := ActualsResponse{
+		Accepted:       len(records),
+		WithPrediction: withPrediction,
+		LatencyMs:      float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateActual validates an actual like a /predict/simple request, plus non-negative sales.
+func (h *Handlers) validateActual(a ActualInput) *ValidationError {
+	if err := h.validateStoreNbr(a.StoreNbr); err != nil {
+		return err
+	}
+	if err := ValidateFamily(a.Family); err != nil {
+		return err
+	}
+	if err := ValidateDate(a.Date); err != nil {
+		return err
+	}
+	if a.Sales < 0 || math.IsNaN(a.Sales) || math.IsInf(a.Sales, 0) {
+		return &ValidationError{Message: "sales must be a non-negative number", Code: CodeInvalidRequest}
+	}
+	return nil
+}
+
+// liveAccuracy computes accuracy from stored actuals and their predictions, filtered by
+// the store_nbr, family, start_date and end_date query parameters. Returns false when
+// no stored actual has a prediction, and an error when the store can't be queried.
+func (h *Handlers) liveAccuracy(r *http.Request) (AccuracyResponse, bool, *ValidationError, error) {
+	q := r.URL.Query()
+	filter := actuals.Filter{
+		Family:    q.Get("family"),
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	}
+	storeNbr, err := queryInt(r, "store_nbr", 0)
+	if err != nil {
+		return AccuracyResponse{}, false, &ValidationError{Message: "store_nbr must be an integer", Code: CodeInvalidStore}, nil
+	}
+	filter.StoreNbr = storeNbr
+	for _, d := range []string{filter.StartDate, filter.EndDate} {
+		if d == "" {
+			continue
+		}
+		if err := ValidateDate(d); err != nil {
+			return AccuracyResponse{}, false, err, nil
+		}
+	}
+
+	stored, err := h.actuals.List(filter)
+	if err != nil {
+		return AccuracyResponse{}, false, nil, err
+	}
+	var records []actuals.Record
+	for _, rec := range stored {
+		if rec.Prediction != nil {
+			records = append(records, rec)
+		}
+	}
+	if len(records) == 0 {
+		return AccuracyResponse{}, false, nil, nil
+	}
+
+	// Daily totals across the matching series; records are ordered by date
+	var data []AccuracyDataPoint
+	for _, rec := range records {
+		if len(data) == 0 || data[len(data)-1].Date != rec.Date {
+			data = append(data, AccuracyDataPoint{Date: rec.Date})
+		}
+		p := &data[len(data)-1]
+		p.Actual += float32(rec.Actual)
+		p.Predicted += float32(*rec.Prediction)
+	}
+
+	var sumActual, sumPredicted, sumError float64
+	actualsByDay := make([]float64, len(data))
+	predictedByDay := make([]float64, len(data))
+	for i := range data {
+		p := &data[i]
+		p.Error = p.Actual - p.Predicted
+		if p.Actual != 0 {
+			p.MAPE = float32(math.Abs(float64(p.Error)) / float64(p.Actual) * 100)
+		}
+		sumActual += float64(p.Actual)
+		sumPredicted += float64(p.Predicted)
+		sumError += float64(p.Error)
+		actualsByDay[i] = float64(p.Actual)
+		predictedByDay[i] = float64(p.Predicted)
+	}
+
+	m := actuals.Evaluate(records)
+	n := float64(len(data))
+	return AccuracyResponse{
+		Data:   data,
+		Source: "actuals",
+		Summary: AccuracySummary{
+			DataPoints:    len(data),
+			MeanActual:    float32(sumActual / n),
+			MeanPredicted: float32(sumPredicted / n),
+			MeanError:     float32(sumError / n),
+			MeanMAPE:      float32(m.MAPE),
+			Correlation:   float32(pearson(actualsByDay, predictedByDay)),
+			Series:        m.Count,
+			RMSLE:         float32(m.RMSLE),
+			Bias:          float32(m.Bias),
+			BiasPct:       float32(m.BiasPct),
+		},
+	}, true, nil, nil
+}
+
+// pearson returns the correlation of x and y, or 0 when either is constant.
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var sx, sy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		vx += (x[i] - mx) * (x[i] - mx)
+		vy += (y[i] - my) * (y[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
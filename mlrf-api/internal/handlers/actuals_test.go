@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+)
+
+func newTestActualsStore(t *testing.T) *actuals.Store {
+	t.Helper()
+	s, err := actuals.Open(actuals.Config{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "actuals.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// servedPredictions returns a prediction logger whose completed files hold entries.
+func servedPredictions(t *testing.T, entries ...predlog.Entry) *predlog.Logger {
+	t.Helper()
+	l, _ := newTestPredictionLogger(t)
+	for _, e := range entries {
+		l.Log(e)
+	}
+	l.Close()
+	return l
+}
+
+func postActuals(h *Handlers, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.SubmitActuals(w, httptest.NewRequest(http.MethodPost, "/v1/actuals", bytes.NewBufferString(body)))
+	return w
+}
+
+func getAccuracy(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, AccuracyResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Accuracy(w, httptest.NewRequest(http.MethodGet, "/v1/accuracy?"+query, nil))
+	var resp AccuracyResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestSubmitActualsAndLiveAccuracy(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetActualsStore(newTestActualsStore(t), 100)
+	h.SetPredictionLogger(servedPredictions(t,
+		predlog.Entry{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 100},
+		predlog.Entry{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 50},
+		predlog.Entry{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-15", Horizon: 30, Prediction: 80},
+	))
+
+	w := postActuals(h, `{"actuals": [
+		{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": 110},
+		{"store_nbr": 2, "family": "DAIRY", "date": "2017-08-14", "sales": 40},
+		{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-15", "sales": 80}
+	]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ActualsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Accepted != 3 || resp.WithPrediction != 3 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	w, acc := getAccuracy(t, h, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if acc.Source != "actuals" || len(acc.Data) != 2 || acc.Summary.Series != 3 {
+		t.Fatalf("expected live accuracy over 2 days and 3 series, got %+v", acc)
+	}
+	// Day 1: actual 150 vs predicted 150
+	if acc.Data[0].Actual != 150 || acc.Data[0].Predicted != 150 || acc.Data[0].Error != 0 {
+		t.Errorf("unexpected day 1: %+v", acc.Data[0])
+	}
+	// Series MAPE: (|100-110|/110 + |50-40|/40 + 0) / 3
+	wantMAPE := (10.0/110 + 10.0/40) / 3 * 100
+	if math.Abs(float64(acc.Summary.MeanMAPE)-wantMAPE) > 1e-3 {
+		t.Errorf("expected MAPE %f, got %f", wantMAPE, acc.Summary.MeanMAPE)
+	}
+	if acc.Summary.RMSLE <= 0 || acc.Summary.Bias != 0 {
+		t.Errorf("unexpected RMSLE %f or bias %f", acc.Summary.RMSLE, acc.Summary.Bias)
+	}
+
+	// Filters
+	if _, acc := getAccuracy(t, h, "store_nbr=2"); acc.Summary.Series != 1 || acc.Summary.Bias != 10 {
+		t.Errorf("expected store 2 only with bias 10, got %+v", acc.Summary)
+	}
+	if w, _ := getAccuracy(t, h, "start_date=bogus"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid start_date, got %d", w.Code)
+	}
+}
+
+// TestSubmitActualsPairsServedPredictions verifies actuals are paired with the latest
+// prediction served for their series and date, not one scored on submission, and that
+// actuals without a served prediction are stored unpaired.
+func TestSubmitActualsPairsServedPredictions(t *testing.T) {
+	model := &MockInferencer{prediction: 42}
+	h := NewHandlers(model, nil, nil, nil)
+	h.SetActualsStore(newTestActualsStore(t), 100)
+	l, _ := newTestPredictionLogger(t)
+	h.SetPredictionLogger(l)
+
+	for _, horizon := range []string{"15", "30"} {
+		w := httptest.NewRecorder()
+		body := `{"store_nbr":1,"family":"DAIRY","date":"2017-08-14","horizon":` + horizon + `}`
+		h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple", bytes.NewBufferString(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		model.prediction = 60
+	}
+	l.Close()
+	model.prediction = 99
+
+	w := postActuals(h, `{"actuals": [
+		{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": 50},
+		{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-15", "sales": 70}
+	]}`)
+	var resp ActualsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Accepted != 2 || resp.WithPrediction != 1 {
+		t.Fatalf("expected 2 actuals with 1 paired, got %d: %s", w.Code, w.Body.String())
+	}
+
+	records, err := h.actuals.List(actuals.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byDate := make(map[string]actuals.Record)
+	for _, rec := range records {
+		byDate[rec.Date] = rec
+	}
+	if rec := byDate["2017-08-14"]; rec.Prediction == nil || *rec.Prediction != 60 || rec.Horizon != 30 {
+		t.Errorf("expected the latest served prediction 60 at horizon 30, got %+v", rec)
+	}
+	if rec := byDate["2017-08-15"]; rec.Prediction != nil || rec.Model != "" {
+		t.Errorf("expected the unserved actual stored unpaired, got %+v", rec)
+	}
+
+	// A horizon pairs only with predictions served for it
+	postActuals(h, `{"horizon": 15, "actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": 50}]}`)
+	records, _ = h.actuals.List(actuals.Filter{StartDate: "2017-08-14", EndDate: "2017-08-14"})
+	if len(records) != 1 || records[0].Prediction == nil || *records[0].Prediction != 42 || records[0].Horizon != 15 {
+		t.Errorf("expected the prediction 42 served at horizon 15, got %+v", records)
+	}
+}
+
+func TestSubmitActualsWithoutModel(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetActualsStore(newTestActualsStore(t), 100)
+
+	w := postActuals(h, `{"actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": 110}]}`)
+	var resp ActualsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Accepted != 1 || resp.WithPrediction != 0 {
+		t.Errorf("expected actual stored without prediction, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Nothing to evaluate: falls back to the validation set data
+	if _, acc := getAccuracy(t, h, ""); acc.Source == "actuals" || len(acc.Data) == 0 {
+		t.Errorf("expected fallback accuracy data, got %+v", acc)
+	}
+}
+
+func TestSubmitActualsValidation(t *testing.T) {
+	if w := postActuals(NewHandlers(nil, nil, nil, nil), `{"actuals": []}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without actuals store, got %d", w.Code)
+	}
+
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetActualsStore(newTestActualsStore(t), 1)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", `{`},
+		{"empty", `{"actuals": []}`},
+		{"too many", `{"actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14"}, {"store_nbr": 1, "family": "DAIRY", "date": "2017-08-15"}]}`},
+		{"negative sales", `{"actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": -1}]}`},
+		{"invalid family", `{"actuals": [{"store_nbr": 1, "family": "TOYS", "date": "2017-08-14", "sales": 1}]}`},
+		{"invalid horizon", `{"horizon": 7, "actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-14", "sales": 1}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postActuals(h, tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
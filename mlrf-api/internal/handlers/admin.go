@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"strings"
 
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/remote"
+	"github.com/mlrf/mlrf-api/internal/schema"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,43 +26,289 @@ type ReloadResponse struct {
 }
 
 // ReloadFeatures triggers a hot reload of the feature store.
-// Requires admin authentication via X-Admin-Key header (if ADMIN_API_KEY is set).
+// Requires the admin scope.
 func (h *Handlers) ReloadFeatures(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		WriteError(w, r, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
 		return
 	}
 
-	// Verify admin auth
-	adminKey := os.Getenv("ADMIN_API_KEY")
-	if adminKey != "" && r.Header.Get("X-Admin-Key") != adminKey {
-		WriteUnauthorized(w, r, "admin authentication required")
+	// Check if feature store exists
+	if h.featureStore == nil {
+		WriteServiceUnavailable(w, r, "feature store not configured", CodeFeatureStoreUnavailable)
 		return
 	}
 
-	// Check if feature store exists
+	meta, err := h.RefreshFeatures(r.Context())
+	if err != nil {
+		var qerr *features.QualityError
+		if errors.As(err, &qerr) {
+			WriteError(w, r, http.StatusUnprocessableEntity, "reload rejected: "+qerr.Error(), CodeFeatureQualityFailed)
+			return
+		}
+		var serr *schema.MismatchError
+		if errors.As(err, &serr) {
+			WriteError(w, r, http.StatusUnprocessableEntity, "reload rejected: "+serr.Error(), CodeFeatureSchemaMismatch)
+			return
+		}
+		WriteInternalError(w, r, err.Error(), CodeReloadFailed)
+		return
+	}
+
+	resp := ReloadResponse{
+		Status:  "reloaded",
+		Message: "Feature store reloaded successfully",
+		Metadata: map[string]interface{}{
+			"loaded_at":     meta.LoadedAt,
+			"file_path":     meta.FilePath,
+			"row_count":     meta.RowCount,
+			"data_date_min": meta.DataDateMin,
+			"data_date_max": meta.DataDateMax,
+			"version":       meta.Version,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// MaxFeatureUploadBytes caps a parquet delta uploaded to /admin/append-features.
+const MaxFeatureUploadBytes = 512 << 20
+
+// AppendFeaturesRequest names a delta parquet file on the server to merge.
+type AppendFeaturesRequest struct {
+	Path string `json:"path"`
+}
+
+// AppendFeatures merges a delta parquet file (typically only new dates) into the loaded
+// feature store without a full reload. The body is either JSON {"path": ...} naming a
+// file on the server or, with any other Content-Type, the parquet file itself.
+// Requires the admin scope.
+func (h *Handlers) AppendFeatures(w http.ResponseWriter, r *http.Request) {
+
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	var (
+		res features.AppendResult
+		err error
+	)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req AppendFeaturesRequest
+		if !h.decodeJSON(w, r, &req) {
+			return
+		}
+		if req.Path == "" {
+			WriteBadRequest(w, r, "path is required", CodeInvalidRequest)
+			return
+		}
+		log.Info().Str("path", req.Path).Msg("Appending feature delta...")
+		res, err = h.featureStore.Append(req.Path)
+	} else {
+		body, readErr := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxFeatureUploadBytes))
+		if readErr != nil {
+			WriteError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload must not exceed %d bytes", MaxFeatureUploadBytes), CodeInvalidRequest)
+			return
+		}
+		if len(body) == 0 {
+			WriteBadRequest(w, r, "request body must be a parquet file or JSON with a path", CodeInvalidRequest)
+			return
+		}
+		log.Info().Int("bytes", len(body)).Msg("Appending uploaded feature delta...")
+		res, err = h.featureStore.AppendReader(bytes.NewReader(body), int64(len(body)), "upload")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Feature append failed")
+		var qerr *features.QualityError
+		if errors.As(err, &qerr) {
+			WriteError(w, r, http.StatusUnprocessableEntity, "append rejected: "+err.Error(), CodeFeatureQualityFailed)
+			return
+		}
+		var serr *schema.MismatchError
+		if errors.As(err, &serr) {
+			WriteError(w, r, http.StatusUnprocessableEntity, "append rejected: "+err.Error(), CodeFeatureSchemaMismatch)
+			return
+		}
+		WriteBadRequest(w, r, "append failed: "+err.Error(), CodeReloadFailed)
+		return
+	}
+
+	meta := h.featureStore.GetMetadata()
+	h.FeaturesAppended(r.Context())
+
+	resp := ReloadResponse{
+		Status:  "appended",
+		Message: fmt.Sprintf("Appended %d rows (%d new, %d replaced)", res.Rows, res.Added, res.Replaced),
+		Metadata: map[string]interface{}{
+			"source":        res.Source,
+			"rows":          res.Rows,
+			"added":         res.Added,
+			"replaced":      res.Replaced,
+			"row_count":     meta.RowCount,
+			"data_date_min": meta.DataDateMin,
+			"data_date_max": meta.DataDateMax,
+			"version":       meta.Version,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// FeatureQualityResponse reports data quality of the feature store.
+type FeatureQualityResponse struct {
+	Quality  *features.QualityReport `json:"quality"`            // Report of the data being served
+	Rejected *features.QualityReport `json:"rejected,omitempty"` // Latest reload rejected since then
+}
+
+// FeatureQuality returns per-column null, NaN and outlier counts from the feature store's
+// last load, plus the report of any reload rejected since.
+// Requires the admin scope.
+func (h *Handlers) FeatureQuality(w http.ResponseWriter, r *http.Request) {
+
 	if h.featureStore == nil {
 		WriteServiceUnavailable(w, r, "feature store not configured", CodeFeatureStoreUnavailable)
 		return
 	}
 
+	var resp FeatureQualityResponse
+	resp.Quality, resp.Rejected = h.featureStore.Quality()
+	if resp.Quality == nil && resp.Rejected == nil {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ReloadModel triggers a hot reload of the ONNX model.
+// The new model is loaded and smoke-tested before being swapped in; on failure the
+// current model keeps serving. Requires the admin scope.
+func (h *Handlers) ReloadModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, r, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
+		return
+	}
+
+	if h.modelLoader == nil {
+		WriteServiceUnavailable(w, r, "model reload not configured", CodeModelUnavailable)
+		return
+	}
+
+	info, err := h.RefreshModel(r.Context())
+	if err != nil {
+		WriteInternalError(w, r, err.Error(), CodeReloadFailed)
+		return
+	}
+
+	resp := ReloadResponse{
+		Status:  "reloaded",
+		Message: "Model reloaded successfully",
+		Metadata: map[string]interface{}{
+			"loaded_at": info.LoadedAt,
+			"file_path": info.Path,
+			"version":   info.Version,
+		},
+	}
+	if info.SelfTest != nil {
+		resp.Metadata["self_test"] = info.SelfTest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ReloadIntervals triggers a hot reload of the prediction intervals file, e.g. after
+// retraining overwrote it. On failure the current intervals keep serving. Requires
+// the admin scope.
+func (h *Handlers) ReloadIntervals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, r, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
+		return
+	}
+
+	h.intervalsMu.RLock()
+	path, previous := h.intervalsPath, h.intervalsInfo
+	h.intervalsMu.RUnlock()
+	if path == "" {
+		WriteServiceUnavailable(w, r, "prediction intervals path not configured", CodeIntervalsUnavailable)
+		return
+	}
+	if err := h.RefreshIntervals(r.Context()); err != nil {
+		WriteInternalError(w, r, "reload failed: "+err.Error(), CodeReloadFailed)
+		return
+	}
+
+	info := h.IntervalsInfo()
+	resp := ReloadResponse{
+		Status:  "reloaded",
+		Message: "Prediction intervals reloaded successfully",
+		Metadata: map[string]interface{}{
+			"file_path":        info.Path,
+			"version":          info.Version,
+			"sha256":           info.SHA256,
+			"loaded_at":        info.LoadedAt,
+			"previous_version": previous.Version,
+			"changed":          info.SHA256 != previous.SHA256,
+			"global":           info.Global,
+			"store_families":   info.StoreFamilies,
+			"families":         info.Families,
+			"stores":           info.Stores,
+			"horizons":         info.Horizons,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RefreshFeatures reloads the feature store from its file, fetching an object store
+// source again first, and clears results derived from the previous data. It is the
+// reload path of /admin/reload-features and the file watcher.
+func (h *Handlers) RefreshFeatures(ctx context.Context) (features.Metadata, error) {
+	meta, err := h.reloadFeatureStore(ctx)
+	if err != nil {
+		return meta, err
+	}
+	h.RotateCacheNamespace()
+	h.startCacheWarm()
+	h.invalidateHierarchy(ctx)
+	h.invalidateBacktests()
+	h.notifyLive(live.ReasonFeaturesReloaded)
+	return meta, nil
+}
+
+// reloadFeatureStore swaps in the feature file without clearing derived results.
+func (h *Handlers) reloadFeatureStore(ctx context.Context) (features.Metadata, error) {
+	if h.featureStore == nil {
+		return features.Metadata{}, errors.New("feature store not configured")
+	}
+
 	// Get the current file path
 	filePath := h.featureStore.FilePath()
 	if filePath == "" {
-		// Try environment variable
-		filePath = os.Getenv("FEATURE_PATH")
+		// Fall back to the configured FEATURE_PATH
+		filePath = h.featureSource.URL
 		if filePath == "" {
-			filePath = "data/features/feature_matrix.parquet"
+			filePath = DefaultFeaturePath
 		}
 	}
 
+	filePath, err := h.refetch(ctx, h.featureSource, filePath)
+	if err != nil {
+		log.Error().Err(err).Str("url", h.featureSource.URL).Msg("Feature fetch failed")
+		return features.Metadata{}, fmt.Errorf("fetch failed: %w", err)
+	}
+
 	log.Info().Str("path", filePath).Msg("Reloading feature store...")
 
 	// Attempt reload
 	if err := h.featureStore.Load(filePath); err != nil {
 		log.Error().Err(err).Str("path", filePath).Msg("Feature reload failed")
-		WriteInternalError(w, r, "reload failed: "+err.Error(), CodeReloadFailed)
-		return
+		return features.Metadata{}, fmt.Errorf("reload failed: %w", err)
 	}
 
 	// Get updated metadata
@@ -63,19 +319,127 @@ func (h *Handlers) ReloadFeatures(w http.ResponseWriter, r *http.Request) {
 		Str("version", meta.Version).
 		Str("data_range", meta.DataDateMin+" to "+meta.DataDateMax).
 		Msg("Feature store reloaded successfully")
+	return meta, nil
+}
+
+// RefreshModel reloads the ONNX model from its file, fetching an object store source
+// again first. It is the reload path of /admin/reload-model and the file watcher.
+func (h *Handlers) RefreshModel(ctx context.Context) (inference.ModelInfo, error) {
+	info, err := h.reloadModel(ctx, nil)
+	if err != nil {
+		return info, err
+	}
+	h.RotateCacheNamespace()
+	h.startCacheWarm()
+	h.invalidateHierarchy(ctx)
+	h.invalidateBacktests()
+	h.notifyLive(live.ReasonModelReloaded)
+	return info, nil
+}
+
+// reloadModel swaps in the model file and its training and comparison metrics without
+// clearing derived results. A non-nil check must pass on the new model first.
+func (h *Handlers) reloadModel(ctx context.Context, check func(inference.Inferencer) error) (inference.ModelInfo, error) {
+	if h.modelLoader == nil {
+		return inference.ModelInfo{}, errors.New("model reload not configured")
+	}
+
+	// Get the current model path
+	modelPath := h.modelLoader.Info().Path
+	if modelPath == "" {
+		modelPath = h.modelSource.URL
+		if modelPath == "" {
+			modelPath = DefaultModelPath
+		}
+	}
+
+	modelPath, err := h.refetch(ctx, h.modelSource, modelPath)
+	if err != nil {
+		log.Error().Err(err).Str("url", h.modelSource.URL).Msg("Model fetch failed")
+		return inference.ModelInfo{}, fmt.Errorf("fetch failed: %w", err)
+	}
+
+	log.Info().Str("path", modelPath).Msg("Reloading ONNX model...")
+
+	reload := h.modelLoader.Reload
+	if check != nil {
+		reload = func(path string) error { return h.modelLoader.ReloadChecked(path, check) }
+	}
+	if err := reload(modelPath); err != nil {
+		log.Error().Err(err).Str("path", modelPath).Msg("Model reload failed")
+		return inference.ModelInfo{}, fmt.Errorf("reload failed: %w", err)
+	}
+
+	info := h.modelLoader.Info()
+
+	log.Info().
+		Str("path", info.Path).
+		Str("version", info.Version).
+		Msg("ONNX model reloaded successfully")
+	h.refreshTrainingMetrics()
+	h.refreshModelMetrics()
+	return info, nil
+}
+
+// refetch downloads an object store source again so a reload picks up a replaced
+// object, returning its local path. For local sources it returns path unchanged.
+func (h *Handlers) refetch(ctx context.Context, src remote.Source, path string) (string, error) {
+	if h.fetcher == nil || !remote.IsRemote(src.URL) {
+		return path, nil
+	}
+	return h.fetcher.Fetch(ctx, src)
+}
+
+// RotateCacheNamespace scopes cached predictions to the current model and feature
+// versions, so predictions cached before a reload are no longer served. It is called
+// on every model and feature reload, and once at startup. Versions are content hashes,
+// so replicas and restarts serving the same files share a namespace, and a reload of
+// unchanged files keeps it.
+func (h *Handlers) RotateCacheNamespace() {
+	if h.cache == nil {
+		return
+	}
+	var modelVersion, featureVersion string
+	if h.modelLoader != nil {
+		modelVersion = h.modelLoader.Info().Version
+	}
+	if h.featureStore != nil {
+		featureVersion = h.featureStore.GetMetadata().Version
+	}
+	ns := h.cache.SetNamespace(modelVersion, featureVersion)
+	log.Info().
+		Str("namespace", ns).
+		Str("model_version", modelVersion).
+		Str("feature_version", featureVersion).
+		Msg("Prediction cache namespace set")
+}
+
+// FlushCache removes every cached prediction and SHAP explanation, locally and in
+// Redis, and the cached hierarchy trees and backtests. Requires the admin scope.
+func (h *Handlers) FlushCache(w http.ResponseWriter, r *http.Request) {
+	var deleted int
+	if h.cache != nil {
+		var err error
+		if deleted, err = h.cache.Flush(r.Context()); err != nil {
+			log.Error().Err(err).Int("deleted", deleted).Msg("Cache flush failed")
+			WriteInternalError(w, r, "cache flush failed: "+err.Error(), CodeInternalError)
+			return
+		}
+	}
+	h.invalidateHierarchy(r.Context())
+	h.invalidateBacktests()
+	log.Info().Int("redis_keys", deleted).Msg("Caches flushed")
 
 	resp := ReloadResponse{
-		Status:  "reloaded",
-		Message: "Feature store reloaded successfully",
+		Status:  "flushed",
+		Message: fmt.Sprintf("Flushed %d Redis keys", deleted),
 		Metadata: map[string]interface{}{
-			"loaded_at":     meta.LoadedAt,
-			"file_path":     meta.FilePath,
-			"row_count":     meta.RowCount,
-			"data_date_min": meta.DataDateMin,
-			"data_date_max": meta.DataDateMax,
-			"version":       meta.Version,
+			"redis_keys_deleted": deleted,
 		},
 	}
+	if h.cache != nil {
+		resp.Metadata["namespace"] = h.cache.Namespace()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/rs/zerolog/log"
+)
+
+// Aggregation levels for /predict/aggregate.
+const (
+	AggregateLevelStore   = "store"
+	AggregateLevelFamily  = "family"
+	AggregateLevelCluster = "cluster"
+	AggregateLevelTotal   = "total"
+)
+
+// AggregateRequest requests a forecast summed over the series at a hierarchy level.
+type AggregateRequest struct {
+	Level         string `json:"level"`               // "store", "family", "cluster" or "total"
+	StoreNbr      int    `json:"store_nbr,omitempty"` // Required for level "store"
+	Family        string `json:"family,omitempty"`    // Required for level "family"
+	Cluster       int    `json:"cluster,omitempty"`   // Required for level "cluster"
+	Date          string `json:"date"`
+	Horizon       int    `json:"horizon"`
+	IncludeSeries bool   `json:"include_series,omitempty"` // Include the bottom-level predictions
+}
+
+// SeriesPrediction is one bottom-level prediction within an aggregate.
+type SeriesPrediction struct {
+	StoreNbr   int     `json:"store_nbr"`
+	Family     string  `json:"family"`
+	Prediction float32 `json:"prediction"`
+	Cached     bool    `json:"cached"`
+}
+
+// AggregateResponse is the summed forecast for a hierarchy node.
+// Interval offsets of the underlying series are combined in quadrature,
+// assuming independent series errors.
+type AggregateResponse struct {
+	Level       string             `json:"level"`
+	StoreNbr    int                `json:"store_nbr,omitempty"`
+	Family      string             `json:"family,omitempty"`
+	Cluster     int                `json:"cluster,omitempty"`
+	Date        string             `json:"date"`
+	Horizon     int                `json:"horizon"`
+	Prediction  float32            `json:"prediction"`
+	Lower80     float32            `json:"lower_80,omitempty"`
+	Upper80     float32            `json:"upper_80,omitempty"`
+	Lower95     float32            `json:"lower_95,omitempty"`
+	Upper95     float32            `json:"upper_95,omitempty"`
+	SeriesCount int                `json:"series_count"`
+	CachedCount int                `json:"cached_count"`
+	Series      []SeriesPrediction `json:"series,omitempty"`
+	LatencyMs   float64            `json:"latency_ms"`
+}
+
+// PredictAggregate forecasts a store, family, cluster or the total by fanning out to
+// every underlying (store, family) series, summing their predictions and combining
+// their confidence intervals.
+func (h *Handlers) PredictAggregate(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req AggregateRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.validateAggregateRequest(req); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	series := h.aggregateSeries(req)
+	if len(series) == 0 {
+		WriteError(w, r, http.StatusNotFound, "no series found for "+req.Level, CodeFeatureNotFound)
+		return
+	}
+
+	items := make([]SimplePredictRequest, len(series))
+	for i, s := range series {
+		items[i] = SimplePredictRequest{StoreNbr: s.StoreNbr, Family: s.Family, Date: req.Date, Horizon: req.Horizon}
+	}
+
+	predictions, cached, err := h.scoreSeries(r.Context(), items)
+	if errors.Is(err, inference.ErrInvalidPrediction) {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("level", req.Level).Msg("aggregate inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+
+	resp := AggregateResponse{
+		Level:       req.Level,
+		StoreNbr:    req.StoreNbr,
+		Family:      req.Family,
+		Cluster:     req.Cluster,
+		Date:        req.Date,
+		Horizon:     req.Horizon,
+		SeriesCount: len(series),
+	}
+
+	// Sum predictions; accumulate squared interval offsets
+	var total, lower80, upper80, lower95, upper95 float64
+	withIntervals := false
+	for i, s := range series {
+		total += float64(predictions[i])
+		if cached[i] {
+			resp.CachedCount++
+		}
+		if req.IncludeSeries {
+			resp.Series = append(resp.Series, SeriesPrediction{
+				StoreNbr:   s.StoreNbr,
+				Family:     s.Family,
+				Prediction: predictions[i],
+				Cached:     cached[i],
+			})
+		}
+
+		iv, _ := h.lookupIntervals(s.StoreNbr, s.Family, req.Horizon)
+		if iv == nil {
+			continue
+		}
+		withIntervals = true
+		lower80 += float64(iv.Lower80Offset) * float64(iv.Lower80Offset)
+		upper80 += float64(iv.Upper80Offset) * float64(iv.Upper80Offset)
+		lower95 += float64(iv.Lower95Offset) * float64(iv.Lower95Offset)
+		upper95 += float64(iv.Upper95Offset) * float64(iv.Upper95Offset)
+	}
+
+	resp.Prediction = float32(total)
+	if withIntervals {
+		// Floor at zero (sales can't be negative)
+		resp.Lower80 = float32(math.Max(total-math.Sqrt(lower80), 0))
+		resp.Upper80 = float32(total + math.Sqrt(upper80))
+		resp.Lower95 = float32(math.Max(total-math.Sqrt(lower95), 0))
+		resp.Upper95 = float32(total + math.Sqrt(upper95))
+	}
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateAggregateRequest checks the level, its selector, the date and the horizon.
+func (h *Handlers) validateAggregateRequest(req AggregateRequest) *ValidationError {
+	switch req.Level {
+	case AggregateLevelStore:
+		if err := h.validateStoreNbr(req.StoreNbr); err != nil {
+			return err
+		}
+	case AggregateLevelFamily:
+		if err := ValidateFamily(req.Family); err != nil {
+			return err
+		}
+	case AggregateLevelCluster:
+		if req.Cluster <= 0 {
+			return &ValidationError{Message: "cluster must be positive", Code: CodeInvalidRequest}
+		}
+	case AggregateLevelTotal:
+	default:
+		return &ValidationError{
+			Message: "level must be store, family, cluster or total",
+			Code:    CodeInvalidRequest,
+		}
+	}
+	if err := ValidateDate(req.Date); err != nil {
+		return err
+	}
+	return ValidateHorizon(req.Horizon)
+}
+
+// aggregateSeries returns the bottom-level series under the requested node.
+func (h *Handlers) aggregateSeries(req AggregateRequest) []features.Series {
+	all := h.featureStore.Series()
+	switch req.Level {
+	case AggregateLevelStore:
+		return filterSeries(all, []int{req.StoreNbr}, nil)
+	case AggregateLevelFamily:
+		return filterSeries(all, nil, []string{req.Family})
+	case AggregateLevelCluster:
+		var series []features.Series
+		for _, s := range all {
+			if cluster, ok := h.featureStore.Cluster(s.StoreNbr); ok && cluster == req.Cluster {
+				series = append(series, s)
+			}
+		}
+		return series
+	default:
+		return all
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func TestPredictAggregate(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	row := func(store int32, family string, cluster int32, lag float64) features.FeatureRow {
+		return features.FeatureRow{StoreNbr: store, Family: family, Date: date, Cluster: cluster, SalesLag1: lag}
+	}
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		row(1, "DAIRY", 13, 100), row(1, "BEVERAGES", 13, 200),
+		row(2, "DAIRY", 13, 50), row(2, "BEVERAGES", 13, 25),
+		row(3, "DAIRY", 7, 1000),
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+	h.intervals = &PredictionIntervals{Lower80Offset: -3, Upper80Offset: 4, Lower95Offset: -6, Upper95Offset: 8}
+
+	tests := []struct {
+		name   string
+		body   string
+		total  float32
+		series int
+	}{
+		{"store", `{"level":"store","store_nbr":1,"date":"2017-08-15","horizon":30}`, 300, 2},
+		{"family", `{"level":"family","family":"DAIRY","date":"2017-08-15","horizon":30}`, 1150, 3},
+		{"cluster", `{"level":"cluster","cluster":13,"date":"2017-08-15","horizon":30}`, 375, 4},
+		{"total", `{"level":"total","date":"2017-08-15","horizon":30,"include_series":true}`, 1375, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.PredictAggregate(w, httptest.NewRequest(http.MethodPost, "/v1/predict/aggregate", bytes.NewBufferString(tt.body)))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp AggregateResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Prediction != tt.total || resp.SeriesCount != tt.series {
+				t.Errorf("expected total %f over %d series, got %f over %d", tt.total, tt.series, resp.Prediction, resp.SeriesCount)
+			}
+
+			// Offsets add in quadrature: sqrt(n) times a single series' offset
+			n := math.Sqrt(float64(tt.series))
+			if got, want := float64(resp.Upper80-resp.Prediction), 4*n; math.Abs(got-want) > 1e-3 {
+				t.Errorf("expected upper_80 offset %f, got %f", want, got)
+			}
+			if got, want := float64(resp.Prediction-resp.Lower95), 6*n; math.Abs(got-want) > 1e-3 {
+				t.Errorf("expected lower_95 offset %f, got %f", want, got)
+			}
+			if (len(resp.Series) > 0) != (tt.name == "total") {
+				t.Errorf("series breakdown should only be included on request, got %d", len(resp.Series))
+			}
+		})
+	}
+}
+
+func TestPredictAggregateValidation(t *testing.T) {
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)},
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"unknown level", `{"level":"region","date":"2017-08-15","horizon":30}`, http.StatusBadRequest},
+		{"store level without store", `{"level":"store","date":"2017-08-15","horizon":30}`, http.StatusBadRequest},
+		{"invalid horizon", `{"level":"total","date":"2017-08-15","horizon":7}`, http.StatusBadRequest},
+		{"unknown store", `{"level":"store","store_nbr":9,"date":"2017-08-15","horizon":30}`, http.StatusBadRequest},
+		{"no matching series", `{"level":"family","family":"BEVERAGES","date":"2017-08-15","horizon":30}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.PredictAggregate(w, httptest.NewRequest(http.MethodPost, "/v1/predict/aggregate", bytes.NewBufferString(tt.body)))
+			if w.Code != tt.code {
+				t.Errorf("expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
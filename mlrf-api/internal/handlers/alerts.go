@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/alerts"
+)
+
+// AlertsResponse lists forecast accuracy alerts.
+type AlertsResponse struct {
+	Firing        int            `json:"firing"`
+	MAPEThreshold float64        `json:"mape_threshold"`
+	WindowDays    int            `json:"window_days"`
+	LastCheck     *time.Time     `json:"last_check,omitempty"` // Unset before the first check
+	Alerts        []alerts.Alert `json:"alerts"`               // Firing first, then by family
+}
+
+// SetAlertMonitor enables /alerts.
+func (h *Handlers) SetAlertMonitor(m *alerts.Monitor) {
+	h.alerts = m
+}
+
+// Alerts returns the accuracy alert state of every family that has breached the
+// MAPE threshold, optionally filtered with ?state=firing or ?state=resolved.
+func (h *Handlers) Alerts(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		WriteServiceUnavailable(w, r, "accuracy alerts not enabled", CodeAlertsUnavailable)
+		return
+	}
+
+	state := alerts.State(r.URL.Query().Get("state"))
+	if state != "" && state != alerts.StateFiring && state != alerts.StateResolved {
+		WriteBadRequest(w, r, "state must be firing or resolved", CodeInvalidRequest)
+		return
+	}
+
+	cfg := h.alerts.Config()
+	resp := AlertsResponse{
+		MAPEThreshold: cfg.MAPEThreshold,
+		WindowDays:    cfg.WindowDays,
+		Alerts:        []alerts.Alert{},
+	}
+	if last := h.alerts.LastCheck(); !last.IsZero() {
+		resp.LastCheck = &last
+	}
+	for _, a := range h.alerts.Alerts() {
+		if a.State == alerts.StateFiring {
+			resp.Firing++
+		}
+		if state == "" || a.State == state {
+			resp.Alerts = append(resp.Alerts, a)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
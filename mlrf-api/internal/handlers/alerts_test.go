@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/alerts"
+)
+
+func getAlerts(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, AlertsResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Alerts(w, httptest.NewRequest(http.MethodGet, "/v1/alerts?"+query, nil))
+	var resp AlertsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestAlerts(t *testing.T) {
+	store := newTestActualsStore(t)
+	pred := func(v float64) *float64 { return &v }
+	if err := store.Add([]actuals.Record{
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Actual: 100, Prediction: pred(150)},
+		{StoreNbr: 1, Family: "EGGS", Date: "2017-08-01", Actual: 100, Prediction: pred(101)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := alerts.NewMonitor(alerts.Config{Interval: time.Hour, WindowDays: 7, MAPEThreshold: 20, MinSamples: 1}, store)
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetAlertMonitor(monitor)
+
+	w, resp := getAlerts(t, h, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.LastCheck != nil || len(resp.Alerts) != 0 {
+		t.Errorf("expected no alerts before the first check, got %+v", resp)
+	}
+
+	monitor.Check(context.Background())
+
+	_, resp = getAlerts(t, h, "")
+	if resp.Firing != 1 || len(resp.Alerts) != 1 || resp.Alerts[0].Family != "DAIRY" {
+		t.Errorf("expected DAIRY firing, got %+v", resp)
+	}
+	if resp.MAPEThreshold != 20 || resp.WindowDays != 7 || resp.LastCheck == nil {
+		t.Errorf("unexpected alert metadata: %+v", resp)
+	}
+
+	_, resp = getAlerts(t, h, "state=resolved")
+	if resp.Firing != 1 || len(resp.Alerts) != 0 {
+		t.Errorf("expected no resolved alerts, got %+v", resp)
+	}
+
+	if w, _ := getAlerts(t, h, "state=bogus"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown state, got %d", w.Code)
+	}
+}
+
+func TestAlertsUnavailable(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getAlerts(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 without a monitor, got %d", w.Code)
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+	"github.com/rs/zerolog/log"
+)
+
+// Limits of the anomalies returned by /monitoring/anomalies.
+const (
+	DefaultAnomalyLimit = 100
+	MaxAnomalyLimit     = 1000
+)
+
+// AnomaliesResponse lists the series-dates of a window whose actual sales deviated
+// from their forecast, most severe first.
+type AnomaliesResponse struct {
+	Method      string                 `json:"method"`
+	WindowStart string                 `json:"window_start"`
+	WindowEnd   string                 `json:"window_end"`
+	Checked     int                    `json:"checked"`  // Actuals with a prediction that were scored
+	Unscored    int                    `json:"unscored"` // Actuals without an interval or enough baseline
+	Count       int                    `json:"count"`    // Anomalies found, before the limit
+	Critical    int                    `json:"critical"`
+	Anomalies   []anomaly.Anomaly      `json:"anomalies"`
+	Stores      []anomaly.StoreSummary `json:"stores"` // Per-store rollup of all anomalies found
+}
+
+// SetAnomalyConfig sets the detection method and thresholds of /monitoring/anomalies.
+func (h *Handlers) SetAnomalyConfig(cfg anomaly.Config) {
+	h.anomalyCfg = cfg
+}
+
+// Anomalies flags submitted actuals that deviate from the forecast logged with them:
+// outside the forecast interval, or by a robust z-score of the residual against the
+// series' residuals over the preceding baseline days. ?end_date= (default the latest
+// actual with a prediction) and ?days= select the window; ?store_nbr=, ?family=,
+// ?method=, ?severity= (the minimum, warning or critical) and ?limit= narrow the result.
+func (h *Handlers) Anomalies(w http.ResponseWriter, r *http.Request) {
+	if h.actuals == nil {
+		WriteServiceUnavailable(w, r, "actuals tracking not enabled", CodeActualsUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	cfg := h.anomalyCfg
+	if method := q.Get("method"); method != "" {
+		switch method {
+		case anomaly.MethodAuto, anomaly.MethodInterval, anomaly.MethodZScore:
+			cfg.Method = method
+		default:
+			WriteBadRequest(w, r, "method must be auto, interval or zscore", CodeInvalidRequest)
+			return
+		}
+	}
+	severity := q.Get("severity")
+	if severity != "" && severity != anomaly.SeverityWarning && severity != anomaly.SeverityCritical {
+		WriteBadRequest(w, r, "severity must be warning or critical", CodeInvalidRequest)
+		return
+	}
+	storeNbr, err := queryInt(r, "store_nbr", 0)
+	if err != nil {
+		WriteBadRequest(w, r, "store_nbr must be an integer", CodeInvalidStore)
+		return
+	}
+	if storeNbr != 0 {
+		if verr := h.validateStoreNbr(storeNbr); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	family := q.Get("family")
+	if family != "" {
+		if verr := ValidateFamily(family); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	days, err := queryInt(r, "days", cfg.WindowDays)
+	if err != nil || days < 1 || days > anomaly.MaxWindowDays {
+		WriteBadRequest(w, r, fmt.Sprintf("days must be between 1 and %d", anomaly.MaxWindowDays), CodeInvalidRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultAnomalyLimit)
+	if err != nil || limit < 1 || limit > MaxAnomalyLimit {
+		WriteBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", MaxAnomalyLimit), CodeInvalidRequest)
+		return
+	}
+
+	end := q.Get("end_date")
+	if end == "" {
+		end, err = h.latestPredictedActual(storeNbr, family)
+		if err != nil {
+			log.Error().Err(err).Msg("Anomaly check failed")
+			WriteServiceUnavailable(w, r, "actuals store unavailable", CodeActualsUnavailable)
+			return
+		}
+		if end == "" {
+			WriteError(w, r, http.StatusNotFound, "no actuals with a prediction to check", CodeActualsUnavailable)
+			return
+		}
+	}
+	if verr := ValidateDate(end); verr != nil {
+		WriteBadRequest(w, r, "end_date: "+verr.Message, verr.Code)
+		return
+	}
+
+	endDate, _ := time.Parse(DateFormat, end)
+	startDate := endDate.AddDate(0, 0, -(days - 1))
+	start := startDate.Format(DateFormat)
+	records, err := h.actuals.List(actuals.Filter{
+		StoreNbr:  storeNbr,
+		Family:    family,
+		StartDate: startDate.AddDate(0, 0, -cfg.BaselineDays).Format(DateFormat),
+		EndDate:   end,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Anomaly check failed")
+		WriteServiceUnavailable(w, r, "actuals store unavailable", CodeActualsUnavailable)
+		return
+	}
+
+	// Residuals before the window are the z-score baseline; records are ordered by date
+	baseline := make(map[anomaly.Series][]float64)
+	var window []anomaly.Observation
+	for _, rec := range records {
+		if rec.Prediction == nil {
+			continue
+		}
+		if rec.Date < start {
+			s := anomaly.Series{StoreNbr: rec.StoreNbr, Family: rec.Family}
+			baseline[s] = append(baseline[s], rec.Actual-*rec.Prediction)
+			continue
+		}
+		horizon := rec.Horizon
+		if horizon == 0 {
+			horizon = 30
+		}
+		bands := h.applyIntervals(rec.StoreNbr, rec.Family, horizon, float32(*rec.Prediction))
+		window = append(window, anomaly.Observation{
+			StoreNbr:   rec.StoreNbr,
+			Family:     rec.Family,
+			Date:       rec.Date,
+			Actual:     rec.Actual,
+			Prediction: *rec.Prediction,
+			Lower80:    float64(bands.Lower80),
+			Upper80:    float64(bands.Upper80),
+			Lower95:    float64(bands.Lower95),
+			Upper95:    float64(bands.Upper95),
+		})
+	}
+
+	res := anomaly.Detect(window, baseline, cfg)
+	found := res.Anomalies
+	if severity == anomaly.SeverityCritical {
+		found = nil
+		for _, a := range res.Anomalies {
+			if a.Severity == anomaly.SeverityCritical {
+				found = append(found, a)
+			}
+		}
+	}
+
+	resp := AnomaliesResponse{
+		Method:      cfg.Method,
+		WindowStart: start,
+		WindowEnd:   end,
+		Checked:     res.Checked,
+		Unscored:    res.Unscored,
+		Count:       len(found),
+		Anomalies:   found[:min(len(found), limit)],
+		Stores:      anomaly.ByStore(found),
+	}
+	for _, a := range found {
+		if a.Severity == anomaly.SeverityCritical {
+			resp.Critical++
+		}
+	}
+	if resp.Anomalies == nil {
+		resp.Anomalies = []anomaly.Anomaly{}
+		resp.Stores = []anomaly.StoreSummary{}
+	}
+
+	if resp.Critical > 0 {
+		log.Warn().
+			Int("critical", resp.Critical).
+			Int("stores", len(resp.Stores)).
+			Str("window_start", start).
+			Str("window_end", end).
+			Msg("Sales anomalies detected")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// latestPredictedActual returns the latest date of an actual with a prediction among
+// the matching series, or "" when there is none.
+func (h *Handlers) latestPredictedActual(storeNbr int, family string) (string, error) {
+	records, err := h.actuals.List(actuals.Filter{StoreNbr: storeNbr, Family: family})
+	if err != nil {
+		return "", err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Prediction != nil {
+			return records[i].Date, nil
+		}
+	}
+	return "", nil
+}
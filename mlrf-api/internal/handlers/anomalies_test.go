@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+)
+
+func getAnomalies(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, AnomaliesResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Anomalies(w, httptest.NewRequest(http.MethodGet, "/v1/monitoring/anomalies?"+query, nil))
+	var resp AnomaliesResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+// newAnomalyHandlers returns handlers with two weeks of DAIRY actuals of stores 1 and 2
+// predicted at 100, residuals alternating -2 and +2, and the given actuals on
+// 2017-08-15.
+func newAnomalyHandlers(t *testing.T, store1, store2 float64) *Handlers {
+	t.Helper()
+	s := newTestActualsStore(t)
+	prediction := 100.0
+	var records []actuals.Record
+	for day := 1; day <= 14; day++ {
+		for storeNbr := 1; storeNbr <= 2; storeNbr++ {
+			records = append(records, actuals.Record{
+				StoreNbr:   storeNbr,
+				Family:     "DAIRY",
+				Date:       fmt.Sprintf("2017-08-%02d", day),
+				Actual:     prediction + float64(4*(day%2)-2),
+				Prediction: &prediction,
+			})
+		}
+	}
+	records = append(records,
+		actuals.Record{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-15", Actual: store1, Prediction: &prediction},
+		actuals.Record{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-15", Actual: store2, Prediction: &prediction},
+		actuals.Record{StoreNbr: 3, Family: "DAIRY", Date: "2017-08-16", Actual: 500}, // Without a prediction
+	)
+	if err := s.Add(records); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetActualsStore(s, 100)
+	h.SetAnomalyConfig(anomaly.Config{Method: anomaly.MethodAuto, BaselineDays: 28, MinBaseline: 7, ZThreshold: 3.5, CriticalZ: 5, WindowDays: 1})
+	return h
+}
+
+func TestAnomalies(t *testing.T) {
+	// Residuals of +/-2 give a robust sigma of 2.97: 120 is 6.7 sigmas above, 88 4 below
+	h := newAnomalyHandlers(t, 120, 88)
+
+	w, resp := getAnomalies(t, h, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.WindowStart != "2017-08-15" || resp.WindowEnd != "2017-08-15" || resp.Method != anomaly.MethodAuto {
+		t.Errorf("expected the latest predicted day, got %+v", resp)
+	}
+	if resp.Checked != 2 || resp.Count != 2 || resp.Critical != 1 || len(resp.Stores) != 2 {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	if a := resp.Anomalies[0]; a.StoreNbr != 1 || a.Severity != anomaly.SeverityCritical || a.Direction != anomaly.DirectionAbove || a.Method != anomaly.MethodZScore {
+		t.Errorf("expected a critical anomaly above for store 1, got %+v", a)
+	}
+	if a := resp.Anomalies[1]; a.StoreNbr != 2 || a.Severity != anomaly.SeverityWarning || a.Deviation != -12 {
+		t.Errorf("expected a warning below for store 2, got %+v", a)
+	}
+
+	_, resp = getAnomalies(t, h, "severity=critical")
+	if resp.Count != 1 || len(resp.Stores) != 1 || resp.Stores[0].StoreNbr != 1 {
+		t.Errorf("expected only the critical anomaly, got %+v", resp)
+	}
+	_, resp = getAnomalies(t, h, "store_nbr=2&family=DAIRY&days=3")
+	if resp.WindowStart != "2017-08-13" || resp.Checked != 3 || resp.Count != 1 || resp.Anomalies[0].Date != "2017-08-15" {
+		t.Errorf("unexpected store 2 window %+v", resp)
+	}
+	_, resp = getAnomalies(t, h, "limit=1")
+	if resp.Count != 2 || len(resp.Anomalies) != 1 || len(resp.Stores) != 2 {
+		t.Errorf("expected the limit to apply to anomalies only, got %+v", resp)
+	}
+
+	// Without intervals nothing is scored by interval
+	_, resp = getAnomalies(t, h, "method=interval")
+	if resp.Checked != 0 || resp.Unscored != 2 || resp.Anomalies == nil || len(resp.Anomalies) != 0 {
+		t.Errorf("expected unscored actuals, got %+v", resp)
+	}
+	h.intervals = &PredictionIntervals{Lower80Offset: -5, Upper80Offset: 5, Lower95Offset: -15, Upper95Offset: 15}
+	_, resp = getAnomalies(t, h, "")
+	if resp.Critical != 1 || resp.Anomalies[0].Method != anomaly.MethodInterval || resp.Anomalies[0].Upper != 115 || resp.Anomalies[1].Severity != anomaly.SeverityWarning {
+		t.Errorf("expected anomalies by interval, got %+v", resp)
+	}
+}
+
+func TestAnomaliesErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getAnomalies(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without actuals, got %d", w.Code)
+	}
+
+	h.SetActualsStore(newTestActualsStore(t), 100)
+	if w, _ := getAnomalies(t, h, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without predicted actuals, got %d", w.Code)
+	}
+	for query, code := range map[string]string{
+		"method=stl":          CodeInvalidRequest,
+		"severity=info":       CodeInvalidRequest,
+		"days=91":             CodeInvalidRequest,
+		"limit=0":             CodeInvalidRequest,
+		"store_nbr=x":         CodeInvalidStore,
+		"family=NOPE":         CodeInvalidFamily,
+		"end_date=15/08/2017": CodeInvalidDate,
+		"end_date=2017-08-15": "", // An empty window
+	} {
+		w, _ := getAnomalies(t, h, query)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if code == "" {
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", query, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", query, code, w.Code, resp.Code)
+		}
+	}
+}
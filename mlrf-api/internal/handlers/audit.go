@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/audit"
+)
+
+// Limits of the entries returned by /admin/audit.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditResponse lists recorded administrative calls, newest first.
+type AuditResponse struct {
+	Count   int           `json:"count"` // Matching entries, before the limit
+	Entries []audit.Entry `json:"entries"`
+}
+
+// SetAuditStore enables /admin/audit.
+func (h *Handlers) SetAuditStore(s *audit.Store) {
+	h.auditLog = s
+}
+
+// AdminAudit returns the audit trail of /admin calls, newest first. ?api_key=,
+// ?path= (the path and those below it), ?outcome= (success, denied or failure),
+// ?since= and ?until= (RFC 3339) narrow it and ?limit= bounds it. Requires the
+// admin scope.
+func (h *Handlers) AdminAudit(w http.ResponseWriter, r *http.Request) {
+	if h.auditLog == nil {
+		WriteServiceUnavailable(w, r, "admin audit store not available", CodeAuditUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	f := audit.Filter{APIKey: q.Get("api_key"), Path: q.Get("path"), Outcome: q.Get("outcome")}
+	switch f.Outcome {
+	case "", audit.OutcomeSuccess, audit.OutcomeDenied, audit.OutcomeFailure:
+	default:
+		WriteBadRequest(w, r, "outcome must be success, denied or failure", CodeInvalidRequest)
+		return
+	}
+	for name, t := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			WriteBadRequest(w, r, name+" must be an RFC 3339 time, e.g. 2017-08-16T00:00:00Z", CodeInvalidRequest)
+			return
+		}
+		*t = parsed
+	}
+	limit, err := queryInt(r, "limit", DefaultAuditLimit)
+	if err != nil || limit < 1 || limit > MaxAuditLimit {
+		WriteBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", MaxAuditLimit), CodeInvalidRequest)
+		return
+	}
+
+	entries, count := h.auditLog.List(f, limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditResponse{Count: count, Entries: entries})
+}
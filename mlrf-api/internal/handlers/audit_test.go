@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/audit"
+)
+
+func getAudit(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, AuditResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.AdminAudit(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+query, nil))
+	var resp AuditResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestAdminAudit(t *testing.T) {
+	s, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl"), MaxParamBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetAuditStore(s)
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/admin/reload-model", "/admin/cache/flush", "/admin/reload-model"} {
+		outcome := audit.OutcomeSuccess
+		if i == 1 {
+			outcome = audit.OutcomeFailure
+		}
+		s.Append(audit.Entry{Time: start.Add(time.Duration(i) * time.Hour), APIKey: "ops", Method: http.MethodPost, Path: path, Outcome: outcome})
+	}
+
+	w, resp := getAudit(t, h, "")
+	if w.Code != http.StatusOK || resp.Count != 3 || len(resp.Entries) != 3 || resp.Entries[0].ID != 3 {
+		t.Fatalf("expected every entry newest first, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, resp := getAudit(t, h, "path=/admin/reload-model&limit=1"); resp.Count != 2 || len(resp.Entries) != 1 {
+		t.Errorf("expected one of two reloads, got %+v", resp)
+	}
+	if _, resp := getAudit(t, h, "outcome=failure"); resp.Count != 1 || resp.Entries[0].Path != "/admin/cache/flush" {
+		t.Errorf("expected the failed flush, got %+v", resp)
+	}
+	if _, resp := getAudit(t, h, "since=2026-10-01T13:00:00Z&until=2026-10-01T14:00:00Z"); resp.Count != 1 || resp.Entries[0].ID != 2 {
+		t.Errorf("expected the entry within the hour, got %+v", resp)
+	}
+	if _, resp := getAudit(t, h, "api_key=ci"); resp.Count != 0 || resp.Entries == nil {
+		t.Errorf("expected an empty list for another key, got %+v", resp)
+	}
+}
+
+func TestAdminAuditErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getAudit(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without an audit store, got %d", w.Code)
+	}
+
+	s, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	h.SetAuditStore(s)
+	for _, query := range []string{"outcome=ok", "since=yesterday", "limit=0", "limit=1001"} {
+		if w, _ := getAudit(t, h, query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/backtest"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultBacktestDays is the range replayed when no start date is given.
+	DefaultBacktestDays = 28
+
+	// DefaultBacktestStepDays is the number of days per rolling origin.
+	DefaultBacktestStepDays = 7
+
+	// DefaultBacktestMaxDays caps the backtest range when no limit is configured.
+	DefaultBacktestMaxDays = 366
+)
+
+// BacktestRequest selects the date range and series to replay.
+type BacktestRequest struct {
+	StartDate string   `json:"start_date,omitempty"` // Default: 28 days before end_date
+	EndDate   string   `json:"end_date,omitempty"`   // Default: the feature store's latest date
+	Horizon   int      `json:"horizon,omitempty"`    // Horizon of the interval bands (default 30)
+	StepDays  int      `json:"step_days,omitempty"`  // Days per rolling origin (default 7, or the range if shorter)
+	StoreNbrs []int    `json:"store_nbrs,omitempty"` // Filter; empty = all stores
+	Families  []string `json:"families,omitempty"`   // Filter; empty = all families
+}
+
+// BacktestResponse reports rolling-origin accuracy overall, per fold and per series.
+type BacktestResponse struct {
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Horizon      int    `json:"horizon"`
+	StepDays     int    `json:"step_days"`
+	ModelVersion string `json:"model_version,omitempty"`
+	backtest.Result
+	Cached    bool    `json:"cached"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// SetBacktestCache enables caching /backtest results and sets the longest range a
+// backtest may cover. The cache is invalidated on feature store and model reloads
+// and when actuals are submitted.
+func (h *Handlers) SetBacktestCache(c *backtest.Cache, maxDays int) {
+	h.backtests = c
+	h.backtestMaxDays = maxDays
+}
+
+// invalidateBacktests drops cached results and read-only ETags after the model,
+// features or actuals change.
+func (h *Handlers) invalidateBacktests() {
+	h.bumpRevision()
+	if h.backtests != nil {
+		h.backtests.Invalidate()
+	}
+}
+
+// Backtest replays a date range of the feature store through the current model and
+// compares each prediction with the realized sales for its series and date. Realized
+// sales come from submitted actuals where present, otherwise from the next day's
+// sales_lag_1 in the feature matrix. The range is split into rolling origins of
+// step_days, and RMSLE, MAPE and 80/95% interval coverage are reported overall,
+// per origin and per (store, family).
+func (h *Handlers) Backtest(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req BacktestRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if !h.canScoreSeries() {
+		if h.onnx == nil {
+			WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+			return
+		}
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	if req.EndDate == "" {
+		req.EndDate = h.featureStore.GetMetadata().DataDateMax
+	}
+	if err := ValidateDate(req.EndDate); err != nil {
+		WriteBadRequest(w, r, "end_date: "+err.Message, err.Code)
+		return
+	}
+	endDate, _ := time.Parse(DateFormat, req.EndDate)
+	if req.StartDate == "" {
+		req.StartDate = endDate.AddDate(0, 0, -(DefaultBacktestDays - 1)).Format(DateFormat)
+	}
+	if err := ValidateDate(req.StartDate); err != nil {
+		WriteBadRequest(w, r, "start_date: "+err.Message, err.Code)
+		return
+	}
+	startDate, _ := time.Parse(DateFormat, req.StartDate)
+	if req.Horizon == 0 {
+		req.Horizon = 30
+	}
+
+	maxDays := h.backtestMaxDays
+	if maxDays == 0 {
+		maxDays = DefaultBacktestMaxDays
+	}
+	days := int(endDate.Sub(startDate).Hours()/24) + 1
+	if days < 1 {
+		WriteBadRequest(w, r, "start_date must not be after end_date", CodeInvalidRequest)
+		return
+	}
+	if days > maxDays {
+		WriteBadRequest(w, r, fmt.Sprintf("backtest range must not exceed %d days", maxDays), CodeInvalidRequest)
+		return
+	}
+	if req.StepDays == 0 {
+		req.StepDays = min(DefaultBacktestStepDays, days)
+	}
+	if err := ValidateHorizon(req.Horizon); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if req.StepDays < 1 || req.StepDays > days {
+		WriteBadRequest(w, r, fmt.Sprintf("step_days must be between 1 and the range length (%d)", days), CodeInvalidRequest)
+		return
+	}
+	for _, storeNbr := range req.StoreNbrs {
+		if err := h.validateStoreNbr(storeNbr); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+	for _, family := range req.Families {
+		if err := ValidateFamily(family); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+
+	resp := BacktestResponse{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Horizon:   req.Horizon,
+		StepDays:  req.StepDays,
+	}
+	if h.modelLoader != nil {
+		resp.ModelVersion = h.modelLoader.Info().Version
+	}
+
+	key, _ := json.Marshal(req)
+	if h.backtests != nil {
+		if res, ok := h.backtests.Get(string(key)); ok {
+			resp.Result = *res
+			resp.Cached = true
+			resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+			metrics.RecordBacktest("hit", time.Since(start).Seconds())
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+
+	windows := backtest.Windows(startDate, endDate, req.StepDays)
+	points, err := h.replayBacktest(r.Context(), req, windows)
+	if errors.Is(err, inference.ErrInvalidPrediction) {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("backtest inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+	if len(points) == 0 {
+		WriteError(w, r, http.StatusNotFound, "no realized sales found in the backtest range", CodeFeatureNotFound)
+		return
+	}
+
+	res := backtest.Summarize(points, windows)
+	if h.backtests != nil {
+		h.backtests.Set(string(key), &res)
+	}
+	metrics.RecordBacktest("miss", time.Since(start).Seconds())
+
+	log.Info().
+		Str("start_date", req.StartDate).
+		Str("end_date", req.EndDate).
+		Int("points", res.Overall.Count).
+		Float64("rmsle", res.Overall.RMSLE).
+		Dur("duration", time.Since(start)).
+		Msg("Backtest completed")
+
+	resp.Result = res
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// replayBacktest scores every (series, date) in the windows that has a feature matrix
+// row and a realized sales value, returning the paired points.
+func (h *Handlers) replayBacktest(ctx context.Context, req BacktestRequest, windows []backtest.Window) ([]backtest.Point, error) {
+	submitted := make(map[string]float64)
+	if h.actuals != nil {
+		records, err := h.actuals.List(actuals.Filter{StartDate: req.StartDate, EndDate: req.EndDate})
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			submitted[fmt.Sprintf("%d_%s_%s", rec.StoreNbr, rec.Family, rec.Date)] = rec.Actual
+		}
+	}
+
+	var points []backtest.Point
+	var batch [][]float32
+	for _, s := range filterSeries(h.featureStore.Series(), req.StoreNbrs, req.Families) {
+		for fold, win := range windows {
+			for d := win.Origin; !d.After(win.End); d = d.AddDate(0, 0, 1) {
+				f, ok := h.featureStore.Lookup(s.StoreNbr, s.Family, d)
+				if !ok {
+					continue
+				}
+				date := d.Format(DateFormat)
+				actual, ok := submitted[fmt.Sprintf("%d_%s_%s", s.StoreNbr, s.Family, date)]
+				if !ok {
+					sales, known := h.featureStore.Actual(s.StoreNbr, s.Family, d)
+					if !known {
+						continue
+					}
+					actual = float64(sales)
+				}
+				points = append(points, backtest.Point{
+					Fold:     fold,
+					StoreNbr: s.StoreNbr,
+					Family:   s.Family,
+					Date:     date,
+					Actual:   actual,
+				})
+				batch = append(batch, f)
+			}
+		}
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+
+	families := make([]string, len(points))
+	for i, p := range points {
+		families[i] = p.Family
+	}
+	predictions, err := inference.PredictBatch(inference.WithMetricLabels(ctx, "", families...), h.onnx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.guardBatch(predictions); err != nil {
+		return nil, err
+	}
+	for i := range points {
+		p := &points[i]
+		p.Prediction = float64(predictions[i])
+		bands := h.predictionIntervals(p.StoreNbr, p.Family, req.Horizon, batch[i], predictions[i])
+		if bands.Set != "" {
+			p.Interval = &backtest.Interval{
+				Lower80: float64(bands.Lower80),
+				Upper80: float64(bands.Upper80),
+				Lower95: float64(bands.Lower95),
+				Upper95: float64(bands.Upper95),
+			}
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/backtest"
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func postBacktest(t *testing.T, h *Handlers, body string) (*httptest.ResponseRecorder, BacktestResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Backtest(w, httptest.NewRequest(http.MethodPost, "/v1/backtest", bytes.NewBufferString(body)))
+	var resp BacktestResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+// newBacktestHandlers serves one series whose sales_lag_1 rises by 10 a day from
+// 2017-08-01 to 2017-08-05, so lagInferencer under-predicts each day's sales by 10.
+func newBacktestHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	var rows []features.FeatureRow
+	for i := 0; i < 5; i++ {
+		rows = append(rows, features.FeatureRow{
+			StoreNbr:  1,
+			Family:    "DAIRY",
+			Date:      time.Date(2017, 8, 1+i, 0, 0, 0, 0, time.UTC),
+			SalesLag1: float64(10 * (i + 1)),
+		})
+	}
+	h := NewHandlers(lagInferencer{}, nil, newTestFeatureStore(t, rows), nil)
+	h.intervals = &PredictionIntervals{Lower80Offset: -5, Upper80Offset: 5, Lower95Offset: -15, Upper95Offset: 15}
+	h.SetBacktestCache(backtest.NewCache(backtest.Config{CacheTTL: time.Hour, CacheMaxEntries: 10}), 30)
+	h.SetActualsStore(newTestActualsStore(t), 100)
+	return h
+}
+
+func TestBacktest(t *testing.T) {
+	h := newBacktestHandlers(t)
+
+	// A submitted actual overrides the feature matrix and matches the prediction exactly
+	if err := h.actuals.Add([]actuals.Record{{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-04", Actual: 40}}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"start_date": "2017-08-01", "end_date": "2017-08-05", "step_days": 2}`
+	w, resp := postBacktest(t, h, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The last date has no realized sales
+	if resp.Overall.Count != 4 {
+		t.Errorf("expected 4 evaluated points, got %d", resp.Overall.Count)
+	}
+	wantMAPE := (50 + 100.0/3 + 25 + 0) / 4
+	if math.Abs(resp.Overall.MAPE-wantMAPE) > 1e-6 {
+		t.Errorf("expected MAPE %v, got %v", wantMAPE, resp.Overall.MAPE)
+	}
+	if resp.Overall.Coverage80 == nil || *resp.Overall.Coverage80 != 0.25 {
+		t.Errorf("expected 80%% coverage 0.25, got %v", resp.Overall.Coverage80)
+	}
+	if resp.Overall.Coverage95 == nil || *resp.Overall.Coverage95 != 1 {
+		t.Errorf("expected 95%% coverage 1, got %v", resp.Overall.Coverage95)
+	}
+
+	if len(resp.Folds) != 3 {
+		t.Fatalf("expected 3 folds, got %d", len(resp.Folds))
+	}
+	for i, want := range []int{2, 2, 0} {
+		if resp.Folds[i].Count != want {
+			t.Errorf("fold %d: expected %d points, got %d", i, want, resp.Folds[i].Count)
+		}
+	}
+	if len(resp.Series) != 1 || resp.Series[0].StoreNbr != 1 || resp.Series[0].Count != 4 {
+		t.Errorf("unexpected series metrics: %+v", resp.Series)
+	}
+	if resp.Cached {
+		t.Error("expected first backtest not to be cached")
+	}
+
+	if _, resp := postBacktest(t, h, body); !resp.Cached {
+		t.Error("expected repeated backtest to be served from cache")
+	}
+
+	// New actuals invalidate cached results
+	if w := postActuals(h, `{"actuals": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-01", "sales": 10}]}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, resp := postBacktest(t, h, body); resp.Cached {
+		t.Error("expected submitted actuals to invalidate the cache")
+	}
+}
+
+func TestBacktestDefaults(t *testing.T) {
+	h := newBacktestHandlers(t)
+
+	w, resp := postBacktest(t, h, `{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.EndDate != "2017-08-05" || resp.StepDays != DefaultBacktestStepDays || resp.Horizon != 30 {
+		t.Errorf("unexpected defaults: %+v", resp)
+	}
+	if resp.Overall.Count != 4 {
+		t.Errorf("expected 4 evaluated points, got %d", resp.Overall.Count)
+	}
+}
+
+func TestBacktestValidation(t *testing.T) {
+	h := newBacktestHandlers(t)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"start after end", `{"start_date": "2017-08-05", "end_date": "2017-08-01"}`, http.StatusBadRequest},
+		{"range too long", `{"start_date": "2017-01-01", "end_date": "2017-08-01"}`, http.StatusBadRequest},
+		{"step longer than range", `{"start_date": "2017-08-01", "end_date": "2017-08-02", "step_days": 3}`, http.StatusBadRequest},
+		{"invalid date", `{"start_date": "08/01/2017"}`, http.StatusBadRequest},
+		{"unknown family", `{"families": ["NOT A FAMILY"]}`, http.StatusBadRequest},
+		{"no realized sales", `{"start_date": "2017-09-01", "end_date": "2017-09-05"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, _ := postBacktest(t, h, tt.body); w.Code != tt.code {
+				t.Errorf("expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	noModel := NewHandlers(nil, nil, nil, nil)
+	if w, _ := postBacktest(t, noModel, `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 without a model, got %d", w.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/calendar"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/rs/zerolog/log"
+)
+
+// HolidaysResponse lists the holidays of the calendar in a date range.
+type HolidaysResponse struct {
+	Holidays []calendar.Holiday `json:"holidays"`
+	Count    int                `json:"count"`
+	First    string             `json:"first"` // Date of the calendar's first holiday
+	Last     string             `json:"last"`  // Date of its last; later dates are never holidays
+}
+
+// LoadHolidayCalendar loads the holiday calendar that sets is_holiday of days rolled
+// forward past the feature matrix. This is optional - without it, rolled days keep
+// the is_holiday of their series' last known row.
+func (h *Handlers) LoadHolidayCalendar(path string) error {
+	c, err := calendar.Load(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load holiday calendar")
+		return err
+	}
+	h.holidays.Store(c)
+	first, last := c.Span()
+	log.Info().Str("path", path).Int("holidays", c.Len()).Str("first", first).Str("last", last).Msg("Holiday calendar loaded")
+	return nil
+}
+
+// featureBuilder returns a builder over store that flags the calendar's holidays and
+// applies the regressor overrides file.
+func (h *Handlers) featureBuilder(store *features.Store) *features.FeatureBuilder {
+	b := features.NewFeatureBuilder(store).WithRegressors(h.regressors.Load())
+	if c := h.holidays.Load(); c != nil {
+		b = b.WithHolidays(c)
+	}
+	return b
+}
+
+// Holidays returns the holidays of the calendar from start_date to end_date, both
+// optional, or of ?year=. ?locale= keeps National, Regional or Local holidays only;
+// is_holiday flags the national ones. Supports If-None-Match (see notModified).
+func (h *Handlers) Holidays(w http.ResponseWriter, r *http.Request) {
+	c := h.holidays.Load()
+	if c == nil {
+		WriteServiceUnavailable(w, r, "holiday calendar not loaded", CodeCalendarUnavailable)
+		return
+	}
+	first, last := c.Span()
+	if h.notModified(w, r, first, last, strconv.Itoa(c.Len())) {
+		return
+	}
+
+	q := r.URL.Query()
+	from, _ := time.Parse(calendar.DateFormat, first)
+	to, _ := time.Parse(calendar.DateFormat, last)
+	if year := q.Get("year"); year != "" {
+		if q.Get("start_date") != "" || q.Get("end_date") != "" {
+			WriteBadRequest(w, r, "filter by year or date range, not both", CodeInvalidRequest)
+			return
+		}
+		y, err := strconv.Atoi(year)
+		if err != nil || y < 1 || y > 9999 {
+			WriteBadRequest(w, r, "year must be a calendar year, e.g. 2017", CodeInvalidDate)
+			return
+		}
+		from = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to = time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+	for _, p := range []struct {
+		name string
+		date *time.Time
+	}{{"start_date", &from}, {"end_date", &to}} {
+		if v := q.Get(p.name); v != "" {
+			if verr := ValidateDate(v); verr != nil {
+				WriteBadRequest(w, r, p.name+": "+verr.Message, verr.Code)
+				return
+			}
+			*p.date, _ = time.Parse(DateFormat, v)
+		}
+	}
+	if to.Before(from) {
+		WriteBadRequest(w, r, "end_date is before start_date", CodeInvalidDate)
+		return
+	}
+
+	holidays := c.Between(from, to)
+	if locale := q.Get("locale"); locale != "" {
+		kept := holidays[:0]
+		for _, hol := range holidays {
+			if hol.Locale == locale {
+				kept = append(kept, hol)
+			}
+		}
+		holidays = kept
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HolidaysResponse{Holidays: holidays, Count: len(holidays), First: first, Last: last})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+const testHolidays = `date,type,locale,locale_name,description,transferred
+2017-08-10,Holiday,National,Ecuador,Primer Grito de Independencia,True
+2017-08-11,Transfer,National,Ecuador,Traslado Primer Grito de Independencia,False
+2017-08-15,Holiday,Local,Riobamba,Fundacion de Riobamba,False
+2017-12-25,Holiday,National,Ecuador,Navidad,False
+`
+
+func loadTestHolidays(t *testing.T, h *Handlers) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "holidays_events.csv")
+	if err := os.WriteFile(path, []byte(testHolidays), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadHolidayCalendar(path); err != nil {
+		t.Fatalf("LoadHolidayCalendar failed: %v", err)
+	}
+}
+
+func getHolidays(h *Handlers, target string) (*httptest.ResponseRecorder, HolidaysResponse) {
+	w := httptest.NewRecorder()
+	h.Holidays(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp HolidaysResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestHolidays(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getHolidays(h, "/v1/calendar/holidays"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a calendar, got %d", w.Code)
+	}
+	loadTestHolidays(t, h)
+
+	w, resp := getHolidays(h, "/v1/calendar/holidays")
+	if w.Code != http.StatusOK || resp.Count != 4 || resp.First != "2017-08-10" || resp.Last != "2017-12-25" {
+		t.Fatalf("unexpected holidays %d %+v", w.Code, resp)
+	}
+	if _, resp = getHolidays(h, "/v1/calendar/holidays?start_date=2017-08-11&end_date=2017-08-31&locale=National"); resp.Count != 1 || resp.Holidays[0].Type != "Transfer" {
+		t.Errorf("unexpected national holidays in August %+v", resp.Holidays)
+	}
+	if _, resp = getHolidays(h, "/v1/calendar/holidays?year=2018"); resp.Count != 0 || resp.Holidays == nil {
+		t.Errorf("expected an empty list past the calendar, got %+v", resp)
+	}
+
+	for target, code := range map[string]string{
+		"/v1/calendar/holidays?year=twenty":                               CodeInvalidDate,
+		"/v1/calendar/holidays?start_date=08/10/2017":                     CodeInvalidDate,
+		"/v1/calendar/holidays?start_date=2017-09-01&end_date=2017-08-01": CodeInvalidDate,
+		"/v1/calendar/holidays?year=2017&start_date=2017-01-01":           CodeInvalidRequest,
+	} {
+		w, _ := getHolidays(h, target)
+		var errResp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &errResp)
+		if w.Code != http.StatusBadRequest || errResp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", target, code, w.Code, errResp.Code)
+		}
+	}
+}
+
+func TestForecastHolidays(t *testing.T) {
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 9, 0, 0, 0, 0, time.UTC), Year: 2017, IsHoliday: 0},
+	})
+	i, _ := schema.Features.Index("is_holiday")
+	h := NewHandlers(columnInferencer{index: i}, nil, store, nil)
+	loadTestHolidays(t, h)
+
+	w := httptest.NewRecorder()
+	h.Forecast(w, httptest.NewRequest(http.MethodPost, "/v1/forecast", strings.NewReader(`{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-10","horizon":15}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ForecastResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	// Rolled-forward days flag the national holidays, not the last row's is_holiday
+	for _, p := range resp.Forecast {
+		want := float32(0)
+		if p.Date == "2017-08-10" || p.Date == "2017-08-11" {
+			want = 1
+		}
+		if p.Prediction != want {
+			t.Errorf("%s: expected is_holiday %v, got %v", p.Date, want, p.Prediction)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/rs/zerolog/log"
+)
+
+// Calibration statuses of an interval band.
+const (
+	CalibrationOK           = "ok"
+	CalibrationTooNarrow    = "too_narrow"   // Fewer actuals inside the band than its level
+	CalibrationTooWide      = "too_wide"     // More actuals inside the band than its level
+	CalibrationInsufficient = "insufficient" // Fewer actuals than min_samples
+)
+
+// Defaults of /accuracy/calibration.
+const (
+	DefaultCalibrationMinSamples = 30
+	DefaultCalibrationTolerance  = 0.05
+)
+
+// CalibrationStats is the empirical coverage of the 80% and 95% bands over a set of actuals.
+type CalibrationStats struct {
+	Count      int     `json:"count"`
+	Coverage80 float64 `json:"coverage_80"` // Fraction of actuals inside the 80% band
+	Coverage95 float64 `json:"coverage_95"`
+	Status80   string  `json:"status_80"`
+	Status95   string  `json:"status_95"`
+}
+
+// FamilyCalibration is the coverage of one family's intervals.
+type FamilyCalibration struct {
+	Family string `json:"family"`
+	CalibrationStats
+	Recalibrated *PredictionIntervals `json:"recalibrated,omitempty"` // Offsets from the family's residuals, when mis-calibrated
+}
+
+// CalibrationResponse reports interval coverage against submitted actuals.
+type CalibrationResponse struct {
+	StartDate     string              `json:"start_date"` // First and last date of the actuals evaluated
+	EndDate       string              `json:"end_date"`
+	MinSamples    int                 `json:"min_samples"`
+	Tolerance     float64             `json:"tolerance"`
+	Overall       CalibrationStats    `json:"overall"`
+	Families      []FamilyCalibration `json:"families"` // Ordered by family
+	MisCalibrated int                 `json:"mis_calibrated"`
+}
+
+// calibrationPoint is an actual with the interval served for its prediction. Residuals
+// are divided by the horizon scaling of the bands so they compare to unscaled offsets.
+type calibrationPoint struct {
+	in80, in95             bool
+	residual80, residual95 float64
+}
+
+// Calibration measures how often submitted actuals fell inside the 80% and 95% bands
+// of their predictions, overall and per family, with ?start_date=, ?end_date=,
+// ?store_nbr= and ?family= filters. A band whose coverage is more than ?tolerance=
+// from its level is mis-calibrated, and the family gets offsets recomputed from its
+// residuals the way training computes them. ?format=intervals returns the loaded
+// intervals file with those offsets merged into by_family, ready to be written back.
+func (h *Handlers) Calibration(w http.ResponseWriter, r *http.Request) {
+	if h.actuals == nil {
+		WriteServiceUnavailable(w, r, "actuals tracking not enabled", CodeActualsUnavailable)
+		return
+	}
+	h.intervalsMu.RLock()
+	loaded := h.intervals != nil || h.intervalSets != nil
+	h.intervalsMu.RUnlock()
+	if !loaded {
+		WriteServiceUnavailable(w, r, "prediction intervals not loaded", CodeIntervalsUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := actuals.Filter{
+		Family:    q.Get("family"),
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	}
+	storeNbr, err := queryInt(r, "store_nbr", 0)
+	if err != nil {
+		WriteBadRequest(w, r, "store_nbr must be an integer", CodeInvalidStore)
+		return
+	}
+	filter.StoreNbr = storeNbr
+	if filter.Family != "" {
+		if verr := ValidateFamily(filter.Family); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	for _, d := range []string{filter.StartDate, filter.EndDate} {
+		if d == "" {
+			continue
+		}
+		if verr := ValidateDate(d); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	minSamples, err := queryInt(r, "min_samples", DefaultCalibrationMinSamples)
+	if err != nil || minSamples < 1 {
+		WriteBadRequest(w, r, "min_samples must be a positive integer", CodeInvalidRequest)
+		return
+	}
+	tolerance := DefaultCalibrationTolerance
+	if val := q.Get("tolerance"); val != "" {
+		var perr error
+		if tolerance, perr = strconv.ParseFloat(val, 64); perr != nil || tolerance <= 0 || tolerance >= 1 {
+			WriteBadRequest(w, r, "tolerance must be between 0 and 1, exclusive", CodeInvalidRequest)
+			return
+		}
+	}
+	format := q.Get("format")
+	if format != "" && format != "intervals" {
+		WriteBadRequest(w, r, "format must be intervals", CodeInvalidRequest)
+		return
+	}
+	if format == "intervals" && storeNbr != 0 {
+		WriteBadRequest(w, r, "format=intervals recalibrates families across all stores; drop store_nbr", CodeInvalidRequest)
+		return
+	}
+
+	records, err := h.actuals.List(filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Calibration failed")
+		WriteServiceUnavailable(w, r, "actuals store unavailable", CodeActualsUnavailable)
+		return
+	}
+
+	resp := CalibrationResponse{MinSamples: minSamples, Tolerance: tolerance, Families: []FamilyCalibration{}}
+	var all []calibrationPoint
+	byFamily := make(map[string][]calibrationPoint)
+	for _, rec := range records {
+		if rec.Prediction == nil {
+			continue
+		}
+		p, ok := h.calibrationPoint(rec)
+		if !ok {
+			continue
+		}
+		if resp.StartDate == "" {
+			resp.StartDate = rec.Date
+		}
+		resp.EndDate = rec.Date
+		all = append(all, p)
+		byFamily[rec.Family] = append(byFamily[rec.Family], p)
+	}
+	if len(all) == 0 {
+		WriteError(w, r, http.StatusNotFound, "no actuals with a prediction and interval to evaluate", CodeActualsUnavailable)
+		return
+	}
+
+	resp.Overall = calibrationStats(all, minSamples, tolerance)
+	for family, points := range byFamily {
+		fc := FamilyCalibration{Family: family, CalibrationStats: calibrationStats(points, minSamples, tolerance)}
+		if fc.miscalibrated() {
+			resp.MisCalibrated++
+			iv := recalibrate(points)
+			fc.Recalibrated = &iv
+		}
+		resp.Families = append(resp.Families, fc)
+	}
+	sort.Slice(resp.Families, func(i, j int) bool { return resp.Families[i].Family < resp.Families[j].Family })
+
+	if format == "intervals" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.recalibratedIntervals(resp.Families))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// calibrationPoint pairs an actual with the static interval of its prediction. Returns
+// false when no interval applies to the series.
+func (h *Handlers) calibrationPoint(rec actuals.Record) (calibrationPoint, bool) {
+	horizon := rec.Horizon
+	if horizon == 0 {
+		horizon = 30
+	}
+	bands := h.applyIntervals(rec.StoreNbr, rec.Family, horizon, float32(*rec.Prediction))
+	if bands.Upper95 == 0 && bands.Lower95 == 0 {
+		return calibrationPoint{}, false
+	}
+
+	r80, r95 := float32(1), float32(1)
+	if keyed := h.keyedIntervals(); keyed != nil {
+		r80, r95 = keyed.horizonRatios(horizon)
+	}
+	if r80 <= 0 || r95 <= 0 {
+		r80, r95 = 1, 1
+	}
+	residual := rec.Actual - *rec.Prediction
+	return calibrationPoint{
+		in80:       rec.Actual >= float64(bands.Lower80) && rec.Actual <= float64(bands.Upper80),
+		in95:       rec.Actual >= float64(bands.Lower95) && rec.Actual <= float64(bands.Upper95),
+		residual80: residual / float64(r80),
+		residual95: residual / float64(r95),
+	}, true
+}
+
+// keyedIntervals returns the loaded keyed intervals, nil when none are.
+func (h *Handlers) keyedIntervals() *KeyedPredictionIntervals {
+	h.intervalsMu.RLock()
+	defer h.intervalsMu.RUnlock()
+	return h.intervalSets
+}
+
+// recalibratedIntervals returns a copy of the loaded intervals with the recalibrated
+// family offsets in by_family.
+func (h *Handlers) recalibratedIntervals(families []FamilyCalibration) KeyedPredictionIntervals {
+	var out KeyedPredictionIntervals
+	if keyed := h.keyedIntervals(); keyed != nil {
+		out = *keyed
+	} else {
+		h.intervalsMu.RLock()
+		out.Global = h.intervals
+		h.intervalsMu.RUnlock()
+	}
+
+	byFamily := make(map[string]PredictionIntervals, len(out.ByFamily))
+	for family, iv := range out.ByFamily {
+		byFamily[family] = iv
+	}
+	for _, fc := range families {
+		if fc.Recalibrated != nil {
+			byFamily[fc.Family] = *fc.Recalibrated
+		}
+	}
+	if len(byFamily) > 0 {
+		out.ByFamily = byFamily
+	}
+	return out
+}
+
+// miscalibrated reports whether either band of a family with enough samples is off its level.
+func (f FamilyCalibration) miscalibrated() bool {
+	return f.Status80 == CalibrationTooNarrow || f.Status80 == CalibrationTooWide ||
+		f.Status95 == CalibrationTooNarrow || f.Status95 == CalibrationTooWide
+}
+
+// calibrationStats computes the coverage of points and grades each band against its level.
+func calibrationStats(points []calibrationPoint, minSamples int, tolerance float64) CalibrationStats {
+	s := CalibrationStats{Count: len(points)}
+	var in80, in95 int
+	for _, p := range points {
+		if p.in80 {
+			in80++
+		}
+		if p.in95 {
+			in95++
+		}
+	}
+	s.Coverage80 = float64(in80) / float64(len(points))
+	s.Coverage95 = float64(in95) / float64(len(points))
+
+	status := func(coverage, level float64) string {
+		switch {
+		case len(points) < minSamples:
+			return CalibrationInsufficient
+		case coverage < level-tolerance:
+			return CalibrationTooNarrow
+		case coverage > level+tolerance:
+			return CalibrationTooWide
+		}
+		return CalibrationOK
+	}
+	s.Status80 = status(s.Coverage80, 0.8)
+	s.Status95 = status(s.Coverage95, 0.95)
+	return s
+}
+
+// recalibrate computes interval offsets from the residual percentiles of points, as
+// training does from the validation residuals: P10-P90 for the 80% band and P2.5-P97.5
+// for the 95% band.
+func recalibrate(points []calibrationPoint) PredictionIntervals {
+	r80 := make([]float64, len(points))
+	r95 := make([]float64, len(points))
+	var sum, sumAbs float64
+	for i, p := range points {
+		r80[i], r95[i] = p.residual80, p.residual95
+		sum += p.residual80
+		sumAbs += math.Abs(p.residual80)
+	}
+	sort.Float64s(r80)
+	sort.Float64s(r95)
+
+	n := float64(len(points))
+	mean := sum / n
+	var sq float64
+	for _, r := range r80 {
+		sq += (r - mean) * (r - mean)
+	}
+	return PredictionIntervals{
+		Lower80Offset: float32(percentile(r80, 10)),
+		Upper80Offset: float32(percentile(r80, 90)),
+		Lower95Offset: float32(percentile(r95, 2.5)),
+		Upper95Offset: float32(percentile(r95, 97.5)),
+		Std:           float32(math.Sqrt(sq / n)),
+		MeanAbsError:  float32(sumAbs / n),
+		NSamples:      len(points),
+	}
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating linearly
+// between the closest ranks like numpy.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+)
+
+func getCalibration(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, CalibrationResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Calibration(w, httptest.NewRequest(http.MethodGet, "/v1/accuracy/calibration?"+query, nil))
+	var resp CalibrationResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+// newCalibrationHandlers returns handlers with 40 DAIRY actuals, 31 of store 1 and 9 of
+// store 2, whose residuals run from -20 to 19 around a prediction of 100, and 10
+// BEVERAGES actuals on their prediction.
+func newCalibrationHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	s := newTestActualsStore(t)
+	prediction := 100.0
+	var records []actuals.Record
+	for i := 0; i < 40; i++ {
+		date := fmt.Sprintf("2017-07-%02d", i%31+1)
+		records = append(records,
+			actuals.Record{StoreNbr: 1 + i/31, Family: "DAIRY", Date: date, Actual: prediction + float64(i-20), Prediction: &prediction})
+		if i < 10 {
+			records = append(records, actuals.Record{StoreNbr: 1, Family: "BEVERAGES", Date: date, Actual: prediction, Prediction: &prediction})
+		}
+	}
+	if err := s.Add(records); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetActualsStore(s, 100)
+	return h
+}
+
+func TestCalibration(t *testing.T) {
+	h := newCalibrationHandlers(t)
+	h.intervals = &PredictionIntervals{Lower80Offset: -5, Upper80Offset: 5, Lower95Offset: -10, Upper95Offset: 10}
+
+	w, resp := getCalibration(t, h, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Overall.Count != 50 || resp.StartDate != "2017-07-01" || resp.EndDate != "2017-07-31" || len(resp.Families) != 2 {
+		t.Fatalf("unexpected calibration %+v", resp)
+	}
+	// BEVERAGES is always inside but has too few actuals to judge
+	if f := resp.Families[0]; f.Family != "BEVERAGES" || f.Coverage80 != 1 || f.Status80 != CalibrationInsufficient || f.Recalibrated != nil {
+		t.Errorf("unexpected BEVERAGES calibration %+v", f)
+	}
+	// 11 of the DAIRY residuals are within 5, 21 within 10
+	f := resp.Families[1]
+	if f.Coverage80 != 11.0/40 || f.Coverage95 != 21.0/40 || f.Status80 != CalibrationTooNarrow || f.Status95 != CalibrationTooNarrow || resp.MisCalibrated != 1 {
+		t.Fatalf("unexpected DAIRY calibration %+v", f)
+	}
+	iv := f.Recalibrated
+	if iv == nil || math.Abs(float64(iv.Lower80Offset)+16.1) > 1e-4 || math.Abs(float64(iv.Upper95Offset)-18.025) > 1e-4 || iv.NSamples != 40 || iv.MeanAbsError != 10 {
+		t.Errorf("unexpected recalibrated offsets %+v", iv)
+	}
+
+	// Judged on fewer samples, BEVERAGES' bands cover every actual and are too wide
+	if _, resp := getCalibration(t, h, "family=BEVERAGES&min_samples=5"); resp.Overall.Status80 != CalibrationTooWide || resp.Families[0].Recalibrated == nil {
+		t.Errorf("expected always-covered intervals to be too wide, got %+v", resp)
+	}
+	if _, resp := getCalibration(t, h, "store_nbr=2"); resp.Overall.Count != 9 || resp.StartDate != "2017-07-01" {
+		t.Errorf("unexpected store 2 calibration %+v", resp)
+	}
+}
+
+func TestCalibrationIntervalsFormat(t *testing.T) {
+	h := newCalibrationHandlers(t)
+	global := PredictionIntervals{Lower80Offset: -5, Upper80Offset: 5, Lower95Offset: -10, Upper95Offset: 10}
+	h.intervals = &global
+	h.intervalSets = &KeyedPredictionIntervals{
+		Global:    &global,
+		ByFamily:  map[string]PredictionIntervals{"EGGS": global},
+		ByHorizon: map[string]PredictionIntervals{"30": {Lower80Offset: -10, Upper80Offset: 10, Lower95Offset: -20, Upper95Offset: 20}},
+	}
+
+	w := httptest.NewRecorder()
+	h.Calibration(w, httptest.NewRequest(http.MethodGet, "/v1/accuracy/calibration?format=intervals", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	keyed, err := parsePredictionIntervals(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("expected an intervals file, got %v", err)
+	}
+	if keyed.Global == nil || len(keyed.ByHorizon) != 1 || keyed.ByFamily["EGGS"] != global {
+		t.Errorf("expected the loaded intervals to be kept, got %+v", keyed)
+	}
+	// Served at 30 days the bands are twice as wide, so the offsets are halved
+	dairy, ok := keyed.ByFamily["DAIRY"]
+	if !ok || math.Abs(float64(dairy.Lower80Offset)+8.05) > 1e-4 || math.Abs(float64(dairy.Upper95Offset)-9.0125) > 1e-4 {
+		t.Errorf("unexpected DAIRY offsets %+v", dairy)
+	}
+	if _, ok := keyed.ByFamily["BEVERAGES"]; ok {
+		t.Error("expected BEVERAGES to keep its intervals")
+	}
+	if len(h.intervalSets.ByFamily) != 1 {
+		t.Error("expected the loaded intervals to be unchanged")
+	}
+}
+
+func TestCalibrationErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getCalibration(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without actuals, got %d", w.Code)
+	}
+	h = newCalibrationHandlers(t)
+	w, _ := getCalibration(t, h, "")
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusServiceUnavailable || errResp.Code != CodeIntervalsUnavailable {
+		t.Errorf("expected 503 %s without intervals, got %d %s", CodeIntervalsUnavailable, w.Code, errResp.Code)
+	}
+
+	h.intervals = &PredictionIntervals{Lower80Offset: -5, Upper80Offset: 5, Lower95Offset: -10, Upper95Offset: 10}
+	if w, _ := getCalibration(t, h, "start_date=2017-08-01"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without actuals in range, got %d", w.Code)
+	}
+	for query, code := range map[string]string{
+		"store_nbr=x":                  CodeInvalidStore,
+		"family=NOPE":                  CodeInvalidFamily,
+		"end_date=31/07/2017":          CodeInvalidDate,
+		"min_samples=0":                CodeInvalidRequest,
+		"tolerance=1":                  CodeInvalidRequest,
+		"format=csv":                   CodeInvalidRequest,
+		"format=intervals&store_nbr=1": CodeInvalidRequest,
+	} {
+		w, _ := getCalibration(t, h, query)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", query, code, w.Code, resp.Code)
+		}
+	}
+}
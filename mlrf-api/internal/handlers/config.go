@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var errConfigUnavailable = errors.New("configuration not loaded")
+
+// ConfigResponse is the effective configuration reported by /admin/config.
+type ConfigResponse struct {
+	File     string                               `json:"file,omitempty"` // Configuration file, if one was loaded
+	Settings map[string]map[string]config.Setting `json:"settings"`       // By section and key; secrets redacted
+}
+
+// SetConfig sets the configuration reported by /admin/config, and applies its log
+// level, cache TTLs and feature staleness threshold. Call it at startup: the request
+// limits, readiness checks and data paths it also sets are read once.
+func (h *Handlers) SetConfig(cfg *config.Config) {
+	h.config.Store(cfg)
+	h.drainTimeout = cfg.Server.DrainTimeout
+	h.maxBatchSize = cfg.Predictions.MaxBatchSize
+	h.streamLimit = cfg.Predictions.StreamMaxBatchSize
+	h.SetStrictJSON(cfg.Server.StrictJSON)
+	h.SetMockFallbacks(cfg.Server.MockFallbacks)
+	h.importanceSample = cfg.Data.GlobalImportanceSample
+	h.hierarchyDataPath = cfg.Data.HierarchyDataPath
+	h.readiness = ReadinessConfig{
+		RequireModel:    cfg.Health.RequireModel,
+		RequireFeatures: cfg.Health.RequireFeatures,
+		RequireRedis:    cfg.Health.RequireRedis,
+		RedisTimeout:    cfg.Health.RedisTimeout,
+	}
+	h.applyConfig(cfg)
+	if level, err := zerolog.ParseLevel(cfg.Server.LogLevel); err == nil {
+		h.setLogLevel(level, 0)
+	}
+}
+
+// OnConfigReload registers fn to apply the runtime settings of each configuration
+// reloaded by RefreshConfig, for components the handlers don't own (middleware).
+func (h *Handlers) OnConfigReload(fn func(*config.Config)) {
+	h.configHooks = append(h.configHooks, fn)
+}
+
+// applyConfig applies the runtime settings of cfg to the handlers' dependencies.
+func (h *Handlers) applyConfig(cfg *config.Config) {
+	if h.cache != nil {
+		// Validated with the configuration
+		rules, _ := cache.ParseTTLRules(cfg.Cache.TTLPolicy)
+		h.cache.SetTTLPolicy(cache.TTLPolicy{
+			Default:     cfg.Cache.TTL,
+			Negative:    cfg.Cache.NegativeTTL,
+			Rules:       rules,
+			Jitter:      cfg.Cache.TTLJitter,
+			EarlyExpiry: cfg.Cache.EarlyExpiry,
+		})
+	}
+	if h.featureStore != nil {
+		h.featureStore.SetStalenessThreshold(cfg.Features.StalenessThreshold)
+	}
+}
+
+// RefreshConfig reads the configuration file and environment again and applies the
+// reloadable settings. It returns every changed setting; those not reloadable take
+// effect at the next restart. On an invalid configuration the current one is kept.
+func (h *Handlers) RefreshConfig() ([]config.Change, error) {
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
+
+	cur := h.config.Load()
+	if cur == nil {
+		return nil, errConfigUnavailable
+	}
+	next, err := cur.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	h.config.Store(next)
+	h.applyConfig(next)
+	// A temporary level from /admin/log-level is only replaced when the configured one changes
+	if next.Server.LogLevel != cur.Server.LogLevel {
+		if level, err := zerolog.ParseLevel(next.Server.LogLevel); err == nil {
+			h.setLogLevel(level, 0)
+		}
+	}
+	for _, fn := range h.configHooks {
+		fn(next)
+	}
+
+	changes := cur.Changes(next)
+	for _, c := range changes {
+		if !c.Reloadable {
+			log.Warn().Str("setting", c.Key).Str("env", c.Env).Msg("Changed setting takes effect after a restart")
+		}
+	}
+	log.Info().Str("file", next.File()).Int("changes", len(changes)).Msg("Configuration reloaded")
+	return changes, nil
+}
+
+// AdminConfig returns the effective configuration with secrets redacted, and where each
+// setting came from (default, file or env).
+// Requires the admin scope.
+func (h *Handlers) AdminConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Load()
+	if cfg == nil {
+		WriteServiceUnavailable(w, r, "configuration not loaded", CodeConfigUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConfigResponse{File: cfg.File(), Settings: cfg.Redacted()})
+}
+
+// ReloadConfig reloads the configuration file and environment, applying rate limits,
+// CORS origins, the cache TTLs, the log level and the feature staleness threshold without
+// a restart. The response lists the applied changes and those that need a restart.
+// Requires the admin scope.
+func (h *Handlers) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if h.config.Load() == nil {
+		WriteServiceUnavailable(w, r, "configuration not loaded", CodeConfigUnavailable)
+		return
+	}
+
+	changes, err := h.RefreshConfig()
+	if err != nil {
+		WriteInternalError(w, r, "reload failed: "+err.Error(), CodeReloadFailed)
+		return
+	}
+
+	applied, restart := []string{}, []string{}
+	for _, c := range changes {
+		if c.Reloadable {
+			applied = append(applied, c.Key)
+		} else {
+			restart = append(restart, c.Key)
+		}
+	}
+
+	resp := ReloadResponse{
+		Status:  "reloaded",
+		Message: "Configuration reloaded successfully",
+		Metadata: map[string]interface{}{
+			"file":             h.config.Load().File(),
+			"applied":          applied,
+			"restart_required": restart,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
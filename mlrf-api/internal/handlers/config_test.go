@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func TestAdminConfig(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	w := httptest.NewRecorder()
+	h.AdminConfig(w, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 without a configuration, got %d", w.Code)
+	}
+
+	t.Setenv("ADMIN_API_KEY", "secret")
+	t.Setenv("JOB_WORKERS", "4")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetConfig(cfg)
+
+	w = httptest.NewRecorder()
+	h.AdminConfig(w, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ConfigResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if got := resp.Settings["server"]["admin_api_key"]; got.Value != "[REDACTED]" || got.Source != config.SourceEnv {
+		t.Errorf("expected the admin key redacted, got %+v", got)
+	}
+	if got := resp.Settings["predictions"]["job_workers"]; got.Value != float64(4) || got.Env != "JOB_WORKERS" {
+		t.Errorf("expected job_workers 4 from JOB_WORKERS, got %+v", got)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mlrf.toml")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("ADMIN_API_KEY", "")
+	t.Setenv("RATE_LIMIT_RPS", "")
+	t.Setenv("JOB_WORKERS", "")
+	t.Setenv("FEATURE_STALENESS_THRESHOLD", "")
+
+	write("[server]\nrate_limit_rps = 50\n\n[predictions]\njob_workers = 2\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newTestFeatureStore(t, []features.FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)}})
+	h := NewHandlers(nil, nil, store, nil)
+	h.SetConfig(cfg)
+	var rps float64
+	h.OnConfigReload(func(cfg *config.Config) { rps = cfg.Server.RateLimitRPS })
+
+	write("[server]\nrate_limit_rps = 25\n\n[predictions]\njob_workers = 3\n\n[features]\nstaleness_threshold = \"2h\"\n")
+	w := httptest.NewRecorder()
+	h.ReloadConfig(w, httptest.NewRequest(http.MethodPost, "/admin/config/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Metadata struct {
+			Applied         []string `json:"applied"`
+			RestartRequired []string `json:"restart_required"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if got := resp.Metadata.Applied; len(got) != 2 || got[0] != "server.rate_limit_rps" || got[1] != "features.staleness_threshold" {
+		t.Errorf("unexpected applied changes %v", got)
+	}
+	if got := resp.Metadata.RestartRequired; len(got) != 1 || got[0] != "predictions.job_workers" {
+		t.Errorf("unexpected restart-required changes %v", got)
+	}
+	if rps != 25 {
+		t.Errorf("expected the reloaded rate limit applied, got %v", rps)
+	}
+	if got := store.StalenessThreshold(); got != 2*time.Hour {
+		t.Errorf("expected staleness threshold 2h, got %s", got)
+	}
+
+	// An invalid file is rejected and the running configuration kept
+	write("[server]\nrate_limit_rps = \"fast\"\n")
+	w = httptest.NewRecorder()
+	h.ReloadConfig(w, httptest.NewRequest(http.MethodPost, "/admin/config/reload", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for an invalid file, got %d", w.Code)
+	}
+	if got := h.config.Load().Server.RateLimitRPS; got != 25 {
+		t.Errorf("expected the previous configuration kept, got rate limit %v", got)
+	}
+}
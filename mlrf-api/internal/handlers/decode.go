@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SetStrictJSON sets whether request bodies with unknown fields or trailing data are
+// rejected with UNKNOWN_FIELD / INVALID_REQUEST instead of ignored.
+func (h *Handlers) SetStrictJSON(strict bool) {
+	h.strictJSON = strict
+}
+
+// decodeJSON decodes the request body into v, writing the error response and returning
+// false on failure: 413 for a body over the size limit (see middleware.BodyLimit), and
+// 400 for invalid JSON or, in strict mode, unknown fields and trailing data.
+func (h *Handlers) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	if h.strictJSON {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(v)
+	if err == nil && h.strictJSON {
+		if _, trailing := dec.Token(); trailing != io.EOF {
+			err = errTrailingData
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		WriteError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit), CodePayloadTooLarge)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		WriteBadRequest(w, r, strings.TrimPrefix(err.Error(), "json: "), CodeUnknownField)
+	case errors.Is(err, errTrailingData):
+		WriteBadRequest(w, r, err.Error(), CodeInvalidRequest)
+	default:
+		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	}
+	return false
+}
+
+var errTrailingData = errors.New("request body must contain a single JSON value")
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	decode := func(h *Handlers, body string, limit int64) (*httptest.ResponseRecorder, bool) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(body))
+		if limit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		var req PredictRequest
+		return w, h.decodeJSON(w, r, &req)
+	}
+	code := func(w *httptest.ResponseRecorder) string {
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Code
+	}
+
+	h := NewHandlers(nil, nil, nil, nil)
+	body := `{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-01", "horizon": 30, "stor_nbr": 2}`
+	if _, ok := decode(h, body, 0); !ok {
+		t.Error("expected unknown fields ignored by default")
+	}
+	if w, ok := decode(h, body, 20); ok || w.Code != http.StatusRequestEntityTooLarge || code(w) != CodePayloadTooLarge {
+		t.Errorf("expected 413 PAYLOAD_TOO_LARGE, got %d %s", w.Code, code(w))
+	}
+
+	h.SetStrictJSON(true)
+	w, ok := decode(h, body, 0)
+	if ok || w.Code != http.StatusBadRequest || code(w) != CodeUnknownField || !strings.Contains(w.Body.String(), `stor_nbr`) {
+		t.Errorf("expected 400 UNKNOWN_FIELD naming stor_nbr, got %d %s", w.Code, w.Body.String())
+	}
+	if w, ok := decode(h, `{"store_nbr": 1} {"store_nbr": 2}`, 0); ok || code(w) != CodeInvalidRequest {
+		t.Errorf("expected trailing data rejected, got %d %s", w.Code, code(w))
+	}
+	if _, ok := decode(h, `{"store_nbr": 1, "family": "DAIRY"}`+"\n", 0); !ok {
+		t.Error("expected a known-field body accepted in strict mode")
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+// FamiliesResponse lists the product families of the loaded feature matrix.
+type FamiliesResponse struct {
+	Families []features.Family `json:"families"`
+	Count    int               `json:"count"`
+}
+
+// StoresResponse lists the stores of the loaded feature matrix.
+type StoresResponse struct {
+	Stores []features.StoreInfo `json:"stores"`
+	Count  int                  `json:"count"`
+}
+
+// Families returns every product family with its encoded ID, so frontends read the
+// valid families from the data rather than hardcoding them.
+func (h *Handlers) Families(w http.ResponseWriter, r *http.Request) {
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	families, _ := h.featureStore.Dimensions()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FamiliesResponse{Families: families, Count: len(families)})
+}
+
+// Stores returns every store with its cluster and type.
+func (h *Handlers) Stores(w http.ResponseWriter, r *http.Request) {
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	_, stores := h.featureStore.Dimensions()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StoresResponse{Stores: stores, Count: len(stores)})
+}
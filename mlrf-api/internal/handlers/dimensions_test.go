@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func TestFamiliesAndStores(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	row := func(store int32, family string, familyID, cluster, typeID int32) features.FeatureRow {
+		return features.FeatureRow{StoreNbr: store, Family: family, Date: date, Cluster: cluster, FamilyEncoded: familyID, TypeEncoded: typeID}
+	}
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		row(2, "DAIRY", 7, 13, 3),
+		row(1, "DAIRY", 7, 4, 1),
+		row(1, "BEVERAGES", 3, 4, 1),
+	})
+	h := NewHandlers(nil, nil, store, nil)
+
+	w := httptest.NewRecorder()
+	h.Families(w, httptest.NewRequest(http.MethodGet, "/families", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var families FamiliesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &families); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := []features.Family{{Name: "BEVERAGES", ID: 3, Stores: 1}, {Name: "DAIRY", ID: 7, Stores: 2}}
+	if families.Count != 2 || len(families.Families) != 2 || families.Families[0] != want[0] || families.Families[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, families)
+	}
+
+	w = httptest.NewRecorder()
+	h.Stores(w, httptest.NewRequest(http.MethodGet, "/stores", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stores StoresResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stores); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	wantStores := []features.StoreInfo{{StoreNbr: 1, Cluster: 4, Type: 1, Families: 2}, {StoreNbr: 2, Cluster: 13, Type: 3, Families: 1}}
+	if stores.Count != 2 || len(stores.Stores) != 2 || stores.Stores[0] != wantStores[0] || stores.Stores[1] != wantStores[1] {
+		t.Errorf("expected %+v, got %+v", wantStores, stores)
+	}
+
+	w = httptest.NewRecorder()
+	NewHandlers(nil, nil, nil, nil).Families(w, httptest.NewRequest(http.MethodGet, "/families", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 without a feature store, got %d", w.Code)
+	}
+}
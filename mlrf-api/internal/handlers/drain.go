@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDrainTimeout is how long a drain waits for in-flight work when DRAIN_TIMEOUT is unset.
+const DefaultDrainTimeout = 5 * time.Minute
+
+// drainPollInterval is how often a drain checks for remaining work.
+var drainPollInterval = 100 * time.Millisecond
+
+// InFlightCounter reports the number of requests currently being served.
+type InFlightCounter interface {
+	InFlight() int64
+}
+
+// DrainStatus reports the progress of a drain started by POST /admin/drain.
+type DrainStatus struct {
+	Draining             bool       `json:"draining"`
+	Complete             bool       `json:"complete"` // No requests, jobs or prediction log entries remain
+	StartedAt            *time.Time `json:"started_at,omitempty"`
+	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	InFlightRequests     int64      `json:"in_flight_requests"`
+	ActiveJobs           int        `json:"active_jobs"`            // Queued and running async prediction jobs
+	PredictionLogPending int        `json:"prediction_log_pending"` // Entries not yet taken by the log writer
+	Error                string     `json:"error,omitempty"`        // Set if the drain timed out
+}
+
+// drainState tracks a drain; once started it lasts until the process exits.
+type drainState struct {
+	mu          sync.Mutex
+	draining    bool
+	startedAt   time.Time
+	completedAt time.Time
+	err         string
+}
+
+// SetInFlightCounter sets the in-flight request count a drain waits for.
+func (h *Handlers) SetInFlightCounter(c InFlightCounter) {
+	h.inFlight = c
+}
+
+// draining reports whether a drain has started.
+func (h *Handlers) draining() bool {
+	h.drain.mu.Lock()
+	defer h.drain.mu.Unlock()
+	return h.drain.draining
+}
+
+// drainStatus returns the drain state with the work still remaining.
+func (h *Handlers) drainStatus() DrainStatus {
+	h.drain.mu.Lock()
+	status := DrainStatus{Draining: h.drain.draining, Error: h.drain.err}
+	if h.drain.draining {
+		started := h.drain.startedAt
+		status.StartedAt = &started
+	}
+	if !h.drain.completedAt.IsZero() {
+		completed := h.drain.completedAt
+		status.CompletedAt = &completed
+		status.Complete = true
+	}
+	h.drain.mu.Unlock()
+
+	if h.inFlight != nil {
+		status.InFlightRequests = h.inFlight.InFlight()
+	}
+	if h.jobs != nil {
+		status.ActiveJobs = h.jobs.Active()
+	}
+	if h.predLog != nil {
+		status.PredictionLogPending = h.predLog.Pending()
+	}
+	return status
+}
+
+// startDrain marks the server as draining and waits in the background for in-flight
+// requests and jobs to finish, then flushes the prediction log. It returns false if a
+// drain had already started.
+func (h *Handlers) startDrain(timeout time.Duration) bool {
+	h.drain.mu.Lock()
+	defer h.drain.mu.Unlock()
+	if h.drain.draining {
+		return false
+	}
+	h.drain.draining = true
+	h.drain.startedAt = time.Now()
+	log.Info().Dur("timeout", timeout).Msg("Draining: readiness failing and new batch jobs rejected")
+
+	go h.runDrain(timeout)
+	return true
+}
+
+// runDrain waits for the remaining work, recording completion or a timeout.
+func (h *Handlers) runDrain(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		status := h.drainStatus()
+		if status.InFlightRequests == 0 && status.ActiveJobs == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			h.failDrain(fmt.Sprintf("timed out after %s with %d requests in flight and %d active jobs",
+				timeout, status.InFlightRequests, status.ActiveJobs))
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if h.predLog != nil {
+		if err := h.predLog.Flush(ctx); err != nil {
+			h.failDrain("timed out flushing the prediction log")
+			return
+		}
+	}
+
+	h.drain.mu.Lock()
+	h.drain.completedAt = time.Now()
+	elapsed := h.drain.completedAt.Sub(h.drain.startedAt)
+	h.drain.mu.Unlock()
+	log.Info().Dur("duration", elapsed).Msg("Drain complete")
+}
+
+// failDrain records why a drain did not complete.
+func (h *Handlers) failDrain(reason string) {
+	h.drain.mu.Lock()
+	h.drain.err = reason
+	h.drain.mu.Unlock()
+	log.Warn().Str("reason", reason).Msg("Drain incomplete")
+}
+
+// StartDrain puts the server in drain mode for a rolling update: /health/ready fails,
+// new async prediction jobs are rejected, and in-flight requests, jobs and the prediction
+// log buffer are waited for in the background. Returns 202 with the drain progress; the
+// drain cannot be undone short of a restart.
+// Requires the admin scope.
+func (h *Handlers) StartDrain(w http.ResponseWriter, r *http.Request) {
+	h.startDrain(h.drainTimeout)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(h.drainStatus())
+}
+
+// GetDrainStatus returns the drain progress.
+// Requires the admin scope.
+func (h *Handlers) GetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.drainStatus())
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/jobs"
+)
+
+type fakeInFlight struct{ n atomic.Int64 }
+
+func (f *fakeInFlight) InFlight() int64 { return f.n.Load() }
+
+func TestDrain(t *testing.T) {
+	prevPoll := drainPollInterval
+	drainPollInterval = time.Millisecond
+	defer func() { drainPollInterval = prevPoll }()
+
+	manager := jobs.NewManager(jobs.Config{Workers: 1, QueueSize: 2, MaxItems: 10})
+	defer manager.Close()
+	release := make(chan struct{})
+	if _, err := manager.Submit(1, func(ctx context.Context, progress func(int)) (interface{}, error) {
+		<-release
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	inFlight := &fakeInFlight{}
+	inFlight.n.Store(1)
+	h := NewHandlers(lagInferencer{}, nil, nil, nil)
+	h.readiness = ReadinessConfig{RequireModel: true}
+	h.SetJobManager(manager)
+	h.SetInFlightCounter(inFlight)
+
+	status := func() DrainStatus {
+		w := httptest.NewRecorder()
+		h.GetDrainStatus(w, httptest.NewRequest(http.MethodGet, "/admin/drain", nil))
+		var s DrainStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return s
+	}
+	if s := status(); s.Draining || s.Complete {
+		t.Fatalf("expected no drain before POST, got %+v", s)
+	}
+
+	w := httptest.NewRecorder()
+	h.StartDrain(w, httptest.NewRequest(http.MethodPost, "/admin/drain", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Readiness(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"draining"`) {
+		t.Errorf("expected readiness 503 draining, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.SubmitPredictJob(w, httptest.NewRequest(http.MethodPost, "/predict/jobs",
+		strings.NewReader(`{"predictions": [{"store_nbr": 1, "family": "DAIRY", "date": "2017-08-01", "horizon": 30}]}`)))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), CodeDraining) {
+		t.Errorf("expected new jobs rejected with %s, got %d %s", CodeDraining, w.Code, w.Body.String())
+	}
+
+	if s := status(); !s.Draining || s.Complete || s.InFlightRequests != 1 || s.ActiveJobs != 1 {
+		t.Errorf("expected a drain waiting on 1 request and 1 job, got %+v", s)
+	}
+
+	inFlight.n.Store(0)
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for !status().Complete && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := status(); !s.Complete || s.CompletedAt == nil || s.Error != "" {
+		t.Errorf("expected the drain to complete, got %+v", s)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	prevPoll := drainPollInterval
+	drainPollInterval = time.Millisecond
+	defer func() { drainPollInterval = prevPoll }()
+
+	inFlight := &fakeInFlight{}
+	inFlight.n.Store(3)
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetInFlightCounter(inFlight)
+	h.startDrain(20 * time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for h.drainStatus().Error == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := h.drainStatus(); s.Complete || !strings.Contains(s.Error, "3 requests in flight") {
+		t.Errorf("expected the drain to time out on 3 requests, got %+v", s)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/drift"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxDriftWindowDays caps the window compared by /monitoring/feature-drift.
+const MaxDriftWindowDays = 365
+
+// FeatureDriftResponse reports per-feature drift of a recent window against the training reference.
+type FeatureDriftResponse struct {
+	WindowStart  string               `json:"window_start"`
+	WindowEnd    string               `json:"window_end"`
+	Rows         int                  `json:"rows"`                // Feature rows in the window
+	Reference    string               `json:"reference,omitempty"` // Source of the reference distributions
+	PSIThreshold float64              `json:"psi_threshold"`
+	KSThreshold  float64              `json:"ks_threshold"`
+	Drifted      int                  `json:"drifted"`
+	Features     []drift.FeatureDrift `json:"features"` // In model feature order
+}
+
+// SetFeatureDrift enables /monitoring/feature-drift with training reference distributions.
+func (h *Handlers) SetFeatureDrift(ref *drift.Reference, cfg drift.Config) {
+	h.driftRef = ref
+	h.driftCfg = cfg
+}
+
+// FeatureDrift compares the distribution of each model feature over the most recent
+// window of the feature store against the training reference, reporting PSI and KS
+// statistics and flagging features above either threshold. ?end_date= (default the
+// latest date) and ?window_days= select the window.
+func (h *Handlers) FeatureDrift(w http.ResponseWriter, r *http.Request) {
+	if h.driftRef == nil {
+		WriteServiceUnavailable(w, r, "feature drift reference not loaded", CodeDriftUnavailable)
+		return
+	}
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+
+	end := r.URL.Query().Get("end_date")
+	if end == "" {
+		end = h.featureStore.GetMetadata().DataDateMax
+	}
+	if err := ValidateDate(end); err != nil {
+		WriteBadRequest(w, r, "end_date: "+err.Message, err.Code)
+		return
+	}
+	days, err := queryInt(r, "window_days", h.driftCfg.WindowDays)
+	if err != nil || days < 1 || days > MaxDriftWindowDays {
+		WriteBadRequest(w, r, fmt.Sprintf("window_days must be between 1 and %d", MaxDriftWindowDays), CodeInvalidRequest)
+		return
+	}
+
+	endDate, _ := time.Parse(DateFormat, end)
+	start := endDate.AddDate(0, 0, -(days - 1)).Format(DateFormat)
+	rows := h.featureStore.Window(start, end)
+	if len(rows) == 0 {
+		WriteError(w, r, http.StatusNotFound, "no feature rows in the drift window", CodeFeatureNotFound)
+		return
+	}
+
+	resp := FeatureDriftResponse{
+		WindowStart:  start,
+		WindowEnd:    end,
+		Rows:         len(rows),
+		Reference:    h.driftRef.Source,
+		PSIThreshold: h.driftCfg.PSIThreshold,
+		KSThreshold:  h.driftCfg.KSThreshold,
+		Features:     drift.Compare(h.driftRef, inference.FeatureNames(), rows, h.driftCfg.PSIThreshold, h.driftCfg.KSThreshold),
+	}
+	var drifted []string
+	for _, f := range resp.Features {
+		metrics.SetFeatureDrift(f.Name, f.PSI)
+		if f.Drifted {
+			drifted = append(drifted, f.Name)
+		}
+	}
+	resp.Drifted = len(drifted)
+
+	if len(drifted) > 0 {
+		log.Warn().
+			Strs("features", drifted).
+			Str("window_start", start).
+			Str("window_end", end).
+			Msg("Feature drift detected")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
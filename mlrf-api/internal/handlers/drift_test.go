@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/drift"
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func TestFeatureDrift(t *testing.T) {
+	aug14 := time.Date(2017, 8, 14, 0, 0, 0, 0, time.UTC)
+	aug15 := aug14.AddDate(0, 0, 1)
+	row := func(store int32, date time.Time, oil, lag float64) features.FeatureRow {
+		return features.FeatureRow{StoreNbr: store, Family: "DAIRY", Date: date, OilPrice: oil, SalesLag1: lag}
+	}
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		row(1, aug14, 45, 100), row(2, aug14, 45, 100),
+		row(1, aug15, 46, 500), row(2, aug15, 46, 600),
+	})
+	ref, err := drift.ParseReference([]byte(`{
+		"source": "train",
+		"features": [
+			{"name": "oil_price", "edges": [40, 50], "proportions": [0, 1, 0], "mean": 45},
+			{"name": "sales_lag_1", "edges": [200], "proportions": [1, 0], "mean": 120}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, store, nil)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.FeatureDrift(w, httptest.NewRequest(http.MethodGet, "/monitoring/feature-drift"+query, nil))
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a reference, got %d", w.Code)
+	}
+
+	h.SetFeatureDrift(ref, drift.Config{WindowDays: 28, PSIThreshold: 0.2, KSThreshold: 0.1})
+
+	w := get("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp FeatureDriftResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.WindowEnd != "2017-08-15" || resp.WindowStart != "2017-07-19" || resp.Rows != 4 {
+		t.Errorf("unexpected window %+v", resp)
+	}
+	if len(resp.Features) != 2 || resp.Features[0].Name != "oil_price" || resp.Features[1].Name != "sales_lag_1" {
+		t.Fatalf("expected referenced features in model order, got %+v", resp.Features)
+	}
+	if resp.Features[0].Drifted || !resp.Features[1].Drifted || resp.Drifted != 1 {
+		t.Errorf("expected only sales_lag_1 to drift, got %+v", resp.Features)
+	}
+
+	// A one-day window ending on the 14th sees only the reference-like rows
+	w = get("?end_date=2017-08-14&window_days=1")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Rows != 2 || resp.Drifted != 0 {
+		t.Errorf("expected no drift on 2017-08-14, got %+v", resp)
+	}
+
+	if w := get("?window_days=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for window_days=0, got %d", w.Code)
+	}
+	if w := get("?end_date=2016-01-01&window_days=1"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an empty window, got %d", w.Code)
+	}
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/middleware"
 )
 
 // ErrorResponse represents a standardized API error response.
@@ -11,55 +14,109 @@ type ErrorResponse struct {
 	Error     string `json:"error"`
 	Code      string `json:"code"`
 	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"` // When the request is traced
 }
 
 // ContextKey is a custom type for context keys to avoid collisions.
-type ContextKey string
+type ContextKey = middleware.ContextKey
 
-// RequestIDKey is the context key for request ID.
-const RequestIDKey ContextKey = "request_id"
+// RequestIDKey is the context key for request ID, set by middleware.RequestID.
+const RequestIDKey = middleware.RequestIDKey
 
 // Error codes used throughout the API.
 const (
 	// Authentication & Authorization
-	CodeAuthRequired = "AUTH_REQUIRED"
+	CodeAuthRequired      = "AUTH_REQUIRED"
+	CodeInsufficientScope = "INSUFFICIENT_SCOPE"
 
 	// Rate Limiting
-	CodeRateLimited = "RATE_LIMITED"
+	CodeRateLimited   = "RATE_LIMITED"
+	CodeQuotaExceeded = "QUOTA_EXCEEDED"
 
 	// Validation Errors
-	CodeInvalidRequest  = "INVALID_REQUEST"
-	CodeInvalidDate     = "INVALID_DATE"
-	CodeInvalidFamily   = "INVALID_FAMILY"
-	CodeInvalidStore    = "INVALID_STORE"
-	CodeInvalidFeatures = "INVALID_FEATURES"
-	CodeInvalidHorizon  = "INVALID_HORIZON"
-	CodeBatchTooLarge   = "BATCH_TOO_LARGE"
+	CodeInvalidRequest    = "INVALID_REQUEST"
+	CodeInvalidDate       = "INVALID_DATE"
+	CodeInvalidFamily     = "INVALID_FAMILY"
+	CodeInvalidStore      = "INVALID_STORE"
+	CodeInvalidFeatures   = "INVALID_FEATURES"
+	CodeInvalidHorizon    = "INVALID_HORIZON"
+	CodeBatchTooLarge     = "BATCH_TOO_LARGE"
+	CodeInvalidModel      = "INVALID_MODEL"
+	CodeInvalidEngine     = "INVALID_ENGINE"
+	CodeInvalidAdjustment = "INVALID_ADJUSTMENT"
+	CodeInvalidRegressor  = "INVALID_REGRESSOR"
+	CodeUnknownField      = "UNKNOWN_FIELD"
+	CodePayloadTooLarge   = "PAYLOAD_TOO_LARGE"
 
 	// Server Errors
-	CodeModelUnavailable = "MODEL_UNAVAILABLE"
-	CodeInferenceFailed  = "INFERENCE_FAILED"
-	CodeInternalError    = "INTERNAL_ERROR"
-	CodeParseError       = "PARSE_ERROR"
+	CodeModelUnavailable   = "MODEL_UNAVAILABLE"
+	CodeInferenceFailed    = "INFERENCE_FAILED"
+	CodeInvalidPrediction  = "INVALID_PREDICTION"
+	CodeInternalError      = "INTERNAL_ERROR"
+	CodeParseError         = "PARSE_ERROR"
+	CodeConfigUnavailable  = "CONFIG_UNAVAILABLE"
+	CodeMockFallbackDenied = "MOCK_FALLBACK_DENIED"
+	CodeOverloaded         = "OVERLOADED"
+	CodeDeadlineExceeded   = "DEADLINE_EXCEEDED"
 
 	// SHAP Service Errors
 	CodeShapUnavailable = "SHAP_UNAVAILABLE"
 	CodeShapError       = "SHAP_ERROR"
+	CodeShapCircuitOpen = "SHAP_CIRCUIT_OPEN"
 
 	// Feature Store Errors
 	CodeFeatureStoreUnavailable = "FEATURE_STORE_UNAVAILABLE"
 	CodeFeatureNotFound         = "FEATURE_NOT_FOUND"
 	CodeFeatureStoreStale       = "FEATURE_STORE_STALE"
 	CodeReloadFailed            = "RELOAD_FAILED"
+	CodeFeatureQualityFailed    = "FEATURE_QUALITY_FAILED"
+	CodeFeatureSchemaMismatch   = "FEATURE_SCHEMA_MISMATCH"
 
 	// Hierarchy Errors
-	CodeHierarchyUnavailable = "HIERARCHY_UNAVAILABLE"
+	CodeHierarchyUnavailable      = "HIERARCHY_UNAVAILABLE"
+	CodeReconciliationUnavailable = "RECONCILIATION_UNAVAILABLE"
+	CodeHierarchyNodeNotFound     = "HIERARCHY_NODE_NOT_FOUND"
+
+	// Job Errors
+	CodeJobsUnavailable = "JOBS_UNAVAILABLE"
+	CodeJobQueueFull    = "JOB_QUEUE_FULL"
+	CodeJobNotFound     = "JOB_NOT_FOUND"
+	CodeJobNotReady     = "JOB_NOT_READY"
+	CodeDraining        = "DRAINING"
+
+	// Live Update Errors
+	CodeLiveUnavailable = "LIVE_UNAVAILABLE"
+
+	// Actuals Errors
+	CodeActualsUnavailable = "ACTUALS_UNAVAILABLE"
+	CodeAlertsUnavailable  = "ALERTS_UNAVAILABLE"
+
+	// Monitoring Errors
+	CodeDriftUnavailable         = "DRIFT_UNAVAILABLE"
+	CodeSLOUnavailable           = "SLO_UNAVAILABLE"
+	CodePredictionLogUnavailable = "PREDICTION_LOG_UNAVAILABLE"
+	CodePredictionNotFound       = "PREDICTION_NOT_FOUND"
+	CodeIntervalsUnavailable     = "INTERVALS_UNAVAILABLE"
+	CodeAuditUnavailable         = "AUDIT_UNAVAILABLE"
+
+	// Model Registry Errors
+	CodeRegistryUnavailable = "REGISTRY_UNAVAILABLE"
+	CodeShadowUnavailable   = "SHADOW_UNAVAILABLE"
+
+	// Calendar Errors
+	CodeCalendarUnavailable = "CALENDAR_UNAVAILABLE"
 )
 
 // WriteError writes a standardized JSON error response.
 // It sets the Content-Type header, writes the status code, and encodes the error.
 // If a request ID is available in the context, it is included in the response.
+// A server error of a request past its deadline (see middleware.Timeout), whose
+// inference, cache or SHAP call failed for it, is written as 504 DEADLINE_EXCEEDED.
 func WriteError(w http.ResponseWriter, r *http.Request, statusCode int, message string, code string) {
+	if statusCode >= http.StatusInternalServerError && r != nil && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		statusCode, code = http.StatusGatewayTimeout, CodeDeadlineExceeded
+		message = "request deadline exceeded: " + message
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
@@ -73,6 +130,7 @@ func WriteError(w http.ResponseWriter, r *http.Request, statusCode int, message
 		if rid := getRequestID(r.Context()); rid != "" {
 			resp.RequestID = rid
 		}
+		resp.TraceID = middleware.TraceID(r)
 	}
 
 	json.NewEncoder(w).Encode(resp)
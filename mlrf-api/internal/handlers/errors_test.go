@@ -6,7 +6,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestWriteError(t *testing.T) {
@@ -58,6 +63,24 @@ func TestWriteErrorWithRequestID(t *testing.T) {
 	}
 }
 
+func TestWriteErrorWithTraceID(t *testing.T) {
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "GET /test")
+	defer span.End()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	WriteError(w, req, http.StatusInternalServerError, "internal error", "INTERNAL_ERROR")
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.TraceID != span.SpanContext().TraceID().String() {
+		t.Errorf("expected trace_id %s, got '%s'", span.SpanContext().TraceID(), resp.TraceID)
+	}
+}
+
 func TestWriteErrorNilRequest(t *testing.T) {
 	w := httptest.NewRecorder()
 
@@ -354,3 +377,111 @@ func TestPredictSimpleModelUnavailableError(t *testing.T) {
 		t.Errorf("expected code '%s', got '%s'", CodeModelUnavailable, resp.Code)
 	}
 }
+
+func TestErrorMessageEscaping(t *testing.T) {
+	// Validation messages echo client input; quotes and backslashes in it must come
+	// back as valid JSON with the message intact and the request ID attached
+	family := `GROCERY "I" \ x`
+	testCases := []struct {
+		name    string
+		handler func(h *Handlers) http.HandlerFunc
+		method  string
+		target  string
+		body    string
+		message string
+	}{
+		{
+			name:    "Predict",
+			handler: func(h *Handlers) http.HandlerFunc { return h.Predict },
+			method:  http.MethodPost,
+			target:  "/predict",
+			body:    `{"store_nbr": 1, "family": "GROCERY \"I\" \\ x", "date": "2017-08-01"}`,
+			message: "invalid family name: " + family,
+		},
+		{
+			name:    "PredictBatch",
+			handler: func(h *Handlers) http.HandlerFunc { return h.PredictBatch },
+			method:  http.MethodPost,
+			target:  "/predict/batch",
+			body:    `{"predictions": [{"store_nbr": 1, "family": "GROCERY \"I\" \\ x", "date": "2017-08-01"}]}`,
+			message: "prediction[0]: invalid family name: " + family,
+		},
+		{
+			name:    "PredictSimple",
+			handler: func(h *Handlers) http.HandlerFunc { return h.PredictSimple },
+			method:  http.MethodPost,
+			target:  "/predict/simple",
+			body:    `{"store_nbr": 1, "family": "GROCERY \"I\" \\ x", "date": "2017-08-01", "horizon": 30}`,
+			message: "invalid family name: " + family,
+		},
+		{
+			name:    "Explain",
+			handler: func(h *Handlers) http.HandlerFunc { return h.Explain },
+			method:  http.MethodPost,
+			target:  "/explain",
+			body:    `{"store_nbr": 1, "fam\"ily\\": "GROCERY I"}`,
+			message: `unknown field "fam\"ily\\"`,
+		},
+		{
+			name:    "Hierarchy",
+			handler: func(h *Handlers) http.HandlerFunc { return h.Hierarchy },
+			method:  http.MethodGet,
+			target:  "/hierarchy?method=" + url.QueryEscape(`"mint\`),
+			message: `unknown reconciliation method "\"mint\\": must be bottom_up, top_down, mint or ols`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandlers(nil, nil, nil, nil)
+			h.SetStrictJSON(true)
+			ctx := context.WithValue(context.Background(), RequestIDKey, "req-7")
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			tc.handler(h)(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("response is not valid JSON: %v: %s", err, w.Body.String())
+			}
+			if resp.Error != tc.message {
+				t.Errorf("expected error %q, got %q", tc.message, resp.Error)
+			}
+			if resp.RequestID != "req-7" {
+				t.Errorf("expected request_id 'req-7', got '%s'", resp.RequestID)
+			}
+		})
+	}
+}
+
+func TestPredictDeadlineExceeded(t *testing.T) {
+	mockOnnx := &MockInferencer{prediction: 1234.56}
+	h := NewHandlers(mockOnnx, nil, nil, nil)
+
+	// The request's deadline (see middleware.Timeout) passed before inference
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`
+	req := httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	h.Predict(w, req)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Code != CodeDeadlineExceeded {
+		t.Errorf("expected code '%s', got '%s'", CodeDeadlineExceeded, resp.Code)
+	}
+	if n := mockOnnx.CallCount(); n != 0 {
+		t.Errorf("expected the model not to run past the deadline, ran %d times", n)
+	}
+}
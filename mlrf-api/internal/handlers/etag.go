@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bootID distinguishes ETags of this process from those of earlier ones, whose revision
+// counters restarted at the same values.
+var bootID = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// bumpRevision invalidates the ETags of read-only responses after data they depend on
+// (features, model, hierarchy definition, intervals or actuals) changed.
+func (h *Handlers) bumpRevision() {
+	h.revision.Add(1)
+}
+
+// etag returns the entity tag of the response to r: a hash of the model version, the
+// feature store load time, the data revision, the request URI and any extra
+// validators of the handler.
+func (h *Handlers) etag(r *http.Request, extra ...string) string {
+	var modelVersion, featuresLoaded string
+	if h.modelLoader != nil {
+		modelVersion = h.modelLoader.Info().Version
+	}
+	if h.featureStore != nil && h.featureStore.IsLoaded() {
+		featuresLoaded = strconv.FormatInt(h.featureStore.GetMetadata().LoadedAt.UnixNano(), 10)
+	}
+
+	sum := sha256.New()
+	for _, part := range append([]string{bootID, strconv.FormatUint(h.revision.Load(), 10), modelVersion, featuresLoaded, r.URL.RequestURI()}, extra...) {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(sum.Sum(nil)[:12]) + `"`
+}
+
+// notModified sets the ETag of a read-only response and, when the request's
+// If-None-Match already lists it, writes 304 Not Modified and returns true so the
+// handler can skip building the body.
+func (h *Handlers) notModified(w http.ResponseWriter, r *http.Request, extra ...string) bool {
+	tag := h.etag(r, extra...)
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", "no-cache") // Cache, but revalidate every time
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnlyETags(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	get := func(handler http.HandlerFunc, target, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	for target, handler := range map[string]http.HandlerFunc{
+		"/model-metrics":   h.ModelMetrics,
+		"/features/schema": h.FeatureSchema,
+		"/accuracy":        h.Accuracy,
+	} {
+		first := get(handler, target, "")
+		tag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || tag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", target, first.Code, tag)
+		}
+
+		if w := get(handler, target, tag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 without a body, got %d (%d bytes)", target, w.Code, w.Body.Len())
+		}
+		if w := get(handler, target, `"other", W/`+tag); w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected a weak match in a list to give 304, got %d", target, w.Code)
+		}
+		if w := get(handler, target+"?x=1", tag); w.Code != http.StatusOK {
+			t.Errorf("%s: expected another query to get its own ETag, got %d", target, w.Code)
+		}
+	}
+
+	// Reloads and new actuals change every ETag
+	tag := get(h.ModelMetrics, "/model-metrics", "").Header().Get("ETag")
+	h.invalidateHierarchy(context.Background())
+	if w := get(h.ModelMetrics, "/model-metrics", tag); w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Errorf("expected a new ETag after a reload, got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+	tag = get(h.ModelMetrics, "/model-metrics", "").Header().Get("ETag")
+	h.invalidateBacktests()
+	if w := get(h.ModelMetrics, "/model-metrics", tag); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after actuals changed, got %d", w.Code)
+	}
+}
@@ -2,18 +2,43 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/reconcile"
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/mlrf/mlrf-api/internal/shapclient"
+	"github.com/mlrf/mlrf-api/internal/treeshap"
 	"github.com/rs/zerolog/log"
 )
 
+// Explanation engines, selected with the engine field of an /explain request.
+const (
+	EngineAuto    = "auto"    // SHAP service, falling back to native TreeSHAP when it is unavailable or fails
+	EngineSidecar = "sidecar" // SHAP service only
+	EngineNative  = "native"  // Native Go TreeSHAP only
+	EngineOffline = "offline" // Waterfalls precomputed by training (SHAP_DATA_PATH), never used as a fallback
+)
+
+// waterfallMaxDisplay is the number of features shown in a waterfall; the SHAP values
+// of the others are summed into one "Other" entry, as the SHAP service does.
+const waterfallMaxDisplay = 10
+
 // ExplainRequest represents a SHAP explanation request.
 type ExplainRequest struct {
 	StoreNbr int    `json:"store_nbr"`
 	Family   string `json:"family"`
 	Date     string `json:"date"`
+	Engine   string `json:"engine,omitempty"` // auto (default), sidecar, native or offline
 }
 
 // WaterfallFeature represents a single feature in the SHAP waterfall.
@@ -30,15 +55,67 @@ type ExplainResponse struct {
 	BaseValue  float64            `json:"base_value"`
 	Features   []WaterfallFeature `json:"features"`
 	Prediction float64            `json:"prediction"`
+	Engine     string             `json:"engine"` // Engine that computed the values: sidecar, native or offline
+	Cached     bool               `json:"cached"`
+	IsMock     bool               `json:"is_mock,omitempty"` // Explains zero features of an unknown series
+}
+
+// LoadTreeSHAP loads the LightGBM model dump used for native TreeSHAP explanations.
+// This is optional - without it, /explain depends on the SHAP service.
+func (h *Handlers) LoadTreeSHAP(path string) error {
+	m, err := treeshap.Load(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load model dump, native TreeSHAP disabled")
+		return err
+	}
+	if m.NumFeatures() != schema.NumFeatures {
+		err := fmt.Errorf("model dump has %d features, expected %d", m.NumFeatures(), schema.NumFeatures)
+		log.Warn().Err(err).Str("path", path).Msg("Could not load model dump, native TreeSHAP disabled")
+		return err
+	}
+	h.treeSHAP = m
+	log.Info().Str("path", path).Float64("base_value", m.ExpectedValue()).Msg("Loaded model dump for native TreeSHAP")
+	return nil
+}
+
+// LoadOfflineExplanations loads the waterfalls precomputed by training, keyed by
+// "<store_nbr>_<family>", for the offline engine.
+// This is optional - /explain only serves them when a request selects the offline engine.
+func (h *Handlers) LoadOfflineExplanations(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load precomputed SHAP waterfalls")
+		return err
+	}
+	var waterfalls map[string]ExplainResponse
+	if err := json.Unmarshal(data, &waterfalls); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not parse precomputed SHAP waterfalls")
+		return err
+	}
+	for key, resp := range waterfalls {
+		resp.Engine = EngineOffline
+		waterfalls[key] = resp
+	}
+	h.offlineExplanations = waterfalls
+	log.Info().Str("path", path).Int("series", len(waterfalls)).Msg("Loaded precomputed SHAP waterfalls")
+	return nil
+}
+
+// offlineKey is the key of a series in the precomputed waterfalls.
+func offlineKey(storeNbr int, family string) string {
+	return fmt.Sprintf("%d_%s", storeNbr, family)
 }
 
 // Explain returns REAL SHAP waterfall data computed on-demand.
-// This calls the Python SHAP sidecar for actual SHAP computation.
-// No mocks, no pre-computed fallbacks - if SHAP service is unavailable, returns error.
+// This calls the Python SHAP sidecar for actual SHAP computation, or computes TreeSHAP
+// natively from the LightGBM model dump when the sidecar is unavailable or the request
+// selects the native engine. No mocks, no pre-computed fallbacks - if neither engine
+// is available, returns error. Waterfalls precomputed by training are only served when
+// the request explicitly selects the offline engine, e.g. for demos without the models.
 func (h *Handlers) Explain(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	var req ExplainRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -51,6 +128,20 @@ func (h *Handlers) Explain(w http.ResponseWriter, r *http.Request) {
 		WriteBadRequest(w, r, "family is required", CodeInvalidFamily)
 		return
 	}
+	switch req.Engine {
+	case "":
+		req.Engine = EngineAuto
+	case EngineAuto, EngineSidecar, EngineNative, EngineOffline:
+	default:
+		WriteBadRequest(w, r, "engine must be auto, sidecar, native or offline", CodeInvalidEngine)
+		return
+	}
+
+	// Offline waterfalls are looked up by series and need neither features nor a model
+	if req.Engine == EngineOffline {
+		h.explainOffline(w, r, req)
+		return
+	}
 
 	// Check if feature store is available
 	if h.featureStore == nil || !h.featureStore.IsLoaded() {
@@ -66,24 +157,89 @@ func (h *Handlers) Explain(w http.ResponseWriter, r *http.Request) {
 			Str("family", req.Family).
 			Str("date", req.Date).
 			Msg("Features not found, using aggregated/zero features")
+		if !h.allowMock(w, r, "explain") {
+			return
+		}
+	}
+
+	// SHAP values are deterministic for a model and feature vector, so serve them from
+	// the cache when they were computed before
+	ctx := r.Context()
+	var cacheKey string
+	if h.cache != nil {
+		var modelVersion string
+		if h.modelLoader != nil {
+			modelVersion = h.modelLoader.Info().Version
+		}
+		cacheKey = cache.GenerateExplainCacheKey(modelVersion, features)
+
+		var cached ExplainResponse
+		if err := h.cache.GetExplanation(ctx, cacheKey, &cached); err == nil && (req.Engine == EngineAuto || cached.Engine == req.Engine) {
+			cached.Cached = true
+			cached.IsMock = !found
+			metrics.RecordExplainRequest("hit", time.Since(start).Seconds())
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(cached)
+			return
+		}
 	}
 
-	// Check if SHAP client is available
-	if h.shapClient == nil {
+	var resp ExplainResponse
+	switch {
+	case req.Engine == EngineNative || req.Engine == EngineAuto && h.shapClient == nil:
+		if h.treeSHAP == nil {
+			WriteServiceUnavailable(w, r, "SHAP service not available", CodeShapUnavailable)
+			return
+		}
+		var err error
+		if resp, err = h.explainNative(features); err != nil {
+			log.Error().Err(err).Int("store", req.StoreNbr).Str("family", req.Family).Msg("Native TreeSHAP failed")
+			WriteInternalError(w, r, "SHAP computation failed: "+err.Error(), CodeShapError)
+			return
+		}
+
+	case h.shapClient == nil:
 		WriteServiceUnavailable(w, r, "SHAP service not available", CodeShapUnavailable)
 		return
+
+	default:
+		var err error
+		resp, err = h.explainSidecar(r, req, features)
+		if err != nil && req.Engine == EngineAuto && h.treeSHAP != nil {
+			log.Warn().Err(err).Int("store", req.StoreNbr).Str("family", req.Family).Msg("SHAP service failed, using native TreeSHAP")
+			resp, err = h.explainNative(features)
+		}
+		if err != nil {
+			writeShapError(w, r, req, err)
+			return
+		}
 	}
 
-	// Call SHAP sidecar for real-time computation
-	ctx := r.Context()
-	shapResp, err := h.shapClient.Explain(ctx, req.StoreNbr, req.Family, req.Date, features)
+	resp.IsMock = !found
+	if h.cache != nil {
+		if err := h.cache.SetExplanation(ctx, cacheKey, resp); err != nil {
+			log.Warn().Err(err).Msg("failed to cache SHAP explanation")
+		}
+	}
+	metrics.RecordExplainRequest("miss", time.Since(start).Seconds())
+
+	log.Debug().
+		Int("store", req.StoreNbr).
+		Str("family", req.Family).
+		Str("engine", resp.Engine).
+		Float64("prediction", resp.Prediction).
+		Int("features", len(resp.Features)).
+		Msg("SHAP explanation computed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// explainSidecar computes an explanation with the SHAP service.
+func (h *Handlers) explainSidecar(r *http.Request, req ExplainRequest, features []float32) (ExplainResponse, error) {
+	shapResp, err := h.shapClient.Explain(r.Context(), req.StoreNbr, req.Family, req.Date, features)
 	if err != nil {
-		log.Error().Err(err).
-			Int("store", req.StoreNbr).
-			Str("family", req.Family).
-			Msg("SHAP computation failed")
-		WriteInternalError(w, r, "SHAP computation failed: "+err.Error(), CodeShapError)
-		return
+		return ExplainResponse{}, err
 	}
 
 	// Convert client response to handler response
@@ -91,6 +247,7 @@ func (h *Handlers) Explain(w http.ResponseWriter, r *http.Request) {
 		BaseValue:  shapResp.BaseValue,
 		Prediction: shapResp.Prediction,
 		Features:   make([]WaterfallFeature, len(shapResp.Features)),
+		Engine:     EngineSidecar,
 	}
 	for i, f := range shapResp.Features {
 		resp.Features[i] = WaterfallFeature{
@@ -101,18 +258,100 @@ func (h *Handlers) Explain(w http.ResponseWriter, r *http.Request) {
 			Direction:  f.Direction,
 		}
 	}
+	return resp, nil
+}
 
-	log.Debug().
-		Int("store", req.StoreNbr).
-		Str("family", req.Family).
-		Float64("prediction", resp.Prediction).
-		Int("features", len(resp.Features)).
-		Msg("SHAP explanation computed")
+// explainNative computes an explanation with native TreeSHAP, building the same
+// waterfall as the SHAP service: the features with the largest absolute SHAP values,
+// then the rest summed into "Other" when that sum is significant.
+func (h *Handlers) explainNative(features []float32) (ExplainResponse, error) {
+	values, err := h.treeSHAP.SHAP(features)
+	if err != nil {
+		return ExplainResponse{}, err
+	}
+
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return math.Abs(values[order[a]]) > math.Abs(values[order[b]]) })
+
+	resp := ExplainResponse{BaseValue: h.treeSHAP.ExpectedValue(), Engine: EngineNative}
+	direction := func(v float64) string {
+		if v > 0 {
+			return "positive"
+		}
+		return "negative"
+	}
+
+	cumulative := resp.BaseValue
+	top := order
+	if len(top) > waterfallMaxDisplay {
+		top = order[:waterfallMaxDisplay]
+	}
+	for _, i := range top {
+		cumulative += values[i]
+		resp.Features = append(resp.Features, WaterfallFeature{
+			Name:       schema.Features.Column(i).Name,
+			Value:      float64(features[i]),
+			ShapValue:  values[i],
+			Cumulative: cumulative,
+			Direction:  direction(values[i]),
+		})
+	}
+
+	if rest := order[len(top):]; len(rest) > 0 {
+		var other float64
+		for _, i := range rest {
+			other += values[i]
+		}
+		if math.Abs(other) > 0.01 {
+			cumulative += other
+			resp.Features = append(resp.Features, WaterfallFeature{
+				Name:       fmt.Sprintf("Other (%d features)", len(rest)),
+				ShapValue:  other,
+				Cumulative: cumulative,
+				Direction:  direction(other),
+			})
+		}
+	}
 
+	resp.Prediction = cumulative
+	return resp, nil
+}
+
+// explainOffline serves the waterfall precomputed by training for the request's series.
+// The waterfall is the same for every date.
+func (h *Handlers) explainOffline(w http.ResponseWriter, r *http.Request, req ExplainRequest) {
+	if h.offlineExplanations == nil {
+		WriteServiceUnavailable(w, r, "precomputed SHAP waterfalls not loaded", CodeShapUnavailable)
+		return
+	}
+	resp, ok := h.offlineExplanations[offlineKey(req.StoreNbr, req.Family)]
+	if !ok {
+		WriteError(w, r, http.StatusNotFound, fmt.Sprintf("no precomputed waterfall for store %d, family %s", req.StoreNbr, req.Family), CodeFeatureNotFound)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// writeShapError writes the error of a failed SHAP service call.
+func writeShapError(w http.ResponseWriter, r *http.Request, req ExplainRequest, err error) {
+	var openErr *shapclient.CircuitOpenError
+	if errors.As(err, &openErr) {
+		// Fail fast instead of waiting out the timeout of a service known to be down
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(openErr.RetryAfter.Seconds()))))
+		WriteServiceUnavailable(w, r, "SHAP service unavailable: circuit breaker open", CodeShapCircuitOpen)
+		return
+	}
+	log.Error().Err(err).
+		Int("store", req.StoreNbr).
+		Str("family", req.Family).
+		Msg("SHAP computation failed")
+	WriteInternalError(w, r, "SHAP computation failed: "+err.Error(), CodeShapError)
+}
+
 // HierarchyNode represents a node in the forecast hierarchy.
 type HierarchyNode struct {
 	ID                 string          `json:"id"`
@@ -123,42 +362,128 @@ type HierarchyNode struct {
 	PreviousPrediction *float64        `json:"previous_prediction,omitempty"`
 	TrendPercent       *float64        `json:"trend_percent,omitempty"`
 	Children           []HierarchyNode `json:"children,omitempty"`
+	IsMock             bool            `json:"is_mock,omitempty"` // PreviousPrediction and TrendPercent are fabricated
 }
 
 // Hierarchy returns the full hierarchy tree with predictions.
-// Requires pre-computed hierarchy data - returns error if unavailable.
+// The tree follows the hierarchy definition when one is loaded (see loadHierarchy),
+// otherwise it requires pre-computed hierarchy data - returns error if unavailable.
+// With ?method=bottom_up|top_down|mint|ols the tree is reconciled on demand
+// (see reconcileHierarchy); ?horizon= sets the model horizon (default 30).
+// Supports If-None-Match (see notModified).
 func (h *Handlers) Hierarchy(w http.ResponseWriter, r *http.Request) {
+	if h.notModified(w, r) {
+		return
+	}
+	hierarchy, ok := h.requestHierarchy(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hierarchy)
+}
+
+// requestHierarchy builds the tree for the request's date, method and horizon query
+// parameters, serving it from the hierarchy cache when possible. On failure it writes
+// the error response and returns false.
+func (h *Handlers) requestHierarchy(w http.ResponseWriter, r *http.Request) (*HierarchyNode, bool) {
 	date := r.URL.Query().Get("date")
 	if date == "" {
 		date = "2017-08-01"
 	}
 
-	// Load hierarchy data from file (must exist, no mocks)
-	hierarchyFile := os.Getenv("HIERARCHY_DATA_PATH")
-	if hierarchyFile == "" {
-		hierarchyFile = "models/hierarchy_data.json"
+	var method reconcile.Method
+	horizon := 30
+	if name := r.URL.Query().Get("method"); name != "" {
+		m, err := reconcile.ParseMethod(name)
+		if err != nil {
+			WriteBadRequest(w, r, err.Error(), CodeInvalidRequest)
+			return nil, false
+		}
+		if m == reconcile.MethodMinT && h.covariance == nil {
+			WriteServiceUnavailable(w, r, "mint reconciliation requires a covariance matrix", CodeReconciliationUnavailable)
+			return nil, false
+		}
+		if verr := ValidateDate(date); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return nil, false
+		}
+		if hz := r.URL.Query().Get("horizon"); hz != "" {
+			horizon, _ = strconv.Atoi(hz)
+			if verr := ValidateHorizon(horizon); verr != nil {
+				WriteBadRequest(w, r, verr.Message, verr.Code)
+				return nil, false
+			}
+		}
+		method = m
+	}
+
+	// Only well-formed dates are cached, so arbitrary query strings can't fill the cache
+	start := time.Now()
+	cacheKey := cache.HierarchyCacheKey(date, string(method), horizon)
+	cacheable := h.hierarchyCache != nil && ValidateDate(date) == nil
+	if cacheable {
+		if data, ok := h.hierarchyCache.Get(r.Context(), cacheKey); ok {
+			var hierarchy HierarchyNode
+			if err := json.Unmarshal(data, &hierarchy); err == nil {
+				if method != "" {
+					w.Header().Set(ReconciliationMethodHeader, string(method))
+				}
+				if hierarchy.IsMock {
+					metrics.RecordMockResponse("hierarchy")
+				}
+				metrics.RecordHierarchyRequest("hit", time.Since(start).Seconds())
+				return &hierarchy, true
+			}
+		}
 	}
 
-	data, err := os.ReadFile(hierarchyFile)
-	if err != nil {
-		log.Error().Err(err).Str("file", hierarchyFile).Msg("Hierarchy data file not found")
+	// Leaf forecasts are refreshed from the model when reconciling
+	hierarchy, err := h.loadHierarchy(r.Context(), date, horizon, method != "")
+	switch {
+	case errors.Is(err, errHierarchyUnavailable):
+		log.Error().Err(err).Msg("Hierarchy data not available")
 		WriteServiceUnavailable(w, r, "hierarchy data not available", CodeHierarchyUnavailable)
-		return
+		return nil, false
+	case errors.Is(err, errHierarchyParse):
+		WriteInternalError(w, r, "failed to parse hierarchy data", CodeParseError)
+		return nil, false
+	case errors.Is(err, inference.ErrInvalidPrediction):
+		writeInvalidPrediction(w, r, err)
+		return nil, false
+	case err != nil:
+		log.Error().Err(err).Msg("Hierarchy leaf inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return nil, false
 	}
 
-	var hierarchy HierarchyNode
-	if err := json.Unmarshal(data, &hierarchy); err != nil {
-		WriteInternalError(w, r, "failed to parse hierarchy data", CodeParseError)
-		return
+	if method != "" {
+		if err := h.reconcileHierarchy(hierarchy, method); err != nil {
+			log.Error().Err(err).Str("method", string(method)).Msg("Hierarchy reconciliation failed")
+			WriteInternalError(w, r, "reconciliation failed: "+err.Error(), CodeInternalError)
+			return nil, false
+		}
+		w.Header().Set(ReconciliationMethodHeader, string(method))
 	}
 
-	// Add trend data if not already present in loaded data
-	if hierarchy.TrendPercent == nil {
-		addTrendToNode(&hierarchy, 0.12)
+	// Add trend data if not already present in loaded data. The trends are made up, so
+	// MOCK_FALLBACKS=deny leaves them out rather than failing the whole tree
+	if hierarchy.TrendPercent == nil && !h.denyMocks {
+		addTrendToNode(hierarchy, 0.12)
+		metrics.RecordMockResponse("hierarchy")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(hierarchy)
+	if cacheable {
+		if data, err := json.Marshal(hierarchy); err == nil {
+			if err := h.hierarchyCache.Set(r.Context(), cacheKey, data); err != nil {
+				log.Warn().Err(err).Msg("failed to cache hierarchy")
+			}
+		}
+	}
+	metrics.RecordHierarchyRequest("miss", time.Since(start).Seconds())
+
+	return hierarchy, true
 }
 
 // calculateTrend computes the trend percentage between current and previous values.
@@ -170,7 +495,8 @@ func calculateTrend(current, previous float64) float64 {
 	return ((current - previous) / previous) * 100
 }
 
-// addTrendToNode adds previous prediction and trend percentage to a node.
+// addTrendToNode adds previous prediction and trend percentage to a node, flagging it
+// as mock data.
 // It uses a deterministic variation based on the node's ID to generate "previous" values.
 func addTrendToNode(node *HierarchyNode, variationFactor float64) {
 	// Generate a previous prediction with some variation
@@ -179,6 +505,7 @@ func addTrendToNode(node *HierarchyNode, variationFactor float64) {
 	trend := calculateTrend(node.Prediction, previous)
 	node.PreviousPrediction = &previous
 	node.TrendPercent = &trend
+	node.IsMock = true
 
 	// Recursively add trends to children
 	for i := range node.Children {
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/shapclient"
+)
+
+// testModelDump is a LightGBM model dump over the 27 model features with one tree
+// splitting on sales_lag_1 (feature 12) and one on oil_price (feature 7).
+const testModelDump = `{
+  "max_feature_idx": 26,
+  "tree_info": [
+    {"tree_index": 0, "tree_structure": {
+      "split_feature": 12, "threshold": 50, "decision_type": "<=", "default_left": true, "missing_type": "None", "internal_count": 100,
+      "left_child": {"leaf_value": 10, "leaf_count": 40},
+      "right_child": {"leaf_value": 200, "leaf_count": 60}
+    }},
+    {"tree_index": 1, "tree_structure": {
+      "split_feature": 7, "threshold": 60, "decision_type": "<=", "default_left": true, "missing_type": "None", "internal_count": 100,
+      "left_child": {"leaf_value": -5, "leaf_count": 50},
+      "right_child": {"leaf_value": 5, "leaf_count": 50}
+    }}
+  ]
+}`
+
+// newExplainHandlers returns handlers with one feature row (sales_lag_1 = 100,
+// oil_price = 40), native TreeSHAP loaded and the given SHAP client.
+func newExplainHandlers(t *testing.T, sc *shapclient.Client) *Handlers {
+	t.Helper()
+	date := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "GROCERY I", Date: date, SalesLag1: 100, OilPrice: 40},
+	})
+	h := NewHandlers(nil, nil, store, sc)
+
+	path := filepath.Join(t.TempDir(), "lightgbm_model.json")
+	if err := os.WriteFile(path, []byte(testModelDump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadTreeSHAP(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return h
+}
+
+func postExplain(h *Handlers, body string) (*httptest.ResponseRecorder, ExplainResponse) {
+	w := httptest.NewRecorder()
+	h.Explain(w, httptest.NewRequest(http.MethodPost, "/explain", bytes.NewBufferString(body)))
+	var resp ExplainResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestExplainNative(t *testing.T) {
+	h := newExplainHandlers(t, nil)
+
+	for _, engine := range []string{"", EngineNative} {
+		w, resp := postExplain(h, `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","engine":"`+engine+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("engine %q: expected 200, got %d: %s", engine, w.Code, w.Body.String())
+		}
+		if resp.Engine != EngineNative {
+			t.Errorf("engine %q: expected the native engine, got %q", engine, resp.Engine)
+		}
+
+		// Base value: 0.4*10 + 0.6*200 + 0 = 124; prediction: 200 - 5 = 195
+		if math.Abs(resp.BaseValue-124) > 1e-9 || math.Abs(resp.Prediction-195) > 1e-9 {
+			t.Errorf("expected base 124 and prediction 195, got %v and %v", resp.BaseValue, resp.Prediction)
+		}
+		if len(resp.Features) < 2 {
+			t.Fatalf("expected a waterfall, got %+v", resp.Features)
+		}
+		first, second := resp.Features[0], resp.Features[1]
+		if first.Name != "sales_lag_1" || first.Value != 100 || math.Abs(first.ShapValue-76) > 1e-9 || first.Direction != "positive" {
+			t.Errorf("unexpected first feature %+v", first)
+		}
+		if second.Name != "oil_price" || math.Abs(second.ShapValue+5) > 1e-9 || math.Abs(second.Cumulative-195) > 1e-9 || second.Direction != "negative" {
+			t.Errorf("unexpected second feature %+v", second)
+		}
+	}
+}
+
+func TestExplainEngines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			json.NewEncoder(w).Encode(shapclient.HealthResponse{Healthy: true})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"model crashed"}`))
+	}))
+	defer server.Close()
+
+	sc, err := shapclient.NewClient(server.Listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newExplainHandlers(t, sc)
+
+	// auto falls back to native TreeSHAP when the SHAP service fails
+	w, resp := postExplain(h, `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01"}`)
+	if w.Code != http.StatusOK || resp.Engine != EngineNative {
+		t.Errorf("expected a native fallback, got %d %q", w.Code, resp.Engine)
+	}
+
+	// sidecar reports the failure
+	w, _ = postExplain(h, `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","engine":"sidecar"}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 from the sidecar engine, got %d", w.Code)
+	}
+
+	w, _ = postExplain(h, `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","engine":"magic"}`)
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusBadRequest || errResp.Code != CodeInvalidEngine {
+		t.Errorf("expected 400 INVALID_ENGINE, got %d %s", w.Code, errResp.Code)
+	}
+}
+
+func TestLoadTreeSHAPFeatureMismatch(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	path := filepath.Join(t.TempDir(), "lightgbm_model.json")
+	dump := `{"max_feature_idx": 3, "tree_info": [{"tree_structure": {"leaf_value": 1}}]}`
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadTreeSHAP(path); err == nil {
+		t.Error("expected an error for a model dump with 4 features")
+	}
+	if h.treeSHAP != nil {
+		t.Error("expected native TreeSHAP to stay disabled")
+	}
+}
+
+func TestExplainOffline(t *testing.T) {
+	// The offline engine needs neither features nor a model
+	h := NewHandlers(nil, nil, nil, nil)
+
+	w, _ := postExplain(h, `{"store_nbr":1,"family":"GROCERY I","engine":"offline"}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without precomputed waterfalls, got %d", w.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), "shap_waterfall.json")
+	data := `{"1_GROCERY I": {"base_value": 100, "prediction": 130, "features": [
+	  {"name": "sales_lag_1", "value": 12, "shap_value": 30, "cumulative": 130, "direction": "positive"}
+	]}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadOfflineExplanations(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w, resp := postExplain(h, `{"store_nbr":1,"family":"GROCERY I","engine":"offline"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Engine != EngineOffline || resp.Prediction != 130 || len(resp.Features) != 1 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	w, _ = postExplain(h, `{"store_nbr":2,"family":"GROCERY I","engine":"offline"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a series without a waterfall, got %d", w.Code)
+	}
+
+	// Other engines never fall back to the precomputed waterfalls
+	w, _ = postExplain(h, `{"store_nbr":1,"family":"GROCERY I"}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a feature store, got %d", w.Code)
+	}
+}
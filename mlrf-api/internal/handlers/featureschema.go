@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// FeatureSchemaResponse lists the model's input features in order.
+type FeatureSchemaResponse struct {
+	NumFeatures   int               `json:"num_features"`
+	Features      []schema.Column   `json:"features"`       // In feature vector order
+	WhatIfAliases map[string]string `json:"whatif_aliases"` // Extra /whatif adjustment names and the column each adjusts
+}
+
+// FeatureSchema returns the canonical feature order, so clients building feature
+// vectors for /predict or adjustments for /whatif index the same columns as the model.
+// Supports If-None-Match (see notModified).
+func (h *Handlers) FeatureSchema(w http.ResponseWriter, r *http.Request) {
+	if h.notModified(w, r) {
+		return
+	}
+	resp := FeatureSchemaResponse{
+		NumFeatures:   schema.Features.Len(),
+		Features:      schema.Features.Columns(),
+		WhatIfAliases: whatIfAliases,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// ForecastRequest represents a multi-horizon daily forecast request.
+type ForecastRequest struct {
+	StoreNbr  int    `json:"store_nbr"`
+	Family    string `json:"family"`
+	StartDate string `json:"start_date"`
+	Horizon   int    `json:"horizon"` // Number of days to forecast, one of ValidHorizons
+	// Future values of oil_price or onpromotion by date, e.g. {"oil_price": {"2017-08-16": 48.5}},
+	// taking precedence over REGRESSOR_OVERRIDES_PATH and the last known row
+	Regressors map[string]map[string]float32 `json:"regressors,omitempty"`
+}
+
+// ForecastPoint is a single day in a forecast series.
+type ForecastPoint struct {
+	Date       string  `json:"date"`
+	Prediction float32 `json:"prediction"`
+	Lower80    float32 `json:"lower_80,omitempty"`
+	Upper80    float32 `json:"upper_80,omitempty"`
+	Lower95    float32 `json:"lower_95,omitempty"`
+	Upper95    float32 `json:"upper_95,omitempty"`
+}
+
+// ForecastResponse contains a daily forecast series with confidence bands.
+type ForecastResponse struct {
+	StoreNbr    int             `json:"store_nbr"`
+	Family      string          `json:"family"`
+	StartDate   string          `json:"start_date"`
+	Horizon     int             `json:"horizon"`
+	Forecast    []ForecastPoint `json:"forecast"`
+	Total       float32         `json:"total"`
+	IntervalSet string          `json:"interval_set,omitempty"` // Intervals used for the last day's bands, e.g. "store_family@30d" or "quantile"
+	LatencyMs   float64         `json:"latency_ms"`
+}
+
+// Forecast handles multi-horizon forecast requests.
+// Features come from the feature store where available and are rolled forward
+// day by day by features.FeatureBuilder, feeding prior predictions back in as lags.
+func (h *Handlers) Forecast(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req ForecastRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Validate request
+	if err := h.validateStoreNbr(req.StoreNbr); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateFamily(req.Family); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateDate(req.StartDate); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateHorizon(req.Horizon); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+
+	regressors, verr := forecastRegressors(req.StoreNbr, req.Family, req.Regressors)
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	startDate, _ := time.Parse(DateFormat, req.StartDate)
+
+	var store *features.Store
+	if h.featureStore != nil && h.featureStore.IsLoaded() {
+		store = h.featureStore
+	} else {
+		log.Debug().Msg("Feature store unavailable for forecast, rolling forward from zero features")
+	}
+
+	featureRows, predictions, err := h.featureBuilder(store).WithRegressors(regressors).Series(req.StoreNbr, req.Family, startDate, req.Horizon, h.predictor(r.Context(), req.Family))
+	if err != nil {
+		writeScenarioError(w, r, err)
+		return
+	}
+	recordForecastLookups(store, req.StoreNbr, req.Family, startDate, req.Horizon)
+	middleware.SetModelVersion(r.Context(), h.modelVersion(""))
+
+	points := make([]ForecastPoint, 0, req.Horizon)
+	var total float32
+	var intervalSet string
+	for i, prediction := range predictions {
+		total += prediction
+
+		// Day i is forecast i+1 days ahead, so its bands widen with the lead time
+		bands := h.predictionIntervals(req.StoreNbr, req.Family, i+1, featureRows[i], prediction)
+		intervalSet = bands.Set
+		points = append(points, ForecastPoint{
+			Date:       startDate.AddDate(0, 0, i).Format(DateFormat),
+			Prediction: prediction,
+			Lower80:    bands.Lower80,
+			Upper80:    bands.Upper80,
+			Lower95:    bands.Lower95,
+			Upper95:    bands.Upper95,
+		})
+	}
+
+	resp := ForecastResponse{
+		StoreNbr:    req.StoreNbr,
+		Family:      req.Family,
+		StartDate:   req.StartDate,
+		Horizon:     req.Horizon,
+		Forecast:    points,
+		Total:       total,
+		IntervalSet: intervalSet,
+		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// recordForecastLookups records the source of each forecast day's features in the
+// feature store lookup metrics: a feature matrix row, a row rolled forward from the
+// series' last known row, or one rolled forward from zeros for an unknown series.
+func recordForecastLookups(store *features.Store, storeNbr int, family string, start time.Time, days int) {
+	known := false
+	if store != nil {
+		_, known = store.LastDate(storeNbr, family)
+	}
+	for i := 0; i < days; i++ {
+		source := features.SourceZeros
+		if known {
+			source = features.SourceRolledForward
+			if _, ok := store.Lookup(storeNbr, family, start.AddDate(0, 0, i)); ok {
+				source = features.SourceExact
+			}
+		}
+		metrics.RecordFeatureStoreLookup(string(source))
+	}
+}
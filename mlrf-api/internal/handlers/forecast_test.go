@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestForecast(t *testing.T) {
+	mockOnnx := &MockInferencer{prediction: 100}
+	h := NewHandlers(mockOnnx, nil, nil, nil)
+	h.intervals = &PredictionIntervals{Lower80Offset: -10, Upper80Offset: 10, Lower95Offset: -20, Upper95Offset: 20}
+
+	body := `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":15}`
+	req := httptest.NewRequest(http.MethodPost, "/forecast", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.Forecast(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ForecastResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(resp.Forecast) != 15 {
+		t.Fatalf("expected 15 forecast points, got %d", len(resp.Forecast))
+	}
+	if resp.Forecast[0].Date != "2017-08-16" || resp.Forecast[14].Date != "2017-08-30" {
+		t.Errorf("unexpected date range %s to %s", resp.Forecast[0].Date, resp.Forecast[14].Date)
+	}
+	if resp.Forecast[0].Upper95 != 120 {
+		t.Errorf("expected upper_95 120, got %v", resp.Forecast[0].Upper95)
+	}
+	if resp.Total != 1500 {
+		t.Errorf("expected total 1500, got %v", resp.Total)
+	}
+	if mockOnnx.CallCount() != 15 {
+		t.Errorf("expected 15 inference calls, got %d", mockOnnx.CallCount())
+	}
+}
+
+func TestForecastRecordsMetrics(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	zeros := metrics.FeatureStoreLookups.WithLabelValues("zeros")
+	initialLookups := testutil.ToFloat64(zeros)
+	predictions := metrics.PredictionCount.WithLabelValues(metrics.LabelDefault, "GROCERY I")
+	initialPredictions := testutil.ToFloat64(predictions)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":15}`
+	req := httptest.NewRequest(http.MethodPost, "/forecast", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	h.Forecast(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if v := testutil.ToFloat64(zeros) - initialLookups; v != 15 {
+		t.Errorf("expected 15 zeros lookups, got %v", v)
+	}
+	if v := testutil.ToFloat64(predictions) - initialPredictions; v != 15 {
+		t.Errorf("expected 15 GROCERY I predictions counted, got %v", v)
+	}
+}
+
+func TestForecastValidation(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+
+	testCases := []struct {
+		name    string
+		payload string
+	}{
+		{"invalid json", `{invalid}`},
+		{"missing store", `{"family":"GROCERY I","start_date":"2017-08-16","horizon":15}`},
+		{"invalid family", `{"store_nbr":1,"family":"NOPE","start_date":"2017-08-16","horizon":15}`},
+		{"invalid date", `{"store_nbr":1,"family":"GROCERY I","start_date":"08/16/2017","horizon":15}`},
+		{"invalid horizon", `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":500}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/forecast", bytes.NewReader([]byte(tc.payload)))
+			w := httptest.NewRecorder()
+
+			h.Forecast(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestForecastWithoutONNX(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":15}`
+	req := httptest.NewRequest(http.MethodPost, "/forecast", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.Forecast(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
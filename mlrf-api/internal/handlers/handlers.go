@@ -2,23 +2,129 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/alerts"
+	"github.com/mlrf/mlrf-api/internal/anomaly"
+	"github.com/mlrf/mlrf-api/internal/audit"
+	"github.com/mlrf/mlrf-api/internal/backtest"
 	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/calendar"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/drift"
 	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/hierarchy"
+	"github.com/mlrf/mlrf-api/internal/history"
 	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/mlrf/mlrf-api/internal/reconcile"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/remote"
 	"github.com/mlrf/mlrf-api/internal/shapclient"
+	"github.com/mlrf/mlrf-api/internal/slo"
+	"github.com/mlrf/mlrf-api/internal/treeshap"
 	"github.com/rs/zerolog/log"
 )
 
+// Default file paths, used until SetConfig or the matching Load call sets them.
+const (
+	DefaultModelPath               = "models/lightgbm_model.onnx"
+	DefaultFeaturePath             = "data/features/feature_matrix.parquet"
+	DefaultHierarchyDataPath       = "models/hierarchy_data.json"
+	DefaultHierarchyDefinitionPath = "models/hierarchy.json"
+)
+
 // Handlers holds dependencies for HTTP handlers.
 type Handlers struct {
-	onnx         inference.Inferencer
-	cache        *cache.RedisCache
-	featureStore *features.Store
-	intervals    *PredictionIntervals
-	shapClient   *shapclient.Client
+	onnx                inference.Inferencer
+	cache               *cache.RedisCache
+	featureStore        *features.Store
+	intervals           *PredictionIntervals
+	intervalSets        *KeyedPredictionIntervals // per-(store, family) offsets; intervals is the global fallback
+	intervalsPath       string
+	intervalsInfo       IntervalsInfo
+	intervalsMu         sync.RWMutex // guards intervals, intervalSets and intervalsInfo, swapped by RefreshIntervals
+	shapClient          *shapclient.Client
+	treeSHAP            *treeshap.Model            // native TreeSHAP fallback for /explain
+	offlineExplanations map[string]ExplainResponse // precomputed waterfalls for the offline engine
+	globalImportance    *GlobalImportance          // precomputed by training; nil computes it for /explain/global
+	importanceCache     *computedImportance
+	importanceMu        sync.Mutex // serializes computing global importance
+	importanceSample    int        // series per family explained for computed global importance
+	modelLoader         ModelReloader
+	fetcher             *remote.Fetcher
+	modelSource         remote.Source // s3:// or gs:// sources are fetched again on reload
+	featureSource       remote.Source
+	registry            *inference.Registry
+	shadow              *inference.ShadowRunner
+	quantiles           *inference.QuantileEnsemble
+	guard               *inference.Guard // clamps or rejects NaN and out-of-range predictions; nil serves them as is
+	trainingMetrics     *TrainingMetrics // of the serving model, reloaded with it
+	trainingMetricsPath string
+	trainingMetricsMu   sync.RWMutex      // guards trainingMetrics and trainingMetricsPath
+	modelMetrics        *ModelMetricsFile // model comparison from training, reloaded with the model
+	modelMetricsPath    string
+	modelMetricsMu      sync.RWMutex // guards modelMetrics and modelMetricsPath
+	jobs                *jobs.Manager
+	live                *live.Hub
+	liveOrigins         func(origin string) bool             // browser origins allowed to open /ws; same-origin if nil
+	covariance          *reconcile.Covariance                // forecast error covariance for MinT reconciliation
+	hierarchyDef        atomic.Pointer[hierarchy.Definition] // swapped by /admin/reload-hierarchy
+	hierarchyPath       string
+	hierarchyDataPath   string                              // pre-computed tree, read as-is without a definition
+	holidays            atomic.Pointer[calendar.Calendar]   // sets is_holiday of rolled-forward days
+	regressors          atomic.Pointer[features.Regressors] // supplied future values of oil_price and onpromotion
+	history             atomic.Pointer[history.Store]       // daily sales served by /historical
+	historyLoader       func(context.Context) (*history.Store, error)
+	hierarchyCache      *cache.HierarchyCache
+	simpleFlight        cache.Group[simpleFlightResult] // de-duplicates concurrent /predict/simple misses
+	actuals             *actuals.Store
+	actualsMax          int
+	alerts              *alerts.Monitor
+	sloTracker          *slo.Tracker
+	driftRef            *drift.Reference
+	driftCfg            drift.Config
+	anomalyCfg          anomaly.Config
+	predLog             *predlog.Logger
+	auditLog            *audit.Store
+	backtests           *backtest.Cache
+	backtestMaxDays     int
+	refresher           *refresh.Scheduler
+	ingest              *ingest.Consumer
+	warm                CacheWarmConfig               // hot series warmed after startup and reloads
+	warmCancel          context.CancelFunc            // cancels the running warm
+	warmMu              sync.Mutex                    // guards warm and warmCancel
+	config              atomic.Pointer[config.Config] // swapped by RefreshConfig
+	configMu            sync.Mutex                    // serializes RefreshConfig
+	configHooks         []func(*config.Config)
+	logLevel            logLevelState // temporary level set by PUT /admin/log-level
+	readiness           ReadinessConfig
+	inFlight            InFlightCounter
+	drain               drainState
+	drainTimeout        time.Duration
+	maxBatchSize        int
+	streamLimit         int
+	strictJSON          bool          // reject unknown fields in request bodies
+	denyMocks           bool          // MOCK_FALLBACKS=deny: 503 instead of fabricated data
+	revision            atomic.Uint64 // bumped on data changes, part of read-only ETags
+}
+
+// ModelReloader is implemented by inference engines that support hot model reload.
+type ModelReloader interface {
+	Reload(modelPath string) error
+	// ReloadChecked runs check on the new model before swapping it in
+	ReloadChecked(modelPath string, check func(inference.Inferencer) error) error
+	Info() inference.ModelInfo
 }
 
 // NewHandlers creates a new Handlers instance.
@@ -29,53 +135,251 @@ type Handlers struct {
 // - shapClient: SHAP service client (nil returns 503 for /explain)
 func NewHandlers(onnx inference.Inferencer, c *cache.RedisCache, fs *features.Store, sc *shapclient.Client) *Handlers {
 	return &Handlers{
-		onnx:         onnx,
-		cache:        c,
-		featureStore: fs,
-		intervals:    nil,
-		shapClient:   sc,
+		onnx:              onnx,
+		cache:             c,
+		featureStore:      fs,
+		intervals:         nil,
+		shapClient:        sc,
+		readiness:         DefaultReadinessConfig(),
+		anomalyCfg:        anomaly.DefaultConfig(),
+		drainTimeout:      DefaultDrainTimeout,
+		maxBatchSize:      MaxBatchSize,
+		streamLimit:       MaxStreamBatchSize,
+		importanceSample:  DefaultImportanceSamplePerFamily,
+		hierarchyDataPath: DefaultHierarchyDataPath,
 	}
 }
 
+// SetModelReloader enables the /admin/reload-model endpoint.
+func (h *Handlers) SetModelReloader(m ModelReloader) {
+	h.modelLoader = m
+}
+
+// SetRemoteSources makes /admin/reload-model and /admin/reload-features download
+// object store sources again before reloading. Local sources are reloaded as before.
+func (h *Handlers) SetRemoteSources(f *remote.Fetcher, model, features remote.Source) {
+	h.fetcher = f
+	h.modelSource = model
+	h.featureSource = features
+}
+
+// SetModelRegistry enables per-request model selection via the "model" request field.
+func (h *Handlers) SetModelRegistry(r *inference.Registry) {
+	h.registry = r
+}
+
+// SetShadowRunner enables shadow-mode evaluation of a challenger model on champion traffic.
+func (h *Handlers) SetShadowRunner(s *inference.ShadowRunner) {
+	h.shadow = s
+}
+
+// SetQuantileEnsemble enables model-based P10/P50/P90 quantiles in prediction responses.
+func (h *Handlers) SetQuantileEnsemble(q *inference.QuantileEnsemble) {
+	h.quantiles = q
+}
+
+// SetPredictionGuard checks /predict, /predict/batch, /predict/simple and forecast
+// predictions against g before they are served.
+func (h *Handlers) SetPredictionGuard(g *inference.Guard) {
+	h.guard = g
+}
+
+// SetJobManager enables asynchronous batch prediction jobs.
+func (h *Handlers) SetJobManager(m *jobs.Manager) {
+	h.jobs = m
+}
+
+// submitShadow queues a champion prediction for asynchronous challenger comparison,
+// attributed to the current champion when in.ChampionKey is empty.
+// No-op when shadow mode is disabled; never affects the response.
+func (h *Handlers) submitShadow(in inference.ShadowInput) {
+	if h.shadow == nil {
+		return
+	}
+	if in.ChampionKey == "" && h.registry != nil {
+		in.ChampionKey = h.registry.Champion()
+	}
+	h.shadow.Submit(in)
+}
+
+// resolveModel returns the inference engine for the requested model name and its registry key.
+// An empty name selects the default (champion) model.
+func (h *Handlers) resolveModel(name string) (inference.Inferencer, string, *ValidationError) {
+	if h.registry == nil {
+		if name != "" {
+			return nil, "", &ValidationError{
+				Message: "model selection is not available: no model registry loaded",
+				Code:    CodeInvalidModel,
+			}
+		}
+		return h.onnx, "", nil
+	}
+
+	model, key, ok := h.registry.Get(name)
+	if !ok {
+		return nil, "", &ValidationError{
+			Message: fmt.Sprintf("unknown model: %s", name),
+			Code:    CodeInvalidModel,
+		}
+	}
+	return model, key, nil
+}
+
+// modelVersion returns the version of the model serving a request: its registry key,
+// or for the default model ("") its loaded version.
+func (h *Handlers) modelVersion(key string) string {
+	if key == "" && h.modelLoader != nil {
+		return h.modelLoader.Info().Version
+	}
+	return key
+}
+
 // LoadPredictionIntervals loads prediction intervals from a JSON file.
+// Accepts the flat global offsets written by training or a keyed file with
+// per-(store, family), per-family, per-store and per-horizon offsets (see KeyedPredictionIntervals).
 // This is optional - if the file doesn't exist, CI fields will be omitted from responses.
 func (h *Handlers) LoadPredictionIntervals(path string) error {
+	h.intervalsMu.Lock()
+	h.intervalsPath = path
+	h.intervalsMu.Unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		log.Warn().Err(err).Str("path", path).Msg("Could not load prediction intervals, CIs will be omitted")
 		return err
 	}
 
-	var intervals PredictionIntervals
-	if err := json.Unmarshal(data, &intervals); err != nil {
+	keyed, err := parsePredictionIntervals(data)
+	if err != nil {
 		log.Warn().Err(err).Msg("Could not parse prediction intervals JSON")
 		return err
 	}
+	info := newIntervalsInfo(path, data, keyed)
+
+	h.intervalsMu.Lock()
+	h.intervalSets = keyed
+	h.intervals = keyed.Global
+	h.intervalsInfo = info
+	h.intervalsMu.Unlock()
+
+	event := log.Info().
+		Str("version", info.Version).
+		Int("store_family", len(keyed.ByStoreFamily)).
+		Int("family", len(keyed.ByFamily)).
+		Int("store", len(keyed.ByStore)).
+		Int("horizon", len(keyed.ByHorizon))
+	if keyed.Global != nil {
+		event = event.
+			Float32("lower_80", keyed.Global.Lower80Offset).
+			Float32("upper_80", keyed.Global.Upper80Offset).
+			Float32("lower_95", keyed.Global.Lower95Offset).
+			Float32("upper_95", keyed.Global.Upper95Offset)
+	}
+	event.Msg("Loaded prediction intervals")
+
+	if missing := keyed.missingHorizons(Horizons()); len(keyed.ByHorizon) > 0 && len(missing) > 0 {
+		log.Warn().Ints("horizons", missing).Msg("No horizon intervals for allowed horizons, serving unscaled intervals for them")
+	}
+	return nil
+}
 
-	h.intervals = &intervals
-	log.Info().
-		Float32("lower_80", intervals.Lower80Offset).
-		Float32("upper_80", intervals.Upper80Offset).
-		Float32("lower_95", intervals.Lower95Offset).
-		Float32("upper_95", intervals.Upper95Offset).
-		Msg("Loaded prediction intervals")
+// RefreshIntervals reloads prediction intervals from the configured file and clears
+// results that carry confidence bands. On failure the current intervals keep serving.
+func (h *Handlers) RefreshIntervals(ctx context.Context) error {
+	h.intervalsMu.RLock()
+	path := h.intervalsPath
+	h.intervalsMu.RUnlock()
+	if path == "" {
+		return errors.New("prediction intervals path not configured")
+	}
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		return err
+	}
+	h.invalidateHierarchy(ctx)
+	h.invalidateBacktests()
 	return nil
 }
 
-// applyIntervals computes confidence intervals for a prediction.
-// Returns lower_80, upper_80, lower_95, upper_95 values.
-func (h *Handlers) applyIntervals(prediction float32) (float32, float32, float32, float32) {
+// IntervalsInfo describes the loaded prediction intervals; empty until a file loads.
+func (h *Handlers) IntervalsInfo() IntervalsInfo {
+	h.intervalsMu.RLock()
+	defer h.intervalsMu.RUnlock()
+	return h.intervalsInfo
+}
+
+// lookupIntervals returns the most specific interval offsets for a series and horizon,
+// with the name of the interval set used. Falls back to the global intervals when no
+// keyed file is loaded.
+func (h *Handlers) lookupIntervals(storeNbr int, family string, horizon int) (*PredictionIntervals, string) {
+	h.intervalsMu.RLock()
+	defer h.intervalsMu.RUnlock()
+	if h.intervalSets != nil {
+		if iv, set := h.intervalSets.Lookup(storeNbr, family, horizon); iv != nil {
+			return iv, set
+		}
+	}
 	if h.intervals == nil {
+		return nil, ""
+	}
+	return h.intervals, IntervalLevelGlobal
+}
+
+// z95OverZ80 scales the P10-P90 spread (+/-1.2816 sigma) to a 95% band (+/-1.96 sigma).
+const z95OverZ80 = 1.96 / 1.2816
+
+// intervalBands holds the confidence bands computed for a prediction.
+type intervalBands struct {
+	Lower80   float32
+	Upper80   float32
+	Lower95   float32
+	Upper95   float32
+	Quantiles *inference.Quantiles // nil unless computed by the quantile ensemble
+	Set       string               // interval set used, e.g. "store_family@30d" or "quantile"
+}
+
+// predictionIntervals computes confidence intervals for a prediction from its features.
+// With a quantile ensemble, the 80% band is the model P10-P90 range and the 95% band
+// extrapolates its spread around P50; otherwise static offsets are used.
+func (h *Handlers) predictionIntervals(storeNbr int, family string, horizon int, features []float32, prediction float32) intervalBands {
+	if h.quantiles == nil {
+		return h.applyIntervals(storeNbr, family, horizon, prediction)
+	}
+
+	q, err := h.quantiles.Predict(features)
+	if err != nil {
+		log.Warn().Err(err).Msg("quantile inference failed, using static intervals")
+		return h.applyIntervals(storeNbr, family, horizon, prediction)
+	}
+
+	lower95 := q.P50 - (q.P50-q.P10)*z95OverZ80
+	upper95 := q.P50 + (q.P90-q.P50)*z95OverZ80
+
+	// Floor at zero (sales can't be negative)
+	return intervalBands{
+		Lower80:   max(q.P10, 0),
+		Upper80:   q.P90,
+		Lower95:   max(lower95, 0),
+		Upper95:   upper95,
+		Quantiles: &q,
+		Set:       "quantile",
+	}
+}
+
+// applyIntervals computes confidence intervals for a prediction using the most
+// specific offsets available for the series and horizon.
+func (h *Handlers) applyIntervals(storeNbr int, family string, horizon int, prediction float32) intervalBands {
+	intervals, set := h.lookupIntervals(storeNbr, family, horizon)
+	if intervals == nil {
 		// Return zeros if intervals not loaded
-		return 0, 0, 0, 0
+		return intervalBands{}
 	}
 
 	// Apply offsets to prediction
 	// Ensure lower bounds don't go negative for sales data
-	lower80 := prediction + h.intervals.Lower80Offset
-	upper80 := prediction + h.intervals.Upper80Offset
-	lower95 := prediction + h.intervals.Lower95Offset
-	upper95 := prediction + h.intervals.Upper95Offset
+	lower80 := prediction + intervals.Lower80Offset
+	upper80 := prediction + intervals.Upper80Offset
+	lower95 := prediction + intervals.Lower95Offset
+	upper95 := prediction + intervals.Upper95Offset
 
 	// Floor at zero (sales can't be negative)
 	if lower80 < 0 {
@@ -85,5 +389,11 @@ func (h *Handlers) applyIntervals(prediction float32) (float32, float32, float32
 		lower95 = 0
 	}
 
-	return lower80, upper80, lower95, upper95
+	return intervalBands{
+		Lower80: lower80,
+		Upper80: upper80,
+		Lower95: lower95,
+		Upper95: upper95,
+		Set:     set,
+	}
 }
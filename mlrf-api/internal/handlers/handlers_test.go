@@ -2,12 +2,36 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+	"github.com/mlrf/mlrf-api/internal/remote"
+	"github.com/mlrf/mlrf-api/internal/tracing"
+	"github.com/parquet-go/parquet-go"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestHealth(t *testing.T) {
@@ -32,6 +56,100 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthFeatureRefresh(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	scheduler, err := refresh.NewScheduler(refresh.Config{Cron: "0 2 * * *", Timeout: time.Minute}, func(context.Context) (int, error) {
+		return 0, errors.New("fetch failed: connection refused")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.Run(context.Background())
+	h.SetRefreshScheduler(scheduler)
+
+	w := httptest.NewRecorder()
+	h.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var resp HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Refresh == nil || resp.Refresh.LastStatus != "failed" || resp.Refresh.LastError == "" {
+		t.Fatalf("expected the failed refresh in /health, got %+v", resp.Refresh)
+	}
+	if resp.Status != "degraded" {
+		t.Errorf("expected status 'degraded' after a failed refresh, got '%s'", resp.Status)
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	h.readiness = ReadinessConfig{RequireModel: true, RequireFeatures: true, RequireRedis: true, RedisTimeout: time.Second}
+
+	w := httptest.NewRecorder()
+	h.Liveness(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected liveness status 200 without dependencies, got %d", w.Code)
+	}
+
+	ready := func() (int, ProbeResponse) {
+		w := httptest.NewRecorder()
+		h.Readiness(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var resp ProbeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return w.Code, resp
+	}
+
+	code, resp := ready()
+	if code != http.StatusServiceUnavailable || resp.Status != "not ready" {
+		t.Errorf("expected 503 not ready without a model, got %d %s", code, resp.Status)
+	}
+	for _, name := range []string{"model", "feature_store", "redis"} {
+		if c := resp.Checks[name]; c.Status != "fail" || !c.Required || c.Message == "" {
+			t.Errorf("expected the %s check to fail, got %+v", name, c)
+		}
+	}
+
+	// Model loaded and fresh features; Redis not required
+	h = NewHandlers(lagInferencer{}, nil, newTestFeatureStore(t, []features.FeatureRow{{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)}}), nil)
+	h.readiness = ReadinessConfig{RequireModel: true, RequireFeatures: true, RequireRedis: false, RedisTimeout: time.Second}
+	code, resp = ready()
+	if code != http.StatusOK || resp.Status != "ready" {
+		t.Errorf("expected 200 ready, got %d %+v", code, resp)
+	}
+	if c := resp.Checks["redis"]; c.Status != "fail" || c.Required {
+		t.Errorf("expected an optional failing redis check, got %+v", c)
+	}
+
+	h.featureStore.SetStalenessThreshold(time.Nanosecond)
+	if code, resp = ready(); code != http.StatusServiceUnavailable || resp.Checks["feature_store"].Status != "fail" {
+		t.Errorf("expected 503 with stale features, got %d %+v", code, resp.Checks["feature_store"])
+	}
+	h.featureStore.SetStalenessThreshold(24 * time.Hour)
+
+	// A model failing its export-time self-test is not ready
+	h.SetModelReloader(&mockModelReloader{info: inference.ModelInfo{SelfTest: &inference.SelfTestResult{Error: "case 0: prediction 50 outside the exported range"}}})
+	if code, resp = ready(); code != http.StatusServiceUnavailable || !strings.Contains(resp.Checks["model"].Message, "self-test failed") {
+		t.Errorf("expected 503 with a failed self-test, got %d %+v", code, resp.Checks["model"])
+	}
+}
+
+func TestReadinessConfig(t *testing.T) {
+	t.Setenv("READY_REQUIRE_REDIS", "false")
+	t.Setenv("READY_REDIS_TIMEOUT", "2s")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlers(nil, nil, nil, nil)
+	h.SetConfig(cfg)
+	if r := h.readiness; !r.RequireModel || !r.RequireFeatures || r.RequireRedis || r.RedisTimeout != 2*time.Second {
+		t.Errorf("unexpected readiness config %+v", r)
+	}
+}
+
 func TestPredictInvalidRequest(t *testing.T) {
 	h := NewHandlers(nil, nil, nil, nil)
 
@@ -545,6 +663,132 @@ func TestBatchPredictWithoutRedis(t *testing.T) {
 	}
 }
 
+// TestPredictionGuard verifies NaN and out-of-range predictions are guarded before
+// they are served, with the action taken noted in the response.
+func TestPredictionGuard(t *testing.T) {
+	feats := `[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]`
+	predictBody := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":` + feats + `}`
+
+	t.Run("negative prediction clamped", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{prediction: -250}, nil, nil, nil)
+		h.SetPredictionGuard(inference.NewGuard(inference.GuardConfig{Min: 0, Max: 1e7, Policy: inference.GuardPolicyClamp}))
+
+		w := httptest.NewRecorder()
+		h.Predict(w, httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(predictBody)))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp PredictResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Prediction != 0 || resp.Guard != inference.GuardClamped {
+			t.Errorf("expected prediction 0 clamped, got %v with guard %q", resp.Prediction, resp.Guard)
+		}
+	})
+
+	t.Run("NaN zeroed in batch", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{prediction: float32(math.NaN())}, nil, nil, nil)
+		h.SetPredictionGuard(inference.NewGuard(inference.GuardConfig{Min: 0, Max: 1e7, Policy: inference.GuardPolicyZero}))
+
+		w := httptest.NewRecorder()
+		body := `{"predictions":[` + predictBody + `,` + predictBody + `]}`
+		h.PredictBatch(w, httptest.NewRequest(http.MethodPost, "/predict/batch", strings.NewReader(body)))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp BatchPredictResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		for i, pred := range resp.Predictions {
+			if pred.Prediction != 0 || pred.Guard != inference.GuardZeroed {
+				t.Errorf("prediction[%d]: expected 0 zeroed, got %v with guard %q", i, pred.Prediction, pred.Guard)
+			}
+		}
+	})
+
+	t.Run("NaN rejected", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{prediction: float32(math.NaN())}, nil, nil, nil)
+		h.SetPredictionGuard(inference.NewGuard(inference.GuardConfig{Min: 0, Max: 1e7, Policy: inference.GuardPolicyError}))
+
+		for _, tc := range []struct {
+			path    string
+			body    string
+			handler http.HandlerFunc
+		}{
+			{"/predict", predictBody, h.Predict},
+			{"/predict/batch", `{"predictions":[` + predictBody + `]}`, h.PredictBatch},
+			{"/predict/simple", `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`, h.PredictSimple},
+			{"/forecast", `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":15}`, h.Forecast},
+			{"/whatif", `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":15}`, h.WhatIf},
+		} {
+			w := httptest.NewRecorder()
+			tc.handler(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("%s: expected status 500, got %d: %s", tc.path, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s: failed to parse response: %v", tc.path, err)
+			}
+			if resp.Code != CodeInvalidPrediction {
+				t.Errorf("%s: expected code %s, got %s", tc.path, CodeInvalidPrediction, resp.Code)
+			}
+		}
+	})
+
+	t.Run("job and series predictions clamped", func(t *testing.T) {
+		store := newTestFeatureStore(t, []features.FeatureRow{
+			{StoreNbr: 1, Family: "GROCERY I", Date: time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)},
+		})
+		h := NewHandlers(&MockInferencer{prediction: -250}, newTestCache(t), store, nil)
+		h.SetPredictionGuard(inference.NewGuard(inference.GuardConfig{Min: 0, Max: 1e7, Policy: inference.GuardPolicyClamp}))
+		ctx := context.Background()
+
+		var req PredictRequest
+		json.Unmarshal([]byte(predictBody), &req)
+		results, err := h.runPredictJob(ctx, []PredictRequest{req}, func(int) {})
+		if err != nil {
+			t.Fatalf("job failed: %v", err)
+		}
+		if results[0].Prediction != 0 || results[0].Guard != inference.GuardClamped {
+			t.Errorf("job: expected prediction 0 clamped, got %v with guard %q", results[0].Prediction, results[0].Guard)
+		}
+
+		item := SimplePredictRequest{StoreNbr: 1, Family: "GROCERY I", Date: "2017-08-01", Horizon: 30}
+		predictions, _, err := h.scoreSeries(ctx, []SimplePredictRequest{item})
+		if err != nil {
+			t.Fatalf("series scoring failed: %v", err)
+		}
+		if predictions[0] != 0 {
+			t.Errorf("series: expected prediction 0, got %v", predictions[0])
+		}
+		cached, err := h.cache.GetPrediction(ctx, cache.GenerateCacheKey(item.StoreNbr, item.Family, item.Date, item.Horizon))
+		if err != nil {
+			t.Fatalf("expected the series prediction cached: %v", err)
+		}
+		if cached.Prediction != 0 || cached.Guard != inference.GuardClamped {
+			t.Errorf("cache: expected prediction 0 clamped, got %v with guard %q", cached.Prediction, cached.Guard)
+		}
+	})
+
+	t.Run("in-range prediction unmarked", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{prediction: 1234.5}, nil, nil, nil)
+		h.SetPredictionGuard(inference.NewGuard(inference.DefaultGuardConfig()))
+
+		w := httptest.NewRecorder()
+		h.Predict(w, httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(predictBody)))
+
+		if strings.Contains(w.Body.String(), `"guard"`) {
+			t.Errorf("expected no guard field, got %s", w.Body.String())
+		}
+	})
+}
+
 // TestPredictWithoutFeatureStore verifies the API uses zero features when feature store is unavailable.
 // This tests the /predict/simple endpoint which relies on the feature store.
 func TestPredictWithoutFeatureStore(t *testing.T) {
@@ -618,6 +862,109 @@ func TestPredictSimpleWithAllDependencies(t *testing.T) {
 	}
 }
 
+func TestPredictSimpleFeatureSource(t *testing.T) {
+	aug14 := time.Date(2017, 8, 14, 0, 0, 0, 0, time.UTC)
+	aug15 := aug14.AddDate(0, 0, 1)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug14, SalesLag1: 10},
+		{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 12},
+	})
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, store, nil)
+
+	predict := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple", strings.NewReader(body)))
+		return w
+	}
+
+	for _, tc := range []struct {
+		body string
+		want features.FeatureSource
+	}{
+		{`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15}`, features.SourceExact},
+		{`{"store_nbr":1,"family":"DAIRY","date":"2017-08-01","horizon":15}`, features.SourceAggregated},
+		{`{"store_nbr":1,"family":"DAIRY","date":"2017-08-20","horizon":15}`, features.SourceRolledForward},
+		{`{"store_nbr":1,"family":"BEVERAGES","date":"2017-08-15","horizon":15}`, features.SourceZeros},
+	} {
+		w := predict(tc.body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tc.body, w.Code, w.Body.String())
+		}
+		var resp PredictResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.FeatureSource != tc.want {
+			t.Errorf("%s: expected feature_source %q, got %q", tc.body, tc.want, resp.FeatureSource)
+		}
+	}
+
+	// strict_features refuses to predict on zeros but allows the other fallbacks
+	w := predict(`{"store_nbr":1,"family":"BEVERAGES","date":"2017-08-15","horizon":15,"strict_features":true}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if errResp.Code != CodeFeatureNotFound {
+		t.Errorf("expected %s, got %+v", CodeFeatureNotFound, errResp)
+	}
+	if w := predict(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-01","horizon":15,"strict_features":true}`); w.Code != http.StatusOK {
+		t.Errorf("expected aggregated features to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Without a feature store every prediction is on zeros
+	h = NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	if w := predict(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"strict_features":true}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 without a feature store, got %d", w.Code)
+	}
+}
+
+// newTestCache returns a cache whose Redis is unreachable, so predictions are only
+// cached in process.
+func newTestCache(t *testing.T) *cache.RedisCache {
+	t.Helper()
+	c := cache.NewRedisCacheWithClient(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}), cache.DefaultConfig())
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// TestNoDataEntryIsNotAPrediction checks that the "no data" entry a strict
+// /predict/simple request caches is never served as a prediction of zero.
+func TestNoDataEntryIsNotAPrediction(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 100}, newTestCache(t), nil, nil)
+
+	w := httptest.NewRecorder()
+	body := `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"strict_features":true}`
+	h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple", strings.NewReader(body)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	zeros := strings.TrimSuffix(strings.Repeat("0,", RequiredFeatureCount), ",")
+	w = httptest.NewRecorder()
+	body = `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"features":[` + zeros + `]}`
+	h.Predict(w, httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(body)))
+	var resp PredictResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Prediction != 100 || resp.Cached {
+		t.Errorf("expected /predict to run inference, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// /predict cached its prediction; use another date for the batch
+	w = httptest.NewRecorder()
+	h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple",
+		strings.NewReader(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-16","horizon":15,"strict_features":true}`)))
+	w = httptest.NewRecorder()
+	body = `{"predictions":[{"store_nbr":1,"family":"DAIRY","date":"2017-08-16","horizon":15,"features":[` + zeros + `]}]}`
+	h.PredictBatch(w, httptest.NewRequest(http.MethodPost, "/predict/batch", strings.NewReader(body)))
+	var batch BatchPredictResponse
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if w.Code != http.StatusOK || len(batch.Predictions) != 1 || batch.Predictions[0].Prediction != 100 || batch.Predictions[0].Cached {
+		t.Errorf("expected /predict/batch to run inference, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 // TestInferenceFailure verifies proper error handling when inference fails.
 func TestInferenceFailure(t *testing.T) {
 	mockOnnx := &MockInferencer{err: fmt.Errorf("simulated inference failure")}
@@ -706,3 +1053,684 @@ func TestConcurrentPredictions(t *testing.T) {
 		t.Errorf("expected %d model calls, got %d", numRequests, mockOnnx.CallCount())
 	}
 }
+
+// mockModelReloader is a ModelReloader for testing /admin/reload-model.
+type mockModelReloader struct {
+	info      inference.ModelInfo
+	err       error
+	reloadCnt int
+	next      inference.Inferencer // Model checked by ReloadChecked, if set
+}
+
+func (m *mockModelReloader) Reload(modelPath string) error {
+	m.reloadCnt++
+	if m.err != nil {
+		return m.err
+	}
+	m.info.Path = modelPath
+	m.info.Version = fmt.Sprintf("v%d", m.reloadCnt)
+	return nil
+}
+
+func (m *mockModelReloader) ReloadChecked(modelPath string, check func(inference.Inferencer) error) error {
+	if m.next != nil {
+		if err := check(m.next); err != nil {
+			m.reloadCnt++
+			return err
+		}
+	}
+	return m.Reload(modelPath)
+}
+
+func (m *mockModelReloader) Info() inference.ModelInfo {
+	return m.info
+}
+
+func TestReloadModel(t *testing.T) {
+	t.Run("not configured returns 503", func(t *testing.T) {
+		h := NewHandlers(nil, nil, nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil)
+		w := httptest.NewRecorder()
+		h.ReloadModel(w, req)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", w.Code)
+		}
+	})
+
+	t.Run("successful reload", func(t *testing.T) {
+		reloader := &mockModelReloader{info: inference.ModelInfo{Path: "models/model.onnx"}}
+		h := NewHandlers(nil, nil, nil, nil)
+		h.SetModelReloader(reloader)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil)
+		w := httptest.NewRecorder()
+		h.ReloadModel(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp ReloadResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Metadata["file_path"] != "models/model.onnx" {
+			t.Errorf("expected file_path models/model.onnx, got %v", resp.Metadata["file_path"])
+		}
+		if reloader.reloadCnt != 1 {
+			t.Errorf("expected 1 reload, got %d", reloader.reloadCnt)
+		}
+	})
+
+	t.Run("remote source is fetched again", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/models/model.onnx" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte("onnx"))
+		}))
+		defer srv.Close()
+		fetcher := remote.NewFetcher(remote.Config{CacheDir: t.TempDir(), Timeout: 5 * time.Second, GCSEndpoint: srv.URL})
+		reloader := &mockModelReloader{}
+		h := NewHandlers(nil, nil, nil, nil)
+		h.SetModelReloader(reloader)
+
+		h.SetRemoteSources(fetcher, remote.Source{URL: "gs://models/model.onnx"}, remote.Source{})
+		w := httptest.NewRecorder()
+		h.ReloadModel(w, httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if data, err := os.ReadFile(reloader.info.Path); err != nil || string(data) != "onnx" {
+			t.Errorf("expected the reload to use the downloaded model, got %s (%v)", reloader.info.Path, err)
+		}
+
+		h.SetRemoteSources(fetcher, remote.Source{URL: "gs://models/missing.onnx"}, remote.Source{})
+		w = httptest.NewRecorder()
+		h.ReloadModel(w, httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil))
+		if w.Code != http.StatusInternalServerError || reloader.reloadCnt != 1 {
+			t.Errorf("expected a failed fetch to return 500 without reloading, got %d", w.Code)
+		}
+	})
+
+	t.Run("failed reload returns 500", func(t *testing.T) {
+		h := NewHandlers(nil, nil, nil, nil)
+		h.SetModelReloader(&mockModelReloader{err: fmt.Errorf("smoke test failed")})
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil)
+		w := httptest.NewRecorder()
+		h.ReloadModel(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+
+		var errResp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &errResp)
+		if errResp.Code != CodeReloadFailed {
+			t.Errorf("expected code %s, got %s", CodeReloadFailed, errResp.Code)
+		}
+	})
+}
+
+// TestPredictModelSelection verifies the optional "model" field routes to registered models.
+func TestPredictModelSelection(t *testing.T) {
+	champion := &MockInferencer{prediction: 100}
+	challenger := &MockInferencer{prediction: 200}
+
+	registry := inference.NewRegistry()
+	registry.Register(inference.ManifestEntry{Name: "lightgbm", Version: "1", Path: "a.onnx"}, champion)
+	registry.Register(inference.ManifestEntry{Name: "challenger", Path: "b.onnx"}, challenger)
+
+	h := NewHandlers(champion, nil, nil, nil)
+	h.SetModelRegistry(registry)
+
+	features := `[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]`
+	testCases := []struct {
+		name       string
+		model      string
+		status     int
+		prediction float32
+		modelKey   string
+	}{
+		{"default is champion", "", http.StatusOK, 100, "lightgbm@1"},
+		{"explicit challenger", "challenger", http.StatusOK, 200, "challenger"},
+		{"explicit versioned key", "lightgbm@1", http.StatusOK, 100, "lightgbm@1"},
+		{"unknown model", "missing", http.StatusBadRequest, 0, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":%s,"model":%q}`, features, tc.model)
+			req := httptest.NewRequest(http.MethodPost, "/predict", bytes.NewReader([]byte(body)))
+			w := httptest.NewRecorder()
+
+			h.Predict(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				var errResp ErrorResponse
+				json.Unmarshal(w.Body.Bytes(), &errResp)
+				if errResp.Code != CodeInvalidModel {
+					t.Errorf("expected code %s, got %s", CodeInvalidModel, errResp.Code)
+				}
+				return
+			}
+
+			var resp PredictResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Prediction != tc.prediction {
+				t.Errorf("expected prediction %v, got %v", tc.prediction, resp.Prediction)
+			}
+			if resp.Model != tc.modelKey {
+				t.Errorf("expected model %q, got %q", tc.modelKey, resp.Model)
+			}
+		})
+	}
+}
+
+func TestPredictModelSelectionWithoutRegistry(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"model":"challenger"}`
+	req := httptest.NewRequest(http.MethodPost, "/predict", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.Predict(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+// TestPredictShadowMode verifies champion traffic is replayed against the challenger
+// without changing the response.
+func TestPredictShadowMode(t *testing.T) {
+	champion := &MockInferencer{prediction: 100}
+	challenger := &MockInferencer{prediction: 150}
+
+	h := NewHandlers(champion, nil, nil, nil)
+	shadow := inference.NewShadowRunner("challenger", challenger, inference.ShadowConfig{Workers: 1, QueueSize: 10})
+	h.SetShadowRunner(shadow)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`
+	req := httptest.NewRequest(http.MethodPost, "/predict", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.Predict(w, req)
+	shadow.Close()
+
+	var resp PredictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Prediction != 100 {
+		t.Errorf("expected champion prediction 100, got %v", resp.Prediction)
+	}
+	if challenger.CallCount() != 1 {
+		t.Errorf("expected challenger to be called once, got %d", challenger.CallCount())
+	}
+}
+
+// TestPredictSimpleQuantiles verifies intervals come from the quantile models
+// instead of static offsets when an ensemble is loaded.
+func TestPredictSimpleQuantiles(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	h.intervals = &PredictionIntervals{Lower80Offset: -1, Upper80Offset: 1, Lower95Offset: -2, Upper95Offset: 2}
+	h.SetQuantileEnsemble(inference.NewQuantileEnsemble(
+		&MockInferencer{prediction: 80},
+		&MockInferencer{prediction: 100},
+		&MockInferencer{prediction: 130},
+	))
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.PredictSimple(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp PredictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Quantiles == nil || resp.Quantiles.P10 != 80 || resp.Quantiles.P50 != 100 || resp.Quantiles.P90 != 130 {
+		t.Fatalf("expected quantiles p10=80 p50=100 p90=130, got %+v", resp.Quantiles)
+	}
+	if resp.Lower80 != 80 || resp.Upper80 != 130 {
+		t.Errorf("expected 80%% interval [80, 130], got [%v, %v]", resp.Lower80, resp.Upper80)
+	}
+	if resp.Lower95 >= 80 || resp.Upper95 <= 130 {
+		t.Errorf("expected 95%% interval wider than 80%%, got [%v, %v]", resp.Lower95, resp.Upper95)
+	}
+}
+
+// TestPredictSimpleCacheHitMatchesMiss verifies a cached /predict/simple response
+// carries the same intervals, quantiles and interval set as the miss that cached it.
+func TestPredictSimpleCacheHitMatchesMiss(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		quantiles bool
+	}{
+		{"static intervals", false},
+		{"quantile models", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandlers(&MockInferencer{prediction: 100}, newTestCache(t), nil, nil)
+			h.intervals = &PredictionIntervals{Lower80Offset: -10, Upper80Offset: 10, Lower95Offset: -20, Upper95Offset: 20}
+			if tc.quantiles {
+				h.SetQuantileEnsemble(inference.NewQuantileEnsemble(
+					&MockInferencer{prediction: 80},
+					&MockInferencer{prediction: 100},
+					&MockInferencer{prediction: 130},
+				))
+			}
+
+			predict := func() PredictResponse {
+				t.Helper()
+				body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`
+				w := httptest.NewRecorder()
+				h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple", strings.NewReader(body)))
+				if w.Code != http.StatusOK {
+					t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+				}
+				var resp PredictResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to parse response: %v", err)
+				}
+				return resp
+			}
+
+			miss, hit := predict(), predict()
+			if miss.Cached || !hit.Cached {
+				t.Fatalf("expected a miss then a hit, got cached=%v then %v", miss.Cached, hit.Cached)
+			}
+			if miss.IntervalSet == "" || (tc.quantiles && miss.Quantiles == nil) {
+				t.Fatalf("expected the miss to carry intervals, got %+v", miss)
+			}
+			for _, resp := range []*PredictResponse{&miss, &hit} {
+				resp.Cached, resp.LatencyMs, resp.TraceID = false, 0, ""
+			}
+			if !reflect.DeepEqual(miss, hit) {
+				t.Errorf("expected the hit to match the miss:\nmiss %+v\nhit  %+v", miss, hit)
+			}
+		})
+	}
+}
+
+// TestBatchPredictConfigurableLimit verifies MAX_BATCH_SIZE raises the batch limit
+// and that parallel validation reports the first invalid item.
+func TestBatchPredictConfigurableLimit(t *testing.T) {
+	t.Setenv("MAX_BATCH_SIZE", "500")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockOnnx := &MockInferencer{prediction: 7}
+	h := NewHandlers(mockOnnx, nil, nil, nil)
+	h.SetConfig(cfg)
+
+	w := httptest.NewRecorder()
+	h.PredictBatch(w, httptest.NewRequest(http.MethodPost, "/predict/batch", bytes.NewReader(batchBody(400))))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for 400 items, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchPredictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Predictions) != 400 || resp.Predictions[399].Prediction != 7 {
+		t.Errorf("unexpected predictions: %d", len(resp.Predictions))
+	}
+
+	w = httptest.NewRecorder()
+	h.PredictBatch(w, httptest.NewRequest(http.MethodPost, "/predict/batch", bytes.NewReader(batchBody(501))))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 above MAX_BATCH_SIZE, got %d", w.Code)
+	}
+
+	// Invalid items at 350 and 300: the lowest index is reported
+	items := strings.Split(string(batchBody(400)), `{"store_nbr":1,`)
+	items[301] = strings.Replace(items[301], `"family":"GROCERY I"`, `"family":"NOPE"`, 1)
+	items[351] = strings.Replace(items[351], `"family":"GROCERY I"`, `"family":"NOPE"`, 1)
+	w = httptest.NewRecorder()
+	h.PredictBatch(w, httptest.NewRequest(http.MethodPost, "/predict/batch", strings.NewReader(strings.Join(items, `{"store_nbr":1,`))))
+
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusBadRequest || !strings.HasPrefix(errResp.Error, "prediction[300]") {
+		t.Errorf("expected error for prediction[300], got %d %q", w.Code, errResp.Error)
+	}
+}
+
+func TestFeatureQuality(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, OilPrice: 47},
+	})
+	h := NewHandlers(nil, nil, store, nil)
+
+	get := func() FeatureQualityResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.FeatureQuality(w, httptest.NewRequest(http.MethodGet, "/admin/feature-quality", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp FeatureQualityResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp
+	}
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ReloadFeatures(w, httptest.NewRequest(http.MethodPost, "/admin/reload-features", nil))
+		return w
+	}
+
+	if resp := get(); resp.Quality == nil || resp.Quality.Status != features.QualityPassed || resp.Rejected != nil {
+		t.Fatalf("expected a passed report, got %+v", resp)
+	}
+
+	// Replace the file with negative lags
+	if err := parquet.WriteFile(store.FilePath(), []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, OilPrice: 47, SalesLag7: -1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := reload()
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if errResp.Code != CodeFeatureQualityFailed || !strings.Contains(errResp.Error, "sales_lag_7") {
+		t.Errorf("unexpected error %+v", errResp)
+	}
+	if resp := get(); resp.Quality.Status != features.QualityPassed || resp.Rejected == nil || resp.Rejected.Status != features.QualityRejected {
+		t.Errorf("expected the rejected reload alongside the served report, got %+v", resp)
+	}
+
+	// In degrade mode the file loads and health reports it
+	store.SetQualityConfig(features.QualityConfig{Mode: features.QualityModeDegrade, MaxNullRate: 0.01, MaxOutlierRate: 0.01})
+	if w := reload(); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := get(); resp.Quality.Status != features.QualityDegraded || resp.Rejected != nil {
+		t.Errorf("expected a degraded report, got %+v", resp)
+	}
+
+	hw := httptest.NewRecorder()
+	h.Health(hw, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health HealthResponse
+	if err := json.Unmarshal(hw.Body.Bytes(), &health); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if health.Status != "degraded" || health.FeatureStore.Quality != features.QualityDegraded {
+		t.Errorf("expected degraded health, got %s / %+v", health.Status, health.FeatureStore)
+	}
+}
+
+func TestAppendFeatures(t *testing.T) {
+	aug15 := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	aug16 := aug15.AddDate(0, 0, 1)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug15, SalesLag1: 10},
+	})
+	h := NewHandlers(nil, nil, store, nil)
+
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/append-features", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		h.AppendFeatures(w, req)
+		return w
+	}
+
+	// Delta by path
+	path := filepath.Join(t.TempDir(), "delta.parquet")
+	if err := parquet.WriteFile(path, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: aug16, SalesLag1: 20},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(AppendFeaturesRequest{Path: path})
+	w := post("application/json", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReloadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Status != "appended" || resp.Metadata["added"] != float64(1) || resp.Metadata["data_date_max"] != "2017-08-16" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	// Uploaded delta
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, []features.FeatureRow{
+		{StoreNbr: 2, Family: "EGGS", Date: aug16, SalesLag1: 5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if w := post("application/vnd.apache.parquet", buf.Bytes()); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := store.Lookup(2, "EGGS", aug16); !ok {
+		t.Error("expected the uploaded row to be merged")
+	}
+
+	if w := post("application/json", []byte(`{}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a path, got %d", w.Code)
+	}
+	if w := post("application/octet-stream", []byte("not parquet")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed upload, got %d", w.Code)
+	}
+
+	// A delta without the model's feature columns
+	type keysOnly struct {
+		StoreNbr int32     `parquet:"store_nbr"`
+		Family   string    `parquet:"family"`
+		Date     time.Time `parquet:"date"`
+	}
+	buf.Reset()
+	if err := parquet.Write(&buf, []keysOnly{{StoreNbr: 3, Family: "BREAD", Date: aug16}}); err != nil {
+		t.Fatal(err)
+	}
+	w = post("application/vnd.apache.parquet", buf.Bytes())
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusUnprocessableEntity || errResp.Code != CodeFeatureSchemaMismatch {
+		t.Errorf("expected 422 %s, got %d: %s", CodeFeatureSchemaMismatch, w.Code, w.Body.String())
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	before := h.revision.Load()
+
+	w := httptest.NewRecorder()
+	h.FlushCache(w, httptest.NewRequest(http.MethodPost, "/admin/cache/flush", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReloadResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Status != "flushed" {
+		t.Errorf("expected status flushed, got %q", resp.Status)
+	}
+	if h.revision.Load() == before {
+		t.Error("expected a flush to invalidate read-only ETags")
+	}
+}
+
+// blockingInferencer counts predictions, each blocking until release is closed.
+type blockingInferencer struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingInferencer) Predict([]float32) (float32, error) {
+	b.calls.Add(1)
+	<-b.release
+	return 100, nil
+}
+
+func (b *blockingInferencer) PredictBatch(batch [][]float32) ([]float32, error) {
+	out := make([]float32, len(batch))
+	for i, f := range batch {
+		out[i], _ = b.Predict(f)
+	}
+	return out, nil
+}
+
+func TestPredictSimpleDeduplicatesConcurrentMisses(t *testing.T) {
+	model := &blockingInferencer{release: make(chan struct{})}
+	h := NewHandlers(model, nil, nil, nil)
+	flightKey := simpleFlightKey(cache.GenerateCacheKey(1, "GROCERY I", "2017-08-01", 30), false)
+
+	predict := func(ctx context.Context) *httptest.ResponseRecorder {
+		body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`
+		w := httptest.NewRecorder()
+		h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/predict/simple", strings.NewReader(body)).WithContext(ctx))
+		return w
+	}
+
+	// The request running the inference has already gone away
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		predict(cancelled)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for model.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	const n = 9
+	codes := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = predict(context.Background()).Code
+		}(i)
+	}
+	for h.simpleFlight.Waiters(flightKey) != n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A waiter whose own deadline passes gives up without waiting for the inference
+	expiring, cancelExpiring := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelExpiring()
+	if w := predict(expiring); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504 for a waiter past its deadline, got %d: %s", w.Code, w.Body.String())
+	}
+
+	close(model.release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected 200, got %d", i, code)
+		}
+	}
+	if calls := model.calls.Load(); calls != 1 {
+		t.Errorf("expected one inference for identical requests, got %d", calls)
+	}
+}
+
+func TestPredictSimpleSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC), SalesLag1: 10},
+	})
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, store, nil)
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "POST /predict/simple")
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple",
+		strings.NewReader(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	h.PredictSimple(w, req)
+	root.End()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp PredictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.TraceID != root.SpanContext().TraceID().String() {
+		t.Errorf("expected trace_id %s, got %q", root.SpanContext().TraceID(), resp.TraceID)
+	}
+
+	attrs := make(map[string]map[attribute.Key]attribute.Value)
+	for _, span := range rec.Ended() {
+		if span.SpanContext().SpanID() != root.SpanContext().SpanID() && span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of the request span", span.Name())
+		}
+		attrs[span.Name()] = make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			attrs[span.Name()][kv.Key] = kv.Value
+		}
+	}
+	if v := attrs["features.lookup"][tracing.AttrFeatureSrc]; v.AsString() != string(features.SourceExact) {
+		t.Errorf("expected the lookup span to record the exact source, got %v", attrs["features.lookup"])
+	}
+	if _, ok := attrs["inference.predict"][tracing.AttrInferenceMs]; !ok {
+		t.Errorf("expected the inference span to record inference_ms, got %v", attrs["inference.predict"])
+	}
+	request := attrs["POST /predict/simple"]
+	if request[tracing.AttrCacheHit].AsBool() || request[tracing.AttrPrediction].AsFloat64() != 100 ||
+		request[tracing.AttrStoreNbr].AsInt64() != 1 || request[tracing.AttrHorizon].AsInt64() != 15 {
+		t.Errorf("unexpected request span attributes %v", request)
+	}
+}
+
+func TestPredictSimpleAccessLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rl, err := middleware.NewRequestLogger(middleware.RequestLoggerConfig{SampleRate: 1, File: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	h.SetModelReloader(&mockModelReloader{info: inference.ModelInfo{Version: "1502755200"}})
+	handler := rl.Middleware(http.HandlerFunc(h.PredictSimple))
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple",
+		strings.NewReader(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15}`))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &line); err != nil {
+		t.Fatalf("invalid access log %q: %v", data, err)
+	}
+	if line["cache_hit"] != false || line["model_version"] != "1502755200" {
+		t.Errorf("expected a cache miss served by the loaded model version, got %v", line)
+	}
+}
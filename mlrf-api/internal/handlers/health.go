@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/refresh"
 )
 
 // FeatureStoreHealth represents the health status of the feature store.
@@ -18,11 +22,14 @@ type FeatureStoreHealth struct {
 	DataAge     string `json:"data_age,omitempty"`
 	RowCount    int    `json:"row_count,omitempty"`
 	Version     string `json:"version,omitempty"`
+	Backend     string `json:"backend,omitempty"` // memory, duckdb or sqlite
+	Quality     string `json:"quality,omitempty"` // passed or degraded; see /admin/feature-quality
 }
 
 // ShapHealth represents the health status of the SHAP service.
 type ShapHealth struct {
-	Status string `json:"status"`
+	Status  string `json:"status"`
+	Circuit string `json:"circuit,omitempty"` // Circuit breaker state: closed, half-open or open
 }
 
 // HealthResponse represents the health check response.
@@ -32,6 +39,8 @@ type HealthResponse struct {
 	Redis        string              `json:"redis,omitempty"`
 	FeatureStore *FeatureStoreHealth `json:"feature_store,omitempty"`
 	Shap         *ShapHealth         `json:"shap,omitempty"`
+	Refresh      *refresh.Status     `json:"feature_refresh,omitempty"` // Set when FEATURE_REFRESH_CRON is configured
+	Ingest       *ingest.Status      `json:"feature_ingest,omitempty"`  // Set when INGEST_BROKER is configured
 }
 
 // Health returns the health status of the API.
@@ -56,10 +65,26 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 
 	// Check Feature Store
 	resp.FeatureStore = h.getFeatureStoreHealth()
-	if resp.FeatureStore != nil && !resp.FeatureStore.Fresh && resp.FeatureStore.Loaded {
+	if resp.FeatureStore != nil && resp.FeatureStore.Loaded &&
+		(!resp.FeatureStore.Fresh || resp.FeatureStore.Quality == features.QualityDegraded) {
 		resp.Status = "degraded"
 	}
 
+	// Check scheduled feature refresh
+	if h.refresher != nil {
+		status := h.refresher.Status()
+		resp.Refresh = &status
+		if status.LastStatus == "failed" {
+			resp.Status = "degraded"
+		}
+	}
+
+	// Check streamed feature ingestion
+	if h.ingest != nil {
+		status := h.ingest.Status()
+		resp.Ingest = &status
+	}
+
 	// Check SHAP service
 	resp.Shap = h.getShapHealth(r.Context())
 
@@ -68,6 +93,110 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// ReadinessConfig selects the checks /health/ready requires to pass.
+type ReadinessConfig struct {
+	RequireModel    bool          // ONNX model loaded
+	RequireFeatures bool          // Feature store loaded and fresh
+	RequireRedis    bool          // Redis reachable within RedisTimeout
+	RedisTimeout    time.Duration // Timeout of the Redis ping
+}
+
+// DefaultReadinessConfig returns the default readiness checks: all of them, with a
+// 500ms Redis ping.
+func DefaultReadinessConfig() ReadinessConfig {
+	return ReadinessConfig{
+		RequireModel:    true,
+		RequireFeatures: true,
+		RequireRedis:    true,
+		RedisTimeout:    500 * time.Millisecond,
+	}
+}
+
+// ProbeResponse is the response of /health/live and /health/ready.
+type ProbeResponse struct {
+	Status string                    `json:"status"`           // alive, ready, not ready or draining
+	Checks map[string]ReadinessCheck `json:"checks,omitempty"` // model, feature_store and redis
+}
+
+// ReadinessCheck is the result of one readiness check.
+type ReadinessCheck struct {
+	Status   string `json:"status"`            // pass or fail
+	Required bool   `json:"required"`          // A failing required check makes the server not ready
+	Message  string `json:"message,omitempty"` // Why the check failed
+}
+
+// Liveness reports that the process is up and serving HTTP. It always returns 200, so
+// a liveness probe only restarts a server that stopped responding.
+func (h *Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProbeResponse{Status: "alive"})
+}
+
+// Readiness reports whether the server can serve predictions: 200 when every required
+// check passes, 503 otherwise or while draining, so load balancers only route traffic
+// to ready servers.
+func (h *Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	resp := ProbeResponse{Status: "ready", Checks: h.readinessChecks(r.Context())}
+	status := http.StatusOK
+	for _, check := range resp.Checks {
+		if check.Required && check.Status != "pass" {
+			resp.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if h.draining() {
+		resp.Status = "draining"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// readinessChecks runs the model, feature store and Redis checks.
+func (h *Handlers) readinessChecks(ctx context.Context) map[string]ReadinessCheck {
+	cfg := h.readiness
+	check := func(required bool, failure string) ReadinessCheck {
+		if failure != "" {
+			return ReadinessCheck{Status: "fail", Required: required, Message: failure}
+		}
+		return ReadinessCheck{Status: "pass", Required: required}
+	}
+
+	var model, featureStore, redis string
+	if h.onnx == nil {
+		model = "model not loaded"
+	} else if h.modelLoader != nil {
+		if st := h.modelLoader.Info().SelfTest; st != nil && !st.Passed {
+			model = "model self-test failed: " + st.Error
+		}
+	}
+
+	switch {
+	case h.featureStore == nil || !h.featureStore.IsLoaded():
+		featureStore = "feature store not loaded"
+	case !h.featureStore.IsFresh():
+		featureStore = "feature store is stale (loaded " + h.featureStore.Age().Round(time.Second).String() + " ago)"
+	}
+
+	if h.cache == nil {
+		redis = "redis not connected"
+	} else {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.RedisTimeout)
+		defer cancel()
+		if err := h.cache.Ping(pingCtx); err != nil {
+			redis = "redis unreachable: " + err.Error()
+		}
+	}
+
+	return map[string]ReadinessCheck{
+		"model":         check(cfg.RequireModel, model),
+		"feature_store": check(cfg.RequireFeatures, featureStore),
+		"redis":         check(cfg.RequireRedis, redis),
+	}
+}
+
 // getFeatureStoreHealth returns the health status of the feature store.
 func (h *Handlers) getFeatureStoreHealth() *FeatureStoreHealth {
 	if h.featureStore == nil {
@@ -95,7 +224,14 @@ func (h *Handlers) getFeatureStoreHealth() *FeatureStoreHealth {
 		health.DataAge = h.featureStore.DataAge().Round(time.Hour).String()
 		health.RowCount = meta.RowCount
 		health.Version = meta.Version
+		health.Backend = meta.Backend
 
+		if quality, _ := h.featureStore.Quality(); quality != nil {
+			health.Quality = quality.Status
+			if quality.Status == features.QualityDegraded {
+				health.Status = "degraded"
+			}
+		}
 		if !fresh {
 			health.Status = "stale"
 		}
@@ -114,16 +250,23 @@ func (h *Handlers) getShapHealth(ctx context.Context) *ShapHealth {
 		}
 	}
 
+	var circuit string
+	if b := h.shapClient.Breaker(); b != nil {
+		circuit = b.State().String()
+	}
+
 	// Try to check SHAP service health
 	healthy, err := h.shapClient.Health(ctx)
 	if err != nil || !healthy {
 		return &ShapHealth{
-			Status: "unavailable",
+			Status:  "unavailable",
+			Circuit: circuit,
 		}
 	}
 
 	return &ShapHealth{
-		Status: "healthy",
+		Status:  "healthy",
+		Circuit: circuit,
 	}
 }
 
@@ -153,25 +296,3 @@ func (h *Handlers) Metrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
-
-// ModelMetric represents model performance metrics for comparison.
-type ModelMetric struct {
-	Model string  `json:"model"`
-	RMSLE float64 `json:"rmsle"`
-	MAPE  float64 `json:"mape"`
-	RMSE  float64 `json:"rmse"`
-}
-
-// ModelMetrics returns model comparison metrics for the dashboard.
-func (h *Handlers) ModelMetrics(w http.ResponseWriter, r *http.Request) {
-	// Model comparison data - LightGBM from actual training, others estimated
-	metrics := []ModelMetric{
-		{Model: "LightGBM + MinTrace", RMSLE: 0.4770, MAPE: 0.15, RMSE: 214.58},
-		{Model: "AutoARIMA + BottomUp", RMSLE: 0.5200, MAPE: 0.19, RMSE: 245.00},
-		{Model: "ETS + TopDown", RMSLE: 0.5800, MAPE: 0.22, RMSE: 280.00},
-		{Model: "SeasonalNaive", RMSLE: 0.6521, MAPE: 0.28, RMSE: 320.00},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(metrics)
-}
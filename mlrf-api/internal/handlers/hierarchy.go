@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/hierarchy"
+	"github.com/rs/zerolog/log"
+)
+
+// errHierarchyUnavailable is returned when neither hierarchy data nor a definition
+// with a model to score it is available.
+var errHierarchyUnavailable = errors.New("hierarchy data not available")
+
+// errHierarchyParse is returned when the hierarchy data file is not a valid tree.
+var errHierarchyParse = errors.New("failed to parse hierarchy data")
+
+// LoadHierarchyDefinition loads the hierarchy definition used by /hierarchy and its
+// reconciliation. This is optional - without it, the tree comes from HIERARCHY_DATA_PATH.
+// The path is remembered for /admin/reload-hierarchy even if loading fails.
+func (h *Handlers) LoadHierarchyDefinition(path string) error {
+	h.hierarchyPath = path
+	return h.loadHierarchyDefinition(path)
+}
+
+// loadHierarchyDefinition swaps in the definition at path.
+func (h *Handlers) loadHierarchyDefinition(path string) error {
+	def, err := hierarchy.Load(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load hierarchy definition")
+		return err
+	}
+	h.hierarchyDef.Store(def)
+	log.Info().
+		Strs("levels", def.Levels).
+		Int("nodes", def.Size()).
+		Int("leaves", len(def.Leaves())).
+		Msg("Loaded hierarchy definition")
+	return nil
+}
+
+// SetHierarchyCache enables caching of assembled /hierarchy trees per date, method and horizon.
+// The cache is invalidated on feature store, model and hierarchy definition reloads.
+func (h *Handlers) SetHierarchyCache(c *cache.HierarchyCache) {
+	h.hierarchyCache = c
+}
+
+// invalidateHierarchy drops cached trees and read-only ETags after a reload.
+func (h *Handlers) invalidateHierarchy(ctx context.Context) {
+	h.bumpRevision()
+	if h.hierarchyCache == nil {
+		return
+	}
+	if err := h.hierarchyCache.Invalidate(ctx); err != nil {
+		log.Warn().Err(err).Msg("failed to invalidate hierarchy cache")
+	}
+}
+
+// ReloadHierarchy triggers a hot reload of the hierarchy definition. On failure the
+// current definition stays in use. Requires the admin scope.
+func (h *Handlers) ReloadHierarchy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, r, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
+		return
+	}
+
+	path := h.hierarchyDefinitionPath()
+	log.Info().Str("path", path).Msg("Reloading hierarchy definition...")
+
+	if err := h.loadHierarchyDefinition(path); err != nil {
+		WriteInternalError(w, r, "reload failed: "+err.Error(), CodeReloadFailed)
+		return
+	}
+	h.invalidateHierarchy(r.Context())
+
+	def := h.hierarchyDef.Load()
+	resp := ReloadResponse{
+		Status:  "reloaded",
+		Message: "Hierarchy definition reloaded successfully",
+		Metadata: map[string]interface{}{
+			"file_path": path,
+			"levels":    def.Levels,
+			"nodes":     def.Size(),
+			"leaves":    len(def.Leaves()),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// hierarchyDefinitionPath returns the path of the hierarchy definition file, from
+// LoadHierarchyDefinition or else DefaultHierarchyDefinitionPath.
+func (h *Handlers) hierarchyDefinitionPath() string {
+	if h.hierarchyPath != "" {
+		return h.hierarchyPath
+	}
+	return DefaultHierarchyDefinitionPath
+}
+
+// loadHierarchy builds the forecast tree. Without a definition the tree is read as-is
+// from HIERARCHY_DATA_PATH. With one, the tree follows the definition: base forecasts
+// are taken from the data file by node ID where present, family leaves are scored by
+// the model when available, and aggregates without a base forecast are summed from
+// their children. Leaves are also rescored when refresh is set.
+func (h *Handlers) loadHierarchy(ctx context.Context, date string, horizon int, refresh bool) (*HierarchyNode, error) {
+	data, dataErr := h.readHierarchyData()
+	def := h.hierarchyDef.Load()
+	if def == nil {
+		if dataErr != nil {
+			return nil, dataErr
+		}
+		if refresh && h.canScoreSeries() {
+			if err := h.refreshLeafForecasts(ctx, data, date, horizon); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+
+	base := make(map[string]HierarchyNode)
+	switch {
+	case dataErr == nil:
+		indexHierarchy(data, base)
+	case !errors.Is(dataErr, errHierarchyUnavailable):
+		return nil, dataErr
+	}
+	if len(base) == 0 && !h.canScoreSeries() {
+		return nil, errHierarchyUnavailable
+	}
+
+	root := buildHierarchy(def, def.Root(), base)
+	if h.canScoreSeries() {
+		if err := h.refreshLeafForecasts(ctx, &root, date, horizon); err != nil {
+			return nil, err
+		}
+	}
+	fillAggregates(&root, base)
+	return &root, nil
+}
+
+// canScoreSeries reports whether leaf forecasts can be scored from feature store features.
+func (h *Handlers) canScoreSeries() bool {
+	return h.onnx != nil && h.featureStore != nil && h.featureStore.IsLoaded()
+}
+
+// readHierarchyData reads the pre-computed hierarchy tree from HIERARCHY_DATA_PATH.
+func (h *Handlers) readHierarchyData() (*HierarchyNode, error) {
+	hierarchyFile := h.hierarchyDataPath
+
+	data, err := os.ReadFile(hierarchyFile)
+	if err != nil {
+		log.Debug().Err(err).Str("file", hierarchyFile).Msg("Hierarchy data file not found")
+		return nil, errHierarchyUnavailable
+	}
+
+	var node HierarchyNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("%w: %v", errHierarchyParse, err)
+	}
+	return &node, nil
+}
+
+// indexHierarchy maps node IDs to nodes for base forecast lookup.
+func indexHierarchy(n *HierarchyNode, index map[string]HierarchyNode) {
+	index[n.ID] = *n
+	for i := range n.Children {
+		indexHierarchy(&n.Children[i], index)
+	}
+}
+
+// buildHierarchy converts a definition subtree into a forecast tree, taking base
+// forecasts and actuals from base by node ID.
+func buildHierarchy(def *hierarchy.Definition, n hierarchy.Node, base map[string]HierarchyNode) HierarchyNode {
+	node := HierarchyNode{ID: n.ID, Name: n.Name, Level: n.Level}
+	if b, ok := base[n.ID]; ok {
+		node.Prediction = b.Prediction
+		node.Actual = b.Actual
+	}
+	for _, c := range def.Children(n.ID) {
+		node.Children = append(node.Children, buildHierarchy(def, c, base))
+	}
+	return node
+}
+
+// fillAggregates sets aggregates without a base forecast to the sum of their children.
+func fillAggregates(n *HierarchyNode, base map[string]HierarchyNode) float64 {
+	if len(n.Children) == 0 {
+		return n.Prediction
+	}
+	var sum float64
+	for i := range n.Children {
+		sum += fillAggregates(&n.Children[i], base)
+	}
+	if _, ok := base[n.ID]; !ok {
+		n.Prediction = sum
+	}
+	return n.Prediction
+}
+
+const (
+	// DefaultHierarchyPageSize is the number of children returned when no limit is given.
+	DefaultHierarchyPageSize = 50
+
+	// MaxHierarchyPageSize caps the children per page.
+	MaxHierarchyPageSize = 500
+
+	// MaxHierarchyDepth caps the levels returned below a node.
+	MaxHierarchyDepth = 5
+)
+
+// HierarchyChildrenResponse is one page of a node's children.
+type HierarchyChildrenResponse struct {
+	Node       HierarchyNode   `json:"node"` // Without children
+	Children   []HierarchyNode `json:"children"`
+	Total      int             `json:"total"` // Children matching min_prediction, before paging
+	Offset     int             `json:"offset"`
+	Limit      int             `json:"limit"`
+	Depth      int             `json:"depth"`
+	NextOffset *int            `json:"next_offset,omitempty"` // Set when more children follow
+}
+
+// HierarchyChildren returns a page of a node's children for drill-down.
+// Query parameters:
+//   - offset, limit: page through the children (default 0 and 50, max 500)
+//   - depth: levels returned below the node (default 1 = children only, max 5)
+//   - min_prediction: drop nodes predicted below this value at every level
+//
+// The date, method and horizon parameters are applied as for /hierarchy.
+// Node IDs containing "/" must be escaped as %2F.
+func (h *Handlers) HierarchyChildren(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := url.PathUnescape(chi.URLParam(r, "nodeID"))
+	if err != nil {
+		WriteBadRequest(w, r, "invalid node id", CodeInvalidRequest)
+		return
+	}
+
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		WriteBadRequest(w, r, "offset must be a non-negative integer", CodeInvalidRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultHierarchyPageSize)
+	if err != nil || limit < 1 || limit > MaxHierarchyPageSize {
+		WriteBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", MaxHierarchyPageSize), CodeInvalidRequest)
+		return
+	}
+	depth, err := queryInt(r, "depth", 1)
+	if err != nil || depth < 1 || depth > MaxHierarchyDepth {
+		WriteBadRequest(w, r, fmt.Sprintf("depth must be between 1 and %d", MaxHierarchyDepth), CodeInvalidRequest)
+		return
+	}
+	minPrediction := math.Inf(-1)
+	if v := r.URL.Query().Get("min_prediction"); v != "" {
+		minPrediction, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			WriteBadRequest(w, r, "min_prediction must be a number", CodeInvalidRequest)
+			return
+		}
+	}
+
+	root, ok := h.requestHierarchy(w, r)
+	if !ok {
+		return
+	}
+
+	node := findHierarchyNode(root, nodeID)
+	if node == nil {
+		WriteError(w, r, http.StatusNotFound, "hierarchy node not found: "+nodeID, CodeHierarchyNodeNotFound)
+		return
+	}
+
+	children := pruneHierarchy(node.Children, depth-1, minPrediction)
+	resp := HierarchyChildrenResponse{
+		Node:   *node,
+		Total:  len(children),
+		Offset: offset,
+		Limit:  limit,
+		Depth:  depth,
+	}
+	resp.Node.Children = nil
+
+	if offset < len(children) {
+		end := min(offset+limit, len(children))
+		resp.Children = children[offset:end]
+		if end < len(children) {
+			resp.NextOffset = &end
+		}
+	} else {
+		resp.Children = []HierarchyNode{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// queryInt parses an integer query parameter, returning def when it is absent.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// findHierarchyNode returns the node with the given ID, or nil.
+func findHierarchyNode(n *HierarchyNode, id string) *HierarchyNode {
+	if n.ID == id {
+		return n
+	}
+	for i := range n.Children {
+		if found := findHierarchyNode(&n.Children[i], id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// pruneHierarchy copies nodes predicted at or above minPrediction, keeping depth
+// further levels of their children.
+func pruneHierarchy(nodes []HierarchyNode, depth int, minPrediction float64) []HierarchyNode {
+	pruned := make([]HierarchyNode, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Prediction < minPrediction {
+			continue
+		}
+		if depth > 0 {
+			n.Children = pruneHierarchy(n.Children, depth-1, minPrediction)
+		} else {
+			n.Children = nil
+		}
+		pruned = append(pruned, n)
+	}
+	return pruned
+}
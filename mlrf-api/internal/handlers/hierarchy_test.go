@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+const testHierarchyDefinition = `{
+  "levels": ["total", "state", "store", "family"],
+  "nodes": [
+    {"id": "total", "name": "Total", "level": "total"},
+    {"id": "pichincha", "name": "Pichincha", "level": "state", "parent": "total"},
+    {"id": "store_1", "name": "Store 1", "level": "store", "parent": "pichincha"},
+    {"id": "1_DAIRY", "name": "DAIRY", "level": "family", "parent": "store_1"},
+    {"id": "1_BREAD/BAKERY", "name": "BREAD/BAKERY", "level": "family", "parent": "store_1"},
+    {"id": "store_2", "name": "Store 2", "level": "store", "parent": "pichincha"},
+    {"id": "2_DAIRY", "name": "DAIRY", "level": "family", "parent": "store_2"}
+  ]
+}`
+
+// writeHierarchyDefinition writes a definition file and returns its path.
+func writeHierarchyDefinition(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hierarchy.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHierarchyFromDefinition(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, SalesLag1: 5},
+		{StoreNbr: 1, Family: "BREAD/BAKERY", Date: date, SalesLag1: 7},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, SalesLag1: 11},
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+	h.hierarchyDataPath = filepath.Join(t.TempDir(), "missing.json")
+	if err := h.LoadHierarchyDefinition(writeHierarchyDefinition(t, testHierarchyDefinition)); err != nil {
+		t.Fatal(err)
+	}
+
+	w, root := getHierarchy(t, h, "date=2017-08-15")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Leaves scored by the model, aggregates summed through the state level
+	if root.ID != "total" || len(root.Children) != 1 || root.Children[0].Level != "state" {
+		t.Fatalf("tree does not follow the definition: %+v", root)
+	}
+	state := root.Children[0]
+	if root.Prediction != 23 || state.Prediction != 23 || state.Children[0].Prediction != 12 {
+		t.Errorf("unexpected aggregates: total=%f state=%f store_1=%f",
+			root.Prediction, state.Prediction, state.Children[0].Prediction)
+	}
+}
+
+func TestHierarchyDefinitionWithData(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+	if err := h.LoadHierarchyDefinition(writeHierarchyDefinition(t, testHierarchyDefinition)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, root := getHierarchy(t, h, "")
+
+	// Base forecasts come from the data file by ID; the state node is not in it
+	state := root.Children[0]
+	if root.Prediction != 100 || state.Prediction != 90 || state.Children[0].Prediction != 40 {
+		t.Errorf("unexpected base forecasts: total=%f state=%f store_1=%f",
+			root.Prediction, state.Prediction, state.Children[0].Prediction)
+	}
+
+	// Reconciliation runs over the defined tree
+	_, root = getHierarchy(t, h, "method=bottom_up")
+	if root.Prediction != 60 || root.Children[0].Prediction != 60 {
+		t.Errorf("expected bottom-up total 60 through the state, got %f / %f",
+			root.Prediction, root.Children[0].Prediction)
+	}
+}
+
+func TestHierarchyDefinitionWithoutForecasts(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	h.hierarchyDataPath = filepath.Join(t.TempDir(), "missing.json")
+	if err := h.LoadHierarchyDefinition(writeHierarchyDefinition(t, testHierarchyDefinition)); err != nil {
+		t.Fatal(err)
+	}
+
+	if w, _ := getHierarchy(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without data or model, got %d", w.Code)
+	}
+}
+
+func TestReloadHierarchy(t *testing.T) {
+	path := writeHierarchyDefinition(t, testHierarchyDefinition)
+	h := NewHandlers(nil, nil, nil, nil)
+	if err := h.LoadHierarchyDefinition(path); err != nil {
+		t.Fatal(err)
+	}
+
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ReloadHierarchy(w, httptest.NewRequest(http.MethodPost, "/admin/reload-hierarchy", nil))
+		return w
+	}
+
+	// An invalid file keeps the current definition
+	os.WriteFile(path, []byte(`{"levels": ["total"], "nodes": []}`), 0o644)
+	if w := reload(); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for invalid definition, got %d", w.Code)
+	}
+	if h.hierarchyDef.Load().Size() != 7 {
+		t.Error("expected previous definition to stay in use")
+	}
+
+	os.WriteFile(path, []byte(`{"levels": ["total"], "nodes": [{"id": "total", "name": "Total", "level": "total"}]}`), 0o644)
+	if w := reload(); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if h.hierarchyDef.Load().Size() != 1 {
+		t.Error("expected reloaded definition")
+	}
+}
+
+// getChildren requests /hierarchy/{nodeID}/children through a router, so escaped IDs are exercised.
+func getChildren(t *testing.T, h *Handlers, target string) (*httptest.ResponseRecorder, HierarchyChildrenResponse) {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Get("/hierarchy/{nodeID}/children", h.HierarchyChildren)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp HierarchyChildrenResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+	}
+	return w, resp
+}
+
+func TestHierarchyChildren(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+
+	t.Run("children only by default", func(t *testing.T) {
+		w, resp := getChildren(t, h, "/hierarchy/total/children")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if resp.Node.ID != "total" || resp.Node.Children != nil {
+			t.Errorf("expected total without children, got %+v", resp.Node)
+		}
+		if resp.Total != 2 || len(resp.Children) != 2 || resp.NextOffset != nil {
+			t.Errorf("expected both stores on one page, got %+v", resp)
+		}
+		if resp.Children[0].Children != nil {
+			t.Error("expected grandchildren to be omitted at depth 1")
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		_, first := getChildren(t, h, "/hierarchy/total/children?limit=1")
+		if len(first.Children) != 1 || first.Children[0].ID != "store_1" || first.NextOffset == nil || *first.NextOffset != 1 {
+			t.Fatalf("unexpected first page: %+v", first)
+		}
+		_, second := getChildren(t, h, "/hierarchy/total/children?limit=1&offset=1")
+		if len(second.Children) != 1 || second.Children[0].ID != "store_2" || second.NextOffset != nil {
+			t.Errorf("unexpected second page: %+v", second)
+		}
+		_, past := getChildren(t, h, "/hierarchy/total/children?offset=5")
+		if past.Children == nil || len(past.Children) != 0 {
+			t.Errorf("expected an empty page past the end, got %+v", past.Children)
+		}
+	})
+
+	t.Run("depth and threshold", func(t *testing.T) {
+		_, resp := getChildren(t, h, "/hierarchy/total/children?depth=2&min_prediction=15")
+		if len(resp.Children) != 2 {
+			t.Fatalf("expected both stores above threshold, got %d", len(resp.Children))
+		}
+		// 1_DAIRY (10) is below the threshold
+		store1 := resp.Children[0]
+		if len(store1.Children) != 1 || store1.Children[0].ID != "1_BREAD/BAKERY" {
+			t.Errorf("expected only 1_BREAD/BAKERY under store_1, got %+v", store1.Children)
+		}
+	})
+
+	t.Run("escaped node id", func(t *testing.T) {
+		w, resp := getChildren(t, h, "/hierarchy/1_BREAD%2FBAKERY/children")
+		if w.Code != http.StatusOK || resp.Node.ID != "1_BREAD/BAKERY" || len(resp.Children) != 0 {
+			t.Errorf("expected leaf with no children, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if w, _ := getChildren(t, h, "/hierarchy/store_99/children"); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for unknown node, got %d", w.Code)
+		}
+		for _, q := range []string{"limit=0", "limit=501", "offset=-1", "depth=0", "depth=6", "min_prediction=x"} {
+			if w, _ := getChildren(t, h, "/hierarchy/total/children?"+q); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %s, got %d", q, w.Code)
+			}
+		}
+	})
+}
+
+func TestHierarchyCache(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+	h.SetHierarchyCache(cache.NewHierarchyCache(nil, cache.HierarchyConfig{TTL: time.Hour, MaxEntries: 10}))
+
+	_, first := getHierarchy(t, h, "date=2017-08-01")
+	if first.Prediction != 100 {
+		t.Fatalf("unexpected total %f", first.Prediction)
+	}
+
+	// Served from cache even though the file changed
+	os.WriteFile(h.hierarchyDataPath, []byte(`{"id": "total", "name": "Total", "level": "total", "prediction": 7}`), 0o644)
+	if _, cached := getHierarchy(t, h, "date=2017-08-01"); cached.Prediction != 100 {
+		t.Errorf("expected cached total 100, got %f", cached.Prediction)
+	}
+
+	// Methods are cached separately and keep their header on hits
+	getHierarchy(t, h, "date=2017-08-01&method=bottom_up")
+	w, _ := getHierarchy(t, h, "date=2017-08-01&method=bottom_up")
+	if w.Header().Get(ReconciliationMethodHeader) != "bottom_up" {
+		t.Error("expected reconciliation header on cache hit")
+	}
+
+	// A feature reload invalidates the cache
+	h.invalidateHierarchy(context.Background())
+	if _, fresh := getHierarchy(t, h, "date=2017-08-01"); fresh.Prediction != 7 {
+		t.Errorf("expected reloaded total 7, got %f", fresh.Prediction)
+	}
+}
+
+func TestReloadInvalidatesHierarchyCache(t *testing.T) {
+	path := writeHierarchyDefinition(t, testHierarchyDefinition)
+	h := NewHandlers(nil, nil, nil, nil)
+	h.LoadHierarchyDefinition(path)
+	hc := cache.NewHierarchyCache(nil, cache.HierarchyConfig{TTL: time.Hour, MaxEntries: 10})
+	h.SetHierarchyCache(hc)
+	hc.Set(context.Background(), cache.HierarchyCacheKey("2017-08-01", "", 30), []byte(`{}`))
+
+	w := httptest.NewRecorder()
+	h.ReloadHierarchy(w, httptest.NewRequest(http.MethodPost, "/admin/reload-hierarchy", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if hc.Len() != 0 {
+		t.Errorf("expected reload to invalidate cached trees, %d left", hc.Len())
+	}
+}
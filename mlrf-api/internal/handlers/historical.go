@@ -1,21 +1,36 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
-	"sort"
 	"time"
 
+	"github.com/mlrf/mlrf-api/internal/history"
 	"github.com/rs/zerolog/log"
 )
 
+// Levels of /historical: a single series, or the sum over a store, family or everything.
+const (
+	HistoryLevelSeries = "series"
+	HistoryLevelStore  = AggregateLevelStore
+	HistoryLevelFamily = AggregateLevelFamily
+	HistoryLevelTotal  = AggregateLevelTotal
+)
+
+// maxHistoryDays caps the days of one /historical response.
+const maxHistoryDays = 365
+
 // HistoricalRequest for fetching historical sales data.
 type HistoricalRequest struct {
-	StoreNbr int    `json:"store_nbr"`
-	Family   string `json:"family"`
-	EndDate  string `json:"end_date"`
-	Days     int    `json:"days"` // Number of days of history
+	StoreNbr  int    `json:"store_nbr"`            // Required at series and store level
+	Family    string `json:"family"`               // Required at series and family level
+	Level     string `json:"level,omitempty"`      // series (default), store, family or total
+	StartDate string `json:"start_date,omitempty"` // Defaults to days before end_date
+	EndDate   string `json:"end_date"`             // Last day of history, inclusive
+	Days      int    `json:"days"`                 // Number of days of history without start_date
 }
 
 // HistoricalPoint represents a single historical data point.
@@ -26,147 +41,173 @@ type HistoricalPoint struct {
 
 // HistoricalResponse contains historical sales data.
 type HistoricalResponse struct {
-	Data   []HistoricalPoint `json:"data"`
-	IsMock bool              `json:"is_mock,omitempty"`
+	Level     string            `json:"level"`
+	StoreNbr  int               `json:"store_nbr,omitempty"`
+	Family    string            `json:"family,omitempty"`
+	StartDate string            `json:"start_date"`
+	EndDate   string            `json:"end_date"`
+	Data      []HistoricalPoint `json:"data"` // One point per day with sales data
+	IsMock    bool              `json:"is_mock,omitempty"`
+}
+
+// LoadHistory loads the daily sales history served by /historical: the preprocessed
+// training data (parquet) or a JSON file of "<store_nbr>_<family>_<date>" keys.
+// The path is remembered for /admin/reload-all even if loading fails.
+func (h *Handlers) LoadHistory(path string) error {
+	h.SetHistoryLoader(func(context.Context) (*history.Store, error) {
+		return history.Load(path)
+	})
+	if _, err := h.RefreshHistory(context.Background()); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load sales history")
+		return err
+	}
+	return nil
+}
+
+// SetHistoryLoader sets how /admin/reload-all reads the sales history again, e.g.
+// from a database table.
+func (h *Handlers) SetHistoryLoader(load func(context.Context) (*history.Store, error)) {
+	h.historyLoader = load
+}
+
+// RefreshHistory reads the sales history again. On failure the current history keeps
+// serving.
+func (h *Handlers) RefreshHistory(ctx context.Context) (history.Info, error) {
+	if h.historyLoader == nil {
+		return history.Info{}, errors.New("sales history not configured")
+	}
+	s, err := h.historyLoader(ctx)
+	if err != nil {
+		return history.Info{}, err
+	}
+	h.SetHistory(s)
+	return s.Info(), nil
 }
 
-// historicalData stores pre-loaded historical sales data.
-// Key format: "storeNbr_family_date" -> sales value
-var historicalData map[string]float64
+// SetHistory sets the daily sales history served by /historical.
+func (h *Handlers) SetHistory(s *history.Store) {
+	h.history.Store(s)
+	info := s.Info()
+	log.Info().
+		Str("source", info.Source).
+		Int("series", info.Series).
+		Int("rows", info.Rows).
+		Str("first_date", info.FirstDate).
+		Str("last_date", info.LastDate).
+		Msg("Sales history loaded")
+}
 
-// Historical returns historical sales data for a store/family combination.
+// Historical returns the daily sales history of a series, or of a store, family or
+// the total summed over their series.
 func (h *Handlers) Historical(w http.ResponseWriter, r *http.Request) {
 	var req HistoricalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	// Validate request
-	if req.StoreNbr <= 0 || req.StoreNbr > 54 {
-		WriteBadRequest(w, r, "store_nbr must be between 1 and 54", CodeInvalidStore)
-		return
-	}
-	if req.Family == "" {
-		WriteBadRequest(w, r, "family is required", CodeInvalidFamily)
+	level, series, verr := h.historySeries(req.Level, req.StoreNbr, req.Family)
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
 		return
 	}
-	if req.Days <= 0 {
-		req.Days = 28 // Default to 4 weeks of history
-	}
-	if req.Days > 365 {
-		req.Days = 365 // Cap at 1 year
-	}
 
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	endDate, err := time.Parse(DateFormat, req.EndDate)
 	if err != nil {
 		WriteBadRequest(w, r, "end_date must be in YYYY-MM-DD format", CodeInvalidDate)
 		return
 	}
-
-	// Try to get real historical data
-	points, isMock := h.getHistoricalData(req.StoreNbr, req.Family, endDate, req.Days)
-
-	if isMock {
-		log.Warn().
-			Int("store_nbr", req.StoreNbr).
-			Str("family", req.Family).
-			Msg("Returning mock historical data")
+	var startDate time.Time
+	if req.StartDate != "" {
+		if startDate, err = time.Parse(DateFormat, req.StartDate); err != nil {
+			WriteBadRequest(w, r, "start_date must be in YYYY-MM-DD format", CodeInvalidDate)
+			return
+		}
+		if startDate.After(endDate) {
+			WriteBadRequest(w, r, "start_date must not be after end_date", CodeInvalidDate)
+			return
+		}
+		if days := int(endDate.Sub(startDate).Hours()/24) + 1; days > maxHistoryDays {
+			WriteBadRequest(w, r, fmt.Sprintf("history range is %d days, more than %d", days, maxHistoryDays), CodeInvalidDate)
+			return
+		}
+	} else {
+		if req.Days <= 0 {
+			req.Days = 28 // Default to 4 weeks of history
+		}
+		req.Days = min(req.Days, maxHistoryDays)
+		startDate = endDate.AddDate(0, 0, 1-req.Days)
 	}
 
 	resp := HistoricalResponse{
-		Data:   points,
-		IsMock: isMock,
+		Level:     level,
+		StoreNbr:  series.StoreNbr,
+		Family:    series.Family,
+		StartDate: startDate.Format(DateFormat),
+		EndDate:   endDate.Format(DateFormat),
+	}
+	if s := h.history.Load(); s != nil {
+		// A series missing from the history has no sales to show, not made-up ones
+		points, _ := s.Range(series, startDate, endDate)
+		resp.Data = make([]HistoricalPoint, len(points))
+		for i, p := range points {
+			resp.Data[i] = HistoricalPoint{Date: p.Date, Actual: p.Sales}
+		}
+	} else {
+		log.Warn().
+			Int("store_nbr", series.StoreNbr).
+			Str("family", series.Family).
+			Msg("Returning mock historical data")
+		if !h.allowMock(w, r, "historical") {
+			return
+		}
+		resp.Data = generateMockHistorical(startDate, endDate)
+		resp.IsMock = true
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
-// getHistoricalData retrieves historical data from loaded data or generates mock.
-func (h *Handlers) getHistoricalData(storeNbr int, family string, endDate time.Time, days int) ([]HistoricalPoint, bool) {
-	points := make([]HistoricalPoint, 0, days)
-
-	// Try to load from pre-computed historical data file
-	historicalFile := os.Getenv("HISTORICAL_DATA_PATH")
-	if historicalFile == "" {
-		historicalFile = "models/historical_data.json"
+// historySeries validates a history level and the store and family it needs, and
+// returns the level, defaulting to series, and the series or aggregate to read.
+func (h *Handlers) historySeries(level string, storeNbr int, family string) (string, history.Series, *ValidationError) {
+	if level == "" {
+		level = HistoryLevelSeries
 	}
-
-	// Load historical data if not already loaded
-	if historicalData == nil {
-		data, err := os.ReadFile(historicalFile)
-		if err == nil {
-			var loaded map[string]float64
-			if json.Unmarshal(data, &loaded) == nil {
-				historicalData = loaded
-				log.Info().Int("entries", len(historicalData)).Msg("Loaded historical data")
-			}
+	var series history.Series
+	switch level {
+	case HistoryLevelSeries, HistoryLevelStore, HistoryLevelFamily, HistoryLevelTotal:
+	default:
+		return level, series, &ValidationError{
+			Message: fmt.Sprintf("level must be %s, %s, %s or %s", HistoryLevelSeries, HistoryLevelStore, HistoryLevelFamily, HistoryLevelTotal),
+			Code:    CodeInvalidRequest,
 		}
 	}
-
-	// Try to get data from feature store (using lag features as proxy for historical sales)
-	if h.featureStore != nil {
-		for i := days; i > 0; i -= 7 { // Weekly intervals
-			date := endDate.AddDate(0, 0, -i)
-			dateStr := date.Format("2006-01-02")
-
-			// Try pre-loaded historical data first
-			if historicalData != nil {
-				key := formatHistoricalKey(storeNbr, family, dateStr)
-				if val, ok := historicalData[key]; ok {
-					points = append(points, HistoricalPoint{
-						Date:   dateStr,
-						Actual: val,
-					})
-					continue
-				}
-			}
-
-			// Fall back to feature store - use sales_lag_7 as proxy
-			features, found := h.featureStore.GetFeatures(storeNbr, family, dateStr)
-			if found && len(features) > 13 {
-				// Index 13 is sales_lag_7 in the feature vector
-				salesLag7 := float64(features[13])
-				if salesLag7 > 0 {
-					points = append(points, HistoricalPoint{
-						Date:   dateStr,
-						Actual: salesLag7,
-					})
-				}
-			}
+	if level == HistoryLevelSeries || level == HistoryLevelStore {
+		if err := h.validateStoreNbr(storeNbr); err != nil {
+			return level, series, err
 		}
+		series.StoreNbr = storeNbr
 	}
-
-	// If we got some real data, return it
-	if len(points) > 0 {
-		// Sort by date
-		sort.Slice(points, func(i, j int) bool {
-			return points[i].Date < points[j].Date
-		})
-		return points, false
+	if level == HistoryLevelSeries || level == HistoryLevelFamily {
+		if family == "" {
+			return level, series, &ValidationError{Message: "family is required", Code: CodeInvalidFamily}
+		}
+		series.Family = family
 	}
-
-	// Generate mock data as fallback
-	return generateMockHistorical(endDate, days), true
-}
-
-// formatHistoricalKey creates a lookup key for historical data.
-func formatHistoricalKey(storeNbr int, family, date string) string {
-	return string(rune(storeNbr)) + "_" + family + "_" + date
+	return level, series, nil
 }
 
-// generateMockHistorical creates mock historical data for demo purposes.
-func generateMockHistorical(endDate time.Time, days int) []HistoricalPoint {
+// generateMockHistorical creates daily mock historical data for demo purposes.
+func generateMockHistorical(startDate, endDate time.Time) []HistoricalPoint {
 	points := make([]HistoricalPoint, 0)
 	baseValue := 45000.0
 
-	for i := days; i > 0; i -= 7 {
-		date := endDate.AddDate(0, 0, -i)
-		// Add some variation to make it look realistic
-		variation := 1.0 + (float64(i%14)-7.0)*0.02
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		// Add a weekly pattern to make it look realistic
+		variation := 1.0 + (float64(date.Weekday())-3.0)*0.02
 		points = append(points, HistoricalPoint{
-			Date:   date.Format("2006-01-02"),
+			Date:   date.Format(DateFormat),
 			Actual: baseValue * variation,
 		})
 	}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func postHistorical(h *Handlers, body string) (*httptest.ResponseRecorder, HistoricalResponse) {
+	w := httptest.NewRecorder()
+	h.Historical(w, httptest.NewRequest(http.MethodPost, "/v1/historical", strings.NewReader(body)))
+	var resp HistoricalResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestHistorical(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "historical_data.json")
+	if err := os.WriteFile(path, []byte(`{
+		"1_DAIRY_2017-08-13": 10, "1_DAIRY_2017-08-14": 11, "1_DAIRY_2017-08-15": 12,
+		"1_BREAD/BAKERY_2017-08-15": 3, "12_DAIRY_2017-08-15": 20
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlers(nil, nil, nil, nil)
+	if err := h.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+
+	// Every day up to and including end_date, for store 12 too
+	w, resp := postHistorical(h, `{"store_nbr":1,"family":"DAIRY","end_date":"2017-08-15","days":2}`)
+	if w.Code != http.StatusOK || resp.IsMock || resp.StartDate != "2017-08-14" || len(resp.Data) != 2 || resp.Data[1].Actual != 12 {
+		t.Errorf("unexpected series history %d %+v", w.Code, resp)
+	}
+	if _, resp := postHistorical(h, `{"store_nbr":12,"family":"DAIRY","end_date":"2017-08-15"}`); len(resp.Data) != 1 || resp.Data[0].Actual != 20 {
+		t.Errorf("unexpected store 12 history %+v", resp.Data)
+	}
+
+	tests := []struct {
+		body string
+		want float64
+	}{
+		{`{"level":"store","store_nbr":1,"start_date":"2017-08-15","end_date":"2017-08-15"}`, 15},
+		{`{"level":"family","family":"DAIRY","start_date":"2017-08-15","end_date":"2017-08-15"}`, 32},
+		{`{"level":"total","start_date":"2017-08-15","end_date":"2017-08-15"}`, 35},
+	}
+	for _, tt := range tests {
+		if w, resp := postHistorical(h, tt.body); w.Code != http.StatusOK || len(resp.Data) != 1 || resp.Data[0].Actual != tt.want {
+			t.Errorf("%s: expected %v, got %d %+v", tt.body, tt.want, w.Code, resp.Data)
+		}
+	}
+
+	// A series without history is empty rather than made up
+	if w, resp := postHistorical(h, `{"store_nbr":2,"family":"DAIRY","end_date":"2017-08-15"}`); w.Code != http.StatusOK || resp.IsMock || resp.Data == nil || len(resp.Data) != 0 {
+		t.Errorf("expected empty history, got %d %+v", w.Code, resp)
+	}
+
+	for body, code := range map[string]string{
+		`{"level":"region","end_date":"2017-08-15"}`:                                         CodeInvalidRequest,
+		`{"level":"store","end_date":"2017-08-15"}`:                                          CodeInvalidStore,
+		`{"store_nbr":1,"end_date":"2017-08-15"}`:                                            CodeInvalidFamily,
+		`{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-15"}`: CodeInvalidDate,
+		`{"store_nbr":1,"family":"DAIRY","start_date":"2016-01-01","end_date":"2017-08-15"}`: CodeInvalidDate,
+		`{"store_nbr":1,"family":"DAIRY","end_date":"15/08/2017"}`:                           CodeInvalidDate,
+	} {
+		w, _ := postHistorical(h, body)
+		var errResp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &errResp)
+		if w.Code != http.StatusBadRequest || errResp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", body, code, w.Code, errResp.Code)
+		}
+	}
+}
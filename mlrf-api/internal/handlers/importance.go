@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultImportanceSamplePerFamily is the number of series per family whose latest
+// features are explained when global importance is computed rather than loaded.
+const DefaultImportanceSamplePerFamily = 10
+
+var errImportanceUnavailable = errors.New("no SHAP engine available")
+
+// FeatureImportance is the mean absolute SHAP value of a feature.
+type FeatureImportance struct {
+	Feature     string  `json:"feature"`
+	MeanAbsShap float64 `json:"mean_abs_shap"`
+}
+
+// GlobalImportance is the mean |SHAP| importance of every feature overall and per
+// family. It is the format of GLOBAL_IMPORTANCE_PATH, written by training.
+type GlobalImportance struct {
+	GeneratedAt time.Time                      `json:"generated_at"`
+	SampleSize  int                            `json:"sample_size"` // Explained feature vectors
+	Overall     []FeatureImportance            `json:"overall"`     // Most important first
+	Families    map[string][]FeatureImportance `json:"families"`
+
+	source string // file or computed
+	engine string // Engine of computed importance: sidecar or native
+}
+
+// GlobalImportanceResponse is returned by /explain/global.
+type GlobalImportanceResponse struct {
+	Source      string                         `json:"source"`           // file (precomputed by training) or computed (from the feature store)
+	Engine      string                         `json:"engine,omitempty"` // SHAP engine of computed importance
+	GeneratedAt time.Time                      `json:"generated_at"`
+	SampleSize  int                            `json:"sample_size"`
+	Family      string                         `json:"family,omitempty"`
+	Features    []FeatureImportance            `json:"features"`           // Overall, or of Family; most important first
+	Families    map[string][]FeatureImportance `json:"families,omitempty"` // Per family, unless Family is set
+}
+
+// computedImportance is global importance computed for a data revision.
+type computedImportance struct {
+	revision   uint64
+	importance *GlobalImportance
+}
+
+// LoadGlobalImportance loads precomputed global feature importance.
+// This is optional - without it, /explain/global computes importance over a sample of
+// the feature store.
+func (h *Handlers) LoadGlobalImportance(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load global feature importance")
+		return err
+	}
+	var gi GlobalImportance
+	if err := json.Unmarshal(data, &gi); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not parse global feature importance")
+		return err
+	}
+	if len(gi.Overall) == 0 {
+		err := fmt.Errorf("%s: no overall importance", path)
+		log.Warn().Err(err).Msg("Could not load global feature importance")
+		return err
+	}
+	gi.source = "file"
+	sortImportance(gi.Overall)
+	for _, fi := range gi.Families {
+		sortImportance(fi)
+	}
+	h.globalImportance = &gi
+	log.Info().Str("path", path).Int("families", len(gi.Families)).Int("sample_size", gi.SampleSize).Msg("Loaded global feature importance")
+	return nil
+}
+
+// GlobalImportance returns the mean |SHAP| importance of each feature overall and per
+// family, for a global importance chart. ?family= returns one family's importance.
+// Importance comes from the file written by training or, without one, is computed over
+// the latest features of a sample of series per family and kept until the features or
+// model change.
+func (h *Handlers) GlobalImportance(w http.ResponseWriter, r *http.Request) {
+	family := r.URL.Query().Get("family")
+	if family != "" {
+		if err := ValidateFamily(family); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+
+	gi := h.globalImportance
+	if gi == nil {
+		if h.featureStore == nil || !h.featureStore.IsLoaded() {
+			WriteServiceUnavailable(w, r, "feature store not available", CodeFeatureStoreUnavailable)
+			return
+		}
+		if h.notModified(w, r) {
+			return
+		}
+		var err error
+		if gi, err = h.computedGlobalImportance(r.Context()); err != nil {
+			if errors.Is(err, errImportanceUnavailable) {
+				WriteServiceUnavailable(w, r, "SHAP service not available", CodeShapUnavailable)
+				return
+			}
+			WriteInternalError(w, r, "global importance failed: "+err.Error(), CodeShapError)
+			return
+		}
+	} else if h.notModified(w, r) {
+		return
+	}
+
+	resp := GlobalImportanceResponse{
+		Source:      gi.source,
+		Engine:      gi.engine,
+		GeneratedAt: gi.GeneratedAt,
+		SampleSize:  gi.SampleSize,
+		Features:    gi.Overall,
+		Families:    gi.Families,
+	}
+	if family != "" {
+		fi, ok := gi.Families[family]
+		if !ok {
+			WriteError(w, r, http.StatusNotFound, "no importance for family "+family, CodeFeatureNotFound)
+			return
+		}
+		resp.Family, resp.Features, resp.Families = family, fi, nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// computedGlobalImportance returns importance computed for the current data revision,
+// computing it when the features or model changed since.
+func (h *Handlers) computedGlobalImportance(ctx context.Context) (*GlobalImportance, error) {
+	h.importanceMu.Lock()
+	defer h.importanceMu.Unlock()
+
+	revision := h.revision.Load()
+	if c := h.importanceCache; c != nil && c.revision == revision {
+		return c.importance, nil
+	}
+	gi, err := h.computeGlobalImportance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.importanceCache = &computedImportance{revision: revision, importance: gi}
+	return gi, nil
+}
+
+// computeGlobalImportance explains the latest features of up to importanceSample
+// series per family, with native TreeSHAP when loaded and the SHAP service otherwise,
+// and averages the absolute SHAP values.
+func (h *Handlers) computeGlobalImportance(ctx context.Context) (*GlobalImportance, error) {
+	var explain func(s features.Series, vec []float32) ([]float64, error)
+	engine := EngineNative
+	switch {
+	case h.treeSHAP != nil:
+		explain = func(_ features.Series, vec []float32) ([]float64, error) {
+			return h.treeSHAP.SHAP(vec)
+		}
+	case h.shapClient != nil:
+		engine = EngineSidecar
+		explain = func(s features.Series, vec []float32) ([]float64, error) {
+			resp, err := h.shapClient.Explain(ctx, s.StoreNbr, s.Family, "", vec)
+			if err != nil {
+				return nil, err
+			}
+			// Features outside the waterfall (summed into "Other") count as zero
+			values := make([]float64, schema.NumFeatures)
+			for _, f := range resp.Features {
+				if i, ok := schema.Features.Index(f.Name); ok {
+					values[i] = f.ShapValue
+				}
+			}
+			return values, nil
+		}
+	default:
+		return nil, errImportanceUnavailable
+	}
+
+	byFamily := make(map[string][]features.Series)
+	for _, s := range h.featureStore.Series() {
+		byFamily[s.Family] = append(byFamily[s.Family], s)
+	}
+
+	overall := make([]float64, schema.NumFeatures)
+	gi := &GlobalImportance{
+		GeneratedAt: time.Now().UTC(),
+		Families:    make(map[string][]FeatureImportance, len(byFamily)),
+		source:      "computed",
+		engine:      engine,
+	}
+	var lastErr error
+	for family, series := range byFamily {
+		sums := make([]float64, schema.NumFeatures)
+		n := 0
+		for _, s := range sampleSeries(series, h.importanceSample) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			date, ok := h.featureStore.LastDate(s.StoreNbr, s.Family)
+			if !ok {
+				continue
+			}
+			vec, ok := h.featureStore.Lookup(s.StoreNbr, s.Family, date)
+			if !ok {
+				continue
+			}
+			values, err := explain(s, vec)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			for i, v := range values {
+				sums[i] += math.Abs(v)
+				overall[i] += math.Abs(v)
+			}
+			n++
+		}
+		if n > 0 {
+			gi.Families[family] = importanceOf(sums, n)
+			gi.SampleSize += n
+		}
+	}
+	if gi.SampleSize == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("no feature vectors to explain")
+	}
+	gi.Overall = importanceOf(overall, gi.SampleSize)
+
+	log.Info().
+		Str("engine", engine).
+		Int("sample_size", gi.SampleSize).
+		Int("families", len(gi.Families)).
+		Msg("Computed global feature importance")
+	return gi, nil
+}
+
+// sampleSeries returns up to n series spread evenly over series.
+func sampleSeries(series []features.Series, n int) []features.Series {
+	if len(series) <= n {
+		return series
+	}
+	out := make([]features.Series, n)
+	for i := range out {
+		out[i] = series[i*len(series)/n]
+	}
+	return out
+}
+
+// importanceOf turns sums of absolute SHAP values over n vectors into importance,
+// most important first.
+func importanceOf(sums []float64, n int) []FeatureImportance {
+	out := make([]FeatureImportance, len(sums))
+	for i, sum := range sums {
+		out[i] = FeatureImportance{Feature: schema.Features.Column(i).Name, MeanAbsShap: sum / float64(n)}
+	}
+	sortImportance(out)
+	return out
+}
+
+// sortImportance orders importance most important first.
+func sortImportance(fi []FeatureImportance) {
+	sort.SliceStable(fi, func(i, j int) bool { return fi[i].MeanAbsShap > fi[j].MeanAbsShap })
+}
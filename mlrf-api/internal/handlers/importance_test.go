@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func getGlobalImportance(h *Handlers, query string) (*httptest.ResponseRecorder, GlobalImportanceResponse) {
+	w := httptest.NewRecorder()
+	h.GlobalImportance(w, httptest.NewRequest(http.MethodGet, "/explain/global"+query, nil))
+	var resp GlobalImportanceResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestGlobalImportanceComputed(t *testing.T) {
+	h := newExplainHandlers(t, nil)
+
+	w, resp := getGlobalImportance(h, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Source != "computed" || resp.Engine != EngineNative || resp.SampleSize != 1 {
+		t.Errorf("unexpected source %q, engine %q or sample size %d", resp.Source, resp.Engine, resp.SampleSize)
+	}
+	if len(resp.Features) < 2 {
+		t.Fatalf("expected importance of every feature, got %+v", resp.Features)
+	}
+	first, second := resp.Features[0], resp.Features[1]
+	if first.Feature != "sales_lag_1" || math.Abs(first.MeanAbsShap-76) > 1e-9 {
+		t.Errorf("unexpected first feature %+v", first)
+	}
+	if second.Feature != "oil_price" || math.Abs(second.MeanAbsShap-5) > 1e-9 {
+		t.Errorf("unexpected second feature %+v", second)
+	}
+	if _, ok := resp.Families["GROCERY I"]; !ok {
+		t.Errorf("expected GROCERY I importance, got %v", resp.Families)
+	}
+
+	// A family returns only its importance
+	w, resp = getGlobalImportance(h, "?family=GROCERY+I")
+	if w.Code != http.StatusOK || resp.Family != "GROCERY I" || resp.Families != nil || resp.Features[0].Feature != "sales_lag_1" {
+		t.Errorf("unexpected family response %d %+v", w.Code, resp)
+	}
+
+	w, _ = getGlobalImportance(h, "?family=BEVERAGES")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a family without data, got %d", w.Code)
+	}
+	w, _ = getGlobalImportance(h, "?family=NOPE")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid family, got %d", w.Code)
+	}
+
+	// Without a SHAP engine there is nothing to compute with
+	h.treeSHAP = nil
+	h.importanceCache = nil
+	if w, _ = getGlobalImportance(h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a SHAP engine, got %d", w.Code)
+	}
+}
+
+func TestGlobalImportanceFile(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	path := filepath.Join(t.TempDir(), "global_importance.json")
+	data := `{
+  "generated_at": "2024-01-15T00:00:00Z",
+  "sample_size": 500,
+  "overall": [{"feature": "oil_price", "mean_abs_shap": 2}, {"feature": "sales_lag_1", "mean_abs_shap": 30}],
+  "families": {"BEVERAGES": [{"feature": "sales_lag_1", "mean_abs_shap": 40}]}
+}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadGlobalImportance(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The file is served without a feature store
+	w, resp := getGlobalImportance(h, "")
+	if w.Code != http.StatusOK || resp.Source != "file" || resp.SampleSize != 500 {
+		t.Fatalf("unexpected response %d %+v", w.Code, resp)
+	}
+	if resp.Features[0].Feature != "sales_lag_1" {
+		t.Errorf("expected importance sorted most important first, got %+v", resp.Features)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"overall": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewHandlers(nil, nil, nil, nil).LoadGlobalImportance(path); err == nil {
+		t.Error("expected an error for a file without overall importance")
+	}
+}
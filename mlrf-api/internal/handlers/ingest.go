@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/ingest"
+	"github.com/mlrf/mlrf-api/internal/live"
+)
+
+// SetIngestConsumer reports streamed feature ingestion in /health.
+func (h *Handlers) SetIngestConsumer(c *ingest.Consumer) {
+	h.ingest = c
+}
+
+// ApplyFeatureRows merges streamed feature rows into the feature store. Results cached
+// from the previous features are left to FeaturesAppended, which the consumer calls
+// at most once per INGEST_INVALIDATE_INTERVAL rather than for every batch.
+// Returns an error wrapping features.ErrNotLoaded until the store is loaded.
+func (h *Handlers) ApplyFeatureRows(_ context.Context, rows []features.FeatureRow) (features.AppendResult, error) {
+	if h.featureStore == nil {
+		return features.AppendResult{}, errors.New("feature store not configured")
+	}
+	return h.featureStore.AppendRows(rows, "stream")
+}
+
+// FeaturesAppended invalidates results computed from the features before an append
+// and pushes fresh predictions to live subscribers.
+func (h *Handlers) FeaturesAppended(ctx context.Context) {
+	h.RotateCacheNamespace()
+	h.startCacheWarm()
+	h.invalidateHierarchy(ctx)
+	h.invalidateBacktests()
+	h.notifyLive(live.ReasonFeaturesAppended)
+}
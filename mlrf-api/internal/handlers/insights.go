@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultTopMovers is the number of movers returned when no limit is given.
+	DefaultTopMovers = 10
+
+	// MaxTopMovers caps the number of movers per ranking.
+	MaxTopMovers = 100
+)
+
+// TopMoversRequest selects the series and dates to compare.
+type TopMoversRequest struct {
+	Date        string   `json:"date,omitempty"`         // Defaults to the feature store's latest date
+	CompareDays int      `json:"compare_days,omitempty"` // Days back to compare against (default 7)
+	Horizon     int      `json:"horizon,omitempty"`      // Default 30
+	StoreNbrs   []int    `json:"store_nbrs,omitempty"`   // Filter; empty = all stores
+	Families    []string `json:"families,omitempty"`     // Filter; empty = all families
+	Limit       int      `json:"limit,omitempty"`        // Top-N per ranking (default 10, max 100)
+}
+
+// Mover is a series' prediction change between the compare date and the date.
+type Mover struct {
+	StoreNbr           int     `json:"store_nbr"`
+	Family             string  `json:"family"`
+	Prediction         float32 `json:"prediction"`
+	PreviousPrediction float32 `json:"previous_prediction"`
+	Delta              float32 `json:"delta"`
+	DeltaPct           float32 `json:"delta_pct"`
+}
+
+// TopMoversResponse ranks the series that changed most.
+type TopMoversResponse struct {
+	Date           string  `json:"date"`
+	CompareDate    string  `json:"compare_date"`
+	Horizon        int     `json:"horizon"`
+	SeriesScored   int     `json:"series_scored"`
+	PreviousCached int     `json:"previous_cached"` // Prior predictions served from cache
+	ByAbsolute     []Mover `json:"by_absolute_delta"`
+	ByPercent      []Mover `json:"by_percent_delta"` // Excludes series with a zero previous prediction
+	LatencyMs      float64 `json:"latency_ms"`
+}
+
+// TopMovers scores every (store, family) series, or a filtered subset, for a date and
+// the same date compare_days earlier, and returns the top-N by absolute and percentage
+// change. Prior predictions are read from the cache when available.
+func (h *Handlers) TopMovers(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req TopMoversRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+		return
+	}
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	if req.Date == "" {
+		req.Date = h.featureStore.GetMetadata().DataDateMax
+	}
+	if req.Horizon == 0 {
+		req.Horizon = 30
+	}
+	if req.CompareDays == 0 {
+		req.CompareDays = 7
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultTopMovers
+	}
+
+	if err := ValidateDate(req.Date); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateHorizon(req.Horizon); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if req.CompareDays < 1 || req.CompareDays > 365 {
+		WriteBadRequest(w, r, "compare_days must be between 1 and 365", CodeInvalidRequest)
+		return
+	}
+	if req.Limit < 1 || req.Limit > MaxTopMovers {
+		WriteBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", MaxTopMovers), CodeInvalidRequest)
+		return
+	}
+	for _, storeNbr := range req.StoreNbrs {
+		if err := h.validateStoreNbr(storeNbr); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+	for _, family := range req.Families {
+		if err := ValidateFamily(family); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+	}
+
+	date, _ := time.Parse(DateFormat, req.Date)
+	compareDate := date.AddDate(0, 0, -req.CompareDays).Format(DateFormat)
+
+	series := filterSeries(h.featureStore.Series(), req.StoreNbrs, req.Families)
+	items := make([]SimplePredictRequest, 0, 2*len(series))
+	for _, s := range series {
+		items = append(items,
+			SimplePredictRequest{StoreNbr: s.StoreNbr, Family: s.Family, Date: req.Date, Horizon: req.Horizon},
+			SimplePredictRequest{StoreNbr: s.StoreNbr, Family: s.Family, Date: compareDate, Horizon: req.Horizon},
+		)
+	}
+
+	predictions, cached, err := h.scoreSeries(r.Context(), items)
+	if errors.Is(err, inference.ErrInvalidPrediction) {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("top movers inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+
+	movers := make([]Mover, len(series))
+	previousCached := 0
+	for i, s := range series {
+		current, previous := predictions[2*i], predictions[2*i+1]
+		if cached[2*i+1] {
+			previousCached++
+		}
+		movers[i] = Mover{
+			StoreNbr:           s.StoreNbr,
+			Family:             s.Family,
+			Prediction:         current,
+			PreviousPrediction: previous,
+			Delta:              current - previous,
+		}
+		if previous != 0 {
+			movers[i].DeltaPct = (current - previous) / previous * 100
+		}
+	}
+
+	absDelta := func(m Mover) (float64, bool) {
+		return math.Abs(float64(m.Delta)), true
+	}
+	pctDelta := func(m Mover) (float64, bool) {
+		return math.Abs(float64(m.DeltaPct)), m.PreviousPrediction != 0
+	}
+
+	resp := TopMoversResponse{
+		Date:           req.Date,
+		CompareDate:    compareDate,
+		Horizon:        req.Horizon,
+		SeriesScored:   len(series),
+		PreviousCached: previousCached,
+		ByAbsolute:     topMovers(movers, req.Limit, absDelta),
+		ByPercent:      topMovers(movers, req.Limit, pctDelta),
+		LatencyMs:      float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// filterSeries keeps series matching the store and family filters. Empty filters match everything.
+func filterSeries(series []features.Series, storeNbrs []int, families []string) []features.Series {
+	stores := make(map[int]bool, len(storeNbrs))
+	for _, s := range storeNbrs {
+		stores[s] = true
+	}
+	fams := make(map[string]bool, len(families))
+	for _, f := range families {
+		fams[f] = true
+	}
+
+	filtered := series[:0:0]
+	for _, s := range series {
+		if (len(stores) == 0 || stores[s.StoreNbr]) && (len(fams) == 0 || fams[s.Family]) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// topMovers returns up to n movers ordered by descending score, skipping those
+// the score function marks as not rankable. Ties keep series order.
+func topMovers(movers []Mover, n int, score func(Mover) (float64, bool)) []Mover {
+	ranked := make([]Mover, 0, len(movers))
+	for _, m := range movers {
+		if _, ok := score(m); ok {
+			ranked = append(ranked, m)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, _ := score(ranked[i])
+		b, _ := score(ranked[j])
+		return a > b
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// scoreSeries predicts series from feature store features with the champion model.
+// Cached predictions (as written by /predict/simple) are reused; the rest are scored
+// in one batch and cached. Returns predictions in input order and which were cached.
+func (h *Handlers) scoreSeries(ctx context.Context, items []SimplePredictRequest) ([]float32, []bool, error) {
+	predictions := make([]float32, len(items))
+	cached := make([]bool, len(items))
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = cache.GenerateCacheKey(item.StoreNbr, item.Family, item.Date, item.Horizon)
+	}
+
+	if h.cache != nil {
+		hits, err := h.cache.GetPredictions(ctx, keys)
+		if err != nil {
+			log.Warn().Err(err).Msg("series cache lookup failed")
+		}
+		for i, key := range keys {
+			if hit, ok := hits[key]; ok {
+				predictions[i] = hit.Prediction
+				cached[i] = true
+			}
+		}
+	}
+
+	var misses []int
+	var lookups []features.FeatureKey
+	for i, item := range items {
+		if cached[i] {
+			continue
+		}
+		misses = append(misses, i)
+		lookups = append(lookups, features.FeatureKey{StoreNbr: item.StoreNbr, Family: item.Family, Date: item.Date})
+	}
+	if len(misses) == 0 {
+		return predictions, cached, nil
+	}
+
+	resolved := h.lookupFeaturesBatch(ctx, lookups)
+	batch := make([][]float32, len(resolved))
+	families := make([]string, len(resolved))
+	for j, r := range resolved {
+		batch[j] = r.Features
+		families[j] = lookups[j].Family
+	}
+
+	scored, err := inference.PredictBatch(inference.WithMetricLabels(ctx, "", families...), h.onnx, batch)
+	if err != nil {
+		return nil, nil, err
+	}
+	guards, err := h.guardBatch(scored)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	toCache := make(map[string]*cache.PredictionResult, len(misses))
+	for j, i := range misses {
+		predictions[i] = scored[j]
+		toCache[keys[i]] = &cache.PredictionResult{
+			StoreNbr:      items[i].StoreNbr,
+			Family:        items[i].Family,
+			Date:          items[i].Date,
+			Horizon:       items[i].Horizon,
+			Prediction:    scored[j],
+			FeatureSource: string(resolved[j].Source),
+			Guard:         guards[j],
+		}
+	}
+	if h.cache != nil {
+		if err := h.cache.SetPredictions(ctx, cache.EndpointInsights, toCache); err != nil {
+			log.Warn().Err(err).Msg("failed to cache series predictions")
+		}
+	}
+
+	return predictions, cached, nil
+}
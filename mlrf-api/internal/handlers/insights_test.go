@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/parquet-go/parquet-go"
+)
+
+// newTestFeatureStore writes rows to a temporary parquet file and loads it.
+func newTestFeatureStore(t *testing.T, rows []features.FeatureRow) *features.Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "features.parquet")
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatalf("failed to write parquet: %v", err)
+	}
+	store, err := features.NewStore(path)
+	if err != nil {
+		t.Fatalf("failed to load feature store: %v", err)
+	}
+	return store
+}
+
+// lagInferencer predicts the sales_lag_1 feature, so tests control predictions through features.
+type lagInferencer struct{}
+
+func (lagInferencer) Predict(f []float32) (float32, error) { return f[12], nil }
+
+func (l lagInferencer) PredictBatch(batch [][]float32) ([]float32, error) {
+	out := make([]float32, len(batch))
+	for i, f := range batch {
+		out[i], _ = l.Predict(f)
+	}
+	return out, nil
+}
+
+func TestTopMovers(t *testing.T) {
+	now := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	prior := now.AddDate(0, 0, -7)
+	row := func(store int32, family string, date time.Time, lag float64) features.FeatureRow {
+		return features.FeatureRow{StoreNbr: store, Family: family, Date: date, SalesLag1: lag}
+	}
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		row(1, "DAIRY", prior, 100), row(1, "DAIRY", now, 110), // +10, +10%
+		row(2, "DAIRY", prior, 1000), row(2, "DAIRY", now, 800), // -200, -20%
+		row(3, "BEVERAGES", prior, 10), row(3, "BEVERAGES", now, 30), // +20, +200%
+		row(4, "BEVERAGES", prior, 0), row(4, "BEVERAGES", now, 5), // +5, no percentage
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.TopMovers(w, httptest.NewRequest(http.MethodPost, "/v1/insights/top-movers", bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := post(`{"limit": 2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp TopMoversResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.Date != "2017-08-15" || resp.CompareDate != "2017-08-08" || resp.SeriesScored != 4 {
+		t.Errorf("unexpected summary %+v", resp)
+	}
+	if len(resp.ByAbsolute) != 2 || resp.ByAbsolute[0].StoreNbr != 2 || resp.ByAbsolute[1].StoreNbr != 3 {
+		t.Errorf("unexpected absolute ranking %+v", resp.ByAbsolute)
+	}
+	if resp.ByAbsolute[0].Delta != -200 {
+		t.Errorf("expected delta -200, got %f", resp.ByAbsolute[0].Delta)
+	}
+	if len(resp.ByPercent) != 2 || resp.ByPercent[0].StoreNbr != 3 || resp.ByPercent[1].StoreNbr != 2 {
+		t.Errorf("unexpected percent ranking %+v", resp.ByPercent)
+	}
+
+	// Filter to a family
+	w = post(`{"families": ["DAIRY"]}`)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.SeriesScored != 2 || len(resp.ByAbsolute) != 2 {
+		t.Errorf("expected 2 DAIRY series, got %+v", resp)
+	}
+
+	if w := post(`{"limit": 1000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for oversized limit, got %d", w.Code)
+	}
+}
+
+func TestTopMoversWithoutFeatureStore(t *testing.T) {
+	h := NewHandlers(&MockInferencer{}, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.TopMovers(w, httptest.NewRequest(http.MethodPost, "/v1/insights/top-movers", bytes.NewBufferString(`{}`)))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Interval levels, from most to least specific.
+const (
+	IntervalLevelStoreFamily = "store_family"
+	IntervalLevelFamily      = "family"
+	IntervalLevelStore       = "store"
+	IntervalLevelGlobal      = "global"
+)
+
+// KeyedPredictionIntervals holds interval offsets at several levels of the hierarchy.
+// Keys follow the feature store convention: "storeNbr_family" for ByStoreFamily,
+// the family name for ByFamily and the store number for ByStore.
+// ByHorizon is keyed by forecast horizon in days ("15", "30", ...), one of ValidHorizons;
+// each entry covers lead times up to its horizon.
+type KeyedPredictionIntervals struct {
+	Global        *PredictionIntervals           `json:"global,omitempty"`
+	ByStoreFamily map[string]PredictionIntervals `json:"by_store_family,omitempty"`
+	ByFamily      map[string]PredictionIntervals `json:"by_family,omitempty"`
+	ByStore       map[string]PredictionIntervals `json:"by_store,omitempty"`
+	ByHorizon     map[string]PredictionIntervals `json:"by_horizon,omitempty"`
+}
+
+// IntervalsInfo describes a loaded prediction intervals file.
+type IntervalsInfo struct {
+	Path          string    `json:"file_path"`
+	Version       string    `json:"version"` // Modification time of the file in Unix seconds, like the feature store's
+	SHA256        string    `json:"sha256"`
+	LoadedAt      time.Time `json:"loaded_at"`
+	Global        bool      `json:"global"` // Global offsets present
+	StoreFamilies int       `json:"store_families"`
+	Families      int       `json:"families"`
+	Stores        int       `json:"stores"`
+	Horizons      int       `json:"horizons"`
+}
+
+// newIntervalsInfo describes the intervals parsed from the file at path holding data.
+func newIntervalsInfo(path string, data []byte, keyed *KeyedPredictionIntervals) IntervalsInfo {
+	sum := sha256.Sum256(data)
+	info := IntervalsInfo{
+		Path:          path,
+		SHA256:        hex.EncodeToString(sum[:]),
+		LoadedAt:      time.Now(),
+		Global:        keyed.Global != nil,
+		StoreFamilies: len(keyed.ByStoreFamily),
+		Families:      len(keyed.ByFamily),
+		Stores:        len(keyed.ByStore),
+		Horizons:      len(keyed.ByHorizon),
+	}
+	if stat, err := os.Stat(path); err == nil {
+		info.Version = fmt.Sprintf("%d", stat.ModTime().Unix())
+	}
+	return info
+}
+
+// Lookup returns the intervals for a series and lead time in days, and the name of the
+// interval set used. Series offsets follow store_family -> family -> store -> global. The
+// horizon intervals used are those of the nearest horizon at or above the lead time (see
+// horizonBucket); when there are some, global offsets are replaced by them and series
+// offsets are scaled by the ratio of the horizon band width to the global band width.
+// Horizon-scaled sets are named "<level>@<h>d" after the horizon used.
+func (k *KeyedPredictionIntervals) Lookup(storeNbr int, family string, leadTime int) (*PredictionIntervals, string) {
+	iv, level := k.lookupSeries(storeNbr, family)
+
+	byHorizon, horizon, ok := k.horizonBucket(leadTime)
+	if !ok {
+		return iv, level
+	}
+	set := fmt.Sprintf("%s@%dd", level, horizon)
+
+	if iv == nil || level == IntervalLevelGlobal {
+		return &byHorizon, fmt.Sprintf("%s@%dd", IntervalLevelGlobal, horizon)
+	}
+	if k.Global == nil {
+		return iv, level
+	}
+	scaled := scaleIntervals(*iv, *k.Global, byHorizon)
+	return &scaled, set
+}
+
+// horizonBucket returns the ByHorizon intervals of the smallest horizon at or above
+// leadTime, and that horizon. It reports false when every horizon is shorter than
+// leadTime, in which case the horizon-independent offsets apply.
+func (k *KeyedPredictionIntervals) horizonBucket(leadTime int) (PredictionIntervals, int, bool) {
+	best := 0
+	for key := range k.ByHorizon {
+		h, err := strconv.Atoi(key)
+		if err != nil || h < leadTime {
+			continue
+		}
+		if best == 0 || h < best {
+			best = h
+		}
+	}
+	if best == 0 {
+		return PredictionIntervals{}, 0, false
+	}
+	return k.ByHorizon[strconv.Itoa(best)], best, true
+}
+
+// missingHorizons returns the horizons without a ByHorizon entry at or above them.
+// Series intervals are served unscaled for them.
+func (k *KeyedPredictionIntervals) missingHorizons(horizons []int) []int {
+	var missing []int
+	for _, h := range horizons {
+		if _, _, ok := k.horizonBucket(h); !ok {
+			missing = append(missing, h)
+		}
+	}
+	return missing
+}
+
+// lookupSeries returns the most specific horizon-independent intervals for a series.
+func (k *KeyedPredictionIntervals) lookupSeries(storeNbr int, family string) (*PredictionIntervals, string) {
+	if iv, ok := k.ByStoreFamily[fmt.Sprintf("%d_%s", storeNbr, family)]; ok {
+		return &iv, IntervalLevelStoreFamily
+	}
+	if iv, ok := k.ByFamily[family]; ok {
+		return &iv, IntervalLevelFamily
+	}
+	if iv, ok := k.ByStore[strconv.Itoa(storeNbr)]; ok {
+		return &iv, IntervalLevelStore
+	}
+	if k.Global != nil {
+		return k.Global, IntervalLevelGlobal
+	}
+	return nil, ""
+}
+
+// horizonRatios returns the factors Lookup scales series offsets by at horizon, 1 for
+// both bands when it doesn't scale them.
+func (k *KeyedPredictionIntervals) horizonRatios(horizon int) (r80, r95 float32) {
+	byHorizon, _, ok := k.horizonBucket(horizon)
+	if !ok || k.Global == nil {
+		return 1, 1
+	}
+	return bandRatios(*k.Global, byHorizon)
+}
+
+// scaleIntervals widens or narrows iv by the ratio of target to base band widths.
+// Each band is scaled independently; a degenerate base band leaves it unchanged.
+func scaleIntervals(iv, base, target PredictionIntervals) PredictionIntervals {
+	r80, r95 := bandRatios(base, target)
+	iv.Lower80Offset *= r80
+	iv.Upper80Offset *= r80
+	iv.Lower95Offset *= r95
+	iv.Upper95Offset *= r95
+	return iv
+}
+
+// bandRatios returns the ratios of target to base widths of the 80% and 95% bands.
+func bandRatios(base, target PredictionIntervals) (r80, r95 float32) {
+	ratio := func(baseLower, baseUpper, targetLower, targetUpper float32) float32 {
+		if width := baseUpper - baseLower; width > 0 {
+			return (targetUpper - targetLower) / width
+		}
+		return 1
+	}
+	r80 = ratio(base.Lower80Offset, base.Upper80Offset, target.Lower80Offset, target.Upper80Offset)
+	r95 = ratio(base.Lower95Offset, base.Upper95Offset, target.Lower95Offset, target.Upper95Offset)
+	return r80, r95
+}
+
+// parsePredictionIntervals decodes either a keyed intervals file or the legacy
+// flat file of global offsets written by training.
+func parsePredictionIntervals(data []byte) (*KeyedPredictionIntervals, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	_, hasGlobal := fields["global"]
+	_, hasStoreFamily := fields["by_store_family"]
+	_, hasFamily := fields["by_family"]
+	_, hasStore := fields["by_store"]
+	_, hasHorizon := fields["by_horizon"]
+	if hasGlobal || hasStoreFamily || hasFamily || hasStore || hasHorizon {
+		var keyed KeyedPredictionIntervals
+		if err := json.Unmarshal(data, &keyed); err != nil {
+			return nil, err
+		}
+		return &keyed, nil
+	}
+
+	var global PredictionIntervals
+	if err := json.Unmarshal(data, &global); err != nil {
+		return nil, err
+	}
+	return &KeyedPredictionIntervals{Global: &global}, nil
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeIntervalsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prediction_intervals.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write intervals file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPredictionIntervalsLegacyFormat(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	path := writeIntervalsFile(t, `{"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20}`)
+
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b := h.applyIntervals(1, "GROCERY I", 30, 100)
+	if b.Lower80 != 90 || b.Upper80 != 110 || b.Lower95 != 80 || b.Upper95 != 120 {
+		t.Errorf("unexpected intervals: %+v", b)
+	}
+	if b.Set != IntervalLevelGlobal {
+		t.Errorf("expected interval set %s, got %s", IntervalLevelGlobal, b.Set)
+	}
+}
+
+func TestLoadPredictionIntervalsKeyedFallback(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	path := writeIntervalsFile(t, `{
+		"global": {"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20},
+		"by_store_family": {"1_GROCERY I": {"lower_80_offset":-50,"upper_80_offset":50,"lower_95_offset":-90,"upper_95_offset":90}},
+		"by_family": {"BOOKS": {"lower_80_offset":-1,"upper_80_offset":1,"lower_95_offset":-2,"upper_95_offset":2}},
+		"by_store": {"2": {"lower_80_offset":-5,"upper_80_offset":5,"lower_95_offset":-8,"upper_95_offset":8}}
+	}`)
+
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		storeNbr int
+		family   string
+		level    string
+		upper80  float32
+	}{
+		{"store family", 1, "GROCERY I", IntervalLevelStoreFamily, 150},
+		{"family", 2, "BOOKS", IntervalLevelFamily, 101},
+		{"store", 2, "GROCERY I", IntervalLevelStore, 105},
+		{"global", 3, "GROCERY I", IntervalLevelGlobal, 110},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := h.applyIntervals(tc.storeNbr, tc.family, 30, 100)
+			if b.Set != tc.level {
+				t.Errorf("expected level %s, got %s", tc.level, b.Set)
+			}
+			if b.Upper80 != tc.upper80 {
+				t.Errorf("expected upper_80 %v, got %v", tc.upper80, b.Upper80)
+			}
+		})
+	}
+}
+
+func TestLoadPredictionIntervalsInvalid(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	if err := h.LoadPredictionIntervals(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if err := h.LoadPredictionIntervals(writeIntervalsFile(t, `{not json`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	if b := h.applyIntervals(1, "GROCERY I", 30, 100); b != (intervalBands{}) {
+		t.Errorf("expected zero intervals when none loaded, got %+v", b)
+	}
+}
+
+func TestLoadPredictionIntervalsByHorizon(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	path := writeIntervalsFile(t, `{
+		"global": {"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20},
+		"by_store_family": {"1_GROCERY I": {"lower_80_offset":-50,"upper_80_offset":50,"lower_95_offset":-90,"upper_95_offset":90}},
+		"by_horizon": {"15": {"lower_80_offset":-5,"upper_80_offset":5,"lower_95_offset":-10,"upper_95_offset":10},
+		               "90": {"lower_80_offset":-30,"upper_80_offset":30,"lower_95_offset":-60,"upper_95_offset":60}}
+	}`)
+
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		storeNbr int
+		family   string
+		horizon  int
+		set      string
+		upper80  float32
+		upper95  float32
+	}{
+		{"global horizon 15", 2, "BOOKS", 15, "global@15d", 105, 110},
+		{"global horizon 90", 2, "BOOKS", 90, "global@90d", 130, 160},
+		{"global lead time 1 uses horizon 15", 2, "BOOKS", 1, "global@15d", 105, 110},
+		{"global lead time 30 uses horizon 90", 2, "BOOKS", 30, "global@90d", 130, 160},
+		{"global beyond every horizon", 2, "BOOKS", 120, IntervalLevelGlobal, 110, 120},
+		{"store family scaled to horizon 90", 1, "GROCERY I", 90, "store_family@90d", 250, 370},
+		{"store family scaled to horizon 15", 1, "GROCERY I", 15, "store_family@15d", 125, 145},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := h.applyIntervals(tc.storeNbr, tc.family, tc.horizon, 100)
+			if b.Set != tc.set {
+				t.Errorf("expected interval set %s, got %s", tc.set, b.Set)
+			}
+			if b.Upper80 != tc.upper80 || b.Upper95 != tc.upper95 {
+				t.Errorf("expected upper_80 %v upper_95 %v, got %v %v", tc.upper80, tc.upper95, b.Upper80, b.Upper95)
+			}
+		})
+	}
+}
+
+func TestMissingHorizons(t *testing.T) {
+	keyed, err := parsePredictionIntervals([]byte(`{"by_horizon": {"15": {}, "90": {}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := keyed.missingHorizons([]int{7, 15, 45, 90, 120})
+	if len(missing) != 1 || missing[0] != 120 {
+		t.Errorf("expected missing horizons [120], got %v", missing)
+	}
+}
+
+// TestForecastIntervalsByLeadTime checks each forecast day's bands come from the
+// horizon covering its own lead time rather than the request horizon.
+func TestForecastIntervalsByLeadTime(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	path := writeIntervalsFile(t, `{
+		"global": {"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20},
+		"by_horizon": {"15": {"lower_80_offset":-5,"upper_80_offset":5,"lower_95_offset":-10,"upper_95_offset":10},
+		               "30": {"lower_80_offset":-30,"upper_80_offset":30,"lower_95_offset":-60,"upper_95_offset":60}}
+	}`)
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := `{"store_nbr":1,"family":"GROCERY I","start_date":"2017-08-16","horizon":30}`
+	w := httptest.NewRecorder()
+	h.Forecast(w, httptest.NewRequest(http.MethodPost, "/forecast", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ForecastResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	first, last := resp.Forecast[0], resp.Forecast[len(resp.Forecast)-1]
+	if first.Upper80 != 105 || first.Upper95 != 110 {
+		t.Errorf("day 1: expected upper_80 105 upper_95 110, got %v %v", first.Upper80, first.Upper95)
+	}
+	if day15 := resp.Forecast[14]; day15.Upper80 != 105 {
+		t.Errorf("day 15: expected upper_80 105, got %v", day15.Upper80)
+	}
+	if day16 := resp.Forecast[15]; day16.Upper80 != 130 {
+		t.Errorf("day 16: expected upper_80 130, got %v", day16.Upper80)
+	}
+	if last.Upper80 != 130 || last.Upper95 != 160 {
+		t.Errorf("day 30: expected upper_80 130 upper_95 160, got %v %v", last.Upper80, last.Upper95)
+	}
+	if resp.IntervalSet != "global@30d" {
+		t.Errorf("expected interval_set global@30d, got %q", resp.IntervalSet)
+	}
+}
+
+func TestPredictSimpleIntervalSet(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, nil, nil)
+	path := writeIntervalsFile(t, `{"by_horizon": {"60": {"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20}}}`)
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":60}`
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.PredictSimple(w, req)
+
+	var resp PredictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.IntervalSet != "global@60d" {
+		t.Errorf("expected interval_set global@60d, got %q", resp.IntervalSet)
+	}
+	if resp.Upper80 != 110 {
+		t.Errorf("expected upper_80 110, got %v", resp.Upper80)
+	}
+}
+
+func TestRefreshIntervals(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if err := h.RefreshIntervals(context.Background()); err == nil {
+		t.Error("expected an error before any intervals path is configured")
+	}
+
+	path := writeIntervalsFile(t, `{"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20}`)
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"lower_80_offset":-1,"upper_80_offset":1,"lower_95_offset":-2,"upper_95_offset":2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.RefreshIntervals(context.Background()); err != nil {
+		t.Fatalf("RefreshIntervals failed: %v", err)
+	}
+	if b := h.applyIntervals(1, "GROCERY I", 30, 100); b.Lower80 != 99 || b.Upper95 != 102 {
+		t.Errorf("expected the rewritten intervals, got %+v", b)
+	}
+
+	// An invalid rewrite keeps the current intervals
+	os.WriteFile(path, []byte(`{not json`), 0o644)
+	if err := h.RefreshIntervals(context.Background()); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if b := h.applyIntervals(1, "GROCERY I", 30, 100); b.Lower80 != 99 {
+		t.Errorf("expected the previous intervals to keep serving, got %+v", b)
+	}
+}
+
+func TestReloadIntervals(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	reload := func() (*httptest.ResponseRecorder, ReloadResponse) {
+		w := httptest.NewRecorder()
+		h.ReloadIntervals(w, httptest.NewRequest(http.MethodPost, "/admin/reload-intervals", nil))
+		var resp ReloadResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	if w, _ := reload(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without an intervals path, got %d", w.Code)
+	}
+
+	path := writeIntervalsFile(t, `{"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20}`)
+	if err := h.LoadPredictionIntervals(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := h.IntervalsInfo()
+	if loaded.Version == "" || loaded.SHA256 == "" || !loaded.Global {
+		t.Fatalf("expected version info of the loaded file, got %+v", loaded)
+	}
+
+	os.WriteFile(path, []byte(`{"global":{"lower_80_offset":-1,"upper_80_offset":1,"lower_95_offset":-2,"upper_95_offset":2},"by_family":{"DAIRY":{"lower_80_offset":-3,"upper_80_offset":3,"lower_95_offset":-6,"upper_95_offset":6}}}`), 0o644)
+	w, resp := reload()
+	if w.Code != http.StatusOK || resp.Status != "reloaded" {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Metadata["changed"] != true || resp.Metadata["families"] != float64(1) || resp.Metadata["sha256"] == loaded.SHA256 || resp.Metadata["previous_version"] != loaded.Version {
+		t.Errorf("unexpected reload metadata %+v", resp.Metadata)
+	}
+	if b := h.applyIntervals(1, "DAIRY", 30, 100); b.Lower80 != 97 {
+		t.Errorf("expected the reloaded intervals, got %+v", b)
+	}
+
+	// An invalid file keeps the current intervals
+	os.WriteFile(path, []byte(`{not json`), 0o644)
+	if w, _ := reload(); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for an invalid file, got %d", w.Code)
+	}
+	if h.IntervalsInfo().Families != 1 {
+		t.Error("expected the previous intervals to stay in use")
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+	"github.com/rs/zerolog/log"
+)
+
+// jobChunkSize is the number of rows scored per inference call in a job.
+const jobChunkSize = 1000
+
+// JobResponse describes a background job and where to find its result.
+type JobResponse struct {
+	jobs.Job
+	StatusURL string `json:"status_url"`
+	ResultURL string `json:"result_url,omitempty"`
+}
+
+// JobResultResponse is the downloadable result of a completed prediction job.
+type JobResultResponse struct {
+	JobID       string            `json:"job_id"`
+	Predictions []PredictResponse `json:"predictions"`
+}
+
+// SubmitPredictJob accepts a large batch for asynchronous scoring.
+// Returns 202 Accepted with the job ID; poll GET /jobs/{id} for status.
+func (h *Handlers) SubmitPredictJob(w http.ResponseWriter, r *http.Request) {
+	if h.jobs == nil {
+		WriteServiceUnavailable(w, r, "batch jobs not enabled", CodeJobsUnavailable)
+		return
+	}
+	if h.draining() {
+		WriteServiceUnavailable(w, r, "server is draining; submit the job to another instance", CodeDraining)
+		return
+	}
+
+	var req BatchPredictRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Predictions) == 0 {
+		WriteBadRequest(w, r, "predictions array is empty", "EMPTY_BATCH")
+		return
+	}
+	if len(req.Predictions) > h.jobs.MaxItems() {
+		WriteBadRequest(w, r, fmt.Sprintf("job size exceeds maximum of %d", h.jobs.MaxItems()), CodeBatchTooLarge)
+		return
+	}
+	for i, pred := range req.Predictions {
+		if err := h.validateBatchItem(pred); err != nil {
+			WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
+			return
+		}
+	}
+
+	items := req.Predictions
+	requestID, endpoint := getRequestID(r.Context()), r.URL.Path
+	job, err := h.jobs.Submit(len(items), func(ctx context.Context, progress func(int)) (interface{}, error) {
+		results, err := h.runPredictJob(ctx, items, progress)
+		if err == nil {
+			h.logPredictions(requestID, endpoint, items, results)
+		}
+		return results, err
+	})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		WriteServiceUnavailable(w, r, "job queue is full, retry later", CodeJobQueueFull)
+		return
+	}
+
+	resp := newJobResponse(r, job)
+	log.Info().Str("job_id", job.ID).Int("items", job.Total).Msg("Prediction job queued")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", resp.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetJob returns the status of a background job.
+func (h *Handlers) GetJob(w http.ResponseWriter, r *http.Request) {
+	if h.jobs == nil {
+		WriteServiceUnavailable(w, r, "batch jobs not enabled", CodeJobsUnavailable)
+		return
+	}
+
+	job, ok := h.jobs.Get(chi.URLParam(r, "id"))
+	if !ok {
+		WriteError(w, r, http.StatusNotFound, "job not found", CodeJobNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newJobResponse(r, job))
+}
+
+// GetJobResult downloads the predictions of a completed job.
+// Returns 409 Conflict while the job is still queued or running, or if it failed.
+func (h *Handlers) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	if h.jobs == nil {
+		WriteServiceUnavailable(w, r, "batch jobs not enabled", CodeJobsUnavailable)
+		return
+	}
+
+	job, result, ok := h.jobs.Result(chi.URLParam(r, "id"))
+	if !ok {
+		WriteError(w, r, http.StatusNotFound, "job not found", CodeJobNotFound)
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		WriteError(w, r, http.StatusConflict, fmt.Sprintf("job is %s", job.Status), CodeJobNotReady)
+		return
+	}
+
+	predictions, _ := result.([]PredictResponse)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="predictions-%s.json"`, job.ID))
+	json.NewEncoder(w).Encode(JobResultResponse{JobID: job.ID, Predictions: predictions})
+}
+
+// runPredictJob scores items in chunks, grouped by model, preserving input order.
+func (h *Handlers) runPredictJob(ctx context.Context, items []PredictRequest, progress func(int)) ([]PredictResponse, error) {
+	type group struct {
+		model   inference.Inferencer
+		key     string
+		indices []int
+	}
+	groups := make(map[string]*group)
+	var order []string
+	for i, item := range items {
+		model, key, verr := h.resolveModel(item.Model)
+		if verr != nil {
+			return nil, verr
+		}
+		if model == nil {
+			return nil, errors.New("model not loaded")
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{model: model, key: key}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.indices = append(g.indices, i)
+	}
+
+	results := make([]PredictResponse, len(items))
+	processed := 0
+	for _, key := range order {
+		g := groups[key]
+		for start := 0; start < len(g.indices); start += jobChunkSize {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			end := min(start+jobChunkSize, len(g.indices))
+			chunk := g.indices[start:end]
+			batch := make([][]float32, len(chunk))
+			families := make([]string, len(chunk))
+			for j, idx := range chunk {
+				batch[j] = items[idx].Features
+				families[j] = items[idx].Family
+			}
+
+			chunkStart := time.Now()
+			preds, err := inference.PredictBatch(inference.WithMetricLabels(ctx, g.key, families...), g.model, batch)
+			if err != nil {
+				return nil, fmt.Errorf("inference failed: %w", err)
+			}
+			guards, err := h.guardBatch(preds)
+			if err != nil {
+				return nil, err
+			}
+			latency := float64(time.Since(chunkStart).Microseconds()) / 1000 / float64(len(chunk))
+
+			for j, idx := range chunk {
+				item := items[idx]
+				results[idx] = PredictResponse{
+					StoreNbr:   item.StoreNbr,
+					Family:     item.Family,
+					Date:       item.Date,
+					Prediction: preds[j],
+					Model:      g.key,
+					LatencyMs:  latency,
+					Guard:      guards[j],
+				}
+			}
+
+			processed += len(chunk)
+			progress(processed)
+		}
+	}
+	return results, nil
+}
+
+// newJobResponse adds status and result URLs to a job snapshot.
+// URLs keep the request's version prefix (e.g. /v1) when present.
+func newJobResponse(r *http.Request, job jobs.Job) JobResponse {
+	prefix := ""
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		prefix = "/v1"
+	}
+	resp := JobResponse{
+		Job:       job,
+		StatusURL: prefix + "/jobs/" + job.ID,
+	}
+	if job.Status == jobs.StatusCompleted {
+		resp.ResultURL = resp.StatusURL + "/result"
+	}
+	return resp
+}
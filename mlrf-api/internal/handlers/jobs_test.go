@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mlrf/mlrf-api/internal/jobs"
+)
+
+// jobRequest builds a request with the chi {id} URL param set.
+func jobRequest(path, id string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func batchBody(n int) []byte {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`
+	}
+	return []byte(`{"predictions":[` + strings.Join(items, ",") + `]}`)
+}
+
+func TestPredictJobLifecycle(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+	manager := jobs.NewManager(jobs.Config{Workers: 1, QueueSize: 2, MaxItems: 5000})
+	defer manager.Close()
+	h.SetJobManager(manager)
+
+	// Larger than the synchronous batch limit
+	req := httptest.NewRequest(http.MethodPost, "/v1/predict/jobs", bytes.NewReader(batchBody(MaxBatchSize+50)))
+	w := httptest.NewRecorder()
+	h.SubmitPredictJob(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var submitted JobResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &submitted); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if submitted.StatusURL != "/v1/jobs/"+submitted.ID || w.Header().Get("Location") != submitted.StatusURL {
+		t.Errorf("unexpected status URL %q (Location %q)", submitted.StatusURL, w.Header().Get("Location"))
+	}
+
+	var status JobResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		w = httptest.NewRecorder()
+		h.GetJob(w, jobRequest("/jobs/"+submitted.ID, submitted.ID))
+		json.Unmarshal(w.Body.Bytes(), &status)
+		if status.Status == jobs.StatusCompleted {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status.Status != jobs.StatusCompleted {
+		t.Fatalf("expected job to complete, got %+v", status)
+	}
+	if status.ResultURL != "/jobs/"+submitted.ID+"/result" {
+		t.Errorf("unexpected result URL %q", status.ResultURL)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetJobResult(w, jobRequest(status.ResultURL, submitted.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("expected attachment download, got %q", w.Header().Get("Content-Disposition"))
+	}
+	var result JobResultResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	if len(result.Predictions) != MaxBatchSize+50 || result.Predictions[0].Prediction != 42 {
+		t.Errorf("unexpected result: %d predictions", len(result.Predictions))
+	}
+}
+
+func TestPredictJobValidation(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+	manager := jobs.NewManager(jobs.Config{Workers: 1, QueueSize: 1, MaxItems: 3})
+	defer manager.Close()
+	h.SetJobManager(manager)
+
+	testCases := []struct {
+		name string
+		body []byte
+	}{
+		{"invalid json", []byte(`{invalid}`)},
+		{"empty", []byte(`{"predictions":[]}`)},
+		{"too large", batchBody(4)},
+		{"invalid item", []byte(`{"predictions":[{"store_nbr":0,"family":"GROCERY I","date":"2017-08-01","features":[]}]}`)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/predict/jobs", bytes.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			h.SubmitPredictJob(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestPredictJobNotFoundAndNotReady(t *testing.T) {
+	h := NewHandlers(&MockInferencer{err: fmt.Errorf("boom")}, nil, nil, nil)
+	manager := jobs.NewManager(jobs.Config{Workers: 1, QueueSize: 1, MaxItems: 10})
+	defer manager.Close()
+	h.SetJobManager(manager)
+
+	w := httptest.NewRecorder()
+	h.GetJob(w, jobRequest("/jobs/missing", "missing"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+
+	// A failed job has no downloadable result
+	req := httptest.NewRequest(http.MethodPost, "/predict/jobs", bytes.NewReader(batchBody(2)))
+	w = httptest.NewRecorder()
+	h.SubmitPredictJob(w, req)
+	var submitted JobResponse
+	json.Unmarshal(w.Body.Bytes(), &submitted)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := manager.Get(submitted.ID); job.Status == jobs.StatusFailed {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetJobResult(w, jobRequest("/jobs/"+submitted.ID+"/result", submitted.ID))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+}
+
+func TestPredictJobWithoutManager(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/predict/jobs", bytes.NewReader(batchBody(1)))
+	w := httptest.NewRecorder()
+	h.SubmitPredictJob(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// liveWriteTimeout bounds a single WebSocket write before the client is disconnected.
+	liveWriteTimeout = 10 * time.Second
+
+	// liveHeartbeat is how often an idle connection is pinged to keep proxies from closing it.
+	liveHeartbeat = 30 * time.Second
+)
+
+// LiveRequest is a client-to-server WebSocket command.
+type LiveRequest struct {
+	Action        string              `json:"action"` // "subscribe" or "unsubscribe"
+	Subscriptions []live.Subscription `json:"subscriptions"`
+}
+
+// SetLiveHub enables the /ws endpoint for pushed prediction updates.
+func (h *Handlers) SetLiveHub(hub *live.Hub) {
+	h.live = hub
+}
+
+// SetLiveOrigins sets which browser origins may open /ws, typically the CORS
+// policy's. Without it only same-origin pages may connect.
+func (h *Handlers) SetLiveOrigins(allowed func(origin string) bool) {
+	h.liveOrigins = allowed
+}
+
+// Live upgrades the connection to a WebSocket on which clients subscribe to
+// (store, family, date, horizon) tuples. The current prediction is sent on subscribe,
+// and updated predictions are pushed whenever the feature store or model is reloaded.
+func (h *Handlers) Live(w http.ResponseWriter, r *http.Request) {
+	if h.live == nil {
+		WriteServiceUnavailable(w, r, "live updates not enabled", CodeLiveUnavailable)
+		return
+	}
+
+	websocket.Server{Handshake: h.liveHandshake, Handler: h.serveLive}.ServeHTTP(w, r)
+}
+
+// liveHandshake rejects upgrades from browser pages on origins that aren't allowed
+// (403), so another site can't open a connection with the user's credentials.
+// Clients that send no Origin, which browsers always do, are not checked.
+func (h *Handlers) liveHandshake(_ *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if h.liveOrigins != nil {
+		if h.liveOrigins(origin) {
+			return nil
+		}
+	} else if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return nil
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// serveLive runs one WebSocket connection: a reader handling subscription commands
+// and a writer draining the client's send queue.
+func (h *Handlers) serveLive(ws *websocket.Conn) {
+	defer ws.Close()
+
+	// Clear deadlines inherited from the HTTP server; writes set their own
+	ws.SetDeadline(time.Time{})
+
+	client := h.live.Register()
+	defer h.live.Unregister(client)
+
+	go h.writeLive(ws, client)
+
+	for {
+		var req LiveRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Debug().Err(err).Msg("live connection closed")
+			}
+			return
+		}
+		h.handleLiveRequest(client, req)
+	}
+}
+
+// writeLive writes queued messages to the connection until the client is unregistered
+// or a write fails, sending a ping when idle.
+func (h *Handlers) writeLive(ws *websocket.Conn, client *live.Client) {
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var msg live.Message
+		select {
+		case m, ok := <-client.Send():
+			if !ok {
+				ws.Close()
+				return
+			}
+			msg = m
+		case <-heartbeat.C:
+			msg = live.Message{Type: live.TypePing}
+		}
+
+		ws.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		if err := websocket.JSON.Send(ws, msg); err != nil {
+			log.Debug().Err(err).Msg("live write failed")
+			ws.Close()
+			return
+		}
+	}
+}
+
+// handleLiveRequest applies a subscribe or unsubscribe command.
+func (h *Handlers) handleLiveRequest(client *live.Client, req LiveRequest) {
+	switch req.Action {
+	case "subscribe":
+		for _, s := range req.Subscriptions {
+			if err := h.validateSubscription(s); err != nil {
+				client.Enqueue(live.Message{Type: live.TypeError, Subscription: &s, Error: err.Message, Code: err.Code})
+				return
+			}
+		}
+		added, err := client.Subscribe(req.Subscriptions)
+		if err != nil {
+			client.Enqueue(live.Message{Type: live.TypeError, Error: err.Error(), Code: CodeInvalidRequest})
+			return
+		}
+		client.Enqueue(live.Message{Type: live.TypeSubscribed, Subscriptions: added})
+		for _, s := range added {
+			client.Enqueue(live.Score(live.ReasonSubscribe, s, h.livePrediction))
+		}
+	case "unsubscribe":
+		client.Unsubscribe(req.Subscriptions)
+		client.Enqueue(live.Message{Type: live.TypeUnsubscribed, Subscriptions: req.Subscriptions})
+	default:
+		client.Enqueue(live.Message{Type: live.TypeError, Error: "unknown action: " + req.Action, Code: CodeInvalidRequest})
+	}
+}
+
+// validateSubscription validates a subscription like a /predict/simple request.
+func (h *Handlers) validateSubscription(s live.Subscription) *ValidationError {
+	if err := h.validateStoreNbr(s.StoreNbr); err != nil {
+		return err
+	}
+	if err := ValidateFamily(s.Family); err != nil {
+		return err
+	}
+	if err := ValidateDate(s.Date); err != nil {
+		return err
+	}
+	return ValidateHorizon(s.Horizon)
+}
+
+// livePrediction scores a subscription with the current features and model.
+func (h *Handlers) livePrediction(s live.Subscription) (interface{}, error) {
+	if h.onnx == nil {
+		return nil, errModelUnavailable
+	}
+	flight, err := h.simplePrediction(context.Background(), SimplePredictRequest{
+		StoreNbr: s.StoreNbr,
+		Family:   s.Family,
+		Date:     s.Date,
+		Horizon:  s.Horizon,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return flight.resp, nil
+}
+
+// notifyLive pushes fresh predictions to live subscribers after a reload.
+// Runs in the background so the reload response is not delayed.
+func (h *Handlers) notifyLive(reason string) {
+	if h.live != nil {
+		go h.live.Broadcast(reason, h.livePrediction)
+	}
+}
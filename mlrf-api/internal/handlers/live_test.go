@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/live"
+	"golang.org/x/net/websocket"
+)
+
+// liveMessage mirrors live.Message with the prediction decoded.
+type liveMessage struct {
+	Type   string          `json:"type"`
+	Reason string          `json:"reason"`
+	Data   PredictResponse `json:"data"`
+	Error  string          `json:"error"`
+	Code   string          `json:"code"`
+}
+
+func receiveLive(t *testing.T, ws *websocket.Conn) liveMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg liveMessage
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatalf("failed to receive live message: %v", err)
+	}
+	return msg
+}
+
+func TestLiveSubscribeAndReload(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+	h.SetLiveHub(live.NewHub(live.Config{MaxSubscriptions: 10, SendBuffer: 16}))
+
+	srv := httptest.NewServer(http.HandlerFunc(h.Live))
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer ws.Close()
+
+	sub := LiveRequest{
+		Action:        "subscribe",
+		Subscriptions: []live.Subscription{{StoreNbr: 1, Family: "GROCERY I", Date: "2017-08-01", Horizon: 30}},
+	}
+	if err := websocket.JSON.Send(ws, sub); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if msg := receiveLive(t, ws); msg.Type != live.TypeSubscribed {
+		t.Fatalf("expected subscribed, got %+v", msg)
+	}
+	msg := receiveLive(t, ws)
+	if msg.Type != live.TypePrediction || msg.Reason != live.ReasonSubscribe || msg.Data.Prediction != 42 {
+		t.Fatalf("expected initial prediction, got %+v", msg)
+	}
+
+	h.notifyLive(live.ReasonModelReloaded)
+
+	msg = receiveLive(t, ws)
+	if msg.Type != live.TypePrediction || msg.Reason != live.ReasonModelReloaded {
+		t.Fatalf("expected pushed update after reload, got %+v", msg)
+	}
+	if msg.Data.StoreNbr != 1 || msg.Data.Family != "GROCERY I" {
+		t.Errorf("unexpected update payload %+v", msg.Data)
+	}
+}
+
+func TestLiveInvalidSubscription(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+	h.SetLiveHub(live.NewHub(live.DefaultConfig()))
+
+	srv := httptest.NewServer(http.HandlerFunc(h.Live))
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer ws.Close()
+
+	websocket.JSON.Send(ws, LiveRequest{
+		Action:        "subscribe",
+		Subscriptions: []live.Subscription{{StoreNbr: 0, Family: "GROCERY I", Date: "2017-08-01", Horizon: 30}},
+	})
+
+	if msg := receiveLive(t, ws); msg.Type != live.TypeError || msg.Code != CodeInvalidStore {
+		t.Errorf("expected %s error, got %+v", CodeInvalidStore, msg)
+	}
+}
+
+func TestLiveUnavailable(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.Live(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+func TestLiveOrigin(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+	h.SetLiveHub(live.NewHub(live.DefaultConfig()))
+
+	srv := httptest.NewServer(http.HandlerFunc(h.Live))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	upgrade := func(origin string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Without allowed origins only the server's own origin may connect
+	if status := upgrade("http://evil.example"); status != http.StatusForbidden {
+		t.Errorf("expected a cross-origin upgrade to be rejected with 403, got %d", status)
+	}
+	if _, err := websocket.Dial(wsURL, "", "http://evil.example"); err == nil {
+		t.Error("expected dialing from another origin to fail")
+	}
+
+	h.SetLiveOrigins(func(origin string) bool { return origin == "http://localhost:5173" })
+	if status := upgrade(srv.URL); status != http.StatusForbidden {
+		t.Errorf("expected an origin missing from the allowed origins to be rejected with 403, got %d", status)
+	}
+	ws, err := websocket.Dial(wsURL, "", "http://localhost:5173")
+	if err != nil {
+		t.Fatalf("expected an allowed origin to connect: %v", err)
+	}
+	ws.Close()
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxLogLevelDuration is the longest a temporary log level may stay in effect.
+const MaxLogLevelDuration = 24 * time.Hour
+
+// LogLevelRequest is the body of PUT /admin/log-level.
+type LogLevelRequest struct {
+	Level    string `json:"level"`              // trace, debug, info, warn, error, fatal, panic or disabled
+	Duration string `json:"duration,omitempty"` // Revert to the previous level after this long (e.g. "15m")
+}
+
+// LogLevelResponse reports the current log level and any pending revert.
+type LogLevelResponse struct {
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// logLevelState tracks a temporary log level set with a duration.
+type logLevelState struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	revertTo zerolog.Level
+	revertAt time.Time
+}
+
+// setLogLevel sets the global log level. With a duration it reverts afterwards to the
+// level in effect before the first of any overlapping temporary changes; without one
+// it cancels a pending revert.
+func (h *Handlers) setLogLevel(level zerolog.Level, d time.Duration) {
+	s := &h.logLevel
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	} else {
+		s.revertTo = zerolog.GlobalLevel()
+	}
+	zerolog.SetGlobalLevel(level)
+	if d <= 0 {
+		s.revertAt = time.Time{}
+		return
+	}
+
+	s.revertAt = time.Now().Add(d)
+	s.timer = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		zerolog.SetGlobalLevel(s.revertTo)
+		s.timer = nil
+		s.revertAt = time.Time{}
+		log.Info().Str("level", s.revertTo.String()).Msg("Temporary log level expired")
+	})
+}
+
+// logLevelResponse reports the current level and pending revert.
+func (h *Handlers) logLevelResponse() LogLevelResponse {
+	s := &h.logLevel
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := LogLevelResponse{Level: zerolog.GlobalLevel().String()}
+	if s.timer != nil {
+		at := s.revertAt
+		resp.RevertTo = s.revertTo.String()
+		resp.RevertAt = &at
+	}
+	return resp
+}
+
+// GetLogLevel returns the current log level.
+// Requires the admin scope.
+func (h *Handlers) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.logLevelResponse())
+}
+
+// UpdateLogLevel switches the log level at runtime, optionally for a limited duration,
+// so debug logging can be enabled in production without a redeploy.
+// Requires the admin scope.
+func (h *Handlers) UpdateLogLevel(w http.ResponseWriter, r *http.Request) {
+
+	var req LogLevelRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	level, err := zerolog.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		WriteBadRequest(w, r, "level must be trace, debug, info, warn, error, fatal, panic or disabled", CodeInvalidRequest)
+		return
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		d, err = time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > MaxLogLevelDuration {
+			WriteBadRequest(w, r, "duration must be a positive duration of at most 24h", CodeInvalidRequest)
+			return
+		}
+	}
+
+	h.setLogLevel(level, d)
+	log.Info().Str("level", level.String()).Dur("duration", d).Msg("Log level changed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.logLevelResponse())
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogLevel(t *testing.T) {
+	prev := zerolog.GlobalLevel()
+	defer zerolog.SetGlobalLevel(prev)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	h := NewHandlers(nil, nil, nil, nil)
+	put := func(body string) (*httptest.ResponseRecorder, LogLevelResponse) {
+		w := httptest.NewRecorder()
+		h.UpdateLogLevel(w, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(body)))
+		var resp LogLevelResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	for _, body := range []string{`{"level": "loud"}`, `{}`, `{"level": "debug", "duration": "48h"}`, `{"level": "debug", "duration": "-1m"}`} {
+		if w, _ := put(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	w, resp := put(`{"level": "debug", "duration": "50ms"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Level != "debug" || resp.RevertTo != "info" || resp.RevertAt == nil {
+		t.Errorf("expected debug reverting to info, got %+v", resp)
+	}
+	if zerolog.GlobalLevel() != zerolog.DebugLevel {
+		t.Errorf("expected the global level debug, got %s", zerolog.GlobalLevel())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for zerolog.GlobalLevel() != zerolog.InfoLevel && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	w = httptest.NewRecorder()
+	h.GetLogLevel(w, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+	var current LogLevelResponse
+	json.Unmarshal(w.Body.Bytes(), &current)
+	if current.Level != "info" || current.RevertAt != nil {
+		t.Errorf("expected the level reverted to info, got %+v", current)
+	}
+
+	// Without a duration the level stays
+	if _, resp := put(`{"level": "warn"}`); resp.Level != "warn" || resp.RevertAt != nil {
+		t.Errorf("expected a permanent warn level, got %+v", resp)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+)
+
+// Mock fallback modes, set with MOCK_FALLBACKS.
+const (
+	MockFallbacksAllow = "allow" // Serve fabricated data flagged with "is_mock": true
+	MockFallbacksDeny  = "deny"  // Return 503 MOCK_FALLBACK_DENIED instead
+)
+
+// SetMockFallbacks sets whether endpoints without real data serve fabricated data,
+// flagged with "is_mock": true (allow), or return 503 MOCK_FALLBACK_DENIED (deny).
+func (h *Handlers) SetMockFallbacks(mode string) {
+	h.denyMocks = mode == MockFallbacksDeny
+}
+
+// allowMock reports whether endpoint may answer with fabricated data. It counts the mock
+// response when allowed, and otherwise writes the 503 response.
+func (h *Handlers) allowMock(w http.ResponseWriter, r *http.Request, endpoint string) bool {
+	if h.denyMocks {
+		WriteServiceUnavailable(w, r, endpoint+" data not available and MOCK_FALLBACKS=deny", CodeMockFallbackDenied)
+		return false
+	}
+	metrics.RecordMockResponse(endpoint)
+	return true
+}
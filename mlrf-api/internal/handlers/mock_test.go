@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMockFallbacks(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	accuracy := func() (*httptest.ResponseRecorder, AccuracyResponse) {
+		w := httptest.NewRecorder()
+		h.Accuracy(w, httptest.NewRequest(http.MethodGet, "/accuracy", nil))
+		var resp AccuracyResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	historical := func() (*httptest.ResponseRecorder, HistoricalResponse) {
+		w := httptest.NewRecorder()
+		body := `{"store_nbr":1,"family":"GROCERY I","end_date":"2017-08-01","days":28}`
+		h.Historical(w, httptest.NewRequest(http.MethodPost, "/historical", bytes.NewBufferString(body)))
+		var resp HistoricalResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// Without accuracy_data.json or historical data, responses are flagged mocks
+	if w, resp := accuracy(); w.Code != http.StatusOK || !resp.IsMock {
+		t.Errorf("expected flagged mock accuracy, got %d is_mock=%v", w.Code, resp.IsMock)
+	}
+	if w, resp := historical(); w.Code != http.StatusOK || !resp.IsMock {
+		t.Errorf("expected flagged mock history, got %d is_mock=%v", w.Code, resp.IsMock)
+	}
+
+	h.SetMockFallbacks(MockFallbacksDeny)
+	wa, _ := accuracy()
+	wh, _ := historical()
+	for name, w := range map[string]*httptest.ResponseRecorder{"accuracy": wa, "historical": wh} {
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusServiceUnavailable || resp.Code != CodeMockFallbackDenied {
+			t.Errorf("%s: expected 503 MOCK_FALLBACK_DENIED, got %d %s", name, w.Code, resp.Code)
+		}
+	}
+}
+
+func TestExplainMockFallback(t *testing.T) {
+	h := newExplainHandlers(t, nil)
+
+	// Store 2 has no features, so its explanation is of zeros
+	w, resp := postExplain(h, `{"store_nbr":2,"family":"GROCERY I","date":"2017-08-01"}`)
+	if w.Code != http.StatusOK || !resp.IsMock {
+		t.Errorf("expected a flagged mock explanation, got %d is_mock=%v", w.Code, resp.IsMock)
+	}
+	if _, resp := postExplain(h, `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01"}`); resp.IsMock {
+		t.Error("expected a known series not to be flagged")
+	}
+
+	h.SetMockFallbacks(MockFallbacksDeny)
+	if w, _ := postExplain(h, `{"store_nbr":2,"family":"GROCERY I","date":"2017-08-01"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with MOCK_FALLBACKS=deny, got %d", w.Code)
+	}
+}
+
+func TestAddTrendToNodeFlagsMock(t *testing.T) {
+	root := HierarchyNode{Prediction: 100, Children: []HierarchyNode{{Prediction: 40}}}
+	addTrendToNode(&root, 0.12)
+	if !root.IsMock || !root.Children[0].IsMock {
+		t.Error("expected fabricated trends to be flagged as mock")
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// TrainingMetrics are the evaluation results of the serving model, exported by the
+// training pipeline to metrics.json next to it.
+type TrainingMetrics struct {
+	CVRMSLE    float64    `json:"cv_rmsle"`
+	CVRMSLEStd float64    `json:"cv_rmsle_std"`
+	FinalRMSLE float64    `json:"final_rmsle"`
+	FinalRMSE  float64    `json:"final_rmse"`
+	FinalMAE   float64    `json:"final_mae"`
+	NStores    int        `json:"n_stores"`
+	NFamilies  int        `json:"n_families"`
+	NSeries    int        `json:"n_series"`
+	ONNXValid  *bool      `json:"onnx_valid,omitempty"`  // The ONNX export reproduced the training framework's predictions
+	ExportedAt *time.Time `json:"exported_at,omitempty"` // When the model was exported; unset in files of older pipelines
+}
+
+// ModelRegistryInfo lists the models of the registry and the one serving by default.
+type ModelRegistryInfo struct {
+	Champion string                      `json:"champion"`
+	Models   []inference.RegisteredModel `json:"models"`
+}
+
+// ModelResponse describes the serving model.
+type ModelResponse struct {
+	Loaded          bool                      `json:"loaded"`
+	Version         string                    `json:"version,omitempty"`
+	Path            string                    `json:"path,omitempty"`
+	LoadedAt        *time.Time                `json:"loaded_at,omitempty"`
+	ExportedAt      *time.Time                `json:"exported_at,omitempty"` // From the training metrics, else the file's modification time
+	File            *inference.ModelFile      `json:"file,omitempty"`
+	SelfTest        *inference.SelfTestResult `json:"self_test,omitempty"`
+	NumFeatures     int                       `json:"num_features"`
+	Features        []schema.Column           `json:"features"` // Input features in feature vector order
+	TrainingMetrics *TrainingMetrics          `json:"training_metrics,omitempty"`
+	Registry        *ModelRegistryInfo        `json:"registry,omitempty"` // When a model manifest is loaded
+}
+
+// LoadTrainingMetrics loads the training metrics of the serving model from a JSON file
+// written by the training pipeline. They are loaded again whenever the model reloads.
+// This is optional - if the file doesn't exist, /model omits training metrics.
+func (h *Handlers) LoadTrainingMetrics(path string) error {
+	h.trainingMetricsMu.Lock()
+	defer h.trainingMetricsMu.Unlock()
+	h.trainingMetricsPath = path
+
+	m, err := readTrainingMetrics(path)
+	h.trainingMetrics = m
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load training metrics")
+		return err
+	}
+	log.Info().Str("path", path).Float64("final_rmsle", m.FinalRMSLE).Msg("Training metrics loaded")
+	return nil
+}
+
+// refreshTrainingMetrics loads the training metrics again after a model reload, if
+// they were loaded at startup.
+func (h *Handlers) refreshTrainingMetrics() {
+	h.trainingMetricsMu.RLock()
+	path := h.trainingMetricsPath
+	h.trainingMetricsMu.RUnlock()
+	if path != "" {
+		h.LoadTrainingMetrics(path)
+	}
+}
+
+func readTrainingMetrics(path string) (*TrainingMetrics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m TrainingMetrics
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse training metrics: %w", err)
+	}
+	return &m, nil
+}
+
+// Model returns metadata of the serving model: its version, file hash and ONNX
+// header, feature schema, training metrics and the registry's champion.
+// Supports If-None-Match (see notModified).
+func (h *Handlers) Model(w http.ResponseWriter, r *http.Request) {
+	if h.notModified(w, r) {
+		return
+	}
+	resp := ModelResponse{
+		Loaded:      h.onnx != nil,
+		NumFeatures: schema.Features.Len(),
+		Features:    schema.Features.Columns(),
+	}
+	if h.modelLoader != nil {
+		info := h.modelLoader.Info()
+		resp.Version = info.Version
+		resp.Path = info.Path
+		resp.LoadedAt = &info.LoadedAt
+		resp.File = info.File
+		resp.SelfTest = info.SelfTest
+		if info.File != nil {
+			resp.ExportedAt = &info.File.ModifiedAt
+		}
+	}
+
+	h.trainingMetricsMu.RLock()
+	resp.TrainingMetrics = h.trainingMetrics
+	h.trainingMetricsMu.RUnlock()
+	if resp.TrainingMetrics != nil && resp.TrainingMetrics.ExportedAt != nil {
+		resp.ExportedAt = resp.TrainingMetrics.ExportedAt
+	}
+
+	if h.registry != nil {
+		resp.Registry = &ModelRegistryInfo{
+			Champion: h.registry.Champion(),
+			Models:   h.registry.Models(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+)
+
+func getModel(t *testing.T, h *Handlers) ModelResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Model(w, httptest.NewRequest(http.MethodGet, "/v1/model", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ModelResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return resp
+}
+
+func TestModel(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+	modified := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	reloader := &mockModelReloader{info: inference.ModelInfo{
+		Path:     "models/lightgbm_model.onnx",
+		Version:  "1759320000",
+		LoadedAt: time.Now(),
+		File:     &inference.ModelFile{SHA256: "abc123", Opset: 15, ModifiedAt: modified},
+	}}
+	h.SetModelReloader(reloader)
+
+	registry := inference.NewRegistry()
+	registry.Register(inference.ManifestEntry{Name: "lightgbm", Version: "1", Path: "a.onnx"}, &MockInferencer{})
+	registry.Register(inference.ManifestEntry{Name: "lightgbm", Version: "2", Path: "b.onnx"}, &MockInferencer{})
+	if err := registry.SetChampion("lightgbm@2"); err != nil {
+		t.Fatal(err)
+	}
+	h.SetModelRegistry(registry)
+
+	resp := getModel(t, h)
+	if !resp.Loaded || resp.Version != "1759320000" || resp.File == nil || resp.File.SHA256 != "abc123" || resp.File.Opset != 15 {
+		t.Errorf("unexpected model metadata %+v", resp)
+	}
+	if resp.NumFeatures != RequiredFeatureCount || len(resp.Features) != RequiredFeatureCount {
+		t.Errorf("expected the %d-feature schema, got %d features", RequiredFeatureCount, len(resp.Features))
+	}
+	if resp.Registry == nil || resp.Registry.Champion != "lightgbm@2" || len(resp.Registry.Models) != 2 {
+		t.Errorf("unexpected registry %+v", resp.Registry)
+	}
+	// Without training metrics, the export time is the file's
+	if resp.TrainingMetrics != nil || resp.ExportedAt == nil || !resp.ExportedAt.Equal(modified) {
+		t.Errorf("expected the file's modification time as export time, got %v", resp.ExportedAt)
+	}
+
+	// Training metrics are loaded again when the model reloads
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	if err := os.WriteFile(path, []byte(`{"cv_rmsle":0.48,"final_rmsle":0.477,"final_rmse":214.58,"n_series":1782,"onnx_valid":true,"exported_at":"2026-10-02T08:30:00+00:00","prediction_intervals":{"lower_80":-10}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadTrainingMetrics(path); err != nil {
+		t.Fatalf("LoadTrainingMetrics failed: %v", err)
+	}
+	resp = getModel(t, h)
+	m := resp.TrainingMetrics
+	if m == nil || m.FinalRMSLE != 0.477 || m.NSeries != 1782 || m.ONNXValid == nil || !*m.ONNXValid {
+		t.Fatalf("unexpected training metrics %+v", m)
+	}
+	exported := time.Date(2026, 10, 2, 8, 30, 0, 0, time.UTC)
+	if resp.ExportedAt == nil || !resp.ExportedAt.Equal(exported) {
+		t.Errorf("expected export time %v from the training metrics, got %v", exported, resp.ExportedAt)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"final_rmsle":0.46}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.RefreshModel(httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil).Context()); err != nil {
+		t.Fatalf("RefreshModel failed: %v", err)
+	}
+	if m := getModel(t, h).TrainingMetrics; m == nil || m.FinalRMSLE != 0.46 {
+		t.Errorf("expected reloaded training metrics, got %+v", m)
+	}
+}
+
+func TestModelWithoutModel(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if err := h.LoadTrainingMetrics(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing metrics file")
+	}
+
+	resp := getModel(t, h)
+	if resp.Loaded || resp.File != nil || resp.TrainingMetrics != nil || resp.Registry != nil {
+		t.Errorf("expected no model metadata, got %+v", resp)
+	}
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/rs/zerolog/log"
+)
+
+// Windows of /models/comparison.
+const (
+	DefaultComparisonWindow = 24 * time.Hour
+	MaxComparisonWindow     = 30 * 24 * time.Hour
+)
+
+// ShadowDivergence summarizes how a challenger's shadow predictions differed from the
+// champion's on the same requests. Deltas are challenger - champion.
+type ShadowDivergence struct {
+	Champion     string  `json:"champion"`
+	Challenger   string  `json:"challenger"`
+	Count        int     `json:"count"`  // Comparisons where both models predicted
+	Errors       int     `json:"errors"` // Challenger failures
+	MeanAbsDelta float64 `json:"mean_abs_delta"`
+	P95AbsDelta  float64 `json:"p95_abs_delta"`
+	MaxAbsDelta  float64 `json:"max_abs_delta"`
+	MeanRelDelta float64 `json:"mean_rel_delta"` // Mean |delta| / |champion|, over non-zero champion predictions
+	Bias         float64 `json:"bias"`           // Mean delta
+}
+
+// ModelLatency is the inference latency of a model's timed predictions in milliseconds.
+type ModelLatency struct {
+	Count  int     `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+}
+
+// ModelAccuracy is a model's error against submitted actuals.
+type ModelAccuracy struct {
+	Count   int     `json:"count"` // Series-dates with an actual
+	MAPE    float64 `json:"mape"`
+	RMSLE   float64 `json:"rmsle"`
+	Bias    float64 `json:"bias"`
+	BiasPct float64 `json:"bias_pct"`
+}
+
+// OnlineModelEvaluation is one registered model's online record over the window.
+type OnlineModelEvaluation struct {
+	Key         string         `json:"key"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version,omitempty"`
+	Champion    bool           `json:"champion"`
+	Challenger  bool           `json:"challenger"`
+	Predictions int            `json:"predictions"` // Logged predictions, as champion or challenger
+	Latency     *ModelLatency  `json:"latency,omitempty"`
+	Accuracy    *ModelAccuracy `json:"accuracy,omitempty"`
+}
+
+// ModelComparisonResponse compares the registered models over the shadow-mode log.
+type ModelComparisonResponse struct {
+	Window      string                  `json:"window"`
+	Since       time.Time               `json:"since"`
+	Champion    string                  `json:"champion"`
+	Challenger  string                  `json:"challenger"`
+	Comparisons int                     `json:"comparisons"` // Logged comparisons in the window
+	Pairs       []ShadowDivergence      `json:"pairs"`       // One per champion/challenger pairing, e.g. before and after a promotion
+	Models      []OnlineModelEvaluation `json:"models"`
+}
+
+// PromoteModelRequest names the registered model to make the champion.
+type PromoteModelRequest struct {
+	Model string `json:"model"`
+}
+
+// seriesDate identifies a prediction joined to an actual.
+type seriesDate struct {
+	storeNbr int
+	family   string
+	date     string
+}
+
+// modelLog collects one model's side of the shadow comparisons.
+type modelLog struct {
+	count     int
+	latencies []float64
+	latest    map[seriesDate]float64 // Latest prediction per series-date
+}
+
+// ModelComparison reports, for each registered model, the divergence of shadow
+// predictions from the champion's, their error against submitted actuals and their
+// inference latency, over the shadow-mode comparisons of the last ?window= (a
+// duration, default 24h) and optionally one ?family=. Predictions served from cache
+// count for divergence and accuracy but not latency.
+func (h *Handlers) ModelComparison(w http.ResponseWriter, r *http.Request) {
+	if h.registry == nil {
+		WriteServiceUnavailable(w, r, "model comparison requires a model registry", CodeRegistryUnavailable)
+		return
+	}
+	if h.shadow == nil {
+		WriteServiceUnavailable(w, r, "shadow mode not enabled (set SHADOW_MODEL)", CodeShadowUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	window := DefaultComparisonWindow
+	if val := q.Get("window"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed <= 0 || parsed > MaxComparisonWindow {
+			WriteBadRequest(w, r, fmt.Sprintf("window must be a duration up to %s, e.g. 24h", MaxComparisonWindow), CodeInvalidRequest)
+			return
+		}
+		window = parsed
+	}
+	family := q.Get("family")
+	if family != "" {
+		if verr := ValidateFamily(family); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+
+	since := time.Now().Add(-window)
+	var records []inference.ShadowRecord
+	for _, rec := range h.shadow.Records(since) {
+		if family == "" || rec.Family == family {
+			records = append(records, rec)
+		}
+	}
+
+	resp := ModelComparisonResponse{
+		Window:      window.String(),
+		Since:       since,
+		Champion:    h.registry.Champion(),
+		Challenger:  h.shadow.Name(),
+		Comparisons: len(records),
+		Pairs:       shadowDivergence(records),
+	}
+
+	logs := make(map[string]*modelLog)
+	side := func(key string, sd seriesDate, prediction float32, latency time.Duration) {
+		l, ok := logs[key]
+		if !ok {
+			l = &modelLog{latest: make(map[seriesDate]float64)}
+			logs[key] = l
+		}
+		l.count++
+		if latency > 0 {
+			l.latencies = append(l.latencies, float64(latency.Microseconds())/1000)
+		}
+		if sd.date != "" {
+			l.latest[sd] = float64(prediction)
+		}
+	}
+	minDate, maxDate := "", ""
+	for _, rec := range records {
+		sd := seriesDate{storeNbr: rec.StoreNbr, family: rec.Family, date: rec.Date}
+		side(rec.ChampionKey, sd, rec.Champion, rec.ChampionLatency)
+		if !rec.Err {
+			side(rec.ChallengerKey, sd, rec.Challenger, rec.ChallengerLatency)
+		}
+		if rec.Date != "" && (minDate == "" || rec.Date < minDate) {
+			minDate = rec.Date
+		}
+		if rec.Date > maxDate {
+			maxDate = rec.Date
+		}
+	}
+	observed, err := h.observedActuals(family, minDate, maxDate)
+	if err != nil {
+		log.Error().Err(err).Msg("Model comparison failed")
+		WriteServiceUnavailable(w, r, "actuals store unavailable", CodeActualsUnavailable)
+		return
+	}
+
+	for _, m := range h.registry.Models() {
+		eval := OnlineModelEvaluation{
+			Key:        m.Key,
+			Name:       m.Name,
+			Version:    m.Version,
+			Champion:   m.Champion,
+			Challenger: m.Key == resp.Challenger,
+		}
+		if l, ok := logs[m.Key]; ok {
+			eval.Predictions = l.count
+			eval.Latency = latencyStats(l.latencies)
+			eval.Accuracy = accuracyAgainst(l.latest, observed)
+		}
+		resp.Models = append(resp.Models, eval)
+	}
+	if resp.Models == nil {
+		resp.Models = []OnlineModelEvaluation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PromoteModel makes a registered model the champion serving requests without a
+// model. When it was the shadow challenger, the previous champion becomes the
+// challenger, so the comparison continues the other way round. Cached predictions,
+// hierarchies and backtests of the previous champion are cleared. The promotion is
+// not written to the manifest and is lost on restart.
+func (h *Handlers) PromoteModel(w http.ResponseWriter, r *http.Request) {
+	if h.registry == nil {
+		WriteServiceUnavailable(w, r, "model promotion requires a model registry", CodeRegistryUnavailable)
+		return
+	}
+	var req PromoteModelRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Model == "" {
+		WriteBadRequest(w, r, "model is required", CodeInvalidRequest)
+		return
+	}
+	_, key, ok := h.registry.Get(req.Model)
+	if !ok {
+		WriteBadRequest(w, r, fmt.Sprintf("unknown model: %s", req.Model), CodeInvalidModel)
+		return
+	}
+
+	previous := h.registry.Champion()
+	resp := ReloadResponse{
+		Status:   "unchanged",
+		Message:  fmt.Sprintf("%s is already the champion", key),
+		Metadata: map[string]interface{}{"champion": key, "previous": previous},
+	}
+	if key != previous {
+		previousModel, _, _ := h.registry.Get(previous)
+		if err := h.registry.SetChampion(key); err != nil {
+			WriteInternalError(w, r, err.Error(), CodeReloadFailed)
+			return
+		}
+		if h.shadow != nil && h.shadow.Name() == key && previousModel != nil {
+			h.shadow.SetChallenger(previous, previousModel)
+		}
+		log.Info().Str("champion", key).Str("previous", previous).Msg("Model promoted to champion")
+
+		ctx := r.Context()
+		h.RotateCacheNamespace()
+		h.bumpRevision()
+		h.startCacheWarm()
+		h.invalidateHierarchy(ctx)
+		h.invalidateBacktests()
+		h.notifyLive(live.ReasonModelPromoted)
+		resp.Status = "promoted"
+		resp.Message = fmt.Sprintf("%s promoted to champion", key)
+	}
+	if h.shadow != nil {
+		resp.Metadata["challenger"] = h.shadow.Name()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// observedActuals returns the submitted actuals between two dates, by series-date.
+func (h *Handlers) observedActuals(family, startDate, endDate string) (map[seriesDate]float64, error) {
+	if h.actuals == nil || startDate == "" {
+		return nil, nil
+	}
+	records, err := h.actuals.List(actuals.Filter{Family: family, StartDate: startDate, EndDate: endDate})
+	if err != nil {
+		return nil, err
+	}
+	observed := make(map[seriesDate]float64)
+	for _, rec := range records {
+		observed[seriesDate{storeNbr: rec.StoreNbr, family: rec.Family, date: rec.Date}] = rec.Actual
+	}
+	return observed, nil
+}
+
+// shadowDivergence summarizes records per champion/challenger pair, ordered by
+// champion then challenger.
+func shadowDivergence(records []inference.ShadowRecord) []ShadowDivergence {
+	type pair struct{ champion, challenger string }
+	deltas := make(map[pair][]float64)
+	out := make(map[pair]*ShadowDivergence)
+	rel := make(map[pair][]float64)
+	for _, rec := range records {
+		p := pair{rec.ChampionKey, rec.ChallengerKey}
+		d, ok := out[p]
+		if !ok {
+			d = &ShadowDivergence{Champion: p.champion, Challenger: p.challenger}
+			out[p] = d
+		}
+		if rec.Err {
+			d.Errors++
+			continue
+		}
+		delta := float64(rec.Challenger - rec.Champion)
+		deltas[p] = append(deltas[p], delta)
+		if rec.Champion != 0 {
+			rel[p] = append(rel[p], math.Abs(delta)/math.Abs(float64(rec.Champion)))
+		}
+	}
+
+	result := make([]ShadowDivergence, 0, len(out))
+	for p, d := range out {
+		abs := make([]float64, len(deltas[p]))
+		var sum, sumAbs float64
+		for i, delta := range deltas[p] {
+			abs[i] = math.Abs(delta)
+			sum += delta
+			sumAbs += abs[i]
+		}
+		if n := len(abs); n > 0 {
+			sort.Float64s(abs)
+			d.Count = n
+			d.MeanAbsDelta = sumAbs / float64(n)
+			d.P95AbsDelta = percentile(abs, 95)
+			d.MaxAbsDelta = abs[n-1]
+			d.Bias = sum / float64(n)
+		}
+		if n := len(rel[p]); n > 0 {
+			var sumRel float64
+			for _, v := range rel[p] {
+				sumRel += v
+			}
+			d.MeanRelDelta = sumRel / float64(n)
+		}
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Champion != result[j].Champion {
+			return result[i].Champion < result[j].Champion
+		}
+		return result[i].Challenger < result[j].Challenger
+	})
+	return result
+}
+
+// latencyStats summarizes latencies in milliseconds, nil when there are none.
+func latencyStats(latencies []float64) *ModelLatency {
+	if len(latencies) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return &ModelLatency{
+		Count:  len(sorted),
+		MeanMs: sum / float64(len(sorted)),
+		P50Ms:  percentile(sorted, 50),
+		P95Ms:  percentile(sorted, 95),
+	}
+}
+
+// accuracyAgainst evaluates the predictions that have an observed actual, nil when none do.
+func accuracyAgainst(predictions, observed map[seriesDate]float64) *ModelAccuracy {
+	var records []actuals.Record
+	for sd, prediction := range predictions {
+		actual, ok := observed[sd]
+		if !ok {
+			continue
+		}
+		p := prediction
+		records = append(records, actuals.Record{StoreNbr: sd.storeNbr, Family: sd.family, Date: sd.date, Actual: actual, Prediction: &p})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	m := actuals.Evaluate(records)
+	return &ModelAccuracy{Count: m.Count, MAPE: m.MAPE, RMSLE: m.RMSLE, Bias: m.Bias, BiasPct: m.BiasPct}
+}
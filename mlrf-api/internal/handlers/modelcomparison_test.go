@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/actuals"
+	"github.com/mlrf/mlrf-api/internal/inference"
+)
+
+// newShadowHandlers returns handlers serving a registry whose champion predicts 100
+// and whose challenger, replayed in shadow mode, predicts 110.
+func newShadowHandlers(t *testing.T) (*Handlers, *inference.Registry, *inference.ShadowRunner) {
+	t.Helper()
+	champion := &MockInferencer{prediction: 100}
+	challenger := &MockInferencer{prediction: 110}
+	registry := inference.NewRegistry()
+	registry.Register(inference.ManifestEntry{Name: "lightgbm", Version: "1", Path: "a.onnx"}, champion)
+	registry.Register(inference.ManifestEntry{Name: "lightgbm", Version: "2", Path: "b.onnx"}, challenger)
+	if err := registry.SetChampion("lightgbm@1"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandlers(registry.Default(), nil, nil, nil)
+	h.SetModelRegistry(registry)
+	shadow := inference.NewShadowRunner("lightgbm@2", challenger, inference.ShadowConfig{Workers: 1, QueueSize: 10, LogSize: 100})
+	t.Cleanup(shadow.Close)
+	h.SetShadowRunner(shadow)
+	return h, registry, shadow
+}
+
+func predictChampion(t *testing.T, h *Handlers, storeNbr int, date string) {
+	t.Helper()
+	body := fmt.Sprintf(`{"store_nbr":%d,"family":"DAIRY","date":%q,"features":[%s0]}`, storeNbr, date, strings.Repeat("0,", RequiredFeatureCount-1))
+	w := httptest.NewRecorder()
+	h.Predict(w, httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func getModelComparison(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, ModelComparisonResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ModelComparison(w, httptest.NewRequest(http.MethodGet, "/v1/models/comparison?"+query, nil))
+	var resp ModelComparisonResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestModelComparison(t *testing.T) {
+	h, _, shadow := newShadowHandlers(t)
+	// Store 2's actual is closer to the challenger
+	s := newTestActualsStore(t)
+	if err := s.Add([]actuals.Record{
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-16", Actual: 100},
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-16", Actual: 110},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h.SetActualsStore(s, 100)
+
+	for storeNbr := 1; storeNbr <= 3; storeNbr++ {
+		predictChampion(t, h, storeNbr, "2017-08-16")
+	}
+	shadow.Close()
+
+	w, resp := getModelComparison(t, h, "window=1h")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Champion != "lightgbm@1" || resp.Challenger != "lightgbm@2" || resp.Comparisons != 3 || resp.Window != "1h0m0s" {
+		t.Fatalf("unexpected comparison %+v", resp)
+	}
+	if len(resp.Pairs) != 1 {
+		t.Fatalf("expected one pair, got %+v", resp.Pairs)
+	}
+	if p := resp.Pairs[0]; p.Count != 3 || p.MeanAbsDelta != 10 || p.MaxAbsDelta != 10 || p.Bias != 10 || math.Abs(p.MeanRelDelta-0.1) > 1e-9 {
+		t.Errorf("unexpected divergence %+v", p)
+	}
+
+	if len(resp.Models) != 2 {
+		t.Fatalf("expected both registered models, got %+v", resp.Models)
+	}
+	champion, challenger := resp.Models[0], resp.Models[1]
+	if !champion.Champion || champion.Predictions != 3 || champion.Latency == nil || champion.Latency.Count != 3 {
+		t.Errorf("unexpected champion evaluation %+v", champion)
+	}
+	if !challenger.Challenger || challenger.Predictions != 3 || challenger.Latency == nil {
+		t.Errorf("unexpected challenger evaluation %+v", challenger)
+	}
+	// Joined on the two series-dates with actuals
+	if champion.Accuracy == nil || champion.Accuracy.Count != 2 || champion.Accuracy.Bias != -5 {
+		t.Errorf("unexpected champion accuracy %+v", champion.Accuracy)
+	}
+	if challenger.Accuracy == nil || challenger.Accuracy.Count != 2 || challenger.Accuracy.Bias != 5 {
+		t.Errorf("unexpected challenger accuracy %+v", challenger.Accuracy)
+	}
+
+	if _, resp := getModelComparison(t, h, "family=BEVERAGES"); resp.Comparisons != 0 || len(resp.Pairs) != 0 || resp.Models[0].Accuracy != nil {
+		t.Errorf("expected no comparisons for another family, got %+v", resp)
+	}
+}
+
+func TestModelComparisonErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := getModelComparison(t, h, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a registry, got %d", w.Code)
+	}
+	h.SetModelRegistry(inference.NewRegistry())
+	w, _ := getModelComparison(t, h, "")
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusServiceUnavailable || errResp.Code != CodeShadowUnavailable {
+		t.Errorf("expected 503 %s without shadow mode, got %d %s", CodeShadowUnavailable, w.Code, errResp.Code)
+	}
+
+	h, _, _ = newShadowHandlers(t)
+	for query, code := range map[string]string{
+		"window=1d":   CodeInvalidRequest,
+		"window=-1h":  CodeInvalidRequest,
+		"window=800h": CodeInvalidRequest,
+		"family=NOPE": CodeInvalidFamily,
+	} {
+		w, _ := getModelComparison(t, h, query)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", query, code, w.Code, resp.Code)
+		}
+	}
+}
+
+func promote(h *Handlers, body string) (*httptest.ResponseRecorder, ReloadResponse) {
+	w := httptest.NewRecorder()
+	h.PromoteModel(w, httptest.NewRequest(http.MethodPost, "/admin/models/promote", bytes.NewReader([]byte(body))))
+	var resp ReloadResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestPromoteModel(t *testing.T) {
+	h, registry, shadow := newShadowHandlers(t)
+
+	w, resp := promote(h, `{"model":"lightgbm@2"}`)
+	if w.Code != http.StatusOK || resp.Status != "promoted" {
+		t.Fatalf("expected promotion, got %d: %s", w.Code, w.Body.String())
+	}
+	if registry.Champion() != "lightgbm@2" || resp.Metadata["previous"] != "lightgbm@1" {
+		t.Errorf("expected lightgbm@2 to replace lightgbm@1, got %s %+v", registry.Champion(), resp.Metadata)
+	}
+	// The former champion is now shadowed, and requests follow the new champion
+	if shadow.Name() != "lightgbm@1" || resp.Metadata["challenger"] != "lightgbm@1" {
+		t.Errorf("expected the former champion to become the challenger, got %s", shadow.Name())
+	}
+	if p, err := h.onnx.Predict(make([]float32, RequiredFeatureCount)); err != nil || p != 110 {
+		t.Errorf("expected the new champion to serve, got %v %v", p, err)
+	}
+
+	if _, resp := promote(h, `{"model":"lightgbm@2"}`); resp.Status != "unchanged" {
+		t.Errorf("expected promoting the champion to be a no-op, got %+v", resp)
+	}
+}
+
+func TestPromoteModelErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if w, _ := promote(h, `{"model":"lightgbm"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a registry, got %d", w.Code)
+	}
+
+	h, _, _ = newShadowHandlers(t)
+	for body, code := range map[string]string{
+		`{}`:                  CodeInvalidRequest,
+		`{"model":"xgboost"}`: CodeInvalidModel,
+	} {
+		w, _ := promote(h, body)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", body, code, w.Code, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EvaluationMetrics are a model's errors on held-out data.
+type EvaluationMetrics struct {
+	RMSLE    float64 `json:"rmsle"`
+	MAPE     float64 `json:"mape"`
+	RMSE     float64 `json:"rmse"`
+	MAE      float64 `json:"mae,omitempty"`
+	NSamples int     `json:"n_samples,omitempty"`
+}
+
+// ModelEvaluation is one model of model_metrics.json: its overall metrics and their
+// breakdowns by forecast horizon (in days, as a string) and product family.
+type ModelEvaluation struct {
+	Model string `json:"model"`
+	EvaluationMetrics
+	ByHorizon map[string]EvaluationMetrics `json:"by_horizon,omitempty"`
+	ByFamily  map[string]EvaluationMetrics `json:"by_family,omitempty"`
+}
+
+// ModelMetricsFile is the model comparison exported by the training pipeline to
+// model_metrics.json.
+type ModelMetricsFile struct {
+	GeneratedAt *time.Time        `json:"generated_at,omitempty"`
+	Models      []ModelEvaluation `json:"models"`
+}
+
+// ModelMetric represents model performance metrics for comparison.
+type ModelMetric struct {
+	Model    string   `json:"model"`
+	RMSLE    float64  `json:"rmsle"`
+	MAPE     float64  `json:"mape"`
+	RMSE     float64  `json:"rmse"`
+	MAE      float64  `json:"mae,omitempty"`
+	NSamples int      `json:"n_samples,omitempty"`
+	Horizon  int      `json:"horizon,omitempty"`  // Set when filtered by ?horizon=
+	Family   string   `json:"family,omitempty"`   // Set when filtered by ?family=
+	Horizons []int    `json:"horizons,omitempty"` // Horizons ?horizon= can select for this model
+	Families []string `json:"families,omitempty"` // Families ?family= can select for this model
+	IsMock   bool     `json:"is_mock,omitempty"`  // Estimated sample data, without model_metrics.json
+}
+
+// mockModelMetrics returns sample comparison data when model_metrics.json is not
+// available - LightGBM from an actual training run, the others estimated.
+func mockModelMetrics() []ModelMetric {
+	return []ModelMetric{
+		{Model: "LightGBM + MinTrace", RMSLE: 0.4770, MAPE: 0.15, RMSE: 214.58, IsMock: true},
+		{Model: "AutoARIMA + BottomUp", RMSLE: 0.5200, MAPE: 0.19, RMSE: 245.00, IsMock: true},
+		{Model: "ETS + TopDown", RMSLE: 0.5800, MAPE: 0.22, RMSE: 280.00, IsMock: true},
+		{Model: "SeasonalNaive", RMSLE: 0.6521, MAPE: 0.28, RMSE: 320.00, IsMock: true},
+	}
+}
+
+// LoadModelMetrics loads the model comparison from a JSON file written by the training
+// pipeline. It is loaded again whenever the model reloads. This is optional - if the
+// file doesn't exist, /model-metrics serves sample data (see allowMock).
+func (h *Handlers) LoadModelMetrics(path string) error {
+	h.modelMetricsMu.Lock()
+	defer h.modelMetricsMu.Unlock()
+	h.modelMetricsPath = path
+
+	m, err := readModelMetrics(path)
+	h.modelMetrics = m
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load model metrics")
+		return err
+	}
+	log.Info().Str("path", path).Int("models", len(m.Models)).Msg("Model metrics loaded")
+	return nil
+}
+
+// refreshModelMetrics loads the model comparison again after a model reload, if it
+// was loaded at startup.
+func (h *Handlers) refreshModelMetrics() {
+	h.modelMetricsMu.RLock()
+	path := h.modelMetricsPath
+	h.modelMetricsMu.RUnlock()
+	if path != "" {
+		h.LoadModelMetrics(path)
+	}
+}
+
+func readModelMetrics(path string) (*ModelMetricsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m ModelMetricsFile
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse model metrics: %w", err)
+	}
+	if len(m.Models) == 0 {
+		return nil, errors.New("no models in model metrics")
+	}
+	return &m, nil
+}
+
+// ModelMetrics returns model comparison metrics for the dashboard, from the training
+// pipeline's model_metrics.json. ?horizon= or ?family= select a breakdown instead of
+// the overall metrics; models without it are left out. Without the file it returns
+// flagged sample data (see allowMock). Supports If-None-Match (see notModified).
+func (h *Handlers) ModelMetrics(w http.ResponseWriter, r *http.Request) {
+	h.modelMetricsMu.RLock()
+	file := h.modelMetrics
+	h.modelMetricsMu.RUnlock()
+
+	var fileVersion string
+	if file != nil && file.GeneratedAt != nil {
+		fileVersion = file.GeneratedAt.String()
+	}
+	if h.notModified(w, r, fileVersion) {
+		return
+	}
+
+	var horizon int
+	if hz := r.URL.Query().Get("horizon"); hz != "" {
+		horizon, _ = strconv.Atoi(hz)
+		if verr := ValidateHorizon(horizon); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	family := r.URL.Query().Get("family")
+	if family != "" {
+		if verr := ValidateFamily(family); verr != nil {
+			WriteBadRequest(w, r, verr.Message, verr.Code)
+			return
+		}
+	}
+	if horizon != 0 && family != "" {
+		WriteBadRequest(w, r, "filter by horizon or family, not both", CodeInvalidRequest)
+		return
+	}
+
+	if file == nil {
+		if h.allowMock(w, r, "model_metrics") {
+			metrics := mockModelMetrics()
+			if horizon != 0 || family != "" {
+				metrics = []ModelMetric{} // Sample data has no breakdowns
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(metrics)
+		}
+		return
+	}
+
+	metrics := make([]ModelMetric, 0, len(file.Models))
+	for _, m := range file.Models {
+		values := m.EvaluationMetrics
+		switch {
+		case horizon != 0:
+			v, ok := m.ByHorizon[strconv.Itoa(horizon)]
+			if !ok {
+				continue
+			}
+			values = v
+		case family != "":
+			v, ok := m.ByFamily[family]
+			if !ok {
+				continue
+			}
+			values = v
+		}
+
+		metric := ModelMetric{
+			Model:    m.Model,
+			RMSLE:    values.RMSLE,
+			MAPE:     values.MAPE,
+			RMSE:     values.RMSE,
+			MAE:      values.MAE,
+			NSamples: values.NSamples,
+			Horizon:  horizon,
+			Family:   family,
+		}
+		if horizon == 0 && family == "" {
+			metric.Horizons, metric.Families = m.breakdowns()
+		}
+		metrics = append(metrics, metric)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// breakdowns lists the horizons and families the model's metrics are broken down by,
+// in ascending order.
+func (m ModelEvaluation) breakdowns() ([]int, []string) {
+	var horizons []int
+	for key := range m.ByHorizon {
+		if hz, err := strconv.Atoi(key); err == nil {
+			horizons = append(horizons, hz)
+		}
+	}
+	sort.Ints(horizons)
+
+	var families []string
+	for family := range m.ByFamily {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	return horizons, families
+}
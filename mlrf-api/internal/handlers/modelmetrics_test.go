@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+)
+
+const testModelMetrics = `{
+	"generated_at": "2026-10-02T08:30:00+00:00",
+	"models": [
+		{"model": "LightGBM + MinTrace", "rmsle": 0.47, "mape": 0.14, "rmse": 210.5, "mae": 80.2, "n_samples": 160380,
+		 "by_horizon": {"15": {"rmsle": 0.41, "mape": 0.12, "rmse": 190}, "30": {"rmsle": 0.45, "mape": 0.13, "rmse": 201}},
+		 "by_family": {"DAIRY": {"rmsle": 0.38, "mape": 0.1, "rmse": 150}}},
+		{"model": "SeasonalNaive", "rmsle": 0.65, "mape": 0.28, "rmse": 320,
+		 "by_horizon": {"15": {"rmsle": 0.6, "mape": 0.25, "rmse": 300}}}
+	]
+}`
+
+func getModelMetrics(t *testing.T, h *Handlers, target string) []ModelMetric {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ModelMetrics(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got %d: %s", target, w.Code, w.Body.String())
+	}
+	var metrics []ModelMetric
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return metrics
+}
+
+func TestModelMetrics(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+	path := filepath.Join(t.TempDir(), "model_metrics.json")
+	if err := os.WriteFile(path, []byte(testModelMetrics), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadModelMetrics(path); err != nil {
+		t.Fatalf("LoadModelMetrics failed: %v", err)
+	}
+
+	metrics := getModelMetrics(t, h, "/v1/model-metrics")
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 models, got %+v", metrics)
+	}
+	lgbm := metrics[0]
+	if lgbm.Model != "LightGBM + MinTrace" || lgbm.RMSLE != 0.47 || lgbm.MAE != 80.2 || lgbm.NSamples != 160380 || lgbm.IsMock {
+		t.Errorf("unexpected overall metrics %+v", lgbm)
+	}
+	if len(lgbm.Horizons) != 2 || lgbm.Horizons[0] != 15 || lgbm.Horizons[1] != 30 || len(lgbm.Families) != 1 || lgbm.Families[0] != "DAIRY" {
+		t.Errorf("unexpected breakdowns %v, %v", lgbm.Horizons, lgbm.Families)
+	}
+
+	// A breakdown replaces the overall metrics, leaving out models without it
+	metrics = getModelMetrics(t, h, "/v1/model-metrics?horizon=15")
+	if len(metrics) != 2 || metrics[0].Horizon != 15 || metrics[0].RMSLE != 0.41 || metrics[1].RMSLE != 0.6 || metrics[0].Horizons != nil {
+		t.Errorf("unexpected horizon 15 metrics %+v", metrics)
+	}
+	metrics = getModelMetrics(t, h, "/v1/model-metrics?horizon=30")
+	if len(metrics) != 1 || metrics[0].RMSLE != 0.45 {
+		t.Errorf("expected only LightGBM at horizon 30, got %+v", metrics)
+	}
+	metrics = getModelMetrics(t, h, "/v1/model-metrics?family=DAIRY")
+	if len(metrics) != 1 || metrics[0].Family != "DAIRY" || metrics[0].RMSE != 150 {
+		t.Errorf("unexpected DAIRY metrics %+v", metrics)
+	}
+
+	// The comparison is loaded again when the model reloads
+	h.SetModelReloader(&mockModelReloader{info: inference.ModelInfo{Path: "models/lightgbm_model.onnx", LoadedAt: time.Now()}})
+	if err := os.WriteFile(path, []byte(`{"models":[{"model":"LightGBM + MinTrace","rmsle":0.44}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.RefreshModel(httptest.NewRequest(http.MethodPost, "/admin/reload-model", nil).Context()); err != nil {
+		t.Fatalf("RefreshModel failed: %v", err)
+	}
+	if metrics := getModelMetrics(t, h, "/v1/model-metrics"); len(metrics) != 1 || metrics[0].RMSLE != 0.44 {
+		t.Errorf("expected reloaded model metrics, got %+v", metrics)
+	}
+}
+
+func TestModelMetricsMock(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	if err := h.LoadModelMetrics(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing model metrics file")
+	}
+
+	metrics := getModelMetrics(t, h, "/v1/model-metrics")
+	if len(metrics) != 4 || !metrics[0].IsMock {
+		t.Errorf("expected flagged sample metrics, got %+v", metrics)
+	}
+	if metrics := getModelMetrics(t, h, "/v1/model-metrics?family=DAIRY"); len(metrics) != 0 {
+		t.Errorf("expected no breakdowns of sample metrics, got %+v", metrics)
+	}
+
+	h.SetMockFallbacks(MockFallbacksDeny)
+	w := httptest.NewRecorder()
+	h.ModelMetrics(w, httptest.NewRequest(http.MethodGet, "/v1/model-metrics", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with mock fallbacks denied, got %d", w.Code)
+	}
+}
+
+func TestModelMetricsInvalidFilters(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	for target, code := range map[string]string{
+		"/v1/model-metrics?horizon=7":                  CodeInvalidHorizon,
+		"/v1/model-metrics?family=SHOES":               CodeInvalidFamily,
+		"/v1/model-metrics?horizon=15&family=DAIRY":    CodeInvalidRequest,
+		"/v1/model-metrics?horizon=fifteen&family=ALL": CodeInvalidHorizon,
+	} {
+		w := httptest.NewRecorder()
+		h.ModelMetrics(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to parse response: %v", target, err)
+		}
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", target, code, w.Code, resp.Code)
+		}
+	}
+}
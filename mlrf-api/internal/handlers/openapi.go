@@ -0,0 +1,705 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/openapi"
+)
+
+// APIVersion is the version reported in the OpenAPI document.
+const APIVersion = "1.0.0"
+
+// apiPrefix is the versioned route group documented in the spec.
+// Unversioned legacy paths still work but are deprecated.
+const apiPrefix = "/v1"
+
+var (
+	specOnce sync.Once
+	spec     *openapi.Document
+)
+
+// OpenAPISpec returns the OpenAPI document generated from the handler request/response types.
+// Admin endpoints are intentionally excluded.
+func OpenAPISpec() *openapi.Document {
+	specOnce.Do(func() {
+		spec = buildOpenAPISpec()
+	})
+	return spec
+}
+
+func buildOpenAPISpec() *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "MLRF API",
+		Version:     APIVersion,
+		Description: "Hierarchical revenue forecasting API. Authenticate with the X-API-Key header when API_KEY is set.",
+	})
+
+	badRequest := b.JSONResponse("Invalid request", ErrorResponse{})
+	unavailable := b.JSONResponse("Dependency unavailable", ErrorResponse{})
+	internal := b.JSONResponse("Internal error", ErrorResponse{})
+
+	b.Add(http.MethodGet, "/health", &openapi.Operation{
+		Summary:     "Service health",
+		OperationID: "health",
+		Tags:        []string{"ops"},
+		Responses:   map[string]openapi.Response{"200": b.JSONResponse("Health status", HealthResponse{})},
+	})
+	b.Add(http.MethodGet, "/health/live", &openapi.Operation{
+		Summary:     "Liveness probe",
+		OperationID: "liveness",
+		Tags:        []string{"ops"},
+		Responses:   map[string]openapi.Response{"200": b.JSONResponse("Process is up", ProbeResponse{})},
+	})
+	b.Add(http.MethodGet, "/health/ready", &openapi.Operation{
+		Summary:     "Readiness probe: model loaded, feature store fresh and Redis reachable",
+		OperationID: "readiness",
+		Tags:        []string{"ops"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Ready to serve", ProbeResponse{}),
+			"503": b.JSONResponse("A required check failed", ProbeResponse{}),
+		},
+	})
+
+	b.Add(http.MethodGet, "/slo", &openapi.Operation{
+		Summary:     "Availability and latency compliance, error budget and burn rate per route",
+		OperationID: "slo",
+		Tags:        []string{"ops"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("SLO compliance", SLOResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict", &openapi.Operation{
+		Summary:     "Predict sales from a feature vector",
+		OperationID: "predict",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(PredictRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Prediction", PredictResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict/simple", &openapi.Operation{
+		Summary:     "Predict sales using features from the feature store",
+		OperationID: "predictSimple",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(SimplePredictRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Prediction with confidence intervals", PredictResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict/batch", &openapi.Operation{
+		Summary:     "Predict sales for up to MAX_BATCH_SIZE (default 100) feature vectors",
+		OperationID: "predictBatch",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(BatchPredictRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Predictions", BatchPredictResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	item := b.Schema(PredictResponse{})
+	b.Add(http.MethodPost, apiPrefix+"/predict/stream", &openapi.Operation{
+		Summary:     "Stream predictions as NDJSON, or SSE with Accept: text/event-stream",
+		OperationID: "predictStream",
+		Tags:        []string{"predictions"},
+		Parameters: []openapi.Parameter{
+			{Name: "format", In: "query", Description: "ndjson (default) or sse", Schema: &openapi.Schema{Type: "string"}},
+		},
+		RequestBody: b.JSONBody(BatchPredictRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "One PredictResponse per item, in input order",
+				Content: map[string]openapi.MediaType{
+					"application/x-ndjson": {Schema: item},
+					"text/event-stream":    {Schema: item},
+				},
+			},
+			"400": badRequest,
+		},
+	})
+
+	jobID := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}
+	notFound := b.JSONResponse("Job not found", ErrorResponse{})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict/jobs", &openapi.Operation{
+		Summary:     "Queue a large batch for asynchronous scoring",
+		OperationID: "submitPredictJob",
+		Tags:        []string{"jobs"},
+		RequestBody: b.JSONBody(BatchPredictRequest{}),
+		Responses: map[string]openapi.Response{
+			"202": b.JSONResponse("Job accepted", JobResponse{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/jobs/{id}", &openapi.Operation{
+		Summary:     "Job status",
+		OperationID: "getJob",
+		Tags:        []string{"jobs"},
+		Parameters:  []openapi.Parameter{jobID},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Job status", JobResponse{}),
+			"404": notFound,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/jobs/{id}/result", &openapi.Operation{
+		Summary:     "Download the predictions of a completed job",
+		OperationID: "getJobResult",
+		Tags:        []string{"jobs"},
+		Parameters:  []openapi.Parameter{jobID},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Job predictions", JobResultResponse{}),
+			"404": notFound,
+			"409": b.JSONResponse("Job not completed", ErrorResponse{}),
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/forecast", &openapi.Operation{
+		Summary:     "Daily multi-horizon forecast",
+		OperationID: "forecast",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(ForecastRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Forecast series", ForecastResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/whatif", &openapi.Operation{
+		Summary:     "Compare a prediction, or a forecast window in horizon mode, against adjusted features",
+		OperationID: "whatIf",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(WhatIfRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Original and adjusted predictions, with the daily series in horizon mode", WhatIfResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/whatif/sweep", &openapi.Operation{
+		Summary:     "Prediction curve over a range of adjustments of one feature",
+		OperationID: "whatIfSweep",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(WhatIfSweepRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Baseline and one prediction per step", WhatIfSweepResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/promotions/impact", &openapi.Operation{
+		Summary:     "Incremental sales of promotion plans, aggregated up the hierarchy",
+		OperationID: "promotionsImpact",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(PromotionImpactRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Uplift per plan, hierarchy node and family, and in total", PromotionImpactResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/features/schema", &openapi.Operation{
+		Summary:     "Model input features in feature vector order",
+		OperationID: "featureSchema",
+		Tags:        []string{"predictions"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Feature schema", FeatureSchemaResponse{}),
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/families", &openapi.Operation{
+		Summary:     "Product families in the feature matrix, with encoded IDs",
+		OperationID: "families",
+		Tags:        []string{"predictions"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Families", FamiliesResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/stores", &openapi.Operation{
+		Summary:     "Stores in the feature matrix, with cluster and type",
+		OperationID: "stores",
+		Tags:        []string{"predictions"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Stores", StoresResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/calendar/holidays", &openapi.Operation{
+		Summary:     "Holidays of the calendar that sets is_holiday of rolled-forward days",
+		OperationID: "holidays",
+		Tags:        []string{"predictions"},
+		Parameters: []openapi.Parameter{
+			{Name: "start_date", In: "query", Description: "First date, default the calendar's first holiday", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "end_date", In: "query", Description: "Last date, default the calendar's last holiday", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "year", In: "query", Description: "Holidays of one year, instead of a date range", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "locale", In: "query", Description: "National, Regional or Local holidays only", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Holidays in date order", HolidaysResponse{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/explain", &openapi.Operation{
+		Summary:     "SHAP explanation for a prediction",
+		OperationID: "explain",
+		Tags:        []string{"explanations"},
+		RequestBody: b.JSONBody(ExplainRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("SHAP waterfall", ExplainResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No precomputed waterfall for the series (offline engine)", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/explain/global", &openapi.Operation{
+		Summary:     "Global mean |SHAP| feature importance, overall and per family",
+		OperationID: "globalImportance",
+		Tags:        []string{"explanations"},
+		Parameters: []openapi.Parameter{{
+			Name:        "family",
+			In:          "query",
+			Description: "Product family; omit for overall and per-family importance",
+			Schema:      &openapi.Schema{Type: "string"},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Feature importance", GlobalImportanceResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No importance for the family", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	hierarchyParams := []openapi.Parameter{{
+		Name:        "date",
+		In:          "query",
+		Description: "Forecast date (YYYY-MM-DD), defaults to 2017-08-01",
+		Schema:      &openapi.Schema{Type: "string", Format: "date"},
+	}, {
+		Name:        "method",
+		In:          "query",
+		Description: "Reconciliation method: bottom_up, top_down, mint or ols. Omit for unreconciled forecasts",
+		Schema:      &openapi.Schema{Type: "string"},
+	}, {
+		Name:        "horizon",
+		In:          "query",
+		Description: "Forecast horizon in days used to refresh leaf forecasts when reconciling",
+		Schema:      &openapi.Schema{Type: "integer"},
+	}}
+
+	b.Add(http.MethodGet, apiPrefix+"/hierarchy", &openapi.Operation{
+		Summary:     "Forecast hierarchy tree",
+		OperationID: "hierarchy",
+		Tags:        []string{"hierarchy"},
+		Parameters:  hierarchyParams,
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Hierarchy tree", HierarchyNode{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/hierarchy/validate", &openapi.Operation{
+		Summary:     "Check that every aggregate equals the sum of its children",
+		OperationID: "validateHierarchy",
+		Tags:        []string{"hierarchy"},
+		Parameters: append([]openapi.Parameter{{
+			Name:        "tolerance",
+			In:          "query",
+			Description: "Accepted relative deviation of a parent from its children's sum (default 0.001)",
+			Schema:      &openapi.Schema{Type: "number"},
+		}}, hierarchyParams...),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Per-node residuals", HierarchyValidationResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/hierarchy/{nodeID}/children", &openapi.Operation{
+		Summary:     "Page through a hierarchy node's children",
+		OperationID: "hierarchyChildren",
+		Tags:        []string{"hierarchy"},
+		Parameters: append([]openapi.Parameter{{
+			Name:        "nodeID",
+			In:          "path",
+			Required:    true,
+			Description: "Node ID; escape \"/\" as %2F",
+			Schema:      &openapi.Schema{Type: "string"},
+		}, {
+			Name:        "offset",
+			In:          "query",
+			Description: "Children to skip (default 0)",
+			Schema:      &openapi.Schema{Type: "integer"},
+		}, {
+			Name:        "limit",
+			In:          "query",
+			Description: "Children per page (default 50, max 500)",
+			Schema:      &openapi.Schema{Type: "integer"},
+		}, {
+			Name:        "depth",
+			In:          "query",
+			Description: "Levels returned below the node (default 1, max 5)",
+			Schema:      &openapi.Schema{Type: "integer"},
+		}, {
+			Name:        "min_prediction",
+			In:          "query",
+			Description: "Omit nodes predicted below this value",
+			Schema:      &openapi.Schema{Type: "number"},
+		}}, hierarchyParams...),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Page of children", HierarchyChildrenResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("Node not found", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/historical", &openapi.Operation{
+		Summary:     "Daily sales of a series, store, family or the total",
+		OperationID: "historical",
+		Tags:        []string{"history"},
+		RequestBody: b.JSONBody(HistoricalRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Historical sales", HistoricalResponse{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/series", &openapi.Operation{
+		Summary:     "Contiguous daily history and forecast with intervals of a series or aggregate, paged by date",
+		OperationID: "series",
+		Tags:        []string{"history"},
+		Parameters: []openapi.Parameter{
+			{Name: "level", In: "query", Description: "series (default), store, family or total", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "store_nbr", In: "query", Description: "Store, required at series and store level", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "family", In: "query", Description: "Product family, required at series and family level", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "start_date", In: "query", Description: "First forecast day, default the day after the last history day", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "horizon", In: "query", Description: "Forecast days (default the shortest valid horizon)", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "history_days", In: "query", Description: "Days of history before start_date (default 28, max 365)", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "offset", In: "query", Description: "Days to skip (default 0)", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "limit", In: "query", Description: "Days per page (default 100, max 500)", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("A page of history and forecast days", SeriesResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No series in the feature matrix for the node", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/accuracy", &openapi.Operation{
+		Summary:     "Predicted vs actual accuracy, live from submitted actuals or from the validation set",
+		OperationID: "accuracy",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "store_nbr", In: "query", Description: "Filter live accuracy to a store", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "family", In: "query", Description: "Filter live accuracy to a family", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "start_date", In: "query", Description: "First date (inclusive)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "end_date", In: "query", Description: "Last date (inclusive)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Accuracy data", AccuracyResponse{}),
+			"400": badRequest,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/accuracy/calibration", &openapi.Operation{
+		Summary:     "Empirical 80% and 95% interval coverage against submitted actuals, with recalibrated family offsets",
+		OperationID: "calibration",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "store_nbr", In: "query", Description: "Only actuals of this store", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "family", In: "query", Description: "Only actuals of this family", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "start_date", In: "query", Description: "First date (inclusive)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "end_date", In: "query", Description: "Last date (inclusive)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "min_samples", In: "query", Description: "Actuals a family needs to be graded (default 30)", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "tolerance", In: "query", Description: "Largest coverage gap from the band's level still graded ok (default 0.05)", Schema: &openapi.Schema{Type: "number"}},
+			{Name: "format", In: "query", Description: "intervals: return the loaded intervals file with the recalibrated offsets in by_family", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Interval coverage overall and per family", CalibrationResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No actuals with a prediction and interval", ErrorResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/actuals", &openapi.Operation{
+		Summary:     "Submit realized sales for accuracy tracking",
+		OperationID: "submitActuals",
+		Tags:        []string{"metrics"},
+		RequestBody: b.JSONBody(ActualsRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Actuals stored", ActualsResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/alerts", &openapi.Operation{
+		Summary:     "Forecast accuracy alerts per family",
+		OperationID: "alerts",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "state", In: "query", Description: "Filter by alert state: firing or resolved", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Alert state", AlertsResponse{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/monitoring/feature-drift", &openapi.Operation{
+		Summary:     "Per-feature PSI and KS drift of recent features against the training reference",
+		OperationID: "featureDrift",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "end_date", In: "query", Description: "Last date of the window (default: latest feature date)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "window_days", In: "query", Description: "Days in the window (default FEATURE_DRIFT_WINDOW_DAYS)", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Feature drift", FeatureDriftResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No feature rows in the window", ErrorResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/monitoring/anomalies", &openapi.Operation{
+		Summary:     "Recent actuals outside their forecast interval or by a robust residual z-score, most severe first",
+		OperationID: "anomalies",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "end_date", In: "query", Description: "Last date of the window (default: latest actual with a prediction)", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+			{Name: "days", In: "query", Description: "Days in the window (default ANOMALY_WINDOW_DAYS, max 90)", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "store_nbr", In: "query", Description: "Only this store", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "family", In: "query", Description: "Only this product family", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "method", In: "query", Description: "auto, interval or zscore (default ANOMALY_METHOD)", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "severity", In: "query", Description: "Minimum severity: warning or critical", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "limit", In: "query", Description: "Maximum anomalies returned (default 100, max 1000)", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Ranked anomalies with a per-store rollup", AnomaliesResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No actuals with a prediction", ErrorResponse{}),
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/backtest", &openapi.Operation{
+		Summary:     "Replay a date range through the model and score it against realized sales",
+		OperationID: "backtest",
+		Tags:        []string{"metrics"},
+		RequestBody: b.JSONBody(BacktestRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Rolling-origin accuracy and interval coverage", BacktestResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No realized sales in the range", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/model-metrics", &openapi.Operation{
+		Summary:     "Model comparison metrics from training, overall or by horizon or family",
+		OperationID: "modelMetrics",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "horizon", In: "query", Description: "Metrics over the first horizon days of the validation window", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "family", In: "query", Description: "Metrics of one product family", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Model metrics", []ModelMetric{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/models/comparison", &openapi.Operation{
+		Summary:     "Champion/challenger divergence, error vs actuals and latency of each registered model from the shadow-mode log",
+		OperationID: "modelComparison",
+		Tags:        []string{"metrics"},
+		Parameters: []openapi.Parameter{
+			{Name: "window", In: "query", Description: "Duration of shadow comparisons to evaluate, e.g. 24h (default 24h, at most 720h)", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "family", In: "query", Description: "Only comparisons of this family", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Divergence per champion/challenger pair and evaluation per model", ModelComparisonResponse{}),
+			"400": badRequest,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, apiPrefix+"/model", &openapi.Operation{
+		Summary:     "Serving model metadata: version, file hash, ONNX opset, feature schema, training metrics and registry champion",
+		OperationID: "model",
+		Tags:        []string{"metrics"},
+		Responses:   map[string]openapi.Response{"200": b.JSONResponse("Model metadata", ModelResponse{})},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict/aggregate", &openapi.Operation{
+		Summary:     "Forecast a store, family, cluster or the total by summing its series",
+		OperationID: "predictAggregate",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(AggregateRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Aggregated prediction", AggregateResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No series at this level", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/predict/replay", &openapi.Operation{
+		Summary:     "Re-run a logged request's predictions or a feature vector against a model version and diff the results",
+		OperationID: "predictReplay",
+		Tags:        []string{"predictions"},
+		RequestBody: b.JSONBody(ReplayRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Original and replayed predictions", ReplayResponse{}),
+			"400": badRequest,
+			"404": b.JSONResponse("No predictions logged under the request ID", ErrorResponse{}),
+			"422": b.JSONResponse("A prediction logged without features and no feature store", ErrorResponse{}),
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodPost, apiPrefix+"/insights/top-movers", &openapi.Operation{
+		Summary:     "Series whose predictions changed most versus compare_days earlier",
+		OperationID: "topMovers",
+		Tags:        []string{"insights"},
+		RequestBody: b.JSONBody(TopMoversRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Top movers by absolute and percentage delta", TopMoversResponse{}),
+			"400": badRequest,
+			"500": internal,
+			"503": unavailable,
+		},
+	})
+
+	b.Add(http.MethodGet, "/ws", &openapi.Operation{
+		Summary:     "WebSocket for live prediction updates; send LiveRequest messages to subscribe",
+		OperationID: "live",
+		Tags:        []string{"predictions"},
+		Parameters: []openapi.Parameter{{
+			Name:        middleware.WebSocketTokenParam,
+			In:          "query",
+			Description: "Token from POST /ws/token, for browsers that can't send X-API-Key",
+			Schema:      &openapi.Schema{Type: "string"},
+		}},
+		Responses: map[string]openapi.Response{
+			"101": {Description: "Switched to WebSocket; server sends live.Message JSON frames"},
+			"401": b.JSONResponse("Missing, invalid or expired API key or token", ErrorResponse{}),
+			"403": {Description: "Origin not allowed"},
+			"503": b.JSONResponse("Live updates not enabled", ErrorResponse{}),
+		},
+	})
+
+	b.Add(http.MethodPost, "/ws/token", &openapi.Operation{
+		Summary:     "Short-lived token authenticating a /ws upgrade with the request's API key",
+		OperationID: "liveToken",
+		Tags:        []string{"predictions"},
+		Responses: map[string]openapi.Response{
+			"200": b.JSONResponse("Token to pass as the token query parameter of /ws", middleware.WebSocketToken{}),
+			"401": b.JSONResponse("Missing or invalid API key", ErrorResponse{}),
+		},
+	})
+
+	doc := b.Document()
+	setHorizonEnum(doc)
+	return doc
+}
+
+// setHorizonEnum restricts every integer horizon property and parameter to ValidHorizons.
+func setHorizonEnum(doc *openapi.Document) {
+	var enum []interface{}
+	for _, h := range Horizons() {
+		enum = append(enum, h)
+	}
+	for _, s := range doc.Components.Schemas {
+		if prop, ok := s.Properties["horizon"]; ok && prop.Type == "integer" {
+			prop.Enum = enum
+		}
+	}
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			for _, p := range op.Parameters {
+				if p.Name == "horizon" && p.Schema != nil {
+					p.Schema.Enum = enum
+				}
+			}
+		}
+	}
+}
+
+// OpenAPI serves the OpenAPI document as JSON.
+func (h *Handlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OpenAPISpec())
+}
+
+// swaggerUIPage renders Swagger UI from the CDN against /openapi.json.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>MLRF API Docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Docs serves Swagger UI for the OpenAPI document.
+func (h *Handlers) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+
+	h.OpenAPI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse spec: %v", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("expected OpenAPI 3 document, got %s", doc.OpenAPI)
+	}
+
+	for path, method := range map[string]string{
+		"/health/ready":       "get",
+		"/v1/predict":         "post",
+		"/v1/predict/batch":   "post",
+		"/v1/whatif":          "post",
+		"/v1/features/schema": "get",
+		"/v1/families":        "get",
+		"/v1/stores":          "get",
+		"/v1/explain":         "post",
+		"/v1/hierarchy":       "get",
+		"/v1/historical":      "post",
+		"/v1/accuracy":        "get",
+	} {
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("expected %s %s in spec", strings.ToUpper(method), path)
+		}
+	}
+
+	for _, schema := range []string{"PredictRequest", "PredictResponse", "BatchPredictRequest", "ErrorResponse", "HierarchyNode"} {
+		if _, ok := doc.Components.Schemas[schema]; !ok {
+			t.Errorf("expected schema %s in components", schema)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	w := httptest.NewRecorder()
+
+	h.Docs(w, req)
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML content type, got %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Error("expected Swagger UI to load /openapi.json")
+	}
+}
+
+func TestOpenAPIHorizonEnum(t *testing.T) {
+	SetValidHorizons([]int{45, 7})
+	t.Cleanup(func() { SetValidHorizons(DefaultHorizons) })
+
+	doc := buildOpenAPISpec()
+	prop := doc.Components.Schemas["SimplePredictRequest"].Properties["horizon"]
+	if len(prop.Enum) != 2 || prop.Enum[0] != 7 || prop.Enum[1] != 45 {
+		t.Errorf("expected horizon enum [7 45], got %v", prop.Enum)
+	}
+	for _, p := range doc.Paths[apiPrefix+"/hierarchy"]["get"].Parameters {
+		if p.Name == "horizon" && len(p.Schema.Enum) != 2 {
+			t.Errorf("expected the horizon parameter to list 2 horizons, got %v", p.Schema.Enum)
+		}
+	}
+}
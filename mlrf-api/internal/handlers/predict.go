@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/tracing"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // PredictRequest represents a single prediction request.
@@ -17,20 +27,41 @@ type PredictRequest struct {
 	Date     string    `json:"date"`
 	Features []float32 `json:"features"`
 	Horizon  int       `json:"horizon"`
+	Model    string    `json:"model,omitempty"` // Registered model name or "name@version"; empty = champion
 }
 
 // PredictResponse represents a single prediction response.
 type PredictResponse struct {
-	StoreNbr   int     `json:"store_nbr"`
-	Family     string  `json:"family"`
-	Date       string  `json:"date"`
-	Prediction float32 `json:"prediction"`
-	Lower80    float32 `json:"lower_80,omitempty"`
-	Upper80    float32 `json:"upper_80,omitempty"`
-	Lower95    float32 `json:"lower_95,omitempty"`
-	Upper95    float32 `json:"upper_95,omitempty"`
-	Cached     bool    `json:"cached"`
-	LatencyMs  float64 `json:"latency_ms"`
+	StoreNbr    int                  `json:"store_nbr"`
+	Family      string               `json:"family"`
+	Date        string               `json:"date"`
+	Prediction  float32              `json:"prediction"`
+	Lower80     float32              `json:"lower_80,omitempty"`
+	Upper80     float32              `json:"upper_80,omitempty"`
+	Lower95     float32              `json:"lower_95,omitempty"`
+	Upper95     float32              `json:"upper_95,omitempty"`
+	Quantiles   *inference.Quantiles `json:"quantiles,omitempty"`    // Model-based P10/P50/P90 when a quantile ensemble is loaded
+	IntervalSet string               `json:"interval_set,omitempty"` // Intervals used for the bands, e.g. "store_family@30d" or "quantile"
+	Model       string               `json:"model,omitempty"`
+	Cached      bool                 `json:"cached"`
+	LatencyMs   float64              `json:"latency_ms"`
+	TraceID     string               `json:"trace_id,omitempty"` // When the request is traced; unset in batches
+
+	// FeatureSource is how /predict/simple looked up features: exact, aggregated,
+	// zeros or rolled_forward. Unset when the client sent the features.
+	FeatureSource features.FeatureSource `json:"feature_source,omitempty"`
+
+	// Guard is the action the prediction guard took on the model output: clamped or
+	// zeroed. Unset when the prediction was served as the model returned it.
+	Guard string `json:"guard,omitempty"`
+}
+
+// predictCacheKey returns the cache key for a request, scoped to the model if one was selected.
+func predictCacheKey(req PredictRequest, modelKey string) string {
+	if req.Model != "" {
+		return cache.GenerateModelCacheKey(modelKey, req.StoreNbr, req.Family, req.Date, req.Horizon)
+	}
+	return cache.GenerateCacheKey(req.StoreNbr, req.Family, req.Date, req.Horizon)
 }
 
 // PredictionIntervals holds the offsets for confidence intervals.
@@ -54,15 +85,20 @@ type BatchPredictRequest struct {
 type BatchPredictResponse struct {
 	Predictions []PredictResponse `json:"predictions"`
 	LatencyMs   float64           `json:"latency_ms"`
+	TraceID     string            `json:"trace_id,omitempty"`
 }
 
 // SimplePredictRequest represents a simplified prediction request without features.
 // Features are generated internally (currently as zeros, future: feature matrix lookup).
 type SimplePredictRequest struct {
-	StoreNbr int    `json:"store_nbr"`
-	Family   string `json:"family"`
-	Date     string `json:"date"`
-	Horizon  int    `json:"horizon"`
+	StoreNbr       int    `json:"store_nbr"`
+	Family         string `json:"family"`
+	Date           string `json:"date"`
+	Horizon        int    `json:"horizon"`
+	StrictFeatures bool   `json:"strict_features,omitempty"` // Return 422 rather than predict on zero features
+	// Values of oil_price or onpromotion on date, e.g. {"oil_price": 48.5}, taking precedence
+	// over REGRESSOR_OVERRIDES_PATH and the looked up features. Such predictions aren't cached.
+	Regressors map[string]float32 `json:"regressors,omitempty"`
 }
 
 // Predict handles single prediction requests.
@@ -71,13 +107,12 @@ func (h *Handlers) Predict(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req PredictRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate request
-	if err := ValidateStoreNbr(req.StoreNbr); err != nil {
+	if err := h.validateStoreNbr(req.StoreNbr); err != nil {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
@@ -93,19 +128,42 @@ func (h *Handlers) Predict(w http.ResponseWriter, r *http.Request) {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
+	model, modelKey, verr := h.resolveModel(req.Model)
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+
+	tracing.SetSpanAttributes(ctx, requestAttributes(req.StoreNbr, req.Family, req.Date, req.Horizon)...)
 
 	// Check cache first
-	cacheKey := cache.GenerateCacheKey(req.StoreNbr, req.Family, req.Date, req.Horizon)
+	cacheKey := predictCacheKey(req, modelKey)
 	if h.cache != nil {
 		if cached, err := h.cache.GetPrediction(ctx, cacheKey); err == nil {
+			tracing.SetSpanAttributes(ctx, tracing.AttrCacheHit.Bool(true), tracing.AttrPrediction.Float64(float64(cached.Prediction)))
+			h.annotateAccess(ctx, true, modelKey)
 			resp := PredictResponse{
 				StoreNbr:   cached.StoreNbr,
 				Family:     cached.Family,
 				Date:       cached.Date,
 				Prediction: cached.Prediction,
+				Model:      modelKey,
 				Cached:     true,
 				LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+				TraceID:    middleware.TraceID(r),
+				Guard:      cached.Guard,
 			}
+			if req.Model == "" {
+				h.submitShadow(inference.ShadowInput{
+					StoreNbr:    req.StoreNbr,
+					Family:      req.Family,
+					Date:        req.Date,
+					Features:    req.Features,
+					ChampionKey: modelKey,
+					Champion:    cached.Prediction,
+				})
+			}
+			h.logPrediction(r, req.Horizon, req.Features, resp)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(resp)
 			return
@@ -113,17 +171,37 @@ func (h *Handlers) Predict(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run inference
-	if h.onnx == nil {
+	if model == nil {
 		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
 		return
 	}
 
-	prediction, err := h.onnx.Predict(req.Features)
+	inferStart := time.Now()
+	prediction, err := inference.Predict(inference.WithMetricLabels(ctx, modelKey, req.Family), model, req.Features)
+	inferLatency := time.Since(inferStart)
 	if err != nil {
 		log.Error().Err(err).Msg("inference failed")
 		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
 		return
 	}
+	prediction, guard, err := h.guard.Check(prediction)
+	if err != nil {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
+	tracing.SetSpanAttributes(ctx, tracing.AttrCacheHit.Bool(false), tracing.AttrPrediction.Float64(float64(prediction)))
+	h.annotateAccess(ctx, false, modelKey)
+	if req.Model == "" {
+		h.submitShadow(inference.ShadowInput{
+			StoreNbr:        req.StoreNbr,
+			Family:          req.Family,
+			Date:            req.Date,
+			Features:        req.Features,
+			ChampionKey:     modelKey,
+			Champion:        prediction,
+			ChampionLatency: inferLatency,
+		})
+	}
 
 	// Cache result
 	if h.cache != nil {
@@ -133,129 +211,377 @@ func (h *Handlers) Predict(w http.ResponseWriter, r *http.Request) {
 			Date:       req.Date,
 			Horizon:    req.Horizon,
 			Prediction: prediction,
+			Guard:      guard,
 		}
-		if err := h.cache.SetPrediction(ctx, cacheKey, result); err != nil {
+		if err := h.cache.SetPrediction(ctx, cache.EndpointPredict, cacheKey, result); err != nil {
 			log.Warn().Err(err).Msg("failed to cache prediction")
 		}
 	}
 
+	var quantiles *inference.Quantiles
+	if h.quantiles != nil {
+		quantiles = h.predictionIntervals(req.StoreNbr, req.Family, req.Horizon, req.Features, prediction).Quantiles
+	}
+
 	resp := PredictResponse{
 		StoreNbr:   req.StoreNbr,
 		Family:     req.Family,
 		Date:       req.Date,
 		Prediction: prediction,
+		Quantiles:  quantiles,
+		Model:      modelKey,
 		Cached:     false,
 		LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+		TraceID:    middleware.TraceID(r),
+		Guard:      guard,
 	}
+	h.logPrediction(r, req.Horizon, req.Features, resp)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// validateBatchItem validates a single prediction within a batch.
+func (h *Handlers) validateBatchItem(pred PredictRequest) *ValidationError {
+	if err := h.validateStoreNbr(pred.StoreNbr); err != nil {
+		return err
+	}
+	if err := ValidateFamily(pred.Family); err != nil {
+		return err
+	}
+	if err := ValidateDate(pred.Date); err != nil {
+		return err
+	}
+	if err := ValidateFeatures(pred.Features); err != nil {
+		return err
+	}
+	if _, _, err := h.resolveModel(pred.Model); err != nil {
+		return err
+	}
+	return nil
+}
+
 // PredictBatch handles batch prediction requests.
+// Items are validated in parallel and scored together by scoreBatch.
 func (h *Handlers) PredictBatch(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	ctx := r.Context()
 
 	var req BatchPredictRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate batch size
-	if err := ValidateBatchSize(len(req.Predictions)); err != nil {
+	if err := ValidateBatchSizeLimit(len(req.Predictions), h.maxBatchSize); err != nil {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
+	metrics.RecordBatchSize(len(req.Predictions))
 
 	// Validate each prediction in the batch
-	for i, pred := range req.Predictions {
-		if err := ValidateStoreNbr(pred.StoreNbr); err != nil {
-			WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
-			return
-		}
-		if err := ValidateFamily(pred.Family); err != nil {
-			WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
-			return
-		}
-		if err := ValidateDate(pred.Date); err != nil {
-			WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
-			return
-		}
-		if err := ValidateFeatures(pred.Features); err != nil {
-			WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
-			return
+	if i, err := h.validateBatch(req.Predictions); err != nil {
+		WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
+		return
+	}
+
+	responses, err := h.scoreBatch(ctx, req.Predictions)
+	if errors.Is(err, errModelUnavailable) {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+	if errors.Is(err, inference.ErrInvalidPrediction) {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("batch inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+
+	h.logPredictions(getRequestID(ctx), r.URL.Path, req.Predictions, responses)
+	h.annotateBatchAccess(ctx, responses)
+
+	resp := BatchPredictResponse{
+		Predictions: responses,
+		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+		TraceID:     middleware.TraceID(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// annotateAccess records in the access log whether a request was answered from cache
+// and the model serving it.
+func (h *Handlers) annotateAccess(ctx context.Context, cacheHit bool, modelKey string) {
+	middleware.SetCacheHit(ctx, cacheHit)
+	middleware.SetModelVersion(ctx, h.modelVersion(modelKey))
+}
+
+// annotateBatchAccess records in the access log whether every prediction of a batch
+// was answered from cache, and the model version if one model served them all.
+func (h *Handlers) annotateBatchAccess(ctx context.Context, responses []PredictResponse) {
+	cached, mixed := true, false
+	for _, resp := range responses {
+		cached = cached && resp.Cached
+		mixed = mixed || resp.Model != responses[0].Model
+	}
+	middleware.SetCacheHit(ctx, cached)
+	if len(responses) > 0 && !mixed {
+		middleware.SetModelVersion(ctx, h.modelVersion(responses[0].Model))
+	}
+}
+
+// errModelUnavailable is returned by scoreBatch when an uncached item has no model loaded.
+var errModelUnavailable = errors.New("model not loaded")
+
+// writeInvalidPrediction rejects a request whose prediction the guard failed, under
+// its error policy.
+func writeInvalidPrediction(w http.ResponseWriter, r *http.Request, err error) {
+	log.Warn().Err(err).Msg("prediction rejected by guard")
+	WriteInternalError(w, r, err.Error(), CodeInvalidPrediction)
+}
+
+// guardBatch runs a batch of model outputs through the prediction guard in place
+// and returns the guard action for each. Every prediction is checked even when
+// one is rejected, so callers that only cache can skip the rejected entries
+// (action GuardRejected); the first rejection is returned as the error.
+func (h *Handlers) guardBatch(predictions []float32) ([]string, error) {
+	guards := make([]string, len(predictions))
+	var rejected error
+	for i, p := range predictions {
+		prediction, guard, err := h.guard.Check(p)
+		if err != nil && rejected == nil {
+			rejected = fmt.Errorf("prediction[%d]: %w", i, err)
 		}
+		predictions[i], guards[i] = prediction, guard
 	}
+	return guards, rejected
+}
 
-	responses := make([]PredictResponse, 0, len(req.Predictions))
+// scoreBatch predicts already-validated items, returning responses in input order.
+// Cache lookups are pipelined into one MGET and uncached items run through a
+// single PredictBatch call per model.
+func (h *Handlers) scoreBatch(ctx context.Context, items []PredictRequest) ([]PredictResponse, error) {
+	// Resolve models and cache keys
+	n := len(items)
+	models := make([]inference.Inferencer, n)
+	modelKeys := make([]string, n)
+	cacheKeys := make([]string, n)
+	for i, pred := range items {
+		models[i], modelKeys[i], _ = h.resolveModel(pred.Model)
+		cacheKeys[i] = predictCacheKey(pred, modelKeys[i])
+	}
 
-	for _, pred := range req.Predictions {
-		predStart := time.Now()
+	responses := make([]PredictResponse, n)
+	done := make([]bool, n)
 
-		// Check cache first
-		cacheKey := cache.GenerateCacheKey(pred.StoreNbr, pred.Family, pred.Date, pred.Horizon)
-		if h.cache != nil {
-			if cached, err := h.cache.GetPrediction(ctx, cacheKey); err == nil {
-				responses = append(responses, PredictResponse{
-					StoreNbr:   cached.StoreNbr,
-					Family:     cached.Family,
-					Date:       cached.Date,
-					Prediction: cached.Prediction,
-					Cached:     true,
-					LatencyMs:  float64(time.Since(predStart).Microseconds()) / 1000,
-				})
+	// Pipelined cache lookup
+	if h.cache != nil {
+		lookupStart := time.Now()
+		hits, err := h.cache.GetPredictions(ctx, cacheKeys)
+		if err != nil {
+			log.Warn().Err(err).Msg("batch cache lookup failed")
+		}
+		latency := float64(time.Since(lookupStart).Microseconds()) / 1000
+		for i, key := range cacheKeys {
+			cached, ok := hits[key]
+			if !ok {
 				continue
 			}
+			responses[i] = PredictResponse{
+				StoreNbr:   cached.StoreNbr,
+				Family:     cached.Family,
+				Date:       cached.Date,
+				Prediction: cached.Prediction,
+				Model:      modelKeys[i],
+				Cached:     true,
+				LatencyMs:  latency,
+				Guard:      cached.Guard,
+			}
+			done[i] = true
 		}
+	}
 
-		// Run inference
-		if h.onnx == nil {
-			WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
-			return
+	// Group uncached items by model, preserving order
+	groups := make(map[string][]int)
+	var order []string
+	for i := range items {
+		if done[i] {
+			continue
+		}
+		if models[i] == nil {
+			return nil, errModelUnavailable
+		}
+		if _, ok := groups[modelKeys[i]]; !ok {
+			order = append(order, modelKeys[i])
+		}
+		groups[modelKeys[i]] = append(groups[modelKeys[i]], i)
+	}
+
+	toCache := make(map[string]*cache.PredictionResult)
+	for _, key := range order {
+		indices := groups[key]
+		batch := make([][]float32, len(indices))
+		families := make([]string, len(indices))
+		for j, i := range indices {
+			batch[j] = items[i].Features
+			families[j] = items[i].Family
 		}
 
-		prediction, err := h.onnx.Predict(pred.Features)
+		inferStart := time.Now()
+		predictions, err := inference.PredictBatch(inference.WithMetricLabels(ctx, key, families...), models[indices[0]], batch)
 		if err != nil {
-			log.Error().Err(err).Msg("batch inference failed")
-			WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
-			return
+			return nil, err
 		}
+		latency := float64(time.Since(inferStart).Microseconds()) / 1000
 
-		// Cache result
-		if h.cache != nil {
-			result := &cache.PredictionResult{
+		for j, i := range indices {
+			pred := items[i]
+			prediction, guard, err := h.guard.Check(predictions[j])
+			if err != nil {
+				return nil, fmt.Errorf("prediction[%d]: %w", i, err)
+			}
+			responses[i] = PredictResponse{
+				StoreNbr:   pred.StoreNbr,
+				Family:     pred.Family,
+				Date:       pred.Date,
+				Prediction: prediction,
+				Model:      key,
+				Cached:     false,
+				LatencyMs:  latency,
+				Guard:      guard,
+			}
+			toCache[cacheKeys[i]] = &cache.PredictionResult{
 				StoreNbr:   pred.StoreNbr,
 				Family:     pred.Family,
 				Date:       pred.Date,
 				Horizon:    pred.Horizon,
 				Prediction: prediction,
+				Guard:      guard,
 			}
-			if err := h.cache.SetPrediction(ctx, cacheKey, result); err != nil {
-				log.Warn().Err(err).Msg("failed to cache batch prediction")
+		}
+	}
+
+	// Cache results
+	if h.cache != nil {
+		if err := h.cache.SetPredictions(ctx, cache.EndpointBatch, toCache); err != nil {
+			log.Warn().Err(err).Msg("failed to cache batch predictions")
+		}
+	}
+
+	return responses, nil
+}
+
+// parallelValidationThreshold is the batch size above which items are validated concurrently.
+const parallelValidationThreshold = 256
+
+// validateBatch validates every item and returns the lowest failing index and its error.
+// Large batches are split across GOMAXPROCS workers.
+func (h *Handlers) validateBatch(items []PredictRequest) (int, *ValidationError) {
+	if len(items) < parallelValidationThreshold {
+		for i, item := range items {
+			if err := h.validateBatchItem(item); err != nil {
+				return i, err
 			}
 		}
+		return -1, nil
+	}
 
-		responses = append(responses, PredictResponse{
-			StoreNbr:   pred.StoreNbr,
-			Family:     pred.Family,
-			Date:       pred.Date,
-			Prediction: prediction,
-			Cached:     false,
-			LatencyMs:  float64(time.Since(predStart).Microseconds()) / 1000,
-		})
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(items) + workers - 1) / workers
+	errs := make([]*ValidationError, len(items))
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(items); start += chunk {
+		end := min(start+chunk, len(items))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if err := h.validateBatchItem(items[i]); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(start, end)
 	}
+	wg.Wait()
 
-	resp := BatchPredictResponse{
-		Predictions: responses,
-		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+	for i, err := range errs {
+		if err != nil {
+			return i, err
+		}
 	}
+	return -1, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+// lookupFeatures returns features for a series and date from the feature store, and
+// how they were found. Dates after the series' last known date are rolled forward with
+// prior model predictions; other misses fall back to the store's aggregated features.
+// The source is recorded in the feature store lookup metrics.
+func (h *Handlers) lookupFeatures(ctx context.Context, storeNbr int, family, date string) ([]float32, features.FeatureSource) {
+	d, _ := time.Parse(DateFormat, date)
+	if last, ok := h.featureStore.LastDate(storeNbr, family); ok && d.After(last) {
+		rolled, _, err := h.featureBuilder(h.featureStore).Build(storeNbr, family, date, h.predictor(ctx, family))
+		if err == nil {
+			metrics.RecordFeatureStoreLookup(string(features.SourceRolledForward))
+			return rolled, features.SourceRolledForward
+		}
+		log.Warn().Err(err).Str("date", date).Msg("feature rollforward failed, using aggregated features")
+	}
+
+	feats, source := h.featureStore.ResolveFeatures(storeNbr, family, date)
+	metrics.RecordFeatureStoreLookup(string(source))
+	return h.applyRegressorOverrides(feats, storeNbr, family, date), source
+}
+
+// lookupFeaturesBatch is lookupFeatures for many keys, resolving them from the feature
+// store under one lock. Only keys past their series' last date are rolled forward.
+func (h *Handlers) lookupFeaturesBatch(ctx context.Context, keys []features.FeatureKey) []features.FeatureResult {
+	results := h.featureStore.GetFeaturesBatch(keys)
+	for i, k := range keys {
+		if results[i].Source == features.SourceExact {
+			continue
+		}
+		d, _ := time.Parse(DateFormat, k.Date)
+		if last, ok := h.featureStore.LastDate(k.StoreNbr, k.Family); !ok || !d.After(last) {
+			continue
+		}
+		rolled, _, err := h.featureBuilder(h.featureStore).Build(k.StoreNbr, k.Family, k.Date, h.predictor(ctx, k.Family))
+		if err != nil {
+			log.Warn().Err(err).Str("date", k.Date).Msg("feature rollforward failed, using aggregated features")
+			continue
+		}
+		results[i] = features.FeatureResult{Features: rolled, Source: features.SourceRolledForward}
+	}
+	for i, r := range results {
+		metrics.RecordFeatureStoreLookup(string(r.Source))
+		results[i].Features = h.applyRegressorOverrides(r.Features, keys[i].StoreNbr, keys[i].Family, keys[i].Date)
+	}
+	return results
 }
 
+// predictor returns a features.Predictor running the model through inference.Predict,
+// so predictions made rolling features of a family forward are traced and counted too.
+func (h *Handlers) predictor(ctx context.Context, family string) features.Predictor {
+	ctx = inference.WithMetricLabels(ctx, "", family)
+	return func(f []float32) (float32, error) {
+		prediction, err := inference.Predict(ctx, h.onnx, f)
+		if err != nil {
+			return 0, err
+		}
+		prediction, _, err = h.guard.Check(prediction)
+		return prediction, err
+	}
+}
+
+// errNoFeatures is returned by simplePrediction for a strict request whose series has
+// no features.
+var errNoFeatures = errors.New("no features found")
+
 // PredictSimple handles simplified prediction requests without feature arrays.
 // It generates mock features (27 zeros) and delegates to the inference engine.
 // This endpoint is designed for dashboard use where features aren't available client-side.
@@ -264,13 +590,12 @@ func (h *Handlers) PredictSimple(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req SimplePredictRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate request
-	if err := ValidateStoreNbr(req.StoreNbr); err != nil {
+	if err := h.validateStoreNbr(req.StoreNbr); err != nil {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
@@ -287,18 +612,61 @@ func (h *Handlers) PredictSimple(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check cache first
+	regressors, verr := simpleRegressors(req.StoreNbr, req.Family, req.Date, req.Regressors)
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+
+	tracing.SetSpanAttributes(ctx, requestAttributes(req.StoreNbr, req.Family, req.Date, req.Horizon)...)
+
+	// Check cache first; predictions with request regressors bypass it
 	cacheKey := cache.GenerateCacheKey(req.StoreNbr, req.Family, req.Date, req.Horizon)
-	if h.cache != nil {
-		if cached, err := h.cache.GetPrediction(ctx, cacheKey); err == nil {
+	noDataKey := cache.GenerateNoDataKey(req.StoreNbr, req.Family, req.Date, req.Horizon)
+	useCache := h.cache != nil && regressors == nil
+	if useCache {
+		// Strict requests are refused on a prediction made on zeros or a "no data" entry;
+		// others predict on zeros, so only strict misses look the "no data" entry up
+		cached, err := h.cache.GetPrediction(ctx, cacheKey)
+		zeros := err == nil && cached.FeatureSource == string(features.SourceZeros)
+		if err != nil && req.StrictFeatures {
+			_, nerr := h.cache.GetPrediction(ctx, noDataKey)
+			zeros = nerr == nil
+		}
+		if zeros && req.StrictFeatures {
+			tracing.SetSpanAttributes(ctx, tracing.AttrCacheHit.Bool(true))
+			middleware.SetCacheHit(ctx, true)
+			writeNoFeatures(w, r, req)
+			return
+		}
+		if err == nil {
+			tracing.SetSpanAttributes(ctx, tracing.AttrCacheHit.Bool(true), tracing.AttrPrediction.Float64(float64(cached.Prediction)))
+			h.annotateAccess(ctx, true, "")
+			// Entries are shared with endpoints that serve no bands, so they are
+			// recomputed; only the quantile models need the features
+			var feats []float32
+			if h.quantiles != nil {
+				feats, _ = h.simpleFeatures(ctx, req)
+			}
+			bands := h.predictionIntervals(req.StoreNbr, req.Family, req.Horizon, feats, cached.Prediction)
 			resp := PredictResponse{
-				StoreNbr:   cached.StoreNbr,
-				Family:     cached.Family,
-				Date:       cached.Date,
-				Prediction: cached.Prediction,
-				Cached:     true,
-				LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+				StoreNbr:      cached.StoreNbr,
+				Family:        cached.Family,
+				Date:          cached.Date,
+				Prediction:    cached.Prediction,
+				Lower80:       bands.Lower80,
+				Upper80:       bands.Upper80,
+				Lower95:       bands.Lower95,
+				Upper95:       bands.Upper95,
+				Quantiles:     bands.Quantiles,
+				IntervalSet:   bands.Set,
+				FeatureSource: features.FeatureSource(cached.FeatureSource),
+				Cached:        true,
+				LatencyMs:     float64(time.Since(start).Microseconds()) / 1000,
+				TraceID:       middleware.TraceID(r),
+				Guard:         cached.Guard,
 			}
+			h.logPrediction(r, req.Horizon, nil, resp)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(resp)
 			return
@@ -311,53 +679,182 @@ func (h *Handlers) PredictSimple(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Look up real features from feature store, or use zeros as fallback
-	var features []float32
-	if h.featureStore != nil && h.featureStore.IsLoaded() {
-		features, _ = h.featureStore.GetFeatures(req.StoreNbr, req.Family, req.Date)
-	} else {
-		// Fallback to zeros if feature store is unavailable
-		features = make([]float32, 27)
-		log.Debug().Msg("Feature store unavailable, using zero features")
+	// Concurrent misses for the same key share one inference
+	flightKey := simpleFlightKey(cacheKey, req.StrictFeatures)
+	if regressors != nil {
+		flightKey += ":" + regressorsKey(req.Regressors)
 	}
+	flight, err, shared := h.simpleFlight.Do(ctx, flightKey, func() (simpleFlightResult, error) {
+		// Detached from the request that happens to run it, which may be cancelled
+		// while others wait on the result
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), simpleFlightTimeout)
+		defer cancel()
+		flight, err := h.simplePrediction(ctx, req, regressors)
+		if errors.Is(err, errNoFeatures) && useCache {
+			// Cache that the series has none, so strict retries skip the lookup
+			result := &cache.PredictionResult{
+				StoreNbr:      req.StoreNbr,
+				Family:        req.Family,
+				Date:          req.Date,
+				Horizon:       req.Horizon,
+				FeatureSource: string(features.SourceZeros),
+				NoData:        true,
+			}
+			if err := h.cache.SetPrediction(ctx, cache.EndpointSimple, noDataKey, result); err != nil {
+				log.Warn().Err(err).Msg("failed to cache missing features")
+			}
+		}
+		if err != nil {
+			return simpleFlightResult{}, err
+		}
+		resp := flight.resp
+		h.submitShadow(inference.ShadowInput{
+			StoreNbr:        req.StoreNbr,
+			Family:          req.Family,
+			Date:            req.Date,
+			Features:        flight.features,
+			Champion:        resp.Prediction,
+			ChampionLatency: flight.latency,
+		})
 
-	prediction, err := h.onnx.Predict(features)
+		if useCache {
+			result := &cache.PredictionResult{
+				StoreNbr:      req.StoreNbr,
+				Family:        req.Family,
+				Date:          req.Date,
+				Horizon:       req.Horizon,
+				Prediction:    resp.Prediction,
+				FeatureSource: string(resp.FeatureSource),
+				Guard:         resp.Guard,
+			}
+			if err := h.cache.SetPrediction(ctx, cache.EndpointSimple, cacheKey, result); err != nil {
+				log.Warn().Err(err).Msg("failed to cache prediction")
+			}
+		}
+		return flight, nil
+	})
+	if shared {
+		metrics.RecordDeduplicatedPrediction()
+	}
+	if errors.Is(err, errNoFeatures) {
+		writeNoFeatures(w, r, req)
+		return
+	}
+	if errors.Is(err, inference.ErrInvalidPrediction) {
+		writeInvalidPrediction(w, r, err)
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("inference failed")
 		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
 		return
 	}
+	resp, features := flight.resp, flight.features
+	tracing.SetSpanAttributes(ctx, tracing.AttrCacheHit.Bool(false), tracing.AttrPrediction.Float64(float64(resp.Prediction)))
+	h.annotateAccess(ctx, false, "")
 
-	// Cache result
-	if h.cache != nil {
-		result := &cache.PredictionResult{
-			StoreNbr:   req.StoreNbr,
-			Family:     req.Family,
-			Date:       req.Date,
-			Horizon:    req.Horizon,
-			Prediction: prediction,
-		}
-		if err := h.cache.SetPrediction(ctx, cacheKey, result); err != nil {
-			log.Warn().Err(err).Msg("failed to cache prediction")
-		}
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	resp.TraceID = middleware.TraceID(r)
+	h.logPrediction(r, req.Horizon, features, resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// requestAttributes returns the span attributes identifying a prediction request.
+func requestAttributes(storeNbr int, family, date string, horizon int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		tracing.AttrStoreNbr.Int(storeNbr),
+		tracing.AttrFamily.String(family),
+		tracing.AttrDate.String(date),
+		tracing.AttrHorizon.Int(horizon),
 	}
+}
+
+// simpleFlightTimeout bounds a /predict/simple inference shared by concurrent requests.
+// It runs apart from the request contexts, each of which still ends its own wait.
+const simpleFlightTimeout = 10 * time.Second
 
-	// Compute confidence intervals
-	lower80, upper80, lower95, upper95 := h.applyIntervals(prediction)
+// simpleFlightResult is a /predict/simple inference shared by concurrent requests.
+type simpleFlightResult struct {
+	resp     PredictResponse
+	features []float32
+	latency  time.Duration // Champion inference time
+}
+
+// simpleFlightKey de-duplicates /predict/simple inference by cache key. Strict requests
+// are kept apart, since they fail where others predict on zeros.
+func simpleFlightKey(cacheKey string, strict bool) string {
+	if strict {
+		return cacheKey + ":strict"
+	}
+	return cacheKey
+}
+
+// simplePrediction looks up features for a series, scores them with the champion model
+// and computes confidence intervals. Bypasses the cache; returns the features used and
+// the inference time.
+// Returns errNoFeatures instead of predicting on zeros when req.StrictFeatures is set.
+// The request's regressors, if any, are set on the features found.
+func (h *Handlers) simplePrediction(ctx context.Context, req SimplePredictRequest, regressors *features.Regressors) (simpleFlightResult, error) {
+	feats, source := h.simpleFeatures(ctx, req)
+	if req.StrictFeatures && source == features.SourceZeros {
+		return simpleFlightResult{}, errNoFeatures
+	}
+	if d, err := time.Parse(DateFormat, req.Date); err == nil {
+		feats = regressors.Apply(feats, req.StoreNbr, req.Family, d)
+	}
+
+	inferStart := time.Now()
+	prediction, err := inference.Predict(inference.WithMetricLabels(ctx, "", req.Family), h.onnx, feats)
+	latency := time.Since(inferStart)
+	if err != nil {
+		return simpleFlightResult{}, err
+	}
+	prediction, guard, err := h.guard.Check(prediction)
+	if err != nil {
+		return simpleFlightResult{}, err
+	}
+
+	// Compute confidence intervals (model quantiles when available)
+	bands := h.predictionIntervals(req.StoreNbr, req.Family, req.Horizon, feats, prediction)
 
 	resp := PredictResponse{
-		StoreNbr:   req.StoreNbr,
-		Family:     req.Family,
-		Date:       req.Date,
-		Prediction: prediction,
-		Lower80:    lower80,
-		Upper80:    upper80,
-		Lower95:    lower95,
-		Upper95:    upper95,
-		Cached:     false,
-		LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+		StoreNbr:      req.StoreNbr,
+		Family:        req.Family,
+		Date:          req.Date,
+		Prediction:    prediction,
+		Lower80:       bands.Lower80,
+		Upper80:       bands.Upper80,
+		Lower95:       bands.Lower95,
+		Upper95:       bands.Upper95,
+		Quantiles:     bands.Quantiles,
+		IntervalSet:   bands.Set,
+		FeatureSource: source,
+		Cached:        false,
+		Guard:         guard,
+	}
+	return simpleFlightResult{resp: resp, features: feats, latency: latency}, nil
+}
+
+// simpleFeatures looks up the features of a /predict/simple request's series in the
+// feature store, or uses zeros when it is unavailable.
+func (h *Handlers) simpleFeatures(ctx context.Context, req SimplePredictRequest) ([]float32, features.FeatureSource) {
+	_, span := tracing.Start(ctx, "features.lookup", requestAttributes(req.StoreNbr, req.Family, req.Date, req.Horizon)...)
+	defer span.End()
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		log.Debug().Msg("Feature store unavailable, using zero features")
+		span.SetAttributes(tracing.AttrFeatureSrc.String(string(features.SourceZeros)))
+		return h.applyRegressorOverrides(make([]float32, RequiredFeatureCount), req.StoreNbr, req.Family, req.Date), features.SourceZeros
 	}
+	feats, source := h.lookupFeatures(ctx, req.StoreNbr, req.Family, req.Date)
+	span.SetAttributes(tracing.AttrFeatureSrc.String(string(source)))
+	return feats, source
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+// writeNoFeatures rejects a strict_features request whose series has no features.
+func writeNoFeatures(w http.ResponseWriter, r *http.Request, req SimplePredictRequest) {
+	WriteError(w, r, http.StatusUnprocessableEntity,
+		fmt.Sprintf("no features for store %d, family %s on %s; strict_features forbids predicting on zeros", req.StoreNbr, req.Family, req.Date),
+		CodeFeatureNotFound)
 }
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/predlog"
+)
+
+// SetPredictionLogger enables persisting every served prediction for auditing.
+func (h *Handlers) SetPredictionLogger(l *predlog.Logger) {
+	h.predLog = l
+}
+
+// logPrediction queues a served prediction for the prediction log. features may be
+// nil for cache hits. No-op when prediction logging is disabled.
+func (h *Handlers) logPrediction(r *http.Request, horizon int, features []float32, resp PredictResponse) {
+	if h.predLog == nil {
+		return
+	}
+	h.predLog.Log(h.predictionLogEntry(getRequestID(r.Context()), r.URL.Path, horizon, features, resp))
+}
+
+// logPredictions queues a batch of served predictions. Takes the request ID and
+// endpoint rather than the request so async jobs can log after their request ends.
+func (h *Handlers) logPredictions(requestID, endpoint string, items []PredictRequest, responses []PredictResponse) {
+	if h.predLog == nil {
+		return
+	}
+	for i, resp := range responses {
+		h.predLog.Log(h.predictionLogEntry(requestID, endpoint, items[i].Horizon, items[i].Features, resp))
+	}
+}
+
+// predictionLogEntry builds a log entry. The champion's version comes from the model
+// reloader; registry models are identified by their "name@version" key.
+func (h *Handlers) predictionLogEntry(requestID, endpoint string, horizon int, features []float32, resp PredictResponse) predlog.Entry {
+	version := resp.Model
+	if version == "" && h.modelLoader != nil {
+		version = h.modelLoader.Info().Version
+	}
+
+	entry := predlog.Entry{
+		Timestamp:    time.Now().UTC(),
+		RequestID:    requestID,
+		Endpoint:     endpoint,
+		StoreNbr:     int32(resp.StoreNbr),
+		Family:       resp.Family,
+		Date:         resp.Date,
+		Horizon:      int32(horizon),
+		Model:        resp.Model,
+		ModelVersion: version,
+		Features:     features,
+		Prediction:   resp.Prediction,
+		Cached:       resp.Cached,
+		LatencyMs:    resp.LatencyMs,
+	}
+	if resp.Lower80 != 0 || resp.Upper80 != 0 {
+		entry.Lower80, entry.Upper80 = &resp.Lower80, &resp.Upper80
+	}
+	if resp.Lower95 != 0 || resp.Upper95 != 0 {
+		entry.Lower95, entry.Upper95 = &resp.Lower95, &resp.Upper95
+	}
+	return entry
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/parquet-go/parquet-go"
+)
+
+// readPredictionLog closes the logger and returns every logged entry.
+func readPredictionLog(t *testing.T, l *predlog.Logger, dir string) []predlog.Entry {
+	t.Helper()
+	l.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "predictions-*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []predlog.Entry
+	for _, f := range files {
+		rows, err := parquet.ReadFile[predlog.Entry](f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f, err)
+		}
+		entries = append(entries, rows...)
+	}
+	return entries
+}
+
+func newTestPredictionLogger(t *testing.T) (*predlog.Logger, string) {
+	t.Helper()
+	dir := t.TempDir()
+	l, err := predlog.NewLogger(predlog.Config{
+		Enabled:        true,
+		Dir:            dir,
+		BufferSize:     100,
+		BatchSize:      10,
+		FlushInterval:  time.Hour,
+		MaxRowsPerFile: 1000,
+		RotateInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	return l, dir
+}
+
+func TestPredictionLogging(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+	l, dir := newTestPredictionLogger(t)
+	h.SetPredictionLogger(l)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple", bytes.NewReader([]byte(body)))
+	req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "req-1"))
+	w := httptest.NewRecorder()
+	h.PredictSimple(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/predict/batch", bytes.NewReader(batchBody(3)))
+	w = httptest.NewRecorder()
+	h.PredictBatch(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	entries := readPredictionLog(t, l, dir)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 logged predictions, got %d", len(entries))
+	}
+
+	simple := entries[0]
+	if simple.RequestID != "req-1" || simple.Endpoint != "/predict/simple" {
+		t.Errorf("unexpected request metadata: %+v", simple)
+	}
+	if simple.StoreNbr != 1 || simple.Family != "GROCERY I" || simple.Horizon != 30 || simple.Prediction != 42 {
+		t.Errorf("unexpected logged prediction: %+v", simple)
+	}
+
+	for _, e := range entries[1:] {
+		if e.Endpoint != "/predict/batch" {
+			t.Errorf("expected endpoint /predict/batch, got %q", e.Endpoint)
+		}
+		if len(e.Features) != 27 {
+			t.Errorf("expected 27 logged features, got %d", len(e.Features))
+		}
+	}
+}
+
+func TestPredictionLoggingDisabled(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+
+	body := `{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","horizon":30}`
+	req := httptest.NewRequest(http.MethodPost, "/predict/simple", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	h.PredictSimple(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 without a prediction logger, got %d", w.Code)
+	}
+}
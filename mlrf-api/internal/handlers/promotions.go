@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/hierarchy"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// PromotionPlan is a promotion of one series over a date range, inclusive.
+type PromotionPlan struct {
+	StoreNbr    int                `json:"store_nbr"`
+	Family      string             `json:"family"`
+	StartDate   string             `json:"start_date"`
+	EndDate     string             `json:"end_date"`
+	Adjustments map[string]float32 `json:"adjustments,omitempty"` // /whatif adjustments of the promoted days; default {"onpromotion": 1}
+}
+
+// PromotionImpactRequest lists the promotion plans to evaluate.
+type PromotionImpactRequest struct {
+	Plans []PromotionPlan `json:"plans"`
+}
+
+// PromotionImpact compares forecast sales without and with promotions.
+type PromotionImpact struct {
+	Baseline  float32 `json:"baseline"`
+	Promoted  float32 `json:"promoted"`
+	Uplift    float32 `json:"uplift"` // Incremental sales, promoted - baseline
+	UpliftPct float32 `json:"uplift_pct"`
+}
+
+// PromotionPlanImpact is the impact of one plan over its days.
+type PromotionPlanImpact struct {
+	PromotionPlan
+	Days int `json:"days"`
+	PromotionImpact
+	Warnings []WhatIfWarning `json:"warnings,omitempty"`
+}
+
+// PromotionNodeImpact is the impact summed at a hierarchy node.
+type PromotionNodeImpact struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Level  string `json:"level"`
+	Parent string `json:"parent,omitempty"`
+	PromotionImpact
+}
+
+// PromotionFamilyImpact is the impact summed over the stores of a family.
+type PromotionFamilyImpact struct {
+	Family string `json:"family"`
+	PromotionImpact
+}
+
+// PromotionImpactResponse is the impact of the plans, per plan, aggregated up the
+// hierarchy and per family, and in total.
+type PromotionImpactResponse struct {
+	Plans              []PromotionPlanImpact   `json:"plans"`
+	Hierarchy          []PromotionNodeImpact   `json:"hierarchy"` // Nodes above the promoted series, top-down
+	Families           []PromotionFamilyImpact `json:"families"`
+	Total              PromotionImpact         `json:"total"`
+	IncrementalRevenue float32                 `json:"incremental_revenue"` // Total uplift
+	LatencyMs          float64                 `json:"latency_ms"`
+}
+
+// defaultPromotion is the adjustment of a plan without adjustments.
+var defaultPromotion = map[string]float32{"onpromotion": 1}
+
+// add accumulates another impact, leaving the percentage to finish.
+func (p *PromotionImpact) add(o PromotionImpact) {
+	p.Baseline += o.Baseline
+	p.Promoted += o.Promoted
+	p.Uplift += o.Uplift
+}
+
+// finish sets the uplift percentage of an accumulated impact.
+func (p PromotionImpact) finish() PromotionImpact {
+	p.UpliftPct = deltaPercent(p.Baseline, p.Promoted)
+	return p
+}
+
+// PromotionsImpact evaluates promotion plans: each plan's series is rolled forward
+// over its days as is and with the promotion applied, as /whatif does in horizon
+// mode, and the incremental sales are summed up the hierarchy.
+func (h *Handlers) PromotionsImpact(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req PromotionImpactRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.Plans) == 0 {
+		WriteBadRequest(w, r, "plans is required", CodeInvalidRequest)
+		return
+	}
+	if len(req.Plans) > h.maxBatchSize {
+		WriteBadRequest(w, r, fmt.Sprintf("plans exceed maximum of %d", h.maxBatchSize), CodeBatchTooLarge)
+		return
+	}
+	days, i, verr := h.validatePromotionPlans(req.Plans)
+	if verr != nil {
+		WriteBadRequest(w, r, fmt.Sprintf("plans[%d]: %s", i, verr.Message), verr.Code)
+		return
+	}
+
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	var store *features.Store
+	if h.featureStore != nil && h.featureStore.IsLoaded() {
+		store = h.featureStore
+	} else {
+		log.Debug().Msg("Feature store unavailable for promotion impact, rolling forward from zero features")
+	}
+
+	resp := PromotionImpactResponse{Plans: make([]PromotionPlanImpact, len(req.Plans))}
+	leaves := make(map[string]PromotionImpact)
+	families := make(map[string]PromotionImpact)
+	for i, plan := range req.Plans {
+		adjustments := plan.Adjustments
+		if len(adjustments) == 0 {
+			adjustments = defaultPromotion
+		}
+		startDate, _ := time.Parse(DateFormat, plan.StartDate)
+		sc, err := h.rollScenario(r.Context(), store, plan.StoreNbr, plan.Family, startDate, days[i], adjustments)
+		if err != nil {
+			log.Warn().Err(err).Int("plan", i).Msg("promotion plan failed")
+			writeScenarioError(w, r, fmt.Errorf("plans[%d]: %w", i, err))
+			return
+		}
+
+		var impact PromotionImpact
+		for d := range sc.baseline {
+			impact.Baseline += sc.baseline[d]
+			impact.Promoted += sc.adjusted[d]
+		}
+		impact.Uplift = impact.Promoted - impact.Baseline
+		impact = impact.finish()
+		resp.Plans[i] = PromotionPlanImpact{PromotionPlan: plan, Days: days[i], PromotionImpact: impact, Warnings: sc.warnings}
+
+		id := seriesNodeID(plan.StoreNbr, plan.Family)
+		leaf := leaves[id]
+		leaf.add(impact)
+		leaves[id] = leaf
+		family := families[plan.Family]
+		family.add(impact)
+		families[plan.Family] = family
+		resp.Total.add(impact)
+	}
+	middleware.SetModelVersion(r.Context(), h.modelVersion(""))
+
+	if def := h.hierarchyDef.Load(); def != nil {
+		resp.Hierarchy = promotionHierarchy(def, leaves)
+	} else {
+		resp.Hierarchy = promotionStoreHierarchy(req.Plans, leaves)
+	}
+	resp.Families = make([]PromotionFamilyImpact, 0, len(families))
+	for family, impact := range families {
+		resp.Families = append(resp.Families, PromotionFamilyImpact{Family: family, PromotionImpact: impact.finish()})
+	}
+	sort.Slice(resp.Families, func(i, j int) bool { return resp.Families[i].Family < resp.Families[j].Family })
+	resp.Total = resp.Total.finish()
+	resp.IncrementalRevenue = resp.Total.Uplift
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validatePromotionPlans validates the plans and returns the number of days of each.
+// A plan lasts at most the longest forecast horizon, and plans of the same series
+// may not overlap, so no promoted day is counted twice.
+func (h *Handlers) validatePromotionPlans(plans []PromotionPlan) ([]int, int, *ValidationError) {
+	horizons := Horizons()
+	maxDays := horizons[len(horizons)-1]
+
+	type span struct{ start, end time.Time }
+	spans := make(map[string][]span, len(plans))
+	days := make([]int, len(plans))
+	for i, plan := range plans {
+		if err := h.validateStoreNbr(plan.StoreNbr); err != nil {
+			return nil, i, err
+		}
+		if err := ValidateFamily(plan.Family); err != nil {
+			return nil, i, err
+		}
+		if err := ValidateDate(plan.StartDate); err != nil {
+			return nil, i, err
+		}
+		if err := ValidateDate(plan.EndDate); err != nil {
+			return nil, i, err
+		}
+		start, _ := time.Parse(DateFormat, plan.StartDate)
+		end, _ := time.Parse(DateFormat, plan.EndDate)
+		days[i] = int(end.Sub(start).Hours()/24) + 1
+		if days[i] < 1 {
+			return nil, i, &ValidationError{Message: "end_date is before start_date", Code: CodeInvalidDate}
+		}
+		if days[i] > maxDays {
+			return nil, i, &ValidationError{Message: fmt.Sprintf("a plan lasts at most %d days", maxDays), Code: CodeInvalidDate}
+		}
+		if unknown := unknownAdjustments(plan.Adjustments); len(unknown) > 0 {
+			return nil, i, &ValidationError{Message: fmt.Sprintf("unknown adjustment feature %q, see /features/schema", unknown[0]), Code: CodeInvalidAdjustment}
+		}
+
+		id := seriesNodeID(plan.StoreNbr, plan.Family)
+		for _, s := range spans[id] {
+			if !start.After(s.end) && !end.Before(s.start) {
+				return nil, i, &ValidationError{Message: "overlaps another plan of the same series", Code: CodeInvalidRequest}
+			}
+		}
+		spans[id] = append(spans[id], span{start, end})
+	}
+	return days, 0, nil
+}
+
+// seriesNodeID is the hierarchy node ID of a bottom-level series.
+func seriesNodeID(storeNbr int, family string) string {
+	return strconv.Itoa(storeNbr) + "_" + family
+}
+
+// promotionHierarchy sums leaf impacts up the hierarchy definition, returning the
+// nodes with promoted series below them, top-down. Series outside the definition
+// only count toward the total.
+func promotionHierarchy(def *hierarchy.Definition, leaves map[string]PromotionImpact) []PromotionNodeImpact {
+	var nodes []PromotionNodeImpact
+	var walk func(n hierarchy.Node) (PromotionImpact, bool)
+	walk = func(n hierarchy.Node) (PromotionImpact, bool) {
+		children := def.Children(n.ID)
+		if len(children) == 0 {
+			impact, ok := leaves[n.ID]
+			if ok {
+				nodes = append(nodes, PromotionNodeImpact{ID: n.ID, Name: n.Name, Level: n.Level, Parent: n.Parent, PromotionImpact: impact.finish()})
+			}
+			return impact, ok
+		}
+
+		pos := len(nodes)
+		nodes = append(nodes, PromotionNodeImpact{ID: n.ID, Name: n.Name, Level: n.Level, Parent: n.Parent})
+		var sum PromotionImpact
+		promoted := false
+		for _, c := range children {
+			if impact, ok := walk(c); ok {
+				sum.add(impact)
+				promoted = true
+			}
+		}
+		if !promoted {
+			nodes = nodes[:pos]
+			return sum, false
+		}
+		nodes[pos].PromotionImpact = sum.finish()
+		return sum, true
+	}
+	walk(def.Root())
+	return nodes
+}
+
+// promotionStoreHierarchy sums leaf impacts over total, store and family levels, as
+// used without a hierarchy definition.
+func promotionStoreHierarchy(plans []PromotionPlan, leaves map[string]PromotionImpact) []PromotionNodeImpact {
+	byStore := make(map[int]map[string]bool)
+	for _, plan := range plans {
+		if byStore[plan.StoreNbr] == nil {
+			byStore[plan.StoreNbr] = make(map[string]bool)
+		}
+		byStore[plan.StoreNbr][plan.Family] = true
+	}
+	stores := make([]int, 0, len(byStore))
+	for storeNbr := range byStore {
+		stores = append(stores, storeNbr)
+	}
+	sort.Ints(stores)
+
+	nodes := []PromotionNodeImpact{{ID: "total", Name: "Total", Level: AggregateLevelTotal}}
+	var total PromotionImpact
+	for _, storeNbr := range stores {
+		storeID := "store_" + strconv.Itoa(storeNbr)
+		pos := len(nodes)
+		nodes = append(nodes, PromotionNodeImpact{ID: storeID, Name: "Store " + strconv.Itoa(storeNbr), Level: AggregateLevelStore, Parent: "total"})
+
+		families := make([]string, 0, len(byStore[storeNbr]))
+		for family := range byStore[storeNbr] {
+			families = append(families, family)
+		}
+		sort.Strings(families)
+		var sum PromotionImpact
+		for _, family := range families {
+			id := seriesNodeID(storeNbr, family)
+			impact := leaves[id]
+			sum.add(impact)
+			nodes = append(nodes, PromotionNodeImpact{ID: id, Name: family, Level: AggregateLevelFamily, Parent: storeID, PromotionImpact: impact.finish()})
+		}
+		nodes[pos].PromotionImpact = sum.finish()
+		total.add(sum)
+	}
+	nodes[0].PromotionImpact = total.finish()
+	return nodes
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// promoInferencer predicts 100, or 120 for a promoted series.
+type promoInferencer struct{}
+
+func (promoInferencer) Predict(f []float32) (float32, error) {
+	i, _ := schema.Features.Index("onpromotion")
+	return 100 + 20*f[i], nil
+}
+
+func (p promoInferencer) PredictBatch(batch [][]float32) ([]float32, error) {
+	out := make([]float32, len(batch))
+	for i, f := range batch {
+		out[i], _ = p.Predict(f)
+	}
+	return out, nil
+}
+
+func promotionsImpact(t *testing.T, h *Handlers, body string) PromotionImpactResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.PromotionsImpact(w, httptest.NewRequest(http.MethodPost, "/v1/promotions/impact", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp PromotionImpactResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return resp
+}
+
+const testPromotionPlans = `{"plans":[
+	{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-25"},
+	{"store_nbr":1,"family":"DAIRY","start_date":"2017-09-01","end_date":"2017-09-05"},
+	{"store_nbr":2,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-20"},
+	{"store_nbr":1,"family":"BREAD/BAKERY","start_date":"2017-08-16","end_date":"2017-08-16","adjustments":{"onpromotion":0}}
+]}`
+
+func TestPromotionsImpact(t *testing.T) {
+	h := NewHandlers(promoInferencer{}, nil, nil, nil)
+	resp := promotionsImpact(t, h, testPromotionPlans)
+
+	if len(resp.Plans) != 4 {
+		t.Fatalf("expected 4 plans, got %d", len(resp.Plans))
+	}
+	p := resp.Plans[0]
+	if p.Days != 10 || p.Baseline != 1000 || p.Promoted != 1200 || p.Uplift != 200 || p.UpliftPct != 20 {
+		t.Errorf("unexpected impact of the first plan %+v", p)
+	}
+	if resp.Plans[3].Uplift != 0 {
+		t.Errorf("expected no uplift for a plan without promotion, got %+v", resp.Plans[3])
+	}
+
+	// 15 promoted days of 1_DAIRY, 5 of 2_DAIRY, at 20 each
+	if resp.IncrementalRevenue != 400 || resp.Total.Uplift != 400 || resp.Total.Baseline != 2100 {
+		t.Errorf("unexpected total %+v, incremental revenue %v", resp.Total, resp.IncrementalRevenue)
+	}
+
+	// Without a definition: total, then each store and its families
+	var ids []string
+	for _, n := range resp.Hierarchy {
+		ids = append(ids, n.ID)
+	}
+	if strings.Join(ids, ",") != "total,store_1,1_BREAD/BAKERY,1_DAIRY,store_2,2_DAIRY" {
+		t.Fatalf("unexpected hierarchy %v", ids)
+	}
+	if n := resp.Hierarchy[1]; n.Uplift != 300 || n.Parent != "total" || n.Level != AggregateLevelStore {
+		t.Errorf("unexpected store_1 impact %+v", n)
+	}
+	if len(resp.Families) != 2 || resp.Families[1].Family != "DAIRY" || resp.Families[1].Uplift != 400 {
+		t.Errorf("unexpected family impacts %+v", resp.Families)
+	}
+}
+
+func TestPromotionsImpactDefinition(t *testing.T) {
+	h := NewHandlers(promoInferencer{}, nil, nil, nil)
+	if err := h.LoadHierarchyDefinition(writeHierarchyDefinition(t, testHierarchyDefinition)); err != nil {
+		t.Fatal(err)
+	}
+	resp := promotionsImpact(t, h, `{"plans":[
+		{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-25"},
+		{"store_nbr":2,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-20"}
+	]}`)
+
+	// Summed through the state level; 1_BREAD/BAKERY has no plan and is left out
+	var ids []string
+	for _, n := range resp.Hierarchy {
+		ids = append(ids, n.ID)
+	}
+	if strings.Join(ids, ",") != "total,pichincha,store_1,1_DAIRY,store_2,2_DAIRY" {
+		t.Fatalf("unexpected hierarchy %v", ids)
+	}
+	if n := resp.Hierarchy[1]; n.Level != "state" || n.Uplift != 300 || n.Baseline != 1500 {
+		t.Errorf("unexpected state impact %+v", n)
+	}
+	if resp.Hierarchy[0].Uplift != resp.IncrementalRevenue {
+		t.Errorf("expected the root to sum to the incremental revenue, got %v and %v", resp.Hierarchy[0].Uplift, resp.IncrementalRevenue)
+	}
+}
+
+func TestPromotionsImpactValidation(t *testing.T) {
+	h := NewHandlers(promoInferencer{}, nil, nil, nil)
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"no plans", `{"plans":[]}`, CodeInvalidRequest},
+		{"end before start", `{"plans":[{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-15"}]}`, CodeInvalidDate},
+		{"too long", `{"plans":[{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2018-08-16"}]}`, CodeInvalidDate},
+		{"bad family", `{"plans":[{"store_nbr":1,"family":"NOPE","start_date":"2017-08-16","end_date":"2017-08-20"}]}`, CodeInvalidFamily},
+		{"unknown adjustment", `{"plans":[{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-20","adjustments":{"discount":0.2}}]}`, CodeInvalidAdjustment},
+		{"overlap", `{"plans":[
+			{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","end_date":"2017-08-20"},
+			{"store_nbr":1,"family":"DAIRY","start_date":"2017-08-20","end_date":"2017-08-22"}
+		]}`, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.PromotionsImpact(w, httptest.NewRequest(http.MethodPost, "/v1/promotions/impact", strings.NewReader(tt.body)))
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if w.Code != http.StatusBadRequest || resp.Code != tt.code {
+				t.Errorf("expected 400 %s, got %d %s: %s", tt.code, w.Code, resp.Code, resp.Error)
+			}
+		})
+	}
+}
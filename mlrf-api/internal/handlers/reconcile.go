@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mlrf/mlrf-api/internal/reconcile"
+	"github.com/rs/zerolog/log"
+)
+
+// ReconciliationMethodHeader reports the reconciliation method applied to /hierarchy.
+const ReconciliationMethodHeader = "X-Reconciliation-Method"
+
+// LoadReconciliationCovariance loads the forecast error covariance used by MinT reconciliation.
+// This is optional - without it, /hierarchy?method=mint is unavailable.
+func (h *Handlers) LoadReconciliationCovariance(path string) error {
+	cov, err := reconcile.LoadCovariance(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load reconciliation covariance, MinT disabled")
+		return err
+	}
+	h.covariance = cov
+	log.Info().Int("nodes", cov.Size()).Bool("diagonal", cov.Matrix == nil).Msg("Loaded reconciliation covariance")
+	return nil
+}
+
+// reconcileHierarchy replaces the tree's predictions with coherent reconciled forecasts.
+func (h *Handlers) reconcileHierarchy(root *HierarchyNode, method reconcile.Method) error {
+	tree := toReconcileNode(root)
+	if err := reconcile.Reconcile(tree, method, h.covariance); err != nil {
+		return err
+	}
+	fromReconcileNode(root, tree)
+	return nil
+}
+
+// refreshLeafForecasts scores every family leaf with the model.
+func (h *Handlers) refreshLeafForecasts(ctx context.Context, root *HierarchyNode, date string, horizon int) error {
+	var leaves []*HierarchyNode
+	var items []SimplePredictRequest
+	var walk func(n *HierarchyNode)
+	walk = func(n *HierarchyNode) {
+		if len(n.Children) == 0 {
+			if storeNbr, family, ok := leafSeries(n); ok {
+				leaves = append(leaves, n)
+				items = append(items, SimplePredictRequest{StoreNbr: storeNbr, Family: family, Date: date, Horizon: horizon})
+			}
+			return
+		}
+		for i := range n.Children {
+			walk(&n.Children[i])
+		}
+	}
+	walk(root)
+
+	predictions, _, err := h.scoreSeries(ctx, items)
+	if err != nil {
+		return err
+	}
+	for i, leaf := range leaves {
+		leaf.Prediction = float64(predictions[i])
+	}
+	return nil
+}
+
+// leafSeries returns the (store, family) series of a family leaf, whose ID is
+// "<store_nbr>_<family>" and whose name is the family.
+func leafSeries(n *HierarchyNode) (int, string, bool) {
+	if n.Level != "family" {
+		return 0, "", false
+	}
+	prefix, _, _ := strings.Cut(n.ID, "_")
+	storeNbr, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0, "", false
+	}
+	return storeNbr, n.Name, true
+}
+
+// toReconcileNode copies node IDs and predictions into a reconciliation tree.
+func toReconcileNode(n *HierarchyNode) *reconcile.Node {
+	node := &reconcile.Node{ID: n.ID, Forecast: n.Prediction}
+	for i := range n.Children {
+		node.Children = append(node.Children, toReconcileNode(&n.Children[i]))
+	}
+	return node
+}
+
+// fromReconcileNode copies reconciled forecasts back into the hierarchy.
+func fromReconcileNode(n *HierarchyNode, node *reconcile.Node) {
+	n.Prediction = node.Forecast
+	for i := range n.Children {
+		fromReconcileNode(&n.Children[i], node.Children[i])
+	}
+}
+
+// DefaultCoherenceTolerance is the relative parent/children deviation accepted by /hierarchy/validate.
+const DefaultCoherenceTolerance = 0.001
+
+// NodeResidual is the coherence error of one aggregate node.
+type NodeResidual struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Level            string  `json:"level"`
+	Prediction       float64 `json:"prediction"`
+	ChildrenSum      float64 `json:"children_sum"`
+	Residual         float64 `json:"residual"`          // prediction - children_sum
+	RelativeResidual float64 `json:"relative_residual"` // residual / |prediction|
+	Coherent         bool    `json:"coherent"`
+}
+
+// HierarchyValidationResponse reports whether every aggregate equals the sum of its children.
+type HierarchyValidationResponse struct {
+	Coherent        bool           `json:"coherent"`
+	Method          string         `json:"method,omitempty"`
+	Tolerance       float64        `json:"tolerance"`
+	NodesChecked    int            `json:"nodes_checked"`
+	IncoherentNodes int            `json:"incoherent_nodes"`
+	MaxAbsResidual  float64        `json:"max_abs_residual"`
+	Nodes           []NodeResidual `json:"nodes"` // Every aggregate node, in tree order
+}
+
+// ValidateHierarchy checks the tree served by /hierarchy for coherence and reports
+// each aggregate node's residual against the sum of its children. ?tolerance= sets
+// the accepted relative deviation (default 0.001); date, method and horizon are
+// applied as for /hierarchy, so a reconciliation method can be checked directly.
+func (h *Handlers) ValidateHierarchy(w http.ResponseWriter, r *http.Request) {
+	tolerance := DefaultCoherenceTolerance
+	if v := r.URL.Query().Get("tolerance"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 || t > 1 {
+			WriteBadRequest(w, r, "tolerance must be a number between 0 and 1", CodeInvalidRequest)
+			return
+		}
+		tolerance = t
+	}
+
+	root, ok := h.requestHierarchy(w, r)
+	if !ok {
+		return
+	}
+
+	nodes := make(map[string]*HierarchyNode)
+	var index func(n *HierarchyNode)
+	index = func(n *HierarchyNode) {
+		nodes[n.ID] = n
+		for i := range n.Children {
+			index(&n.Children[i])
+		}
+	}
+	index(root)
+
+	resp := HierarchyValidationResponse{
+		Coherent:  true,
+		Method:    r.URL.Query().Get("method"),
+		Tolerance: tolerance,
+		Nodes:     []NodeResidual{},
+	}
+	for _, res := range reconcile.Check(toReconcileNode(root), tolerance) {
+		n := nodes[res.ID]
+		resp.Nodes = append(resp.Nodes, NodeResidual{
+			ID:               res.ID,
+			Name:             n.Name,
+			Level:            n.Level,
+			Prediction:       res.Forecast,
+			ChildrenSum:      res.ChildrenSum,
+			Residual:         res.Residual,
+			RelativeResidual: res.Relative,
+			Coherent:         res.Coherent,
+		})
+		resp.MaxAbsResidual = math.Max(resp.MaxAbsResidual, math.Abs(res.Residual))
+		if !res.Coherent {
+			resp.Coherent = false
+			resp.IncoherentNodes++
+		}
+	}
+	resp.NodesChecked = len(resp.Nodes)
+
+	if !resp.Coherent {
+		log.Warn().
+			Int("incoherent", resp.IncoherentNodes).
+			Float64("max_abs_residual", resp.MaxAbsResidual).
+			Str("method", resp.Method).
+			Msg("Hierarchy is not coherent")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+const testHierarchy = `{
+  "id": "total", "name": "Total", "level": "total", "prediction": 100,
+  "children": [
+    {"id": "store_1", "name": "Store 1", "level": "store", "prediction": 40, "children": [
+      {"id": "1_DAIRY", "name": "DAIRY", "level": "family", "prediction": 10},
+      {"id": "1_BREAD/BAKERY", "name": "BREAD/BAKERY", "level": "family", "prediction": 30}
+    ]},
+    {"id": "store_2", "name": "Store 2", "level": "store", "prediction": 50, "children": [
+      {"id": "2_DAIRY", "name": "DAIRY", "level": "family", "prediction": 20}
+    ]}
+  ]
+}`
+
+// setTestHierarchy points the hierarchy data path of h at testHierarchy.
+func setTestHierarchy(t *testing.T, h *Handlers) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hierarchy.json")
+	if err := os.WriteFile(path, []byte(testHierarchy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.hierarchyDataPath = path
+}
+
+func getHierarchy(t *testing.T, h *Handlers, query string) (*httptest.ResponseRecorder, HierarchyNode) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Hierarchy(w, httptest.NewRequest(http.MethodGet, "/v1/hierarchy?"+query, nil))
+	var node HierarchyNode
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &node); err != nil {
+			t.Fatalf("failed to parse hierarchy: %v", err)
+		}
+	}
+	return w, node
+}
+
+func TestHierarchyReconcileBottomUpWithModel(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, SalesLag1: 5},
+		{StoreNbr: 1, Family: "BREAD/BAKERY", Date: date, SalesLag1: 7},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, SalesLag1: 11},
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+	setTestHierarchy(t, h)
+
+	w, root := getHierarchy(t, h, "date=2017-08-15&method=bottom_up")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get(ReconciliationMethodHeader) != "bottom_up" {
+		t.Errorf("expected method header, got %q", w.Header().Get(ReconciliationMethodHeader))
+	}
+	// Leaves come from the model: 5 + 7 and 11
+	if root.Prediction != 23 || root.Children[0].Prediction != 12 || root.Children[1].Prediction != 11 {
+		t.Errorf("unexpected reconciled tree: total=%f store_1=%f store_2=%f",
+			root.Prediction, root.Children[0].Prediction, root.Children[1].Prediction)
+	}
+}
+
+func TestHierarchyReconcileTopDown(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+
+	_, root := getHierarchy(t, h, "method=top_down")
+
+	// Total 100 split 40:50 between stores, then by family proportions
+	store1 := root.Children[0]
+	if math.Abs(store1.Prediction-400.0/9) > 1e-9 {
+		t.Errorf("expected store_1 = 44.44, got %f", store1.Prediction)
+	}
+	if sum := store1.Children[0].Prediction + store1.Children[1].Prediction; math.Abs(sum-store1.Prediction) > 1e-9 {
+		t.Errorf("store_1 children sum to %f, expected %f", sum, store1.Prediction)
+	}
+}
+
+func TestHierarchyReconcileErrors(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+
+	if w, _ := getHierarchy(t, h, "method=middle_out"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown method, got %d", w.Code)
+	}
+	if w, _ := getHierarchy(t, h, "method=mint"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for mint without covariance, got %d", w.Code)
+	}
+	if w, _ := getHierarchy(t, h, "method=ols&horizon=7"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid horizon, got %d", w.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), "cov.json")
+	os.WriteFile(path, []byte(`{"ids":["total","store_1","store_2","1_DAIRY","1_BREAD/BAKERY","2_DAIRY"],"diagonal":[1,1,1,1,1,1]}`), 0o644)
+	if err := h.LoadReconciliationCovariance(path); err != nil {
+		t.Fatal(err)
+	}
+	if w, root := getHierarchy(t, h, "method=mint"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for mint with covariance, got %d: %s", w.Code, w.Body.String())
+	} else if sum := root.Children[0].Prediction + root.Children[1].Prediction; math.Abs(sum-root.Prediction) > 1e-6 {
+		t.Errorf("mint total %f is not the sum of stores %f", root.Prediction, sum)
+	}
+}
+
+func TestValidateHierarchy(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	setTestHierarchy(t, h)
+
+	validate := func(query string) (*httptest.ResponseRecorder, HierarchyValidationResponse) {
+		w := httptest.NewRecorder()
+		h.ValidateHierarchy(w, httptest.NewRequest(http.MethodGet, "/v1/hierarchy/validate?"+query, nil))
+		var resp HierarchyValidationResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// Base forecasts: total 100 vs 40 + 50, store_2 50 vs 20
+	w, resp := validate("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Coherent || resp.NodesChecked != 3 || resp.IncoherentNodes != 2 || resp.MaxAbsResidual != 30 {
+		t.Errorf("unexpected validation summary: %+v", resp)
+	}
+	total := resp.Nodes[0]
+	if total.ID != "total" || total.ChildrenSum != 90 || total.Residual != 10 || total.Coherent {
+		t.Errorf("unexpected total residual: %+v", total)
+	}
+	if store1 := resp.Nodes[1]; store1.ID != "store_1" || !store1.Coherent {
+		t.Errorf("expected store_1 to be coherent: %+v", store1)
+	}
+
+	// Reconciled trees are coherent
+	for _, method := range []string{"bottom_up", "top_down", "ols"} {
+		if _, resp := validate("method=" + method); !resp.Coherent {
+			t.Errorf("expected %s to be coherent, got %+v", method, resp.Nodes)
+		}
+	}
+
+	if w, _ := validate("tolerance=-1"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative tolerance, got %d", w.Code)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/refresh"
+)
+
+// warmChunkSize is the number of series scored per PredictBatch call when warming.
+const warmChunkSize = 1000
+
+// SetRefreshScheduler reports the scheduled feature refresh in /health.
+func (h *Handlers) SetRefreshScheduler(s *refresh.Scheduler) {
+	h.refresher = s
+}
+
+// WarmPredictionCache scores every series at its latest feature date and caches the
+// result under the /predict/simple key for each valid horizon, so the first dashboard
+// requests after a refresh are cache hits. Returns the number of entries cached.
+func (h *Handlers) WarmPredictionCache(ctx context.Context) (int, error) {
+	if h.cache == nil || h.onnx == nil || h.featureStore == nil || !h.featureStore.IsLoaded() {
+		return 0, nil
+	}
+
+	type warmItem struct {
+		series   features.Series
+		date     string
+		features []float32
+	}
+	var series []features.Series
+	var keys []features.FeatureKey
+	for _, s := range h.featureStore.Series() {
+		last, ok := h.featureStore.LastDate(s.StoreNbr, s.Family)
+		if !ok {
+			continue
+		}
+		series = append(series, s)
+		keys = append(keys, features.FeatureKey{StoreNbr: s.StoreNbr, Family: s.Family, Date: last.Format(DateFormat)})
+	}
+
+	var items []warmItem
+	for i, r := range h.featureStore.GetFeaturesBatch(keys) {
+		if r.Source != features.SourceExact {
+			continue
+		}
+		items = append(items, warmItem{series: series[i], date: keys[i].Date, features: r.Features})
+	}
+
+	warmed := 0
+	for start := 0; start < len(items); start += warmChunkSize {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+		chunk := items[start:min(start+warmChunkSize, len(items))]
+		batch := make([][]float32, len(chunk))
+		families := make([]string, len(chunk))
+		for i, item := range chunk {
+			batch[i] = item.features
+			families[i] = item.series.Family
+		}
+		predictions, err := inference.PredictBatch(inference.WithMetricLabels(ctx, "", families...), h.onnx, batch)
+		if err != nil {
+			return warmed, err
+		}
+		guards, err := h.guardBatch(predictions)
+		if err != nil && !errors.Is(err, inference.ErrInvalidPrediction) {
+			return warmed, err
+		}
+
+		toCache := make(map[string]*cache.PredictionResult, len(chunk)*len(ValidHorizons))
+		for i, item := range chunk {
+			if guards[i] == inference.GuardRejected {
+				continue
+			}
+			for horizon := range ValidHorizons {
+				key := cache.GenerateCacheKey(item.series.StoreNbr, item.series.Family, item.date, horizon)
+				toCache[key] = &cache.PredictionResult{
+					StoreNbr:      item.series.StoreNbr,
+					Family:        item.series.Family,
+					Date:          item.date,
+					Horizon:       horizon,
+					Prediction:    predictions[i],
+					FeatureSource: string(features.SourceExact),
+					Guard:         guards[i],
+				}
+			}
+		}
+		if err := h.cache.SetPredictions(ctx, cache.EndpointSimple, toCache); err != nil {
+			return warmed, err
+		}
+		warmed += len(toCache)
+	}
+	return warmed, nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/rs/zerolog/log"
+)
+
+// LoadRegressorOverrides loads supplied future values of exogenous features, such as
+// finance's oil price forecast, applied wherever features are looked up or rolled
+// forward. This is optional - without it, rolled-forward days keep the regressor
+// values of their series' last known row.
+func (h *Handlers) LoadRegressorOverrides(path string) error {
+	r, err := features.LoadRegressors(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Could not load regressor overrides")
+		return err
+	}
+	h.regressors.Store(r)
+	log.Info().Str("path", path).Int("values", r.Len()).Msg("Regressor overrides loaded")
+	return nil
+}
+
+// applyRegressorOverrides returns f with the overrides file's values for the series and date.
+func (h *Handlers) applyRegressorOverrides(f []float32, storeNbr int, family, date string) []float32 {
+	d, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return f
+	}
+	return h.regressors.Load().Apply(f, storeNbr, family, d)
+}
+
+// forecastRegressors converts the regressors of a /forecast request, feature to date
+// to value, into values for its series.
+func forecastRegressors(storeNbr int, family string, values map[string]map[string]float32) (*features.Regressors, *ValidationError) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	r := features.NewRegressors()
+	for _, feature := range sortedKeys(values) {
+		for _, date := range sortedKeys(values[feature]) {
+			v := features.RegressorValue{Feature: feature, StoreNbr: storeNbr, Family: family, Date: date, Value: values[feature][date]}
+			if err := r.Add(v); err != nil {
+				return nil, &ValidationError{Message: fmt.Sprintf("regressors.%s: %v", feature, err), Code: CodeInvalidRegressor}
+			}
+		}
+	}
+	return r, nil
+}
+
+// simpleRegressors converts the regressors of a /predict/simple request, feature to
+// value on the request's date, into values for its series.
+func simpleRegressors(storeNbr int, family, date string, values map[string]float32) (*features.Regressors, *ValidationError) {
+	byDate := make(map[string]map[string]float32, len(values))
+	for feature, v := range values {
+		byDate[feature] = map[string]float32{date: v}
+	}
+	return forecastRegressors(storeNbr, family, byDate)
+}
+
+// regressorsKey is a canonical form of request regressors, telling apart concurrent
+// /predict/simple requests that supply different values.
+func regressorsKey(values map[string]float32) string {
+	var b strings.Builder
+	for _, feature := range sortedKeys(values) {
+		b.WriteString(feature)
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatFloat(float64(values[feature]), 'g', -1, 32))
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// newOilHandlers returns handlers predicting the oil price, over a DAIRY series of
+// store 1 last known on 2017-08-15 at an oil price of 40, with oil at 45 from the
+// overrides file from 2017-08-16 to 2017-08-20.
+func newOilHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC), Year: 2017, OilPrice: 40},
+	})
+	i, _ := schema.Features.Index("oil_price")
+	h := NewHandlers(columnInferencer{index: i}, nil, store, nil)
+
+	path := filepath.Join(t.TempDir(), "regressor_overrides.json")
+	if err := os.WriteFile(path, []byte(`{"regressors":[{"feature":"oil_price","start_date":"2017-08-16","end_date":"2017-08-20","value":45}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadRegressorOverrides(path); err != nil {
+		t.Fatalf("LoadRegressorOverrides failed: %v", err)
+	}
+	return h
+}
+
+func TestForecastRegressors(t *testing.T) {
+	h := newOilHandlers(t)
+	w := httptest.NewRecorder()
+	h.Forecast(w, httptest.NewRequest(http.MethodPost, "/v1/forecast", strings.NewReader(`{
+		"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","horizon":15,
+		"regressors":{"oil_price":{"2017-08-18":50,"2017-08-25":55}}
+	}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ForecastResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	// Request values beat the file's, which beat the last known row's
+	want := map[string]float32{"2017-08-16": 45, "2017-08-18": 50, "2017-08-20": 45, "2017-08-21": 40, "2017-08-25": 55}
+	for _, p := range resp.Forecast {
+		if v, ok := want[p.Date]; ok && p.Prediction != v {
+			t.Errorf("%s: expected oil price %v, got %v", p.Date, v, p.Prediction)
+		}
+	}
+}
+
+func TestPredictSimpleRegressors(t *testing.T) {
+	h := newOilHandlers(t)
+	predictSimple := func(body string) (int, PredictResponse, ErrorResponse) {
+		w := httptest.NewRecorder()
+		h.PredictSimple(w, httptest.NewRequest(http.MethodPost, "/v1/predict/simple", strings.NewReader(body)))
+		var resp PredictResponse
+		var errResp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		json.Unmarshal(w.Body.Bytes(), &errResp)
+		return w.Code, resp, errResp
+	}
+
+	if _, resp, _ := predictSimple(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15}`); resp.Prediction != 40 {
+		t.Errorf("expected the known oil price 40, got %v", resp.Prediction)
+	}
+	if _, resp, _ := predictSimple(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-17","horizon":15}`); resp.Prediction != 45 {
+		t.Errorf("expected the overrides file's oil price 45, got %v", resp.Prediction)
+	}
+	if _, resp, _ := predictSimple(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"regressors":{"oil_price":52}}`); resp.Prediction != 52 {
+		t.Errorf("expected the requested oil price 52, got %v", resp.Prediction)
+	}
+
+	for _, body := range []string{
+		`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"regressors":{"transactions":1}}`,
+		`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"regressors":{"oil_price":-1}}`,
+	} {
+		if code, _, errResp := predictSimple(body); code != http.StatusBadRequest || errResp.Code != CodeInvalidRegressor {
+			t.Errorf("%s: expected 400 %s, got %d %s", body, CodeInvalidRegressor, code, errResp.Code)
+		}
+	}
+}
+
+func TestForecastInvalidRegressors(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+	w := httptest.NewRecorder()
+	h.Forecast(w, httptest.NewRequest(http.MethodPost, "/v1/forecast", strings.NewReader(`{
+		"store_nbr":1,"family":"DAIRY","start_date":"2017-08-16","horizon":15,
+		"regressors":{"oil_price":{"next week":50}}
+	}`)))
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Code != CodeInvalidRegressor {
+		t.Errorf("expected 400 %s, got %d %s", CodeInvalidRegressor, w.Code, resp.Code)
+	}
+}
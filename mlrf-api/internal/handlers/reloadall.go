@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/live"
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// Components of /admin/reload-all, in reload order.
+const (
+	ComponentFeatures            = "features"
+	ComponentModel               = "model"
+	ComponentFeatureSchemaCheck  = "feature_schema_check"
+	ComponentIntervals           = "intervals"
+	ComponentHierarchyDefinition = "hierarchy_definition"
+	ComponentHistory             = "history"
+)
+
+// Statuses of a component of /admin/reload-all, and of the reload as a whole.
+const (
+	ReloadStatusReloaded = "reloaded"
+	ReloadStatusPassed   = "passed"  // The check succeeded
+	ReloadStatusSkipped  = "skipped" // Not configured
+	ReloadStatusFailed   = "failed"  // The previous version keeps serving
+	ReloadStatusPartial  = "partial" // Some components failed, others reloaded
+)
+
+// reloadCheckSeries is the number of series whose latest features the schema check scores.
+const reloadCheckSeries = 5
+
+// ComponentReload is the outcome of reloading one component.
+type ComponentReload struct {
+	Component  string                 `json:"component"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message,omitempty"` // Why it failed or was skipped
+	Code       string                 `json:"code,omitempty"`    // Error code of a failure, e.g. FEATURE_QUALITY_FAILED
+	DurationMs float64                `json:"duration_ms"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ReloadAllResponse reports a reload of every component.
+type ReloadAllResponse struct {
+	Status        string            `json:"status"`
+	Components    []ComponentReload `json:"components"` // In reload order
+	CachesFlushed bool              `json:"caches_flushed"`
+	DurationMs    float64           `json:"duration_ms"`
+}
+
+// ReloadAll reloads the feature store, the model, the prediction intervals, the
+// hierarchy definition and the sales history in that order. The schema check scores
+// the latest features of a few series with a model, to check the two fit: a new model
+// is checked against the features before it is swapped in, and the features against
+// the serving model after that, the previous features being restored when they fail.
+// Cached predictions, hierarchies and backtests are flushed once at the end. A
+// component that fails keeps its previous version serving and the others still
+// reload; the response is 500 when any failed. Requires the admin scope.
+func (h *Handlers) ReloadAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, r, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
+		return
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	resp := ReloadAllResponse{}
+	run := func(component string, reload func() (ComponentReload, error)) ComponentReload {
+		began := time.Now()
+		c, err := reload()
+		c.Component = component
+		if err != nil {
+			c.Status = ReloadStatusFailed
+			c.Message = err.Error()
+			if c.Code == "" {
+				c.Code = CodeReloadFailed
+			}
+			log.Error().Err(err).Str("component", component).Msg("Reload failed")
+		}
+		c.DurationMs = float64(time.Since(began).Microseconds()) / 1000
+		resp.Components = append(resp.Components, c)
+		return c
+	}
+
+	// The features reload first so a new model is checked against the features it
+	// will serve. The snapshot keeps the previous features in memory until the check
+	// against the serving model has passed.
+	var snapshot features.Snapshot
+	if h.featureStore != nil {
+		snapshot = h.featureStore.Snapshot()
+	}
+	feats := run(ComponentFeatures, func() (ComponentReload, error) {
+		if h.featureStore == nil {
+			return skipped("feature store not configured"), nil
+		}
+		meta, err := h.reloadFeatureStore(ctx)
+		var qerr *features.QualityError
+		var serr *schema.MismatchError
+		switch {
+		case errors.As(err, &qerr):
+			return ComponentReload{Code: CodeFeatureQualityFailed}, err
+		case errors.As(err, &serr):
+			return ComponentReload{Code: CodeFeatureSchemaMismatch}, err
+		case err != nil:
+			return ComponentReload{}, err
+		}
+		return ComponentReload{Status: ReloadStatusReloaded, Metadata: map[string]interface{}{
+			"file_path":     meta.FilePath,
+			"version":       meta.Version,
+			"row_count":     meta.RowCount,
+			"data_date_min": meta.DataDateMin,
+			"data_date_max": meta.DataDateMax,
+		}}, nil
+	})
+	model := run(ComponentModel, func() (ComponentReload, error) {
+		if h.modelLoader == nil {
+			return skipped("model reload not configured"), nil
+		}
+		var check func(inference.Inferencer) error
+		checkFailed := false
+		if h.featureStore != nil && h.featureStore.IsLoaded() {
+			check = func(next inference.Inferencer) error {
+				if _, err := h.checkFeaturesAgainstModel(ctx, next); err != nil {
+					checkFailed = true
+					return fmt.Errorf("feature schema check: %w", err)
+				}
+				return nil
+			}
+		}
+		info, err := h.reloadModel(ctx, check)
+		if err != nil {
+			if checkFailed {
+				return ComponentReload{Code: CodeFeatureSchemaMismatch}, err
+			}
+			return ComponentReload{}, err
+		}
+		return ComponentReload{Status: ReloadStatusReloaded, Metadata: map[string]interface{}{
+			"file_path": info.Path,
+			"version":   info.Version,
+			"loaded_at": info.LoadedAt,
+		}}, nil
+	})
+	check := run(ComponentFeatureSchemaCheck, func() (ComponentReload, error) {
+		if h.onnx == nil || h.featureStore == nil || !h.featureStore.IsLoaded() {
+			return skipped("needs a loaded model and feature store"), nil
+		}
+		checked, err := h.checkFeaturesAgainstModel(ctx, h.onnx)
+		if err != nil {
+			return ComponentReload{Code: CodeFeatureSchemaMismatch}, err
+		}
+		return ComponentReload{Status: ReloadStatusPassed, Metadata: map[string]interface{}{
+			"series_checked": checked,
+			"num_features":   schema.Features.Len(),
+		}}, nil
+	})
+	if check.Status == ReloadStatusFailed && feats.Status == ReloadStatusReloaded {
+		// The serving model can't score the new features; serve the previous ones again
+		h.featureStore.Restore(snapshot)
+		feats.Status = ReloadStatusFailed
+		feats.Code = CodeFeatureSchemaMismatch
+		feats.Message = "rolled back: " + check.Message
+		resp.Components[0] = feats
+		log.Warn().Str("reason", check.Message).Msg("Feature reload rolled back")
+	}
+	intervals := run(ComponentIntervals, func() (ComponentReload, error) {
+		h.intervalsMu.RLock()
+		path, loaded := h.intervalsPath, h.intervalsInfo.Path != ""
+		h.intervalsMu.RUnlock()
+		if path == "" {
+			return skipped("prediction intervals path not configured"), nil
+		}
+		if err := h.RefreshIntervals(ctx); err != nil {
+			return optionalFileFailed(err, loaded)
+		}
+		info := h.IntervalsInfo()
+		return ComponentReload{Status: ReloadStatusReloaded, Metadata: map[string]interface{}{
+			"file_path": info.Path,
+			"version":   info.Version,
+			"sha256":    info.SHA256,
+		}}, nil
+	})
+	run(ComponentHierarchyDefinition, func() (ComponentReload, error) {
+		path := h.hierarchyDefinitionPath()
+		if err := h.loadHierarchyDefinition(path); err != nil {
+			return optionalFileFailed(err, h.hierarchyDef.Load() != nil)
+		}
+		def := h.hierarchyDef.Load()
+		return ComponentReload{Status: ReloadStatusReloaded, Metadata: map[string]interface{}{
+			"file_path": path,
+			"levels":    def.Levels,
+			"nodes":     def.Size(),
+		}}, nil
+	})
+	run(ComponentHistory, func() (ComponentReload, error) {
+		if h.historyLoader == nil {
+			return skipped("sales history not configured"), nil
+		}
+		info, err := h.RefreshHistory(ctx)
+		if err != nil {
+			return optionalFileFailed(err, h.history.Load() != nil)
+		}
+		return ComponentReload{Status: ReloadStatusReloaded, Metadata: map[string]interface{}{
+			"source":     info.Source,
+			"series":     info.Series,
+			"rows":       info.Rows,
+			"first_date": info.FirstDate,
+			"last_date":  info.LastDate,
+		}}, nil
+	})
+
+	// Every reloaded component invalidates cached results; flush them once
+	reloaded, failed := 0, 0
+	for _, c := range resp.Components {
+		switch c.Status {
+		case ReloadStatusReloaded:
+			reloaded++
+		case ReloadStatusFailed:
+			failed++
+		}
+	}
+	if reloaded > 0 {
+		h.RotateCacheNamespace()
+		h.invalidateHierarchy(ctx)
+		h.invalidateBacktests()
+		h.startCacheWarm()
+		resp.CachesFlushed = true
+	}
+	switch {
+	case model.Status == ReloadStatusReloaded:
+		h.notifyLive(live.ReasonModelReloaded)
+	case feats.Status == ReloadStatusReloaded || intervals.Status == ReloadStatusReloaded:
+		h.notifyLive(live.ReasonFeaturesReloaded)
+	}
+
+	resp.Status = ReloadStatusReloaded
+	status := http.StatusOK
+	if failed > 0 {
+		resp.Status = ReloadStatusPartial
+		if reloaded == 0 {
+			resp.Status = ReloadStatusFailed
+		}
+		status = http.StatusInternalServerError
+	}
+	resp.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	log.Info().
+		Str("status", resp.Status).
+		Int("reloaded", reloaded).
+		Int("failed", failed).
+		Float64("duration_ms", resp.DurationMs).
+		Msg("Reloaded all components")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// skipped is the outcome of a component that isn't configured.
+func skipped(reason string) ComponentReload {
+	return ComponentReload{Status: ReloadStatusSkipped, Message: reason}
+}
+
+// optionalFileFailed is the outcome of an optional file failing to reload: skipped
+// when the file doesn't exist and none was loaded before, otherwise failed.
+func optionalFileFailed(err error, loaded bool) (ComponentReload, error) {
+	if errors.Is(err, fs.ErrNotExist) && !loaded {
+		return skipped(err.Error()), nil
+	}
+	return ComponentReload{}, err
+}
+
+// checkFeaturesAgainstModel scores the latest features of up to reloadCheckSeries
+// series, spread over the feature store, with model, and returns how many were
+// scored. It fails when a vector doesn't have the model's feature count or the model
+// fails or predicts outside the guard on it.
+func (h *Handlers) checkFeaturesAgainstModel(ctx context.Context, model inference.Inferencer) (int, error) {
+	series := h.featureStore.Series()
+	if len(series) == 0 {
+		return 0, errors.New("feature store has no series to check")
+	}
+	step := max(len(series)/reloadCheckSeries, 1)
+	checked := 0
+	for i := 0; i < len(series) && checked < reloadCheckSeries; i += step {
+		s := series[i]
+		last, ok := h.featureStore.LastDate(s.StoreNbr, s.Family)
+		if !ok {
+			continue
+		}
+		feats, ok := h.featureStore.Lookup(s.StoreNbr, s.Family, last)
+		if !ok {
+			continue
+		}
+		if len(feats) != schema.Features.Len() {
+			return checked, fmt.Errorf("store %d, family %s has %d features, model expects %d", s.StoreNbr, s.Family, len(feats), schema.Features.Len())
+		}
+		pred, err := inference.Predict(inference.WithMetricLabels(ctx, "", s.Family), model, feats)
+		if err != nil {
+			return checked, fmt.Errorf("store %d, family %s: %w", s.StoreNbr, s.Family, err)
+		}
+		if _, _, err := h.guard.Check(pred); err != nil {
+			return checked, fmt.Errorf("store %d, family %s: %w", s.StoreNbr, s.Family, err)
+		}
+		checked++
+	}
+	if checked == 0 {
+		return 0, errors.New("no series has features to check")
+	}
+	return checked, nil
+}
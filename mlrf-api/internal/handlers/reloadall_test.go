@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+)
+
+func reloadAll(t *testing.T, h *Handlers) (*httptest.ResponseRecorder, ReloadAllResponse, map[string]ComponentReload) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ReloadAll(w, httptest.NewRequest(http.MethodPost, "/admin/reload-all", nil))
+	var resp ReloadAllResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	byComponent := make(map[string]ComponentReload)
+	for _, c := range resp.Components {
+		byComponent[c.Component] = c
+	}
+	return w, resp, byComponent
+}
+
+func TestReloadAll(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, SalesLag1: 10},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, SalesLag1: 20},
+	})
+	h := NewHandlers(&MockInferencer{prediction: 100}, nil, store, nil)
+	reloader := &mockModelReloader{info: inference.ModelInfo{Path: "models/model.onnx"}}
+	h.SetModelReloader(reloader)
+	intervalsPath := writeIntervalsFile(t, `{"lower_80_offset":-10,"upper_80_offset":10,"lower_95_offset":-20,"upper_95_offset":20}`)
+	if err := h.LoadPredictionIntervals(intervalsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadHierarchyDefinition(writeHierarchyDefinition(t, testHierarchyDefinition)); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := filepath.Join(t.TempDir(), "history.json")
+	os.WriteFile(historyPath, []byte(`{"1_DAIRY_2017-08-14": 9, "1_DAIRY_2017-08-15": 10}`), 0o644)
+	if err := h.LoadHistory(historyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	w, resp, components := reloadAll(t, h)
+	if w.Code != http.StatusOK || resp.Status != ReloadStatusReloaded || !resp.CachesFlushed {
+		t.Fatalf("expected every component to reload, got %d: %s", w.Code, w.Body.String())
+	}
+	order := []string{ComponentFeatures, ComponentModel, ComponentFeatureSchemaCheck, ComponentIntervals, ComponentHierarchyDefinition, ComponentHistory}
+	for i, c := range resp.Components {
+		if c.Component != order[i] {
+			t.Errorf("expected %s at %d, got %s", order[i], i, c.Component)
+		}
+	}
+	if c := components[ComponentFeatureSchemaCheck]; c.Status != ReloadStatusPassed || c.Metadata["series_checked"] != float64(2) {
+		t.Errorf("expected both series to be checked, got %+v", c)
+	}
+	if c := components[ComponentModel]; c.Status != ReloadStatusReloaded || reloader.reloadCnt != 1 || c.Metadata["version"] != "v1" {
+		t.Errorf("expected the model to reload once, got %+v", c)
+	}
+	if c := components[ComponentHistory]; c.Status != ReloadStatusReloaded || c.Metadata["rows"] != float64(2) {
+		t.Errorf("unexpected history reload %+v", c)
+	}
+
+	// A broken intervals file fails alone, keeping the current intervals
+	os.WriteFile(intervalsPath, []byte(`{not json`), 0o644)
+	w, resp, components = reloadAll(t, h)
+	if w.Code != http.StatusInternalServerError || resp.Status != ReloadStatusPartial {
+		t.Fatalf("expected a partial reload, got %d: %s", w.Code, w.Body.String())
+	}
+	if c := components[ComponentIntervals]; c.Status != ReloadStatusFailed || c.Code != CodeReloadFailed {
+		t.Errorf("expected the intervals to fail, got %+v", c)
+	}
+	if components[ComponentHistory].Status != ReloadStatusReloaded || h.IntervalsInfo().Path != intervalsPath {
+		t.Error("expected the other components to reload and the intervals to keep serving")
+	}
+
+	// A new model that can't score the features is never swapped in
+	reloader.next = &MockInferencer{err: errors.New("input shape mismatch")}
+	_, _, components = reloadAll(t, h)
+	if c := components[ComponentModel]; c.Status != ReloadStatusFailed || c.Code != CodeFeatureSchemaMismatch || reloader.info.Version != "v2" {
+		t.Errorf("expected the new model to be rejected before the swap, got %+v serving %s", c, reloader.info.Version)
+	}
+	if components[ComponentFeatures].Status != ReloadStatusReloaded || components[ComponentFeatureSchemaCheck].Status != ReloadStatusPassed {
+		t.Errorf("expected the features to pass against the serving model, got %+v", components)
+	}
+
+	// New features the serving model can't score are rolled back
+	loadedAt := store.GetMetadata().LoadedAt
+	h.onnx = &MockInferencer{err: errors.New("input shape mismatch")}
+	_, _, components = reloadAll(t, h)
+	if c := components[ComponentFeatureSchemaCheck]; c.Status != ReloadStatusFailed || c.Code != CodeFeatureSchemaMismatch {
+		t.Errorf("expected the schema check to fail, got %+v", c)
+	}
+	if c := components[ComponentFeatures]; c.Status != ReloadStatusFailed || c.Code != CodeFeatureSchemaMismatch {
+		t.Errorf("expected the features to be rolled back, got %+v", c)
+	}
+	if !store.GetMetadata().LoadedAt.Equal(loadedAt) || !store.IsLoaded() {
+		t.Error("expected the previous features to keep serving")
+	}
+}
+
+func TestReloadAllSkipsUnconfigured(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	h.LoadHierarchyDefinition(filepath.Join(t.TempDir(), "missing.json"))
+
+	w, resp, _ := reloadAll(t, h)
+	if w.Code != http.StatusOK || resp.Status != ReloadStatusReloaded || resp.CachesFlushed {
+		t.Fatalf("expected nothing to reload, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, c := range resp.Components {
+		if c.Status != ReloadStatusSkipped {
+			t.Errorf("expected %s to be skipped, got %+v", c.Component, c)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/predlog"
+	"github.com/rs/zerolog/log"
+)
+
+// Feature sources of replayed predictions besides a feature store lookup.
+const (
+	replaySourceLogged  = "logged"  // The features logged with the original prediction
+	replaySourceRequest = "request" // The features sent in the replay request
+)
+
+// ReplayRequest selects predictions to re-run against a model: those logged under a
+// previous request's ID, or one feature vector.
+type ReplayRequest struct {
+	RequestID  string    `json:"request_id,omitempty"` // Replay the predictions logged under this request ID
+	StoreNbr   int       `json:"store_nbr,omitempty"`  // With request_id, only this store's predictions
+	Family     string    `json:"family,omitempty"`     // With request_id, only this family's predictions
+	Date       string    `json:"date,omitempty"`
+	Horizon    int       `json:"horizon,omitempty"`
+	Features   []float32 `json:"features,omitempty"`   // Replay this feature vector instead of a logged request
+	Prediction *float32  `json:"prediction,omitempty"` // The original prediction of features, to diff against
+	Model      string    `json:"model,omitempty"`      // Registered model name or "name@version"; empty = champion
+}
+
+// ReplayPrediction is a prediction of a replayed feature vector.
+type ReplayPrediction struct {
+	Prediction   float32    `json:"prediction"`
+	Model        string     `json:"model,omitempty"`
+	ModelVersion string     `json:"model_version,omitempty"`
+	Timestamp    *time.Time `json:"timestamp,omitempty"` // When the original prediction was served
+	Endpoint     string     `json:"endpoint,omitempty"`  // Where the original prediction was served
+	Cached       bool       `json:"cached,omitempty"`
+	Guard        string     `json:"guard,omitempty"`
+}
+
+// ReplayResult compares the original and replayed prediction of one feature vector.
+type ReplayResult struct {
+	StoreNbr      int               `json:"store_nbr"`
+	Family        string            `json:"family"`
+	Date          string            `json:"date"`
+	Horizon       int               `json:"horizon"`
+	Features      []float32         `json:"features"`
+	FeatureSource string            `json:"feature_source"`     // logged, request, or the lookup source of a cache hit logged without features
+	Original      *ReplayPrediction `json:"original,omitempty"` // Unset for features sent without their prediction
+	Replay        ReplayPrediction  `json:"replay"`
+	Diff          *float32          `json:"diff,omitempty"`     // replay - original
+	DiffPct       *float64          `json:"diff_pct,omitempty"` // diff as a percentage of original; unset when original is 0
+	Identical     bool              `json:"identical"`          // The replay reproduced the original bit for bit
+}
+
+// ReplayResponse holds the replayed predictions, in logged order.
+type ReplayResponse struct {
+	RequestID string         `json:"request_id,omitempty"`
+	Results   []ReplayResult `json:"results"`
+	LatencyMs float64        `json:"latency_ms"`
+	TraceID   string         `json:"trace_id,omitempty"`
+}
+
+// PredictReplay handles POST /predict/replay. It re-runs the predictions logged under
+// a previous request ID (see predlog), or a feature vector, against the champion or a
+// registered model version, and returns the original and replayed predictions with
+// their difference. Replays skip the cache and are not logged.
+func (h *Handlers) PredictReplay(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+
+	var req ReplayRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if (req.RequestID == "") == (req.Features == nil) {
+		WriteBadRequest(w, r, "exactly one of request_id or features is required", CodeInvalidRequest)
+		return
+	}
+	model, modelKey, verr := h.resolveModel(req.Model)
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+
+	var results []ReplayResult
+	if req.RequestID != "" {
+		if h.predLog == nil {
+			WriteServiceUnavailable(w, r, "prediction logging is disabled", CodePredictionLogUnavailable)
+			return
+		}
+		entries, err := h.predLog.Find(req.RequestID)
+		if err != nil {
+			log.Error().Err(err).Str("replay_request_id", req.RequestID).Msg("prediction log search failed")
+			WriteInternalError(w, r, "failed to search the prediction log", CodeInternalError)
+			return
+		}
+		for _, e := range entries {
+			if (req.StoreNbr != 0 && int(e.StoreNbr) != req.StoreNbr) || (req.Family != "" && e.Family != req.Family) {
+				continue
+			}
+			results = append(results, loggedReplay(e))
+		}
+		if len(results) == 0 {
+			WriteError(w, r, http.StatusNotFound, fmt.Sprintf("no logged predictions for request %s", req.RequestID), CodePredictionNotFound)
+			return
+		}
+		if err := ValidateBatchSizeLimit(len(results), h.maxBatchSize); err != nil {
+			WriteBadRequest(w, r, fmt.Sprintf("request %s logged %d predictions: %s; filter by store_nbr or family", req.RequestID, len(results), err.Message), err.Code)
+			return
+		}
+	} else {
+		if err := ValidateFeatures(req.Features); err != nil {
+			WriteBadRequest(w, r, err.Message, err.Code)
+			return
+		}
+		result := ReplayResult{
+			StoreNbr:      req.StoreNbr,
+			Family:        req.Family,
+			Date:          req.Date,
+			Horizon:       req.Horizon,
+			Features:      req.Features,
+			FeatureSource: replaySourceRequest,
+		}
+		if req.Prediction != nil {
+			result.Original = &ReplayPrediction{Prediction: *req.Prediction}
+		}
+		results = []ReplayResult{result}
+	}
+
+	// Cache hits of /predict/simple are logged without features; look them up again
+	for i := range results {
+		res := &results[i]
+		if len(res.Features) > 0 {
+			continue
+		}
+		if h.featureStore == nil || !h.featureStore.IsLoaded() {
+			WriteError(w, r, http.StatusUnprocessableEntity,
+				fmt.Sprintf("the prediction for store %d, family %s was logged without features and no feature store is loaded", res.StoreNbr, res.Family),
+				CodeFeatureNotFound)
+			return
+		}
+		feats, source := h.lookupFeatures(ctx, res.StoreNbr, res.Family, res.Date)
+		res.Features, res.FeatureSource = feats, string(source)
+	}
+
+	if model == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	batch := make([][]float32, len(results))
+	families := make([]string, len(results))
+	for i, res := range results {
+		batch[i] = res.Features
+		families[i] = res.Family
+	}
+	predictions, err := inference.PredictBatch(inference.WithMetricLabels(ctx, modelKey, families...), model, batch)
+	if err != nil {
+		log.Error().Err(err).Msg("replay inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+	h.annotateAccess(ctx, false, modelKey)
+
+	version := h.modelVersion(modelKey)
+	for i := range results {
+		prediction, guard, err := h.guard.Check(predictions[i])
+		if err != nil {
+			writeInvalidPrediction(w, r, err)
+			return
+		}
+		res := &results[i]
+		res.Replay = ReplayPrediction{Prediction: prediction, Model: modelKey, ModelVersion: version, Guard: guard}
+		if res.Original != nil {
+			diff := prediction - res.Original.Prediction
+			res.Diff = &diff
+			res.Identical = math.Float32bits(prediction) == math.Float32bits(res.Original.Prediction)
+			if res.Original.Prediction != 0 {
+				pct := float64(diff) / math.Abs(float64(res.Original.Prediction)) * 100
+				res.DiffPct = &pct
+			}
+		}
+	}
+
+	resp := ReplayResponse{
+		RequestID: req.RequestID,
+		Results:   results,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		TraceID:   middleware.TraceID(r),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// loggedReplay returns the replay of a logged prediction, before it is re-run.
+func loggedReplay(e predlog.Entry) ReplayResult {
+	timestamp := e.Timestamp
+	return ReplayResult{
+		StoreNbr:      int(e.StoreNbr),
+		Family:        e.Family,
+		Date:          e.Date,
+		Horizon:       int(e.Horizon),
+		Features:      e.Features,
+		FeatureSource: replaySourceLogged,
+		Original: &ReplayPrediction{
+			Prediction:   e.Prediction,
+			Model:        e.Model,
+			ModelVersion: e.ModelVersion,
+			Timestamp:    &timestamp,
+			Endpoint:     e.Endpoint,
+			Cached:       e.Cached,
+		},
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// replay posts a /predict/replay request.
+func replay(h *Handlers, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/predict/replay", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.PredictReplay(w, req)
+	return w
+}
+
+func TestPredictReplayLoggedRequest(t *testing.T) {
+	mockOnnx := &MockInferencer{prediction: 42}
+	h := NewHandlers(mockOnnx, nil, nil, nil)
+	l, _ := newTestPredictionLogger(t)
+	defer l.Close()
+	h.SetPredictionLogger(l)
+
+	body := `{"predictions":[
+		{"store_nbr":1,"family":"GROCERY I","date":"2017-08-01","features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]},
+		{"store_nbr":2,"family":"DAIRY","date":"2017-08-01","features":[1,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/predict/batch", bytes.NewReader([]byte(body)))
+	req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "req-batch"))
+	w := httptest.NewRecorder()
+	h.PredictBatch(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// The model changed since
+	mockOnnx.mu.Lock()
+	mockOnnx.prediction = 50.4
+	mockOnnx.mu.Unlock()
+
+	w = replay(h, `{"request_id":"req-batch"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReplayResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.RequestID != "req-batch" || len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results for req-batch, got %+v", resp)
+	}
+	for i, res := range resp.Results {
+		if res.StoreNbr != i+1 || res.FeatureSource != replaySourceLogged || len(res.Features) != RequiredFeatureCount {
+			t.Errorf("result %d: unexpected series or features %+v", i, res)
+		}
+		if res.Original == nil || res.Original.Prediction != 42 || res.Original.Endpoint != "/predict/batch" || res.Original.Timestamp == nil {
+			t.Errorf("result %d: unexpected original %+v", i, res.Original)
+		}
+		if res.Replay.Prediction != 50.4 || res.Identical {
+			t.Errorf("result %d: unexpected replay %+v", i, res.Replay)
+		}
+		if res.Diff == nil || *res.Diff != float32(50.4)-42 || res.DiffPct == nil || *res.DiffPct < 19.9 || *res.DiffPct > 20.1 {
+			t.Errorf("result %d: unexpected diff %v, %v", i, res.Diff, res.DiffPct)
+		}
+	}
+
+	// One series of the batch
+	w = replay(h, `{"request_id":"req-batch","family":"DAIRY"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].StoreNbr != 2 {
+		t.Errorf("expected only the DAIRY prediction, got %+v", resp.Results)
+	}
+
+	w = replay(h, `{"request_id":"req-unknown"}`)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), CodePredictionNotFound) {
+		t.Errorf("expected 404 %s, got %d: %s", CodePredictionNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestPredictReplayFeatures(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+
+	w := replay(h, `{"features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"prediction":42}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReplayResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(resp.Results))
+	}
+	res := resp.Results[0]
+	if res.FeatureSource != replaySourceRequest || !res.Identical || res.Diff == nil || *res.Diff != 0 {
+		t.Errorf("expected an identical replay of the request's features, got %+v", res)
+	}
+
+	// Without the original prediction there is nothing to diff
+	w = replay(h, `{"features":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`)
+	if !strings.Contains(w.Body.String(), `"replay":{"prediction":42`) || strings.Contains(w.Body.String(), `"diff"`) {
+		t.Errorf("expected a replay without diff, got %s", w.Body.String())
+	}
+}
+
+func TestPredictReplayErrors(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 42}, nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"neither", `{"model":""}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"both", `{"request_id":"req-1","features":[1]}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"bad features", `{"features":[1,2]}`, http.StatusBadRequest, CodeInvalidFeatures},
+		{"unknown model", `{"request_id":"req-1","model":"challenger"}`, http.StatusBadRequest, CodeInvalidModel},
+		{"logging disabled", `{"request_id":"req-1"}`, http.StatusServiceUnavailable, CodePredictionLogUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := replay(h, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("expected code %s, got %s", tt.code, resp.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/history"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+)
+
+const (
+	// DefaultSeriesPageSize is the number of days returned by /series when no limit is given.
+	DefaultSeriesPageSize = 100
+	// MaxSeriesPageSize caps the limit of /series.
+	MaxSeriesPageSize = 500
+	// defaultSeriesHistoryDays is the history leading into the forecast by default.
+	defaultSeriesHistoryDays = 28
+)
+
+// SeriesPoint is one day of a /series response: the actual sales of a history day, or
+// the prediction and bands of a forecast day.
+type SeriesPoint struct {
+	Date       string   `json:"date"`
+	Actual     *float64 `json:"actual,omitempty"`     // History days with sales data
+	Prediction *float32 `json:"prediction,omitempty"` // Forecast days
+	Lower80    float32  `json:"lower_80,omitempty"`
+	Upper80    float32  `json:"upper_80,omitempty"`
+	Lower95    float32  `json:"lower_95,omitempty"`
+	Upper95    float32  `json:"upper_95,omitempty"`
+}
+
+// SeriesResponse is a page of the contiguous daily history and forecast of a series
+// or aggregate node.
+type SeriesResponse struct {
+	Level         string        `json:"level"`
+	StoreNbr      int           `json:"store_nbr,omitempty"`
+	Family        string        `json:"family,omitempty"`
+	HistoryStart  string        `json:"history_start"`
+	ForecastStart string        `json:"forecast_start"`
+	EndDate       string        `json:"end_date"`
+	Horizon       int           `json:"horizon"`
+	SeriesCount   int           `json:"series_count,omitempty"` // Bottom-level series forecast, when the page has forecast days
+	IntervalSet   string        `json:"interval_set,omitempty"` // Intervals of a single series; aggregates combine theirs in quadrature
+	Points        []SeriesPoint `json:"points"`
+	Total         int           `json:"total"` // Days of history and forecast
+	Offset        int           `json:"offset"`
+	Limit         int           `json:"limit"`
+	NextOffset    *int          `json:"next_offset,omitempty"` // Set when more days follow
+	LatencyMs     float64       `json:"latency_ms"`
+}
+
+// Series returns the daily actual sales of a series, store, family or the total
+// followed without a gap by its forecast with confidence bands, one point per day and
+// paged by date. Query parameters:
+//   - level: series (default, needs store_nbr and family), store, family or total
+//   - start_date: first forecast day, default the day after the node's last history
+//   - horizon: forecast days, one of the valid horizons (default the shortest)
+//   - history_days: days of history before start_date (default 28, max 365)
+//   - offset, limit: page through the days (default 0 and 100, max 500)
+//
+// Forecast days are only computed when the page includes them. Supports
+// If-None-Match (see notModified).
+func (h *Handlers) Series(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	storeNbr, err := queryInt(r, "store_nbr", 0)
+	if err != nil {
+		WriteBadRequest(w, r, "store_nbr must be an integer", CodeInvalidStore)
+		return
+	}
+	level, node, verr := h.historySeries(q.Get("level"), storeNbr, q.Get("family"))
+	if verr == nil && node.Family != "" {
+		verr = ValidateFamily(node.Family)
+	}
+	if verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+
+	horizon, err := queryInt(r, "horizon", DefaultHorizons[0])
+	if err != nil {
+		WriteBadRequest(w, r, "horizon must be an integer", CodeInvalidHorizon)
+		return
+	}
+	if verr := ValidateHorizon(horizon); verr != nil {
+		WriteBadRequest(w, r, verr.Message, verr.Code)
+		return
+	}
+	historyDays, err := queryInt(r, "history_days", defaultSeriesHistoryDays)
+	if err != nil || historyDays < 0 || historyDays > maxHistoryDays {
+		WriteBadRequest(w, r, fmt.Sprintf("history_days must be between 0 and %d", maxHistoryDays), CodeInvalidRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		WriteBadRequest(w, r, "offset must be a non-negative integer", CodeInvalidRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultSeriesPageSize)
+	if err != nil || limit < 1 || limit > MaxSeriesPageSize {
+		WriteBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", MaxSeriesPageSize), CodeInvalidRequest)
+		return
+	}
+
+	hist := h.history.Load()
+	var forecastStart time.Time
+	if v := q.Get("start_date"); v != "" {
+		if verr := ValidateDate(v); verr != nil {
+			WriteBadRequest(w, r, "start_date: "+verr.Message, verr.Code)
+			return
+		}
+		forecastStart, _ = time.Parse(DateFormat, v)
+	} else if _, last, ok := historySpan(hist, node); ok {
+		forecastStart = last.AddDate(0, 0, 1)
+	} else {
+		WriteBadRequest(w, r, "start_date is required without sales history for the series", CodeInvalidDate)
+		return
+	}
+
+	var historyLoaded string
+	if hist != nil {
+		historyLoaded = strconv.FormatInt(hist.Info().LoadedAt.UnixNano(), 10)
+	}
+	if h.notModified(w, r, historyLoaded) {
+		return
+	}
+
+	historyStart := forecastStart.AddDate(0, 0, -historyDays)
+	resp := SeriesResponse{
+		Level:         level,
+		StoreNbr:      node.StoreNbr,
+		Family:        node.Family,
+		HistoryStart:  historyStart.Format(DateFormat),
+		ForecastStart: forecastStart.Format(DateFormat),
+		EndDate:       forecastStart.AddDate(0, 0, horizon-1).Format(DateFormat),
+		Horizon:       horizon,
+		Total:         historyDays + horizon,
+		Offset:        offset,
+		Limit:         limit,
+		Points:        []SeriesPoint{},
+	}
+	if offset < resp.Total {
+		end := min(offset+limit, resp.Total)
+		if end < resp.Total {
+			resp.NextOffset = &end
+		}
+
+		resp.Points = make([]SeriesPoint, end-offset)
+		for i := range resp.Points {
+			resp.Points[i].Date = historyStart.AddDate(0, 0, offset+i).Format(DateFormat)
+		}
+
+		// History days of the page: every day is a point, with actual set when there is data
+		if offset < historyDays && hist != nil {
+			actuals, _ := hist.Range(node, historyStart.AddDate(0, 0, offset), historyStart.AddDate(0, 0, min(end, historyDays)-1))
+			byDate := make(map[string]float64, len(actuals))
+			for _, p := range actuals {
+				byDate[p.Date] = p.Sales
+			}
+			for i := range resp.Points[:min(end, historyDays)-offset] {
+				if v, ok := byDate[resp.Points[i].Date]; ok {
+					resp.Points[i].Actual = &v
+				}
+			}
+		}
+
+		// Forecast days of the page, rolled forward from the forecast start
+		if end > historyDays {
+			if !h.forecastSeriesPage(w, r, level, node, forecastStart, &resp, max(offset, historyDays)-historyDays, end-historyDays) {
+				return
+			}
+		}
+	}
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// historySpan returns the first and last history date of a series or aggregate.
+func historySpan(s *history.Store, node history.Series) (first, last time.Time, ok bool) {
+	if s == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return s.Span(node)
+}
+
+// forecastSeriesPage fills in the forecast days from, to (exclusive, counted from the
+// forecast start) of a /series page, summing the forecasts of the series under an
+// aggregate node and combining their interval offsets in quadrature like
+// /predict/aggregate. It writes the error response and returns false on failure.
+func (h *Handlers) forecastSeriesPage(w http.ResponseWriter, r *http.Request, level string, node history.Series, start time.Time, resp *SeriesResponse, from, to int) bool {
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return false
+	}
+	var store *features.Store
+	if h.featureStore != nil && h.featureStore.IsLoaded() {
+		store = h.featureStore
+	}
+
+	series := []features.Series{{StoreNbr: node.StoreNbr, Family: node.Family}}
+	if level != HistoryLevelSeries {
+		if store == nil {
+			WriteServiceUnavailable(w, r, "feature store not loaded", CodeFeatureStoreUnavailable)
+			return false
+		}
+		series = h.aggregateSeries(AggregateRequest{Level: level, StoreNbr: node.StoreNbr, Family: node.Family})
+		if len(series) == 0 {
+			WriteError(w, r, http.StatusNotFound, "no series found for "+level, CodeFeatureNotFound)
+			return false
+		}
+	}
+
+	n := to - from
+	total := make([]float64, n)
+	var lower80, upper80, lower95, upper95 []float64
+	for _, s := range series {
+		if err := r.Context().Err(); err != nil {
+			writeScenarioError(w, r, err)
+			return false
+		}
+		// The forecast depends on every earlier day, so roll up to the page's last day
+		featureRows, predictions, err := h.featureBuilder(store).Series(s.StoreNbr, s.Family, start, to, h.predictor(r.Context(), s.Family))
+		if err != nil {
+			writeScenarioError(w, r, fmt.Errorf("store %d, family %s: %w", s.StoreNbr, s.Family, err))
+			return false
+		}
+		for d := from; d < to; d++ {
+			prediction := predictions[d]
+			total[d-from] += float64(prediction)
+
+			bands := h.predictionIntervals(s.StoreNbr, s.Family, d+1, featureRows[d], prediction)
+			if bands.Upper80 == 0 {
+				continue
+			}
+			if lower80 == nil {
+				lower80, upper80, lower95, upper95 = make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+			}
+			i := d - from
+			lower80[i] += square(prediction - bands.Lower80)
+			upper80[i] += square(bands.Upper80 - prediction)
+			lower95[i] += square(prediction - bands.Lower95)
+			upper95[i] += square(bands.Upper95 - prediction)
+			if level == HistoryLevelSeries {
+				resp.IntervalSet = bands.Set
+			}
+		}
+	}
+	middleware.SetModelVersion(r.Context(), h.modelVersion(""))
+
+	resp.SeriesCount = len(series)
+	page := resp.Points[len(resp.Points)-n:]
+	for i := range page {
+		prediction := float32(total[i])
+		page[i].Prediction = &prediction
+		if lower80 != nil {
+			// Floor at zero (sales can't be negative)
+			page[i].Lower80 = float32(math.Max(total[i]-math.Sqrt(lower80[i]), 0))
+			page[i].Upper80 = float32(total[i] + math.Sqrt(upper80[i]))
+			page[i].Lower95 = float32(math.Max(total[i]-math.Sqrt(lower95[i]), 0))
+			page[i].Upper95 = float32(total[i] + math.Sqrt(upper95[i]))
+		}
+	}
+	return true
+}
+
+// square returns the square of an interval offset.
+func square(v float32) float64 {
+	return float64(v) * float64(v)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+// newSeriesHandlers returns handlers predicting with mock for every day, with DAIRY and
+// BEVERAGES of store 1 and DAIRY of store 2 in the feature matrix, and history of
+// store 1 DAIRY on 2017-08-13 and 2017-08-15.
+func newSeriesHandlers(t *testing.T, mock *MockInferencer) *Handlers {
+	t.Helper()
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, Year: 2017},
+		{StoreNbr: 1, Family: "BEVERAGES", Date: date, Year: 2017},
+		{StoreNbr: 2, Family: "DAIRY", Date: date, Year: 2017},
+	})
+	h := NewHandlers(mock, nil, store, nil)
+	h.intervals = &PredictionIntervals{Lower80Offset: -3, Upper80Offset: 4, Lower95Offset: -6, Upper95Offset: 8}
+
+	path := filepath.Join(t.TempDir(), "historical_data.json")
+	if err := os.WriteFile(path, []byte(`{"1_DAIRY_2017-08-13": 7, "1_DAIRY_2017-08-15": 0}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	return h
+}
+
+func getSeries(t *testing.T, h *Handlers, target string) (*httptest.ResponseRecorder, SeriesResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Series(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp SeriesResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+	}
+	return w, resp
+}
+
+func TestSeries(t *testing.T) {
+	h := newSeriesHandlers(t, &MockInferencer{prediction: 10})
+
+	w, resp := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&history_days=3")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// The forecast follows the last history day; a day without sales has no actual
+	if resp.HistoryStart != "2017-08-13" || resp.ForecastStart != "2017-08-16" || resp.EndDate != "2017-08-30" || resp.Total != 18 || len(resp.Points) != 18 {
+		t.Fatalf("unexpected series %+v", resp)
+	}
+	p := resp.Points
+	if p[0].Actual == nil || *p[0].Actual != 7 || p[1].Actual != nil || p[2].Actual == nil || *p[2].Actual != 0 || p[2].Prediction != nil {
+		t.Errorf("unexpected history points %+v", p[:3])
+	}
+	if p[3].Date != "2017-08-16" || p[3].Prediction == nil || *p[3].Prediction != 10 || p[3].Actual != nil || p[3].Lower80 != 7 || p[3].Upper95 != 18 {
+		t.Errorf("unexpected first forecast point %+v", p[3])
+	}
+	if resp.SeriesCount != 1 || resp.IntervalSet != IntervalLevelGlobal {
+		t.Errorf("expected a single series with global intervals, got %d %q", resp.SeriesCount, resp.IntervalSet)
+	}
+
+	// A store sums its series, combining their offsets in quadrature
+	_, resp = getSeries(t, h, "/v1/series?level=store&store_nbr=1&start_date=2017-08-16&history_days=0&horizon=30")
+	if len(resp.Points) != 30 || resp.SeriesCount != 2 || resp.IntervalSet != "" {
+		t.Fatalf("unexpected store series %+v", resp)
+	}
+	if got := *resp.Points[0].Prediction; got != 20 {
+		t.Errorf("expected a store forecast of 20, got %v", got)
+	}
+	if got, want := float64(resp.Points[0].Upper80-20), 4*math.Sqrt2; math.Abs(got-want) > 1e-3 {
+		t.Errorf("expected upper_80 offset %f, got %f", want, got)
+	}
+	if _, resp := getSeries(t, h, "/v1/series?level=family&family=DAIRY&start_date=2017-08-16&history_days=0"); resp.SeriesCount != 2 || *resp.Points[0].Prediction != 20 {
+		t.Errorf("unexpected family series %+v", resp)
+	}
+}
+
+func TestSeriesPaging(t *testing.T) {
+	mock := &MockInferencer{prediction: 10}
+	h := newSeriesHandlers(t, mock)
+
+	// A page of history only needs no forecast
+	_, first := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&history_days=3&limit=2")
+	if len(first.Points) != 2 || first.NextOffset == nil || *first.NextOffset != 2 || first.Points[0].Actual == nil || mock.callCount != 0 {
+		t.Errorf("unexpected first page %+v after %d predictions", first, mock.callCount)
+	}
+	_, second := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&history_days=3&offset=2&limit=2")
+	if len(second.Points) != 2 || second.Points[0].Date != "2017-08-15" || second.Points[1].Prediction == nil || *second.NextOffset != 4 {
+		t.Errorf("unexpected second page %+v", second)
+	}
+	_, last := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&history_days=3&offset=16")
+	if len(last.Points) != 2 || last.Points[1].Date != "2017-08-30" || last.NextOffset != nil {
+		t.Errorf("unexpected last page %+v", last)
+	}
+	if _, past := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&offset=100"); past.Points == nil || len(past.Points) != 0 {
+		t.Errorf("expected an empty page past the end, got %+v", past)
+	}
+}
+
+func TestSeriesErrors(t *testing.T) {
+	h := newSeriesHandlers(t, &MockInferencer{prediction: 10})
+	for target, code := range map[string]string{
+		"/v1/series?family=DAIRY":                                             CodeInvalidStore,
+		"/v1/series?store_nbr=1":                                              CodeInvalidFamily,
+		"/v1/series?level=cluster":                                            CodeInvalidRequest,
+		"/v1/series?store_nbr=1&family=DAIRY&horizon=7":                       CodeInvalidHorizon,
+		"/v1/series?store_nbr=1&family=DAIRY&limit=501":                       CodeInvalidRequest,
+		"/v1/series?store_nbr=1&family=DAIRY&history_days=366":                CodeInvalidRequest,
+		"/v1/series?store_nbr=2&family=DAIRY":                                 CodeInvalidDate,
+		"/v1/series?store_nbr=1&family=DAIRY&start_date=16/08/2017":           CodeInvalidDate,
+		"/v1/series?store_nbr=1&family=DAIRY&start_date=2017-08-16&offset=-1": CodeInvalidRequest,
+	} {
+		w, _ := getSeries(t, h, target)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: expected 400 %s, got %d %s", target, code, w.Code, resp.Code)
+		}
+	}
+
+	// Aggregates need the feature store to find their series, forecasts the model
+	h = NewHandlers(nil, nil, nil, nil)
+	if w, _ := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&start_date=2017-08-16"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a model, got %d", w.Code)
+	}
+	h = NewHandlers(&MockInferencer{prediction: 10}, nil, nil, nil)
+	if w, _ := getSeries(t, h, "/v1/series?level=total&start_date=2017-08-16"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a feature store, got %d", w.Code)
+	}
+	if w, resp := getSeries(t, h, "/v1/series?store_nbr=1&family=DAIRY&start_date=2017-08-16&history_days=0"); w.Code != http.StatusOK || len(resp.Points) != 15 {
+		t.Errorf("expected a forecast rolled from zeros, got %d", w.Code)
+	}
+}
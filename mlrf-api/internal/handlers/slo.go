@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mlrf/mlrf-api/internal/slo"
+)
+
+// SLOResponse reports compliance with the per-route service level objectives.
+type SLOResponse struct {
+	WindowHours float64      `json:"window_hours"` // Compliance window
+	Objectives  []slo.Status `json:"objectives"`   // By route
+}
+
+// SetSLOTracker enables /slo.
+func (h *Handlers) SetSLOTracker(t *slo.Tracker) {
+	h.sloTracker = t
+}
+
+// SLO returns each tracked route's availability and latency compliance over the SLO
+// window, the error budget left and the burn rate over recent windows.
+func (h *Handlers) SLO(w http.ResponseWriter, r *http.Request) {
+	if h.sloTracker == nil {
+		WriteServiceUnavailable(w, r, "SLO tracking not enabled", CodeSLOUnavailable)
+		return
+	}
+
+	resp := SLOResponse{
+		WindowHours: h.sloTracker.Config().Window.Hours(),
+		Objectives:  h.sloTracker.Statuses(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
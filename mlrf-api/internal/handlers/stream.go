@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// streamChunkSize is the number of items scored before results are flushed.
+	// Only one chunk is computed ahead of the client, so a slow reader stalls inference.
+	streamChunkSize = 256
+
+	// streamWriteTimeout bounds how long a chunk may wait for the client to drain it.
+	// Clients that stop reading for longer are disconnected.
+	streamWriteTimeout = 30 * time.Second
+)
+
+// StreamSummary is sent as the final SSE "done" event.
+type StreamSummary struct {
+	Count     int     `json:"count"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// streamEncoder writes streamed predictions as NDJSON lines or SSE events.
+type streamEncoder struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	sse bool
+}
+
+// event writes one record. NDJSON ignores the event name and id.
+func (e *streamEncoder) event(name string, id int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if e.sse {
+		if id >= 0 {
+			_, err = fmt.Fprintf(e.w, "event: %s\nid: %d\ndata: %s\n\n", name, id, data)
+		} else {
+			_, err = fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, data)
+		}
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, "%s\n", data)
+	return err
+}
+
+// flush pushes buffered records to the client and extends the write deadline
+// for the next chunk.
+func (e *streamEncoder) flush() error {
+	if err := e.rc.Flush(); err != nil {
+		return err
+	}
+	if err := e.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// wantsSSE reports whether the client asked for Server-Sent Events rather than NDJSON.
+func wantsSSE(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "sse"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// PredictStream scores a large batch and streams each PredictResponse as soon as
+// its chunk finishes inference, instead of waiting for the whole batch.
+// Responds with NDJSON by default, or SSE with "Accept: text/event-stream" or ?format=sse.
+// Results are in input order. Errors after the stream starts are sent in-band as
+// an ErrorResponse line (NDJSON) or "error" event (SSE) and end the stream.
+func (h *Handlers) PredictStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+
+	var req BatchPredictRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := ValidateBatchSizeLimit(len(req.Predictions), h.streamLimit); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if i, err := h.validateBatch(req.Predictions); err != nil {
+		WriteBadRequest(w, r, fmt.Sprintf("prediction[%d]: %s", i, err.Message), err.Code)
+		return
+	}
+
+	enc := &streamEncoder{w: w, rc: http.NewResponseController(w), sse: wantsSSE(r)}
+	if enc.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	if err := enc.flush(); err != nil {
+		log.Warn().Err(err).Msg("stream flush failed")
+		return
+	}
+
+	sent := 0
+	for offset := 0; offset < len(req.Predictions); offset += streamChunkSize {
+		if ctx.Err() != nil {
+			log.Info().Int("sent", sent).Msg("stream client disconnected")
+			return
+		}
+
+		chunk := req.Predictions[offset:min(offset+streamChunkSize, len(req.Predictions))]
+		responses, err := h.scoreBatch(ctx, chunk)
+		if err != nil {
+			log.Error().Err(err).Int("offset", offset).Msg("stream inference failed")
+			resp := ErrorResponse{Error: "inference failed", Code: CodeInferenceFailed, RequestID: getRequestID(ctx), TraceID: middleware.TraceID(r)}
+			if errors.Is(err, errModelUnavailable) {
+				resp.Error, resp.Code = "model not loaded", CodeModelUnavailable
+			} else if errors.Is(err, inference.ErrInvalidPrediction) {
+				resp.Error, resp.Code = err.Error(), CodeInvalidPrediction
+			}
+			enc.event("error", -1, resp)
+			enc.flush()
+			return
+		}
+
+		h.logPredictions(getRequestID(ctx), r.URL.Path, chunk, responses)
+
+		for i, resp := range responses {
+			if err := enc.event("prediction", offset+i, resp); err != nil {
+				log.Warn().Err(err).Int("sent", sent).Msg("stream write failed")
+				return
+			}
+		}
+		if err := enc.flush(); err != nil {
+			log.Warn().Err(err).Int("sent", sent).Msg("stream flush failed")
+			return
+		}
+		sent += len(responses)
+	}
+
+	if enc.sse {
+		enc.event("done", -1, StreamSummary{
+			Count:     sent,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		})
+		enc.flush()
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPredictStreamNDJSON(t *testing.T) {
+	mock := &MockInferencer{prediction: 42}
+	h := NewHandlers(mock, nil, nil, nil)
+
+	n := streamChunkSize + 10
+	req := httptest.NewRequest(http.MethodPost, "/v1/predict/stream", bytes.NewReader(batchBody(n)))
+	w := httptest.NewRecorder()
+	h.PredictStream(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON content type, got %q", ct)
+	}
+	if !w.Flushed {
+		t.Error("expected stream to be flushed")
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var resp PredictResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("line %d is not a PredictResponse: %v", lines, err)
+		}
+		if resp.Prediction != 42 {
+			t.Errorf("line %d: expected prediction 42, got %f", lines, resp.Prediction)
+		}
+		lines++
+	}
+	if lines != n {
+		t.Errorf("expected %d lines, got %d", n, lines)
+	}
+}
+
+func TestPredictStreamSSE(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 7}, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/predict/stream", bytes.NewReader(batchBody(3)))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	h.PredictStream(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected SSE content type, got %q", ct)
+	}
+	body := w.Body.String()
+	if got := strings.Count(body, "event: prediction\n"); got != 3 {
+		t.Errorf("expected 3 prediction events, got %d:\n%s", got, body)
+	}
+	if !strings.Contains(body, "id: 2\n") {
+		t.Error("expected events to carry their batch index as id")
+	}
+	if !strings.Contains(body, "event: done\ndata: {\"count\":3,") {
+		t.Errorf("expected done event with count, got:\n%s", body)
+	}
+}
+
+func TestPredictStreamErrors(t *testing.T) {
+	t.Run("invalid item rejected before streaming", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{}, nil, nil, nil)
+		body := bytes.Replace(batchBody(2), []byte(`"store_nbr":1`), []byte(`"store_nbr":0`), 1)
+
+		w := httptest.NewRecorder()
+		h.PredictStream(w, httptest.NewRequest(http.MethodPost, "/v1/predict/stream", bytes.NewReader(body)))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{}, nil, nil, nil)
+		h.streamLimit = 5
+
+		w := httptest.NewRecorder()
+		h.PredictStream(w, httptest.NewRequest(http.MethodPost, "/v1/predict/stream", bytes.NewReader(batchBody(6))))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("inference failure sent in-band", func(t *testing.T) {
+		h := NewHandlers(&MockInferencer{err: errors.New("boom")}, nil, nil, nil)
+
+		w := httptest.NewRecorder()
+		h.PredictStream(w, httptest.NewRequest(http.MethodPost, "/v1/predict/stream", bytes.NewReader(batchBody(2))))
+
+		var resp ErrorResponse
+		if err := json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &resp); err != nil {
+			t.Fatalf("expected a single error line, got %q", w.Body.String())
+		}
+		if resp.Code != CodeInferenceFailed {
+			t.Errorf("expected code %s, got %s", CodeInferenceFailed, resp.Code)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// Sweep limits and the default range, a ±20% multiplier.
+const (
+	defaultSweepSteps = 5
+	maxSweepSteps     = 101
+	defaultSweepFrom  = 0.8
+	defaultSweepTo    = 1.2
+)
+
+// WhatIfSweepRequest varies one feature of a series and date over a range.
+type WhatIfSweepRequest struct {
+	StoreNbr int     `json:"store_nbr"`
+	Family   string  `json:"family"`
+	Date     string  `json:"date"`
+	Feature  string  `json:"feature"` // A schema column or /whatif alias, e.g. "oil_price"
+	From     float32 `json:"from"`    // First adjustment, as in /whatif; from and to default to 0.8 and 1.2
+	To       float32 `json:"to"`      // Last adjustment
+	Steps    int     `json:"steps"`   // Evenly spaced adjustments from from to to, default 5
+}
+
+// WhatIfSweepPoint is the prediction for one adjustment of the swept feature.
+type WhatIfSweepPoint struct {
+	Adjustment   float32 `json:"adjustment"`
+	FeatureValue float32 `json:"feature_value"` // The feature after the adjustment
+	Prediction   float32 `json:"prediction"`
+	Delta        float32 `json:"delta"`
+	DeltaPct     float32 `json:"delta_pct"`
+	Warning      string  `json:"warning,omitempty"` // "clamped" or "rounded" when the feature isn't the adjustment asked for
+}
+
+// WhatIfSweepResponse is the prediction curve of a sensitivity sweep.
+type WhatIfSweepResponse struct {
+	Feature   string             `json:"feature"`
+	Column    string             `json:"column"` // Schema column the feature resolved to
+	BaseValue float32            `json:"base_value"`
+	Baseline  float32            `json:"baseline"` // Prediction without adjustment
+	Points    []WhatIfSweepPoint `json:"points"`
+	Range     float32            `json:"range"` // Highest minus lowest prediction, for ranking features in tornado charts
+	LatencyMs float64            `json:"latency_ms"`
+}
+
+// WhatIfSweep handles sensitivity sweeps: it predicts a series and date with one
+// feature adjusted to each of steps values from from to to, and the baseline, in a
+// single batch inference call.
+func (h *Handlers) WhatIfSweep(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req WhatIfSweepRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.validateStoreNbr(req.StoreNbr); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateFamily(req.Family); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	if err := ValidateDate(req.Date); err != nil {
+		WriteBadRequest(w, r, err.Message, err.Code)
+		return
+	}
+	col, ok := whatIfFeatureIndex(req.Feature)
+	if !ok {
+		WriteBadRequest(w, r, fmt.Sprintf("unknown feature %q, see /features/schema", req.Feature), CodeInvalidAdjustment)
+		return
+	}
+	if req.Steps == 0 {
+		req.Steps = defaultSweepSteps
+	}
+	if req.Steps < 2 || req.Steps > maxSweepSteps {
+		WriteBadRequest(w, r, fmt.Sprintf("steps must be between 2 and %d", maxSweepSteps), CodeInvalidRequest)
+		return
+	}
+	if req.From == 0 && req.To == 0 {
+		req.From, req.To = defaultSweepFrom, defaultSweepTo
+	}
+
+	if h.onnx == nil {
+		WriteServiceUnavailable(w, r, "model not loaded", CodeModelUnavailable)
+		return
+	}
+
+	base := h.whatIfFeatures(req.StoreNbr, req.Family, req.Date)
+
+	// The baseline first, then one row per step
+	batch := make([][]float32, 0, req.Steps+1)
+	batch = append(batch, base)
+	adjustments := make([]float32, req.Steps)
+	warnings := make([]string, req.Steps)
+	for i := range adjustments {
+		adjustments[i] = req.From + (req.To-req.From)*float32(i)/float32(req.Steps-1)
+		row := make([]float32, len(base))
+		copy(row, base)
+		row[col.Index], warnings[i] = adjustFeature(col, base[col.Index], adjustments[i])
+		batch = append(batch, row)
+	}
+
+	ctx := inference.WithMetricLabels(r.Context(), "", req.Family)
+	predictions, err := inference.PredictBatch(ctx, h.onnx, batch)
+	if err != nil {
+		log.Error().Err(err).Str("feature", req.Feature).Msg("sweep inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		return
+	}
+	for i, p := range predictions {
+		if predictions[i], _, err = h.guard.Check(p); err != nil {
+			writeInvalidPrediction(w, r, err)
+			return
+		}
+	}
+	middleware.SetModelVersion(ctx, h.modelVersion(""))
+
+	baseline := predictions[0]
+	resp := WhatIfSweepResponse{
+		Feature:   req.Feature,
+		Column:    col.Name,
+		BaseValue: base[col.Index],
+		Baseline:  baseline,
+		Points:    make([]WhatIfSweepPoint, req.Steps),
+	}
+	low, high := predictions[1], predictions[1]
+	for i, adjustment := range adjustments {
+		prediction := predictions[i+1]
+		low, high = min(low, prediction), max(high, prediction)
+		resp.Points[i] = WhatIfSweepPoint{
+			Adjustment:   adjustment,
+			FeatureValue: batch[i+1][col.Index],
+			Prediction:   prediction,
+			Delta:        prediction - baseline,
+			DeltaPct:     deltaPercent(baseline, prediction),
+			Warning:      warnings[i],
+		}
+	}
+	resp.Range = high - low
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+func sweep(h *Handlers, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.WhatIfSweep(w, httptest.NewRequest(http.MethodPost, "/v1/whatif/sweep", strings.NewReader(body)))
+	return w
+}
+
+func TestWhatIfSweep(t *testing.T) {
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC), Year: 2017, DayOfWeek: 1, OilPrice: 40},
+	})
+	i, _ := schema.Features.Index("oil_price")
+	h := NewHandlers(columnInferencer{index: i}, nil, store, nil)
+
+	w := sweep(h, `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"oil_price"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp WhatIfSweepResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Column != "oil_price" || resp.BaseValue != 40 || resp.Baseline != 40 || len(resp.Points) != defaultSweepSteps {
+		t.Fatalf("unexpected sweep %+v", resp)
+	}
+	// 0.8, 0.9, 1.0, 1.1, 1.2 of an oil price of 40
+	for i, want := range []float64{32, 36, 40, 44, 48} {
+		p := resp.Points[i]
+		if math.Abs(float64(p.Prediction)-want) > 1e-3 || math.Abs(float64(p.FeatureValue)-want) > 1e-3 || math.Abs(float64(p.Delta)-(want-40)) > 1e-3 {
+			t.Errorf("step %d: expected prediction %v, got %+v", i, want, p)
+		}
+	}
+	if math.Abs(float64(resp.Range)-16) > 1e-3 || math.Abs(float64(resp.Points[4].DeltaPct)-20) > 1e-3 {
+		t.Errorf("expected range 16 and +20%% at the top, got %v and %v", resp.Range, resp.Points[4].DeltaPct)
+	}
+
+	// Calendar features are set, aliases resolve
+	i, _ = schema.Features.Index("dayofweek")
+	h = NewHandlers(columnInferencer{index: i}, nil, store, nil)
+	w = sweep(h, `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"day_of_week","from":1,"to":7,"steps":7}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Points) != 7 || resp.Points[0].Prediction != 1 || resp.Points[6].Prediction != 6 {
+		t.Errorf("expected days of week 1 to 6 (clamped), got %+v", resp.Points)
+	}
+	if resp.Points[5].Warning != "" || resp.Points[6].Warning != WhatIfWarningClamped {
+		t.Errorf("expected only the last step flagged as clamped, got %+v", resp.Points)
+	}
+}
+
+func TestWhatIfSweepValidation(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 1}, nil, nil, nil)
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"unknown feature", `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"transactions"}`, CodeInvalidAdjustment},
+		{"one step", `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"oil_price","steps":1}`, CodeInvalidRequest},
+		{"too many steps", `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"oil_price","steps":500}`, CodeInvalidRequest},
+		{"bad family", `{"store_nbr":1,"family":"NOPE","date":"2017-08-15","feature":"oil_price"}`, CodeInvalidFamily},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sweep(h, tt.body)
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if w.Code != http.StatusBadRequest || resp.Code != tt.code {
+				t.Errorf("expected 400 %s, got %d %s", tt.code, w.Code, resp.Code)
+			}
+		})
+	}
+
+	if w := sweep(NewHandlers(nil, nil, nil, nil), `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","feature":"oil_price"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a model, got %d", w.Code)
+	}
+}
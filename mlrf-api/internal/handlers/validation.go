@@ -2,18 +2,32 @@ package handlers
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/mlrf/mlrf-api/internal/schema"
 )
 
 const (
-	// MaxBatchSize is the maximum number of predictions allowed in a batch request.
+	// MaxBatchSize is the default maximum number of predictions allowed in a batch request.
+	// Override with the MAX_BATCH_SIZE environment variable.
 	MaxBatchSize = 100
 
+	// MaxStreamBatchSize is the default maximum number of predictions in a streaming request.
+	// Override with the STREAM_MAX_BATCH_SIZE environment variable.
+	MaxStreamBatchSize = 50000
+
 	// RequiredFeatureCount is the expected number of features for ONNX inference.
-	RequiredFeatureCount = 27
+	RequiredFeatureCount = schema.NumFeatures
 
 	// DateFormat is the expected date format for prediction requests.
 	DateFormat = "2006-01-02"
+
+	// MaxStoreNbr is the highest store number in the Kaggle Store Sales dataset. Stores
+	// 1 to MaxStoreNbr are accepted until a feature store is loaded.
+	MaxStoreNbr = 54
 )
 
 // ValidFamilies contains all valid product family names from the Kaggle Store Sales dataset.
@@ -54,12 +68,69 @@ var ValidFamilies = map[string]bool{
 	"SEAFOOD":                    true,
 }
 
+// DefaultHorizons are the forecast horizons in days accepted unless FORECAST_HORIZONS is set.
+var DefaultHorizons = []int{15, 30, 60, 90}
+
+// MaxHorizon is the longest forecast horizon FORECAST_HORIZONS may allow.
+const MaxHorizon = 365
+
 // ValidHorizons contains the allowed forecast horizons in days.
-var ValidHorizons = map[int]bool{
-	15: true,
-	30: true,
-	60: true,
-	90: true,
+// Replace it with SetValidHorizons before serving requests.
+var ValidHorizons = horizonSet(DefaultHorizons)
+
+// horizonSet builds a ValidHorizons set from a list of horizons.
+func horizonSet(horizons []int) map[int]bool {
+	set := make(map[int]bool, len(horizons))
+	for _, h := range horizons {
+		set[h] = true
+	}
+	return set
+}
+
+// SetValidHorizons replaces the allowed forecast horizons. It is not safe to call
+// while requests are being served.
+func SetValidHorizons(horizons []int) {
+	ValidHorizons = horizonSet(horizons)
+}
+
+// Horizons returns the allowed forecast horizons in ascending order.
+func Horizons() []int {
+	horizons := make([]int, 0, len(ValidHorizons))
+	for h := range ValidHorizons {
+		horizons = append(horizons, h)
+	}
+	sort.Ints(horizons)
+	return horizons
+}
+
+// ParseHorizons parses a comma-separated list of horizons in days, each between 1
+// and MaxHorizon.
+func ParseHorizons(s string) ([]int, error) {
+	var horizons []int
+	for _, field := range strings.Split(s, ",") {
+		h, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || h < 1 || h > MaxHorizon {
+			return nil, fmt.Errorf("invalid horizon %q: must be a whole number of days between 1 and %d", strings.TrimSpace(field), MaxHorizon)
+		}
+		horizons = append(horizons, h)
+	}
+	return horizons, nil
+}
+
+// horizonList formats the allowed horizons for messages, as in "15, 30, 60, or 90".
+func horizonList() string {
+	horizons := Horizons()
+	parts := make([]string, len(horizons))
+	for i, h := range horizons {
+		parts[i] = strconv.Itoa(h)
+	}
+	switch len(parts) {
+	case 1:
+		return parts[0]
+	case 2:
+		return parts[0] + " or " + parts[1]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + ", or " + parts[len(parts)-1]
 }
 
 // ValidationError represents a validation error with a code for structured responses.
@@ -106,7 +177,7 @@ func ValidateFamily(family string) *ValidationError {
 	return nil
 }
 
-// ValidateStoreNbr checks if the store number is positive.
+// ValidateStoreNbr checks if the store number is between 1 and MaxStoreNbr.
 func ValidateStoreNbr(storeNbr int) *ValidationError {
 	if storeNbr <= 0 {
 		return &ValidationError{
@@ -114,14 +185,35 @@ func ValidateStoreNbr(storeNbr int) *ValidationError {
 			Code:    "INVALID_STORE",
 		}
 	}
+	if storeNbr > MaxStoreNbr {
+		return &ValidationError{
+			Message: fmt.Sprintf("store_nbr must be between 1 and %d", MaxStoreNbr),
+			Code:    "INVALID_STORE",
+		}
+	}
 	return nil
 }
 
-// ValidateHorizon checks if the horizon is one of the allowed values (15, 30, 60, 90).
+// validateStoreNbr checks the store number against the stores of the loaded feature
+// matrix, or with ValidateStoreNbr when none is loaded.
+func (h *Handlers) validateStoreNbr(storeNbr int) *ValidationError {
+	if h.featureStore == nil || !h.featureStore.IsLoaded() || storeNbr <= 0 {
+		return ValidateStoreNbr(storeNbr)
+	}
+	if !h.featureStore.HasStore(storeNbr) {
+		return &ValidationError{
+			Message: fmt.Sprintf("unknown store_nbr: %d", storeNbr),
+			Code:    "INVALID_STORE",
+		}
+	}
+	return nil
+}
+
+// ValidateHorizon checks if the horizon is one of the allowed values in ValidHorizons.
 func ValidateHorizon(horizon int) *ValidationError {
 	if !ValidHorizons[horizon] {
 		return &ValidationError{
-			Message: "horizon must be 15, 30, 60, or 90",
+			Message: "horizon must be " + horizonList(),
 			Code:    "INVALID_HORIZON",
 		}
 	}
@@ -145,17 +237,22 @@ func ValidateFeatures(features []float32) *ValidationError {
 	return nil
 }
 
-// ValidateBatchSize checks if the batch size is within the allowed limit.
+// ValidateBatchSize checks if the batch size is within the default limit.
 func ValidateBatchSize(size int) *ValidationError {
+	return ValidateBatchSizeLimit(size, MaxBatchSize)
+}
+
+// ValidateBatchSizeLimit checks if the batch size is within maxSize.
+func ValidateBatchSizeLimit(size, maxSize int) *ValidationError {
 	if size == 0 {
 		return &ValidationError{
 			Message: "predictions array is empty",
 			Code:    "EMPTY_BATCH",
 		}
 	}
-	if size > MaxBatchSize {
+	if size > maxSize {
 		return &ValidationError{
-			Message: fmt.Sprintf("batch size exceeds maximum of %d", MaxBatchSize),
+			Message: fmt.Sprintf("batch size exceeds maximum of %d", maxSize),
 			Code:    "BATCH_TOO_LARGE",
 		}
 	}
@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
 )
 
 // Unit tests for validation functions
@@ -122,7 +125,8 @@ func TestValidateStoreNbr(t *testing.T) {
 	}{
 		{"valid store 1", 1, false, ""},
 		{"valid store 54", 54, false, ""},
-		{"valid store 100", 100, false, ""},
+		{"store 55", 55, true, "INVALID_STORE"},
+		{"store 100", 100, true, "INVALID_STORE"},
 		{"zero store", 0, true, "INVALID_STORE"},
 		{"negative store", -1, true, "INVALID_STORE"},
 		{"negative large", -100, true, "INVALID_STORE"},
@@ -146,6 +150,30 @@ func TestValidateStoreNbr(t *testing.T) {
 	}
 }
 
+func TestValidateStoreNbrFromFeatureStore(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 3, Family: "DAIRY", Date: date},
+		{StoreNbr: 60, Family: "DAIRY", Date: date},
+	})
+	h := NewHandlers(nil, nil, store, nil)
+
+	for storeNbr, valid := range map[int]bool{3: true, 60: true, 1: false, 54: false, 0: false} {
+		err := h.validateStoreNbr(storeNbr)
+		if valid && err != nil {
+			t.Errorf("unexpected error for store_nbr %d: %s", storeNbr, err.Message)
+		}
+		if !valid && (err == nil || err.Code != "INVALID_STORE") {
+			t.Errorf("expected INVALID_STORE for store_nbr %d, got %v", storeNbr, err)
+		}
+	}
+
+	// Without a feature store the dataset's range applies
+	if err := NewHandlers(nil, nil, nil, nil).validateStoreNbr(54); err != nil {
+		t.Errorf("unexpected error for store_nbr 54: %s", err.Message)
+	}
+}
+
 func TestValidateHorizon(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -182,6 +210,33 @@ func TestValidateHorizon(t *testing.T) {
 	}
 }
 
+func TestConfiguredHorizons(t *testing.T) {
+	t.Cleanup(func() { SetValidHorizons(DefaultHorizons) })
+
+	horizons, err := ParseHorizons("45, 7,15,30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetValidHorizons(horizons)
+
+	if err := ValidateHorizon(7); err != nil {
+		t.Errorf("expected horizon 7 to be valid, got %s", err.Message)
+	}
+	err60 := ValidateHorizon(60)
+	if err60 == nil {
+		t.Fatal("expected horizon 60 to be invalid")
+	}
+	if want := "horizon must be 7, 15, 30, or 45"; err60.Message != want {
+		t.Errorf("expected %q, got %q", want, err60.Message)
+	}
+
+	for _, val := range []string{"7,abc", "0", "400", "15,"} {
+		if _, err := ParseHorizons(val); err == nil {
+			t.Errorf("expected an error for horizons %q", val)
+		}
+	}
+}
+
 func TestValidateFeatures(t *testing.T) {
 	testCases := []struct {
 		name        string
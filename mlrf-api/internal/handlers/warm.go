@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/config"
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultWarmDate is the dashboard's default forecast date.
+const DefaultWarmDate = "2017-08-01"
+
+// warmInterval is the spacing in days of the forecast dates the dashboard requests for
+// a horizon, starting at the forecast date.
+const warmInterval = 7
+
+// warmTimeout bounds a background warming run.
+const warmTimeout = 5 * time.Minute
+
+// CacheWarmConfig selects the series whose dashboard forecasts are cached after
+// startup and every model or feature reload.
+type CacheWarmConfig struct {
+	Series   []features.Series // Always warmed
+	TopK     int               // Also warm the K series with the highest 90-day sales
+	Horizons []int             // Horizons warmed; every valid horizon if empty
+	Date     string            // Forecast date, YYYY-MM-DD
+}
+
+// Enabled reports whether any series is warmed.
+func (c CacheWarmConfig) Enabled() bool {
+	return len(c.Series) > 0 || c.TopK > 0
+}
+
+// ParseCacheWarmConfig builds the warming configuration from the warm_* settings of
+// c. Warming is disabled unless a series list or top-K is set. An invalid series list
+// or horizon list is an error.
+func ParseCacheWarmConfig(c config.CacheConfig) (CacheWarmConfig, error) {
+	cfg := CacheWarmConfig{TopK: c.WarmTopK, Date: DefaultWarmDate}
+	if c.WarmSeries != "" {
+		series, err := ParseWarmSeries(c.WarmSeries)
+		if err != nil {
+			return CacheWarmConfig{}, fmt.Errorf("CACHE_WARM_SERIES: %w", err)
+		}
+		cfg.Series = series
+	}
+	if c.WarmHorizons != "" {
+		horizons, err := ParseHorizons(c.WarmHorizons)
+		if err != nil {
+			return CacheWarmConfig{}, fmt.Errorf("CACHE_WARM_HORIZONS: %w", err)
+		}
+		cfg.Horizons = horizons
+	}
+	if c.WarmDate != "" && ValidateDate(c.WarmDate) == nil {
+		cfg.Date = c.WarmDate
+	}
+	return cfg, nil
+}
+
+// ParseWarmSeries parses a comma-separated list of store:family series, such as
+// "1:GROCERY I,44:BEVERAGES".
+func ParseWarmSeries(s string) ([]features.Series, error) {
+	var series []features.Series
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		store, family, ok := strings.Cut(field, ":")
+		storeNbr, err := strconv.Atoi(strings.TrimSpace(store))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid series %q: must be store:family", field)
+		}
+		family = strings.TrimSpace(family)
+		if verr := ValidateFamily(family); verr != nil {
+			return nil, fmt.Errorf("invalid series %q: %s", field, verr.Message)
+		}
+		series = append(series, features.Series{StoreNbr: storeNbr, Family: family})
+	}
+	return series, nil
+}
+
+// SetCacheWarm sets the series warmed into the prediction cache after every reload
+// and warms them now, in the background. Call it at startup once the cache namespace
+// is set.
+func (h *Handlers) SetCacheWarm(cfg CacheWarmConfig) {
+	h.warmMu.Lock()
+	h.warm = cfg
+	h.warmMu.Unlock()
+	h.startCacheWarm()
+}
+
+// startCacheWarm warms the configured series in the background, cancelling a run
+// still warming for the previous model or features. It is called after startup and
+// every model or feature reload, once the cache namespace is rotated.
+func (h *Handlers) startCacheWarm() {
+	h.warmMu.Lock()
+	defer h.warmMu.Unlock()
+	if !h.warm.Enabled() || h.cache == nil || h.onnx == nil {
+		return
+	}
+	if h.warmCancel != nil {
+		h.warmCancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+	h.warmCancel = cancel
+	cfg := h.warm
+
+	go func() {
+		defer cancel()
+		start := time.Now()
+		warmed, err := h.WarmHotSeries(ctx, cfg)
+		if err != nil {
+			log.Warn().Err(err).Int("warmed", warmed).Msg("Prediction cache warming failed")
+			return
+		}
+		log.Info().
+			Int("warmed", warmed).
+			Str("date", cfg.Date).
+			Dur("duration", time.Since(start)).
+			Msg("Prediction cache warmed for hot series")
+	}()
+}
+
+// WarmHotSeries caches the forecasts the dashboard requests for the configured series
+// and the top-K series by volume: /predict/simple from the forecast date at weekly
+// intervals over each horizon. Dates without features for the series are skipped.
+// Returns the number of entries cached.
+func (h *Handlers) WarmHotSeries(ctx context.Context, cfg CacheWarmConfig) (int, error) {
+	if h.cache == nil {
+		return 0, nil
+	}
+	results, err := h.hotSeriesPredictions(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	if err := h.cache.SetPredictions(ctx, cache.EndpointSimple, results); err != nil {
+		return 0, err
+	}
+	return len(results), nil
+}
+
+// hotSeriesPredictions scores the series and dates warmed by WarmHotSeries, keyed by
+// /predict/simple cache key.
+func (h *Handlers) hotSeriesPredictions(ctx context.Context, cfg CacheWarmConfig) (map[string]*cache.PredictionResult, error) {
+	if h.onnx == nil || h.featureStore == nil || !h.featureStore.IsLoaded() {
+		return nil, nil
+	}
+	start, err := time.Parse(DateFormat, cfg.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid warm date %q", cfg.Date)
+	}
+
+	var horizons []int
+	for _, horizon := range cfg.Horizons {
+		if ValidHorizons[horizon] {
+			horizons = append(horizons, horizon)
+		}
+	}
+	if len(cfg.Horizons) == 0 {
+		horizons = Horizons()
+	}
+	if len(horizons) == 0 {
+		return nil, nil
+	}
+	// The dashboard requests ceil(horizon / 7) weekly dates per horizon
+	weeks := func(horizon int) int { return (horizon + warmInterval - 1) / warmInterval }
+	maxWeeks := 0
+	for _, horizon := range horizons {
+		maxWeeks = max(maxWeeks, weeks(horizon))
+	}
+
+	var keys []features.FeatureKey
+	var keyWeeks []int // Week of each key after the forecast date
+	for _, s := range h.warmSeries(cfg) {
+		for week := 0; week < maxWeeks; week++ {
+			date := start.AddDate(0, 0, week*warmInterval).Format(DateFormat)
+			keyWeeks = append(keyWeeks, week)
+			keys = append(keys, features.FeatureKey{StoreNbr: s.StoreNbr, Family: s.Family, Date: date})
+		}
+	}
+
+	var batch [][]float32
+	var families []string
+	var scored []int
+	var sources []features.FeatureSource
+	for i, r := range h.lookupFeaturesBatch(ctx, keys) {
+		if r.Source == features.SourceZeros {
+			continue
+		}
+		batch = append(batch, r.Features)
+		families = append(families, keys[i].Family)
+		scored = append(scored, i)
+		sources = append(sources, r.Source)
+	}
+
+	results := make(map[string]*cache.PredictionResult)
+	for start := 0; start < len(batch); start += warmChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+warmChunkSize, len(batch))
+		predictions, err := inference.PredictBatch(inference.WithMetricLabels(ctx, "", families[start:end]...), h.onnx, batch[start:end])
+		if err != nil {
+			return nil, err
+		}
+		guards, err := h.guardBatch(predictions)
+		if err != nil && !errors.Is(err, inference.ErrInvalidPrediction) {
+			return nil, err
+		}
+		for j, prediction := range predictions {
+			if guards[j] == inference.GuardRejected {
+				continue
+			}
+			i := scored[start+j]
+			key := keys[i]
+			for _, horizon := range horizons {
+				if keyWeeks[i] >= weeks(horizon) {
+					continue
+				}
+				results[cache.GenerateCacheKey(key.StoreNbr, key.Family, key.Date, horizon)] = &cache.PredictionResult{
+					StoreNbr:      key.StoreNbr,
+					Family:        key.Family,
+					Date:          key.Date,
+					Horizon:       horizon,
+					Prediction:    prediction,
+					FeatureSource: string(sources[start+j]),
+					Guard:         guards[j],
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// warmSeries returns the configured series followed by the top-K series by volume,
+// without duplicates.
+func (h *Handlers) warmSeries(cfg CacheWarmConfig) []features.Series {
+	seen := make(map[features.Series]bool)
+	var out []features.Series
+	for _, s := range append(append([]features.Series(nil), cfg.Series...), h.topSeriesByVolume(cfg.TopK)...) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// topSeriesByVolume returns the k series with the highest 90-day mean sales at their
+// latest date, highest first.
+func (h *Handlers) topSeriesByVolume(k int) []features.Series {
+	if k <= 0 {
+		return nil
+	}
+	col, ok := schema.Features.Index("sales_rolling_mean_90")
+	if !ok {
+		return nil
+	}
+	type ranked struct {
+		series features.Series
+		volume float32
+	}
+	var all []ranked
+	for _, s := range h.featureStore.Series() {
+		last, ok := h.featureStore.LastDate(s.StoreNbr, s.Family)
+		if !ok {
+			continue
+		}
+		if vec, ok := h.featureStore.Lookup(s.StoreNbr, s.Family, last); ok {
+			all = append(all, ranked{series: s, volume: vec[col]})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].volume > all[j].volume })
+
+	out := make([]features.Series, 0, min(k, len(all)))
+	for _, r := range all[:min(k, len(all))] {
+		out = append(out, r.series)
+	}
+	return out
+}
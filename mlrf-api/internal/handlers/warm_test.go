@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/cache"
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+func TestParseWarmSeries(t *testing.T) {
+	series, err := ParseWarmSeries("1:GROCERY I, 44:BEVERAGES")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []features.Series{{StoreNbr: 1, Family: "GROCERY I"}, {StoreNbr: 44, Family: "BEVERAGES"}}
+	if len(series) != len(want) || series[0] != want[0] || series[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, series)
+	}
+
+	for _, invalid := range []string{"GROCERY I", "one:GROCERY I", "1:NOPE", "1:GROCERY I,"} {
+		if _, err := ParseWarmSeries(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestHotSeriesPredictions(t *testing.T) {
+	date := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "GROCERY I", Date: date, SalesLag1: 10, SalesRolMean90: 10},
+		{StoreNbr: 2, Family: "BEVERAGES", Date: date, SalesLag1: 50, SalesRolMean90: 500},
+		{StoreNbr: 3, Family: "DAIRY", Date: date, SalesLag1: 30, SalesRolMean90: 300},
+	})
+	h := NewHandlers(lagInferencer{}, nil, store, nil)
+
+	if top := h.topSeriesByVolume(2); len(top) != 2 || top[0].StoreNbr != 2 || top[1].StoreNbr != 3 {
+		t.Errorf("expected stores 2 and 3 by volume, got %v", top)
+	}
+
+	// Store 9 has no features and is skipped; store 2 is listed and in the top K once
+	cfg := CacheWarmConfig{
+		Series:   []features.Series{{StoreNbr: 9, Family: "GROCERY I"}, {StoreNbr: 2, Family: "BEVERAGES"}},
+		TopK:     1,
+		Horizons: []int{15, 30},
+		Date:     "2017-08-01",
+	}
+	results, err := h.hotSeriesPredictions(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The dashboard requests 3 weekly dates for 15 days and 5 for 30 days
+	if len(results) != 3+5 {
+		t.Errorf("expected 8 cached predictions, got %d", len(results))
+	}
+	first, ok := results[cache.GenerateCacheKey(2, "BEVERAGES", "2017-08-01", 15)]
+	if !ok || first.Prediction != 50 || first.FeatureSource != string(features.SourceExact) {
+		t.Errorf("unexpected prediction for the forecast date %+v", first)
+	}
+	if _, ok := results[cache.GenerateCacheKey(2, "BEVERAGES", "2017-08-29", 30)]; !ok {
+		t.Error("expected the fifth week of the 30-day horizon to be warmed")
+	}
+	if _, ok := results[cache.GenerateCacheKey(2, "BEVERAGES", "2017-08-29", 15)]; ok {
+		t.Error("expected no fifth week for the 15-day horizon")
+	}
+	for _, r := range results {
+		if r.StoreNbr != 2 {
+			t.Errorf("unexpected series warmed %+v", r)
+		}
+	}
+
+	if !cfg.Enabled() || (CacheWarmConfig{Date: DefaultWarmDate}).Enabled() {
+		t.Error("expected warming enabled only with series or top-K")
+	}
+}
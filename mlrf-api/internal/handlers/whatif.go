@@ -1,78 +1,220 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/inference"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/middleware"
+	"github.com/mlrf/mlrf-api/internal/schema"
 	"github.com/rs/zerolog/log"
 )
 
+// What-if modes, set with the request's mode.
+const (
+	WhatIfModeDate    = "date"    // Adjust the prediction of a single date (default)
+	WhatIfModeHorizon = "horizon" // Adjust every day of the forecast window from date
+)
+
 // WhatIfRequest represents a request to explore parameter sensitivity.
 // It takes a base prediction context and adjustments to apply.
 type WhatIfRequest struct {
 	StoreNbr    int                `json:"store_nbr"`
 	Family      string             `json:"family"`
-	Date        string             `json:"date"`
+	Date        string             `json:"date"` // Start of the window in horizon mode
 	Horizon     int                `json:"horizon"`
-	Adjustments map[string]float32 `json:"adjustments"` // Feature adjustments (e.g., "oil_price": 1.2)
+	Mode        string             `json:"mode,omitempty"`
+	Adjustments map[string]float32 `json:"adjustments"`      // Feature adjustments (e.g., "oil_price": 1.2)
+	Strict      bool               `json:"strict,omitempty"` // Reject unknown features with INVALID_ADJUSTMENT instead of skipping them
+}
+
+// What-if warning reasons.
+const (
+	WhatIfWarningUnknown = "unknown_feature" // Skipped: neither a schema column nor an alias
+	WhatIfWarningClamped = "clamped"         // A calendar value outside its range was clamped
+	WhatIfWarningRounded = "rounded"         // A flag was set to the nearer of 0 and 1
+)
+
+// WhatIfWarning reports an adjustment that was skipped or not applied as requested.
+type WhatIfWarning struct {
+	Adjustment string   `json:"adjustment"`
+	Reason     string   `json:"reason"`
+	Requested  float32  `json:"requested"`
+	Applied    *float32 `json:"applied,omitempty"` // Value the feature was set to, unless skipped
+	Message    string   `json:"message"`
 }
 
-// WhatIfResponse contains the baseline and adjusted predictions with delta.
+// WhatIfPoint is one day of a horizon mode scenario.
+type WhatIfPoint struct {
+	Date            string  `json:"date"`
+	Baseline        float32 `json:"baseline"`
+	Adjusted        float32 `json:"adjusted"`
+	Delta           float32 `json:"delta"`
+	CumulativeDelta float32 `json:"cumulative_delta"` // Revenue impact from the start of the window through this day
+}
+
+// WhatIfResponse contains the baseline and adjusted predictions with delta. In horizon
+// mode they are totals over the window, and Series has the daily values.
 type WhatIfResponse struct {
+	Mode      string             `json:"mode"`
 	Original  float32            `json:"original"`
 	Adjusted  float32            `json:"adjusted"`
 	Delta     float32            `json:"delta"`
 	DeltaPct  float32            `json:"delta_pct"`
+	Series    []WhatIfPoint      `json:"series,omitempty"`
 	LatencyMs float64            `json:"latency_ms"`
 	Applied   map[string]float32 `json:"applied"` // Adjustments that were applied
+	Warnings  []WhatIfWarning    `json:"warnings,omitempty"`
+}
+
+// whatIfAliases maps adjustment names accepted by /whatif before it used the shared
+// feature schema to the schema columns they adjust. Schema column names are accepted
+// as they are.
+var whatIfAliases = map[string]string{
+	"day_of_week":     "dayofweek",
+	"day_of_month":    "day",
+	"day_of_year":     "dayofyear",
+	"rolling_mean_7":  "sales_rolling_mean_7",
+	"rolling_mean_28": "sales_rolling_mean_28",
+	"rolling_std_7":   "sales_rolling_std_7",
+	"rolling_std_28":  "sales_rolling_std_28",
+}
+
+// whatIfBounds clamps adjustments of calendar features, which are set directly.
+var whatIfBounds = map[string][2]float32{
+	"dayofweek": {0, 6},
+	"day":       {1, 31},
+	"month":     {1, 12},
+	"dayofyear": {1, 366},
+}
+
+// whatIfFeatureIndex resolves an adjustment name to its schema column and position
+// in the feature vector.
+func whatIfFeatureIndex(name string) (schema.Column, bool) {
+	if alias, ok := whatIfAliases[name]; ok {
+		name = alias
+	}
+	i, ok := schema.Features.Index(name)
+	if !ok {
+		return schema.Column{}, false
+	}
+	return schema.Features.Column(i), true
+}
+
+// adjustFeature returns the adjusted value of a feature, and a warning reason when it
+// differs from the adjustment asked for. Flags (and onpromotion) are set to 0 or 1,
+// calendar fields are set and clamped, and continuous features are multiplied: an
+// adjustment of 1.0 is no change, 1.2 a 20% increase.
+func adjustFeature(col schema.Column, base, adjustment float32) (float32, string) {
+	if col.Name == "onpromotion" || col.Max == 1 {
+		value := float32(0)
+		if adjustment > 0.5 {
+			value = 1
+		}
+		if value != adjustment {
+			return value, WhatIfWarningRounded
+		}
+		return value, ""
+	}
+	if bounds, ok := whatIfBounds[col.Name]; ok {
+		value := min(max(adjustment, bounds[0]), bounds[1])
+		if value != adjustment {
+			return value, WhatIfWarningClamped
+		}
+		return value, ""
+	}
+	return base * adjustment, ""
+}
+
+// applyAdjustments returns a copy of base with the adjustments applied, the
+// adjustments that were, and warnings for those skipped (unknown feature names) or
+// applied differently than asked for, in name order.
+func applyAdjustments(base []float32, adjustments map[string]float32) ([]float32, map[string]float32, []WhatIfWarning) {
+	adjusted := make([]float32, len(base))
+	copy(adjusted, base)
+	applied := make(map[string]float32)
+	var warnings []WhatIfWarning
+
+	names := make([]string, 0, len(adjustments))
+	for name := range adjustments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		adjustment := adjustments[name]
+		col, exists := whatIfFeatureIndex(name)
+		if !exists || col.Index >= len(adjusted) {
+			log.Debug().Str("feature", name).Msg("Skipping unknown what-if feature")
+			warnings = append(warnings, WhatIfWarning{
+				Adjustment: name,
+				Reason:     WhatIfWarningUnknown,
+				Requested:  adjustment,
+				Message:    fmt.Sprintf("unknown feature %q skipped, see /features/schema", name),
+			})
+			continue
+		}
+		value, reason := adjustFeature(col, base[col.Index], adjustment)
+		adjusted[col.Index] = value
+		applied[name] = adjustment
+		if reason != "" {
+			warnings = append(warnings, adjustmentWarning(name, reason, adjustment, value))
+		}
+	}
+	return adjusted, applied, warnings
+}
+
+// adjustmentWarning describes an adjustment adjustFeature applied as value instead.
+func adjustmentWarning(name, reason string, requested, value float32) WhatIfWarning {
+	message := fmt.Sprintf("%s %v rounded to %v", name, requested, value)
+	if reason == WhatIfWarningClamped {
+		message = fmt.Sprintf("%s %v clamped to %v", name, requested, value)
+	}
+	return WhatIfWarning{Adjustment: name, Reason: reason, Requested: requested, Applied: &value, Message: message}
 }
 
-// Feature indices for what-if adjustments.
-// These correspond to positions in the 27-feature vector.
-var whatIfFeatureIndex = map[string]int{
-	"oil_price":       0,  // dcoilwtico
-	"onpromotion":     1,  // Binary promotion flag
-	"day_of_week":     2,  // Day of week (0-6)
-	"day_of_month":    3,  // Day of month (1-31)
-	"month":           4,  // Month (1-12)
-	"year":            5,  // Year
-	"is_payday":       6,  // Is payday (binary)
-	"is_weekend":      7,  // Is weekend (binary)
-	"transactions":    8,  // Number of transactions
-	"sales_lag_7":     9,  // Sales lag 7 days
-	"sales_lag_14":    10, // Sales lag 14 days
-	"sales_lag_28":    11, // Sales lag 28 days
-	"sales_lag_90":    12, // Sales lag 90 days
-	"rolling_mean_7":       13, // 7-day rolling mean
-	"rolling_mean_28":      14, // 28-day rolling mean
-	"rolling_std_7":        15, // 7-day rolling std
-	"rolling_std_28":       16, // 28-day rolling std
-	"day_of_year":          17, // Day of year (1-366)
-	"is_mid_month":         18, // Is mid-month (binary)
-	"is_leap_year":         19, // Is leap year (binary)
-	"sales_rolling_mean_14": 20, // 14-day rolling mean
-	"sales_rolling_mean_90": 21, // 90-day rolling mean
-	"sales_rolling_std_14":  22, // 14-day rolling std
-	"sales_rolling_std_90":  23, // 90-day rolling std
-	"cluster":              24, // Store cluster
-	"family_encoded":       25, // Encoded product family
-	"type_encoded":         26, // Encoded store type
+// unknownAdjustments returns the adjustment names that are neither schema columns
+// nor aliases, sorted.
+func unknownAdjustments(adjustments map[string]float32) []string {
+	var unknown []string
+	for name := range adjustments {
+		if _, ok := whatIfFeatureIndex(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// deltaPercent returns the change from base to adjusted in percent, 0 for a zero base.
+func deltaPercent(base, adjusted float32) float32 {
+	if base == 0 {
+		return 0
+	}
+	return (adjusted - base) / base * 100
 }
 
 // WhatIf handles what-if analysis requests.
-// It computes baseline and adjusted predictions to show feature sensitivity.
+// It computes baseline and adjusted predictions to show feature sensitivity, for one
+// date or, in horizon mode, over a forecast window (see whatIfHorizon).
 func (h *Handlers) WhatIf(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	var req WhatIfRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteBadRequest(w, r, "invalid request body", CodeInvalidRequest)
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate request
-	if err := ValidateStoreNbr(req.StoreNbr); err != nil {
+	if err := h.validateStoreNbr(req.StoreNbr); err != nil {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
@@ -88,6 +230,17 @@ func (h *Handlers) WhatIf(w http.ResponseWriter, r *http.Request) {
 		WriteBadRequest(w, r, err.Message, err.Code)
 		return
 	}
+	if req.Mode == "" {
+		req.Mode = WhatIfModeDate
+	}
+	if req.Mode != WhatIfModeDate && req.Mode != WhatIfModeHorizon {
+		WriteBadRequest(w, r, "mode must be date or horizon", CodeInvalidRequest)
+		return
+	}
+	if unknown := unknownAdjustments(req.Adjustments); req.Strict && len(unknown) > 0 {
+		WriteBadRequest(w, r, fmt.Sprintf("unknown adjustment features: %s, see /features/schema", strings.Join(unknown, ", ")), CodeInvalidAdjustment)
+		return
+	}
 
 	// Check ONNX availability
 	if h.onnx == nil {
@@ -95,96 +248,148 @@ func (h *Handlers) WhatIf(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get baseline features
-	var baseFeatures []float32
-	if h.featureStore != nil && h.featureStore.IsLoaded() {
-		baseFeatures, _ = h.featureStore.GetFeatures(req.StoreNbr, req.Family, req.Date)
-	} else {
-		baseFeatures = make([]float32, 27)
-		log.Debug().Msg("Feature store unavailable for what-if, using zero features")
+	if req.Mode == WhatIfModeHorizon {
+		h.whatIfHorizon(w, r, req, start)
+		return
 	}
 
+	// Get baseline features
+	baseFeatures := h.whatIfFeatures(req.StoreNbr, req.Family, req.Date)
+
 	// Compute baseline prediction
-	basePrediction, err := h.onnx.Predict(baseFeatures)
+	predict := h.predictor(r.Context(), req.Family)
+	basePrediction, err := predict(baseFeatures)
 	if err != nil {
-		log.Error().Err(err).Msg("baseline inference failed")
-		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		writeScenarioError(w, r, err)
 		return
 	}
 
 	// Apply adjustments to create modified features
-	adjustedFeatures := make([]float32, len(baseFeatures))
-	copy(adjustedFeatures, baseFeatures)
-	appliedAdjustments := make(map[string]float32)
-
-	for name, adjustment := range req.Adjustments {
-		idx, exists := whatIfFeatureIndex[name]
-		if !exists {
-			// Skip unknown features, but don't error
-			log.Debug().Str("feature", name).Msg("Skipping unknown what-if feature")
-			continue
-		}
-		if idx < len(adjustedFeatures) {
-			// For multipliers (like oil_price), multiply the value
-			// For binary flags (like onpromotion), set directly
-			switch name {
-			case "onpromotion", "is_payday", "is_weekend":
-				// Binary: set to 0 or 1
-				if adjustment > 0.5 {
-					adjustedFeatures[idx] = 1.0
-				} else {
-					adjustedFeatures[idx] = 0.0
-				}
-			case "day_of_week":
-				// Bounded: 0-6
-				if adjustment < 0 {
-					adjustedFeatures[idx] = 0
-				} else if adjustment > 6 {
-					adjustedFeatures[idx] = 6
-				} else {
-					adjustedFeatures[idx] = adjustment
-				}
-			case "month":
-				// Bounded: 1-12
-				if adjustment < 1 {
-					adjustedFeatures[idx] = 1
-				} else if adjustment > 12 {
-					adjustedFeatures[idx] = 12
-				} else {
-					adjustedFeatures[idx] = adjustment
-				}
-			default:
-				// For continuous features like oil_price, apply as multiplier
-				// adjustment of 1.0 = no change, 1.2 = 20% increase
-				adjustedFeatures[idx] = baseFeatures[idx] * adjustment
-			}
-			appliedAdjustments[name] = adjustment
-		}
-	}
+	adjustedFeatures, appliedAdjustments, warnings := applyAdjustments(baseFeatures, req.Adjustments)
 
 	// Compute adjusted prediction
-	adjustedPrediction, err := h.onnx.Predict(adjustedFeatures)
+	adjustedPrediction, err := predict(adjustedFeatures)
 	if err != nil {
-		log.Error().Err(err).Msg("adjusted inference failed")
-		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+		writeScenarioError(w, r, err)
 		return
 	}
 
-	// Calculate delta
-	delta := adjustedPrediction - basePrediction
-	var deltaPct float32
-	if basePrediction != 0 {
-		deltaPct = (delta / basePrediction) * 100
-	}
+	middleware.SetModelVersion(r.Context(), h.modelVersion(""))
 
 	resp := WhatIfResponse{
+		Mode:      WhatIfModeDate,
 		Original:  basePrediction,
 		Adjusted:  adjustedPrediction,
-		Delta:     delta,
-		DeltaPct:  deltaPct,
+		Delta:     adjustedPrediction - basePrediction,
+		DeltaPct:  deltaPercent(basePrediction, adjustedPrediction),
 		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
 		Applied:   appliedAdjustments,
+		Warnings:  warnings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// whatIfFeatures returns the baseline features of a what-if series and date: the
+// feature store's, or zeros without a feature store, with the regressor overrides.
+func (h *Handlers) whatIfFeatures(storeNbr int, family, date string) []float32 {
+	if h.featureStore == nil || !h.featureStore.IsLoaded() {
+		log.Debug().Msg("Feature store unavailable for what-if, using zero features")
+		return h.applyRegressorOverrides(make([]float32, RequiredFeatureCount), storeNbr, family, date)
+	}
+	f, source := h.featureStore.ResolveFeatures(storeNbr, family, date)
+	metrics.RecordFeatureStoreLookup(string(source))
+	return h.applyRegressorOverrides(f, storeNbr, family, date)
+}
+
+// scenario is a series rolled forward over a window as is and with adjustments.
+type scenario struct {
+	baseline []float32
+	adjusted []float32
+	applied  map[string]float32
+	warnings []WhatIfWarning
+}
+
+// rollScenario rolls a series forward over days from start twice, as /forecast does:
+// once as is and once with the adjustments applied to every day, so adjusted
+// predictions feed the lags of the days after them. A nil store starts from zero
+// features.
+func (h *Handlers) rollScenario(ctx context.Context, store *features.Store, storeNbr int, family string, start time.Time, days int, adjustments map[string]float32) (scenario, error) {
+	builder := h.featureBuilder(store)
+	predict := h.predictor(ctx, family)
+	_, baseline, err := builder.Series(storeNbr, family, start, days, predict)
+	if err != nil {
+		return scenario{}, err
+	}
+
+	// Every day is adjusted alike, so the last day's applied adjustments and warnings
+	// stand for the window
+	s := scenario{baseline: baseline}
+	adjust := func(f []float32) []float32 {
+		var adjusted []float32
+		adjusted, s.applied, s.warnings = applyAdjustments(f, adjustments)
+		return adjusted
+	}
+	if _, s.adjusted, err = builder.WithAdjustment(adjust).Series(storeNbr, family, start, days, predict); err != nil {
+		return scenario{}, err
+	}
+	recordForecastLookups(store, storeNbr, family, start, days)
+	return s, nil
+}
+
+// writeScenarioError writes the response for a rollScenario error.
+func writeScenarioError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, features.ErrRollForwardLimit):
+		WriteBadRequest(w, r, err.Error(), CodeInvalidDate)
+	case errors.Is(err, inference.ErrInvalidPrediction):
+		writeInvalidPrediction(w, r, err)
+	default:
+		log.Error().Err(err).Msg("scenario inference failed")
+		WriteInternalError(w, r, "inference failed", CodeInferenceFailed)
+	}
+}
+
+// whatIfHorizon answers a horizon mode what-if request over the window from the
+// request's date (see rollScenario).
+func (h *Handlers) whatIfHorizon(w http.ResponseWriter, r *http.Request, req WhatIfRequest, start time.Time) {
+	startDate, _ := time.Parse(DateFormat, req.Date)
+
+	var store *features.Store
+	if h.featureStore != nil && h.featureStore.IsLoaded() {
+		store = h.featureStore
+	} else {
+		log.Debug().Msg("Feature store unavailable for what-if, rolling forward from zero features")
+	}
+
+	sc, err := h.rollScenario(r.Context(), store, req.StoreNbr, req.Family, startDate, req.Horizon, req.Adjustments)
+	if err != nil {
+		writeScenarioError(w, r, err)
+		return
+	}
+	middleware.SetModelVersion(r.Context(), h.modelVersion(""))
+
+	resp := WhatIfResponse{
+		Mode:     WhatIfModeHorizon,
+		Series:   make([]WhatIfPoint, len(sc.baseline)),
+		Applied:  sc.applied,
+		Warnings: sc.warnings,
+	}
+	for i := range sc.baseline {
+		resp.Original += sc.baseline[i]
+		resp.Adjusted += sc.adjusted[i]
+		resp.Series[i] = WhatIfPoint{
+			Date:            startDate.AddDate(0, 0, i).Format(DateFormat),
+			Baseline:        sc.baseline[i],
+			Adjusted:        sc.adjusted[i],
+			Delta:           sc.adjusted[i] - sc.baseline[i],
+			CumulativeDelta: resp.Adjusted - resp.Original,
+		}
 	}
+	resp.Delta = resp.Adjusted - resp.Original
+	resp.DeltaPct = deltaPercent(resp.Original, resp.Adjusted)
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
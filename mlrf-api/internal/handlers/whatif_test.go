@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/schema"
+)
+
+// columnInferencer predicts the value of one feature column, so tests can see which
+// position of the vector an adjustment changed.
+type columnInferencer struct {
+	index int
+}
+
+func (c columnInferencer) Predict(f []float32) (float32, error) {
+	return f[c.index], nil
+}
+
+func (c columnInferencer) PredictBatch(batch [][]float32) ([]float32, error) {
+	out := make([]float32, len(batch))
+	for i, f := range batch {
+		out[i] = f[c.index]
+	}
+	return out, nil
+}
+
+func TestWhatIfAdjustsSchemaColumns(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, Year: 2017, DayOfWeek: 1, OilPrice: 40},
+	})
+
+	whatIf := func(column string, adjustments map[string]float32) WhatIfResponse {
+		t.Helper()
+		i, ok := schema.Features.Index(column)
+		if !ok {
+			t.Fatalf("unknown column %s", column)
+		}
+		h := NewHandlers(columnInferencer{index: i}, nil, store, nil)
+		body, _ := json.Marshal(WhatIfRequest{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-15", Horizon: 15, Adjustments: adjustments})
+		w := httptest.NewRecorder()
+		h.WhatIf(w, httptest.NewRequest(http.MethodPost, "/whatif", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp WhatIfResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp
+	}
+
+	// oil_price is a multiplier on the oil price column, not the year
+	resp := whatIf("oil_price", map[string]float32{"oil_price": 1.5})
+	if resp.Original != 40 || resp.Adjusted != 60 {
+		t.Errorf("expected oil price 40 -> 60, got %v -> %v", resp.Original, resp.Adjusted)
+	}
+	if resp := whatIf("year", map[string]float32{"oil_price": 1.5}); resp.Adjusted != 2017 {
+		t.Errorf("expected the year untouched, got %v", resp.Adjusted)
+	}
+
+	// Legacy names resolve to their schema column; calendar fields are clamped
+	resp = whatIf("dayofweek", map[string]float32{"day_of_week": 9})
+	if resp.Original != 1 || resp.Adjusted != 6 {
+		t.Errorf("expected day of week 1 -> 6, got %v -> %v", resp.Original, resp.Adjusted)
+	}
+
+	// Unknown names are skipped
+	resp = whatIf("oil_price", map[string]float32{"transactions": 2, "onpromotion": 1})
+	if _, ok := resp.Applied["transactions"]; ok || resp.Applied["onpromotion"] != 1 {
+		t.Errorf("unexpected applied adjustments %v", resp.Applied)
+	}
+}
+
+func TestWhatIfWarnings(t *testing.T) {
+	h := NewHandlers(&MockInferencer{prediction: 10}, nil, nil, nil)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.WhatIf(w, httptest.NewRequest(http.MethodPost, "/whatif", bytes.NewReader([]byte(body))))
+		return w
+	}
+
+	w := post(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"adjustments":{"transactions":2,"day_of_week":9,"onpromotion":0.7,"oil_price":1.1}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp WhatIfResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	// In name order; oil_price is applied as asked
+	want := []struct {
+		adjustment, reason string
+		applied            float32
+	}{
+		{"day_of_week", WhatIfWarningClamped, 6},
+		{"onpromotion", WhatIfWarningRounded, 1},
+		{"transactions", WhatIfWarningUnknown, 0},
+	}
+	if len(resp.Warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %+v", len(want), resp.Warnings)
+	}
+	for i, w := range want {
+		got := resp.Warnings[i]
+		if got.Adjustment != w.adjustment || got.Reason != w.reason || got.Message == "" {
+			t.Errorf("warning %d: expected %s %s, got %+v", i, w.adjustment, w.reason, got)
+		}
+		if (got.Applied == nil) != (w.reason == WhatIfWarningUnknown) || (got.Applied != nil && *got.Applied != w.applied) {
+			t.Errorf("warning %d: expected applied %v, got %v", i, w.applied, got.Applied)
+		}
+	}
+
+	// Strict mode rejects unknown features, but not clamped values
+	w = post(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"strict":true,"adjustments":{"transactions":2,"oil_price":1.1}}`)
+	var errResp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || errResp.Code != CodeInvalidAdjustment || !strings.Contains(errResp.Error, "transactions") {
+		t.Errorf("expected 400 %s naming transactions, got %d %+v", CodeInvalidAdjustment, w.Code, errResp)
+	}
+	if w := post(`{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"strict":true,"adjustments":{"day_of_week":9}}`); w.Code != http.StatusOK {
+		t.Errorf("expected strict mode to accept a clamped value, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWhatIfHorizon(t *testing.T) {
+	date := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	store := newTestFeatureStore(t, []features.FeatureRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: date, Year: 2017, DayOfWeek: 1, OilPrice: 40},
+	})
+	i, _ := schema.Features.Index("oil_price")
+	h := NewHandlers(columnInferencer{index: i}, nil, store, nil)
+
+	body := `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"mode":"horizon","adjustments":{"oil_price":1.5,"transactions":2}}`
+	w := httptest.NewRecorder()
+	h.WhatIf(w, httptest.NewRequest(http.MethodPost, "/whatif", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp WhatIfResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.Mode != WhatIfModeHorizon || len(resp.Series) != 15 {
+		t.Fatalf("expected a 15-day horizon series, got %+v", resp)
+	}
+	// Every day of the window is adjusted, the known first day and the rolled ones
+	for i, p := range resp.Series {
+		if p.Baseline != 40 || p.Adjusted != 60 || p.Delta != 20 || p.CumulativeDelta != float32(20*(i+1)) {
+			t.Errorf("day %d: unexpected point %+v", i, p)
+		}
+	}
+	if resp.Series[0].Date != "2017-08-15" || resp.Series[14].Date != "2017-08-29" {
+		t.Errorf("unexpected window %s to %s", resp.Series[0].Date, resp.Series[14].Date)
+	}
+	if resp.Original != 600 || resp.Adjusted != 900 || resp.Delta != 300 || resp.DeltaPct != 50 {
+		t.Errorf("unexpected totals %v -> %v (%v, %v%%)", resp.Original, resp.Adjusted, resp.Delta, resp.DeltaPct)
+	}
+	if _, ok := resp.Applied["transactions"]; ok || resp.Applied["oil_price"] != 1.5 {
+		t.Errorf("unexpected applied adjustments %v", resp.Applied)
+	}
+
+	body = `{"store_nbr":1,"family":"DAIRY","date":"2017-08-15","horizon":15,"mode":"window"}`
+	w = httptest.NewRecorder()
+	h.WhatIf(w, httptest.NewRequest(http.MethodPost, "/whatif", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown mode, got %d", w.Code)
+	}
+}
+
+func TestWhatIfFeatureIndex(t *testing.T) {
+	for alias, column := range whatIfAliases {
+		col, ok := whatIfFeatureIndex(alias)
+		if !ok || col.Name != column {
+			t.Errorf("%s: expected column %s, got %+v", alias, column, col)
+		}
+	}
+	for i, name := range schema.Features.Names() {
+		if col, ok := whatIfFeatureIndex(name); !ok || col.Index != i {
+			t.Errorf("%s: expected index %d, got %+v", name, i, col)
+		}
+	}
+}
+
+func TestFeatureSchema(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+	w := httptest.NewRecorder()
+	h.FeatureSchema(w, httptest.NewRequest(http.MethodGet, "/features/schema", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp FeatureSchemaResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.NumFeatures != RequiredFeatureCount || len(resp.Features) != RequiredFeatureCount {
+		t.Fatalf("expected %d features, got %d / %d", RequiredFeatureCount, resp.NumFeatures, len(resp.Features))
+	}
+	for i, c := range resp.Features {
+		if c.Index != i {
+			t.Errorf("%s: expected index %d, got %d", c.Name, i, c.Index)
+		}
+	}
+	if resp.Features[0].Name != "year" || resp.Features[7].Name != "oil_price" {
+		t.Errorf("unexpected order %s, %s", resp.Features[0].Name, resp.Features[7].Name)
+	}
+	if resp.WhatIfAliases["day_of_week"] != "dayofweek" {
+		t.Errorf("expected the day_of_week alias, got %v", resp.WhatIfAliases)
+	}
+}
@@ -0,0 +1,146 @@
+// Package hierarchy loads the forecast hierarchy definition: the aggregation
+// levels (e.g. total → state → city → store → family) and the parent/child
+// mapping between nodes.
+package hierarchy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Node is one node of the hierarchy. The root has no Parent. Bottom-level
+// (family) node IDs follow the "<store_nbr>_<family>" series convention.
+type Node struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Level  string `json:"level"`
+	Parent string `json:"parent,omitempty"`
+}
+
+// Definition is a validated hierarchy.
+type Definition struct {
+	Levels []string `json:"levels"` // Top to bottom
+	Nodes  []Node   `json:"nodes"`
+
+	root     int
+	depth    map[string]int   // level -> position in Levels
+	byID     map[string]int   // node ID -> index in Nodes
+	children map[string][]int // node ID -> child indexes in file order
+}
+
+// Load reads a hierarchy definition JSON file with "levels" and "nodes".
+func Load(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a hierarchy definition.
+func Parse(data []byte) (*Definition, error) {
+	var d Definition
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse hierarchy definition: %w", err)
+	}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// init validates levels and mappings and builds the indexes.
+// Every child must sit at a deeper level than its parent, which rules out cycles.
+func (d *Definition) init() error {
+	if len(d.Levels) == 0 {
+		return errors.New("hierarchy definition has no levels")
+	}
+	if len(d.Nodes) == 0 {
+		return errors.New("hierarchy definition has no nodes")
+	}
+
+	d.depth = make(map[string]int, len(d.Levels))
+	for i, level := range d.Levels {
+		if _, dup := d.depth[level]; dup {
+			return fmt.Errorf("duplicate hierarchy level %q", level)
+		}
+		d.depth[level] = i
+	}
+
+	d.byID = make(map[string]int, len(d.Nodes))
+	d.children = make(map[string][]int)
+	d.root = -1
+	for i, n := range d.Nodes {
+		if n.ID == "" {
+			return fmt.Errorf("hierarchy node %d has no id", i)
+		}
+		if _, dup := d.byID[n.ID]; dup {
+			return fmt.Errorf("duplicate hierarchy node %q", n.ID)
+		}
+		if _, ok := d.depth[n.Level]; !ok {
+			return fmt.Errorf("hierarchy node %q has unknown level %q", n.ID, n.Level)
+		}
+		d.byID[n.ID] = i
+		if n.Parent == "" {
+			if d.root >= 0 {
+				return fmt.Errorf("hierarchy has multiple roots: %q and %q", d.Nodes[d.root].ID, n.ID)
+			}
+			d.root = i
+		}
+	}
+	if d.root < 0 {
+		return errors.New("hierarchy has no root node")
+	}
+	if root := d.Nodes[d.root]; d.depth[root.Level] != 0 {
+		return fmt.Errorf("hierarchy root %q must be at level %q", root.ID, d.Levels[0])
+	}
+
+	for i, n := range d.Nodes {
+		if n.Parent == "" {
+			continue
+		}
+		p, ok := d.byID[n.Parent]
+		if !ok {
+			return fmt.Errorf("hierarchy node %q has unknown parent %q", n.ID, n.Parent)
+		}
+		if d.depth[n.Level] <= d.depth[d.Nodes[p].Level] {
+			return fmt.Errorf("hierarchy node %q (%s) must be below its parent %q (%s)",
+				n.ID, n.Level, n.Parent, d.Nodes[p].Level)
+		}
+		d.children[n.Parent] = append(d.children[n.Parent], i)
+	}
+	return nil
+}
+
+// Root returns the top node.
+func (d *Definition) Root() Node {
+	return d.Nodes[d.root]
+}
+
+// Children returns the children of a node in file order.
+func (d *Definition) Children(id string) []Node {
+	idx := d.children[id]
+	children := make([]Node, len(idx))
+	for i, j := range idx {
+		children[i] = d.Nodes[j]
+	}
+	return children
+}
+
+// Leaves returns the nodes without children in file order.
+func (d *Definition) Leaves() []Node {
+	var leaves []Node
+	for _, n := range d.Nodes {
+		if len(d.children[n.ID]) == 0 {
+			leaves = append(leaves, n)
+		}
+	}
+	return leaves
+}
+
+// Size returns the number of nodes.
+func (d *Definition) Size() int {
+	return len(d.Nodes)
+}
@@ -0,0 +1,73 @@
+package hierarchy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDefinition = `{
+  "levels": ["total", "state", "store", "family"],
+  "nodes": [
+    {"id": "total", "name": "Total", "level": "total"},
+    {"id": "pichincha", "name": "Pichincha", "level": "state", "parent": "total"},
+    {"id": "store_1", "name": "Store 1", "level": "store", "parent": "pichincha"},
+    {"id": "store_2", "name": "Store 2", "level": "store", "parent": "total"},
+    {"id": "1_DAIRY", "name": "DAIRY", "level": "family", "parent": "store_1"},
+    {"id": "1_BEVERAGES", "name": "BEVERAGES", "level": "family", "parent": "store_1"},
+    {"id": "2_DAIRY", "name": "DAIRY", "level": "family", "parent": "store_2"}
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hierarchy.json")
+	if err := os.WriteFile(path, []byte(testDefinition), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if d.Root().ID != "total" || d.Size() != 7 {
+		t.Errorf("unexpected root %q or size %d", d.Root().ID, d.Size())
+	}
+
+	// Levels may be skipped: store_2 hangs directly off the total
+	children := d.Children("total")
+	if len(children) != 2 || children[0].ID != "pichincha" || children[1].ID != "store_2" {
+		t.Errorf("unexpected children of total: %+v", children)
+	}
+
+	leaves := d.Leaves()
+	if len(leaves) != 3 || leaves[0].ID != "1_DAIRY" || leaves[2].ID != "2_DAIRY" {
+		t.Errorf("unexpected leaves: %+v", leaves)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"no levels", `{"nodes": [{"id": "total", "level": "total"}]}`, "no levels"},
+		{"unknown level", `{"levels": ["total"], "nodes": [{"id": "total", "level": "region"}]}`, "unknown level"},
+		{"duplicate node", `{"levels": ["total"], "nodes": [{"id": "a", "level": "total"}, {"id": "a", "level": "total"}]}`, "duplicate"},
+		{"two roots", `{"levels": ["total"], "nodes": [{"id": "a", "level": "total"}, {"id": "b", "level": "total"}]}`, "multiple roots"},
+		{"unknown parent", `{"levels": ["total", "store"], "nodes": [{"id": "t", "level": "total"}, {"id": "s", "level": "store", "parent": "x"}]}`, "unknown parent"},
+		{"child above parent", `{"levels": ["total", "store"], "nodes": [{"id": "t", "level": "total"}, {"id": "s", "level": "store", "parent": "t"}, {"id": "u", "level": "store", "parent": "s"}]}`, "below its parent"},
+		{"root not top", `{"levels": ["total", "store"], "nodes": [{"id": "s", "level": "store"}]}`, "must be at level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
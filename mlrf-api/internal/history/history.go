@@ -0,0 +1,302 @@
+// Package history holds the daily sales history of every (store, family) series,
+// loaded from the preprocessed training data, a database table or a JSON file, and
+// answers date range queries at series, store, family and total level.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// DateFormat is the format of history dates.
+const DateFormat = "2006-01-02"
+
+// Series identifies a series or an aggregate of series: a zero StoreNbr sums every
+// store and an empty Family every family, so Series{} is the total.
+type Series struct {
+	StoreNbr int
+	Family   string
+}
+
+// Point is the sales of one day.
+type Point struct {
+	Date  string  `json:"date"`
+	Sales float64 `json:"sales"`
+}
+
+// Info describes the loaded history.
+type Info struct {
+	Source    string    `json:"source"`
+	Series    int       `json:"series"` // Bottom-level (store, family) series
+	Rows      int       `json:"rows"`
+	FirstDate string    `json:"first_date"`
+	LastDate  string    `json:"last_date"`
+	LoadedAt  time.Time `json:"loaded_at"`
+}
+
+// Store is an immutable daily sales history. Every series and aggregate is a dense
+// array over its dates, with NaN for days without data.
+type Store struct {
+	daily map[Series]*daily
+	info  Info
+}
+
+// daily is the sales of a series from day number start, one value per day.
+type daily struct {
+	start int
+	sales []float64
+}
+
+// row is one day of sales of a series, as read from a source.
+type row struct {
+	storeNbr int
+	family   string
+	day      int
+	sales    float64
+}
+
+// historyRow is a row of a history parquet file: the preprocessed training data, or
+// any file with these columns.
+type historyRow struct {
+	StoreNbr int32     `parquet:"store_nbr"`
+	Family   string    `parquet:"family"`
+	Date     time.Time `parquet:"date"`
+	Sales    float64   `parquet:"sales"`
+}
+
+// Load reads a history file: a .json file of "<store_nbr>_<family>_<date>" keys to
+// sales, or otherwise a parquet file with store_nbr, family, date and sales columns.
+func Load(path string) (*Store, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return LoadJSON(path)
+	}
+	return LoadParquet(path)
+}
+
+// LoadParquet reads the daily sales of a parquet file with store_nbr, family, date
+// and sales columns, such as data/processed/train_preprocessed.parquet.
+func LoadParquet(path string) (*Store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pf, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	for _, col := range []string{"store_nbr", "family", "date", "sales"} {
+		if _, ok := pf.Schema().Lookup(col); !ok {
+			return nil, fmt.Errorf("history file %s has no %s column", path, col)
+		}
+	}
+
+	reader := parquet.NewReader(pf)
+	defer reader.Close()
+	rows := make([]row, 0, pf.NumRows())
+	for {
+		var r historyRow
+		if err := reader.Read(&r); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read history row %d: %w", len(rows), err)
+		}
+		rows = append(rows, row{storeNbr: int(r.StoreNbr), family: r.Family, day: dayNumber(r.Date), sales: r.Sales})
+	}
+	return build(rows, path)
+}
+
+// LoadJSON reads a JSON object of "<store_nbr>_<family>_<date>" keys to sales.
+func LoadJSON(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parse history: %w", err)
+	}
+
+	rows := make([]row, 0, len(values))
+	for key, sales := range values {
+		// Families contain spaces, commas and slashes but no underscores
+		first, last := strings.Index(key, "_"), strings.LastIndex(key, "_")
+		if first < 0 || first == last {
+			return nil, fmt.Errorf("history key %q is not <store_nbr>_<family>_<date>", key)
+		}
+		storeNbr, err := strconv.Atoi(key[:first])
+		if err != nil {
+			return nil, fmt.Errorf("history key %q: invalid store_nbr", key)
+		}
+		date, err := time.Parse(DateFormat, key[last+1:])
+		if err != nil {
+			return nil, fmt.Errorf("history key %q: invalid date", key)
+		}
+		rows = append(rows, row{storeNbr: storeNbr, family: key[first+1 : last], day: dayNumber(date), sales: sales})
+	}
+	return build(rows, path)
+}
+
+// LoadSQL reads the daily sales of table, which needs store_nbr, family, date and
+// sales columns, from a DuckDB (duckdb=true) or SQLite database.
+func LoadSQL(ctx context.Context, db *sql.DB, table string, duckdb bool) (*Store, error) {
+	dayExpr := `date("date")`
+	if duckdb {
+		dayExpr = `CAST(CAST("date" AS DATE) AS VARCHAR)`
+	}
+	rs, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT store_nbr, family, %s, COALESCE(sales, 0) FROM %s`, dayExpr, table))
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rs.Close()
+
+	var rows []row
+	for rs.Next() {
+		var r row
+		var date string
+		if err := rs.Scan(&r.storeNbr, &r.family, &date, &r.sales); err != nil {
+			return nil, fmt.Errorf("read history row %d: %w", len(rows), err)
+		}
+		d, err := time.Parse(DateFormat, date)
+		if err != nil {
+			return nil, fmt.Errorf("history row %d: invalid date %q", len(rows), date)
+		}
+		r.day = dayNumber(d)
+		rows = append(rows, r)
+	}
+	if err := rs.Err(); err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	return build(rows, table)
+}
+
+// build indexes rows into dense per-series arrays and sums them into store, family
+// and total aggregates. A later row for the same series and day replaces an earlier one.
+func build(rows []row, source string) (*Store, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("history has no rows")
+	}
+
+	bySeries := make(map[Series][]row)
+	first, last := rows[0].day, rows[0].day
+	for _, r := range rows {
+		if r.storeNbr <= 0 || r.family == "" {
+			return nil, fmt.Errorf("history row of store %d, family %q: store_nbr and family are required", r.storeNbr, r.family)
+		}
+		s := Series{StoreNbr: r.storeNbr, Family: r.family}
+		bySeries[s] = append(bySeries[s], r)
+		first, last = min(first, r.day), max(last, r.day)
+	}
+
+	st := &Store{
+		daily: make(map[Series]*daily, len(bySeries)*2),
+		info: Info{
+			Source:    source,
+			Series:    len(bySeries),
+			Rows:      len(rows),
+			FirstDate: dateOf(first).Format(DateFormat),
+			LastDate:  dateOf(last).Format(DateFormat),
+			LoadedAt:  time.Now(),
+		},
+	}
+	for s, rs := range bySeries {
+		lo, hi := rs[0].day, rs[0].day
+		for _, r := range rs {
+			lo, hi = min(lo, r.day), max(hi, r.day)
+		}
+		d := newDaily(lo, hi)
+		for _, r := range rs {
+			d.sales[r.day-lo] = r.sales
+		}
+		st.daily[s] = d
+
+		for _, agg := range []Series{{}, {StoreNbr: s.StoreNbr}, {Family: s.Family}} {
+			a, ok := st.daily[agg]
+			if !ok {
+				a = newDaily(first, last)
+				st.daily[agg] = a
+			}
+			for i, v := range d.sales {
+				if math.IsNaN(v) {
+					continue
+				}
+				j := d.start + i - a.start
+				if math.IsNaN(a.sales[j]) {
+					a.sales[j] = 0
+				}
+				a.sales[j] += v
+			}
+		}
+	}
+	return st, nil
+}
+
+// newDaily returns an array of missing days from day first to last.
+func newDaily(first, last int) *daily {
+	d := &daily{start: first, sales: make([]float64, last-first+1)}
+	for i := range d.sales {
+		d.sales[i] = math.NaN()
+	}
+	return d
+}
+
+// Range returns the sales of a series or aggregate from from to to, inclusive, one
+// point per day with data, and whether the series is in the history at all.
+func (s *Store) Range(series Series, from, to time.Time) ([]Point, bool) {
+	d, ok := s.daily[series]
+	if !ok {
+		return []Point{}, false
+	}
+	lo := max(dayNumber(from), d.start)
+	hi := min(dayNumber(to), d.start+len(d.sales)-1)
+	points := make([]Point, 0, max(hi-lo+1, 0))
+	for day := lo; day <= hi; day++ {
+		if v := d.sales[day-d.start]; !math.IsNaN(v) {
+			points = append(points, Point{Date: dateOf(day).Format(DateFormat), Sales: v})
+		}
+	}
+	return points, true
+}
+
+// Span returns the first and last date of a series or aggregate.
+func (s *Store) Span(series Series) (first, last time.Time, ok bool) {
+	d, ok := s.daily[series]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return dateOf(d.start), dateOf(d.start + len(d.sales) - 1), true
+}
+
+// Info describes the loaded history.
+func (s *Store) Info() Info {
+	return s.info
+}
+
+// dayNumber returns the days since the Unix epoch of a date's calendar day.
+func dayNumber(date time.Time) int {
+	y, m, d := date.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// dateOf is the inverse of dayNumber.
+func dateOf(day int) time.Time {
+	return time.Unix(int64(day)*86400, 0).UTC()
+}
@@ -0,0 +1,162 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	_ "github.com/mlrf/mlrf-api/internal/features" // Links the duckdb and sqlite drivers, as in the server
+)
+
+func day(s string) time.Time {
+	d, _ := time.Parse(DateFormat, s)
+	return d
+}
+
+func writeHistory(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "train_preprocessed.parquet")
+	rows := []historyRow{
+		{StoreNbr: 1, Family: "DAIRY", Date: day("2017-08-01"), Sales: 10},
+		{StoreNbr: 1, Family: "DAIRY", Date: day("2017-08-02"), Sales: 12},
+		{StoreNbr: 1, Family: "DAIRY", Date: day("2017-08-04"), Sales: 14},
+		{StoreNbr: 1, Family: "BREAD/BAKERY", Date: day("2017-08-02"), Sales: 5},
+		{StoreNbr: 2, Family: "DAIRY", Date: day("2017-08-02"), Sales: 20},
+		{StoreNbr: 2, Family: "DAIRY", Date: day("2017-08-03"), Sales: 0},
+	}
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatalf("failed to write parquet: %v", err)
+	}
+	return path
+}
+
+func TestLoadParquet(t *testing.T) {
+	s, err := Load(writeHistory(t))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	info := s.Info()
+	if info.Series != 3 || info.Rows != 6 || info.FirstDate != "2017-08-01" || info.LastDate != "2017-08-04" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	// Daily points, skipping the missing 08-03, with zero sales kept
+	points, ok := s.Range(Series{StoreNbr: 1, Family: "DAIRY"}, day("2017-07-01"), day("2017-08-31"))
+	if !ok || len(points) != 3 || points[2].Date != "2017-08-04" || points[2].Sales != 14 {
+		t.Errorf("unexpected series history %+v", points)
+	}
+	if points, _ := s.Range(Series{StoreNbr: 2, Family: "DAIRY"}, day("2017-08-03"), day("2017-08-03")); len(points) != 1 || points[0].Sales != 0 {
+		t.Errorf("expected a zero sales day, got %+v", points)
+	}
+
+	tests := []struct {
+		name   string
+		series Series
+		want   []float64
+	}{
+		{"store", Series{StoreNbr: 1}, []float64{10, 17, 14}},
+		{"family", Series{Family: "DAIRY"}, []float64{10, 32, 0, 14}},
+		{"total", Series{}, []float64{10, 37, 0, 14}},
+	}
+	for _, tt := range tests {
+		points, ok := s.Range(tt.series, day("2017-08-01"), day("2017-08-04"))
+		if !ok || len(points) != len(tt.want) {
+			t.Errorf("%s: unexpected points %+v", tt.name, points)
+			continue
+		}
+		for i, want := range tt.want {
+			if points[i].Sales != want {
+				t.Errorf("%s: %s expected %v, got %v", tt.name, points[i].Date, want, points[i].Sales)
+			}
+		}
+	}
+
+	if points, ok := s.Range(Series{StoreNbr: 3, Family: "DAIRY"}, day("2017-08-01"), day("2017-08-04")); ok || points == nil || len(points) != 0 {
+		t.Errorf("expected an unknown series to be empty, got %v %+v", ok, points)
+	}
+	if first, last, ok := s.Span(Series{StoreNbr: 1, Family: "BREAD/BAKERY"}); !ok || !first.Equal(day("2017-08-02")) || !last.Equal(first) {
+		t.Errorf("unexpected span %v to %v", first, last)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "historical_data.json")
+	if err := os.WriteFile(path, []byte(`{"1_BREAD/BAKERY_2017-08-01": 5, "12_LIQUOR,WINE,BEER_2017-08-01": 7}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if points, _ := s.Range(Series{StoreNbr: 12, Family: "LIQUOR,WINE,BEER"}, day("2017-08-01"), day("2017-08-01")); len(points) != 1 || points[0].Sales != 7 {
+		t.Errorf("unexpected store 12 history %+v", points)
+	}
+
+	for name, data := range map[string]string{
+		"nofamily.json": `{"1_2017-08-01": 5}`,
+		"nostore.json":  `{"one_DAIRY_2017-08-01": 5}`,
+		"nodate.json":   `{"1_DAIRY_yesterday": 5}`,
+		"empty.json":    `{}`,
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		os.WriteFile(path, []byte(data), 0o644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadSQL(t *testing.T) {
+	for _, driver := range []string{"sqlite", "duckdb"} {
+		t.Run(driver, func(t *testing.T) {
+			db, err := sql.Open(driver, filepath.Join(t.TempDir(), "history."+driver))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			for _, stmt := range []string{
+				`CREATE TABLE sales (store_nbr INTEGER, family VARCHAR, date DATE, sales DOUBLE)`,
+				`INSERT INTO sales VALUES (1, 'DAIRY', '2017-08-01', 10), (1, 'DAIRY', '2017-08-02', NULL), (2, 'EGGS', '2017-08-02', 4.5)`,
+			} {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("%s: %v", stmt, err)
+				}
+			}
+
+			s, err := LoadSQL(context.Background(), db, "sales", driver == "duckdb")
+			if err != nil {
+				t.Fatalf("LoadSQL failed: %v", err)
+			}
+			if info := s.Info(); info.Series != 2 || info.Rows != 3 || info.FirstDate != "2017-08-01" || info.LastDate != "2017-08-02" {
+				t.Errorf("unexpected info %+v", info)
+			}
+			points, ok := s.Range(Series{StoreNbr: 1, Family: "DAIRY"}, day("2017-08-01"), day("2017-08-02"))
+			if !ok || len(points) != 2 || points[0].Sales != 10 || points[1].Sales != 0 {
+				t.Errorf("unexpected series history %+v", points)
+			}
+			if _, err := LoadSQL(context.Background(), db, "missing", driver == "duckdb"); err == nil {
+				t.Error("expected an error for a missing table")
+			}
+		})
+	}
+}
+
+func TestLoadParquetMissingSales(t *testing.T) {
+	type noSales struct {
+		StoreNbr int32     `parquet:"store_nbr"`
+		Family   string    `parquet:"family"`
+		Date     time.Time `parquet:"date"`
+	}
+	path := filepath.Join(t.TempDir(), "features.parquet")
+	if err := parquet.WriteFile(path, []noSales{{StoreNbr: 1, Family: "DAIRY", Date: day("2017-08-01")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a file without sales")
+	}
+}
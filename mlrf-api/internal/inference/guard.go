@@ -0,0 +1,121 @@
+package inference
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+)
+
+// Guard policies for NaN and infinite predictions.
+const (
+	GuardPolicyClamp = "clamp" // Replace with the nearest bound; NaN becomes Min
+	GuardPolicyZero  = "zero"  // Replace with zero
+	GuardPolicyError = "error" // Fail the prediction
+)
+
+// Guard actions, as noted in responses and labelled in metrics.
+const (
+	GuardClamped  = "clamped"
+	GuardZeroed   = "zeroed"
+	GuardRejected = "rejected"
+)
+
+// ErrInvalidPrediction is returned by Guard.Check for a NaN or infinite prediction
+// under the error policy.
+var ErrInvalidPrediction = errors.New("invalid prediction")
+
+// GuardConfig holds the prediction sanity guard configuration.
+type GuardConfig struct {
+	// Min and Max bound served predictions; finite predictions outside are clamped.
+	Min float64
+	Max float64
+	// Policy is how NaN and infinite predictions are handled: clamp, zero or error.
+	Policy string
+}
+
+// DefaultGuardConfig returns the default guard configuration.
+func DefaultGuardConfig() GuardConfig {
+	return GuardConfig{
+		Min:    0,
+		Max:    1e7,
+		Policy: GuardPolicyClamp,
+	}
+}
+
+// ValidGuardPolicy reports whether policy is a known guard policy.
+func ValidGuardPolicy(policy string) bool {
+	switch policy {
+	case GuardPolicyClamp, GuardPolicyZero, GuardPolicyError:
+		return true
+	}
+	return false
+}
+
+// Guard checks model output before it is served, so a model emitting NaN, infinities
+// or absurd values for malformed features can't pass them on to clients. A nil Guard
+// passes every prediction through.
+type Guard struct {
+	cfg GuardConfig
+}
+
+// NewGuard creates a guard. A Max below Min is raised to Min, and an unknown policy
+// is clamp.
+func NewGuard(cfg GuardConfig) *Guard {
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if !ValidGuardPolicy(cfg.Policy) {
+		cfg.Policy = GuardPolicyClamp
+	}
+	return &Guard{cfg: cfg}
+}
+
+// Config returns the guard's configuration.
+func (g *Guard) Config() GuardConfig {
+	return g.cfg
+}
+
+// Check returns the prediction to serve in place of p and the action taken, "" if p
+// is served as is. Finite predictions outside [Min, Max] are clamped; NaN and
+// infinite ones are handled by the policy, and fail with ErrInvalidPrediction under
+// the error policy. Every action is counted in the metrics.
+func (g *Guard) Check(p float32) (float32, string, error) {
+	if g == nil {
+		return p, "", nil
+	}
+	v := float64(p)
+	action := ""
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		switch g.cfg.Policy {
+		case GuardPolicyError:
+			metrics.RecordPredictionGuard(GuardRejected)
+			return 0, GuardRejected, fmt.Errorf("%w: model returned %v", ErrInvalidPrediction, v)
+		case GuardPolicyZero:
+			v, action = 0, GuardZeroed
+		default:
+			if math.IsInf(v, 1) {
+				v = g.cfg.Max
+			} else {
+				v = g.cfg.Min
+			}
+			action = GuardClamped
+		}
+	}
+	if v < g.cfg.Min {
+		v = g.cfg.Min
+		if action == "" {
+			action = GuardClamped
+		}
+	} else if v > g.cfg.Max {
+		v = g.cfg.Max
+		if action == "" {
+			action = GuardClamped
+		}
+	}
+	if action != "" {
+		metrics.RecordPredictionGuard(action)
+	}
+	return float32(v), action, nil
+}
@@ -0,0 +1,95 @@
+package inference
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGuardCheck(t *testing.T) {
+	nan := float32(math.NaN())
+	posInf := float32(math.Inf(1))
+	negInf := float32(math.Inf(-1))
+
+	tests := []struct {
+		name       string
+		policy     string
+		prediction float32
+		want       float32
+		action     string
+		wantErr    bool
+	}{
+		{"in range", GuardPolicyClamp, 1234.5, 1234.5, "", false},
+		{"at bounds", GuardPolicyClamp, 0, 0, "", false},
+		{"negative", GuardPolicyClamp, -5, 0, GuardClamped, false},
+		{"too large", GuardPolicyClamp, 2e7, 1e7, GuardClamped, false},
+		{"NaN clamped", GuardPolicyClamp, nan, 0, GuardClamped, false},
+		{"+Inf clamped", GuardPolicyClamp, posInf, 1e7, GuardClamped, false},
+		{"-Inf clamped", GuardPolicyClamp, negInf, 0, GuardClamped, false},
+		{"NaN zeroed", GuardPolicyZero, nan, 0, GuardZeroed, false},
+		{"+Inf zeroed", GuardPolicyZero, posInf, 0, GuardZeroed, false},
+		{"negative under zero policy", GuardPolicyZero, -5, 0, GuardClamped, false},
+		{"NaN rejected", GuardPolicyError, nan, 0, GuardRejected, true},
+		{"+Inf rejected", GuardPolicyError, posInf, 0, GuardRejected, true},
+		{"negative under error policy", GuardPolicyError, -5, 0, GuardClamped, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuard(GuardConfig{Min: 0, Max: 1e7, Policy: tt.policy})
+			got, action, err := g.Check(tt.prediction)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPrediction) {
+					t.Fatalf("expected ErrInvalidPrediction, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want || action != tt.action {
+				t.Errorf("Check(%v) = %v, %q; want %v, %q", tt.prediction, got, action, tt.want, tt.action)
+			}
+		})
+	}
+}
+
+func TestGuardNilPassesThrough(t *testing.T) {
+	var g *Guard
+	got, action, err := g.Check(float32(math.NaN()))
+	if err != nil || action != "" || !math.IsNaN(float64(got)) {
+		t.Errorf("nil guard Check = %v, %q, %v; want the prediction unchanged", got, action, err)
+	}
+}
+
+func TestGuardRecordsActions(t *testing.T) {
+	clamped := testutil.ToFloat64(metrics.PredictionGuard.WithLabelValues(GuardClamped))
+	rejected := testutil.ToFloat64(metrics.PredictionGuard.WithLabelValues(GuardRejected))
+
+	g := NewGuard(GuardConfig{Min: 0, Max: 100, Policy: GuardPolicyError})
+	g.Check(50)
+	g.Check(-1)
+	g.Check(101)
+	g.Check(float32(math.NaN()))
+
+	if d := testutil.ToFloat64(metrics.PredictionGuard.WithLabelValues(GuardClamped)) - clamped; d != 2 {
+		t.Errorf("expected 2 clamped predictions recorded, got %v", d)
+	}
+	if d := testutil.ToFloat64(metrics.PredictionGuard.WithLabelValues(GuardRejected)) - rejected; d != 1 {
+		t.Errorf("expected 1 rejected prediction recorded, got %v", d)
+	}
+}
+
+func TestNewGuardDefaults(t *testing.T) {
+	g := NewGuard(GuardConfig{Min: 10, Max: 5, Policy: "drop"})
+	if cfg := g.Config(); cfg.Max != 10 || cfg.Policy != GuardPolicyClamp {
+		t.Errorf("expected Max raised to Min and the clamp policy, got %+v", cfg)
+	}
+}
+
+func TestDefaultGuardConfig(t *testing.T) {
+	cfg := DefaultGuardConfig()
+	if cfg.Min != 0 || cfg.Max != 1e7 || cfg.Policy != GuardPolicyClamp {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
@@ -0,0 +1,163 @@
+package inference
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the ONNX ModelProto and OperatorSetIdProto read by ReadModelFile.
+const (
+	onnxIRVersion       protowire.Number = 1
+	onnxProducerName    protowire.Number = 2
+	onnxProducerVersion protowire.Number = 3
+	onnxModelVersion    protowire.Number = 5
+	onnxOpsetImport     protowire.Number = 8
+
+	onnxOpsetDomain  protowire.Number = 1
+	onnxOpsetVersion protowire.Number = 2
+)
+
+// onnxDefaultDomain names the default operator set, whose domain is empty in the file.
+const onnxDefaultDomain = "ai.onnx"
+
+// ModelFile describes an ONNX model file: its content hash and the header fields of
+// the ONNX model.
+type ModelFile struct {
+	SHA256          string           `json:"sha256"`
+	SizeBytes       int64            `json:"size_bytes"`
+	ModifiedAt      time.Time        `json:"modified_at"`
+	IRVersion       int64            `json:"ir_version,omitempty"`
+	Opset           int64            `json:"opset,omitempty"`  // Version of the default (ai.onnx) operator set
+	Opsets          map[string]int64 `json:"opsets,omitempty"` // Operator set versions by domain
+	ProducerName    string           `json:"producer_name,omitempty"`
+	ProducerVersion string           `json:"producer_version,omitempty"`
+	ModelVersion    int64            `json:"model_version,omitempty"`
+}
+
+// ReadModelFile hashes the model file at path and reads its ONNX header. A file the
+// header can't be parsed from is described by its hash, size and time alone; the
+// ONNX Runtime rejects it when it is loaded.
+func ReadModelFile(path string) (*ModelFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	f := &ModelFile{
+		SHA256:     hex.EncodeToString(sum[:]),
+		SizeBytes:  info.Size(),
+		ModifiedAt: info.ModTime().UTC(),
+	}
+	if header, err := parseONNXHeader(data); err == nil {
+		header.SHA256, header.SizeBytes, header.ModifiedAt = f.SHA256, f.SizeBytes, f.ModifiedAt
+		f = header
+	}
+	return f, nil
+}
+
+// parseONNXHeader reads the top-level fields of a serialized ModelProto, skipping the
+// graph and everything else.
+func parseONNXHeader(data []byte) (*ModelFile, error) {
+	f := &ModelFile{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case onnxIRVersion:
+				f.IRVersion = int64(v)
+			case onnxModelVersion:
+				f.ModelVersion = int64(v)
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case onnxProducerName:
+				f.ProducerName = string(v)
+			case onnxProducerVersion:
+				f.ProducerVersion = string(v)
+			case onnxOpsetImport:
+				domain, version, err := parseOpset(v)
+				if err != nil {
+					return nil, err
+				}
+				if f.Opsets == nil {
+					f.Opsets = make(map[string]int64)
+				}
+				f.Opsets[domain] = version
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	if f.IRVersion == 0 {
+		return nil, fmt.Errorf("not an ONNX model: no IR version")
+	}
+	f.Opset = f.Opsets[onnxDefaultDomain]
+	return f, nil
+}
+
+// parseOpset reads an OperatorSetIdProto.
+func parseOpset(data []byte) (string, int64, error) {
+	domain := onnxDefaultDomain
+	var version int64
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case num == onnxOpsetDomain && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			data = data[n:]
+			if len(v) > 0 {
+				domain = string(v)
+			}
+		case num == onnxOpsetVersion && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			data = data[n:]
+			version = int64(v)
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return domain, version, nil
+}
@@ -0,0 +1,80 @@
+package inference
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// onnxHeader serializes the ModelProto fields ReadModelFile reads, with a graph to skip.
+func onnxHeader() []byte {
+	opset := func(domain string, version uint64) []byte {
+		var b []byte
+		if domain != "" {
+			b = protowire.AppendTag(b, onnxOpsetDomain, protowire.BytesType)
+			b = protowire.AppendString(b, domain)
+		}
+		b = protowire.AppendTag(b, onnxOpsetVersion, protowire.VarintType)
+		return protowire.AppendVarint(b, version)
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, onnxIRVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, 8)
+	b = protowire.AppendTag(b, onnxProducerName, protowire.BytesType)
+	b = protowire.AppendString(b, "OnnxMLTools")
+	b = protowire.AppendTag(b, onnxProducerVersion, protowire.BytesType)
+	b = protowire.AppendString(b, "1.12.0")
+	b = protowire.AppendTag(b, 7, protowire.BytesType) // graph
+	b = protowire.AppendBytes(b, []byte("nodes and initializers"))
+	b = protowire.AppendTag(b, onnxOpsetImport, protowire.BytesType)
+	b = protowire.AppendBytes(b, opset("", 15))
+	b = protowire.AppendTag(b, onnxOpsetImport, protowire.BytesType)
+	return protowire.AppendBytes(b, opset("ai.onnx.ml", 3))
+}
+
+func TestReadModelFile(t *testing.T) {
+	data := onnxHeader()
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ReadModelFile(path)
+	if err != nil {
+		t.Fatalf("ReadModelFile failed: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if f.SHA256 != hex.EncodeToString(sum[:]) || f.SizeBytes != int64(len(data)) || f.ModifiedAt.IsZero() {
+		t.Errorf("unexpected file fields %+v", f)
+	}
+	if f.IRVersion != 8 || f.ProducerName != "OnnxMLTools" || f.ProducerVersion != "1.12.0" {
+		t.Errorf("unexpected header %+v", f)
+	}
+	if f.Opset != 15 || f.Opsets["ai.onnx"] != 15 || f.Opsets["ai.onnx.ml"] != 3 {
+		t.Errorf("unexpected opsets %d, %v", f.Opset, f.Opsets)
+	}
+}
+
+func TestReadModelFileNotONNX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, []byte("not a model"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ReadModelFile(path)
+	if err != nil {
+		t.Fatalf("ReadModelFile failed: %v", err)
+	}
+	if f.SHA256 == "" || f.IRVersion != 0 || f.Opsets != nil {
+		t.Errorf("expected only the hash of a file without an ONNX header, got %+v", f)
+	}
+
+	if _, err := ReadModelFile(filepath.Join(t.TempDir(), "missing.onnx")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
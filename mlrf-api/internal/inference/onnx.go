@@ -2,16 +2,42 @@
 package inference
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sync"
 
+	"github.com/mlrf/mlrf-api/internal/schema"
 	ort "github.com/yalue/onnxruntime_go"
 )
 
 // NumFeatures is the expected number of input features for the model.
 // Includes all features: 25 numeric + 2 categorical (integer-encoded)
-const NumFeatures = 27
+const NumFeatures = schema.NumFeatures
+
+// DefaultMaxBatchSize is the default maximum number of rows per batched tensor.
+const DefaultMaxBatchSize = 256
+
+// DefaultLibPath is the ONNX Runtime shared library loaded by default.
+const DefaultLibPath = "libonnxruntime.so"
+
+// SessionConfig holds ONNX session configuration.
+type SessionConfig struct {
+	// MaxBatchSize caps the number of rows sent to the model in a single run.
+	// Larger batches are split into chunks of at most this size.
+	MaxBatchSize int
+
+	// LibPath is the ONNX Runtime shared library, loaded by the first session.
+	LibPath string
+}
+
+// DefaultSessionConfig returns the default session configuration.
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		MaxBatchSize: DefaultMaxBatchSize,
+		LibPath:      DefaultLibPath,
+	}
+}
 
 // ONNXSession wraps ONNX Runtime for thread-safe inference.
 type ONNXSession struct {
@@ -20,26 +46,49 @@ type ONNXSession struct {
 	outputShape  ort.Shape
 	inputTensor  *ort.Tensor[float32]
 	outputTensor *ort.Tensor[float32]
-	mu           sync.Mutex
+
+	// batchSession runs variable-sized (N, NumFeatures) inputs for PredictBatch
+	batchSession *ort.DynamicAdvancedSession
+	maxBatchSize int
+
+	mu sync.Mutex
 }
 
-// NewONNXSession creates a new ONNX inference session.
+// NewONNXSession creates a new ONNX inference session with default configuration.
 func NewONNXSession(modelPath string) (*ONNXSession, error) {
+	return NewONNXSessionWithConfig(modelPath, DefaultSessionConfig())
+}
+
+// NewONNXSessionWithConfig creates a new ONNX inference session.
+func NewONNXSessionWithConfig(modelPath string, cfg SessionConfig) (*ONNXSession, error) {
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultMaxBatchSize
+	}
+
 	// Check if model file exists
 	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("model file not found: %s", modelPath)
 	}
 
-	// Set shared library path based on environment or default
-	libPath := os.Getenv("ONNX_LIB_PATH")
-	if libPath == "" {
-		libPath = "libonnxruntime.so"
+	if cfg.LibPath == "" {
+		cfg.LibPath = DefaultLibPath
 	}
-	ort.SetSharedLibraryPath(libPath)
+	ort.SetSharedLibraryPath(cfg.LibPath)
 
-	// Initialize ONNX Runtime environment
-	if err := ort.InitializeEnvironment(); err != nil {
-		return nil, fmt.Errorf("failed to init onnxruntime: %w", err)
+	// Initialize ONNX Runtime environment (shared by all sessions in the process)
+	if !ort.IsInitialized() {
+		if err := ort.InitializeEnvironment(); err != nil {
+			return nil, fmt.Errorf("failed to init onnxruntime: %w", err)
+		}
+	}
+
+	// Fail fast on a model trained against a different feature schema
+	inputs, _, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model inputs: %w", err)
+	}
+	if err := checkModelInputs(inputs); err != nil {
+		return nil, err
 	}
 
 	// Define shapes (batch=1, features=NumFeatures)
@@ -76,12 +125,28 @@ func NewONNXSession(modelPath string) (*ONNXSession, error) {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
+	// Create a dynamic session for batched inference (tensors allocated per run)
+	batchSession, err := ort.NewDynamicAdvancedSession(
+		modelPath,
+		[]string{"input"},
+		[]string{"variable"},
+		nil,
+	)
+	if err != nil {
+		session.Destroy()
+		inputTensor.Destroy()
+		outputTensor.Destroy()
+		return nil, fmt.Errorf("failed to create batch session: %w", err)
+	}
+
 	return &ONNXSession{
 		session:      session,
 		inputShape:   inputShape,
 		outputShape:  outputShape,
 		inputTensor:  inputTensor,
 		outputTensor: outputTensor,
+		batchSession: batchSession,
+		maxBatchSize: cfg.MaxBatchSize,
 	}, nil
 }
 
@@ -110,20 +175,93 @@ func (s *ONNXSession) Predict(features []float32) (float32, error) {
 }
 
 // PredictBatch runs inference on multiple inputs.
-// More efficient than calling Predict multiple times.
+// Rows are packed into (N, NumFeatures) tensors and run in a single session call
+// per chunk of at most MaxBatchSize rows.
 func (s *ONNXSession) PredictBatch(featureBatch [][]float32) ([]float32, error) {
-	results := make([]float32, len(featureBatch))
+	if len(featureBatch) == 0 {
+		return []float32{}, nil
+	}
+
 	for i, features := range featureBatch {
-		pred, err := s.Predict(features)
+		if len(features) != NumFeatures {
+			return nil, fmt.Errorf("batch item %d: expected %d features, got %d", i, NumFeatures, len(features))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]float32, 0, len(featureBatch))
+	for _, c := range chunkRanges(len(featureBatch), s.maxBatchSize) {
+		preds, err := s.runBatch(featureBatch[c[0]:c[1]])
 		if err != nil {
-			return nil, fmt.Errorf("batch item %d: %w", i, err)
+			return nil, fmt.Errorf("batch rows %d-%d: %w", c[0], c[1]-1, err)
 		}
-		results[i] = pred
+		results = append(results, preds...)
 	}
 	return results, nil
 }
 
-// Close releases all ONNX Runtime resources.
+// runBatch runs a single (N, NumFeatures) tensor through the batch session.
+// Caller must hold s.mu.
+func (s *ONNXSession) runBatch(rows [][]float32) ([]float32, error) {
+	n := len(rows)
+	input, err := ort.NewTensor(ort.NewShape(int64(n), int64(NumFeatures)), flattenRows(rows))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch input tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	output, err := ort.NewEmptyTensor[float32](ort.NewShape(int64(n), 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch output tensor: %w", err)
+	}
+	defer output.Destroy()
+
+	if err := s.batchSession.Run([]ort.ArbitraryTensor{input}, []ort.ArbitraryTensor{output}); err != nil {
+		return nil, fmt.Errorf("batch inference failed: %w", err)
+	}
+
+	preds := make([]float32, n)
+	copy(preds, output.GetData())
+	return preds, nil
+}
+
+// flattenRows packs feature rows into a contiguous row-major slice.
+func flattenRows(rows [][]float32) []float32 {
+	flat := make([]float32, 0, len(rows)*NumFeatures)
+	for _, row := range rows {
+		flat = append(flat, row...)
+	}
+	return flat
+}
+
+// chunkRanges splits n rows into [start, end) ranges of at most size rows.
+func chunkRanges(n, size int) [][2]int {
+	if n <= 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = n
+	}
+	ranges := make([][2]int, 0, (n+size-1)/size)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
+
+// MaxBatchSize returns the maximum number of rows per batched tensor.
+func (s *ONNXSession) MaxBatchSize() int {
+	return s.maxBatchSize
+}
+
+// Close releases the session's ONNX Runtime resources.
+// The shared runtime environment stays initialized; call DestroyEnvironment on shutdown.
 func (s *ONNXSession) Close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -131,51 +269,42 @@ func (s *ONNXSession) Close() {
 	if s.session != nil {
 		s.session.Destroy()
 	}
+	if s.batchSession != nil {
+		s.batchSession.Destroy()
+	}
 	if s.inputTensor != nil {
 		s.inputTensor.Destroy()
 	}
 	if s.outputTensor != nil {
 		s.outputTensor.Destroy()
 	}
-	ort.DestroyEnvironment()
 }
 
-// FeatureNames returns the expected feature names in order.
-// Must match the order in mlrf-ml/src/mlrf_ml/models/lightgbm_model.py FEATURE_COLS + CATEGORICAL_COLS
+// DestroyEnvironment tears down the shared ONNX Runtime environment.
+// Must be called only after every session has been closed.
+func DestroyEnvironment() {
+	if ort.IsInitialized() {
+		ort.DestroyEnvironment()
+	}
+}
+
+// FeatureNames returns the expected feature names in order, as defined by the
+// shared feature schema.
 func FeatureNames() []string {
-	return []string{
-		// Date features
-		"year",
-		"month",
-		"day",
-		"dayofweek",
-		"dayofyear",
-		"is_mid_month",
-		"is_leap_year",
-		// External features
-		"oil_price",
-		"is_holiday",
-		"onpromotion",
-		"promo_rolling_7",
-		// Store metadata
-		"cluster",
-		// Lag features
-		"sales_lag_1",
-		"sales_lag_7",
-		"sales_lag_14",
-		"sales_lag_28",
-		"sales_lag_90",
-		// Rolling features
-		"sales_rolling_mean_7",
-		"sales_rolling_mean_14",
-		"sales_rolling_mean_28",
-		"sales_rolling_mean_90",
-		"sales_rolling_std_7",
-		"sales_rolling_std_14",
-		"sales_rolling_std_28",
-		"sales_rolling_std_90",
-		// Categorical features (integer-encoded)
-		"family",
-		"type",
+	return schema.Features.Names()
+}
+
+// checkModelInputs verifies that the model's "input" tensor takes one value per
+// feature of the schema. A dynamic (-1) feature dimension isn't checked.
+func checkModelInputs(inputs []ort.InputOutputInfo) error {
+	for _, in := range inputs {
+		if in.Name != "input" {
+			continue
+		}
+		if n := len(in.Dimensions); n > 0 && in.Dimensions[n-1] > 0 && in.Dimensions[n-1] != int64(NumFeatures) {
+			return fmt.Errorf("model input %q takes %d features, feature schema has %d", in.Name, in.Dimensions[n-1], NumFeatures)
+		}
+		return nil
 	}
+	return errors.New(`model has no "input" tensor`)
 }
@@ -2,6 +2,8 @@ package inference
 
 import (
 	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
 )
 
 func TestFeatureNames(t *testing.T) {
@@ -47,3 +49,79 @@ func TestNewONNXSessionMissingModel(t *testing.T) {
 		t.Error("expected error for missing model file")
 	}
 }
+
+func TestChunkRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		n        int
+		size     int
+		expected [][2]int
+	}{
+		{"empty", 0, 10, nil},
+		{"single chunk", 5, 10, [][2]int{{0, 5}}},
+		{"exact multiple", 6, 3, [][2]int{{0, 3}, {3, 6}}},
+		{"remainder", 7, 3, [][2]int{{0, 3}, {3, 6}, {6, 7}}},
+		{"unbounded size", 4, 0, [][2]int{{0, 4}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := chunkRanges(tc.n, tc.size)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %d chunks, got %d", len(tc.expected), len(got))
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("chunk %d: expected %v, got %v", i, tc.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFlattenRows(t *testing.T) {
+	rows := [][]float32{make([]float32, NumFeatures), make([]float32, NumFeatures)}
+	rows[0][0] = 1
+	rows[1][0] = 2
+	rows[1][NumFeatures-1] = 3
+
+	flat := flattenRows(rows)
+
+	if len(flat) != 2*NumFeatures {
+		t.Fatalf("expected %d values, got %d", 2*NumFeatures, len(flat))
+	}
+	if flat[0] != 1 || flat[NumFeatures] != 2 || flat[2*NumFeatures-1] != 3 {
+		t.Errorf("rows not packed in row-major order: %v", flat)
+	}
+}
+
+func TestDefaultSessionConfig(t *testing.T) {
+	cfg := DefaultSessionConfig()
+	if cfg.MaxBatchSize != DefaultMaxBatchSize {
+		t.Errorf("expected default max batch size %d, got %d", DefaultMaxBatchSize, cfg.MaxBatchSize)
+	}
+	if cfg.LibPath != DefaultLibPath {
+		t.Errorf("expected library %s, got %s", DefaultLibPath, cfg.LibPath)
+	}
+}
+
+func TestCheckModelInputs(t *testing.T) {
+	testCases := []struct {
+		name    string
+		inputs  []ort.InputOutputInfo
+		wantErr bool
+	}{
+		{"matching", []ort.InputOutputInfo{{Name: "input", Dimensions: ort.NewShape(1, NumFeatures)}}, false},
+		{"dynamic", []ort.InputOutputInfo{{Name: "input", Dimensions: ort.NewShape(-1, -1)}}, false},
+		{"too few", []ort.InputOutputInfo{{Name: "input", Dimensions: ort.NewShape(-1, 25)}}, true},
+		{"no input", []ort.InputOutputInfo{{Name: "features", Dimensions: ort.NewShape(1, NumFeatures)}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkModelInputs(tc.inputs)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
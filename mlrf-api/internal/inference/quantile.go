@@ -0,0 +1,113 @@
+package inference
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Quantiles holds a probabilistic forecast from the P10/P50/P90 quantile models.
+type Quantiles struct {
+	P10 float32 `json:"p10"`
+	P50 float32 `json:"p50"`
+	P90 float32 `json:"p90"`
+}
+
+// QuantileConfig holds the model paths for a quantile ensemble.
+type QuantileConfig struct {
+	P10Path string
+	P50Path string
+	P90Path string
+}
+
+// DefaultQuantileConfig returns a configuration without quantile models. Quantile
+// forecasts are disabled unless all three paths are set.
+func DefaultQuantileConfig() QuantileConfig {
+	return QuantileConfig{}
+}
+
+// Enabled reports whether all three quantile model paths are configured.
+func (c QuantileConfig) Enabled() bool {
+	return c.P10Path != "" && c.P50Path != "" && c.P90Path != ""
+}
+
+// QuantileEnsemble runs separately trained quantile models on the same features.
+type QuantileEnsemble struct {
+	p10 Inferencer
+	p50 Inferencer
+	p90 Inferencer
+}
+
+// NewQuantileEnsemble creates an ensemble from already loaded quantile models.
+func NewQuantileEnsemble(p10, p50, p90 Inferencer) *QuantileEnsemble {
+	return &QuantileEnsemble{p10: p10, p50: p50, p90: p90}
+}
+
+// LoadQuantileEnsemble loads the P10/P50/P90 ONNX models described by cfg.
+func LoadQuantileEnsemble(cfg QuantileConfig, sessionCfg SessionConfig) (*QuantileEnsemble, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("quantile ensemble requires P10, P50 and P90 model paths")
+	}
+
+	paths := []string{cfg.P10Path, cfg.P50Path, cfg.P90Path}
+	sessions := make([]*ONNXSession, 0, len(paths))
+	for _, path := range paths {
+		session, err := NewONNXSessionWithConfig(path, sessionCfg)
+		if err != nil {
+			for _, s := range sessions {
+				s.Close()
+			}
+			return nil, fmt.Errorf("failed to load quantile model %s: %w", path, err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return NewQuantileEnsemble(sessions[0], sessions[1], sessions[2]), nil
+}
+
+// Predict runs all three quantile models on a single feature vector.
+// Quantiles are sorted so independently trained models never cross.
+func (q *QuantileEnsemble) Predict(features []float32) (Quantiles, error) {
+	values := make([]float32, 3)
+	for i, model := range []Inferencer{q.p10, q.p50, q.p90} {
+		v, err := model.Predict(features)
+		if err != nil {
+			return Quantiles{}, fmt.Errorf("quantile model %d: %w", i, err)
+		}
+		values[i] = v
+	}
+	return sortedQuantiles(values[0], values[1], values[2]), nil
+}
+
+// PredictBatch runs all three quantile models on multiple feature vectors.
+func (q *QuantileEnsemble) PredictBatch(featureBatch [][]float32) ([]Quantiles, error) {
+	var outputs [3][]float32
+	for i, model := range []Inferencer{q.p10, q.p50, q.p90} {
+		preds, err := model.PredictBatch(featureBatch)
+		if err != nil {
+			return nil, fmt.Errorf("quantile model %d: %w", i, err)
+		}
+		outputs[i] = preds
+	}
+
+	results := make([]Quantiles, len(featureBatch))
+	for i := range results {
+		results[i] = sortedQuantiles(outputs[0][i], outputs[1][i], outputs[2][i])
+	}
+	return results, nil
+}
+
+// Close releases the quantile model sessions.
+func (q *QuantileEnsemble) Close() {
+	for _, model := range []Inferencer{q.p10, q.p50, q.p90} {
+		if c, ok := model.(interface{ Close() }); ok {
+			c.Close()
+		}
+	}
+}
+
+// sortedQuantiles orders the three quantile outputs ascending.
+func sortedQuantiles(a, b, c float32) Quantiles {
+	v := []float32{a, b, c}
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+	return Quantiles{P10: v[0], P50: v[1], P90: v[2]}
+}
@@ -0,0 +1,70 @@
+package inference
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuantileEnsemblePredict(t *testing.T) {
+	q := NewQuantileEnsemble(&fakeSession{prediction: 80}, &fakeSession{prediction: 100}, &fakeSession{prediction: 130})
+
+	got, err := q.Predict(make([]float32, NumFeatures))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != (Quantiles{P10: 80, P50: 100, P90: 130}) {
+		t.Errorf("unexpected quantiles: %+v", got)
+	}
+}
+
+func TestQuantileEnsembleNoCrossing(t *testing.T) {
+	// Independently trained models can cross; outputs must stay ordered
+	q := NewQuantileEnsemble(&fakeSession{prediction: 120}, &fakeSession{prediction: 100}, &fakeSession{prediction: 90})
+
+	got, err := q.PredictBatch([][]float32{make([]float32, NumFeatures), make([]float32, NumFeatures)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(got))
+	}
+	for _, qs := range got {
+		if qs.P10 != 90 || qs.P50 != 100 || qs.P90 != 120 {
+			t.Errorf("expected sorted quantiles, got %+v", qs)
+		}
+	}
+}
+
+func TestQuantileEnsembleError(t *testing.T) {
+	q := NewQuantileEnsemble(&fakeSession{prediction: 1}, &fakeSession{err: errors.New("boom")}, &fakeSession{prediction: 3})
+
+	if _, err := q.Predict(make([]float32, NumFeatures)); err == nil {
+		t.Error("expected error from failing quantile model")
+	}
+}
+
+func TestQuantileEnsembleClose(t *testing.T) {
+	sessions := []*fakeSession{{}, {}, {}}
+	q := NewQuantileEnsemble(sessions[0], sessions[1], sessions[2])
+	q.Close()
+
+	for i, s := range sessions {
+		if !s.closed {
+			t.Errorf("expected quantile session %d to be closed", i)
+		}
+	}
+}
+
+func TestQuantileConfigEnabled(t *testing.T) {
+	if DefaultQuantileConfig().Enabled() {
+		t.Error("expected quantiles disabled by default")
+	}
+	cfg := QuantileConfig{P10Path: "p10.onnx", P50Path: "p50.onnx"}
+	if cfg.Enabled() {
+		t.Error("expected quantiles disabled when a path is missing")
+	}
+	cfg.P90Path = "p90.onnx"
+	if !cfg.Enabled() {
+		t.Error("expected quantiles enabled when all paths are set")
+	}
+}
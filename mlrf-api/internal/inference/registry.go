@@ -0,0 +1,266 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ManifestEntry describes a single model in the registry manifest.
+type ManifestEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// Key returns the registry key for the entry ("name@version", or "name" if unversioned).
+func (e ManifestEntry) Key() string {
+	if e.Version == "" {
+		return e.Name
+	}
+	return e.Name + "@" + e.Version
+}
+
+// Manifest lists the models to load and which one serves by default.
+// Champion may be a bare name or a "name@version" key.
+type Manifest struct {
+	Champion string          `json:"champion"`
+	Models   []ManifestEntry `json:"models"`
+}
+
+// LoadManifest reads and validates a model manifest JSON file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if len(m.Models) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no models", path)
+	}
+	seen := make(map[string]bool)
+	for i, e := range m.Models {
+		if e.Name == "" || e.Path == "" {
+			return nil, fmt.Errorf("manifest model %d: name and path are required", i)
+		}
+		if seen[e.Key()] {
+			return nil, fmt.Errorf("manifest model %d: duplicate key %s", i, e.Key())
+		}
+		seen[e.Key()] = true
+	}
+	if m.Champion == "" {
+		m.Champion = m.Models[0].Key()
+	}
+	return &m, nil
+}
+
+// RegisteredModel describes a model loaded in the registry.
+type RegisteredModel struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	Path     string `json:"path"`
+	Champion bool   `json:"champion"`
+}
+
+type registryEntry struct {
+	entry ManifestEntry
+	model Inferencer
+}
+
+// Registry holds multiple loaded models keyed by name/version.
+// Thread-safe - lookups may run concurrently with registration.
+type Registry struct {
+	models   map[string]*registryEntry
+	champion string
+	mu       sync.RWMutex
+}
+
+// NewRegistry creates an empty model registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		models: make(map[string]*registryEntry),
+	}
+}
+
+// NewRegistryFromManifest loads every model listed in the manifest.
+// Each model is wrapped in a ReloadableSession so it can be hot-reloaded.
+func NewRegistryFromManifest(m *Manifest, cfg SessionConfig) (*Registry, error) {
+	r := NewRegistry()
+	for _, e := range m.Models {
+		session, err := NewReloadableSession(e.Path, cfg)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("model %s: %w", e.Key(), err)
+		}
+		r.Register(e, session)
+	}
+	if err := r.SetChampion(m.Champion); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds a model to the registry. The first registered model becomes
+// the champion until SetChampion is called.
+func (r *Registry) Register(e ManifestEntry, model Inferencer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models[e.Key()] = &registryEntry{entry: e, model: model}
+	if r.champion == "" {
+		r.champion = e.Key()
+	}
+}
+
+// SetChampion sets the default model used when a request names no model.
+func (r *Registry) SetChampion(ref string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.resolve(ref)
+	if !ok {
+		return fmt.Errorf("unknown model: %s", ref)
+	}
+	r.champion = e.entry.Key()
+	return nil
+}
+
+// Get returns the model for ref, which may be a "name@version" key or a bare name.
+// An empty ref returns the champion.
+func (r *Registry) Get(ref string) (Inferencer, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if ref == "" {
+		ref = r.champion
+	}
+	e, ok := r.resolve(ref)
+	if !ok {
+		return nil, "", false
+	}
+	return e.model, e.entry.Key(), true
+}
+
+// resolve looks up an entry by exact key, then by bare name. Caller must hold r.mu.
+func (r *Registry) resolve(ref string) (*registryEntry, bool) {
+	if e, ok := r.models[ref]; ok {
+		return e, true
+	}
+	if strings.Contains(ref, "@") {
+		return nil, false
+	}
+	// Bare name: pick the highest version registered under that name
+	var best *registryEntry
+	for _, e := range r.models {
+		if e.entry.Name == ref && (best == nil || e.entry.Version > best.entry.Version) {
+			best = e
+		}
+	}
+	return best, best != nil
+}
+
+// Champion returns the key of the default model.
+func (r *Registry) Champion() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.champion
+}
+
+// Models returns all registered models sorted by key.
+func (r *Registry) Models() []RegisteredModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]RegisteredModel, 0, len(r.models))
+	for key, e := range r.models {
+		out = append(out, RegisteredModel{
+			Key:      key,
+			Name:     e.entry.Name,
+			Version:  e.entry.Version,
+			Path:     e.entry.Path,
+			Champion: key == r.champion,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Default returns an Inferencer that always serves with the current champion, so
+// callers holding it follow SetChampion. It also reloads and describes the champion
+// when the champion is a ReloadableSession.
+func (r *Registry) Default() *ChampionModel {
+	return &ChampionModel{registry: r}
+}
+
+// ChampionModel serves predictions with the registry's current champion.
+type ChampionModel struct {
+	registry *Registry
+}
+
+// Predict runs inference with the champion.
+func (c *ChampionModel) Predict(features []float32) (float32, error) {
+	model, key, ok := c.registry.Get("")
+	if !ok {
+		return 0, fmt.Errorf("champion model %s not registered", key)
+	}
+	return model.Predict(features)
+}
+
+// PredictBatch runs batch inference with the champion.
+func (c *ChampionModel) PredictBatch(featureBatch [][]float32) ([]float32, error) {
+	model, key, ok := c.registry.Get("")
+	if !ok {
+		return nil, fmt.Errorf("champion model %s not registered", key)
+	}
+	return model.PredictBatch(featureBatch)
+}
+
+// Reload replaces the champion's model with the one at modelPath.
+func (c *ChampionModel) Reload(modelPath string) error {
+	model, key, _ := c.registry.Get("")
+	reloadable, ok := model.(interface{ Reload(string) error })
+	if !ok {
+		return fmt.Errorf("champion model %s does not support reload", key)
+	}
+	return reloadable.Reload(modelPath)
+}
+
+// ReloadChecked replaces the champion's model with the one at modelPath once it
+// passes check.
+func (c *ChampionModel) ReloadChecked(modelPath string, check func(Inferencer) error) error {
+	model, key, _ := c.registry.Get("")
+	reloadable, ok := model.(interface {
+		ReloadChecked(string, func(Inferencer) error) error
+	})
+	if !ok {
+		return fmt.Errorf("champion model %s does not support reload", key)
+	}
+	return reloadable.ReloadChecked(modelPath, check)
+}
+
+// Info describes the champion's loaded model, or is empty when the champion isn't a
+// ReloadableSession.
+func (c *ChampionModel) Info() ModelInfo {
+	model, _, _ := c.registry.Get("")
+	if described, ok := model.(interface{ Info() ModelInfo }); ok {
+		return described.Info()
+	}
+	return ModelInfo{}
+}
+
+// Close releases every registered model that holds native resources.
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.models {
+		if c, ok := e.model.(interface{ Close() }); ok {
+			c.Close()
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package inference
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("valid manifest defaults champion to first model", func(t *testing.T) {
+		path := filepath.Join(dir, "manifest.json")
+		os.WriteFile(path, []byte(`{"models":[
+			{"name":"lightgbm","version":"2024-01-01","path":"models/lgbm.onnx"},
+			{"name":"challenger","path":"models/challenger.onnx"}
+		]}`), 0644)
+
+		m, err := LoadManifest(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m.Champion != "lightgbm@2024-01-01" {
+			t.Errorf("expected champion lightgbm@2024-01-01, got %s", m.Champion)
+		}
+		if len(m.Models) != 2 {
+			t.Errorf("expected 2 models, got %d", len(m.Models))
+		}
+	})
+
+	invalid := map[string]string{
+		"empty":     `{"models":[]}`,
+		"no path":   `{"models":[{"name":"lightgbm"}]}`,
+		"duplicate": `{"models":[{"name":"a","path":"x"},{"name":"a","path":"y"}]}`,
+		"bad json":  `{models}`,
+	}
+	for name, body := range invalid {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			os.WriteFile(path, []byte(body), 0644)
+			if _, err := LoadManifest(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadManifest(filepath.Join(dir, "missing.json")); err == nil {
+			t.Error("expected error for missing manifest")
+		}
+	})
+}
+
+func TestRegistryGet(t *testing.T) {
+	r := NewRegistry()
+	r.Register(ManifestEntry{Name: "lightgbm", Version: "1", Path: "a.onnx"}, &fakeSession{prediction: 1})
+	r.Register(ManifestEntry{Name: "lightgbm", Version: "2", Path: "b.onnx"}, &fakeSession{prediction: 2})
+	r.Register(ManifestEntry{Name: "challenger", Path: "c.onnx"}, &fakeSession{prediction: 3})
+
+	testCases := []struct {
+		ref      string
+		key      string
+		expected float32
+	}{
+		{"", "lightgbm@1", 1},
+		{"lightgbm@2", "lightgbm@2", 2},
+		{"lightgbm", "lightgbm@2", 2},
+		{"challenger", "challenger", 3},
+	}
+	for _, tc := range testCases {
+		model, key, ok := r.Get(tc.ref)
+		if !ok {
+			t.Fatalf("ref %q: expected model", tc.ref)
+		}
+		if key != tc.key {
+			t.Errorf("ref %q: expected key %s, got %s", tc.ref, tc.key, key)
+		}
+		if pred, _ := model.Predict(nil); pred != tc.expected {
+			t.Errorf("ref %q: expected prediction %v, got %v", tc.ref, tc.expected, pred)
+		}
+	}
+
+	if _, _, ok := r.Get("unknown"); ok {
+		t.Error("expected unknown model lookup to fail")
+	}
+
+	if err := r.SetChampion("challenger"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, key, _ := r.Get(""); key != "challenger" {
+		t.Errorf("expected champion challenger, got %s", key)
+	}
+	if err := r.SetChampion("missing"); err == nil {
+		t.Error("expected error for unknown champion")
+	}
+
+	models := r.Models()
+	if len(models) != 3 || models[0].Key != "challenger" || !models[0].Champion {
+		t.Errorf("unexpected model list: %+v", models)
+	}
+}
+
+func TestRegistryDefaultFollowsChampion(t *testing.T) {
+	r := NewRegistry()
+	r.Register(ManifestEntry{Name: "lightgbm", Path: "a.onnx"}, &fakeSession{prediction: 1})
+	r.Register(ManifestEntry{Name: "challenger", Path: "b.onnx"}, &fakeSession{prediction: 2})
+
+	model := r.Default()
+	if pred, _ := model.Predict(nil); pred != 1 {
+		t.Errorf("expected the first model's prediction, got %v", pred)
+	}
+	r.SetChampion("challenger")
+	if preds, _ := model.PredictBatch([][]float32{nil}); preds[0] != 2 {
+		t.Errorf("expected the new champion's prediction, got %v", preds)
+	}
+	if err := model.Reload("c.onnx"); err == nil {
+		t.Error("expected reload to fail for a champion that can't reload")
+	}
+	if info := model.Info(); info.Path != "" {
+		t.Errorf("expected no info, got %+v", info)
+	}
+}
@@ -0,0 +1,184 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ModelInfo describes the currently loaded model.
+type ModelInfo struct {
+	Path     string    `json:"path"`
+	Version  string    `json:"version"` // Prefix of the file's SHA-256, the same wherever it is loaded
+	LoadedAt time.Time `json:"loaded_at"`
+
+	// Outcome of the export-time self-test, if one is configured
+	SelfTest *SelfTestResult `json:"self_test,omitempty"`
+
+	// Hash and ONNX header of the model file
+	File *ModelFile `json:"file,omitempty"`
+}
+
+// versionLength is the number of hex digits of the model file hash used as its version.
+const versionLength = 16
+
+// sessionLoader creates a new session from a model path.
+// Replaced in tests to avoid requiring the ONNX Runtime shared library.
+type sessionLoader func(modelPath string) (closableInferencer, error)
+
+// closableInferencer is an Inferencer whose resources can be released.
+type closableInferencer interface {
+	Inferencer
+	Close()
+}
+
+// ReloadableSession wraps an inference session that can be atomically swapped
+// for a new model without restarting the server.
+// Thread-safe - in-flight predictions finish on the old session before it is destroyed.
+type ReloadableSession struct {
+	current      closableInferencer
+	info         ModelInfo
+	load         sessionLoader
+	selfTestPath string
+	mu           sync.RWMutex
+}
+
+// Verify ReloadableSession implements Inferencer
+var _ Inferencer = (*ReloadableSession)(nil)
+
+// NewReloadableSession loads the model at modelPath and wraps it for hot reload.
+func NewReloadableSession(modelPath string, cfg SessionConfig) (*ReloadableSession, error) {
+	loader := func(path string) (closableInferencer, error) {
+		return NewONNXSessionWithConfig(path, cfg)
+	}
+	return newReloadableSession(modelPath, loader)
+}
+
+func newReloadableSession(modelPath string, loader sessionLoader) (*ReloadableSession, error) {
+	r := &ReloadableSession{load: loader}
+	if err := r.Reload(modelPath); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Predict runs inference on the current model.
+func (r *ReloadableSession) Predict(features []float32) (float32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Predict(features)
+}
+
+// PredictBatch runs batched inference on the current model.
+func (r *ReloadableSession) PredictBatch(featureBatch [][]float32) ([]float32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.PredictBatch(featureBatch)
+}
+
+// Reload loads the model at modelPath, verifies it with a smoke prediction and
+// the self-test, if one is set, then swaps it in and destroys the previous session.
+// On any failure the current model keeps serving.
+func (r *ReloadableSession) Reload(modelPath string) error {
+	return r.ReloadChecked(modelPath, nil)
+}
+
+// ReloadChecked is Reload with an extra check run on the new model before it is
+// swapped in; when check fails, the current model keeps serving.
+func (r *ReloadableSession) ReloadChecked(modelPath string, check func(Inferencer) error) error {
+	next, err := r.load(modelPath)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+
+	if err := smokeTest(next); err != nil {
+		next.Close()
+		return fmt.Errorf("model smoke test failed: %w", err)
+	}
+
+	r.mu.RLock()
+	selfTestPath := r.selfTestPath
+	r.mu.RUnlock()
+	result, err := runSelfTest(selfTestPath, next)
+	if err != nil {
+		next.Close()
+		return err
+	}
+
+	if check != nil {
+		if err := check(next); err != nil {
+			next.Close()
+			return err
+		}
+	}
+
+	file, err := ReadModelFile(modelPath)
+	if err != nil {
+		next.Close()
+		return fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	prev := r.current
+	r.current = next
+	r.info = ModelInfo{
+		Path:     modelPath,
+		Version:  file.SHA256[:versionLength],
+		LoadedAt: now,
+		SelfTest: result,
+		File:     file,
+	}
+	r.mu.Unlock()
+
+	// Write lock above guarantees no prediction is still running on prev
+	if prev != nil {
+		prev.Close()
+	}
+	return nil
+}
+
+// SetSelfTestPath sets the self-test file that every model must pass, and runs it on
+// the current model. A failure is recorded in Info for readiness checks, but the model
+// keeps serving; a missing file disables the self-test.
+func (r *ReloadableSession) SetSelfTestPath(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.selfTestPath = path
+	if r.current == nil {
+		return nil
+	}
+	result, err := runSelfTest(path, r.current)
+	r.info.SelfTest = result
+	return err
+}
+
+// Info returns metadata about the currently loaded model.
+func (r *ReloadableSession) Info() ModelInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.info
+}
+
+// Close releases the current session.
+func (r *ReloadableSession) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
+
+// smokeTest runs a zero feature vector through the model and checks the output is finite.
+func smokeTest(s Inferencer) error {
+	pred, err := s.Predict(make([]float32, NumFeatures))
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(float64(pred)) || math.IsInf(float64(pred), 0) {
+		return fmt.Errorf("non-finite prediction %v", pred)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSession is a closableInferencer returning a fixed prediction.
+type fakeSession struct {
+	prediction float32
+	err        error
+	closed     bool
+}
+
+func (f *fakeSession) Predict(features []float32) (float32, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.prediction, nil
+}
+
+func (f *fakeSession) PredictBatch(featureBatch [][]float32) ([]float32, error) {
+	results := make([]float32, len(featureBatch))
+	for i := range featureBatch {
+		results[i] = f.prediction
+	}
+	return results, nil
+}
+
+func (f *fakeSession) Close() {
+	f.closed = true
+}
+
+// fakeLoader returns sessions keyed by model path.
+func fakeLoader(sessions map[string]*fakeSession) sessionLoader {
+	return func(path string) (closableInferencer, error) {
+		s, ok := sessions[path]
+		if !ok {
+			return nil, fmt.Errorf("model file not found: %s", path)
+		}
+		return s, nil
+	}
+}
+
+// writeModels writes a model file for each named session to a temporary directory
+// and returns a loader of the sessions by path, and the path of a name.
+func writeModels(t *testing.T, sessions map[string]*fakeSession) (sessionLoader, func(name string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	byPath := make(map[string]*fakeSession)
+	for name, s := range sessions {
+		if err := os.WriteFile(path(name), []byte("model "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		byPath[path(name)] = s
+	}
+	return fakeLoader(byPath), path
+}
+
+func TestReloadableSessionSwap(t *testing.T) {
+	v1 := &fakeSession{prediction: 1}
+	v2 := &fakeSession{prediction: 2}
+	load, path := writeModels(t, map[string]*fakeSession{"v1.onnx": v1, "v2.onnx": v2})
+	r, err := newReloadableSession(path("v1.onnx"), load)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pred, _ := r.Predict(make([]float32, NumFeatures)); pred != 1 {
+		t.Errorf("expected prediction 1, got %v", pred)
+	}
+	v1Version := r.Info().Version
+
+	if err := r.Reload(path("v2.onnx")); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+
+	if pred, _ := r.Predict(make([]float32, NumFeatures)); pred != 2 {
+		t.Errorf("expected prediction 2 after reload, got %v", pred)
+	}
+	if !v1.closed {
+		t.Error("expected old session to be closed after swap")
+	}
+	info := r.Info()
+	if info.Path != path("v2.onnx") {
+		t.Errorf("expected path v2.onnx, got %s", info.Path)
+	}
+
+	// Versions come from the file content, not the time it was loaded
+	if info.Version == v1Version || info.File == nil || info.Version != info.File.SHA256[:versionLength] {
+		t.Errorf("expected the version to be the file hash, got %q (v1 %q)", info.Version, v1Version)
+	}
+	if err := os.WriteFile(path("copy.onnx"), []byte("model v1.onnx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := newReloadableSession(path("copy.onnx"), func(string) (closableInferencer, error) { return &fakeSession{}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Info().Version != v1Version {
+		t.Errorf("expected a copy of v1 to have its version %q, got %q", v1Version, copied.Info().Version)
+	}
+}
+
+func TestReloadableSessionCheckedKeepsModel(t *testing.T) {
+	v1 := &fakeSession{prediction: 1}
+	v2 := &fakeSession{prediction: 2}
+	load, path := writeModels(t, map[string]*fakeSession{"v1.onnx": v1, "v2.onnx": v2})
+	r, err := newReloadableSession(path("v1.onnx"), load)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var checked float32
+	err = r.ReloadChecked(path("v2.onnx"), func(next Inferencer) error {
+		checked, _ = next.Predict(make([]float32, NumFeatures))
+		return fmt.Errorf("wrong feature count")
+	})
+	if err == nil {
+		t.Fatal("expected the failed check to fail the reload")
+	}
+	if checked != 2 {
+		t.Errorf("expected the check to run on the new model, got %v", checked)
+	}
+	if pred, _ := r.Predict(make([]float32, NumFeatures)); pred != 1 || v1.closed || !v2.closed {
+		t.Errorf("expected v1 to keep serving and v2 to be closed, got %v", pred)
+	}
+}
+
+func TestReloadableSessionKeepsModelOnFailure(t *testing.T) {
+	v1 := &fakeSession{prediction: 1}
+	broken := &fakeSession{err: fmt.Errorf("corrupt model")}
+	nan := &fakeSession{prediction: float32(math.NaN())}
+	load, path := writeModels(t, map[string]*fakeSession{
+		"v1.onnx":     v1,
+		"broken.onnx": broken,
+		"nan.onnx":    nan,
+	})
+	r, err := newReloadableSession(path("v1.onnx"), load)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"missing.onnx", "broken.onnx", "nan.onnx"} {
+		if err := r.Reload(path(name)); err == nil {
+			t.Errorf("expected reload of %s to fail", name)
+		}
+	}
+
+	if pred, _ := r.Predict(make([]float32, NumFeatures)); pred != 1 {
+		t.Errorf("expected original model to keep serving, got %v", pred)
+	}
+	if v1.closed {
+		t.Error("original session must not be closed after failed reload")
+	}
+	if !broken.closed || !nan.closed {
+		t.Error("expected rejected sessions to be closed")
+	}
+}
@@ -0,0 +1,104 @@
+package inference
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// SelfTestCase is a feature vector with the prediction range recorded when the model
+// was exported.
+type SelfTestCase struct {
+	Features []float32 `json:"features"`
+	Expected float32   `json:"expected"` // Prediction of the training framework
+	Min      float32   `json:"min"`      // Lowest acceptable ONNX prediction
+	Max      float32   `json:"max"`      // Highest acceptable ONNX prediction
+}
+
+// SelfTest is the set of known inputs a model must reproduce before it serves,
+// written by mlrf-ml next to the ONNX model.
+type SelfTest struct {
+	Cases []SelfTestCase `json:"cases"`
+}
+
+// SelfTestResult reports the outcome of the last self-test of the current model.
+type SelfTestResult struct {
+	Path   string `json:"path"`
+	Passed bool   `json:"passed"`
+	Cases  int    `json:"cases"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LoadSelfTest reads and validates a self-test file.
+func LoadSelfTest(path string) (*SelfTest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read self-test: %w", err)
+	}
+
+	var t SelfTest
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse self-test: %w", err)
+	}
+	if len(t.Cases) == 0 {
+		return nil, fmt.Errorf("self-test %s lists no cases", path)
+	}
+	for i, c := range t.Cases {
+		if len(c.Features) != NumFeatures {
+			return nil, fmt.Errorf("self-test case %d: expected %d features, got %d", i, NumFeatures, len(c.Features))
+		}
+		if c.Min > c.Max {
+			return nil, fmt.Errorf("self-test case %d: min %v is above max %v", i, c.Min, c.Max)
+		}
+	}
+	return &t, nil
+}
+
+// Run predicts every case with m and returns an error describing the first prediction
+// outside its recorded range.
+func (t *SelfTest) Run(m Inferencer) error {
+	batch := make([][]float32, len(t.Cases))
+	for i, c := range t.Cases {
+		batch[i] = c.Features
+	}
+	preds, err := m.PredictBatch(batch)
+	if err != nil {
+		return err
+	}
+	if len(preds) != len(t.Cases) {
+		return fmt.Errorf("expected %d predictions, got %d", len(t.Cases), len(preds))
+	}
+
+	for i, c := range t.Cases {
+		p := preds[i]
+		if math.IsNaN(float64(p)) || p < c.Min || p > c.Max {
+			return fmt.Errorf("case %d: prediction %v outside the exported range [%v, %v] (expected %v)", i, p, c.Min, c.Max, c.Expected)
+		}
+	}
+	return nil
+}
+
+// runSelfTest loads the self-test at path and runs it with m. A missing file skips the
+// self-test, returning a nil result.
+func runSelfTest(path string, m Inferencer) (*SelfTestResult, error) {
+	if path == "" {
+		return nil, nil
+	}
+	t, err := LoadSelfTest(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return &SelfTestResult{Path: path, Error: err.Error()}, err
+	}
+
+	result := &SelfTestResult{Path: path, Cases: len(t.Cases)}
+	if err := t.Run(m); err != nil {
+		result.Error = err.Error()
+		return result, fmt.Errorf("model self-test failed: %w", err)
+	}
+	result.Passed = true
+	return result, nil
+}
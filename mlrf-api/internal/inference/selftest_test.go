@@ -0,0 +1,85 @@
+package inference
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSelfTest(t *testing.T, cases []SelfTestCase) string {
+	t.Helper()
+	data, err := json.Marshal(SelfTest{Cases: cases})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model_selftest.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSelfTestInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cases []SelfTestCase
+		want  string
+	}{
+		"no cases":     {cases: nil, want: "no cases"},
+		"short vector": {cases: []SelfTestCase{{Features: []float32{1}, Max: 1}}, want: "expected 27 features"},
+		"empty range":  {cases: []SelfTestCase{{Features: make([]float32, NumFeatures), Min: 2, Max: 1}}, want: "above max"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadSelfTest(writeSelfTest(t, tc.cases)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error mentioning %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestReloadableSessionSelfTest(t *testing.T) {
+	v1 := &fakeSession{prediction: 10}
+	v2 := &fakeSession{prediction: 11}
+	drifted := &fakeSession{prediction: 50}
+	load, model := writeModels(t, map[string]*fakeSession{"v1.onnx": v1, "v2.onnx": v2, "drifted.onnx": drifted, "failing.onnx": {prediction: 50}})
+	r, err := newReloadableSession(model("v1.onnx"), load)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing file disables the self-test
+	if err := r.SetSelfTestPath(filepath.Join(t.TempDir(), "missing.json")); err != nil || r.Info().SelfTest != nil {
+		t.Fatalf("expected no self-test, got %v %+v", err, r.Info().SelfTest)
+	}
+
+	path := writeSelfTest(t, []SelfTestCase{{Features: make([]float32, NumFeatures), Expected: 10.2, Min: 9.5, Max: 11.5}})
+	if err := r.SetSelfTestPath(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := r.Info().SelfTest; st == nil || !st.Passed || st.Cases != 1 {
+		t.Errorf("expected a passing self-test, got %+v", st)
+	}
+
+	if err := r.Reload(model("v2.onnx")); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+	if err := r.Reload(model("drifted.onnx")); err == nil || !strings.Contains(err.Error(), "outside the exported range") {
+		t.Errorf("expected the drifted model rejected, got %v", err)
+	}
+	if !drifted.closed || r.Info().Path != model("v2.onnx") {
+		t.Errorf("expected v2 to keep serving, got %s", r.Info().Path)
+	}
+
+	// A model failing at startup keeps serving but reports the failure
+	r, err = newReloadableSession(model("failing.onnx"), load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetSelfTestPath(path); err == nil {
+		t.Error("expected a self-test error")
+	}
+	if st := r.Info().SelfTest; st == nil || st.Passed || st.Error == "" {
+		t.Errorf("expected a failed self-test, got %+v", st)
+	}
+}
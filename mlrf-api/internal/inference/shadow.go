@@ -0,0 +1,205 @@
+package inference
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// ShadowConfig holds shadow-mode inference configuration.
+type ShadowConfig struct {
+	// Challenger is the registry name of the model to shadow.
+	Challenger string
+	// Workers is the number of goroutines running challenger inference.
+	Workers int
+	// QueueSize bounds pending shadow jobs; jobs beyond it are dropped.
+	QueueSize int
+	// LogSize is the number of recent comparisons kept for Records; 0 keeps none.
+	LogSize int
+}
+
+// DefaultShadowConfig returns the default shadow configuration, without a challenger.
+func DefaultShadowConfig() ShadowConfig {
+	return ShadowConfig{
+		Workers:   2,
+		QueueSize: 1000,
+		LogSize:   100000,
+	}
+}
+
+// ShadowInput is a champion prediction to replay against the challenger.
+type ShadowInput struct {
+	StoreNbr        int
+	Family          string
+	Date            string
+	Features        []float32
+	ChampionKey     string // Registry key of the champion that served the prediction
+	Champion        float32
+	ChampionLatency time.Duration // Inference time; zero when the prediction was served from cache
+}
+
+// ShadowRecord is the outcome of replaying one champion prediction.
+type ShadowRecord struct {
+	Time              time.Time
+	StoreNbr          int
+	Family            string
+	Date              string
+	ChampionKey       string
+	ChallengerKey     string
+	Champion          float32
+	Challenger        float32 // Zero when Err is set
+	ChampionLatency   time.Duration
+	ChallengerLatency time.Duration
+	Err               bool // The challenger failed
+}
+
+// ShadowRunner asynchronously replays champion predictions against a challenger
+// model, records the deltas as Prometheus histograms and keeps the most recent
+// comparisons for Records.
+// Submit never blocks the request path; jobs are dropped when the queue is full.
+type ShadowRunner struct {
+	challenger Inferencer
+	name       string
+	mu         sync.RWMutex // guards challenger and name, swapped by SetChallenger
+	jobs       chan ShadowInput
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+
+	logMu   sync.Mutex
+	log     []ShadowRecord // ring buffer of LogSize comparisons
+	logNext int
+	logFull bool
+}
+
+// NewShadowRunner starts workers that run challenger inference in the background.
+func NewShadowRunner(name string, challenger Inferencer, cfg ShadowConfig) *ShadowRunner {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1
+	}
+
+	s := &ShadowRunner{
+		challenger: challenger,
+		name:       name,
+		jobs:       make(chan ShadowInput, cfg.QueueSize),
+		log:        make([]ShadowRecord, cfg.LogSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Submit queues a champion prediction for shadow evaluation.
+// Returns false if the job was dropped because the queue is full.
+func (s *ShadowRunner) Submit(in ShadowInput) bool {
+	// Copy features - callers may reuse the slice after returning
+	in.Features = append([]float32(nil), in.Features...)
+
+	select {
+	case s.jobs <- in:
+		return true
+	default:
+		metrics.RecordShadowResult(s.Name(), "dropped")
+		return false
+	}
+}
+
+// Name returns the registry name of the challenger model.
+func (s *ShadowRunner) Name() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.name
+}
+
+// SetChallenger replaces the challenger, e.g. with the former champion after a
+// promotion. Queued jobs run against the new challenger.
+func (s *ShadowRunner) SetChallenger(name string, challenger Inferencer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+	s.challenger = challenger
+}
+
+// Records returns the logged comparisons made at or after since, oldest first.
+func (s *ShadowRunner) Records(since time.Time) []ShadowRecord {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+	ordered := s.log[:s.logNext]
+	if s.logFull {
+		ordered = append(append([]ShadowRecord(nil), s.log[s.logNext:]...), s.log[:s.logNext]...)
+	}
+	var out []ShadowRecord
+	for _, rec := range ordered {
+		if !rec.Time.Before(since) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// record appends a comparison to the log, overwriting the oldest once it is full.
+func (s *ShadowRunner) record(rec ShadowRecord) {
+	if len(s.log) == 0 {
+		return
+	}
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+	s.log[s.logNext] = rec
+	s.logNext++
+	if s.logNext == len(s.log) {
+		s.logNext = 0
+		s.logFull = true
+	}
+}
+
+// worker runs queued shadow jobs until the runner is closed.
+func (s *ShadowRunner) worker() {
+	defer s.wg.Done()
+	for job := range s.jobs {
+		s.mu.RLock()
+		name, challenger := s.name, s.challenger
+		s.mu.RUnlock()
+
+		rec := ShadowRecord{
+			StoreNbr:        job.StoreNbr,
+			Family:          job.Family,
+			Date:            job.Date,
+			ChampionKey:     job.ChampionKey,
+			ChallengerKey:   name,
+			Champion:        job.Champion,
+			ChampionLatency: job.ChampionLatency,
+		}
+		// Labelled with the challenger, so its latency and errors compare with the champion's
+		ctx := WithMetricLabels(context.Background(), name, job.Family)
+		start := time.Now()
+		pred, err := Predict(ctx, challenger, job.Features)
+		rec.ChallengerLatency = time.Since(start)
+		rec.Time = time.Now()
+		if err != nil {
+			log.Debug().Err(err).Str("challenger", name).Msg("Shadow inference failed")
+			metrics.RecordShadowResult(name, "error")
+			rec.Err = true
+			s.record(rec)
+			continue
+		}
+		metrics.RecordShadowDelta(name, job.Champion, pred)
+		rec.Challenger = pred
+		s.record(rec)
+	}
+}
+
+// Close stops accepting jobs and waits for queued jobs to finish.
+func (s *ShadowRunner) Close() {
+	s.closeOnce.Do(func() {
+		close(s.jobs)
+	})
+	s.wg.Wait()
+}
@@ -0,0 +1,121 @@
+package inference
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// countingSession records how many predictions it served.
+type countingSession struct {
+	fakeSession
+	mu    sync.Mutex
+	calls int
+	block chan struct{}
+}
+
+func (c *countingSession) Predict(features []float32) (float32, error) {
+	if c.block != nil {
+		<-c.block
+	}
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return c.fakeSession.Predict(features)
+}
+
+func TestShadowRunnerRecordsDeltas(t *testing.T) {
+	challenger := &countingSession{fakeSession: fakeSession{prediction: 120}}
+	initialOK := testutil.ToFloat64(metrics.ShadowPredictions.WithLabelValues("shadow-test", "ok"))
+	predictions := metrics.PredictionCount.WithLabelValues("shadow-test", "GROCERY I")
+	initialPredictions := testutil.ToFloat64(predictions)
+
+	s := NewShadowRunner("shadow-test", challenger, ShadowConfig{Workers: 2, QueueSize: 10})
+	for i := 0; i < 5; i++ {
+		if !s.Submit(ShadowInput{Family: "GROCERY I", Features: make([]float32, NumFeatures), Champion: 100}) {
+			t.Fatal("unexpected dropped job")
+		}
+	}
+	s.Close()
+
+	if challenger.calls != 5 {
+		t.Errorf("expected 5 challenger calls, got %d", challenger.calls)
+	}
+	if v := testutil.ToFloat64(metrics.ShadowPredictions.WithLabelValues("shadow-test", "ok")) - initialOK; v != 5 {
+		t.Errorf("expected 5 ok shadow results, got %v", v)
+	}
+	if v := testutil.ToFloat64(predictions) - initialPredictions; v != 5 {
+		t.Errorf("expected 5 challenger predictions labelled by challenger and family, got %v", v)
+	}
+}
+
+func TestShadowRunnerDropsWhenFull(t *testing.T) {
+	challenger := &countingSession{block: make(chan struct{})}
+	s := NewShadowRunner("shadow-full", challenger, ShadowConfig{Workers: 1, QueueSize: 1})
+
+	// First job is taken by the blocked worker, second fills the queue
+	accepted := 0
+	for i := 0; i < 5; i++ {
+		if s.Submit(ShadowInput{Family: "GROCERY I", Features: make([]float32, NumFeatures), Champion: 1}) {
+			accepted++
+		}
+	}
+	close(challenger.block)
+	s.Close()
+
+	if accepted >= 5 {
+		t.Errorf("expected some jobs to be dropped, accepted %d", accepted)
+	}
+}
+
+func TestShadowRunnerRecordsErrors(t *testing.T) {
+	challenger := &countingSession{fakeSession: fakeSession{err: fmt.Errorf("boom")}}
+	initial := testutil.ToFloat64(metrics.ShadowPredictions.WithLabelValues("shadow-err", "error"))
+
+	s := NewShadowRunner("shadow-err", challenger, ShadowConfig{Workers: 1, QueueSize: 5, LogSize: 5})
+	s.Submit(ShadowInput{Family: "GROCERY I", Features: make([]float32, NumFeatures), Champion: 1})
+	s.Close()
+
+	if v := testutil.ToFloat64(metrics.ShadowPredictions.WithLabelValues("shadow-err", "error")) - initial; v != 1 {
+		t.Errorf("expected 1 error shadow result, got %v", v)
+	}
+	if recs := s.Records(time.Time{}); len(recs) != 1 || !recs[0].Err || recs[0].ChallengerKey != "shadow-err" {
+		t.Errorf("expected the failure to be logged, got %+v", recs)
+	}
+}
+
+func TestShadowRunnerLog(t *testing.T) {
+	challenger := &countingSession{fakeSession: fakeSession{prediction: 120}}
+	s := NewShadowRunner("shadow-log", challenger, ShadowConfig{Workers: 1, QueueSize: 10, LogSize: 3})
+	for i := 1; i <= 5; i++ {
+		s.Submit(ShadowInput{StoreNbr: i, Family: "DAIRY", Date: "2017-08-16", Features: make([]float32, NumFeatures), ChampionKey: "v1", Champion: 100})
+	}
+	s.Close()
+
+	// The oldest comparisons are overwritten once the log is full
+	recs := s.Records(time.Time{})
+	if len(recs) != 3 || recs[0].StoreNbr != 3 || recs[2].StoreNbr != 5 {
+		t.Fatalf("expected the last 3 comparisons oldest first, got %+v", recs)
+	}
+	if r := recs[0]; r.ChampionKey != "v1" || r.ChallengerKey != "shadow-log" || r.Champion != 100 || r.Challenger != 120 || r.Err {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if recs := s.Records(time.Now().Add(time.Minute)); len(recs) != 0 {
+		t.Errorf("expected no records after since, got %d", len(recs))
+	}
+}
+
+func TestShadowRunnerSetChallenger(t *testing.T) {
+	s := NewShadowRunner("old", &countingSession{fakeSession: fakeSession{prediction: 1}}, ShadowConfig{Workers: 1, QueueSize: 10, LogSize: 10})
+	s.SetChallenger("new", &countingSession{fakeSession: fakeSession{prediction: 2}})
+	s.Submit(ShadowInput{Family: "DAIRY", Features: make([]float32, NumFeatures)})
+	s.Close()
+
+	if recs := s.Records(time.Time{}); s.Name() != "new" || len(recs) != 1 || recs[0].ChallengerKey != "new" || recs[0].Challenger != 2 {
+		t.Errorf("expected the new challenger to be used, got %s %+v", s.Name(), recs)
+	}
+}
@@ -0,0 +1,86 @@
+package inference
+
+import (
+	"context"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/mlrf/mlrf-api/internal/tracing"
+)
+
+// metricLabels identify the model and families of inference calls in the metrics.
+type metricLabels struct {
+	model    string
+	families []string
+}
+
+type metricLabelsKey struct{}
+
+// WithMetricLabels returns a context labelling the inference metrics of Predict and
+// PredictBatch calls made with it by model, a registry key or "" for the default
+// model, and families: one family for every row, or the family of each row of a batch.
+func WithMetricLabels(ctx context.Context, model string, families ...string) context.Context {
+	return context.WithValue(ctx, metricLabelsKey{}, metricLabels{model: model, families: families})
+}
+
+// rowFamilies returns the model labelled by ctx and the family of each of n rows, ""
+// where unknown.
+func rowFamilies(ctx context.Context, n int) (string, []string) {
+	labels, _ := ctx.Value(metricLabelsKey{}).(metricLabels)
+	families := make([]string, n)
+	switch len(labels.families) {
+	case n:
+		copy(families, labels.families)
+	case 1:
+		for i := range families {
+			families[i] = labels.families[0]
+		}
+	}
+	return labels.model, families
+}
+
+// Predict runs m.Predict in an "inference.predict" span recording the inference time,
+// and records the call in the inference metrics under the labels of ctx. It returns
+// ctx's error without running the model once ctx is done, such as past the request's
+// deadline.
+func Predict(ctx context.Context, m Inferencer, features []float32) (float32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	_, span := tracing.Start(ctx, "inference.predict")
+	start := time.Now()
+	prediction, err := m.Predict(features)
+	elapsed := time.Since(start)
+	span.SetAttributes(tracing.AttrInferenceMs.Float64(float64(elapsed.Microseconds()) / 1000))
+	model, families := rowFamilies(ctx, 1)
+	if err == nil {
+		span.SetAttributes(tracing.AttrPrediction.Float64(float64(prediction)))
+		metrics.RecordInference(model, families[0], elapsed.Seconds())
+	} else {
+		metrics.RecordInferenceError(model, families)
+	}
+	tracing.End(span, err)
+	return prediction, err
+}
+
+// PredictBatch runs m.PredictBatch in an "inference.predict_batch" span recording the
+// batch size and inference time, and records the call in the inference metrics under
+// the labels of ctx. Like Predict, it returns ctx's error once ctx is done.
+func PredictBatch(ctx context.Context, m Inferencer, featureBatch [][]float32) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span := tracing.Start(ctx, "inference.predict_batch", tracing.AttrBatchSize.Int(len(featureBatch)))
+	start := time.Now()
+	predictions, err := m.PredictBatch(featureBatch)
+	elapsed := time.Since(start)
+	span.SetAttributes(tracing.AttrInferenceMs.Float64(float64(elapsed.Microseconds()) / 1000))
+	model, families := rowFamilies(ctx, len(featureBatch))
+	if err == nil {
+		metrics.RecordBatchInference(model, families, elapsed.Seconds())
+	} else {
+		metrics.RecordInferenceError(model, families)
+	}
+	tracing.End(span, err)
+	return predictions, err
+}
@@ -0,0 +1,407 @@
+// Package ingest consumes feature rows streamed by the pipeline over Kafka or NATS
+// JetStream and applies them to the feature store incrementally, instead of waiting
+// for the next parquet drop.
+//
+// Each message is one FeatureRow as a JSON object keyed by the parquet column names,
+// with the date as YYYY-MM-DD. Messages are applied in batches; once a batch is
+// applied, the next offset of each partition is written to a checkpoint file, and
+// consumption resumes from it after a restart. Delivery is at least once: messages
+// consumed after the last checkpoint are applied again, replacing the same rows.
+// Caches are invalidated for applied rows at most once per InvalidateInterval.
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Supported brokers.
+const (
+	BrokerKafka = "kafka"
+	BrokerNATS  = "nats"
+)
+
+// Positions consumption starts from without a checkpoint.
+const (
+	StartEarliest = "earliest" // The oldest retained message
+	StartLatest   = "latest"   // Messages published after the consumer starts
+)
+
+// Config holds streamed feature consumer configuration.
+type Config struct {
+	Broker         string        // BrokerKafka or BrokerNATS; empty disables ingestion
+	URL            string        // Comma-separated Kafka brokers, or NATS server URLs
+	Topic          string        // Kafka topic, or NATS JetStream subject
+	Start          string        // StartEarliest or StartLatest, without a checkpoint
+	CheckpointPath string        // JSON file the consumed offsets are written to
+	BatchSize      int           // Rows applied at once
+	FlushInterval  time.Duration // Maximum time a consumed row waits before being applied
+
+	// Minimum time between cache invalidations for applied rows. Rows are visible to
+	// lookups as soon as they are applied; results cached from the previous features
+	// are dropped at most this often, so a steady stream doesn't defeat the cache.
+	InvalidateInterval time.Duration
+}
+
+// DefaultConfig returns the default consumer configuration, without a broker.
+func DefaultConfig() Config {
+	return Config{
+		Topic:          "mlrf.features",
+		Start:          StartEarliest,
+		CheckpointPath: "data/ingest_checkpoint.json",
+		BatchSize:      1000,
+		FlushInterval:  5 * time.Second,
+
+		InvalidateInterval: time.Minute,
+	}
+}
+
+// Message is one message consumed from a partition. NATS subjects have a single
+// partition, 0, whose offsets are stream sequence numbers.
+type Message struct {
+	Partition int
+	Offset    int64
+	Lag       int64 // Messages published to the partition after this one
+	Value     []byte
+}
+
+// Source delivers messages in order within each partition.
+type Source interface {
+	// Messages returns the channel messages are delivered on. It is closed by Close.
+	Messages() <-chan Message
+	Close() error
+}
+
+// Open connects to cfg.Broker, resuming from the checkpoint in cfg.CheckpointPath
+// when it was written for the same broker and topic.
+func Open(cfg Config) (Source, Checkpoint, error) {
+	cp, err := LoadCheckpoint(cfg.CheckpointPath)
+	if err != nil {
+		return nil, Checkpoint{}, err
+	}
+	if len(cp.Offsets) > 0 && (cp.Broker != cfg.Broker || cp.Topic != cfg.Topic) {
+		log.Warn().
+			Str("checkpoint_broker", cp.Broker).
+			Str("checkpoint_topic", cp.Topic).
+			Msg("Ingest checkpoint is for another topic, ignoring it")
+		cp = Checkpoint{}
+	}
+	cp.Broker, cp.Topic = cfg.Broker, cfg.Topic
+
+	var src Source
+	switch cfg.Broker {
+	case BrokerKafka:
+		src, err = NewKafkaSource(cfg, cp.Offsets)
+	case BrokerNATS:
+		src, err = NewNATSSource(cfg, cp.Offsets)
+	default:
+		err = fmt.Errorf("unknown ingest broker %q", cfg.Broker)
+	}
+	if err != nil {
+		return nil, Checkpoint{}, err
+	}
+	return src, cp, nil
+}
+
+// Checkpoint records the next offset to consume from each partition.
+type Checkpoint struct {
+	Broker    string        `json:"broker"`
+	Topic     string        `json:"topic"`
+	Offsets   map[int]int64 `json:"offsets"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// LoadCheckpoint reads the checkpoint at path; a missing file is an empty checkpoint.
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Checkpoint{}, nil
+	}
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("read ingest checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return Checkpoint{}, fmt.Errorf("parse ingest checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// save writes the checkpoint to a temporary file and renames it over path, so a
+// crash leaves either the previous or the new checkpoint.
+func (cp Checkpoint) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create checkpoint directory: %w", err)
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("write ingest checkpoint: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write ingest checkpoint: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync ingest checkpoint: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write ingest checkpoint: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Apply merges a batch of rows into the feature store. It returns an error wrapping
+// features.ErrNotLoaded while the store can't take rows yet, so the batch is retried.
+type Apply func(ctx context.Context, rows []features.FeatureRow) (features.AppendResult, error)
+
+// Invalidate drops results computed from the features before rows were applied. The
+// consumer calls it after applying rows, at most once per Config.InvalidateInterval.
+type Invalidate func(ctx context.Context)
+
+// Status reports the consumer's position and the outcome of its batches.
+type Status struct {
+	Broker      string        `json:"broker"`
+	Topic       string        `json:"topic"`
+	Offsets     map[int]int64 `json:"offsets"` // Next offset of each partition, as checkpointed
+	Lag         map[int]int64 `json:"lag"`     // Messages behind each partition's head
+	Applied     int64         `json:"applied"` // Rows applied since startup
+	Invalid     int64         `json:"invalid"` // Messages that aren't a valid feature row
+	Rejected    int64         `json:"rejected"`
+	LastApplied *time.Time    `json:"last_applied,omitempty"`
+	LastError   string        `json:"last_error,omitempty"` // Of the last batch or checkpoint; cleared by the next success
+}
+
+// Consumer applies the rows of a source in batches. Safe for concurrent use.
+type Consumer struct {
+	cfg        Config
+	src        Source
+	apply      Apply
+	invalidate Invalidate
+	checkpoint Checkpoint
+
+	// Owned by the run goroutine
+	stale       bool      // Rows were applied since the last invalidation
+	invalidated time.Time // Time of the last invalidation
+
+	mu     sync.RWMutex
+	status Status
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConsumer creates a consumer of src positioned at cp, applying rows with apply and
+// then invalidating with invalidate. Call Start to begin applying rows.
+func NewConsumer(cfg Config, src Source, cp Checkpoint, apply Apply, invalidate Invalidate) *Consumer {
+	if cp.Offsets == nil {
+		cp.Offsets = make(map[int]int64)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Consumer{
+		cfg:        cfg,
+		src:        src,
+		apply:      apply,
+		invalidate: invalidate,
+		checkpoint: cp,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	c.status = Status{Broker: cp.Broker, Topic: cp.Topic, Offsets: copyOffsets(cp.Offsets), Lag: make(map[int]int64)}
+	return c
+}
+
+// Start consumes messages until Close.
+func (c *Consumer) Start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// Close stops consuming and closes the source. Rows consumed since the last batch
+// are not applied; they are consumed again after a restart.
+func (c *Consumer) Close() {
+	c.cancel()
+	c.wg.Wait()
+	if err := c.src.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close ingest source")
+	}
+}
+
+// Status returns the consumer's position and counters.
+func (c *Consumer) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := c.status
+	s.Offsets = copyOffsets(s.Offsets)
+	s.Lag = copyOffsets(s.Lag)
+	return s
+}
+
+func (c *Consumer) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var rows []features.FeatureRow
+	pending := make(map[int]int64) // Next offset of each partition once the batch is applied
+	msgs := c.src.Messages()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				c.invalidateStale(true)
+				return
+			}
+			pending[msg.Partition] = msg.Offset + 1
+			metrics.SetIngestLag(msg.Partition, msg.Lag)
+			c.mu.Lock()
+			c.status.Lag[msg.Partition] = msg.Lag
+			c.mu.Unlock()
+
+			row, err := decodeRow(msg.Value)
+			if err != nil {
+				metrics.RecordIngestMessages("invalid", 1)
+				c.mu.Lock()
+				c.status.Invalid++
+				c.mu.Unlock()
+				log.Warn().Err(err).Int("partition", msg.Partition).Int64("offset", msg.Offset).Msg("Skipping invalid feature message")
+				continue
+			}
+			rows = append(rows, row)
+			if len(rows) >= c.cfg.BatchSize {
+				if !c.flush(rows, pending) {
+					return
+				}
+				rows, pending = nil, make(map[int]int64)
+				c.invalidateStale(false)
+			}
+		case <-ticker.C:
+			if len(pending) > 0 {
+				if !c.flush(rows, pending) {
+					return
+				}
+				rows, pending = nil, make(map[int]int64)
+			}
+			c.invalidateStale(false)
+		}
+	}
+}
+
+// invalidateStale invalidates once rows were applied since the last invalidation, if
+// InvalidateInterval has passed since it or force is set.
+func (c *Consumer) invalidateStale(force bool) {
+	if !c.stale || (!force && time.Since(c.invalidated) < c.cfg.InvalidateInterval) {
+		return
+	}
+	c.invalidate(c.ctx)
+	c.stale, c.invalidated = false, time.Now()
+}
+
+// flush applies rows and checkpoints the pending offsets. While the feature store
+// isn't loaded it retries every FlushInterval, consuming nothing meanwhile. A batch
+// the store rejects is skipped. Returns false if the consumer was closed.
+func (c *Consumer) flush(rows []features.FeatureRow, pending map[int]int64) bool {
+	if len(rows) > 0 {
+		res, err := c.apply(c.ctx, rows)
+		for errors.Is(err, features.ErrNotLoaded) {
+			log.Warn().Int("rows", len(rows)).Msg("Feature store not loaded, retrying streamed rows")
+			select {
+			case <-c.ctx.Done():
+				return false
+			case <-time.After(c.cfg.FlushInterval):
+			}
+			res, err = c.apply(c.ctx, rows)
+		}
+
+		c.mu.Lock()
+		if err != nil {
+			c.status.Rejected += int64(len(rows))
+			c.status.LastError = err.Error()
+		} else {
+			now := time.Now()
+			c.status.Applied += int64(len(rows))
+			c.status.LastApplied = &now
+			c.status.LastError = ""
+			c.stale = true
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			metrics.RecordIngestMessages("rejected", len(rows))
+			log.Error().Err(err).Int("rows", len(rows)).Msg("Streamed feature rows rejected")
+		} else {
+			metrics.RecordIngestMessages("applied", len(rows))
+			log.Debug().
+				Int("rows", res.Rows).
+				Int("added", res.Added).
+				Int("replaced", res.Replaced).
+				Msg("Streamed feature rows applied")
+		}
+	}
+
+	for partition, offset := range pending {
+		c.checkpoint.Offsets[partition] = offset
+	}
+	c.checkpoint.UpdatedAt = time.Now().UTC()
+	err := c.checkpoint.save(c.cfg.CheckpointPath)
+	c.mu.Lock()
+	c.status.Offsets = copyOffsets(c.checkpoint.Offsets)
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	c.mu.Unlock()
+	if err != nil {
+		log.Error().Err(err).Str("path", c.cfg.CheckpointPath).Msg("Failed to write ingest checkpoint")
+	}
+	return true
+}
+
+// rowMessage is the JSON form of a FeatureRow, with the date as YYYY-MM-DD.
+type rowMessage struct {
+	features.FeatureRow
+	Date string `json:"date"`
+}
+
+// decodeRow parses a message into a feature row.
+func decodeRow(value []byte) (features.FeatureRow, error) {
+	var msg rowMessage
+	if err := json.Unmarshal(value, &msg); err != nil {
+		return features.FeatureRow{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	row := msg.FeatureRow
+	if row.StoreNbr <= 0 || row.Family == "" {
+		return features.FeatureRow{}, errors.New("store_nbr and family are required")
+	}
+	date, err := time.Parse("2006-01-02", msg.Date)
+	if err != nil {
+		return features.FeatureRow{}, fmt.Errorf("date must be YYYY-MM-DD, got %q", msg.Date)
+	}
+	row.Date = date
+	return row, nil
+}
+
+func copyOffsets(m map[int]int64) map[int]int64 {
+	out := make(map[int]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
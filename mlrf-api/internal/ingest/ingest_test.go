@@ -0,0 +1,211 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/features"
+)
+
+// chanSource delivers the messages sent on its channel.
+type chanSource struct {
+	msgs chan Message
+	once sync.Once
+}
+
+func newChanSource() *chanSource {
+	return &chanSource{msgs: make(chan Message, 10)}
+}
+
+func (s *chanSource) Messages() <-chan Message { return s.msgs }
+
+func (s *chanSource) Close() error {
+	s.once.Do(func() { close(s.msgs) })
+	return nil
+}
+
+// recordingApply collects applied rows, failing batches with a row of family REJECT.
+type recordingApply struct {
+	mu        sync.Mutex
+	rows      []features.FeatureRow
+	notLoaded int // Calls to fail with ErrNotLoaded first
+}
+
+func (a *recordingApply) apply(_ context.Context, rows []features.FeatureRow) (features.AppendResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.notLoaded > 0 {
+		a.notLoaded--
+		return features.AppendResult{}, fmt.Errorf("append: %w", features.ErrNotLoaded)
+	}
+	for _, r := range rows {
+		if r.Family == "REJECT" {
+			return features.AppendResult{}, errors.New("quality check failed")
+		}
+	}
+	a.rows = append(a.rows, rows...)
+	return features.AppendResult{Rows: len(rows), Added: len(rows)}, nil
+}
+
+func (a *recordingApply) applied() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.rows)
+}
+
+func rowMsg(partition int, offset int64, family, date string) Message {
+	return Message{
+		Partition: partition,
+		Offset:    offset,
+		Lag:       5 - offset,
+		Value:     []byte(fmt.Sprintf(`{"store_nbr": 1, "family": %q, "date": %q, "sales_lag_1": 12.5, "onpromotion": 3}`, family, date)),
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDecodeRow(t *testing.T) {
+	row, err := decodeRow([]byte(`{"store_nbr": 3, "family": "DAIRY", "date": "2017-08-16", "sales_lag_7": 4.5, "is_holiday": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.StoreNbr != 3 || row.Family != "DAIRY" || row.SalesLag7 != 4.5 || row.IsHoliday != 1 ||
+		!row.Date.Equal(time.Date(2017, 8, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected row %+v", row)
+	}
+
+	for _, value := range []string{
+		`not json`,
+		`{"family": "DAIRY", "date": "2017-08-16"}`,
+		`{"store_nbr": 3, "date": "2017-08-16"}`,
+		`{"store_nbr": 3, "family": "DAIRY", "date": "16/08/2017"}`,
+	} {
+		if _, err := decodeRow([]byte(value)); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	src := newChanSource()
+	a := &recordingApply{}
+	cfg := Config{CheckpointPath: path, BatchSize: 2, FlushInterval: 10 * time.Millisecond}
+	c := NewConsumer(cfg, src, Checkpoint{Broker: BrokerKafka, Topic: "features"}, a.apply, func(context.Context) {})
+	c.Start()
+	defer c.Close()
+
+	src.msgs <- rowMsg(0, 0, "DAIRY", "2017-08-16")
+	src.msgs <- Message{Partition: 1, Offset: 4, Value: []byte(`{"store_nbr": 1}`)}
+	src.msgs <- rowMsg(1, 5, "EGGS", "2017-08-16")
+	src.msgs <- rowMsg(0, 1, "BREAD/BAKERY", "2017-08-16")
+	waitFor(t, "the rows to be applied and checkpointed", func() bool {
+		status := c.Status()
+		return status.Applied == 3 && status.Offsets[0] == 2 && status.Offsets[1] == 6
+	})
+
+	status := c.Status()
+	if status.Invalid != 1 || status.Lag[0] != 4 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if a.rows[0].SalesLag1 != 12.5 || a.rows[0].OnPromotion != 3 {
+		t.Errorf("unexpected applied row %+v", a.rows[0])
+	}
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Broker != BrokerKafka || cp.Topic != "features" || cp.Offsets[0] != 2 || cp.Offsets[1] != 6 {
+		t.Errorf("unexpected checkpoint %+v", cp)
+	}
+
+	// A rejected batch is skipped, and consumption continues past it
+	src.msgs <- rowMsg(0, 2, "REJECT", "2017-08-17")
+	waitFor(t, "the rejected offset to be checkpointed", func() bool { return c.Status().Offsets[0] == 3 })
+	if status := c.Status(); status.Rejected != 1 || status.LastError == "" {
+		t.Errorf("expected the batch to be rejected, got %+v", status)
+	}
+	src.msgs <- rowMsg(0, 3, "DAIRY", "2017-08-17")
+	waitFor(t, "the next row", func() bool { return c.Status().Applied == 4 })
+	if status := c.Status(); status.LastError != "" || status.LastApplied == nil {
+		t.Errorf("expected the error to clear, got %+v", status)
+	}
+}
+
+func TestConsumerWaitsForFeatureStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	src := newChanSource()
+	a := &recordingApply{notLoaded: 2}
+	c := NewConsumer(Config{CheckpointPath: path, BatchSize: 1, FlushInterval: 5 * time.Millisecond}, src, Checkpoint{}, a.apply, func(context.Context) {})
+	c.Start()
+	defer c.Close()
+
+	src.msgs <- rowMsg(0, 7, "DAIRY", "2017-08-16")
+	waitFor(t, "the row to be applied", func() bool { return a.applied() == 1 })
+	if status := c.Status(); status.Rejected != 0 || status.Offsets[0] != 8 {
+		t.Errorf("expected the row to be retried, not rejected, got %+v", status)
+	}
+}
+
+func TestConsumerCoalescesInvalidation(t *testing.T) {
+	src := newChanSource()
+	a := &recordingApply{}
+	var invalidations atomic.Int32
+	cfg := Config{
+		CheckpointPath:     filepath.Join(t.TempDir(), "checkpoint.json"),
+		BatchSize:          1,
+		FlushInterval:      5 * time.Millisecond,
+		InvalidateInterval: 500 * time.Millisecond,
+	}
+	c := NewConsumer(cfg, src, Checkpoint{}, a.apply, func(context.Context) { invalidations.Add(1) })
+	c.Start()
+	defer c.Close()
+
+	// The first batch invalidates at once; a stream of batches right after it waits
+	for i := int64(0); i < 20; i++ {
+		src.msgs <- rowMsg(0, i, "DAIRY", "2017-08-16")
+	}
+	waitFor(t, "the rows to be applied", func() bool { return c.Status().Applied == 20 })
+	if n := invalidations.Load(); n != 1 {
+		t.Errorf("expected one invalidation for a burst of 20 batches, got %d", n)
+	}
+
+	// The rows applied since are invalidated once the interval has passed
+	waitFor(t, "the trailing invalidation", func() bool { return invalidations.Load() == 2 })
+	time.Sleep(cfg.InvalidateInterval + 100*time.Millisecond)
+	if n := invalidations.Load(); n != 2 {
+		t.Errorf("expected no invalidation without new rows, got %d", n)
+	}
+}
+
+func TestLoadCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "checkpoint.json")
+	cp, err := LoadCheckpoint(path)
+	if err != nil || len(cp.Offsets) != 0 {
+		t.Fatalf("expected an empty checkpoint for a missing file, got %+v %v", cp, err)
+	}
+
+	want := Checkpoint{Broker: BrokerNATS, Topic: "mlrf.features", Offsets: map[int]int64{0: 42}}
+	if err := want.save(path); err != nil {
+		t.Fatal(err)
+	}
+	cp, err = LoadCheckpoint(path)
+	if err != nil || cp.Broker != BrokerNATS || cp.Offsets[0] != 42 {
+		t.Errorf("unexpected checkpoint %+v %v", cp, err)
+	}
+}
@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// connectTimeout bounds connecting to the broker and looking up the topic.
+const connectTimeout = 10 * time.Second
+
+// retryDelay is the wait after a failed fetch before fetching again.
+const retryDelay = time.Second
+
+// KafkaSource reads every partition of a Kafka topic from explicit offsets. Offsets
+// are tracked by the consumer's checkpoint rather than committed to a consumer group.
+type KafkaSource struct {
+	readers []*kafka.Reader
+	msgs    chan Message
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewKafkaSource reads cfg.Topic from the brokers in cfg.URL, starting each partition
+// at its offset in offsets, or at cfg.Start for partitions without one.
+func NewKafkaSource(cfg Config, offsets map[int]int64) (*KafkaSource, error) {
+	brokers := strings.Split(cfg.URL, ",")
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("connect to kafka %s: %w", brokers[0], err)
+	}
+	partitions, err := conn.ReadPartitions(cfg.Topic)
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read partitions of %s: %w", cfg.Topic, err)
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("kafka topic %s has no partitions", cfg.Topic)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	s := &KafkaSource{msgs: make(chan Message), cancel: cancel}
+	for _, p := range partitions {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   brokers,
+			Topic:     cfg.Topic,
+			Partition: p.ID,
+			MaxWait:   time.Second,
+		})
+		start := kafka.FirstOffset
+		if cfg.Start == StartLatest {
+			start = kafka.LastOffset
+		}
+		if offset, ok := offsets[p.ID]; ok {
+			start = offset
+		}
+		if err := r.SetOffset(start); err != nil {
+			r.Close()
+			s.Close()
+			return nil, fmt.Errorf("seek partition %d: %w", p.ID, err)
+		}
+		s.readers = append(s.readers, r)
+		s.wg.Add(1)
+		go s.read(ctx, r)
+	}
+	return s, nil
+}
+
+// read delivers the messages of one partition until ctx is done.
+func (s *KafkaSource) read(ctx context.Context, r *kafka.Reader) {
+	defer s.wg.Done()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("topic", r.Config().Topic).Int("partition", r.Config().Partition).Msg("Kafka fetch failed, retrying")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		msg := Message{Partition: m.Partition, Offset: m.Offset, Lag: max(m.HighWaterMark-m.Offset-1, 0), Value: m.Value}
+		select {
+		case s.msgs <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Messages returns the messages of every partition, in order within each.
+func (s *KafkaSource) Messages() <-chan Message {
+	return s.msgs
+}
+
+// Close stops reading and closes the connections.
+func (s *KafkaSource) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+		for _, r := range s.readers {
+			if cerr := r.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		close(s.msgs)
+	})
+	return err
+}
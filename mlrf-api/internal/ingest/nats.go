@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/zerolog/log"
+)
+
+// NATSSource reads a JetStream subject with an ordered consumer, starting after the
+// last stream sequence consumed. The server keeps no consumer state between restarts.
+type NATSSource struct {
+	nc   *nats.Conn
+	iter jetstream.MessagesContext
+	msgs chan Message
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewNATSSource reads cfg.Topic on the NATS servers in cfg.URL, starting at the
+// stream sequence of partition 0 in offsets, or at cfg.Start without one.
+func NewNATSSource(cfg Config, offsets map[int]int64) (*NATSSource, error) {
+	nc, err := nats.Connect(cfg.URL, nats.Name("mlrf-api"), nats.Timeout(connectTimeout), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("jetstream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	stream, err := js.StreamNameBySubject(ctx, cfg.Topic)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("find the stream of %s: %w", cfg.Topic, err)
+	}
+	consumerCfg := jetstream.OrderedConsumerConfig{FilterSubjects: []string{cfg.Topic}, DeliverPolicy: jetstream.DeliverAllPolicy}
+	if cfg.Start == StartLatest {
+		consumerCfg.DeliverPolicy = jetstream.DeliverNewPolicy
+	}
+	if seq, ok := offsets[0]; ok && seq > 0 {
+		consumerCfg.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		consumerCfg.OptStartSeq = uint64(seq)
+	}
+	consumer, err := js.OrderedConsumer(ctx, stream, consumerCfg)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("create consumer of %s: %w", cfg.Topic, err)
+	}
+	iter, err := consumer.Messages()
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("consume %s: %w", cfg.Topic, err)
+	}
+
+	s := &NATSSource{nc: nc, iter: iter, msgs: make(chan Message), done: make(chan struct{})}
+	s.wg.Add(1)
+	go s.read()
+	return s, nil
+}
+
+// read delivers messages until the iterator is stopped.
+func (s *NATSSource) read() {
+	defer s.wg.Done()
+	for {
+		m, err := s.iter.Next()
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("NATS fetch failed")
+			continue
+		}
+		meta, err := m.Metadata()
+		if err != nil {
+			log.Warn().Err(err).Str("subject", m.Subject()).Msg("Skipping NATS message without JetStream metadata")
+			continue
+		}
+		msg := Message{Offset: int64(meta.Sequence.Stream), Lag: int64(meta.NumPending), Value: m.Data()}
+		select {
+		case s.msgs <- msg:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Messages returns the subject's messages in stream order, all on partition 0.
+func (s *NATSSource) Messages() <-chan Message {
+	return s.msgs
+}
+
+// Close stops consuming and closes the connection.
+func (s *NATSSource) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.iter.Stop()
+		s.wg.Wait()
+		s.nc.Close()
+		close(s.msgs)
+	})
+	return nil
+}
@@ -0,0 +1,268 @@
+// Package jobs runs long-running work, such as large prediction batches, in background workers.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+// Job statuses.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// ErrQueueFull is returned by Submit when no more jobs can be queued.
+var ErrQueueFull = errors.New("job queue is full")
+
+// Config holds job manager configuration.
+type Config struct {
+	Workers   int           // Concurrent jobs
+	QueueSize int           // Jobs waiting for a worker
+	MaxItems  int           // Maximum items per job
+	ResultTTL time.Duration // How long finished jobs and their results are kept
+}
+
+// DefaultConfig returns the default job configuration.
+func DefaultConfig() Config {
+	return Config{
+		Workers:   2,
+		QueueSize: 100,
+		MaxItems:  50000,
+		ResultTTL: time.Hour,
+	}
+}
+
+// RunFunc performs a job's work. It reports progress as items complete and
+// returns the JSON-serializable result.
+type RunFunc func(ctx context.Context, progress func(processed int)) (interface{}, error)
+
+// Job is a snapshot of a background job's state.
+type Job struct {
+	ID          string     `json:"id"`
+	Status      Status     `json:"status"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// entry is the manager's internal record for a job.
+type entry struct {
+	job    Job
+	run    RunFunc
+	result interface{}
+}
+
+// Manager queues jobs and runs them on a fixed pool of workers.
+// Thread-safe for concurrent use.
+type Manager struct {
+	cfg    Config
+	jobs   map[string]*entry
+	queue  chan *entry
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a job manager and starts its workers and cleanup loop.
+func NewManager(cfg Config) *Manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		cfg:    cfg,
+		jobs:   make(map[string]*entry),
+		queue:  make(chan *entry, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	if cfg.ResultTTL > 0 {
+		m.wg.Add(1)
+		go m.cleanupLoop()
+	}
+
+	return m
+}
+
+// MaxItems returns the maximum number of items accepted per job.
+func (m *Manager) MaxItems() int {
+	return m.cfg.MaxItems
+}
+
+// Submit queues a job with total items. Returns ErrQueueFull if the queue is at capacity.
+func (m *Manager) Submit(total int, run RunFunc) (Job, error) {
+	e := &entry{
+		job: Job{
+			ID:        newJobID(),
+			Status:    StatusQueued,
+			Total:     total,
+			CreatedAt: time.Now(),
+		},
+		run: run,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- e:
+		m.jobs[e.job.ID] = e
+		return e.job, nil
+	default:
+		return Job{}, ErrQueueFull
+	}
+}
+
+// Get returns a snapshot of the job with id.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// Result returns the job snapshot and its result. The result is nil unless the job completed.
+func (m *Manager) Result(id string) (Job, interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, false
+	}
+	return e.job, e.result, true
+}
+
+// Active returns the number of queued and running jobs.
+func (m *Manager) Active() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	active := 0
+	for _, e := range m.jobs {
+		if e.job.Status == StatusQueued || e.job.Status == StatusRunning {
+			active++
+		}
+	}
+	return active
+}
+
+// Close stops accepting work, cancels running jobs and waits for workers to exit.
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case e := <-m.queue:
+			m.execute(e)
+		}
+	}
+}
+
+func (m *Manager) execute(e *entry) {
+	started := time.Now()
+	m.mu.Lock()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = &started
+	m.mu.Unlock()
+
+	progress := func(processed int) {
+		m.mu.Lock()
+		e.job.Processed = processed
+		m.mu.Unlock()
+	}
+
+	result, err := e.run(m.ctx, progress)
+
+	completed := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.job.CompletedAt = &completed
+	if err != nil {
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+		log.Warn().Err(err).Str("job_id", e.job.ID).Msg("Job failed")
+		return
+	}
+	e.job.Status = StatusCompleted
+	e.job.Processed = e.job.Total
+	e.result = result
+	log.Info().
+		Str("job_id", e.job.ID).
+		Int("items", e.job.Total).
+		Dur("duration", completed.Sub(started)).
+		Msg("Job completed")
+}
+
+// cleanupLoop drops finished jobs older than the result TTL.
+func (m *Manager) cleanupLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.cfg.ResultTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.expire(now)
+		}
+	}
+}
+
+// expire removes finished jobs that completed more than ResultTTL before now.
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, e := range m.jobs {
+		if e.job.CompletedAt != nil && now.Sub(*e.job.CompletedAt) > m.cfg.ResultTTL {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// newJobID returns a random 128-bit hex job ID.
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
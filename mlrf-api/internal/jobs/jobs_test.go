@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForStatus polls until the job reaches a terminal status or the deadline passes.
+func waitForStatus(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status == StatusCompleted || job.Status == StatusFailed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestManagerRunsJob(t *testing.T) {
+	m := NewManager(Config{Workers: 1, QueueSize: 1, MaxItems: 10})
+	defer m.Close()
+
+	job, err := m.Submit(3, func(ctx context.Context, progress func(int)) (interface{}, error) {
+		progress(2)
+		return []int{1, 2, 3}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status != StatusQueued || job.ID == "" {
+		t.Errorf("expected queued job with ID, got %+v", job)
+	}
+
+	done := waitForStatus(t, m, job.ID)
+	if done.Status != StatusCompleted || done.Processed != 3 {
+		t.Errorf("expected completed job with 3 processed, got %+v", done)
+	}
+	if done.StartedAt == nil || done.CompletedAt == nil {
+		t.Error("expected start and completion timestamps")
+	}
+
+	_, result, _ := m.Result(job.ID)
+	if got, ok := result.([]int); !ok || len(got) != 3 {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestManagerFailedJob(t *testing.T) {
+	m := NewManager(Config{Workers: 1, QueueSize: 1})
+	defer m.Close()
+
+	job, _ := m.Submit(1, func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	done := waitForStatus(t, m, job.ID)
+	if done.Status != StatusFailed || done.Error != "boom" {
+		t.Errorf("expected failed job with error, got %+v", done)
+	}
+	if _, result, _ := m.Result(job.ID); result != nil {
+		t.Errorf("expected nil result for failed job, got %v", result)
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	m := NewManager(Config{Workers: 1, QueueSize: 1})
+	defer m.Close()
+
+	block := make(chan struct{})
+	defer close(block)
+	blocking := func(ctx context.Context, progress func(int)) (interface{}, error) {
+		select {
+		case <-block:
+		case <-ctx.Done():
+		}
+		return nil, nil
+	}
+
+	// First job occupies the worker, second fills the queue
+	first, _ := m.Submit(1, blocking)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := m.Get(first.ID); job.Status == StatusRunning {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := m.Submit(1, blocking); err != nil {
+		t.Fatalf("expected second job to queue, got %v", err)
+	}
+
+	if _, err := m.Submit(1, blocking); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestManagerExpire(t *testing.T) {
+	m := NewManager(Config{Workers: 1, QueueSize: 1, ResultTTL: time.Hour})
+	defer m.Close()
+
+	job, _ := m.Submit(1, func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return "ok", nil
+	})
+	waitForStatus(t, m, job.ID)
+
+	m.expire(time.Now())
+	if _, ok := m.Get(job.ID); !ok {
+		t.Fatal("job should be kept within TTL")
+	}
+
+	m.expire(time.Now().Add(2 * time.Hour))
+	if _, ok := m.Get(job.ID); ok {
+		t.Error("expected job to expire after TTL")
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Workers != 2 || cfg.QueueSize != 100 {
+		t.Errorf("expected 2 workers and a queue of 100, got %+v", cfg)
+	}
+	if cfg.MaxItems != 50000 {
+		t.Errorf("expected 50000 max items, got %d", cfg.MaxItems)
+	}
+	if cfg.ResultTTL != time.Hour {
+		t.Errorf("expected 1h TTL, got %v", cfg.ResultTTL)
+	}
+}
@@ -0,0 +1,251 @@
+// Package live pushes prediction updates to subscribed WebSocket clients.
+package live
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Message types sent to clients.
+const (
+	TypePrediction   = "prediction"
+	TypeError        = "error"
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypePing         = "ping"
+)
+
+// Update reasons attached to prediction messages.
+const (
+	ReasonSubscribe        = "subscribe"
+	ReasonFeaturesReloaded = "features_reloaded"
+	ReasonFeaturesAppended = "features_appended"
+	ReasonModelReloaded    = "model_reloaded"
+	ReasonModelPromoted    = "model_promoted"
+)
+
+// ErrTooManySubscriptions is returned when a client exceeds MaxSubscriptions.
+var ErrTooManySubscriptions = errors.New("too many subscriptions")
+
+// Subscription identifies a series and forecast a client wants updates for.
+type Subscription struct {
+	StoreNbr int    `json:"store_nbr"`
+	Family   string `json:"family"`
+	Date     string `json:"date"`
+	Horizon  int    `json:"horizon"`
+}
+
+// Key returns a unique key for the subscription.
+func (s Subscription) Key() string {
+	return fmt.Sprintf("%d:%s:%s:%d", s.StoreNbr, s.Family, s.Date, s.Horizon)
+}
+
+// Message is a server-to-client WebSocket message.
+type Message struct {
+	Type          string         `json:"type"`
+	Reason        string         `json:"reason,omitempty"` // Why a prediction was pushed, e.g. "model_reloaded"
+	Subscription  *Subscription  `json:"subscription,omitempty"`
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+	Data          interface{}    `json:"data,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	Code          string         `json:"code,omitempty"`
+}
+
+// ScoreFunc computes the current prediction for a subscription.
+type ScoreFunc func(Subscription) (interface{}, error)
+
+// Config holds hub configuration.
+type Config struct {
+	MaxSubscriptions int // Subscriptions per client
+	SendBuffer       int // Queued messages per client before it is dropped as too slow
+}
+
+// DefaultConfig returns the default hub configuration.
+func DefaultConfig() Config {
+	return Config{
+		MaxSubscriptions: 100,
+		SendBuffer:       256,
+	}
+}
+
+// Client is a connected subscriber. Messages queued for it are read from Send.
+type Client struct {
+	hub    *Hub
+	send   chan Message
+	mu     sync.Mutex
+	subs   map[string]Subscription
+	closed bool
+}
+
+// Send returns the channel of messages to write to the connection.
+// It is closed when the client is unregistered.
+func (c *Client) Send() <-chan Message {
+	return c.send
+}
+
+// Subscribe adds subscriptions, returning those not already present.
+// Fails without changes if the client would exceed MaxSubscriptions.
+func (c *Client) Subscribe(subs []Subscription) ([]Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var added []Subscription
+	seen := make(map[string]bool)
+	for _, s := range subs {
+		if _, ok := c.subs[s.Key()]; !ok && !seen[s.Key()] {
+			seen[s.Key()] = true
+			added = append(added, s)
+		}
+	}
+	if len(c.subs)+len(added) > c.hub.cfg.MaxSubscriptions {
+		return nil, ErrTooManySubscriptions
+	}
+	for _, s := range added {
+		c.subs[s.Key()] = s
+	}
+	return added, nil
+}
+
+// Unsubscribe removes subscriptions.
+func (c *Client) Unsubscribe(subs []Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range subs {
+		delete(c.subs, s.Key())
+	}
+}
+
+// Subscriptions returns the client's current subscriptions.
+func (c *Client) Subscriptions() []Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subs := make([]Subscription, 0, len(c.subs))
+	for _, s := range c.subs {
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+// Enqueue queues a message without blocking. Returns false if the client is
+// closed or its send buffer is full.
+func (c *Client) Enqueue(m Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+// close marks the client closed and closes its send channel.
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// Hub tracks connected clients and fans out prediction updates.
+type Hub struct {
+	cfg     Config
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+}
+
+// NewHub creates a hub.
+func NewHub(cfg Config) *Hub {
+	if cfg.MaxSubscriptions <= 0 {
+		cfg.MaxSubscriptions = 100
+	}
+	if cfg.SendBuffer <= 0 {
+		cfg.SendBuffer = 256
+	}
+	return &Hub{cfg: cfg, clients: make(map[*Client]struct{})}
+}
+
+// Register adds a new client.
+func (h *Hub) Register() *Client {
+	c := &Client{
+		hub:  h,
+		send: make(chan Message, h.cfg.SendBuffer),
+		subs: make(map[string]Subscription),
+	}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// Unregister removes a client and closes its send channel. Safe to call more than once.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	c.close()
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Broadcast re-scores every subscription and pushes the results to subscribers.
+// Each distinct subscription is scored once, however many clients share it.
+// Clients whose send buffer is full are dropped rather than blocking the others.
+// Returns the number of messages queued.
+func (h *Hub) Broadcast(reason string, score ScoreFunc) int {
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	results := make(map[string]Message)
+	sent := 0
+	for _, c := range clients {
+		for _, s := range c.Subscriptions() {
+			msg, ok := results[s.Key()]
+			if !ok {
+				msg = Score(reason, s, score)
+				results[s.Key()] = msg
+			}
+			if !c.Enqueue(msg) {
+				log.Warn().Msg("Live client too slow, disconnecting")
+				h.Unregister(c)
+				break
+			}
+			sent++
+		}
+	}
+
+	log.Info().
+		Str("reason", reason).
+		Int("clients", len(clients)).
+		Int("series", len(results)).
+		Int("messages", sent).
+		Msg("Pushed live prediction updates")
+	return sent
+}
+
+// Score builds the prediction (or error) message for a subscription.
+func Score(reason string, s Subscription, score ScoreFunc) Message {
+	sub := s
+	data, err := score(s)
+	if err != nil {
+		return Message{Type: TypeError, Reason: reason, Subscription: &sub, Error: err.Error()}
+	}
+	return Message{Type: TypePrediction, Reason: reason, Subscription: &sub, Data: data}
+}
@@ -0,0 +1,83 @@
+package live
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscribeLimit(t *testing.T) {
+	hub := NewHub(Config{MaxSubscriptions: 2, SendBuffer: 4})
+	c := hub.Register()
+
+	a := Subscription{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Horizon: 30}
+	b := Subscription{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-01", Horizon: 30}
+
+	added, err := c.Subscribe([]Subscription{a, b, a})
+	if err != nil || len(added) != 2 {
+		t.Fatalf("expected 2 new subscriptions, got %d (err %v)", len(added), err)
+	}
+	if _, err := c.Subscribe([]Subscription{{StoreNbr: 3, Family: "DAIRY", Date: "2017-08-01", Horizon: 30}}); !errors.Is(err, ErrTooManySubscriptions) {
+		t.Errorf("expected ErrTooManySubscriptions, got %v", err)
+	}
+
+	c.Unsubscribe([]Subscription{a})
+	if subs := c.Subscriptions(); len(subs) != 1 || subs[0] != b {
+		t.Errorf("expected only %v to remain, got %v", b, subs)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	hub := NewHub(Config{MaxSubscriptions: 10, SendBuffer: 4})
+	shared := Subscription{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Horizon: 30}
+
+	c1 := hub.Register()
+	c2 := hub.Register()
+	c1.Subscribe([]Subscription{shared})
+	c2.Subscribe([]Subscription{shared})
+
+	calls := 0
+	sent := hub.Broadcast(ReasonModelReloaded, func(s Subscription) (interface{}, error) {
+		calls++
+		return 42, nil
+	})
+
+	if calls != 1 {
+		t.Errorf("expected shared subscription to be scored once, got %d", calls)
+	}
+	if sent != 2 {
+		t.Errorf("expected 2 messages, got %d", sent)
+	}
+	for _, c := range []*Client{c1, c2} {
+		msg := <-c.Send()
+		if msg.Type != TypePrediction || msg.Reason != ReasonModelReloaded || msg.Data != 42 {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	}
+}
+
+func TestBroadcastDropsSlowClient(t *testing.T) {
+	hub := NewHub(Config{MaxSubscriptions: 10, SendBuffer: 1})
+	c := hub.Register()
+	c.Subscribe([]Subscription{
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-01", Horizon: 30},
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-01", Horizon: 30},
+	})
+
+	hub.Broadcast(ReasonFeaturesReloaded, func(s Subscription) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	if hub.Clients() != 0 {
+		t.Errorf("expected slow client to be dropped, %d clients remain", hub.Clients())
+	}
+	msg, ok := <-c.Send()
+	if !ok || msg.Type != TypeError || msg.Error != "boom" {
+		t.Errorf("expected buffered error message, got %+v", msg)
+	}
+	if _, ok := <-c.Send(); ok {
+		t.Error("expected send channel to be closed")
+	}
+	if c.Enqueue(Message{Type: TypePing}) {
+		t.Error("expected enqueue on closed client to fail")
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// Label values of the prediction metrics that stand for more than one value.
+const (
+	LabelDefault = "default" // The model serving when no registry model is named
+	LabelUnknown = "unknown" // Inference without a family, such as model smoke tests
+	LabelMixed   = "mixed"   // A batch scoring several families
+	LabelAll     = "all"     // Every family, with the family label disabled
+	LabelOther   = "other"   // Values seen after a label's limit was reached
+)
+
+// LabelConfig bounds the cardinality of the model_version and family labels of the
+// prediction metrics.
+type LabelConfig struct {
+	FamilyLabel      bool // Label predictions by family; every family is "all" if false
+	MaxFamilies      int  // Distinct families labelled before the rest are "other"
+	MaxModelVersions int  // Distinct model versions labelled before the rest are "other"
+}
+
+// DefaultLabelConfig returns the default label configuration.
+func DefaultLabelConfig() LabelConfig {
+	return LabelConfig{
+		FamilyLabel:      true,
+		MaxFamilies:      50,
+		MaxModelVersions: 10,
+	}
+}
+
+// labelLimiter passes through the first max distinct values of a label and maps later
+// ones to LabelOther, so a bad client or a run of model reloads can't grow a metric
+// without bound.
+type labelLimiter struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]bool
+}
+
+func newLabelLimiter(max int) *labelLimiter {
+	return &labelLimiter{max: max, seen: make(map[string]bool)}
+}
+
+func (l *labelLimiter) value(v string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen[v] {
+		return v
+	}
+	if len(l.seen) >= l.max {
+		return LabelOther
+	}
+	l.seen[v] = true
+	return v
+}
+
+var (
+	labelMu       sync.RWMutex
+	familyLabel   = true
+	familyValues  = newLabelLimiter(50)
+	versionValues = newLabelLimiter(10)
+)
+
+// SetLabelConfig sets the cardinality limits of the prediction metric labels. Values
+// already labelled keep counting under their own label.
+func SetLabelConfig(cfg LabelConfig) {
+	labelMu.Lock()
+	defer labelMu.Unlock()
+	familyLabel = cfg.FamilyLabel
+	familyValues.mu.Lock()
+	familyValues.max = cfg.MaxFamilies
+	familyValues.mu.Unlock()
+	versionValues.mu.Lock()
+	versionValues.max = cfg.MaxModelVersions
+	versionValues.mu.Unlock()
+}
+
+// modelVersionLabel returns the model_version label of a registry model key; an empty
+// key is the default model.
+func modelVersionLabel(model string) string {
+	if model == "" {
+		return LabelDefault
+	}
+	return versionValues.value(model)
+}
+
+// familyLabelValue returns the family label of a product family.
+func familyLabelValue(family string) string {
+	labelMu.RLock()
+	enabled := familyLabel
+	labelMu.RUnlock()
+	switch {
+	case !enabled:
+		return LabelAll
+	case family == "":
+		return LabelUnknown
+	default:
+		return familyValues.value(family)
+	}
+}
@@ -2,6 +2,9 @@
 package metrics
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -32,18 +35,31 @@ var (
 		Help: "Total number of cache misses",
 	})
 
-	// InferenceDuration tracks ONNX inference duration in seconds.
-	InferenceDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	// CacheEarlyExpirations counts cache hits served as misses to refresh entries early.
+	CacheEarlyExpirations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mlrf_cache_early_expirations_total",
+		Help: "Total cache hits served as misses to refresh entries before they expire",
+	})
+
+	// InferenceDuration tracks ONNX inference duration in seconds by model version and
+	// family. Batches of several families are labelled "mixed".
+	InferenceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "mlrf_inference_duration_seconds",
-		Help:    "ONNX model inference duration in seconds",
+		Help:    "ONNX model inference duration in seconds by model version and family",
 		Buckets: []float64{.001, .002, .005, .01, .02, .05, .1},
-	})
+	}, []string{"model_version", "family"})
 
-	// PredictionCount tracks total predictions made.
-	PredictionCount = promauto.NewCounter(prometheus.CounterOpts{
+	// PredictionCount tracks total predictions made by model version and family.
+	PredictionCount = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "mlrf_predictions_total",
-		Help: "Total number of predictions made",
-	})
+		Help: "Total number of predictions made by model version and family",
+	}, []string{"model_version", "family"})
+
+	// InferenceErrors counts failed inference calls by model version and family.
+	InferenceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_inference_errors_total",
+		Help: "Total failed inference calls by model version and family",
+	}, []string{"model_version", "family"})
 
 	// BatchSize tracks the size of batch prediction requests.
 	BatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
@@ -58,11 +74,11 @@ var (
 		Help: "Current number of active HTTP connections",
 	})
 
-	// RateLimitRejections counts requests rejected due to rate limiting.
-	RateLimitRejections = promauto.NewCounter(prometheus.CounterOpts{
+	// RateLimitRejections counts requests rejected due to rate limiting by endpoint class.
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "mlrf_rate_limit_rejections_total",
-		Help: "Total number of requests rejected due to rate limiting",
-	})
+		Help: "Total number of requests rejected due to rate limiting by endpoint class",
+	}, []string{"class"})
 
 	// FeatureStoreLookups counts feature store lookup attempts.
 	FeatureStoreLookups = promauto.NewCounterVec(prometheus.CounterOpts{
@@ -70,19 +86,209 @@ var (
 		Help: "Total feature store lookup attempts by result type",
 	}, []string{"result"})
 
-	// HierarchyRequestDuration tracks hierarchy endpoint duration specifically.
-	HierarchyRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	// HierarchyRequestDuration tracks hierarchy tree assembly duration by cache result.
+	HierarchyRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "mlrf_hierarchy_request_duration_seconds",
-		Help:    "Hierarchy endpoint request duration in seconds",
-		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5},
+		Help:    "Hierarchy endpoint request duration in seconds by cache result (hit, miss)",
+		Buckets: []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"cache"})
+
+	// BacktestDuration tracks backtest duration by cache result.
+	BacktestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mlrf_backtest_duration_seconds",
+		Help:    "Backtest endpoint duration in seconds by cache result (hit, miss)",
+		Buckets: []float64{.001, .01, .1, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"cache"})
+
+	// ExplainDuration tracks SHAP explanation duration by cache result.
+	ExplainDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mlrf_explain_duration_seconds",
+		Help:    "Explain endpoint duration in seconds by cache result (hit, miss)",
+		Buckets: []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"cache"})
+
+	// AlertsFiring tracks the number of firing forecast accuracy alerts.
+	AlertsFiring = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_alerts_firing",
+		Help: "Current number of firing forecast accuracy alerts",
 	})
 
+	// AlertNotifications counts alert webhook notifications by outcome.
+	AlertNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_alert_notifications_total",
+		Help: "Total alert webhook notifications by result (sent, failed)",
+	}, []string{"result"})
+
+	// FeatureDriftPSI tracks the latest population stability index of each feature.
+	FeatureDriftPSI = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_feature_drift_psi",
+		Help: "Population stability index of each feature versus the training reference, as of the last drift check",
+	}, []string{"feature"})
+
 	// ExplainRequestDuration tracks SHAP explain endpoint duration.
 	ExplainRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "mlrf_explain_request_duration_seconds",
 		Help:    "SHAP explain endpoint request duration in seconds",
 		Buckets: []float64{.01, .05, .1, .25, .5, 1},
 	})
+
+	// PredictionLogEntries counts prediction log entries by outcome.
+	PredictionLogEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_prediction_log_entries_total",
+		Help: "Total prediction log entries by result (written, dropped, failed)",
+	}, []string{"result"})
+
+	// ShadowPredictionDelta tracks the absolute difference between challenger and champion predictions.
+	ShadowPredictionDelta = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mlrf_shadow_prediction_delta",
+		Help:    "Absolute difference between challenger and champion predictions",
+		Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+	}, []string{"challenger"})
+
+	// ShadowPredictionDeltaRatio tracks the relative difference between challenger and champion predictions.
+	ShadowPredictionDeltaRatio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mlrf_shadow_prediction_delta_ratio",
+		Help:    "Relative difference |challenger - champion| / |champion| between predictions",
+		Buckets: []float64{.01, .02, .05, .1, .2, .5, 1, 2},
+	}, []string{"challenger"})
+
+	// ShadowPredictions counts shadow inference attempts by outcome.
+	ShadowPredictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_shadow_predictions_total",
+		Help: "Total shadow predictions by challenger and result (ok, error, dropped)",
+	}, []string{"challenger", "result"})
+
+	// RemoteFetches counts object store downloads of model and feature files by outcome.
+	RemoteFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_remote_fetches_total",
+		Help: "Total object store fetches by result (downloaded, not_modified, cached, failed)",
+	}, []string{"result"})
+
+	// FileReloads counts reloads triggered by the file watcher by target and outcome.
+	FileReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_file_reloads_total",
+		Help: "Total file watch reloads by target (features, model, intervals) and result (reloaded, failed)",
+	}, []string{"target", "result"})
+
+	// IngestMessages counts streamed feature messages by outcome.
+	IngestMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_ingest_messages_total",
+		Help: "Total streamed feature messages by result (applied, invalid, rejected)",
+	}, []string{"result"})
+
+	// IngestLag tracks how many messages of each partition are not yet consumed.
+	IngestLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_ingest_lag_messages",
+		Help: "Messages behind the head of each streamed partition, as of the last message consumed",
+	}, []string{"partition"})
+
+	// IngestLastApplied tracks when a streamed batch was last applied to the feature store.
+	IngestLastApplied = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_ingest_last_applied_timestamp_seconds",
+		Help: "Unix timestamp of the last streamed feature batch applied",
+	})
+
+	// FeatureRefreshRuns counts scheduled feature refreshes by outcome.
+	FeatureRefreshRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_feature_refresh_runs_total",
+		Help: "Total scheduled feature refresh runs by result (ok, failed)",
+	}, []string{"result"})
+
+	// FeatureRefreshDuration tracks the duration of the last scheduled feature refresh.
+	FeatureRefreshDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_feature_refresh_last_duration_seconds",
+		Help: "Duration of the last scheduled feature refresh in seconds",
+	})
+
+	// FeatureRefreshLastSuccess tracks when a scheduled feature refresh last succeeded.
+	FeatureRefreshLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_feature_refresh_last_success_timestamp_seconds",
+		Help: "Unix time the last successful scheduled feature refresh started",
+	})
+
+	// ShapCircuitState tracks the state of the SHAP service circuit breaker.
+	ShapCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_shap_circuit_state",
+		Help: "State of the SHAP service circuit breaker (0 closed, 1 half-open, 2 open)",
+	})
+
+	// ShapExtraRequests counts SHAP service requests beyond the first of an explanation.
+	ShapExtraRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_shap_extra_requests_total",
+		Help: "Total SHAP service requests beyond the first of an explanation by kind (retry, hedge)",
+	}, []string{"kind"})
+
+	// MockResponses counts responses with fabricated data.
+	MockResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_mock_responses_total",
+		Help: "Total responses with fabricated (is_mock) data by endpoint",
+	}, []string{"endpoint"})
+
+	// ConcurrencyInFlight tracks requests holding a concurrency limiter slot.
+	ConcurrencyInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_concurrency_in_flight",
+		Help: "Inference-heavy requests being served under the concurrency limit",
+	})
+
+	// ConcurrencyQueueDepth tracks requests waiting for a concurrency limiter slot.
+	ConcurrencyQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mlrf_concurrency_queue_depth",
+		Help: "Inference-heavy requests waiting for a concurrency limiter slot",
+	})
+
+	// LoadShed counts requests shed by the concurrency limiter by endpoint class and reason.
+	LoadShed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_load_shed_total",
+		Help: "Total requests shed by the concurrency limiter by endpoint class and reason (queue_full, timeout, canceled)",
+	}, []string{"class", "reason"})
+
+	// Panics counts handler panics recovered by endpoint.
+	Panics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_panics_total",
+		Help: "Total handler panics recovered by endpoint",
+	}, []string{"endpoint"})
+
+	// PredictionGuard counts predictions the sanity guard replaced or rejected by action.
+	PredictionGuard = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_prediction_guard_total",
+		Help: "Total NaN, infinite or out-of-range predictions by guard action (clamped, zeroed, rejected)",
+	}, []string{"action"})
+
+	// DeduplicatedPredictions counts cache misses served by a concurrent request's inference.
+	DeduplicatedPredictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mlrf_predictions_deduplicated_total",
+		Help: "Total /predict/simple cache misses that shared a concurrent identical request's inference",
+	})
+
+	// SLORequests counts requests to routes with objectives by SLI and whether they met it.
+	SLORequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mlrf_slo_requests_total",
+		Help: "Requests to routes with service level objectives by SLI (availability, latency) and result (good, bad)",
+	}, []string{"route", "sli", "result"})
+
+	// SLOObjective is the target good-request ratio of each route and SLI.
+	SLOObjective = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_slo_objective",
+		Help: "Target ratio of good requests by route and SLI",
+	}, []string{"route", "sli"})
+
+	// SLOCompliance is the good-request ratio of each route and SLI over the SLO window.
+	SLOCompliance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_slo_compliance",
+		Help: "Ratio of good requests over the SLO window by route and SLI",
+	}, []string{"route", "sli"})
+
+	// SLOErrorBudgetRemaining is the fraction of the error budget left over the SLO window.
+	SLOErrorBudgetRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_slo_error_budget_remaining",
+		Help: "Fraction of the error budget left over the SLO window by route and SLI; negative once exhausted",
+	}, []string{"route", "sli"})
+
+	// SLOBurnRate is the rate the error budget is spent at over recent windows.
+	SLOBurnRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mlrf_slo_burn_rate",
+		Help: "Error budget burn rate by route, SLI and window; 1 spends the budget exactly over the SLO window",
+	}, []string{"route", "sli", "window"})
 )
 
 // RecordCacheHit increments the cache hit counter.
@@ -95,10 +301,54 @@ func RecordCacheMiss() {
 	CacheMisses.Inc()
 }
 
-// RecordInference records an inference operation with its duration.
-func RecordInference(durationSeconds float64) {
-	InferenceDuration.Observe(durationSeconds)
-	PredictionCount.Inc()
+// RecordCacheEarlyExpiration increments the early expiration counter.
+func RecordCacheEarlyExpiration() {
+	CacheEarlyExpirations.Inc()
+}
+
+// RecordInference records an inference operation of a model on a family with its
+// duration. An empty model is the default model.
+func RecordInference(model, family string, durationSeconds float64) {
+	version := modelVersionLabel(model)
+	InferenceDuration.WithLabelValues(version, familyLabelValue(family)).Observe(durationSeconds)
+	PredictionCount.WithLabelValues(version, familyLabelValue(family)).Inc()
+}
+
+// RecordBatchInference records a batch inference operation of a model with its
+// duration, counting a prediction for the family of each row.
+func RecordBatchInference(model string, families []string, durationSeconds float64) {
+	version := modelVersionLabel(model)
+	InferenceDuration.WithLabelValues(version, batchFamilyLabel(families)).Observe(durationSeconds)
+
+	counts := make(map[string]int)
+	for _, family := range families {
+		counts[familyLabelValue(family)]++
+	}
+	for family, n := range counts {
+		PredictionCount.WithLabelValues(version, family).Add(float64(n))
+	}
+}
+
+// RecordInferenceError records a failed inference call of a model on rows of families.
+func RecordInferenceError(model string, families []string) {
+	InferenceErrors.WithLabelValues(modelVersionLabel(model), batchFamilyLabel(families)).Inc()
+}
+
+// batchFamilyLabel returns the family label of a batch: its family if every row has
+// the same, else "mixed".
+func batchFamilyLabel(families []string) string {
+	if len(families) == 0 {
+		return familyLabelValue("")
+	}
+	for _, family := range families[1:] {
+		if family != families[0] {
+			if familyLabelValue(family) == LabelAll {
+				return LabelAll
+			}
+			return LabelMixed
+		}
+	}
+	return familyLabelValue(families[0])
 }
 
 // RecordBatchSize records the size of a batch request.
@@ -106,13 +356,174 @@ func RecordBatchSize(size int) {
 	BatchSize.Observe(float64(size))
 }
 
-// RecordRateLimitRejection increments the rate limit rejection counter.
-func RecordRateLimitRejection() {
-	RateLimitRejections.Inc()
+// RecordRateLimitRejection increments the rate limit rejection counter of an endpoint class.
+// class should be one of: "read", "predict", "batch", "explain", "admin"
+func RecordRateLimitRejection(class string) {
+	RateLimitRejections.WithLabelValues(class).Inc()
 }
 
 // RecordFeatureStoreLookup records a feature store lookup result.
-// result should be one of: "exact", "aggregated", "zero_fallback"
+// result should be one of: "exact", "rolled_forward", "aggregated", "zeros"
 func RecordFeatureStoreLookup(result string) {
 	FeatureStoreLookups.WithLabelValues(result).Inc()
 }
+
+// RecordHierarchyRequest records how long a hierarchy tree took to serve.
+// cache should be one of: "hit", "miss"
+func RecordHierarchyRequest(cache string, durationSeconds float64) {
+	HierarchyRequestDuration.WithLabelValues(cache).Observe(durationSeconds)
+}
+
+// RecordBacktest records how long a backtest took to serve.
+// cache should be one of: "hit", "miss"
+func RecordBacktest(cache string, durationSeconds float64) {
+	BacktestDuration.WithLabelValues(cache).Observe(durationSeconds)
+}
+
+// RecordExplainRequest records how long a SHAP explanation took to serve.
+// cache should be one of: "hit", "miss"
+func RecordExplainRequest(cache string, durationSeconds float64) {
+	ExplainDuration.WithLabelValues(cache).Observe(durationSeconds)
+}
+
+// SetAlertsFiring sets the number of firing accuracy alerts.
+func SetAlertsFiring(n int) {
+	AlertsFiring.Set(float64(n))
+}
+
+// RecordAlertNotification records an alert webhook notification.
+// result should be one of: "sent", "failed"
+func RecordAlertNotification(result string) {
+	AlertNotifications.WithLabelValues(result).Inc()
+}
+
+// SetFeatureDrift records the latest PSI of a feature.
+func SetFeatureDrift(feature string, psi float64) {
+	FeatureDriftPSI.WithLabelValues(feature).Set(psi)
+}
+
+// RecordPredictionLog records prediction log entries by outcome.
+// result should be one of: "written", "dropped", "failed"
+func RecordPredictionLog(result string, n int) {
+	PredictionLogEntries.WithLabelValues(result).Add(float64(n))
+}
+
+// RecordShadowDelta records the difference between a challenger and champion prediction.
+func RecordShadowDelta(challenger string, champion, shadow float32) {
+	delta := float64(shadow - champion)
+	if delta < 0 {
+		delta = -delta
+	}
+	ShadowPredictionDelta.WithLabelValues(challenger).Observe(delta)
+	if champion != 0 {
+		base := float64(champion)
+		if base < 0 {
+			base = -base
+		}
+		ShadowPredictionDeltaRatio.WithLabelValues(challenger).Observe(delta / base)
+	}
+	ShadowPredictions.WithLabelValues(challenger, "ok").Inc()
+}
+
+// RecordShadowResult records a shadow prediction that produced no delta.
+// result should be one of: "error", "dropped"
+func RecordShadowResult(challenger, result string) {
+	ShadowPredictions.WithLabelValues(challenger, result).Inc()
+}
+
+// RecordRemoteFetch records an object store fetch.
+// result should be one of: "downloaded", "not_modified", "cached", "failed"
+func RecordRemoteFetch(result string) {
+	RemoteFetches.WithLabelValues(result).Inc()
+}
+
+// RecordFileReload records a reload triggered by the file watcher.
+// result should be one of: "reloaded", "failed"
+func RecordFileReload(target, result string) {
+	FileReloads.WithLabelValues(target, result).Inc()
+}
+
+// RecordIngestMessages records streamed feature messages by outcome.
+// result should be one of: "applied", "invalid", "rejected"
+func RecordIngestMessages(result string, n int) {
+	IngestMessages.WithLabelValues(result).Add(float64(n))
+	if result == "applied" {
+		IngestLastApplied.SetToCurrentTime()
+	}
+}
+
+// SetIngestLag records the messages behind the head of a streamed partition.
+func SetIngestLag(partition int, lag int64) {
+	IngestLag.WithLabelValues(strconv.Itoa(partition)).Set(float64(lag))
+}
+
+// RecordFeatureRefresh records a scheduled feature refresh that started at start.
+// result should be one of: "ok", "failed"
+func RecordFeatureRefresh(result string, durationSeconds float64, start time.Time) {
+	FeatureRefreshRuns.WithLabelValues(result).Inc()
+	FeatureRefreshDuration.Set(durationSeconds)
+	if result == "ok" {
+		FeatureRefreshLastSuccess.Set(float64(start.Unix()))
+	}
+}
+
+// SetShapCircuitState records the state of the SHAP service circuit breaker.
+// state should be one of: 0 (closed), 1 (half-open), 2 (open)
+func SetShapCircuitState(state int) {
+	ShapCircuitState.Set(float64(state))
+}
+
+// RecordShapExtraRequest records a retried or hedged SHAP service request.
+// kind should be one of: "retry", "hedge"
+func RecordShapExtraRequest(kind string) {
+	ShapExtraRequests.WithLabelValues(kind).Inc()
+}
+
+// RecordMockResponse records a response with fabricated data.
+// endpoint should be one of: "accuracy", "historical", "hierarchy", "explain"
+func RecordMockResponse(endpoint string) {
+	MockResponses.WithLabelValues(endpoint).Inc()
+}
+
+// RecordLoadShed records a request shed by the concurrency limiter.
+// reason should be one of: "queue_full", "timeout", "canceled"
+func RecordLoadShed(class, reason string) {
+	LoadShed.WithLabelValues(class, reason).Inc()
+}
+
+// RecordPanic records a handler panic recovered on endpoint.
+func RecordPanic(endpoint string) {
+	Panics.WithLabelValues(endpoint).Inc()
+}
+
+// RecordPredictionGuard records a prediction the sanity guard acted on.
+// action should be one of: "clamped", "zeroed", "rejected"
+func RecordPredictionGuard(action string) {
+	PredictionGuard.WithLabelValues(action).Inc()
+}
+
+// RecordDeduplicatedPrediction records a cache miss that shared another request's inference.
+func RecordDeduplicatedPrediction() {
+	DeduplicatedPredictions.Inc()
+}
+
+// RecordSLORequest records whether a request met a route's SLI.
+// sli should be one of: "availability", "latency"
+func RecordSLORequest(route, sli string, good bool) {
+	result := "good"
+	if !good {
+		result = "bad"
+	}
+	SLORequests.WithLabelValues(route, sli, result).Inc()
+}
+
+// SetSLOStatus records the objective, compliance, remaining error budget and burn
+// rates by window of a route's SLI.
+func SetSLOStatus(route, sli string, objective, compliance, budgetRemaining float64, burnRates map[string]float64) {
+	SLOObjective.WithLabelValues(route, sli).Set(objective)
+	SLOCompliance.WithLabelValues(route, sli).Set(compliance)
+	SLOErrorBudgetRemaining.WithLabelValues(route, sli).Set(budgetRemaining)
+	for window, rate := range burnRates {
+		SLOBurnRate.WithLabelValues(route, sli, window).Set(rate)
+	}
+}
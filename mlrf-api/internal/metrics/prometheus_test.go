@@ -67,17 +67,74 @@ func TestCacheMetrics(t *testing.T) {
 
 func TestInferenceMetrics(t *testing.T) {
 	// Record inference operations
-	initialPredictions := testutil.ToFloat64(PredictionCount)
+	predictions := PredictionCount.WithLabelValues("lightgbm@v2", "BEVERAGES")
+	initialPredictions := testutil.ToFloat64(predictions)
 
-	RecordInference(0.002) // 2ms
-	RecordInference(0.003) // 3ms
+	RecordInference("lightgbm@v2", "BEVERAGES", 0.002) // 2ms
+	RecordInference("lightgbm@v2", "BEVERAGES", 0.003) // 3ms
 
 	// Verify prediction count incremented
-	if v := testutil.ToFloat64(PredictionCount) - initialPredictions; v != 2 {
+	if v := testutil.ToFloat64(predictions) - initialPredictions; v != 2 {
 		t.Errorf("expected 2 predictions, got %v", v)
 	}
 }
 
+func TestBatchInferenceMetrics(t *testing.T) {
+	dairy := PredictionCount.WithLabelValues(LabelDefault, "DAIRY")
+	eggs := PredictionCount.WithLabelValues(LabelDefault, "EGGS")
+	initialDairy, initialEggs := testutil.ToFloat64(dairy), testutil.ToFloat64(eggs)
+	mixed := testutil.CollectAndCount(InferenceDuration)
+
+	RecordBatchInference("", []string{"DAIRY", "EGGS", "DAIRY"}, 0.01)
+
+	if v := testutil.ToFloat64(dairy) - initialDairy; v != 2 {
+		t.Errorf("expected 2 DAIRY predictions, got %v", v)
+	}
+	if v := testutil.ToFloat64(eggs) - initialEggs; v != 1 {
+		t.Errorf("expected 1 EGGS prediction, got %v", v)
+	}
+	if testutil.CollectAndCount(InferenceDuration) != mixed+1 {
+		t.Error("expected the batch duration under one mixed family label")
+	}
+}
+
+func TestInferenceErrorMetrics(t *testing.T) {
+	errs := InferenceErrors.WithLabelValues("challenger", LabelMixed)
+	initial := testutil.ToFloat64(errs)
+
+	RecordInferenceError("challenger", []string{"DAIRY", "EGGS"})
+
+	if v := testutil.ToFloat64(errs) - initial; v != 1 {
+		t.Errorf("expected 1 mixed inference error, got %v", v)
+	}
+}
+
+func TestLabelLimits(t *testing.T) {
+	defer SetLabelConfig(DefaultLabelConfig())
+
+	l := newLabelLimiter(2)
+	for _, v := range []string{"a", "b", "c", "a"} {
+		l.value(v)
+	}
+	if got := l.value("c"); got != LabelOther {
+		t.Errorf("expected a value past the limit to be %q, got %q", LabelOther, got)
+	}
+	if got := l.value("a"); got != "a" {
+		t.Errorf("expected a value seen before the limit to keep its label, got %q", got)
+	}
+
+	SetLabelConfig(LabelConfig{FamilyLabel: false, MaxFamilies: 50, MaxModelVersions: 10})
+	if got := familyLabelValue("DAIRY"); got != LabelAll {
+		t.Errorf("expected family %q with the family label disabled, got %q", LabelAll, got)
+	}
+	if got := batchFamilyLabel([]string{"DAIRY", "EGGS"}); got != LabelAll {
+		t.Errorf("expected batch family %q with the family label disabled, got %q", LabelAll, got)
+	}
+	if got := modelVersionLabel(""); got != LabelDefault {
+		t.Errorf("expected model version %q for the default model, got %q", LabelDefault, got)
+	}
+}
+
 func TestBatchSizeMetrics(t *testing.T) {
 	// Record batch sizes (histograms accumulate - verify they don't panic)
 	RecordBatchSize(10)
@@ -92,13 +149,13 @@ func TestBatchSizeMetrics(t *testing.T) {
 }
 
 func TestRateLimitRejectionMetrics(t *testing.T) {
-	initial := testutil.ToFloat64(RateLimitRejections)
+	initial := testutil.ToFloat64(RateLimitRejections.WithLabelValues("batch"))
 
-	RecordRateLimitRejection()
-	RecordRateLimitRejection()
-	RecordRateLimitRejection()
+	RecordRateLimitRejection("batch")
+	RecordRateLimitRejection("batch")
+	RecordRateLimitRejection("batch")
 
-	if v := testutil.ToFloat64(RateLimitRejections) - initial; v != 3 {
+	if v := testutil.ToFloat64(RateLimitRejections.WithLabelValues("batch")) - initial; v != 3 {
 		t.Errorf("expected 3 rate limit rejections, got %v", v)
 	}
 }
@@ -110,7 +167,7 @@ func TestFeatureStoreLookupMetrics(t *testing.T) {
 	RecordFeatureStoreLookup("exact")
 	RecordFeatureStoreLookup("exact")
 	RecordFeatureStoreLookup("aggregated")
-	RecordFeatureStoreLookup("zero_fallback")
+	RecordFeatureStoreLookup("zeros")
 
 	if v := testutil.ToFloat64(FeatureStoreLookups.WithLabelValues("exact")); v != 2 {
 		t.Errorf("expected 2 exact lookups, got %v", v)
@@ -120,8 +177,8 @@ func TestFeatureStoreLookupMetrics(t *testing.T) {
 		t.Errorf("expected 1 aggregated lookup, got %v", v)
 	}
 
-	if v := testutil.ToFloat64(FeatureStoreLookups.WithLabelValues("zero_fallback")); v != 1 {
-		t.Errorf("expected 1 zero_fallback lookup, got %v", v)
+	if v := testutil.ToFloat64(FeatureStoreLookups.WithLabelValues("zeros")); v != 1 {
+		t.Errorf("expected 1 zeros lookup, got %v", v)
 	}
 }
 
@@ -204,3 +261,22 @@ func TestMetricNaming(t *testing.T) {
 		}
 	}
 }
+
+func TestShadowMetrics(t *testing.T) {
+	initialOK := testutil.ToFloat64(ShadowPredictions.WithLabelValues("challenger", "ok"))
+	initialDropped := testutil.ToFloat64(ShadowPredictions.WithLabelValues("challenger", "dropped"))
+
+	RecordShadowDelta("challenger", 100, 110)
+	RecordShadowDelta("challenger", 0, 5) // zero champion skips ratio
+	RecordShadowResult("challenger", "dropped")
+
+	if v := testutil.ToFloat64(ShadowPredictions.WithLabelValues("challenger", "ok")) - initialOK; v != 2 {
+		t.Errorf("expected 2 ok shadow predictions, got %v", v)
+	}
+	if v := testutil.ToFloat64(ShadowPredictions.WithLabelValues("challenger", "dropped")) - initialDropped; v != 1 {
+		t.Errorf("expected 1 dropped shadow prediction, got %v", v)
+	}
+	if count := testutil.CollectAndCount(ShadowPredictionDelta); count < 1 {
+		t.Error("expected delta histogram to have collected metrics")
+	}
+}
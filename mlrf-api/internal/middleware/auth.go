@@ -2,9 +2,34 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers carrying API keys. Keys are never read from query parameters, which end up
+// in access logs, browser history and referrers; /ws takes a short-lived token instead.
+const (
+	APIKeyHeader   = "X-API-Key"
+	AdminKeyHeader = "X-Admin-Key" // Admin key; takes precedence over X-API-Key
+)
+
+// Scopes granted only to keys that list them (or "*"). Any other scope, including
+// ScopeWrite, is granted to keys without a scope list.
+const (
+	ScopeAdmin = "admin" // /admin/* endpoints
+	ScopeWrite = "write" // Endpoints storing data or queueing work: /actuals, /predict/jobs
 )
 
 // errorResponse is the standard error response structure.
@@ -13,38 +38,333 @@ type errorResponse struct {
 	Code  string `json:"code"`
 }
 
-// APIKeyAuth returns middleware that validates API key authentication.
-// If API_KEY environment variable is not set, authentication is disabled (dev mode).
-// The /health endpoint is always accessible without authentication.
-func APIKeyAuth(next http.Handler) http.Handler {
-	apiKey := os.Getenv("API_KEY")
+// publicPaths are served without authentication.
+var publicPaths = map[string]bool{
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+// apiKeyKey is the context key for the API key that authenticated the request.
+type apiKeyKey struct{}
+
+// APIKey is a named API key and its metadata. The secret itself is not exported.
+type APIKey struct {
+	Name   string   `json:"name"`
+	Owner  string   `json:"owner,omitempty"`
+	Scopes []string `json:"scopes,omitempty"` // Path prefixes and scopes the key may use; empty allows all but admin
+
+	// Limits replacing the per-IP rate limit for requests with this key (0 = default)
+	RateLimitRPS   float64 `json:"rate_limit_rps,omitempty"`
+	RateLimitBurst int     `json:"rate_limit_burst,omitempty"`
+	MonthlyQuota   int64   `json:"monthly_quota,omitempty"` // Requests per calendar month (UTC); 0 = unlimited
+
+	hash [sha256.Size]byte
+}
 
-	// If no API key configured, skip authentication (dev mode)
-	if apiKey == "" {
-		return next
+// HasScope reports whether the key may call endpoints in scope.
+func (k *APIKey) HasScope(scope string) bool {
+	if len(k.Scopes) == 0 {
+		return scope != ScopeAdmin
 	}
+	for _, s := range k.Scopes {
+		if s == "*" || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStoreConfig holds where API keys are loaded from.
+type KeyStoreConfig struct {
+	Key      string // Single key named "default" with every scope but admin
+	File     string // JSON file of named keys with owners and scopes
+	AdminKey string // Key named "admin" with every scope
+}
+
+// DefaultKeyStoreConfig returns a key store configuration without keys, which
+// disables authentication.
+func DefaultKeyStoreConfig() KeyStoreConfig {
+	return KeyStoreConfig{}
+}
+
+// keyFile is the format of API_KEYS_FILE.
+type keyFile struct {
+	Keys []struct {
+		APIKey
+		Key string `json:"key"`
+	} `json:"keys"`
+}
+
+// KeyStore holds the accepted API keys. Keys can be replaced while serving, so
+// rotated keys take effect without a restart.
+// Thread-safe.
+type KeyStore struct {
+	keys      atomic.Pointer[[]*APIKey]
+	anonymous atomic.Bool                    // Only an admin key is set: requests without a key are allowed
+	auditLog  atomic.Pointer[zerolog.Logger] // Audit log; the process log if nil
+}
 
+// NewKeyStore loads the keys described by cfg. A store without keys disables
+// authentication (dev mode); with only an admin key, only scoped endpoints need one.
+func NewKeyStore(cfg KeyStoreConfig) (*KeyStore, error) {
+	s := &KeyStore{}
+	if err := s.Load(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load replaces the keys with those described by cfg. On an error the current keys
+// are kept.
+func (s *KeyStore) Load(cfg KeyStoreConfig) error {
+	var keys []*APIKey
+	names := make(map[string]bool)
+	add := func(k APIKey, secret string) error {
+		switch {
+		case k.Name == "":
+			return fmt.Errorf("API key without a name")
+		case secret == "":
+			return fmt.Errorf("API key %q is empty", k.Name)
+		case names[k.Name]:
+			return fmt.Errorf("API key %q is listed twice", k.Name)
+		case k.RateLimitRPS < 0 || k.RateLimitBurst < 0 || k.MonthlyQuota < 0:
+			return fmt.Errorf("API key %q has a negative limit", k.Name)
+		}
+		names[k.Name] = true
+		k.hash = sha256.Sum256([]byte(secret))
+		keys = append(keys, &k)
+		return nil
+	}
+
+	if cfg.Key != "" {
+		if err := add(APIKey{Name: "default"}, cfg.Key); err != nil {
+			return err
+		}
+	}
+	if cfg.AdminKey != "" {
+		if err := add(APIKey{Name: "admin", Scopes: []string{"*"}}, cfg.AdminKey); err != nil {
+			return err
+		}
+	}
+	if cfg.File != "" {
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return fmt.Errorf("failed to read API keys: %w", err)
+		}
+		var f keyFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("failed to parse API keys: %w", err)
+		}
+		for _, k := range f.Keys {
+			if err := add(k.APIKey, k.Key); err != nil {
+				return fmt.Errorf("%s: %w", cfg.File, err)
+			}
+		}
+	}
+
+	s.keys.Store(&keys)
+	s.anonymous.Store(cfg.Key == "" && cfg.File == "")
+	return nil
+}
+
+// Keys returns the metadata of the accepted keys.
+func (s *KeyStore) Keys() []APIKey {
+	keys := *s.keys.Load()
+	out := make([]APIKey, len(keys))
+	for i, k := range keys {
+		out[i] = *k
+		out[i].hash = [sha256.Size]byte{}
+	}
+	return out
+}
+
+// Enabled reports whether any key is configured.
+func (s *KeyStore) Enabled() bool {
+	return len(*s.keys.Load()) > 0
+}
+
+// Lookup returns the key matching secret. Every key is compared in constant time so
+// the response time reveals neither the key nor its position.
+func (s *KeyStore) Lookup(secret string) (*APIKey, bool) {
+	hash := sha256.Sum256([]byte(secret))
+	var match *APIKey
+	for _, k := range *s.keys.Load() {
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			match = k
+		}
+	}
+	return match, match != nil
+}
+
+// Middleware validates the X-Admin-Key or X-API-Key header against the store, or on
+// /ws a WebSocket token in the query. The /health endpoints and the API docs are
+// always accessible, and a key limited to scopes may only call paths whose first
+// segment (after the version prefix) is one of them.
+func (s *KeyStore) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Always allow health checks without auth
-		if r.URL.Path == "/health" {
+		if publicPaths[r.URL.Path] || !s.Enabled() {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Check for API key in header only (query params are logged and insecure)
-		key := r.Header.Get("X-API-Key")
-
-		// Validate API key
-		if key != apiKey {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(errorResponse{
-				Error: "unauthorized: invalid or missing API key",
-				Code:  "AUTH_REQUIRED",
-			})
+		secret := requestKey(r)
+		token := ""
+		if tokenPaths[r.URL.Path] {
+			token = r.URL.Query().Get(WebSocketTokenParam)
+		}
+		if secret == "" && token == "" && s.anonymous.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var key *APIKey
+		var ok bool
+		if secret == "" && token != "" {
+			key, ok = s.tokenKey(token, time.Now())
+		} else {
+			key, ok = s.Lookup(secret)
+		}
+		if !ok {
+			writeAuthError(w, http.StatusUnauthorized, "unauthorized: invalid or missing API key", "AUTH_REQUIRED")
+			return
+		}
+		setAccessAPIKey(r.Context(), key.Name)
+		if scope := requestScope(r.URL.Path); !key.HasScope(scope) {
+			s.auditDenied(r, key, scope)
+			writeAuthError(w, http.StatusForbidden, "forbidden: API key "+key.Name+" lacks scope "+scope, "INSUFFICIENT_SCOPE")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, key)))
 	})
 }
+
+// RequireScope returns middleware that only serves requests whose API key has
+// scope: 401 without a key, 403 with a key lacking the scope. Every privileged call
+// is written to the audit log with the key, its owner and the response status.
+// Without keys (dev mode) every request is served.
+func (s *KeyStore) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, ok := APIKeyFromContext(r.Context())
+			switch {
+			case !ok && s.anonymous.Load() && scope != ScopeAdmin:
+				// Only an admin key is set, so requests without a key keep every other scope
+				key = &APIKey{Name: "anonymous"}
+			case !ok:
+				writeAuthError(w, http.StatusUnauthorized, "unauthorized: "+scope+" endpoints require an API key", "AUTH_REQUIRED")
+				return
+			case !key.HasScope(scope):
+				s.auditDenied(r, key, scope)
+				writeAuthError(w, http.StatusForbidden, "forbidden: API key "+key.Name+" lacks scope "+scope, "INSUFFICIENT_SCOPE")
+				return
+			}
+
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			s.audit().Info().
+				Bool("audit", true).
+				Str("api_key", key.Name).
+				Str("owner", key.Owner).
+				Str("scope", scope).
+				Str("request_id", middleware.GetReqID(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", rw.Status()).
+				Dur("duration", time.Since(start)).
+				Str("remote_addr", ClientIP(r)).
+				Msg("Privileged request")
+		})
+	}
+}
+
+// SetAuditLogger sends the audit log of privileged and denied requests to logger
+// instead of the process log.
+func (s *KeyStore) SetAuditLogger(logger zerolog.Logger) {
+	s.auditLog.Store(&logger)
+}
+
+// audit returns the audit logger.
+func (s *KeyStore) audit() *zerolog.Logger {
+	if l := s.auditLog.Load(); l != nil {
+		return l
+	}
+	return &log.Logger
+}
+
+// auditDenied writes a request refused for lacking scope to the audit log.
+func (s *KeyStore) auditDenied(r *http.Request, key *APIKey, scope string) {
+	s.audit().Warn().
+		Bool("audit", true).
+		Str("api_key", key.Name).
+		Str("owner", key.Owner).
+		Str("scope", scope).
+		Str("request_id", middleware.GetReqID(r.Context())).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int("status", http.StatusForbidden).
+		Str("remote_addr", ClientIP(r)).
+		Msg("Request denied: missing scope")
+}
+
+// requestKey returns the key sent with the request, preferring X-Admin-Key.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(AdminKeyHeader); key != "" {
+		return key
+	}
+	return r.Header.Get(APIKeyHeader)
+}
+
+// APIKeyFromContext returns the API key that authenticated the request, if any.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey{}).(*APIKey)
+	return key, ok
+}
+
+// APIKeyAuth returns middleware that validates API key authentication with the keys
+// of cfg. Without keys authentication is disabled (dev mode); if the keys file can't
+// be loaded every request is rejected.
+// The /health endpoints and the API docs (/openapi.json, /docs) are always accessible without authentication.
+func APIKeyAuth(cfg KeyStoreConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		store, err := NewKeyStore(cfg)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load API keys, rejecting requests")
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if publicPaths[r.URL.Path] {
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeAuthError(w, http.StatusUnauthorized, "unauthorized: invalid or missing API key", "AUTH_REQUIRED")
+			})
+		}
+		return store.Middleware(next)
+	}
+}
+
+// requestScope returns the scope of a request path: its first segment after an
+// optional /v1 prefix, e.g. "predict" for /v1/predict/batch.
+func requestScope(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	if rest, ok := strings.CutPrefix(path, "v1/"); ok {
+		path = rest
+	}
+	scope, _, _ := strings.Cut(path, "/")
+	return scope
+}
+
+// writeAuthError writes an authentication or authorization error.
+func writeAuthError(w http.ResponseWriter, status int, msg, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg, Code: code})
+}
@@ -5,19 +5,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestAPIKeyAuth_NoKeyConfigured(t *testing.T) {
-	// Unset API_KEY to test dev mode
-	os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{})(handler)
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -30,16 +29,12 @@ func TestAPIKeyAuth_NoKeyConfigured(t *testing.T) {
 }
 
 func TestAPIKeyAuth_HealthEndpointAlwaysAllowed(t *testing.T) {
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("healthy"))
 	})
 
-	// Need to recreate handler after setting env var
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	rec := httptest.NewRecorder()
@@ -52,15 +47,12 @@ func TestAPIKeyAuth_HealthEndpointAlwaysAllowed(t *testing.T) {
 }
 
 func TestAPIKeyAuth_ValidKeyInHeader(t *testing.T) {
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	req := httptest.NewRequest("GET", "/predict", nil)
 	req.Header.Set("X-API-Key", "test-secret-key")
@@ -78,15 +70,12 @@ func TestAPIKeyAuth_QueryParamNotAccepted(t *testing.T) {
 	// - They appear in server access logs
 	// - They're stored in browser history
 	// - They can leak via referrer headers
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	// API key in query param should be rejected - must use header
 	req := httptest.NewRequest("GET", "/predict?api_key=test-secret-key", nil)
@@ -100,14 +89,11 @@ func TestAPIKeyAuth_QueryParamNotAccepted(t *testing.T) {
 }
 
 func TestAPIKeyAuth_InvalidKey(t *testing.T) {
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	req := httptest.NewRequest("GET", "/predict", nil)
 	req.Header.Set("X-API-Key", "wrong-key")
@@ -128,14 +114,11 @@ func TestAPIKeyAuth_InvalidKey(t *testing.T) {
 }
 
 func TestAPIKeyAuth_MissingKey(t *testing.T) {
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	req := httptest.NewRequest("GET", "/predict", nil)
 	rec := httptest.NewRecorder()
@@ -155,14 +138,11 @@ func TestAPIKeyAuth_MissingKey(t *testing.T) {
 }
 
 func TestAPIKeyAuth_HeaderOnlyChecked(t *testing.T) {
-	os.Setenv("API_KEY", "test-secret-key")
-	defer os.Unsetenv("API_KEY")
-
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := APIKeyAuth(handler)
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
 
 	// Only header is checked - query param is completely ignored (for security)
 	req := httptest.NewRequest("GET", "/predict?api_key=wrong-key", nil)
@@ -175,3 +155,163 @@ func TestAPIKeyAuth_HeaderOnlyChecked(t *testing.T) {
 		t.Errorf("expected status 200, got %d", rec.Code)
 	}
 }
+
+func TestAPIKeyAuth_DocsEndpointsAllowed(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrappedHandler := APIKeyAuth(KeyStoreConfig{Key: "test-secret-key"})(handler)
+
+	for _, path := range []string{"/openapi.json", "/docs"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"keys": [
+		{"name": "dashboard", "key": "dash-key", "owner": "web team"},
+		{"name": "planning", "key": "plan-key", "owner": "planning", "scopes": ["predict", "hierarchy"]}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewKeyStore(KeyStoreConfig{Key: "env-key", File: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys := store.Keys(); len(keys) != 3 || keys[0].Name != "default" || keys[2].Owner != "planning" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	var gotKey string
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := APIKeyFromContext(r.Context()); ok {
+			gotKey = key.Name
+		}
+	}))
+	for _, tc := range []struct {
+		path, key string
+		status    int
+		name      string
+	}{
+		{"/v1/predict", "env-key", http.StatusOK, "default"},
+		{"/v1/accuracy", "dash-key", http.StatusOK, "dashboard"},
+		{"/v1/predict/batch", "plan-key", http.StatusOK, "planning"},
+		{"/hierarchy", "plan-key", http.StatusOK, "planning"},
+		{"/v1/actuals", "plan-key", http.StatusForbidden, ""},
+		{"/v1/predict", "plan-key-", http.StatusUnauthorized, ""},
+	} {
+		gotKey = ""
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.Header.Set(APIKeyHeader, tc.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status || gotKey != tc.name {
+			t.Errorf("%s with %s: expected %d as %q, got %d as %q", tc.path, tc.key, tc.status, tc.name, rec.Code, gotKey)
+		}
+	}
+
+	// Rotating keys replaces them; an invalid file keeps the current ones
+	if err := store.Load(KeyStoreConfig{Key: "rotated"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Lookup("env-key"); ok {
+		t.Error("expected the old key rejected after rotation")
+	}
+	if err := os.WriteFile(path, []byte(`{"keys": [{"name": "a", "key": "x"}, {"name": "a", "key": "y"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Load(KeyStoreConfig{File: path}); err == nil || !strings.Contains(err.Error(), "listed twice") {
+		t.Errorf("expected a duplicate name error, got %v", err)
+	}
+	if _, ok := store.Lookup("rotated"); !ok {
+		t.Error("expected the current keys kept after a failed load")
+	}
+}
+
+func TestAPIKeyAuth_UnreadableKeysFile(t *testing.T) {
+	cfg := KeyStoreConfig{File: filepath.Join(t.TempDir(), "missing.json")}
+	handler := APIKeyAuth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for path, want := range map[string]int{"/predict": http.StatusUnauthorized, "/health": http.StatusOK} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"keys": [
+		{"name": "reader", "key": "read-key", "scopes": ["predict"]},
+		{"name": "loader", "key": "load-key", "owner": "data team", "scopes": ["predict", "actuals", "write"]},
+		{"name": "ops", "key": "ops-key", "scopes": ["admin"]}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(store *KeyStore, scope, path, header, key string) int {
+		h := store.Middleware(store.RequireScope(scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if key != "" {
+			req.Header.Set(header, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	store, err := NewKeyStore(KeyStoreConfig{Key: "env-key", File: path, AdminKey: "admin-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		scope, path, header, key string
+		status                   int
+	}{
+		{ScopeWrite, "/v1/predict/jobs", APIKeyHeader, "", http.StatusUnauthorized},
+		{ScopeWrite, "/v1/predict/jobs", APIKeyHeader, "env-key", http.StatusOK},
+		{ScopeWrite, "/v1/predict/jobs", APIKeyHeader, "read-key", http.StatusForbidden},
+		{ScopeWrite, "/v1/predict/jobs", APIKeyHeader, "load-key", http.StatusOK},
+		{ScopeWrite, "/v1/actuals", APIKeyHeader, "load-key", http.StatusOK},
+		{ScopeAdmin, "/admin/reload-model", APIKeyHeader, "env-key", http.StatusForbidden},
+		{ScopeAdmin, "/admin/reload-model", APIKeyHeader, "load-key", http.StatusForbidden},
+		{ScopeAdmin, "/admin/reload-model", APIKeyHeader, "ops-key", http.StatusOK},
+		{ScopeAdmin, "/admin/reload-model", AdminKeyHeader, "admin-key", http.StatusOK},
+		{ScopeAdmin, "/admin/reload-model", AdminKeyHeader, "wrong", http.StatusUnauthorized},
+	} {
+		if got := send(store, tc.scope, tc.path, tc.header, tc.key); got != tc.status {
+			t.Errorf("%s %s with %q: expected %d, got %d", tc.scope, tc.path, tc.key, tc.status, got)
+		}
+	}
+
+	// With only an admin key, other endpoints stay open and admin ones need the key
+	store, err = NewKeyStore(KeyStoreConfig{AdminKey: "admin-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := send(store, ScopeWrite, "/actuals", APIKeyHeader, ""); got != http.StatusOK {
+		t.Errorf("expected anonymous writes allowed without API keys, got %d", got)
+	}
+	if got := send(store, ScopeAdmin, "/admin/drain", AdminKeyHeader, ""); got != http.StatusUnauthorized {
+		t.Errorf("expected 401 for admin without the key, got %d", got)
+	}
+	if got := send(store, ScopeAdmin, "/admin/drain", AdminKeyHeader, "admin-key"); got != http.StatusOK {
+		t.Errorf("expected the admin key accepted, got %d", got)
+	}
+
+	// Without keys (dev mode) everything is served
+	store, _ = NewKeyStore(KeyStoreConfig{})
+	if got := send(store, ScopeAdmin, "/admin/drain", APIKeyHeader, ""); got != http.StatusOK {
+		t.Errorf("expected dev mode to serve admin endpoints, got %d", got)
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BodyLimitConfig holds request body size limits.
+type BodyLimitConfig struct {
+	MaxBytes     int64    // Limit of most requests
+	BulkMaxBytes int64    // Limit of paths ending in any of BulkSuffixes
+	BulkSuffixes []string // Endpoints accepting large batches
+	SkipSuffixes []string // Endpoints enforcing their own limit
+}
+
+// DefaultBodyLimitConfig returns default body size limits.
+func DefaultBodyLimitConfig() BodyLimitConfig {
+	return BodyLimitConfig{
+		MaxBytes:     1 << 20,
+		BulkMaxBytes: 32 << 20,
+		BulkSuffixes: []string{"/predict/stream", "/predict/jobs", "/actuals"},
+		SkipSuffixes: []string{"/admin/append-features"},
+	}
+}
+
+// BodyLimit returns middleware that rejects request bodies larger than the configured
+// limit with 413. A declared Content-Length over the limit is rejected before the body
+// is read; otherwise the body is wrapped in http.MaxBytesReader, so handlers reading
+// past the limit get an *http.MaxBytesError.
+func BodyLimit(cfg BodyLimitConfig) func(http.Handler) http.Handler {
+	limitFor := func(path string) int64 {
+		for _, suffix := range cfg.SkipSuffixes {
+			if strings.HasSuffix(path, suffix) {
+				return 0
+			}
+		}
+		for _, suffix := range cfg.BulkSuffixes {
+			if strings.HasSuffix(path, suffix) {
+				return cfg.BulkMaxBytes
+			}
+		}
+		return cfg.MaxBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := limitFor(r.URL.Path)
+			if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				json.NewEncoder(w).Encode(errorResponse{
+					Error: fmt.Sprintf("request body must not exceed %d bytes", limit),
+					Code:  "PAYLOAD_TOO_LARGE",
+				})
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ProxyConfig configures which peers are trusted to report the client IP.
+type ProxyConfig struct {
+	// TrustedProxies are the networks of reverse proxies whose X-Real-IP and
+	// X-Forwarded-For headers are honoured; other peers' headers are ignored
+	TrustedProxies []netip.Prefix
+}
+
+// DefaultProxyConfig returns a proxy configuration trusting no proxy.
+func DefaultProxyConfig() ProxyConfig {
+	return ProxyConfig{}
+}
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or IP addresses, such as
+// "10.0.0.0/8,192.168.1.10".
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: must be a CIDR or IP address", field)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: must be a CIDR or IP address", field)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// clientIPKey is the context key for the client IP resolved by RealIP.
+type clientIPKey struct{}
+
+// RealIP returns middleware that resolves the client IP of requests for the rate
+// limiter and the access log. X-Real-IP, then X-Forwarded-For, are only honoured from a
+// trusted proxy: X-Forwarded-For is read right to left, skipping trusted proxies, so a
+// client can't prepend a spoofed address. Other requests use the peer's RemoteAddr.
+func RealIP(cfg ProxyConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := cfg.clientIP(r)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// ClientIP returns the client IP of r resolved by RealIP, or the host of RemoteAddr for
+// requests it didn't handle.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// remoteHost returns the host of r's RemoteAddr.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientIP resolves the client IP of r.
+func (cfg ProxyConfig) clientIP(r *http.Request) string {
+	peer := remoteHost(r)
+	if !cfg.trusted(peer) {
+		return peer
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+
+	// The nearest address that isn't a trusted proxy is the client. Every proxy
+	// appends its peer, so only the entries after the last untrusted one can be relied on.
+	client := peer
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			break
+		}
+		client = addr.Unmap().String()
+		if !cfg.trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// trusted reports whether ip is in a trusted proxy network.
+func (cfg ProxyConfig) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
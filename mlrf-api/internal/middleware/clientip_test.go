@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cfg := ProxyConfig{TrustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("10.0.0.5/32"),
+	}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xRealIP    string
+		xForwarded string
+		expected   string
+	}{
+		{
+			name:       "RemoteAddr only",
+			remoteAddr: "192.168.1.1:12345",
+			expected:   "192.168.1.1",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "192.168.1.1:12345",
+			xRealIP:    "10.0.0.1",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "X-Forwarded-For from trusted proxy",
+			remoteAddr: "192.168.1.1:12345",
+			xForwarded: "203.0.113.1",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Real-IP over X-Forwarded-For",
+			remoteAddr: "192.168.1.1:12345",
+			xRealIP:    "10.0.0.1",
+			xForwarded: "203.0.113.1",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "X-Forwarded-For skips trusted hops",
+			remoteAddr: "192.168.1.1:12345",
+			xForwarded: "198.51.100.7, 203.0.113.1, 10.0.0.5",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:       "X-Forwarded-For of trusted hops only",
+			remoteAddr: "192.168.1.1:12345",
+			xForwarded: "10.0.0.5",
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "invalid X-Forwarded-For hop",
+			remoteAddr: "192.168.1.1:12345",
+			xForwarded: "not-an-ip, 10.0.0.5",
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "headers from untrusted peer ignored",
+			remoteAddr: "203.0.113.9:12345",
+			xRealIP:    "10.0.0.1",
+			xForwarded: "10.0.0.1",
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "RemoteAddr without port",
+			remoteAddr: "192.168.1.1",
+			expected:   "192.168.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			if tt.xForwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.xForwarded)
+			}
+
+			var ip string
+			RealIP(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if ip != tt.expected {
+				t.Errorf("expected IP %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutRealIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+
+	if ip := ClientIP(req); ip != "192.168.1.1" {
+		t.Errorf("expected the RemoteAddr host, got %s", ip)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies(" 10.0.0.0/8, 192.168.1.10 ,,fd00::/8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.10/32", "fd00::/8"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("expected %d prefixes, got %v", len(want), prefixes)
+	}
+	for i, p := range prefixes {
+		if p.String() != want[i] {
+			t.Errorf("prefix %d: expected %s, got %s", i, want[i], p)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.internal"} {
+		if _, err := ParseTrustedProxies(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDefaultProxyConfig(t *testing.T) {
+	if cfg := DefaultProxyConfig(); len(cfg.TrustedProxies) != 0 {
+		t.Errorf("expected no trusted proxies by default, got %v", cfg.TrustedProxies)
+	}
+}
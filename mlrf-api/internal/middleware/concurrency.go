@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+)
+
+// ConcurrencyConfig holds concurrency limiter configuration.
+type ConcurrencyConfig struct {
+	Limit        int           // Requests to limited classes served at once; 0 disables the limiter
+	MaxQueue     int           // Requests waiting for a slot; more are shed at once
+	MaxWait      time.Duration // Time a request waits for a slot before it is shed
+	Classes      []string      // Endpoint classes limited (see EndpointClass)
+	SkipSuffixes []string      // Endpoints of those classes that aren't limited
+}
+
+// DefaultConcurrencyConfig returns default concurrency limiter configuration.
+func DefaultConcurrencyConfig() ConcurrencyConfig {
+	return ConcurrencyConfig{
+		Limit:    32,
+		MaxQueue: 64,
+		MaxWait:  250 * time.Millisecond,
+		Classes:  []string{ClassPredict, ClassBatch, ClassExplain},
+		// Jobs only queue work; streams pace their own inference and live for minutes
+		SkipSuffixes: []string{"/predict/jobs", "/predict/stream"},
+	}
+}
+
+// ConcurrencyLimiter bounds the requests to inference-heavy endpoints served at once,
+// so a burst queues briefly and is then shed with 503 instead of piling up behind the
+// model until every request times out.
+type ConcurrencyLimiter struct {
+	cfg     ConcurrencyConfig
+	classes map[string]bool
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// NewConcurrencyLimiter creates a concurrency limiter.
+func NewConcurrencyLimiter(cfg ConcurrencyConfig) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{cfg: cfg, classes: make(map[string]bool, len(cfg.Classes))}
+	for _, class := range cfg.Classes {
+		l.classes[class] = true
+	}
+	if cfg.Limit > 0 {
+		l.slots = make(chan struct{}, cfg.Limit)
+	}
+	return l
+}
+
+// InFlight returns the number of limited requests being served.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Waiting returns the number of requests waiting for a slot.
+func (l *ConcurrencyLimiter) Waiting() int64 {
+	return l.waiting.Load()
+}
+
+// limited reports whether requests to path are limited, and their endpoint class.
+func (l *ConcurrencyLimiter) limited(path string) (string, bool) {
+	if l.slots == nil {
+		return "", false
+	}
+	for _, suffix := range l.cfg.SkipSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return "", false
+		}
+	}
+	class := EndpointClass(path)
+	return class, l.classes[class]
+}
+
+// Middleware returns HTTP middleware that serves at most Limit limited requests at
+// once. A request finding every slot taken waits up to MaxWait for one, behind at most
+// MaxQueue others; otherwise it gets 503 OVERLOADED with Retry-After.
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, ok := l.limited(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if reason := l.acquire(r); reason != "" {
+			metrics.RecordLoadShed(class, reason)
+			w.Header().Set("Retry-After", "1")
+			writeRequestError(w, r, http.StatusServiceUnavailable, "server overloaded: too many concurrent requests", "OVERLOADED")
+			return
+		}
+		metrics.ConcurrencyInFlight.Inc()
+		defer func() {
+			<-l.slots
+			metrics.ConcurrencyInFlight.Dec()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a slot, waiting for one if need be. It returns why the request is
+// shed instead: "queue_full", "timeout", or "canceled" if the client went away.
+func (l *ConcurrencyLimiter) acquire(r *http.Request) string {
+	select {
+	case l.slots <- struct{}{}:
+		return ""
+	default:
+	}
+	if l.waiting.Add(1) > int64(l.cfg.MaxQueue) {
+		l.waiting.Add(-1)
+		return "queue_full"
+	}
+	metrics.ConcurrencyQueueDepth.Inc()
+	defer func() {
+		l.waiting.Add(-1)
+		metrics.ConcurrencyQueueDepth.Dec()
+	}()
+
+	timer := time.NewTimer(l.cfg.MaxWait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return ""
+	case <-timer.C:
+		return "timeout"
+	case <-r.Context().Done():
+		return "canceled"
+	}
+}
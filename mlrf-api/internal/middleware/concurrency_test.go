@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// blockingHandler holds requests until release is closed, signalling entered as each
+// one starts.
+func blockingHandler(entered chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestConcurrencyLimiter_ShedsWhenSaturated(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyConfig{
+		Limit:    1,
+		MaxQueue: 0,
+		MaxWait:  time.Second,
+		Classes:  []string{ClassPredict},
+	})
+	entered, release := make(chan struct{}, 2), make(chan struct{})
+	handler := l.Middleware(blockingHandler(entered, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/predict", nil))
+	}()
+	<-entered
+
+	before := testutil.ToFloat64(metrics.LoadShed.WithLabelValues(ClassPredict, "queue_full"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/predict", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+	}
+	var resp requestErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != "OVERLOADED" {
+		t.Errorf("expected an OVERLOADED error response, got %+v (%v)", resp, err)
+	}
+	if got := testutil.ToFloat64(metrics.LoadShed.WithLabelValues(ClassPredict, "queue_full")) - before; got != 1 {
+		t.Errorf("expected 1 queue_full shed recorded, got %v", got)
+	}
+
+	// Classes that aren't limited pass straight through
+	w = httptest.NewRecorder()
+	l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, httptest.NewRequest("GET", "/v1/hierarchy", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected read requests unlimited, got %d", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if l.InFlight() != 0 {
+		t.Errorf("expected the slot released, got %d in flight", l.InFlight())
+	}
+}
+
+func TestConcurrencyLimiter_WaitsForSlot(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyConfig{
+		Limit:    1,
+		MaxQueue: 1,
+		MaxWait:  5 * time.Second,
+		Classes:  []string{ClassBatch},
+	})
+	entered, release := make(chan struct{}, 2), make(chan struct{})
+	handler := l.Middleware(blockingHandler(entered, release))
+
+	codes := make(chan int, 2)
+	serve := func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/predict/batch", nil))
+		codes <- w.Code
+	}
+	go serve()
+	<-entered
+	go serve()
+	for l.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The first request finishing hands its slot to the waiting one
+	release <- struct{}{}
+	<-entered
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", code)
+		}
+	}
+}
+
+func TestConcurrencyLimiter_WaitTimeout(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyConfig{
+		Limit:    1,
+		MaxQueue: 1,
+		MaxWait:  10 * time.Millisecond,
+		Classes:  []string{ClassExplain},
+	})
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	handler := l.Middleware(blockingHandler(entered, release))
+	defer close(release)
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/explain", nil))
+	<-entered
+
+	before := testutil.ToFloat64(metrics.LoadShed.WithLabelValues(ClassExplain, "timeout"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/explain", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 after the wait, got %d", w.Code)
+	}
+	if got := testutil.ToFloat64(metrics.LoadShed.WithLabelValues(ClassExplain, "timeout")) - before; got != 1 {
+		t.Errorf("expected 1 timeout shed recorded, got %v", got)
+	}
+	if l.Waiting() != 0 {
+		t.Errorf("expected no waiting requests, got %d", l.Waiting())
+	}
+}
+
+func TestConcurrencyLimiter_Disabled(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyConfig{Classes: []string{ClassPredict}})
+	w := httptest.NewRecorder()
+	l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, httptest.NewRequest("POST", "/predict", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected a zero limit to disable the limiter, got %d", w.Code)
+	}
+}
+
+func TestDefaultConcurrencyConfig(t *testing.T) {
+	cfg := DefaultConcurrencyConfig()
+	if cfg.Limit != 32 || cfg.MaxQueue != 64 || cfg.MaxWait != 250*time.Millisecond {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
@@ -3,8 +3,8 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"strings"
+	"sync/atomic"
 )
 
 // DefaultCORSOrigins are the allowed origins when CORS_ORIGINS is not set.
@@ -21,67 +21,97 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
-// NewCORSConfig creates a CORS configuration from environment variables.
-func NewCORSConfig() CORSConfig {
-	cfg := CORSConfig{
+// NewCORSConfig creates a CORS configuration allowing origins.
+func NewCORSConfig(origins []string) CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: origins,
 		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key", AcceptVersionHeader},
 	}
+}
 
-	// Parse CORS_ORIGINS from environment
-	originsEnv := os.Getenv("CORS_ORIGINS")
-	if originsEnv == "" {
-		cfg.AllowedOrigins = DefaultCORSOrigins
-	} else {
-		// Split comma-separated origins and trim whitespace
-		origins := strings.Split(originsEnv, ",")
-		for _, origin := range origins {
-			trimmed := strings.TrimSpace(origin)
-			if trimmed != "" {
-				cfg.AllowedOrigins = append(cfg.AllowedOrigins, trimmed)
-			}
+// ParseCORSOrigins splits a comma-separated CORS_ORIGINS value, returning
+// DefaultCORSOrigins when it is empty.
+func ParseCORSOrigins(s string) []string {
+	if s == "" {
+		return DefaultCORSOrigins
+	}
+
+	// Split comma-separated origins and trim whitespace
+	var origins []string
+	for _, origin := range strings.Split(s, ",") {
+		trimmed := strings.TrimSpace(origin)
+		if trimmed != "" {
+			origins = append(origins, trimmed)
 		}
 	}
+	return origins
+}
 
-	return cfg
+// CORSPolicy is a CORS middleware whose allowed origins can be changed at runtime.
+type CORSPolicy struct {
+	allowed atomic.Pointer[map[string]bool]
+	methods string
+	headers string
 }
 
-// CORS returns a middleware that handles Cross-Origin Resource Sharing.
-// It validates the Origin header against the configured whitelist.
-func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+// NewCORSPolicy creates a CORS policy from the configuration.
+func NewCORSPolicy(cfg CORSConfig) *CORSPolicy {
+	p := &CORSPolicy{
+		methods: strings.Join(cfg.AllowedMethods, ", "),
+		headers: strings.Join(cfg.AllowedHeaders, ", "),
+	}
+	p.SetOrigins(cfg.AllowedOrigins)
+	return p
+}
+
+// SetOrigins replaces the allowed origins.
+func (p *CORSPolicy) SetOrigins(origins []string) {
 	// Build a map for O(1) origin lookup
-	allowedMap := make(map[string]bool)
-	for _, origin := range cfg.AllowedOrigins {
+	allowedMap := make(map[string]bool, len(origins))
+	for _, origin := range origins {
 		allowedMap[origin] = true
 	}
+	p.allowed.Store(&allowedMap)
+}
 
-	methods := strings.Join(cfg.AllowedMethods, ", ")
-	headers := strings.Join(cfg.AllowedHeaders, ", ")
+// AllowsOrigin reports whether origin is an allowed origin.
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	return (*p.allowed.Load())[origin]
+}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
+// Middleware handles Cross-Origin Resource Sharing.
+// It validates the Origin header against the allowed origins.
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && p.AllowsOrigin(origin)
 
-			// Only set CORS headers if origin is in whitelist
-			if origin != "" && allowedMap[origin] {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", methods)
-				w.Header().Set("Access-Control-Allow-Headers", headers)
-				w.Header().Set("Vary", "Origin")
-			}
+		// Only set CORS headers if origin is in whitelist
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", p.methods)
+			w.Header().Set("Access-Control-Allow-Headers", p.headers)
+			w.Header().Set("Vary", "Origin")
+		}
 
-			// Handle preflight requests
-			if r.Method == "OPTIONS" {
-				if origin != "" && allowedMap[origin] {
-					w.WriteHeader(http.StatusOK)
-				} else {
-					// Reject preflight from unknown origins
-					w.WriteHeader(http.StatusForbidden)
-				}
-				return
+		// Handle preflight requests
+		if r.Method == "OPTIONS" {
+			if allowed {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				// Reject preflight from unknown origins
+				w.WriteHeader(http.StatusForbidden)
 			}
+			return
+		}
 
-			next.ServeHTTP(w, r)
-		})
-	}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORS returns a middleware that handles Cross-Origin Resource Sharing.
+// It validates the Origin header against the configured whitelist.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	return NewCORSPolicy(cfg).Middleware
 }
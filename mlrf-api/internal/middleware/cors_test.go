@@ -3,15 +3,11 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 )
 
 func TestCORSDefaultOrigins(t *testing.T) {
-	// Unset CORS_ORIGINS to test defaults
-	os.Unsetenv("CORS_ORIGINS")
-
-	cfg := NewCORSConfig()
+	cfg := NewCORSConfig(ParseCORSOrigins(""))
 
 	if len(cfg.AllowedOrigins) != len(DefaultCORSOrigins) {
 		t.Errorf("Expected %d default origins, got %d", len(DefaultCORSOrigins), len(cfg.AllowedOrigins))
@@ -25,10 +21,7 @@ func TestCORSDefaultOrigins(t *testing.T) {
 }
 
 func TestCORSCustomOrigins(t *testing.T) {
-	os.Setenv("CORS_ORIGINS", "https://app.example.com, https://staging.example.com")
-	defer os.Unsetenv("CORS_ORIGINS")
-
-	cfg := NewCORSConfig()
+	cfg := NewCORSConfig(ParseCORSOrigins("https://app.example.com, https://staging.example.com"))
 
 	expected := []string{"https://app.example.com", "https://staging.example.com"}
 	if len(cfg.AllowedOrigins) != len(expected) {
@@ -177,10 +170,7 @@ func TestCORSNoOriginHeader(t *testing.T) {
 }
 
 func TestCORSEmptyOriginsEnv(t *testing.T) {
-	os.Setenv("CORS_ORIGINS", "")
-	defer os.Unsetenv("CORS_ORIGINS")
-
-	cfg := NewCORSConfig()
+	cfg := NewCORSConfig(ParseCORSOrigins(""))
 
 	// Empty env should use defaults
 	if len(cfg.AllowedOrigins) != len(DefaultCORSOrigins) {
@@ -188,3 +178,30 @@ func TestCORSEmptyOriginsEnv(t *testing.T) {
 			len(cfg.AllowedOrigins))
 	}
 }
+
+func TestCORSPolicySetOrigins(t *testing.T) {
+	policy := NewCORSPolicy(CORSConfig{
+		AllowedOrigins: []string{"http://localhost:3000"},
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type"},
+	})
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	policy.SetOrigins(ParseCORSOrigins("https://app.example.com, https://staging.example.com"))
+
+	if got := allowOrigin("https://staging.example.com"); got != "https://staging.example.com" {
+		t.Errorf("Expected the new origin to be allowed, got %q", got)
+	}
+	if got := allowOrigin("http://localhost:3000"); got != "" {
+		t.Errorf("Expected the replaced origin to be rejected, got %q", got)
+	}
+}
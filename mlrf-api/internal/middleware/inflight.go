@@ -0,0 +1,42 @@
+// Package middleware provides HTTP middleware for the MLRF API.
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// InFlightTracker counts requests currently being served, so a draining server can
+// wait for them to complete.
+type InFlightTracker struct {
+	count atomic.Int64
+	skip  map[string]bool
+}
+
+// NewInFlightTracker creates a tracker that ignores requests to skipPaths, such as
+// health probes and long-lived WebSocket connections.
+func NewInFlightTracker(skipPaths []string) *InFlightTracker {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+	return &InFlightTracker{skip: skip}
+}
+
+// InFlight returns the number of requests being served.
+func (t *InFlightTracker) InFlight() int64 {
+	return t.count.Load()
+}
+
+// Middleware returns HTTP middleware that counts in-flight requests.
+func (t *InFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.skip[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t.count.Add(1)
+		defer t.count.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInFlightTracker(t *testing.T) {
+	tracker := NewInFlightTracker([]string{"/health/ready"})
+	var during int64
+	handler := tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = tracker.InFlight()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/predict/batch", nil))
+	if during != 1 {
+		t.Errorf("expected 1 request in flight while serving, got %d", during)
+	}
+	if got := tracker.InFlight(); got != 0 {
+		t.Errorf("expected 0 requests in flight afterwards, got %d", got)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health/ready", nil))
+	if during != 0 {
+		t.Errorf("expected skipped paths not counted, got %d", during)
+	}
+}
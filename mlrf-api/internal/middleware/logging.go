@@ -0,0 +1,216 @@
+// Package middleware provides HTTP middleware for the MLRF API.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestLoggerConfig holds request logging configuration.
+type RequestLoggerConfig struct {
+	// SampleRate is the fraction of successful requests logged; requests answered
+	// with a 4xx or 5xx status are always logged
+	SampleRate float64
+
+	// File receives the access log as JSON lines instead of the process log, rotated
+	// at MaxSizeMB keeping MaxBackups old files
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+
+	// AuditFile receives the audit log of privileged and denied requests; the
+	// access log file, or the process log, if unset
+	AuditFile string
+}
+
+// DefaultRequestLoggerConfig returns default request logging configuration, logging
+// every request to the process log only.
+func DefaultRequestLoggerConfig() RequestLoggerConfig {
+	return RequestLoggerConfig{
+		SampleRate: 1,
+		MaxSizeMB:  100,
+		MaxBackups: 5,
+	}
+}
+
+// accessInfoKey is the context key of the access log fields of a request.
+type accessInfoKey struct{}
+
+// accessInfo holds the access log fields set by inner middleware and handlers while
+// a request is served.
+type accessInfo struct {
+	mu           sync.Mutex
+	apiKey       string
+	cacheHit     *bool
+	modelVersion string
+}
+
+func accessInfoFrom(ctx context.Context) *accessInfo {
+	info, _ := ctx.Value(accessInfoKey{}).(*accessInfo)
+	return info
+}
+
+// SetCacheHit records in the access log whether the request was answered from cache.
+func SetCacheHit(ctx context.Context, hit bool) {
+	if info := accessInfoFrom(ctx); info != nil {
+		info.mu.Lock()
+		info.cacheHit = &hit
+		info.mu.Unlock()
+	}
+}
+
+// SetModelVersion records in the access log the version of the model that served the
+// request.
+func SetModelVersion(ctx context.Context, version string) {
+	if info := accessInfoFrom(ctx); info != nil {
+		info.mu.Lock()
+		info.modelVersion = version
+		info.mu.Unlock()
+	}
+}
+
+// setAccessAPIKey records in the access log the name of the API key of the request.
+func setAccessAPIKey(ctx context.Context, name string) {
+	if info := accessInfoFrom(ctx); info != nil {
+		info.mu.Lock()
+		info.apiKey = name
+		info.mu.Unlock()
+	}
+}
+
+// RequestLogger writes the access log: one structured line per request, sampling
+// successful requests.
+type RequestLogger struct {
+	sampleRate atomic.Uint64 // math.Float64bits of the sample rate
+
+	access *zerolog.Logger // Access log file logger; the process log if nil
+	audit  *zerolog.Logger // Audit log file logger, if any
+	files  []*RotatingFile
+}
+
+// NewRequestLogger creates a request logger, opening its log files if configured.
+func NewRequestLogger(cfg RequestLoggerConfig) (*RequestLogger, error) {
+	l := &RequestLogger{}
+	l.SetSampleRate(cfg.SampleRate)
+
+	open := func(path string) (*zerolog.Logger, error) {
+		f, err := OpenRotatingFile(path, int64(cfg.MaxSizeMB)<<20, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.files = append(l.files, f)
+		logger := zerolog.New(f).With().Timestamp().Logger()
+		return &logger, nil
+	}
+	var err error
+	if cfg.File != "" {
+		if l.access, err = open(cfg.File); err != nil {
+			return nil, fmt.Errorf("access log: %w", err)
+		}
+		l.audit = l.access
+	}
+	if cfg.AuditFile != "" {
+		if l.audit, err = open(cfg.AuditFile); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("audit log: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// AuditLogger returns the logger of the audit log file, if one is configured.
+func (l *RequestLogger) AuditLogger() (zerolog.Logger, bool) {
+	if l.audit == nil {
+		return zerolog.Logger{}, false
+	}
+	return *l.audit, true
+}
+
+// Close closes the log files.
+func (l *RequestLogger) Close() error {
+	var first error
+	for _, f := range l.files {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SetSampleRate changes the fraction of successful requests logged.
+func (l *RequestLogger) SetSampleRate(rate float64) {
+	l.sampleRate.Store(math.Float64bits(rate))
+}
+
+// SampleRate returns the fraction of successful requests logged.
+func (l *RequestLogger) SampleRate() float64 {
+	return math.Float64frombits(l.sampleRate.Load())
+}
+
+// Middleware returns HTTP middleware that logs requests: 5xx at error level, 4xx at
+// warn level and sampled successful requests at info level. Each line carries the
+// request and trace IDs, the API key name, the route pattern, status and latency, and
+// the cache hit and model version recorded by the handler.
+func (l *RequestLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &accessInfo{}
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessInfoKey{}, info)))
+
+		logger := &log.Logger
+		if l.access != nil {
+			logger = l.access
+		}
+		status := rw.Status()
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = logger.Error()
+		case status >= http.StatusBadRequest:
+			event = logger.Warn()
+		default:
+			if rate := l.SampleRate(); rate < 1 && rand.Float64() >= rate {
+				return
+			}
+			event = logger.Info()
+		}
+
+		if traceID := rw.Header().Get(TraceIDHeader); traceID != "" {
+			event.Str("trace_id", traceID)
+		}
+		info.mu.Lock()
+		if info.apiKey != "" {
+			event.Str("api_key", info.apiKey)
+		}
+		if info.cacheHit != nil {
+			event.Bool("cache_hit", *info.cacheHit)
+		}
+		if info.modelVersion != "" {
+			event.Str("model_version", info.modelVersion)
+		}
+		info.mu.Unlock()
+		if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+			event.Str("endpoint", routeCtx.RoutePattern())
+		}
+		event.
+			Str("request_id", middleware.GetReqID(r.Context())).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Float64("latency_ms", float64(time.Since(start).Microseconds())/1000).
+			Str("remote_addr", ClientIP(r)).
+			Msg("Request")
+	})
+}
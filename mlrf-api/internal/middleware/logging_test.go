@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = prev }()
+
+	rl, err := NewRequestLogger(RequestLoggerConfig{SampleRate: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := http.StatusOK
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	serve := func() []map[string]interface{} {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/predict", nil))
+		var lines []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("invalid log line %q: %v", line, err)
+			}
+			lines = append(lines, entry)
+		}
+		return lines
+	}
+
+	if lines := serve(); len(lines) != 0 {
+		t.Errorf("expected a successful request sampled out at rate 0, got %v", lines)
+	}
+
+	status = http.StatusInternalServerError
+	lines := serve()
+	if len(lines) != 1 || lines[0]["level"] != "error" || lines[0]["status"] != float64(500) || lines[0]["path"] != "/predict" {
+		t.Errorf("expected a failed request always logged at error level, got %v", lines)
+	}
+
+	status = http.StatusOK
+	rl.SetSampleRate(1)
+	if lines := serve(); len(lines) != 1 || lines[0]["level"] != "info" {
+		t.Errorf("expected a successful request logged at rate 1, got %v", lines)
+	}
+
+	// The trace ID the tracing middleware returns is logged
+	handler = rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(TraceIDHeader, "4bf92f3577b34da6a3ce929d0e0e4736")
+	}))
+	if lines := serve(); len(lines) != 1 || lines[0]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the trace ID logged, got %v", lines)
+	}
+}
+
+func TestDefaultRequestLoggerConfig(t *testing.T) {
+	if cfg := DefaultRequestLoggerConfig(); cfg.SampleRate != 1 || cfg.File != "" || cfg.AuditFile != "" {
+		t.Errorf("expected every request logged to stderr only, got %+v", cfg)
+	}
+}
+
+// readLogLines parses the JSON lines of a log file.
+func readLogLines(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestAccessLogFields(t *testing.T) {
+	dir := t.TempDir()
+	rl, err := NewRequestLogger(RequestLoggerConfig{SampleRate: 1, File: filepath.Join(dir, "access.log"), MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	store, err := NewKeyStore(KeyStoreConfig{Key: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Use(store.Middleware)
+	r.Get("/v1/stores/{id}", func(w http.ResponseWriter, r *http.Request) {
+		SetCacheHit(r.Context(), true)
+		SetModelVersion(r.Context(), "lightgbm@v2")
+	})
+
+	req := httptest.NewRequest("GET", "/v1/stores/44", nil)
+	req.Header.Set(APIKeyHeader, "secret")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := readLogLines(t, filepath.Join(dir, "access.log"))
+	if len(lines) != 1 {
+		t.Fatalf("expected one access log line, got %v", lines)
+	}
+	line := lines[0]
+	if line["endpoint"] != "/v1/stores/{id}" || line["path"] != "/v1/stores/44" {
+		t.Errorf("expected the route pattern and path logged, got %v", line)
+	}
+	if line["api_key"] != "default" || line["cache_hit"] != true || line["model_version"] != "lightgbm@v2" {
+		t.Errorf("expected the API key, cache hit and model version logged, got %v", line)
+	}
+	if _, ok := line["latency_ms"].(float64); !ok {
+		t.Errorf("expected latency_ms logged, got %v", line)
+	}
+}
+
+func TestAuditLogFile(t *testing.T) {
+	dir := t.TempDir()
+	rl, err := NewRequestLogger(RequestLoggerConfig{SampleRate: 1, AuditFile: filepath.Join(dir, "audit.log"), MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	auditLogger, ok := rl.AuditLogger()
+	if !ok {
+		t.Fatal("expected an audit logger with an audit file")
+	}
+
+	store, err := NewKeyStore(KeyStoreConfig{Key: "secret", AdminKey: "admin-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetAuditLogger(auditLogger)
+	handler := store.Middleware(store.RequireScope(ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	for _, key := range []string{"admin-secret", "secret"} {
+		req := httptest.NewRequest("POST", "/admin/reload", nil)
+		req.Header.Set(AdminKeyHeader, key)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := readLogLines(t, filepath.Join(dir, "audit.log"))
+	if len(lines) != 2 {
+		t.Fatalf("expected the privileged and the denied request audited, got %v", lines)
+	}
+	if lines[0]["audit"] != true || lines[0]["api_key"] != "admin" || lines[0]["status"] != float64(200) {
+		t.Errorf("expected the admin request audited, got %v", lines[0])
+	}
+	if lines[1]["api_key"] != "default" || lines[1]["status"] != float64(403) {
+		t.Errorf("expected the denied request audited, got %v", lines[1])
+	}
+}
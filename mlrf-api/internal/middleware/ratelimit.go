@@ -2,30 +2,78 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
-	"net"
+	"fmt"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter implements per-IP rate limiting using token bucket algorithm.
+// Endpoint classes, rate limited separately (see EndpointClass).
+const (
+	ClassRead    = "read"    // Health, metadata and other reads
+	ClassPredict = "predict" // Single predictions, forecasts and what-if scenarios
+	ClassBatch   = "batch"   // Batch, streamed, aggregate and job predictions, backtests
+	ClassExplain = "explain" // SHAP explanations
+	ClassAdmin   = "admin"   // /admin endpoints
+)
+
+// Classes lists the endpoint classes.
+var Classes = []string{ClassRead, ClassPredict, ClassBatch, ClassExplain, ClassAdmin}
+
+// ClassLimit is the rate and burst of an endpoint class.
+type ClassLimit struct {
+	RequestsPerSecond float64
+	BurstSize         int
+}
+
+// RateLimiter implements per-client rate limiting using token bucket algorithm.
+// Requests with a valid API key are limited per key, with the key's own limits and
+// monthly quota if it has them; other requests are limited per client IP (see RealIP).
+// Each endpoint class has its own bucket per client, with the class's limits if it
+// has them and the default limits otherwise.
+//
+// Rate limits are per replica. Monthly quotas are shared through the QuotaCounter
+// when one is set, and counted per replica otherwise, or while it fails.
 type RateLimiter struct {
 	limiters map[string]*rateLimiterEntry
+	quotas   map[string]*quotaEntry // By API key name, without a counter or while it fails
+	counter  QuotaCounter
+	keys     *KeyStore
 	mu       sync.RWMutex
 	rate     rate.Limit
 	burst    int
+	classes  map[string]ClassLimit
 	cleanup  time.Duration
+	now      func() time.Time
+}
+
+// QuotaCounter counts API key requests in a store shared by every replica, so monthly
+// quotas hold across the deployment. *cache.RedisCache implements it.
+type QuotaCounter interface {
+	// IncrQuota counts a request of key name in the month starting at month and
+	// returns the requests counted in it so far
+	IncrQuota(ctx context.Context, name string, month time.Time) (int64, error)
+}
+
+// quotaEntry counts an API key's requests in the current month.
+type quotaEntry struct {
+	month time.Time // First day of the month (UTC)
+	used  int64
 }
 
 // rateLimiterEntry tracks a limiter and when it was last used.
 type rateLimiterEntry struct {
 	limiter  *rate.Limiter
+	class    string
 	lastSeen time.Time
 }
 
@@ -33,31 +81,80 @@ type rateLimiterEntry struct {
 type RateLimiterConfig struct {
 	RequestsPerSecond float64
 	BurstSize         int
+	ClassLimits       map[string]ClassLimit // Limits by endpoint class; others use the defaults above
 	CleanupInterval   time.Duration
 }
 
+// DefaultClassLimits are the endpoint class limits used without RATE_LIMIT_CLASSES.
+const DefaultClassLimits = "batch=10/20,explain=10/20,admin=5/10"
+
 // DefaultRateLimiterConfig returns default rate limiting configuration.
-// Reads from RATE_LIMIT_RPS and RATE_LIMIT_BURST env vars if set.
 func DefaultRateLimiterConfig() RateLimiterConfig {
-	rps := 100.0
-	burst := 200
+	classes, _ := ParseClassLimits(DefaultClassLimits)
+	return RateLimiterConfig{
+		RequestsPerSecond: 100,
+		BurstSize:         200,
+		ClassLimits:       classes,
+		CleanupInterval:   10 * time.Minute,
+	}
+}
 
-	if val := os.Getenv("RATE_LIMIT_RPS"); val != "" {
-		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed > 0 {
-			rps = parsed
+// ParseClassLimits parses a comma-separated list of endpoint class limits as
+// class=rps/burst, such as "batch=10/20,explain=5/10".
+func ParseClassLimits(s string) (map[string]ClassLimit, error) {
+	limits := make(map[string]ClassLimit)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		class, val, ok := strings.Cut(field, "=")
+		class = strings.TrimSpace(class)
+		if !ok || !isClass(class) {
+			return nil, fmt.Errorf("invalid class limit %q: must be class=rps/burst with class one of %s", field, strings.Join(Classes, ", "))
+		}
+		rpsVal, burstVal, ok := strings.Cut(val, "/")
+		rps, err := strconv.ParseFloat(strings.TrimSpace(rpsVal), 64)
+		if !ok || err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid class limit %q: rps must be a positive number", field)
 		}
+		burst, err := strconv.Atoi(strings.TrimSpace(burstVal))
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid class limit %q: burst must be a positive integer", field)
+		}
+		limits[class] = ClassLimit{RequestsPerSecond: rps, BurstSize: burst}
 	}
+	return limits, nil
+}
 
-	if val := os.Getenv("RATE_LIMIT_BURST"); val != "" {
-		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
-			burst = parsed
+func isClass(class string) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
 		}
 	}
+	return false
+}
 
-	return RateLimiterConfig{
-		RequestsPerSecond: rps,
-		BurstSize:         burst,
-		CleanupInterval:   10 * time.Minute,
+// EndpointClass returns the endpoint class of a request path, with or without the
+// /v1 prefix.
+func EndpointClass(path string) string {
+	if rest := strings.TrimPrefix(path, "/v1"); strings.HasPrefix(rest, "/") {
+		path = rest
+	}
+	switch {
+	case strings.HasPrefix(path, "/admin/"):
+		return ClassAdmin
+	case path == "/predict/batch", path == "/predict/stream", path == "/predict/aggregate",
+		path == "/predict/jobs", path == "/backtest", path == "/promotions/impact":
+		return ClassBatch
+	case path == "/predict", strings.HasPrefix(path, "/predict/"), path == "/forecast",
+		path == "/whatif", strings.HasPrefix(path, "/whatif/"):
+		return ClassPredict
+	case path == "/explain":
+		return ClassExplain
+	default:
+		return ClassRead
 	}
 }
 
@@ -65,9 +162,12 @@ func DefaultRateLimiterConfig() RateLimiterConfig {
 func NewRateLimiter(cfg RateLimiterConfig) *RateLimiter {
 	rl := &RateLimiter{
 		limiters: make(map[string]*rateLimiterEntry),
+		quotas:   make(map[string]*quotaEntry),
 		rate:     rate.Limit(cfg.RequestsPerSecond),
 		burst:    cfg.BurstSize,
+		classes:  cfg.ClassLimits,
 		cleanup:  cfg.CleanupInterval,
+		now:      time.Now,
 	}
 
 	// Start cleanup goroutine to remove stale entries
@@ -97,55 +197,141 @@ func (rl *RateLimiter) cleanup_stale() {
 	}
 }
 
-// getLimiter returns the rate limiter for the given IP address.
-func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
+// SetLimits changes the default rate and burst and the endpoint class limits of every
+// client, including those already tracked.
+func (rl *RateLimiter) SetLimits(requestsPerSecond float64, burst int, classes map[string]ClassLimit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.rate = rate.Limit(requestsPerSecond)
+	rl.burst = burst
+	rl.classes = classes
+	for _, entry := range rl.limiters {
+		limit, b := rl.classLimits(entry.class)
+		entry.limiter.SetLimit(limit)
+		entry.limiter.SetBurst(b)
+	}
+}
+
+// classLimits returns the rate and burst of class. Callers hold rl.mu.
+func (rl *RateLimiter) classLimits(class string) (rate.Limit, int) {
+	if l, ok := rl.classes[class]; ok {
+		return rate.Limit(l.RequestsPerSecond), l.BurstSize
+	}
+	return rl.rate, rl.burst
+}
+
+// SetKeyStore makes requests with a valid API key from store limited per key.
+// Call before serving.
+func (rl *RateLimiter) SetKeyStore(store *KeyStore) {
+	rl.keys = store
+}
+
+// SetQuotaCounter counts monthly quotas with c instead of per replica. Call before
+// serving.
+func (rl *RateLimiter) SetQuotaCounter(c QuotaCounter) {
+	rl.counter = c
+}
+
+// getLimiter returns the rate limiter of class for the given IP address.
+func (rl *RateLimiter) getLimiter(class, ip string) *rate.Limiter {
+	return rl.limiterFor(class, ip, 0, 0)
+}
+
+// limiterFor returns the rate limiter of class tracked under id, using rps and burst
+// when they're set and the class limits otherwise. Limits changed since the limiter was
+// created (a reloaded key file) are applied.
+func (rl *RateLimiter) limiterFor(class, id string, rps float64, burst int) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	entry, exists := rl.limiters[ip]
+	limit, b := rl.classLimits(class)
+	if rps > 0 {
+		limit = rate.Limit(rps)
+	}
+	if burst > 0 {
+		b = burst
+	}
+
+	id = class + "|" + id
+	entry, exists := rl.limiters[id]
 	if !exists {
-		limiter := rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[ip] = &rateLimiterEntry{
+		limiter := rate.NewLimiter(limit, b)
+		rl.limiters[id] = &rateLimiterEntry{
 			limiter:  limiter,
+			class:    class,
 			lastSeen: time.Now(),
 		}
 		return limiter
 	}
 
+	if entry.limiter.Limit() != limit {
+		entry.limiter.SetLimit(limit)
+	}
+	if entry.limiter.Burst() != b {
+		entry.limiter.SetBurst(b)
+	}
 	entry.lastSeen = time.Now()
 	return entry.limiter
 }
 
-// extractIP extracts the IP address from a request.
-// Uses X-Real-IP or X-Forwarded-For if available (set by chi RealIP middleware).
-func extractIP(r *http.Request) string {
-	// chi.RealIP middleware sets r.RemoteAddr to the real IP
-	// But we also check headers for safety
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
-	}
+// useQuota counts a request against the monthly quota of key. It returns the requests
+// remaining this month and when the quota resets, and false once it is used up. When
+// the quota counter fails, the request is counted by this replica alone.
+func (rl *RateLimiter) useQuota(ctx context.Context, key *APIKey) (remaining int64, reset time.Time, ok bool) {
+	now := rl.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	reset = month.AddDate(0, 1, 0)
 
-	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-		return ip
+	if rl.counter != nil {
+		used, err := rl.counter.IncrQuota(ctx, key.Name, month)
+		if err == nil {
+			if used > key.MonthlyQuota {
+				return 0, reset, false
+			}
+			return key.MonthlyQuota - used, reset, true
+		}
+		log.Warn().Err(err).Str("key", key.Name).Msg("Shared quota count failed, counting on this replica")
 	}
 
-	// Fall back to RemoteAddr (which chi.RealIP middleware updates)
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	q, exists := rl.quotas[key.Name]
+	if !exists || !q.month.Equal(month) {
+		q = &quotaEntry{month: month}
+		rl.quotas[key.Name] = q
 	}
-	return ip
+	if q.used >= key.MonthlyQuota {
+		return 0, reset, false
+	}
+	q.used++
+	return key.MonthlyQuota - q.used, reset, true
 }
 
-// Middleware returns HTTP middleware that enforces rate limiting.
+// Middleware returns HTTP middleware that enforces rate limiting. Responses to
+// requests with a key that has a monthly quota carry X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset headers.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := extractIP(r)
-		limiter := rl.getLimiter(ip)
+		var key *APIKey
+		if rl.keys != nil && !publicPaths[r.URL.Path] {
+			if secret := requestKey(r); secret != "" {
+				key, _ = rl.keys.Lookup(secret)
+			}
+		}
+
+		class := EndpointClass(r.URL.Path)
+		var limiter *rate.Limiter
+		if key != nil {
+			limiter = rl.limiterFor(class, "key:"+key.Name, key.RateLimitRPS, key.RateLimitBurst)
+		} else {
+			limiter = rl.getLimiter(class, ClientIP(r))
+		}
 
 		if !limiter.Allow() {
 			// Record rate limit rejection in Prometheus
-			metrics.RecordRateLimitRejection()
+			metrics.RecordRateLimitRejection(class)
 
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Retry-After", "1")
@@ -157,11 +343,31 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if key != nil && key.MonthlyQuota > 0 {
+			remaining, reset, ok := rl.useQuota(r.Context(), key)
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(key.MonthlyQuota, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if !ok {
+				metrics.RecordRateLimitRejection(class)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(rl.now()).Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(errorResponse{
+					Error: "quota exceeded: monthly request quota of API key " + key.Name + " used up",
+					Code:  "QUOTA_EXCEEDED",
+				})
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Size returns the current number of tracked IPs.
+// Size returns the current number of tracked clients, counting each endpoint class
+// a client used.
 func (rl *RateLimiter) Size() int {
 	rl.mu.RLock()
 	defer rl.mu.RUnlock()
@@ -1,13 +1,20 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestRateLimiter_AllowsRequestsWithinLimit(t *testing.T) {
@@ -174,7 +181,8 @@ func TestRateLimiter_UsesXRealIP(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := rl.Middleware(handler)
+	// The peers are a trusted proxy
+	wrappedHandler := RealIP(ProxyConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}})(rl.Middleware(handler))
 
 	// First request with X-Real-IP
 	req := httptest.NewRequest("GET", "/test", nil)
@@ -211,7 +219,8 @@ func TestRateLimiter_UsesXForwardedFor(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := rl.Middleware(handler)
+	// The peers are a trusted proxy
+	wrappedHandler := RealIP(ProxyConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}})(rl.Middleware(handler))
 
 	// First request with X-Forwarded-For
 	req := httptest.NewRequest("GET", "/test", nil)
@@ -236,6 +245,39 @@ func TestRateLimiter_UsesXForwardedFor(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_IgnoresUntrustedForwardingHeaders(t *testing.T) {
+	cfg := RateLimiterConfig{
+		RequestsPerSecond: 1,
+		BurstSize:         1,
+		CleanupInterval:   10 * time.Minute,
+	}
+	rl := NewRateLimiter(cfg)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrappedHandler := RealIP(ProxyConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}})(rl.Middleware(handler))
+
+	// A client rotating spoofed headers is still limited by its own address
+	for i, spoofed := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Real-IP", spoofed)
+		req.Header.Set("X-Forwarded-For", spoofed)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+
+		want := http.StatusOK
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i, want, rec.Code)
+		}
+	}
+}
+
 func TestRateLimiter_ConcurrentRequests(t *testing.T) {
 	cfg := RateLimiterConfig{
 		RequestsPerSecond: 100,
@@ -319,6 +361,163 @@ func TestRateLimiter_Size(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_SetLimits(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterConfig{
+		RequestsPerSecond: 100,
+		BurstSize:         200,
+		CleanupInterval:   10 * time.Minute,
+	})
+
+	wrappedHandler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(ip string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send("192.168.1.1"); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+
+	// Lowered limits apply to tracked and new clients alike
+	rl.SetLimits(0.001, 1, nil)
+	for _, ip := range []string{"192.168.1.1", "192.168.1.2"} {
+		if code := send(ip); code != http.StatusOK {
+			t.Errorf("%s: expected the first request within the new burst to succeed, got %d", ip, code)
+		}
+		if code := send(ip); code != http.StatusTooManyRequests {
+			t.Errorf("%s: expected status 429 past the new burst, got %d", ip, code)
+		}
+	}
+}
+
+func TestRateLimiter_PerAPIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"keys": [
+		{"name": "batch", "key": "batch-key", "rate_limit_burst": 3},
+		{"name": "trial", "key": "trial-key", "rate_limit_burst": 10, "monthly_quota": 2}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewKeyStore(KeyStoreConfig{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := NewRateLimiter(RateLimiterConfig{RequestsPerSecond: 0.001, BurstSize: 1, CleanupInterval: 10 * time.Minute})
+	rl.SetKeyStore(store)
+	rl.now = func() time.Time { return time.Date(2017, 8, 31, 23, 0, 0, 0, time.UTC) }
+
+	wrappedHandler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/predict", nil)
+		req.RemoteAddr = "10.0.0.1:12345" // Every client behind the same NAT
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The per-IP limit doesn't apply to requests with a key, which have their own limits
+	if rec := send(""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec := send(""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the IP limited, got %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := send("batch-key"); rec.Code != http.StatusOK {
+			t.Errorf("request %d: expected the key's burst of 3 allowed, got %d", i, rec.Code)
+		}
+	}
+	if rec := send("batch-key"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 past the key's burst, got %d", rec.Code)
+	}
+
+	// Quota headers count down, then requests are rejected until the next month
+	rec := send("trial-key")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("unexpected quota response %d %v", rec.Code, rec.Header())
+	}
+	if reset := rec.Header().Get("X-RateLimit-Reset"); reset != "1504224000" {
+		t.Errorf("expected the quota to reset on 2017-09-01, got %s", reset)
+	}
+	send("trial-key")
+	rec = send("trial-key")
+	var errResp errorResponse
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if rec.Code != http.StatusTooManyRequests || errResp.Code != "QUOTA_EXCEEDED" || rec.Header().Get("Retry-After") != "3600" {
+		t.Errorf("expected QUOTA_EXCEEDED with Retry-After 3600, got %d %s %s", rec.Code, errResp.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rl.now = func() time.Time { return time.Date(2017, 9, 1, 0, 0, 1, 0, time.UTC) }
+	if rec := send("trial-key"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("expected the quota reset in September, got %d %s", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
+
+// fakeQuotaCounter is a QuotaCounter shared by rate limiters, like Redis across replicas.
+type fakeQuotaCounter struct {
+	counts map[string]int64
+	err    error
+}
+
+func (f *fakeQuotaCounter) IncrQuota(ctx context.Context, name string, month time.Time) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	key := name + ":" + month.Format("2006-01")
+	f.counts[key]++
+	return f.counts[key], nil
+}
+
+func TestRateLimiter_SharedQuota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"keys": [{"name": "trial", "key": "trial-key", "monthly_quota": 2}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewKeyStore(KeyStoreConfig{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &fakeQuotaCounter{counts: make(map[string]int64)}
+	send := func(rl *RateLimiter) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/predict", nil)
+		req.Header.Set(APIKeyHeader, "trial-key")
+		rec := httptest.NewRecorder()
+		rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+		return rec
+	}
+	replicas := make([]*RateLimiter, 2)
+	for i := range replicas {
+		replicas[i] = NewRateLimiter(DefaultRateLimiterConfig())
+		replicas[i].SetKeyStore(store)
+		replicas[i].SetQuotaCounter(counter)
+	}
+
+	// Each replica serves one request; the quota is used up for both
+	if rec := send(replicas[0]); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("unexpected first response %d %v", rec.Code, rec.Header())
+	}
+	if rec := send(replicas[1]); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("expected the second replica to see the first request, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := send(replicas[0]); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the shared quota to be used up, got %d", rec.Code)
+	}
+
+	// While the counter fails, the replica counts on its own
+	counter.err = errors.New("connection refused")
+	if rec := send(replicas[1]); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("expected the replica's own count, got %d %v", rec.Code, rec.Header())
+	}
+}
+
 func TestDefaultRateLimiterConfig(t *testing.T) {
 	// Test default values
 	os.Unsetenv("RATE_LIMIT_RPS")
@@ -334,76 +533,100 @@ func TestDefaultRateLimiterConfig(t *testing.T) {
 	}
 }
 
-func TestDefaultRateLimiterConfig_FromEnv(t *testing.T) {
-	os.Setenv("RATE_LIMIT_RPS", "50")
-	os.Setenv("RATE_LIMIT_BURST", "100")
-	defer os.Unsetenv("RATE_LIMIT_RPS")
-	defer os.Unsetenv("RATE_LIMIT_BURST")
+func TestRateLimiter_EndpointClasses(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterConfig{
+		RequestsPerSecond: 100,
+		BurstSize:         200,
+		ClassLimits:       map[string]ClassLimit{ClassBatch: {RequestsPerSecond: 0.001, BurstSize: 1}},
+		CleanupInterval:   10 * time.Minute,
+	})
+
+	wrappedHandler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(path string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	before := testutil.ToFloat64(metrics.RateLimitRejections.WithLabelValues(ClassBatch))
+	if code := send("/v1/predict/batch"); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if code := send("/predict/batch"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the batch class limited at both paths, got %d", code)
+	}
+	if got := testutil.ToFloat64(metrics.RateLimitRejections.WithLabelValues(ClassBatch)) - before; got != 1 {
+		t.Errorf("expected 1 batch rejection recorded, got %v", got)
+	}
 
-	cfg := DefaultRateLimiterConfig()
+	// Other classes have their own buckets with the default limits
+	for i := 0; i < 5; i++ {
+		if code := send("/v1/predict"); code != http.StatusOK {
+			t.Fatalf("expected predictions unaffected by the batch limit, got %d", code)
+		}
+	}
 
-	if cfg.RequestsPerSecond != 50 {
-		t.Errorf("expected RPS of 50, got %f", cfg.RequestsPerSecond)
+	// Reloaded class limits apply to tracked clients
+	rl.SetLimits(100, 200, map[string]ClassLimit{ClassPredict: {RequestsPerSecond: 0.001, BurstSize: 1}})
+	if code := send("/v1/predict"); code != http.StatusOK {
+		t.Errorf("expected the first request within the new burst to succeed, got %d", code)
 	}
-	if cfg.BurstSize != 100 {
-		t.Errorf("expected burst of 100, got %d", cfg.BurstSize)
+	if code := send("/v1/predict"); code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 past the new predict burst, got %d", code)
 	}
 }
 
-func TestExtractIP(t *testing.T) {
-	tests := []struct {
-		name       string
-		remoteAddr string
-		xRealIP    string
-		xForwarded string
-		expected   string
-	}{
-		{
-			name:       "RemoteAddr only",
-			remoteAddr: "192.168.1.1:12345",
-			expected:   "192.168.1.1",
-		},
-		{
-			name:       "X-Real-IP takes precedence",
-			remoteAddr: "192.168.1.1:12345",
-			xRealIP:    "10.0.0.1",
-			expected:   "10.0.0.1",
-		},
-		{
-			name:       "X-Forwarded-For without X-Real-IP",
-			remoteAddr: "192.168.1.1:12345",
-			xForwarded: "203.0.113.1",
-			expected:   "203.0.113.1",
-		},
-		{
-			name:       "X-Real-IP over X-Forwarded-For",
-			remoteAddr: "192.168.1.1:12345",
-			xRealIP:    "10.0.0.1",
-			xForwarded: "203.0.113.1",
-			expected:   "10.0.0.1",
-		},
-		{
-			name:       "RemoteAddr without port",
-			remoteAddr: "192.168.1.1",
-			expected:   "192.168.1.1",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/test", nil)
-			req.RemoteAddr = tt.remoteAddr
-			if tt.xRealIP != "" {
-				req.Header.Set("X-Real-IP", tt.xRealIP)
-			}
-			if tt.xForwarded != "" {
-				req.Header.Set("X-Forwarded-For", tt.xForwarded)
-			}
+func TestEndpointClass(t *testing.T) {
+	tests := map[string]string{
+		"/health":                 ClassRead,
+		"/v1/hierarchy":           ClassRead,
+		"/v1/jobs/42":             ClassRead,
+		"/v1/predict":             ClassPredict,
+		"/predict/simple":         ClassPredict,
+		"/v1/forecast":            ClassPredict,
+		"/whatif":                 ClassPredict,
+		"/v1/whatif/sweep":        ClassPredict,
+		"/v1/predict/batch":       ClassBatch,
+		"/predict/jobs":           ClassBatch,
+		"/v1/backtest":            ClassBatch,
+		"/v1/promotions/impact":   ClassBatch,
+		"/v1/explain":             ClassExplain,
+		"/v1/explain/global":      ClassRead,
+		"/admin/reload-model":     ClassAdmin,
+		"/v1alpha/predict":        ClassRead,
+		"/admin/config/reload":    ClassAdmin,
+		"/v1/predict/aggregate":   ClassBatch,
+		"/v1/insights/top-movers": ClassRead,
+	}
+	for path, want := range tests {
+		if got := EndpointClass(path); got != want {
+			t.Errorf("%s: expected class %s, got %s", path, want, got)
+		}
+	}
+}
 
-			ip := extractIP(req)
-			if ip != tt.expected {
-				t.Errorf("expected IP %s, got %s", tt.expected, ip)
-			}
-		})
+func TestParseClassLimits(t *testing.T) {
+	limits, err := ParseClassLimits(" batch=10/20, explain=0.5/2,,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(limits) != 2 || limits[ClassBatch] != (ClassLimit{10, 20}) || limits[ClassExplain] != (ClassLimit{0.5, 2}) {
+		t.Errorf("unexpected limits: %v", limits)
+	}
+
+	for _, bad := range []string{"bulk=10/20", "batch=10", "batch=0/20", "batch=10/-1", "batch"} {
+		if _, err := ParseClassLimits(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDefaultRateLimiterConfig_ClassLimits(t *testing.T) {
+	if cfg := DefaultRateLimiterConfig(); cfg.ClassLimits[ClassBatch] != (ClassLimit{10, 20}) {
+		t.Errorf("expected the default batch limit of 10/20, got %v", cfg.ClassLimits[ClassBatch])
 	}
 }
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// requestErrorResponse is the standard error response, with the IDs a client quotes
+// when reporting the failure.
+type requestErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+}
+
+// Recoverer recovers handler panics: it logs the panic and stack with the request's
+// context, counts it in mlrf_panics_total and, unless the handler already started its
+// response, answers 500 with an INTERNAL_ERROR error response. http.ErrAbortHandler
+// is re-panicked so net/http aborts the response as intended.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := newResponseWriter(w)
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+
+			endpoint := r.URL.Path
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+				endpoint = routeCtx.RoutePattern()
+			}
+			requestID := middleware.GetReqID(r.Context())
+			traceID := rw.Header().Get(TraceIDHeader)
+			metrics.RecordPanic(endpoint)
+			log.Error().
+				Str("request_id", requestID).
+				Str("trace_id", traceID).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("endpoint", endpoint).
+				Interface("panic", rec).
+				Bytes("stack", debug.Stack()).
+				Msg("Handler panic recovered")
+
+			// A started response can't be replaced; the client sees it cut short
+			if rw.written || r.Header.Get("Connection") == "Upgrade" {
+				return
+			}
+			writeRequestError(rw, r, http.StatusInternalServerError, "Internal server error", "INTERNAL_ERROR")
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// writeRequestError writes the standard error response with the request and trace IDs.
+func writeRequestError(w http.ResponseWriter, r *http.Request, status int, msg, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(requestErrorResponse{
+		Error:     msg,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+		TraceID:   w.Header().Get(TraceIDHeader),
+	})
+}
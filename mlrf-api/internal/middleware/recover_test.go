@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestRecoverer(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = prev }()
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(Recoverer)
+	r.Get("/boom/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map")
+	})
+	r.Get("/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		panic("mid-stream")
+	})
+
+	before := testutil.ToFloat64(metrics.Panics.WithLabelValues("/boom/{id}"))
+	req := httptest.NewRequest("GET", "/boom/7", nil)
+	req.Header.Set(RequestIDHeader, "req-9")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+	var resp requestErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("expected a JSON error response: %v", err)
+	}
+	if resp.Code != "INTERNAL_ERROR" || resp.RequestID != "req-9" {
+		t.Errorf("expected INTERNAL_ERROR with request_id req-9, got %+v", resp)
+	}
+	if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("/boom/{id}")) - before; got != 1 {
+		t.Errorf("expected 1 panic recorded, got %v", got)
+	}
+	logged := buf.String()
+	for _, want := range []string{`"request_id":"req-9"`, `"endpoint":"/boom/{id}"`, `"panic":"nil map"`, `"stack":`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("expected log to contain %s, got %s", want, logged)
+		}
+	}
+
+	// A started response is left as is
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected the partial response untouched, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	handler := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler re-panicked, got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader echoes the request ID in responses. chi's RequestID middleware also
+// reuses an ID sent by the client in this header.
+const RequestIDHeader = "X-Request-ID"
+
+// ContextKey is a custom type for context keys to avoid collisions.
+type ContextKey string
+
+// RequestIDKey is the context key handlers read the request ID from.
+const RequestIDKey ContextKey = "request_id"
+
+// RequestID copies the request ID set by chi's RequestID middleware, which must run
+// first, into RequestIDKey and echoes it in the X-Request-ID response header, so error
+// responses and client logs carry the ID the request was logged under.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rid := middleware.GetReqID(r.Context()); rid != "" {
+			w.Header().Set(RequestIDHeader, rid)
+			r = r.WithContext(context.WithValue(r.Context(), RequestIDKey, rid))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
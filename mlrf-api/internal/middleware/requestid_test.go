@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	var got interface{}
+	handler := middleware.RequestID(RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context().Value(RequestIDKey)
+	})))
+
+	req := httptest.NewRequest("GET", "/predict", nil)
+	req.Header.Set(RequestIDHeader, "client-42")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got != "client-42" {
+		t.Errorf("expected the client's request ID in the context, got %v", got)
+	}
+	if h := w.Header().Get(RequestIDHeader); h != "client-42" {
+		t.Errorf("expected the request ID echoed in %s, got %q", RequestIDHeader, h)
+	}
+
+	// Without chi's RequestID middleware there is nothing to bridge
+	w = httptest.NewRecorder()
+	RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, httptest.NewRequest("GET", "/predict", nil))
+	if h := w.Header().Get(RequestIDHeader); h != "" {
+		t.Errorf("expected no request ID header, got %q", h)
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(path); got != "fourth\n" {
+		t.Errorf("expected the newest line in the current file, got %q", got)
+	}
+	if got := read(path + ".1"); got != "third\n" {
+		t.Errorf("expected the previous line in the first backup, got %q", got)
+	}
+	if got := read(path + ".2"); got != "second\n" {
+		t.Errorf("expected the oldest kept line in the second backup, got %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, got %v", err)
+	}
+
+	rf.Close()
+	if _, err := rf.Write([]byte("late\n")); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("expected writes after Close to fail, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mlrf/mlrf-api/internal/slo"
+)
+
+// SLO returns middleware recording requests to the routes with objectives in tracker.
+// Routes under /v1 and their unversioned legacy paths count as one route.
+func SLO(tracker *slo.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			routeCtx := chi.RouteContext(r.Context())
+			if routeCtx == nil || routeCtx.RoutePattern() == "" {
+				return
+			}
+			route := strings.TrimPrefix(routeCtx.RoutePattern(), "/v1")
+			tracker.Record(route, rw.Status(), time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mlrf/mlrf-api/internal/slo"
+)
+
+func TestSLOMiddleware(t *testing.T) {
+	cfg := slo.DefaultConfig()
+	cfg.Routes = []string{"/predict"}
+	tracker := slo.NewTracker(cfg)
+
+	r := chi.NewRouter()
+	r.Use(SLO(tracker))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	failed := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }
+	r.Route("/v1", func(r chi.Router) { r.Post("/predict", ok) })
+	r.Post("/predict", failed)
+	r.Get("/health", ok)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/v1/predict", nil),
+		httptest.NewRequest(http.MethodPost, "/predict", nil),
+		httptest.NewRequest(http.MethodGet, "/health", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	statuses := tracker.Statuses()
+	if len(statuses) != 1 || statuses[0].Availability.Total != 2 || statuses[0].Availability.Good != 1 {
+		t.Errorf("expected /v1/predict and /predict counted as one route, got %+v", statuses)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultRouteTimeouts are the route timeouts used without REQUEST_TIMEOUTS.
+const DefaultRouteTimeouts = "/predict=1s,/predict/simple=1s,/backtest=5m"
+
+// timeoutWriteGrace is the time past a request's timeout its response may still be
+// written in, so the deadline error reaches the client.
+const timeoutWriteGrace = 5 * time.Second
+
+// TimeoutConfig holds request timeout configuration.
+type TimeoutConfig struct {
+	Default      time.Duration            // Timeout of routes without an override
+	Routes       map[string]time.Duration // Timeout overrides by route, without the /v1 prefix
+	SkipSuffixes []string                 // Endpoints managing their own per-write deadlines
+}
+
+// DefaultTimeoutConfig returns default request timeout configuration.
+func DefaultTimeoutConfig() TimeoutConfig {
+	cfg := TimeoutConfig{
+		Default:      30 * time.Second,
+		SkipSuffixes: []string{"/predict/stream", "/ws"},
+	}
+	cfg.Routes, _ = ParseTimeouts(DefaultRouteTimeouts)
+	return cfg
+}
+
+// ParseTimeouts parses a comma-separated list of timeouts by route, such as
+// "/predict=1s,/backtest=5m".
+func ParseTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		route, val, ok := strings.Cut(field, "=")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(route), "/") {
+			return nil, fmt.Errorf("invalid route timeout %q: must be route=duration", field)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid route timeout %q: must be a positive duration", field)
+		}
+		timeouts[strings.TrimSpace(route)] = timeout
+	}
+	return timeouts, nil
+}
+
+// timeoutFor returns the timeout of path, or 0 for paths without one.
+func (cfg TimeoutConfig) timeoutFor(path string) time.Duration {
+	for _, suffix := range cfg.SkipSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return 0
+		}
+	}
+	if rest := strings.TrimPrefix(path, "/v1"); strings.HasPrefix(rest, "/") {
+		path = rest
+	}
+	if timeout, ok := cfg.Routes[path]; ok {
+		return timeout
+	}
+	return cfg.Default
+}
+
+// Timeout returns middleware that gives each request a context deadline of its
+// route's timeout, which inference, cache and SHAP calls made with the request context
+// observe. The response write deadline is moved to match, so routes may outlive the
+// server's WriteTimeout. A handler that returns past the deadline without responding
+// gets 504 DEADLINE_EXCEEDED written for it.
+func Timeout(cfg TimeoutConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := cfg.timeoutFor(r.URL.Path)
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			// Writers without deadline support keep the server's WriteTimeout
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + timeoutWriteGrace))
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+			if !rw.written && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				writeRequestError(rw, r, http.StatusGatewayTimeout, fmt.Sprintf("request exceeded its %s timeout", timeout), "DEADLINE_EXCEEDED")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	cfg := TimeoutConfig{
+		Default:      30 * time.Second,
+		Routes:       map[string]time.Duration{"/predict": time.Second, "/backtest": 5 * time.Minute},
+		SkipSuffixes: []string{"/predict/stream"},
+	}
+	var deadline time.Time
+	var hasDeadline bool
+	handler := Timeout(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	}))
+
+	tests := []struct {
+		path    string
+		timeout time.Duration // 0 for no deadline
+	}{
+		{"/v1/predict", time.Second},
+		{"/predict", time.Second},
+		{"/v1/backtest", 5 * time.Minute},
+		{"/v1/hierarchy", 30 * time.Second},
+		{"/v1/predict/stream", 0},
+		{"/predict/stream", 0},
+	}
+
+	for _, tt := range tests {
+		start := time.Now()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", tt.path, nil))
+		if hasDeadline != (tt.timeout > 0) {
+			t.Errorf("%s: expected deadline=%v, got %v", tt.path, tt.timeout > 0, hasDeadline)
+			continue
+		}
+		if hasDeadline {
+			if got := deadline.Sub(start); got < tt.timeout || got > tt.timeout+time.Second/10 {
+				t.Errorf("%s: expected a %s timeout, got %s", tt.path, tt.timeout, got)
+			}
+		}
+	}
+}
+
+func TestTimeoutWritesDeadlineExceeded(t *testing.T) {
+	cfg := TimeoutConfig{Default: 10 * time.Millisecond}
+
+	// A handler giving up at the deadline without responding gets a 504
+	handler := Timeout(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/predict", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d", w.Code)
+	}
+	var resp requestErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != "DEADLINE_EXCEEDED" {
+		t.Errorf("expected a DEADLINE_EXCEEDED error response, got %+v (%v)", resp, err)
+	}
+
+	// A handler's own response is left as is
+	handler = Timeout(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/predict", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the handler's status 503, got %d", w.Code)
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	timeouts, err := ParseTimeouts(" /predict=1s, /backtest = 5m,,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(timeouts) != 2 || timeouts["/predict"] != time.Second || timeouts["/backtest"] != 5*time.Minute {
+		t.Errorf("unexpected timeouts: %v", timeouts)
+	}
+
+	for _, bad := range []string{"predict=1s", "/predict", "/predict=soon", "/predict=0s"} {
+		if _, err := ParseTimeouts(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDefaultTimeoutConfig(t *testing.T) {
+	if cfg := DefaultTimeoutConfig(); cfg.Default != 30*time.Second || cfg.Routes["/predict"] != time.Second {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+}
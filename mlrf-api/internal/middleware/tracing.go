@@ -27,6 +27,11 @@ func Tracing(tp *tracing.TracerProvider) func(http.Handler) http.Handler {
 		// Use otelhttp.NewHandler for automatic span creation and context propagation
 		handler := otelhttp.NewHandler(
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Return the trace ID, so clients can quote it when reporting a problem
+				if traceID := TraceID(r); traceID != "" {
+					w.Header().Set(TraceIDHeader, traceID)
+				}
+
 				// Add request ID to span if available
 				if requestID := chimiddleware.GetReqID(r.Context()); requestID != "" {
 					span := trace.SpanFromContext(r.Context())
@@ -82,6 +87,19 @@ func TracingMiddlewareWithFilter(tp *tracing.TracerProvider, skipPaths []string)
 	}
 }
 
+// TraceIDHeader is the response header carrying the trace ID of a traced request.
+const TraceIDHeader = "X-Trace-Id"
+
+// TraceID returns the trace ID of the request's span if it is recording, or "" if the
+// request isn't traced.
+func TraceID(r *http.Request) string {
+	if !trace.SpanFromContext(r.Context()).IsRecording() {
+		return ""
+	}
+	traceID, _ := InjectTraceContext(r)
+	return traceID
+}
+
 // InjectTraceContext extracts trace ID and span ID from context and returns them.
 // Useful for logging or passing to downstream services.
 func InjectTraceContext(r *http.Request) (traceID, spanID string) {
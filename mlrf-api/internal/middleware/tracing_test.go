@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/mlrf/mlrf-api/internal/tracing"
 )
@@ -158,6 +159,26 @@ func TestInjectTraceContext_NoSpan(t *testing.T) {
 	}
 }
 
+func TestTraceID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if id := TraceID(req); id != "" {
+		t.Errorf("expected no trace ID without a span, got %s", id)
+	}
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(req.Context(), "test-span")
+	defer span.End()
+	if id := TraceID(req.WithContext(ctx)); id != span.SpanContext().TraceID().String() {
+		t.Errorf("expected trace ID %s, got %q", span.SpanContext().TraceID(), id)
+	}
+
+	// A span that isn't recorded isn't exported, so its trace can't be looked up
+	ctx, span = sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())).Tracer("test").Start(req.Context(), "test-span")
+	defer span.End()
+	if id := TraceID(req.WithContext(ctx)); id != "" {
+		t.Errorf("expected no trace ID for an unsampled span, got %s", id)
+	}
+}
+
 func TestTracingMiddleware_WithChiRouter(t *testing.T) {
 	cfg := tracing.Config{
 		Enabled:     false,
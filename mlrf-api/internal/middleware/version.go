@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Version headers.
+const (
+	// AcceptVersionHeader lets clients of unversioned routes request an API version.
+	AcceptVersionHeader = "Accept-Version"
+	// VersionHeader reports the API version that served the request.
+	VersionHeader = "API-Version"
+)
+
+// versionKey is the context key for the negotiated API version.
+type versionKey struct{}
+
+// VersionConfig holds API version negotiation configuration.
+type VersionConfig struct {
+	Supported []string  // Versions that can be requested, e.g. "v1"
+	Default   string    // Version served to legacy clients that don't ask for one
+	Sunset    time.Time // When legacy unversioned routes go away (zero = not announced)
+}
+
+// DefaultVersionConfig returns the version configuration, without a legacy route
+// sunset date.
+func DefaultVersionConfig() VersionConfig {
+	return VersionConfig{
+		Supported: []string{"v1"},
+		Default:   "v1",
+	}
+}
+
+// APIVersion returns the API version negotiated for the request, or "" if none.
+func APIVersion(ctx context.Context) string {
+	if v, ok := ctx.Value(versionKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Version returns middleware that pins requests to a fixed API version.
+// Mounted on versioned route groups such as /v1.
+func Version(version string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(VersionHeader, version)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, version)))
+		})
+	}
+}
+
+// LegacyVersion returns middleware for unversioned (legacy) routes.
+// It negotiates the version from the Accept-Version header (falling back to the
+// default), rejects unsupported versions, and marks responses deprecated with
+// Deprecation, Sunset and successor-version Link headers.
+func LegacyVersion(cfg VersionConfig) func(http.Handler) http.Handler {
+	supported := make(map[string]bool)
+	for _, v := range cfg.Supported {
+		supported[v] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := strings.ToLower(strings.TrimSpace(r.Header.Get(AcceptVersionHeader)))
+			if version == "" {
+				version = cfg.Default
+			}
+			if !supported[version] {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(errorResponse{
+					Error: "unsupported API version: " + version,
+					Code:  "UNSUPPORTED_VERSION",
+				})
+				return
+			}
+
+			w.Header().Set("Deprecation", "true")
+			if !cfg.Sunset.IsZero() {
+				w.Header().Set("Sunset", cfg.Sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Set("Link", "</"+version+r.URL.Path+`>; rel="successor-version"`)
+			w.Header().Set(VersionHeader, version)
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, version)))
+		})
+	}
+}
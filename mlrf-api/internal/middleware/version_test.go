@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersion(t *testing.T) {
+	var got string
+	handler := Version("v1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = APIVersion(r.Context())
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/predict", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got != "v1" {
+		t.Errorf("expected version v1 in context, got %q", got)
+	}
+	if rec.Header().Get(VersionHeader) != "v1" {
+		t.Errorf("expected %s header v1, got %q", VersionHeader, rec.Header().Get(VersionHeader))
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Error("versioned routes must not be marked deprecated")
+	}
+}
+
+func TestLegacyVersion(t *testing.T) {
+	cfg := VersionConfig{
+		Supported: []string{"v1"},
+		Default:   "v1",
+		Sunset:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var got string
+	handler := LegacyVersion(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = APIVersion(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("defaults and marks deprecated", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/predict", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got != "v1" {
+			t.Errorf("expected default version v1, got %q", got)
+		}
+		if rec.Header().Get("Deprecation") != "true" {
+			t.Error("expected Deprecation header")
+		}
+		if rec.Header().Get("Sunset") != "Fri, 01 Jan 2027 00:00:00 GMT" {
+			t.Errorf("unexpected Sunset header %q", rec.Header().Get("Sunset"))
+		}
+		if rec.Header().Get("Link") != `</v1/predict>; rel="successor-version"` {
+			t.Errorf("unexpected Link header %q", rec.Header().Get("Link"))
+		}
+	})
+
+	t.Run("honors Accept-Version", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/predict", nil)
+		req.Header.Set(AcceptVersionHeader, "V1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || got != "v1" {
+			t.Errorf("expected v1 negotiated, got status %d version %q", rec.Code, got)
+		}
+	})
+
+	t.Run("rejects unsupported version", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/predict", nil)
+		req.Header.Set(AcceptVersionHeader, "v9")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestDefaultVersionConfig(t *testing.T) {
+	if cfg := DefaultVersionConfig(); !cfg.Sunset.IsZero() || cfg.Default != "v1" {
+		t.Errorf("unexpected default config: %+v", cfg)
+	}
+}
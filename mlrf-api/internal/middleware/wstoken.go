@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebSocketTokenParam is the query parameter carrying a WebSocket token. Browsers
+// can't set X-API-Key on a WebSocket upgrade, so /ws also accepts a short-lived token
+// issued by POST /ws/token to a request that sent the key.
+const WebSocketTokenParam = "token"
+
+// WebSocketTokenTTL is how long a WebSocket token can be used to connect. It only
+// needs to outlive the upgrade; an open connection is not closed when it expires.
+const WebSocketTokenTTL = time.Minute
+
+// tokenPaths accept a WebSocket token in place of an API key header.
+var tokenPaths = map[string]bool{
+	"/ws": true,
+}
+
+// WebSocketToken is the response of POST /ws/token.
+type WebSocketToken struct {
+	Token     string    `json:"token"` // Empty when authentication is disabled
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueToken returns a WebSocket token for key, valid until now plus
+// WebSocketTokenTTL. The token is "<name>.<expiry>.<mac>", signed with the key's
+// secret hash, so any instance with the same keys accepts it and rotating the key
+// revokes it.
+func (s *KeyStore) IssueToken(key *APIKey, now time.Time) WebSocketToken {
+	expires := now.Add(WebSocketTokenTTL).Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(key.Name)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return WebSocketToken{Token: payload + "." + tokenMAC(key, payload), ExpiresAt: expires.UTC()}
+}
+
+// tokenKey returns the key a WebSocket token was issued to, if it is valid and has
+// not expired at now.
+func (s *KeyStore) tokenKey(token string, now time.Time) (*APIKey, bool) {
+	name, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+	expiry, mac, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, false
+	}
+	rawName, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return nil, false
+	}
+
+	for _, k := range *s.keys.Load() {
+		if k.Name == string(rawName) {
+			return k, hmac.Equal([]byte(mac), []byte(tokenMAC(k, name+"."+expiry)))
+		}
+	}
+	return nil, false
+}
+
+// tokenMAC signs a token payload with the key's secret hash.
+func tokenMAC(key *APIKey, payload string) string {
+	mac := hmac.New(sha256.New, key.hash[:])
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebSocketTokenHandler serves POST /ws/token: a WebSocket token for the API key that
+// authenticated the request. Mount it behind Middleware.
+func (s *KeyStore) WebSocketTokenHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	token := WebSocketToken{ExpiresAt: now.Add(WebSocketTokenTTL).Truncate(time.Second).UTC()}
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		token = s.IssueToken(key, now)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(token)
+}
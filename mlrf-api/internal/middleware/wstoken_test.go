@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWebSocketToken(t *testing.T) {
+	store, err := NewKeyStore(KeyStoreConfig{Key: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ws/token" {
+			store.WebSocketTokenHandler(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/ws/token", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected issuing a token without a key to fail, got %d", rec.Code)
+	}
+	rec := serve(http.MethodPost, "/ws/token", "secret")
+	var token WebSocketToken
+	if err := json.NewDecoder(rec.Body).Decode(&token); err != nil || rec.Code != http.StatusOK || token.Token == "" {
+		t.Fatalf("expected a token, got %d %s", rec.Code, rec.Body)
+	}
+	if ttl := time.Until(token.ExpiresAt); ttl <= 0 || ttl > WebSocketTokenTTL {
+		t.Errorf("unexpected expiry %v", token.ExpiresAt)
+	}
+
+	if rec := serve(http.MethodGet, "/ws?token="+url.QueryEscape(token.Token), ""); rec.Code != http.StatusOK {
+		t.Errorf("expected the token to authenticate /ws, got %d", rec.Code)
+	}
+
+	expired := store.IssueToken((*store.keys.Load())[0], time.Now().Add(-2*WebSocketTokenTTL))
+	forged := token.Token[:len(token.Token)-4] + "0000"
+	for name, target := range map[string]string{
+		"missing":      "/ws",
+		"invalid":      "/ws?token=not-a-token",
+		"forged":       "/ws?token=" + url.QueryEscape(forged),
+		"expired":      "/ws?token=" + url.QueryEscape(expired.Token),
+		"other path":   "/v1/predict?token=" + url.QueryEscape(token.Token),
+		"unknown name": "/ws?token=" + url.QueryEscape("b3RoZXI."+token.Token[len("ZGVmYXVsdA."):]),
+	} {
+		if rec := serve(http.MethodGet, target, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	// Rotating the key revokes its tokens
+	if err := store.Load(KeyStoreConfig{Key: "rotated"}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(http.MethodGet, "/ws?token="+url.QueryEscape(token.Token), ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a token of a rotated key to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testChild struct {
+	Name string `json:"name"`
+}
+
+type testNode struct {
+	ID       string             `json:"id"`
+	Score    float32            `json:"score,omitempty"`
+	Tags     []string           `json:"tags"`
+	Extra    map[string]float64 `json:"extra,omitempty"`
+	Child    *testChild         `json:"child,omitempty"`
+	Children []testNode         `json:"children,omitempty"`
+	Created  time.Time          `json:"created"`
+	Ignored  string             `json:"-"`
+	internal string
+}
+
+func TestSchemaRegistersStructs(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+
+	ref := b.Schema(testNode{})
+	if ref.Ref != "#/components/schemas/testNode" {
+		t.Fatalf("expected $ref to testNode, got %+v", ref)
+	}
+
+	schemas := b.Document().Components.Schemas
+	node, ok := schemas["testNode"]
+	if !ok {
+		t.Fatal("expected testNode registered in components")
+	}
+	if _, ok := schemas["testChild"]; !ok {
+		t.Error("expected nested testChild registered in components")
+	}
+
+	if node.Type != "object" {
+		t.Errorf("expected object type, got %s", node.Type)
+	}
+	if got := node.Properties["score"]; got.Type != "number" || got.Format != "float" {
+		t.Errorf("unexpected float32 schema: %+v", got)
+	}
+	if got := node.Properties["tags"]; got.Type != "array" || got.Items.Type != "string" {
+		t.Errorf("unexpected slice schema: %+v", got)
+	}
+	if got := node.Properties["extra"]; got.Type != "object" || got.AdditionalProperties.Type != "number" {
+		t.Errorf("unexpected map schema: %+v", got)
+	}
+	if got := node.Properties["children"]; got.Items.Ref != "#/components/schemas/testNode" {
+		t.Errorf("expected self reference for children, got %+v", got.Items)
+	}
+	if got := node.Properties["created"]; got.Format != "date-time" {
+		t.Errorf("expected date-time format for time.Time, got %+v", got)
+	}
+	if _, ok := node.Properties["Ignored"]; ok {
+		t.Error("json:\"-\" field must be skipped")
+	}
+	if _, ok := node.Properties["internal"]; ok {
+		t.Error("unexported field must be skipped")
+	}
+
+	if want := []string{"created", "id", "tags"}; !reflect.DeepEqual(node.Required, want) {
+		t.Errorf("expected required %v, got %v", want, node.Required)
+	}
+}
+
+type testEmbedded struct {
+	testChild
+	Count int `json:"count"`
+}
+
+func TestSchemaFlattensEmbeddedStructs(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.Schema(testEmbedded{})
+
+	s := b.Document().Components.Schemas["testEmbedded"]
+	if _, ok := s.Properties["name"]; !ok {
+		t.Errorf("expected embedded field name to be flattened, got %v", s.Properties)
+	}
+	if _, ok := s.Properties["testChild"]; ok {
+		t.Error("expected no property for the embedded struct itself")
+	}
+	if !reflect.DeepEqual(s.Required, []string{"count", "name"}) {
+		t.Errorf("unexpected required fields: %v", s.Required)
+	}
+}
+
+func TestBuilderDocument(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.Add("POST", "/items", &Operation{
+		OperationID: "createItem",
+		RequestBody: b.JSONBody(testChild{}),
+		Responses:   map[string]Response{"200": b.JSONResponse("ok", testNode{})},
+	})
+
+	doc := b.Document()
+	if doc.OpenAPI != Version {
+		t.Errorf("expected openapi %s, got %s", Version, doc.OpenAPI)
+	}
+	op, ok := doc.Paths["/items"]["post"]
+	if !ok {
+		t.Fatal("expected POST /items registered under lowercase method")
+	}
+	if !op.RequestBody.Required {
+		t.Error("expected JSON body to be required")
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("document must serialize: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package predlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// findChunk is the number of rows read from a log file at a time.
+const findChunk = 1024
+
+// Find returns the entries logged under requestID in the completed log files of dir,
+// in the order they were logged. Files are searched newest first, and the search
+// stops at the first older file without a match once entries were found, so a
+// request's entries spanning a rotation are all returned.
+func Find(dir, requestID string) ([]Entry, error) {
+	files, err := completedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Entry
+	for _, path := range files {
+		entries, err := findInFile(path, func(e Entry) bool { return e.RequestID == requestID })
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			if len(found) > 0 {
+				break
+			}
+			continue
+		}
+		found = append(entries, found...)
+	}
+	return found, nil
+}
+
+// Find returns the entries logged under requestID in the logger's completed files.
+// Entries still queued or in the file being written are found once it is completed.
+func (l *Logger) Find(requestID string) ([]Entry, error) {
+	return Find(l.cfg.Dir, requestID)
+}
+
+// SeriesKey identifies the predictions served for one series and date.
+type SeriesKey struct {
+	StoreNbr int32
+	Family   string
+	Date     string
+}
+
+// FindSeries returns the latest entry logged for each key in the completed log files
+// of dir; keys without one are left out. A horizon of 0 matches entries of any horizon.
+// Files are searched newest first, stopping once every key is found.
+func FindSeries(dir string, horizon int32, keys []SeriesKey) (map[SeriesKey]Entry, error) {
+	files, err := completedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[SeriesKey]bool, len(keys))
+	for _, k := range keys {
+		wanted[k] = true
+	}
+	found := make(map[SeriesKey]Entry, len(wanted))
+	for _, path := range files {
+		if len(found) == len(wanted) {
+			break
+		}
+		entries, err := findInFile(path, func(e Entry) bool {
+			k := SeriesKey{StoreNbr: e.StoreNbr, Family: e.Family, Date: e.Date}
+			return wanted[k] && (horizon == 0 || e.Horizon == horizon)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Entries are in log order, so the last of a file is its latest; keys found
+		// in a newer file are kept
+		latest := make(map[SeriesKey]Entry)
+		for _, e := range entries {
+			latest[SeriesKey{StoreNbr: e.StoreNbr, Family: e.Family, Date: e.Date}] = e
+		}
+		for k, e := range latest {
+			if _, ok := found[k]; !ok {
+				found[k] = e
+			}
+		}
+	}
+	return found, nil
+}
+
+// FindSeries returns the latest entry logged for each key in the logger's completed
+// files (see the FindSeries function).
+func (l *Logger) FindSeries(horizon int32, keys []SeriesKey) (map[SeriesKey]Entry, error) {
+	return FindSeries(l.cfg.Dir, horizon, keys)
+}
+
+// completedFiles returns the completed log files of dir, newest first.
+func completedFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "predictions-*.parquet"))
+	if err != nil {
+		return nil, err
+	}
+	// File names embed their UTC creation time, so they sort oldest first
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// findInFile returns the entries of one log file that match.
+func findInFile(path string, match func(Entry) bool) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := parquet.NewGenericReader[Entry](f)
+	defer reader.Close()
+
+	var found []Entry
+	buf := make([]Entry, findChunk)
+	for {
+		// Cleared so matches kept from the last chunk don't share its slices
+		clear(buf)
+		n, err := reader.Read(buf)
+		for _, e := range buf[:n] {
+			if match(e) {
+				found = append(found, e)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package predlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoggerFind(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(testConfig(dir))
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	// 4 rows per file: "batch" spans the first two files, "single" is in the third
+	ids := []string{"other", "other", "other", "batch", "batch", "batch", "other", "other", "single"}
+	for i, id := range ids {
+		l.Log(Entry{
+			Timestamp:  time.Now().UTC(),
+			RequestID:  id,
+			StoreNbr:   int32(i + 1),
+			Family:     "DAIRY",
+			Features:   []float32{float32(i)},
+			Prediction: float32(100 + i),
+		})
+	}
+	l.Close()
+	if files := logFiles(t, dir, "predictions-*.parquet"); len(files) != 3 {
+		t.Fatalf("expected 3 log files, got %v", files)
+	}
+
+	entries, err := l.Find("batch")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if e.StoreNbr != int32(i+4) || len(e.Features) != 1 || e.Features[0] != float32(i+3) || e.Prediction != float32(103+i) {
+			t.Errorf("entry %d: unexpected %+v", i, e)
+		}
+	}
+
+	if entries, err := l.Find("single"); err != nil || len(entries) != 1 || entries[0].StoreNbr != 9 {
+		t.Errorf("expected the single entry, got %+v, %v", entries, err)
+	}
+	if entries, err := l.Find("missing"); err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries, got %+v, %v", entries, err)
+	}
+}
+
+func TestFindSkipsInProgressFiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.BatchSize = 1
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Log(Entry{RequestID: "pending", Prediction: 1})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(logFiles(t, dir, "*"+inProgressSuffix)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry to be written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if entries, err := Find(dir, "pending"); err != nil || len(entries) != 0 {
+		t.Errorf("expected entries of the file being written not to be found, got %+v, %v", entries, err)
+	}
+}
+
+func TestLoggerFindSeries(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(testConfig(dir))
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	// 4 rows per file: store 1's prediction is revised in the second file
+	entries := []Entry{
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 10},
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-14", Horizon: 15, Prediction: 20},
+		{StoreNbr: 2, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 21},
+		{StoreNbr: 3, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 30},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-14", Horizon: 30, Prediction: 11},
+		{StoreNbr: 1, Family: "DAIRY", Date: "2017-08-15", Horizon: 30, Prediction: 12},
+	}
+	for _, e := range entries {
+		l.Log(e)
+	}
+	l.Close()
+
+	key := func(store int32, date string) SeriesKey {
+		return SeriesKey{StoreNbr: store, Family: "DAIRY", Date: date}
+	}
+	keys := []SeriesKey{key(1, "2017-08-14"), key(2, "2017-08-14"), key(4, "2017-08-14")}
+
+	found, err := l.FindSeries(0, keys)
+	if err != nil {
+		t.Fatalf("FindSeries failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 series found, got %+v", found)
+	}
+	if e := found[key(1, "2017-08-14")]; e.Prediction != 11 {
+		t.Errorf("expected the revised prediction 11, got %+v", e)
+	}
+	if e := found[key(2, "2017-08-14")]; e.Prediction != 21 {
+		t.Errorf("expected the last logged prediction 21, got %+v", e)
+	}
+
+	found, err = l.FindSeries(15, keys)
+	if err != nil {
+		t.Fatalf("FindSeries failed: %v", err)
+	}
+	if len(found) != 1 || found[key(2, "2017-08-14")].Prediction != 20 {
+		t.Errorf("expected only the horizon 15 prediction 20, got %+v", found)
+	}
+}
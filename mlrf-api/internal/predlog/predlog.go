@@ -0,0 +1,250 @@
+// Package predlog persists served predictions for auditing and model monitoring.
+//
+// Entries are queued on a buffered channel and written by a single background
+// writer in batches to Parquet files under a directory. Each file is written as
+// "<name>.parquet.inprogress" and renamed to "<name>.parquet" once complete,
+// so readers only ever see finished files. Files rotate by row count and age.
+package predlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+)
+
+// inProgressSuffix marks a log file that is still being written.
+const inProgressSuffix = ".inprogress"
+
+// Config holds prediction logger configuration.
+type Config struct {
+	Enabled        bool
+	Dir            string        // Directory Parquet files are written to
+	BufferSize     int           // Queued entries before new entries are dropped
+	BatchSize      int           // Entries per write (one Parquet row group)
+	FlushInterval  time.Duration // Maximum time an entry waits before being written
+	MaxRowsPerFile int           // Rows before rotating to a new file
+	RotateInterval time.Duration // Maximum age of a file before rotating
+}
+
+// DefaultConfig returns the default prediction logger configuration, disabled.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:        false,
+		Dir:            "data/prediction_logs",
+		BufferSize:     10000,
+		BatchSize:      500,
+		FlushInterval:  5 * time.Second,
+		MaxRowsPerFile: 1000000,
+		RotateInterval: time.Hour,
+	}
+}
+
+// Entry is one served prediction.
+type Entry struct {
+	Timestamp    time.Time `parquet:"timestamp"`
+	RequestID    string    `parquet:"request_id"`
+	Endpoint     string    `parquet:"endpoint"`
+	StoreNbr     int32     `parquet:"store_nbr"`
+	Family       string    `parquet:"family"`
+	Date         string    `parquet:"date"`
+	Horizon      int32     `parquet:"horizon"`
+	Model        string    `parquet:"model"` // Registry key, or empty for the champion
+	ModelVersion string    `parquet:"model_version"`
+	Features     []float32 `parquet:"features,list"` // Empty for cache hits without feature lookup
+	Prediction   float32   `parquet:"prediction"`
+	Lower80      *float32  `parquet:"lower_80,optional"`
+	Upper80      *float32  `parquet:"upper_80,optional"`
+	Lower95      *float32  `parquet:"lower_95,optional"`
+	Upper95      *float32  `parquet:"upper_95,optional"`
+	Cached       bool      `parquet:"cached"`
+	LatencyMs    float64   `parquet:"latency_ms"`
+}
+
+// Logger writes entries asynchronously. Safe for concurrent use.
+type Logger struct {
+	cfg     Config
+	entries chan Entry
+	flush   chan chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	// Owned by the writer goroutine
+	file     *os.File
+	path     string
+	writer   *parquet.GenericWriter[Entry]
+	rows     int
+	openedAt time.Time
+}
+
+// NewLogger creates the log directory and starts the background writer.
+func NewLogger(cfg Config) (*Logger, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create prediction log directory: %w", err)
+	}
+
+	l := &Logger{
+		cfg:     cfg,
+		entries: make(chan Entry, cfg.BufferSize),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Log queues an entry without blocking. Entries are dropped when the buffer is full.
+func (l *Logger) Log(e Entry) {
+	select {
+	case l.entries <- e:
+	default:
+		metrics.RecordPredictionLog("dropped", 1)
+	}
+}
+
+// Pending returns the number of queued entries not yet taken by the writer.
+func (l *Logger) Pending() int {
+	return len(l.entries)
+}
+
+// Flush writes the entries queued so far and completes the current file, returning
+// once it is published or when ctx is done. Unlike Close, the logger keeps accepting
+// entries, which go to a new file.
+func (l *Logger) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case l.flush <- ack:
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes queued entries, completes the current file and stops the writer.
+// Log must not be called after Close.
+func (l *Logger) Close() {
+	l.once.Do(func() {
+		close(l.entries)
+		<-l.done
+	})
+}
+
+// run batches entries until the channel is closed.
+func (l *Logger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case e, ok := <-l.entries:
+			if !ok {
+				l.write(batch)
+				l.closeFile()
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= l.cfg.BatchSize {
+				l.write(batch)
+				batch = batch[:0]
+			}
+		case ack := <-l.flush:
+			// Take what is queued now, then write it all
+			for queued := len(l.entries); queued > 0; queued-- {
+				batch = append(batch, <-l.entries)
+			}
+			l.write(batch)
+			batch = batch[:0]
+			l.closeFile()
+			close(ack)
+		case <-ticker.C:
+			l.write(batch)
+			batch = batch[:0]
+			if l.file != nil && time.Since(l.openedAt) >= l.cfg.RotateInterval {
+				l.closeFile()
+			}
+		}
+	}
+}
+
+// write appends a batch as one row group, opening or rotating files as needed.
+func (l *Logger) write(batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	if l.file != nil && l.rows >= l.cfg.MaxRowsPerFile {
+		l.closeFile()
+	}
+	if l.file == nil {
+		if err := l.openFile(); err != nil {
+			log.Error().Err(err).Msg("failed to open prediction log file")
+			metrics.RecordPredictionLog("failed", len(batch))
+			return
+		}
+	}
+
+	if _, err := l.writer.Write(batch); err != nil {
+		log.Error().Err(err).Str("path", l.path).Msg("failed to write prediction log")
+		metrics.RecordPredictionLog("failed", len(batch))
+		return
+	}
+	if err := l.writer.Flush(); err != nil {
+		log.Error().Err(err).Str("path", l.path).Msg("failed to flush prediction log")
+		metrics.RecordPredictionLog("failed", len(batch))
+		return
+	}
+	l.rows += len(batch)
+	metrics.RecordPredictionLog("written", len(batch))
+}
+
+// openFile starts a new in-progress Parquet file.
+func (l *Logger) openFile() error {
+	now := time.Now().UTC()
+	name := fmt.Sprintf("predictions-%s.parquet", now.Format("20060102T150405.000000000"))
+	path := filepath.Join(l.cfg.Dir, name)
+
+	file, err := os.Create(path + inProgressSuffix)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.path = path
+	l.writer = parquet.NewGenericWriter[Entry](file)
+	l.rows = 0
+	l.openedAt = now
+	return nil
+}
+
+// closeFile writes the Parquet footer and publishes the file under its final name.
+func (l *Logger) closeFile() {
+	if l.file == nil {
+		return
+	}
+	if err := l.writer.Close(); err != nil {
+		log.Error().Err(err).Str("path", l.path).Msg("failed to finish prediction log file")
+	}
+	if err := l.file.Close(); err != nil {
+		log.Error().Err(err).Str("path", l.path).Msg("failed to close prediction log file")
+	}
+	if err := os.Rename(l.path+inProgressSuffix, l.path); err != nil {
+		log.Error().Err(err).Str("path", l.path).Msg("failed to publish prediction log file")
+	}
+	log.Debug().Str("path", l.path).Int("rows", l.rows).Msg("prediction log file completed")
+	l.file = nil
+	l.writer = nil
+}
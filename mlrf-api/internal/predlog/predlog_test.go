@@ -0,0 +1,159 @@
+package predlog
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testConfig(dir string) Config {
+	return Config{
+		Enabled:        true,
+		Dir:            dir,
+		BufferSize:     100,
+		BatchSize:      2,
+		FlushInterval:  time.Hour,
+		MaxRowsPerFile: 4,
+		RotateInterval: time.Hour,
+	}
+}
+
+func logFiles(t *testing.T, dir, pattern string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestLoggerWritesParquet(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(testConfig(dir))
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	lower := float32(90)
+	for i := 0; i < 5; i++ {
+		l.Log(Entry{
+			Timestamp:  time.Now().UTC(),
+			Endpoint:   "/predict",
+			StoreNbr:   int32(i + 1),
+			Family:     "DAIRY",
+			Date:       "2017-08-01",
+			Horizon:    30,
+			Features:   []float32{1, 2, 3},
+			Prediction: 100,
+			Lower80:    &lower,
+		})
+	}
+	l.Close()
+
+	// 5 rows with 4 rows per file: two complete files, none in progress
+	files := logFiles(t, dir, "predictions-*.parquet")
+	if len(files) != 2 {
+		t.Fatalf("expected 2 log files, got %v", files)
+	}
+	if pending := logFiles(t, dir, "*"+inProgressSuffix); len(pending) != 0 {
+		t.Errorf("expected no in-progress files after Close, got %v", pending)
+	}
+
+	var total int
+	for _, f := range files {
+		rows, err := parquet.ReadFile[Entry](f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f, err)
+		}
+		total += len(rows)
+		if rows[0].Family != "DAIRY" || len(rows[0].Features) != 3 || rows[0].Lower80 == nil || *rows[0].Lower80 != 90 {
+			t.Errorf("unexpected row: %+v", rows[0])
+		}
+		if rows[0].Upper80 != nil {
+			t.Error("expected unset interval to round-trip as nil")
+		}
+	}
+	if total != 5 {
+		t.Errorf("expected 5 logged rows, got %d", total)
+	}
+}
+
+func TestLoggerFlushInterval(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.BatchSize = 100
+	cfg.FlushInterval = 10 * time.Millisecond
+	cfg.RotateInterval = 10 * time.Millisecond
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Log(Entry{Family: "DAIRY", Prediction: 1})
+
+	// A partial batch is written on the ticker and the aged file is rotated
+	deadline := time.Now().Add(2 * time.Second)
+	for len(logFiles(t, dir, "predictions-*.parquet")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry to be flushed and published")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLoggerFlush(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.BatchSize = 100
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Log(Entry{Family: "DAIRY", Prediction: 1})
+	l.Log(Entry{Family: "DAIRY", Prediction: 2})
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// The partial batch is written and its file published without waiting for the ticker
+	files := logFiles(t, dir, "predictions-*.parquet")
+	if len(files) != 1 {
+		t.Fatalf("expected 1 published log file, got %v", files)
+	}
+	rows, err := parquet.ReadFile[Entry](files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || l.Pending() != 0 {
+		t.Errorf("expected 2 rows and nothing pending, got %d rows and %d pending", len(rows), l.Pending())
+	}
+
+	// The logger keeps accepting entries
+	l.Log(Entry{Family: "DAIRY", Prediction: 3})
+	l.Close()
+	if files := logFiles(t, dir, "predictions-*.parquet"); len(files) != 2 {
+		t.Errorf("expected a second log file after Close, got %v", files)
+	}
+}
+
+func TestLoggerDropsWhenFull(t *testing.T) {
+	l := &Logger{entries: make(chan Entry, 1)}
+	l.Log(Entry{})
+	l.Log(Entry{}) // must not block
+	if len(l.entries) != 1 {
+		t.Errorf("expected 1 queued entry, got %d", len(l.entries))
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Enabled || cfg.BatchSize != 500 || cfg.FlushInterval != 5*time.Second {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
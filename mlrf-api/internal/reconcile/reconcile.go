@@ -0,0 +1,383 @@
+// Package reconcile makes hierarchical forecasts coherent, so every parent
+// equals the sum of its children.
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Method is a reconciliation method.
+type Method string
+
+// Reconciliation methods.
+const (
+	// MethodBottomUp sums leaf forecasts up the tree.
+	MethodBottomUp Method = "bottom_up"
+
+	// MethodTopDown splits the root forecast down the tree in proportion to
+	// each node's base forecast among its siblings (forecast proportions).
+	MethodTopDown Method = "top_down"
+
+	// MethodMinT is minimum-trace reconciliation weighted by a loaded forecast
+	// error covariance matrix.
+	MethodMinT Method = "mint"
+
+	// MethodOLS is minimum-trace reconciliation with an identity covariance.
+	MethodOLS Method = "ols"
+)
+
+// ErrNoCovariance is returned when MinT is requested without a covariance matrix.
+var ErrNoCovariance = errors.New("mint reconciliation requires a covariance matrix")
+
+// ParseMethod validates a method name.
+func ParseMethod(name string) (Method, error) {
+	switch m := Method(name); m {
+	case MethodBottomUp, MethodTopDown, MethodMinT, MethodOLS:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown reconciliation method %q: must be bottom_up, top_down, mint or ols", name)
+}
+
+// Node is a forecast in a hierarchy. Forecast holds the base forecast on input
+// and the reconciled forecast after Reconcile.
+type Node struct {
+	ID       string
+	Forecast float64
+	Children []*Node
+}
+
+// Covariance is a base forecast error covariance over hierarchy nodes, either a
+// full matrix or its diagonal (WLS). Rows and columns follow IDs.
+type Covariance struct {
+	IDs      []string    `json:"ids"`
+	Matrix   [][]float64 `json:"matrix,omitempty"`
+	Diagonal []float64   `json:"diagonal,omitempty"`
+
+	index map[string]int
+}
+
+// LoadCovariance reads a covariance JSON file with "ids" and either "matrix" or "diagonal".
+func LoadCovariance(path string) (*Covariance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Covariance
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse covariance: %w", err)
+	}
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// NewDiagonalCovariance builds a diagonal covariance from per-node variances.
+func NewDiagonalCovariance(variances map[string]float64) (*Covariance, error) {
+	c := &Covariance{}
+	for id, v := range variances {
+		c.IDs = append(c.IDs, id)
+		c.Diagonal = append(c.Diagonal, v)
+	}
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// init validates dimensions and indexes IDs.
+func (c *Covariance) init() error {
+	n := len(c.IDs)
+	switch {
+	case c.Matrix != nil:
+		if len(c.Matrix) != n {
+			return fmt.Errorf("covariance matrix has %d rows for %d ids", len(c.Matrix), n)
+		}
+		for i, row := range c.Matrix {
+			if len(row) != n {
+				return fmt.Errorf("covariance row %d has %d columns for %d ids", i, len(row), n)
+			}
+		}
+	case c.Diagonal != nil:
+		if len(c.Diagonal) != n {
+			return fmt.Errorf("covariance diagonal has %d entries for %d ids", len(c.Diagonal), n)
+		}
+	default:
+		return errors.New("covariance needs a matrix or diagonal")
+	}
+
+	c.index = make(map[string]int, n)
+	for i, id := range c.IDs {
+		c.index[id] = i
+	}
+	return nil
+}
+
+// Size returns the number of nodes covered.
+func (c *Covariance) Size() int {
+	return len(c.IDs)
+}
+
+// Reconcile replaces each node's base forecast with a coherent forecast using the
+// given method. cov is only used by MethodMinT.
+func Reconcile(root *Node, method Method, cov *Covariance) error {
+	switch method {
+	case MethodBottomUp:
+		bottomUp(root)
+		return nil
+	case MethodTopDown:
+		topDown(root)
+		return nil
+	case MethodMinT:
+		if cov == nil {
+			return ErrNoCovariance
+		}
+		return minTrace(root, cov)
+	case MethodOLS:
+		return minTrace(root, nil)
+	}
+	return fmt.Errorf("unknown reconciliation method %q", method)
+}
+
+// bottomUp sets every parent to the sum of its children.
+func bottomUp(n *Node) float64 {
+	if len(n.Children) == 0 {
+		return n.Forecast
+	}
+	var sum float64
+	for _, c := range n.Children {
+		sum += bottomUp(c)
+	}
+	n.Forecast = sum
+	return sum
+}
+
+// topDown splits each parent's reconciled forecast among its children in
+// proportion to their base forecasts, or equally if those sum to zero.
+func topDown(n *Node) {
+	if len(n.Children) == 0 {
+		return
+	}
+	var sum float64
+	for _, c := range n.Children {
+		sum += c.Forecast
+	}
+	for _, c := range n.Children {
+		if sum != 0 {
+			c.Forecast = n.Forecast * c.Forecast / sum
+		} else {
+			c.Forecast = n.Forecast / float64(len(n.Children))
+		}
+		topDown(c)
+	}
+}
+
+// minTrace applies MinT in its projection form:
+//
+//	y~ = y^ - W C' (C W C')^-1 C y^
+//
+// where each row of the constraint matrix C states that an aggregate node equals
+// the sum of the leaves below it. Only a k x k system is solved, k being the number
+// of aggregate nodes, which is far smaller than the number of leaves. A nil cov
+// uses the identity (OLS).
+func minTrace(root *Node, cov *Covariance) error {
+	nodes, leaves := flatten(root)
+	n := len(nodes)
+
+	// Constraint rows: +1 on the aggregate node, -1 on each leaf below it
+	type term struct {
+		node int
+		coef float64
+	}
+	var constraints [][]term
+	pos := make(map[*Node]int, n)
+	for i, node := range nodes {
+		pos[node] = i
+	}
+	for i, node := range nodes {
+		if len(node.Children) == 0 {
+			continue
+		}
+		row := []term{{i, 1}}
+		for _, leaf := range leaves[node] {
+			row = append(row, term{pos[leaf], -1})
+		}
+		constraints = append(constraints, row)
+	}
+	k := len(constraints)
+	if k == 0 {
+		return nil
+	}
+
+	// Map nodes to covariance rows
+	covIdx := make([]int, n)
+	if cov != nil {
+		for i, node := range nodes {
+			j, ok := cov.index[node.ID]
+			if !ok {
+				return fmt.Errorf("covariance is missing node %q", node.ID)
+			}
+			covIdx[i] = j
+		}
+	}
+	weight := func(i, j int) float64 {
+		switch {
+		case cov == nil:
+			if i == j {
+				return 1
+			}
+			return 0
+		case cov.Matrix != nil:
+			return cov.Matrix[covIdx[i]][covIdx[j]]
+		case i == j:
+			return cov.Diagonal[covIdx[i]]
+		}
+		return 0
+	}
+	dense := cov != nil && cov.Matrix != nil
+
+	// WCt = W C' (n x k)
+	wct := make([][]float64, n)
+	for i := range wct {
+		wct[i] = make([]float64, k)
+	}
+	for v, row := range constraints {
+		for _, t := range row {
+			if dense {
+				for i := 0; i < n; i++ {
+					wct[i][v] += weight(i, t.node) * t.coef
+				}
+			} else {
+				wct[t.node][v] += weight(t.node, t.node) * t.coef
+			}
+		}
+	}
+
+	// M = C W C' (k x k) and r = C y^
+	m := make([][]float64, k)
+	r := make([]float64, k)
+	for u, row := range constraints {
+		m[u] = make([]float64, k)
+		for _, t := range row {
+			for v := 0; v < k; v++ {
+				m[u][v] += t.coef * wct[t.node][v]
+			}
+			r[u] += t.coef * nodes[t.node].Forecast
+		}
+	}
+
+	z, err := solve(m, r)
+	if err != nil {
+		return err
+	}
+
+	for i, node := range nodes {
+		var adj float64
+		for v := 0; v < k; v++ {
+			adj += wct[i][v] * z[v]
+		}
+		node.Forecast -= adj
+	}
+	return nil
+}
+
+// flatten lists nodes in pre-order with the leaves below each aggregate node.
+func flatten(root *Node) ([]*Node, map[*Node][]*Node) {
+	var nodes []*Node
+	leaves := make(map[*Node][]*Node)
+	var walk func(n *Node) []*Node
+	walk = func(n *Node) []*Node {
+		nodes = append(nodes, n)
+		if len(n.Children) == 0 {
+			return []*Node{n}
+		}
+		var below []*Node
+		for _, c := range n.Children {
+			below = append(below, walk(c)...)
+		}
+		leaves[n] = below
+		return below
+	}
+	walk(root)
+	return nodes, leaves
+}
+
+// solve solves a x = b by Gaussian elimination with partial pivoting.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, errors.New("reconciliation system is singular: check the covariance matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for j := col; j < n; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for j := row + 1; j < n; j++ {
+			sum -= a[row][j] * x[j]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+// Residual is the coherence error of an aggregate node.
+type Residual struct {
+	ID          string
+	Forecast    float64
+	ChildrenSum float64
+	Residual    float64 // Forecast - ChildrenSum
+	Relative    float64 // Residual / |Forecast|, or 0 when Forecast is 0
+	Coherent    bool
+}
+
+// Check walks the tree in pre-order and reports the residual of every aggregate
+// node against the sum of its children. A node is coherent when its absolute
+// residual is within tolerance times its absolute forecast (relative tolerance),
+// with a 1e-6 absolute floor for forecasts near zero.
+func Check(root *Node, tolerance float64) []Residual {
+	var residuals []Residual
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if len(n.Children) == 0 {
+			return
+		}
+		var sum float64
+		for _, c := range n.Children {
+			sum += c.Forecast
+		}
+		r := Residual{ID: n.ID, Forecast: n.Forecast, ChildrenSum: sum, Residual: n.Forecast - sum}
+		if n.Forecast != 0 {
+			r.Relative = r.Residual / math.Abs(n.Forecast)
+		}
+		r.Coherent = math.Abs(r.Residual) <= math.Max(tolerance*math.Abs(n.Forecast), 1e-6)
+		residuals = append(residuals, r)
+
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return residuals
+}
@@ -0,0 +1,149 @@
+package reconcile
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// newTree builds total -> {a, b} with the given base forecasts.
+func newTree(total, a, b float64) *Node {
+	return &Node{ID: "total", Forecast: total, Children: []*Node{
+		{ID: "a", Forecast: a},
+		{ID: "b", Forecast: b},
+	}}
+}
+
+func assertForecasts(t *testing.T, root *Node, total, a, b float64) {
+	t.Helper()
+	got := []float64{root.Forecast, root.Children[0].Forecast, root.Children[1].Forecast}
+	want := []float64{total, a, b}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("expected forecasts %v, got %v", want, got)
+			return
+		}
+	}
+}
+
+func TestBottomUp(t *testing.T) {
+	root := &Node{ID: "total", Forecast: 100, Children: []*Node{
+		{ID: "s1", Forecast: 50, Children: []*Node{{ID: "s1_a", Forecast: 3}, {ID: "s1_b", Forecast: 4}}},
+		{ID: "s2", Forecast: 50, Children: []*Node{{ID: "s2_a", Forecast: 5}}},
+	}}
+
+	if err := Reconcile(root, MethodBottomUp, nil); err != nil {
+		t.Fatal(err)
+	}
+	if root.Forecast != 12 || root.Children[0].Forecast != 7 || root.Children[1].Forecast != 5 {
+		t.Errorf("unexpected bottom-up result: total=%f s1=%f s2=%f", root.Forecast, root.Children[0].Forecast, root.Children[1].Forecast)
+	}
+}
+
+func TestTopDown(t *testing.T) {
+	root := newTree(16, 3, 5)
+	if err := Reconcile(root, MethodTopDown, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertForecasts(t, root, 16, 6, 10)
+
+	// Zero base forecasts split equally
+	root = newTree(10, 0, 0)
+	Reconcile(root, MethodTopDown, nil)
+	assertForecasts(t, root, 10, 5, 5)
+}
+
+func TestOLS(t *testing.T) {
+	// Closed form for total = a + b with y^ = (10, 3, 5): b~ = (11/3, 17/3)
+	root := newTree(10, 3, 5)
+	if err := Reconcile(root, MethodOLS, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertForecasts(t, root, 28.0/3, 11.0/3, 17.0/3)
+}
+
+func TestMinT(t *testing.T) {
+	t.Run("identity matrix matches OLS", func(t *testing.T) {
+		cov := &Covariance{
+			IDs:    []string{"b", "a", "total"},
+			Matrix: [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		}
+		if err := cov.init(); err != nil {
+			t.Fatal(err)
+		}
+		root := newTree(10, 3, 5)
+		if err := Reconcile(root, MethodMinT, cov); err != nil {
+			t.Fatal(err)
+		}
+		assertForecasts(t, root, 28.0/3, 11.0/3, 17.0/3)
+	})
+
+	t.Run("noisy total defers to leaves", func(t *testing.T) {
+		cov, err := NewDiagonalCovariance(map[string]float64{"total": 1e9, "a": 1, "b": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := newTree(10, 3, 5)
+		if err := Reconcile(root, MethodMinT, cov); err != nil {
+			t.Fatal(err)
+		}
+		assertForecasts(t, root, 8, 3, 5)
+	})
+
+	t.Run("reconciled forecasts are coherent", func(t *testing.T) {
+		cov, _ := NewDiagonalCovariance(map[string]float64{"total": 4, "a": 1, "b": 2})
+		root := newTree(20, 3, 5)
+		Reconcile(root, MethodMinT, cov)
+		if sum := root.Children[0].Forecast + root.Children[1].Forecast; math.Abs(root.Forecast-sum) > 1e-9 {
+			t.Errorf("total %f != sum of children %f", root.Forecast, sum)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := Reconcile(newTree(1, 1, 1), MethodMinT, nil); !errors.Is(err, ErrNoCovariance) {
+			t.Errorf("expected ErrNoCovariance, got %v", err)
+		}
+		cov, _ := NewDiagonalCovariance(map[string]float64{"total": 1, "a": 1})
+		if err := Reconcile(newTree(1, 1, 1), MethodMinT, cov); err == nil {
+			t.Error("expected error for node missing from covariance")
+		}
+	})
+}
+
+func TestParseMethod(t *testing.T) {
+	if m, err := ParseMethod("mint"); err != nil || m != MethodMinT {
+		t.Errorf("expected mint, got %q (%v)", m, err)
+	}
+	if _, err := ParseMethod("middle_out"); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	root := &Node{ID: "total", Forecast: 100, Children: []*Node{
+		{ID: "a", Forecast: 60, Children: []*Node{{ID: "a1", Forecast: 30}, {ID: "a2", Forecast: 30}}},
+		{ID: "b", Forecast: 40.02},
+	}}
+
+	residuals := Check(root, 0.001)
+	if len(residuals) != 2 || residuals[0].ID != "total" || residuals[1].ID != "a" {
+		t.Fatalf("expected residuals for total and a, got %+v", residuals)
+	}
+
+	// 100 vs 100.02 is within 0.1%
+	total := residuals[0]
+	if math.Abs(total.Residual+0.02) > 1e-9 || !total.Coherent {
+		t.Errorf("expected coherent total with residual -0.02, got %+v", total)
+	}
+
+	if r := Check(root, 0.0001)[0]; r.Coherent {
+		t.Errorf("expected incoherent total at 0.01%% tolerance, got %+v", r)
+	}
+
+	bottomUp(root)
+	for _, r := range Check(root, 0) {
+		if !r.Coherent {
+			t.Errorf("expected bottom-up tree to be coherent, got %+v", r)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package refresh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday 2017-08-16 10:17 UTC
+	from := time.Date(2017, 8, 16, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2017, 8, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2017, 8, 17, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 8, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2017, 8, 16, 11, 0, 0, 0, time.UTC)},
+		{"30 6 * * 1-5", time.Date(2017, 8, 17, 6, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2017, 8, 20, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 1st of the month or any Friday
+		{"0 0 1 * 5", time.Date(2017, 8, 18, 0, 0, 0, 0, time.UTC)},
+		{"5,45 10 * * *", time.Date(2017, 8, 16, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: expected %s, got %s", tt.spec, tt.want, got)
+		}
+	}
+}
+
+func TestScheduleNextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	s, err := ParseSchedule("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Next(time.Date(2017, 8, 16, 10, 0, 0, 0, time.UTC).In(loc))
+	want := time.Date(2017, 8, 17, 7, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("expected 02:00 UTC-5 (%s), got %s", want, got.UTC())
+	}
+}
+
+func TestScheduleNeverMatches(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(time.Date(2017, 8, 16, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("expected zero time for February 30th, got %s", next)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@yearly",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
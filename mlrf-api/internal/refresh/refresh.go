@@ -0,0 +1,166 @@
+// Package refresh reloads the feature store and warms the prediction cache on a cron
+// schedule, so nightly pipeline outputs are served without admin calls.
+package refresh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds scheduled refresh configuration.
+type Config struct {
+	Cron     string         // Five-field cron expression; empty disables scheduled refresh
+	Location *time.Location // Time zone the expression is evaluated in
+	Warm     bool           // Warm the prediction cache after each reload
+	Timeout  time.Duration  // Timeout of one run
+}
+
+// DefaultConfig returns the default scheduled refresh configuration, without a
+// schedule.
+func DefaultConfig() Config {
+	return Config{
+		Location: time.UTC,
+		Warm:     true,
+		Timeout:  30 * time.Minute,
+	}
+}
+
+// Job is one refresh run. It returns the number of predictions warmed.
+type Job func(ctx context.Context) (warmed int, err error)
+
+// Status reports the schedule and the outcome of the last run.
+type Status struct {
+	Schedule     string     `json:"schedule"`
+	NextRun      time.Time  `json:"next_run"`
+	Running      bool       `json:"running"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastStatus   string     `json:"last_status,omitempty"` // ok or failed
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastWarmed   int        `json:"last_warmed,omitempty"` // Predictions cached by the last run
+}
+
+// Scheduler runs a refresh job at the times of a cron schedule. Safe for concurrent use.
+type Scheduler struct {
+	cfg      Config
+	schedule *Schedule
+	job      Job
+	now      func() time.Time
+
+	mu     sync.RWMutex
+	status Status
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler for cfg.Cron. Call Start to begin running job.
+func NewScheduler(cfg Config, job Job) (*Scheduler, error) {
+	schedule, err := ParseSchedule(cfg.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		cfg:      cfg,
+		schedule: schedule,
+		job:      job,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	s.status = Status{Schedule: cfg.Cron, NextRun: s.next()}
+	return s, nil
+}
+
+// next returns the next scheduled run after now.
+func (s *Scheduler) next() time.Time {
+	return s.schedule.Next(s.now().In(s.cfg.Location))
+}
+
+// Start runs the job at each scheduled time until Close.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Close stops the schedule and waits for an in-flight run to finish.
+func (s *Scheduler) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Status returns the schedule and last-run outcome.
+func (s *Scheduler) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	for {
+		next := s.Status().NextRun
+		if next.IsZero() {
+			log.Warn().Str("schedule", s.cfg.Cron).Msg("Feature refresh schedule never matches, stopping")
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.Run(s.ctx)
+		}
+	}
+}
+
+// Run executes the job once and records its outcome, as a scheduled run would.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.status.Running = true
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	start := s.now()
+	warmed, err := s.job(ctx)
+	duration := s.now().Sub(start)
+
+	s.mu.Lock()
+	s.status.Running = false
+	s.status.LastRun = &start
+	s.status.LastDuration = duration.Round(time.Millisecond).String()
+	s.status.LastWarmed = warmed
+	s.status.NextRun = s.next()
+	if err != nil {
+		s.status.LastStatus = "failed"
+		s.status.LastError = err.Error()
+	} else {
+		s.status.LastStatus = "ok"
+		s.status.LastError = ""
+	}
+	result, next := s.status.LastStatus, s.status.NextRun
+	s.mu.Unlock()
+
+	metrics.RecordFeatureRefresh(result, duration.Seconds(), start)
+	if err != nil {
+		log.Error().Err(err).Dur("duration", duration).Time("next_run", next).Msg("Scheduled feature refresh failed")
+		return
+	}
+	log.Info().
+		Str("schedule", s.cfg.Cron).
+		Int("warmed", warmed).
+		Dur("duration", duration).
+		Time("next_run", next).
+		Msg("Scheduled feature refresh completed")
+}
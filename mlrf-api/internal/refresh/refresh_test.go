@@ -0,0 +1,67 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunRecordsStatus(t *testing.T) {
+	var jobErr error
+	s, err := NewScheduler(Config{Cron: "0 2 * * *", Timeout: time.Minute}, func(ctx context.Context) (int, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the run to have a deadline")
+		}
+		return 240, jobErr
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2017, 8, 16, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	s.Run(context.Background())
+	status := s.Status()
+	if status.LastStatus != "ok" || status.LastWarmed != 240 || status.LastError != "" {
+		t.Errorf("unexpected status after a successful run: %+v", status)
+	}
+	if status.LastRun == nil || !status.LastRun.Equal(clock) {
+		t.Errorf("expected last run %s, got %v", clock, status.LastRun)
+	}
+	if want := time.Date(2017, 8, 17, 2, 0, 0, 0, time.UTC); !status.NextRun.Equal(want) {
+		t.Errorf("expected next run %s, got %s", want, status.NextRun)
+	}
+
+	jobErr = errors.New("reload failed: quality check failed")
+	s.Run(context.Background())
+	status = s.Status()
+	if status.LastStatus != "failed" || status.LastError != jobErr.Error() {
+		t.Errorf("unexpected status after a failed run: %+v", status)
+	}
+	if status.Running {
+		t.Error("expected the run to be finished")
+	}
+}
+
+func TestNewSchedulerInvalid(t *testing.T) {
+	if _, err := NewScheduler(Config{Cron: "every night"}, nil); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Cron != "" {
+		t.Errorf("expected no schedule by default, got %q", cfg.Cron)
+	}
+	if cfg.Location != time.UTC {
+		t.Errorf("expected UTC, got %s", cfg.Location)
+	}
+	if !cfg.Warm {
+		t.Error("expected warming to be enabled")
+	}
+	if cfg.Timeout != 30*time.Minute {
+		t.Errorf("expected a 30m timeout, got %s", cfg.Timeout)
+	}
+}
@@ -0,0 +1,289 @@
+// Package remote fetches model and feature files from object storage (s3:// and
+// gs:// URLs) into a local cache, so deployments need no baked-in data volume.
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds object store access and local cache configuration.
+type Config struct {
+	CacheDir string        // Directory holding downloaded files
+	Timeout  time.Duration // Timeout of a whole download
+
+	S3Region        string // Region used for S3 URLs and request signing
+	S3Endpoint      string // Custom S3-compatible endpoint (e.g. MinIO), addressed path-style
+	AWSAccessKeyID  string // Credentials for signed S3 requests; unset sends anonymous requests
+	AWSSecretKey    string
+	AWSSessionToken string
+
+	GCSEndpoint string // Google Cloud Storage endpoint
+	GCSToken    string // OAuth2 access token for GCS; unset sends anonymous requests
+}
+
+// DefaultConfig returns the default remote loading configuration, without
+// credentials.
+func DefaultConfig() Config {
+	return Config{
+		CacheDir:    filepath.Join(os.TempDir(), "mlrf-cache"),
+		Timeout:     10 * time.Minute,
+		S3Region:    "us-east-1",
+		GCSEndpoint: "https://storage.googleapis.com",
+	}
+}
+
+// Source is the location of a model or feature file with its expected checksum.
+type Source struct {
+	URL    string // Local path, s3://bucket/key or gs://bucket/object
+	SHA256 string // Expected hex SHA-256; empty uses the object's ".sha256" sidecar if present
+}
+
+// IsRemote reports whether path is an object store URL.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "s3://") || strings.HasPrefix(path, "gs://")
+}
+
+// Fetcher downloads object store files into the local cache.
+type Fetcher struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewFetcher creates a fetcher.
+func NewFetcher(cfg Config) *Fetcher {
+	return &Fetcher{
+		cfg:    cfg,
+		client: &http.Client{},
+		now:    time.Now,
+	}
+}
+
+// cacheMeta records what a cached file was downloaded from.
+type cacheMeta struct {
+	URL       string    `json:"url"`
+	ETag      string    `json:"etag,omitempty"`
+	SHA256    string    `json:"sha256"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Fetch returns a local path holding the file of src. Local paths are returned
+// unchanged. Remote objects are streamed into the cache directory while their SHA-256
+// is computed, verified and only then moved into place. A cached copy is revalidated
+// with its ETag and reused when unchanged, or when the object store is unreachable.
+func (f *Fetcher) Fetch(ctx context.Context, src Source) (string, error) {
+	if !IsRemote(src.URL) {
+		return src.URL, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Timeout)
+	defer cancel()
+
+	if err := os.MkdirAll(f.cfg.CacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	key := sha256.Sum256([]byte(src.URL))
+	local := filepath.Join(f.cfg.CacheDir, hex.EncodeToString(key[:8])+"-"+path.Base(src.URL))
+	meta, cached := f.readMeta(local)
+
+	expected := strings.ToLower(src.SHA256)
+	if expected == "" {
+		sidecar, err := f.sidecarChecksum(ctx, src.URL)
+		if err != nil && !cached {
+			metrics.RecordRemoteFetch("failed")
+			return "", err
+		}
+		expected = sidecar
+	}
+	if cached && expected != "" && meta.SHA256 != expected {
+		cached = false // The object was replaced
+	}
+
+	req, err := f.newRequest(ctx, src.URL)
+	if err != nil {
+		return "", err
+	}
+	if cached && meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if cached {
+			log.Warn().Err(err).Str("url", src.URL).Str("path", local).Msg("Object store unreachable, using cached copy")
+			metrics.RecordRemoteFetch("cached")
+			return local, nil
+		}
+		metrics.RecordRemoteFetch("failed")
+		return "", fmt.Errorf("failed to fetch %s: %w", src.URL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		metrics.RecordRemoteFetch("not_modified")
+		return local, nil
+	case resp.StatusCode != http.StatusOK:
+		metrics.RecordRemoteFetch("failed")
+		return "", fmt.Errorf("failed to fetch %s: %s", src.URL, resp.Status)
+	}
+
+	start := f.now()
+	sum, size, err := f.download(resp.Body, local, expected)
+	if err != nil {
+		metrics.RecordRemoteFetch("failed")
+		return "", fmt.Errorf("failed to fetch %s: %w", src.URL, err)
+	}
+	f.writeMeta(local, cacheMeta{URL: src.URL, ETag: resp.Header.Get("ETag"), SHA256: sum, FetchedAt: f.now()})
+	metrics.RecordRemoteFetch("downloaded")
+
+	event := log.Info()
+	if expected == "" {
+		event = log.Warn()
+	}
+	event.
+		Str("url", src.URL).
+		Str("path", local).
+		Int64("size_mb", size/(1024*1024)).
+		Str("sha256", sum).
+		Bool("verified", expected != "").
+		Dur("duration", time.Since(start)).
+		Msg("Remote file downloaded")
+	return local, nil
+}
+
+// download streams body into a temporary file next to local, verifies its checksum
+// and renames it into place, returning the checksum and size.
+func (f *Fetcher) download(body io.Reader, local, expected string) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(local), filepath.Base(local)+".*.part")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("download interrupted: %w", err)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if expected != "" && sum != expected {
+		return "", 0, fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", expected, sum)
+	}
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return "", 0, fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return sum, size, nil
+}
+
+// sidecarChecksum reads the hex SHA-256 from the "<object>.sha256" object, as written
+// by sha256sum. A missing sidecar returns an empty checksum.
+func (f *Fetcher) sidecarChecksum(ctx context.Context, rawURL string) (string, error) {
+	req, err := f.newRequest(ctx, rawURL+".sha256")
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum of %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden: // S3 answers 403 for missing keys without ListBucket
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed to fetch checksum of %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of %s: %w", rawURL, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum file for %s", rawURL)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// newRequest builds an authenticated GET request for an s3:// or gs:// URL.
+func (f *Fetcher) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid object URL %q: expected scheme://bucket/key", rawURL)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, f.cfg.S3Region, escapePath(key))
+		if f.cfg.S3Endpoint != "" {
+			endpoint = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(f.cfg.S3Endpoint, "/"), bucket, escapePath(key))
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if f.cfg.AWSAccessKeyID != "" {
+			signV4(req, f.cfg.AWSAccessKeyID, f.cfg.AWSSecretKey, f.cfg.AWSSessionToken, f.cfg.S3Region, f.now())
+		}
+		return req, nil
+	case "gs":
+		endpoint := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(f.cfg.GCSEndpoint, "/"), bucket, escapePath(key))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if f.cfg.GCSToken != "" {
+			req.Header.Set("Authorization", "Bearer "+f.cfg.GCSToken)
+		}
+		return req, nil
+	}
+	return nil, fmt.Errorf("unsupported object store scheme %q", u.Scheme)
+}
+
+// readMeta returns the metadata of a cached file, if the file and its metadata exist.
+func (f *Fetcher) readMeta(local string) (cacheMeta, bool) {
+	var meta cacheMeta
+	data, err := os.ReadFile(local + ".json")
+	if err != nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false
+	}
+	if _, err := os.Stat(local); err != nil {
+		return meta, false
+	}
+	return meta, true
+}
+
+// writeMeta records the metadata of a downloaded file. Failures only cost a
+// revalidation, so they are logged rather than returned.
+func (f *Fetcher) writeMeta(local string, meta cacheMeta) {
+	data, err := json.Marshal(meta)
+	if err == nil {
+		err = os.WriteFile(local+".json", data, 0o644)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", local).Msg("Failed to write cache metadata")
+	}
+}
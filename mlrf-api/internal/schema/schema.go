@@ -0,0 +1,173 @@
+// Package schema defines the model's input features in order. The feature store,
+// inference engines and handlers all index feature vectors through it, so the column
+// order and feature count are defined once.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// NumFeatures is the number of features expected by the model.
+const NumFeatures = 27
+
+// Column is one model input feature.
+type Column struct {
+	Name     string  `json:"name"`  // Feature matrix column name
+	Index    int     `json:"index"` // Position in the feature vector
+	Group    string  `json:"group"` // date, external, store, lag, rolling or categorical
+	Min      float64 `json:"-"`     // Plausible range, checked by the feature quality report
+	Max      float64 `json:"-"`
+	Optional bool    `json:"optional"` // The column may be missing or null; read as zero
+}
+
+// FeatureSchema is an ordered set of feature columns.
+type FeatureSchema struct {
+	columns []Column
+	index   map[string]int
+}
+
+// New builds a schema from columns in feature vector order, numbering them.
+func New(columns ...Column) *FeatureSchema {
+	s := &FeatureSchema{
+		columns: make([]Column, len(columns)),
+		index:   make(map[string]int, len(columns)),
+	}
+	for i, c := range columns {
+		c.Index = i
+		s.columns[i] = c
+		s.index[c.Name] = i
+	}
+	return s
+}
+
+// inf is the upper bound of unbounded columns.
+var inf = math.Inf(1)
+
+// Features is the schema the model was trained on. It must match the order in
+// mlrf-ml/src/mlrf_ml/models/lightgbm_model.py FEATURE_COLS + CATEGORICAL_COLS,
+// whose family and type columns the feature matrix stores as family_encoded and
+// type_encoded. Every feature is a count, calendar field, price or sales statistic,
+// so none may be negative.
+var Features = New(
+	// Date features
+	Column{Name: "year", Group: "date", Max: inf},
+	Column{Name: "month", Group: "date", Max: 12},
+	Column{Name: "day", Group: "date", Max: 31},
+	Column{Name: "dayofweek", Group: "date", Max: 6},
+	Column{Name: "dayofyear", Group: "date", Max: 366},
+	Column{Name: "is_mid_month", Group: "date", Max: 1},
+	Column{Name: "is_leap_year", Group: "date", Max: 1},
+	// External features
+	Column{Name: "oil_price", Group: "external", Max: inf},
+	Column{Name: "is_holiday", Group: "external", Max: 1},
+	Column{Name: "onpromotion", Group: "external", Max: inf},
+	Column{Name: "promo_rolling_7", Group: "external", Max: inf},
+	// Store metadata
+	Column{Name: "cluster", Group: "store", Max: inf},
+	// Lag features
+	Column{Name: "sales_lag_1", Group: "lag", Max: inf},
+	Column{Name: "sales_lag_7", Group: "lag", Max: inf},
+	Column{Name: "sales_lag_14", Group: "lag", Max: inf},
+	Column{Name: "sales_lag_28", Group: "lag", Max: inf},
+	Column{Name: "sales_lag_90", Group: "lag", Max: inf},
+	// Rolling features
+	Column{Name: "sales_rolling_mean_7", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_mean_14", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_mean_28", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_mean_90", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_std_7", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_std_14", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_std_28", Group: "rolling", Max: inf},
+	Column{Name: "sales_rolling_std_90", Group: "rolling", Max: inf},
+	// Categorical features (integer-encoded)
+	Column{Name: "family_encoded", Group: "categorical", Max: inf, Optional: true},
+	Column{Name: "type_encoded", Group: "categorical", Max: inf, Optional: true},
+)
+
+// Len returns the number of features.
+func (s *FeatureSchema) Len() int {
+	return len(s.columns)
+}
+
+// Columns returns the columns in feature vector order.
+func (s *FeatureSchema) Columns() []Column {
+	out := make([]Column, len(s.columns))
+	copy(out, s.columns)
+	return out
+}
+
+// Column returns the column at feature vector position i.
+func (s *FeatureSchema) Column(i int) Column {
+	return s.columns[i]
+}
+
+// Names returns the column names in feature vector order.
+func (s *FeatureSchema) Names() []string {
+	names := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Index returns the feature vector position of a column.
+func (s *FeatureSchema) Index(name string) (int, bool) {
+	i, ok := s.index[name]
+	return i, ok
+}
+
+// SourceColumn describes a column of a feature source (parquet file or table).
+type SourceColumn struct {
+	Name    string
+	Numeric bool
+}
+
+// MismatchError reports a feature source that doesn't provide the schema's columns.
+type MismatchError struct {
+	Source    string
+	Missing   []string // Required columns absent from the source
+	WrongType []string // Columns present with a non-numeric type
+}
+
+func (e *MismatchError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing columns "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.WrongType) > 0 {
+		problems = append(problems, "non-numeric columns "+strings.Join(e.WrongType, ", "))
+	}
+	return fmt.Sprintf("feature schema mismatch in %s: %s", e.Source, strings.Join(problems, "; "))
+}
+
+// Validate checks that a source provides every required feature column with a
+// numeric type, plus the key columns with any type, returning a *MismatchError naming
+// every problem. Extra columns are ignored, and a missing optional column reads as zero.
+func (s *FeatureSchema) Validate(source string, columns []SourceColumn, keys ...string) error {
+	available := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		available[c.Name] = c.Numeric
+	}
+
+	mismatch := &MismatchError{Source: source}
+	for _, key := range keys {
+		if _, ok := available[key]; !ok {
+			mismatch.Missing = append(mismatch.Missing, key)
+		}
+	}
+	for _, c := range s.columns {
+		numeric, ok := available[c.Name]
+		switch {
+		case !ok && !c.Optional:
+			mismatch.Missing = append(mismatch.Missing, c.Name)
+		case ok && !numeric:
+			mismatch.WrongType = append(mismatch.WrongType, c.Name)
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.WrongType) > 0 {
+		return mismatch
+	}
+	return nil
+}
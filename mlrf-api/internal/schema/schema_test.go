@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	if Features.Len() != NumFeatures {
+		t.Fatalf("expected %d features, got %d", NumFeatures, Features.Len())
+	}
+	for i, c := range Features.Columns() {
+		if c.Index != i {
+			t.Errorf("%s: expected index %d, got %d", c.Name, i, c.Index)
+		}
+		if j, ok := Features.Index(c.Name); !ok || j != i {
+			t.Errorf("%s: Index returned %d, %v", c.Name, j, ok)
+		}
+	}
+	for name, want := range map[string]int{"year": 0, "oil_price": 7, "cluster": 11, "sales_lag_7": 13, "type_encoded": 26} {
+		if i, _ := Features.Index(name); i != want {
+			t.Errorf("expected %s at %d, got %d", name, want, i)
+		}
+	}
+	if _, ok := Features.Index("transactions"); ok {
+		t.Error("expected no index for an unknown column")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var columns []SourceColumn
+	for _, name := range append([]string{"store_nbr", "family", "date"}, Features.Names()...) {
+		columns = append(columns, SourceColumn{Name: name, Numeric: name != "family" && name != "date"})
+	}
+	if err := Features.Validate("features.parquet", columns, "store_nbr", "family", "date"); err != nil {
+		t.Fatalf("expected a complete source to validate, got %v", err)
+	}
+
+	// Optional columns may be absent, required ones may not
+	var partial []SourceColumn
+	for _, c := range columns {
+		switch c.Name {
+		case "store_nbr", "family_encoded", "type_encoded", "sales_lag_90":
+		case "oil_price":
+			partial = append(partial, SourceColumn{Name: c.Name})
+		default:
+			partial = append(partial, c)
+		}
+	}
+	err := Features.Validate("features.parquet", partial, "store_nbr", "family", "date")
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a *MismatchError, got %v", err)
+	}
+	if !slices.Equal(mismatch.Missing, []string{"store_nbr", "sales_lag_90"}) {
+		t.Errorf("unexpected missing columns %v", mismatch.Missing)
+	}
+	if !slices.Equal(mismatch.WrongType, []string{"oil_price"}) {
+		t.Errorf("unexpected non-numeric columns %v", mismatch.WrongType)
+	}
+	want := "feature schema mismatch in features.parquet: missing columns store_nbr, sales_lag_90; non-numeric columns oil_price"
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
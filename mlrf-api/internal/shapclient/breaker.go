@@ -0,0 +1,170 @@
+package shapclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// BreakerConfig holds circuit breaker configuration.
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the breaker; 0 disables it
+	OpenDuration     time.Duration // How long the breaker stays open before probing the service
+	HalfOpenProbes   int           // Trial calls let through while half-open; as many successes close it
+}
+
+// DefaultBreakerConfig returns the default circuit breaker configuration.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// State is the state of a circuit breaker.
+type State int
+
+// Breaker states. The values are exported as the mlrf_shap_circuit_state gauge.
+const (
+	StateClosed   State = iota // Calls go through
+	StateHalfOpen              // A limited number of probe calls go through
+	StateOpen                  // Calls fail fast
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitOpenError is returned instead of calling the service while the breaker is open.
+type CircuitOpenError struct {
+	RetryAfter time.Duration // Until the breaker lets a probe through
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("SHAP circuit breaker open, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Breaker is a circuit breaker. After FailureThreshold consecutive failures it opens
+// and rejects calls for OpenDuration, then half-opens and lets HalfOpenProbes calls
+// through: if they all succeed it closes, if one fails it opens again.
+// Thread-safe.
+type Breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the breaker last opened
+	probes    int       // Probe calls started while half-open
+	successes int       // Probe calls that succeeded while half-open
+}
+
+// NewBreaker creates a closed circuit breaker.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	metrics.SetShapCircuitState(int(StateClosed))
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// State returns the current state, moving from open to half-open once OpenDuration
+// has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow reports whether a call may go through. When it may not, the returned error
+// says how long until the breaker lets a probe through. Every allowed call must be
+// followed by Record.
+func (b *Breaker) Allow() error {
+	if b.cfg.FailureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return &CircuitOpenError{RetryAfter: b.openedAt.Add(b.cfg.OpenDuration).Sub(b.now())}
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			// Probes are in flight; their outcome decides the state within the call timeout
+			return &CircuitOpenError{RetryAfter: time.Second}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record records the outcome of a call let through by Allow.
+func (b *Breaker) Record(success bool) {
+	if b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenProbes {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// advance half-opens an open breaker whose OpenDuration has passed. Callers hold mu.
+func (b *Breaker) advance() {
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.cfg.OpenDuration)) {
+		b.setState(StateHalfOpen)
+	}
+}
+
+// setState moves to state, resetting its counters. Callers hold mu.
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	metrics.SetShapCircuitState(int(state))
+
+	event := log.Info()
+	if state == StateOpen {
+		event = log.Warn().Dur("open_duration", b.cfg.OpenDuration)
+	}
+	event.Str("state", state.String()).Msg("SHAP circuit breaker state changed")
+}
@@ -0,0 +1,127 @@
+package shapclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(BreakerConfig{FailureThreshold: 3, OpenDuration: 30 * time.Second, HalfOpenProbes: 2})
+	b.now = func() time.Time { return now }
+
+	call := func(success bool) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		b.Record(success)
+		return nil
+	}
+
+	// A success resets the count of consecutive failures
+	call(false)
+	call(false)
+	call(true)
+	call(false)
+	call(false)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after non-consecutive failures, got %s", b.State())
+	}
+	call(false)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after 3 consecutive failures, got %s", b.State())
+	}
+
+	now = now.Add(10 * time.Second)
+	var openErr *CircuitOpenError
+	if err := b.Allow(); !errors.As(err, &openErr) || openErr.RetryAfter != 20*time.Second {
+		t.Fatalf("expected a circuit open error retrying in 20s, got %v", err)
+	}
+
+	// Half-open: a failed probe opens the breaker again
+	now = now.Add(20 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open after the open duration, got %s", b.State())
+	}
+	if err := call(false); err != nil {
+		t.Fatalf("expected a probe to be allowed, got %v", err)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after a failed probe, got %s", b.State())
+	}
+
+	// Only HalfOpenProbes calls go through, and their success closes the breaker
+	now = now.Add(30 * time.Second)
+	if b.Allow() != nil || b.Allow() != nil {
+		t.Fatal("expected two probes to be allowed")
+	}
+	if err := b.Allow(); !errors.As(err, &openErr) {
+		t.Fatalf("expected a third call to be rejected while probes are in flight, got %v", err)
+	}
+	b.Record(true)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open until every probe succeeded, got %s", b.State())
+	}
+	b.Record(true)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after successful probes, got %s", b.State())
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := NewBreaker(BreakerConfig{})
+	for i := 0; i < 10; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("expected a disabled breaker to allow every call, got %v", err)
+		}
+		b.Record(false)
+	}
+}
+
+func TestExplainWithBreaker(t *testing.T) {
+	var calls, status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(`{"error":"boom"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	client.SetBreaker(NewBreaker(BreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute}))
+	ctx := context.Background()
+
+	// Requests the service rejects as invalid don't open the breaker
+	status.Store(http.StatusBadRequest)
+	for i := 0; i < 3; i++ {
+		client.Explain(ctx, 1, "GROCERY I", "2024-01-15", nil)
+	}
+	if client.Breaker().State() != StateClosed {
+		t.Fatalf("expected closed after client errors, got %s", client.Breaker().State())
+	}
+
+	status.Store(http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		if _, err := client.Explain(ctx, 1, "GROCERY I", "2024-01-15", nil); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+
+	_, err := client.Explain(ctx, 1, "GROCERY I", "2024-01-15", nil)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("expected a circuit open error, got %v", err)
+	}
+	if calls.Load() != 5 {
+		t.Errorf("expected the open breaker to skip the service, got %d calls", calls.Load())
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/mlrf/mlrf-api/internal/tracing"
 	"github.com/rs/zerolog/log"
 )
 
@@ -49,6 +51,9 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	timeout    time.Duration
+	breaker    *Breaker // Optional; nil calls the service unconditionally
+	retry      RetryConfig
+	latency    *latencyWindow // Recent latencies for the hedging delay; nil until SetRetry
 }
 
 // NewClient creates a new SHAP client connected to the given address.
@@ -79,9 +84,28 @@ func NewClient(addr string, timeout time.Duration) (*Client, error) {
 	return client, nil
 }
 
+// SetBreaker sets the circuit breaker guarding Explain. While it is open, Explain
+// returns a *CircuitOpenError without calling the service.
+func (c *Client) SetBreaker(b *Breaker) {
+	c.breaker = b
+}
+
+// Breaker returns the circuit breaker guarding Explain, or nil.
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
 // Explain computes SHAP values for a prediction.
 // This calls the Python SHAP service for REAL computation - no mocks.
-func (c *Client) Explain(ctx context.Context, storeNbr int, family, date string, features []float32) (*ExplainResponse, error) {
+// Transient failures are retried and slow requests hedged as configured by SetRetry.
+func (c *Client) Explain(ctx context.Context, storeNbr int, family, date string, features []float32) (resp *ExplainResponse, err error) {
+	ctx, span := tracing.Start(ctx, "shap.explain",
+		tracing.AttrStoreNbr.Int(storeNbr),
+		tracing.AttrFamily.String(family),
+		tracing.AttrDate.String(date),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	req := ExplainRequest{
 		StoreNbr: storeNbr,
 		Family:   family,
@@ -94,6 +118,43 @@ func (c *Client) Explain(ctx context.Context, storeNbr int, family, date string,
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	if c.breaker == nil {
+		return c.explainWithRetry(ctx, body)
+	}
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err = c.explainWithRetry(ctx, body)
+	// A caller that gave up says nothing about the service; anything else is its outcome
+	if err == nil || !errors.Is(ctx.Err(), context.Canceled) {
+		c.breaker.Record(!isServiceFailure(err))
+	}
+	return resp, err
+}
+
+// statusError is an error response from the service. Requests it rejected as
+// invalid (4xx) don't count as failures of the service.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("SHAP service error (status %d): %s", e.status, e.msg)
+}
+
+// isServiceFailure reports whether err is a failure of the service (an error,
+// timeout or 5xx response) rather than of the request.
+func isServiceFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *statusError
+	return !errors.As(err, &se) || se.status >= http.StatusInternalServerError
+}
+
+// explain makes one call to the /explain endpoint of the service.
+func (c *Client) explain(ctx context.Context, body []byte) (*ExplainResponse, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/explain", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -116,7 +177,7 @@ func (c *Client) Explain(ctx context.Context, storeNbr int, family, date string,
 			Error string `json:"error"`
 		}
 		json.Unmarshal(respBody, &errResp)
-		return nil, fmt.Errorf("SHAP service error (status %d): %s", resp.StatusCode, errResp.Error)
+		return nil, &statusError{status: resp.StatusCode, msg: errResp.Error}
 	}
 
 	var result ExplainResponse
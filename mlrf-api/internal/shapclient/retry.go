@@ -0,0 +1,187 @@
+package shapclient
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+)
+
+// RetryConfig holds retry and hedging configuration.
+type RetryConfig struct {
+	MaxRetries int           // Retries after a transient failure (error, timeout or 5xx); 0 disables retries
+	BaseDelay  time.Duration // Backoff before the first retry, doubled for each further retry
+	MaxDelay   time.Duration // Longest backoff between retries
+	Hedge      bool          // Send a second request when the first is slower than the P95 latency
+}
+
+// DefaultRetryConfig returns the default retry configuration, without hedging.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 2,
+		BaseDelay:  50 * time.Millisecond,
+		MaxDelay:   time.Second,
+	}
+}
+
+// SetRetry sets how Explain retries transient failures and hedges slow requests.
+func (c *Client) SetRetry(cfg RetryConfig) {
+	c.retry = cfg
+	c.latency = newLatencyWindow(latencyWindowSize)
+}
+
+// explainWithRetry calls the service, retrying transient failures with exponential
+// backoff and jitter until MaxRetries is exhausted or ctx is done.
+func (c *Client) explainWithRetry(ctx context.Context, body []byte) (*ExplainResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.explainHedged(ctx, body)
+		if err == nil || !isServiceFailure(err) || attempt >= c.retry.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+		metrics.RecordShapExtraRequest("retry")
+	}
+}
+
+// backoff returns the delay before retry attempt+1: BaseDelay doubled per attempt,
+// capped at MaxDelay, with random jitter of up to half of it so retries from
+// concurrent requests spread out.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay << attempt
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// attemptResult is the outcome of one request of a hedged call.
+type attemptResult struct {
+	resp *ExplainResponse
+	err  error
+}
+
+// explainHedged calls the service once or, with hedging enabled, sends a second
+// request when the first hasn't answered within the P95 latency. The first success
+// wins and the other request is cancelled.
+func (c *Client) explainHedged(ctx context.Context, body []byte) (*ExplainResponse, error) {
+	delay, ok := c.hedgeDelay()
+	if !ok {
+		return c.timedExplain(ctx, body)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult, 2)
+	send := func() {
+		resp, err := c.timedExplain(ctx, body)
+		results <- attemptResult{resp, err}
+	}
+	go send()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending := 1
+	var last error
+	for {
+		select {
+		case <-timer.C:
+			metrics.RecordShapExtraRequest("hedge")
+			pending++
+			go send()
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.resp, nil
+			}
+			last = res.err
+			if pending == 0 {
+				// The first request failed before the hedge was sent; leave it to retries
+				return nil, last
+			}
+		}
+	}
+}
+
+// hedgeDelay returns how long to wait before hedging, and false when hedging is
+// disabled or too few latencies have been observed.
+func (c *Client) hedgeDelay() (time.Duration, bool) {
+	if !c.retry.Hedge || c.latency == nil {
+		return 0, false
+	}
+	return c.latency.quantile(0.95)
+}
+
+// timedExplain calls the service once and records the latency of successful calls.
+func (c *Client) timedExplain(ctx context.Context, body []byte) (*ExplainResponse, error) {
+	start := time.Now()
+	resp, err := c.explain(ctx, body)
+	if err == nil && c.latency != nil {
+		c.latency.observe(time.Since(start))
+	}
+	return resp, err
+}
+
+// latencyWindowSize is the number of recent latencies the hedging delay is computed from.
+const latencyWindowSize = 200
+
+// minLatencySamples is the number of latencies needed before requests are hedged.
+const minLatencySamples = 20
+
+// latencyWindow keeps the most recent latencies of successful calls.
+// Thread-safe.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+// observe records a latency, replacing the oldest once the window is full.
+func (w *latencyWindow) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// quantile returns the q quantile of the recorded latencies, and false with fewer
+// than minLatencySamples of them.
+func (w *latencyWindow) quantile(q float64) (time.Duration, bool) {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	if n < minLatencySamples {
+		w.mu.Unlock()
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), w.samples[:n]...)
+	w.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(q*float64(n-1))], true
+}
@@ -0,0 +1,112 @@
+package shapclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExplainRetry(t *testing.T) {
+	var calls atomic.Int32
+	failures, status := int32(2), http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"busy"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ExplainResponse{Prediction: 42})
+	}))
+	defer server.Close()
+
+	client := &Client{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	client.SetRetry(RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+
+	resp, err := client.Explain(context.Background(), 1, "GROCERY I", "2024-01-15", nil)
+	if err != nil || resp.Prediction != 42 {
+		t.Fatalf("expected success after retries, got %v, %v", resp, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+
+	// Invalid requests are not retried
+	calls.Store(0)
+	status = http.StatusBadRequest
+	if _, err := client.Explain(context.Background(), 1, "GROCERY I", "2024-01-15", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a 400 not to be retried, got %d calls", calls.Load())
+	}
+
+	// Retries stop after MaxRetries
+	calls.Store(0)
+	failures, status = 10, http.StatusBadGateway
+	if _, err := client.Explain(context.Background(), 1, "GROCERY I", "2024-01-15", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 1 call and 2 retries, got %d calls", calls.Load())
+	}
+}
+
+func TestExplainHedge(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// The first request stalls until the hedge has answered and it is cancelled
+			io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		json.NewEncoder(w).Encode(ExplainResponse{Prediction: 7})
+	}))
+	defer server.Close()
+
+	client := &Client{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	client.SetRetry(RetryConfig{Hedge: true})
+	for i := 0; i < minLatencySamples; i++ {
+		client.latency.observe(20 * time.Millisecond)
+	}
+
+	start := time.Now()
+	resp, err := client.Explain(context.Background(), 1, "GROCERY I", "2024-01-15", nil)
+	if err != nil || resp.Prediction != 7 {
+		t.Fatalf("expected the hedged response, got %v, %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the hedge to bound latency, took %s", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestLatencyWindowQuantile(t *testing.T) {
+	w := newLatencyWindow(100)
+	if _, ok := w.quantile(0.95); ok {
+		t.Error("expected no quantile without samples")
+	}
+	for i := 1; i <= 200; i++ {
+		w.observe(time.Duration(i) * time.Millisecond)
+	}
+	// Only the latest 100 samples (101ms to 200ms) count
+	if got, ok := w.quantile(0.95); !ok || got != 195*time.Millisecond {
+		t.Errorf("expected P95 of 195ms, got %s", got)
+	}
+}
@@ -0,0 +1,317 @@
+// Package slo tracks per-route service level objectives: the ratio of requests that
+// succeed and of requests answered within a latency threshold, their compliance over
+// a rolling window and the rate they burn the error budget at.
+package slo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+)
+
+// SLIs, as labelled in metrics.
+const (
+	SLIAvailability = "availability" // Requests answered without a 5xx status
+	SLILatency      = "latency"      // Requests without a 5xx status answered within the threshold
+)
+
+// Default objectives.
+const (
+	DefaultRoutes             = "/predict,/predict/simple,/predict/batch,/forecast,/explain,/hierarchy"
+	DefaultLatencyThresholds  = "/predict/batch=1s,/forecast=1s,/explain=2s"
+	DefaultAvailabilityTarget = 0.999
+	DefaultLatencyTarget      = 0.99
+	DefaultLatencyThreshold   = 250 * time.Millisecond
+	DefaultWindow             = 24 * time.Hour
+)
+
+// BurnWindows are the windows burn rates are reported over, shortest first. Windows
+// longer than the SLO window are left out.
+var BurnWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// bucketSize is the resolution of the rolling window.
+const bucketSize = time.Minute
+
+// Config holds SLO configuration.
+type Config struct {
+	Routes             []string                 // Route patterns tracked, without the /v1 prefix
+	AvailabilityTarget float64                  // Target ratio of requests without a 5xx status
+	LatencyTarget      float64                  // Target ratio of requests within the latency threshold
+	LatencyThreshold   time.Duration            // Latency threshold of routes without an override
+	LatencyThresholds  map[string]time.Duration // Latency threshold overrides by route
+	Window             time.Duration            // Compliance window
+	Interval           time.Duration            // Time between gauge updates
+}
+
+// DefaultConfig returns the default SLO configuration.
+func DefaultConfig() Config {
+	cfg := Config{
+		Routes:             ParseRoutes(DefaultRoutes),
+		AvailabilityTarget: DefaultAvailabilityTarget,
+		LatencyTarget:      DefaultLatencyTarget,
+		LatencyThreshold:   DefaultLatencyThreshold,
+		Window:             DefaultWindow,
+		Interval:           15 * time.Second,
+	}
+	cfg.LatencyThresholds, _ = ParseThresholds(DefaultLatencyThresholds)
+	return cfg
+}
+
+// ParseRoutes parses a comma-separated list of route patterns, such as
+// "/predict,/predict/simple".
+func ParseRoutes(s string) []string {
+	var routes []string
+	for _, route := range strings.Split(s, ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// ParseThresholds parses a comma-separated list of latency thresholds by route, such as
+// "/predict/batch=1s,/explain=2s".
+func ParseThresholds(s string) (map[string]time.Duration, error) {
+	thresholds := make(map[string]time.Duration)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		route, val, ok := strings.Cut(field, "=")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(route), "/") {
+			return nil, fmt.Errorf("invalid latency threshold %q: must be route=duration", field)
+		}
+		threshold, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("invalid latency threshold %q: must be a positive duration", field)
+		}
+		thresholds[strings.TrimSpace(route)] = threshold
+	}
+	return thresholds, nil
+}
+
+// bucket counts the requests to a route in one minute.
+type bucket struct {
+	minute int64 // Unix minute the counts are for
+	total  uint64
+	errors uint64 // Requests answered with a 5xx status
+	slow   uint64 // Requests without a 5xx status slower than the threshold
+}
+
+// routeSeries is the rolling window of one route.
+type routeSeries struct {
+	threshold time.Duration
+	buckets   []bucket
+}
+
+// Tracker records requests to the routes with objectives and reports their
+// compliance. Safe for concurrent use.
+type Tracker struct {
+	cfg Config
+	now func() time.Time
+
+	mu     sync.Mutex
+	routes map[string]*routeSeries
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTracker creates a tracker of the configured routes. Call Start to update the
+// SLO gauges periodically.
+func NewTracker(cfg Config) *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Tracker{
+		cfg:    cfg,
+		now:    time.Now,
+		routes: make(map[string]*routeSeries),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	n := int((cfg.Window + bucketSize - 1) / bucketSize)
+	for _, route := range cfg.Routes {
+		threshold, ok := cfg.LatencyThresholds[route]
+		if !ok {
+			threshold = cfg.LatencyThreshold
+		}
+		t.routes[route] = &routeSeries{threshold: threshold, buckets: make([]bucket, n)}
+	}
+	return t
+}
+
+// Config returns the tracker's configuration.
+func (t *Tracker) Config() Config {
+	return t.cfg
+}
+
+// Start updates the SLO gauges now and then every Interval until Close.
+func (t *Tracker) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.updateMetrics()
+		ticker := time.NewTicker(t.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-ticker.C:
+				t.updateMetrics()
+			}
+		}
+	}()
+}
+
+// Close stops the gauge updates.
+func (t *Tracker) Close() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+// Record records a request to route answered with status after d. Requests to routes
+// without objectives are ignored.
+func (t *Tracker) Record(route string, status int, d time.Duration) {
+	t.mu.Lock()
+	s, ok := t.routes[route]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	failed := status >= 500
+	slow := !failed && d > s.threshold
+
+	minute := t.now().Unix() / int64(bucketSize/time.Second)
+	b := &s.buckets[minute%int64(len(s.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	} else if slow {
+		b.slow++
+	}
+	t.mu.Unlock()
+
+	metrics.RecordSLORequest(route, SLIAvailability, !failed)
+	if !failed {
+		metrics.RecordSLORequest(route, SLILatency, !slow)
+	}
+}
+
+// SLI is the compliance of a route with one objective.
+type SLI struct {
+	Objective            float64            `json:"objective"`              // Target ratio of good requests
+	Compliance           float64            `json:"compliance"`             // Ratio of good requests over the window; 1 without requests
+	Good                 uint64             `json:"good"`                   // Good requests over the window
+	Total                uint64             `json:"total"`                  // Requests over the window the SLI applies to
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"` // Fraction of the budget left; negative once exhausted
+	BurnRates            map[string]float64 `json:"burn_rates"`             // By window; 1 spends the budget exactly over the SLO window
+}
+
+// Status is the compliance of a route with its objectives.
+type Status struct {
+	Route              string  `json:"route"`
+	LatencyThresholdMs float64 `json:"latency_threshold_ms"`
+	Availability       SLI     `json:"availability"`
+	Latency            SLI     `json:"latency"`
+}
+
+// counts are request counts over a span of buckets.
+type counts struct {
+	total, errors, slow uint64
+}
+
+// Statuses returns the compliance of every route with objectives, by route.
+func (t *Tracker) Statuses() []Status {
+	now := t.now().Unix() / int64(bucketSize/time.Second)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := make([]Status, 0, len(t.routes))
+	for route, s := range t.routes {
+		window := s.counts(now, len(s.buckets))
+		status := Status{
+			Route:              route,
+			LatencyThresholdMs: float64(s.threshold.Microseconds()) / 1000,
+			Availability:       newSLI(t.cfg.AvailabilityTarget, window.total, window.errors),
+			Latency:            newSLI(t.cfg.LatencyTarget, window.total-window.errors, window.slow),
+		}
+		for _, w := range BurnWindows {
+			minutes := int(w.Duration / bucketSize)
+			if minutes > len(s.buckets) {
+				break
+			}
+			c := s.counts(now, minutes)
+			status.Availability.BurnRates[w.Name] = burnRate(t.cfg.AvailabilityTarget, c.total, c.errors)
+			status.Latency.BurnRates[w.Name] = burnRate(t.cfg.LatencyTarget, c.total-c.errors, c.slow)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// counts sums the buckets of the last minutes minutes, up to now.
+func (s *routeSeries) counts(now int64, minutes int) counts {
+	var c counts
+	for m := now - int64(minutes) + 1; m <= now; m++ {
+		b := s.buckets[m%int64(len(s.buckets))]
+		if b.minute != m {
+			continue
+		}
+		c.total += b.total
+		c.errors += b.errors
+		c.slow += b.slow
+	}
+	return c
+}
+
+// newSLI returns the compliance with objective of total requests of which bad missed it.
+func newSLI(objective float64, total, bad uint64) SLI {
+	sli := SLI{
+		Objective:            objective,
+		Compliance:           1,
+		Good:                 total - bad,
+		Total:                total,
+		ErrorBudgetRemaining: 1,
+		BurnRates:            make(map[string]float64),
+	}
+	if total > 0 {
+		sli.Compliance = float64(total-bad) / float64(total)
+		sli.ErrorBudgetRemaining = 1 - burnRate(objective, total, bad)
+	}
+	return sli
+}
+
+// burnRate returns the ratio of bad requests to the ratio the objective allows.
+func burnRate(objective float64, total, bad uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - objective)
+}
+
+// updateMetrics sets the SLO gauges from the current statuses.
+func (t *Tracker) updateMetrics() {
+	for _, s := range t.Statuses() {
+		metrics.SetSLOStatus(s.Route, SLIAvailability, s.Availability.Objective, s.Availability.Compliance, s.Availability.ErrorBudgetRemaining, s.Availability.BurnRates)
+		metrics.SetSLOStatus(s.Route, SLILatency, s.Latency.Objective, s.Latency.Compliance, s.Latency.ErrorBudgetRemaining, s.Latency.BurnRates)
+	}
+}
@@ -0,0 +1,85 @@
+package slo
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2017, 8, 15, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(Config{
+		Routes:             []string{"/predict", "/explain"},
+		AvailabilityTarget: 0.99,
+		LatencyTarget:      0.9,
+		LatencyThreshold:   100 * time.Millisecond,
+		LatencyThresholds:  map[string]time.Duration{"/explain": time.Second},
+		Window:             6 * time.Hour,
+	})
+	tracker.now = func() time.Time { return now }
+
+	// Five hours ago: 100 good requests
+	now = now.Add(-5 * time.Hour)
+	for i := 0; i < 100; i++ {
+		tracker.Record("/predict", http.StatusOK, 10*time.Millisecond)
+	}
+	// Now: 98 requests, one failed and one slow, and an untracked route
+	now = now.Add(5 * time.Hour)
+	for i := 0; i < 96; i++ {
+		tracker.Record("/predict", http.StatusOK, 10*time.Millisecond)
+	}
+	tracker.Record("/predict", http.StatusBadRequest, 10*time.Millisecond)
+	tracker.Record("/predict", http.StatusServiceUnavailable, 10*time.Millisecond)
+	tracker.Record("/predict", http.StatusOK, 500*time.Millisecond)
+	tracker.Record("/explain", http.StatusOK, 500*time.Millisecond)
+	tracker.Record("/health", http.StatusInternalServerError, 0)
+
+	statuses := tracker.Statuses()
+	if len(statuses) != 2 || statuses[0].Route != "/explain" || statuses[1].Route != "/predict" {
+		t.Fatalf("expected statuses of /explain and /predict, got %+v", statuses)
+	}
+	if explain := statuses[0]; explain.LatencyThresholdMs != 1000 || explain.Latency.Good != 1 {
+		t.Errorf("expected /explain within its own threshold, got %+v", explain)
+	}
+
+	predict := statuses[1]
+	if predict.Availability.Total != 199 || predict.Availability.Good != 198 {
+		t.Errorf("expected 198 of 199 requests available, got %+v", predict.Availability)
+	}
+	if predict.Latency.Total != 198 || predict.Latency.Good != 197 {
+		t.Errorf("expected 197 of 198 available requests fast enough, got %+v", predict.Latency)
+	}
+	// 1 failure in 199 spends half the 1% budget
+	if got := predict.Availability.ErrorBudgetRemaining; math.Abs(got-(1-100.0/199)) > 1e-9 {
+		t.Errorf("expected about half the availability budget left, got %f", got)
+	}
+	// Over the last 5 minutes, 1 failure in 99 burns the budget at 100/99 times the sustainable rate
+	if got := predict.Availability.BurnRates["5m"]; math.Abs(got-100.0/99) > 1e-9 {
+		t.Errorf("expected a 5m burn rate of 100/99, got %f", got)
+	}
+	if _, ok := predict.Availability.BurnRates["6h"]; !ok {
+		t.Error("expected a 6h burn rate within a 6h window")
+	}
+
+	// Once the window has passed, old requests no longer count
+	now = now.Add(6 * time.Hour)
+	if s := tracker.Statuses()[1]; s.Availability.Total != 0 || s.Availability.Compliance != 1 || s.Availability.ErrorBudgetRemaining != 1 {
+		t.Errorf("expected an empty window, got %+v", s.Availability)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds(DefaultLatencyThresholds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if thresholds["/explain"] != 2*time.Second || thresholds["/predict/batch"] != time.Second {
+		t.Errorf("unexpected thresholds %v", thresholds)
+	}
+	for _, invalid := range []string{"/predict", "predict=1s", "/predict=fast", "/predict=0s"} {
+		if _, err := ParseThresholds(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
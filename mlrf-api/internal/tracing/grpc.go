@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// grpcExportTimeout bounds one export to the collector.
+const grpcExportTimeout = 10 * time.Second
+
+// grpcClient is an otlptrace.Client exporting spans to an OTLP/gRPC collector.
+type grpcClient struct {
+	endpoint string
+	conn     *grpc.ClientConn
+	client   coltracepb.TraceServiceClient
+}
+
+// newGRPCClient returns a client for the collector at endpoint (host:port; an http://
+// scheme is ignored). Like the HTTP exporter, it connects without TLS.
+func newGRPCClient(endpoint string) *grpcClient {
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+	return &grpcClient{endpoint: endpoint}
+}
+
+// Start dials the collector. The connection is made in the background, so a collector
+// that is down doesn't stop startup.
+func (c *grpcClient) Start(ctx context.Context) error {
+	conn, err := grpc.DialContext(ctx, c.endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("dial OTLP collector %s: %w", c.endpoint, err)
+	}
+	c.conn = conn
+	c.client = coltracepb.NewTraceServiceClient(conn)
+	return nil
+}
+
+// Stop closes the connection.
+func (c *grpcClient) Stop(ctx context.Context) error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// UploadTraces exports spans, failing if the collector rejects any.
+func (c *grpcClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	ctx, cancel := context.WithTimeout(ctx, grpcExportTimeout)
+	defer cancel()
+	resp, err := c.client.Export(ctx, &coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedSpans() > 0 {
+		return fmt.Errorf("OTLP collector rejected %d spans: %s", partial.GetRejectedSpans(), partial.GetErrorMessage())
+	}
+	return nil
+}
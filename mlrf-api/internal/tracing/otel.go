@@ -3,7 +3,6 @@ package tracing
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -27,6 +26,12 @@ const (
 	ServiceVersion = "1.0.0"
 )
 
+// OTLP transports, as named by OTEL_EXPORTER_PROTOCOL.
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
 // Config holds configuration for the tracing system.
 type Config struct {
 	// Enabled controls whether tracing is active.
@@ -35,35 +40,54 @@ type Config struct {
 	// Endpoint is the OTLP collector endpoint (e.g., "localhost:4318" for Jaeger).
 	Endpoint string
 
-	// SampleRate is the fraction of traces to sample (0.0-1.0).
+	// Protocol is the OTLP transport: ProtocolHTTP or ProtocolGRPC.
+	Protocol string
+
+	// Sampler names the head sampler, such as SamplerRatio or SamplerRateLimited.
+	Sampler string
+
+	// SampleRate is the fraction of traces to sample (0.0-1.0) by the ratio samplers.
 	SampleRate float64
 
+	// RateLimit is the traces per second sampled by the rate-limited samplers.
+	RateLimit float64
+
+	// TailSampling also exports traces the sampler drops if a span failed or the
+	// request took at least TailLatency.
+	TailSampling bool
+	TailLatency  time.Duration
+
 	// ServiceName overrides the default service name.
 	ServiceName string
+
+	// Environment is the deployment environment recorded on every span.
+	Environment string
 }
 
-// DefaultConfig returns a Config with sensible defaults from environment variables.
+// DefaultConfig returns a Config with sensible defaults: every trace exported over
+// OTLP/HTTP to the local collector.
 func DefaultConfig() Config {
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		endpoint = "localhost:4318"
-	}
-
-	enabled := os.Getenv("OTEL_ENABLED") != "false" && endpoint != ""
-
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = ServiceName
-	}
-
 	return Config{
-		Enabled:     enabled,
-		Endpoint:    endpoint,
+		Enabled:     true,
+		Endpoint:    DefaultEndpoint(ProtocolHTTP),
+		Protocol:    ProtocolHTTP,
+		Sampler:     SamplerRatio,
 		SampleRate:  1.0, // Sample all traces in dev, reduce in production
-		ServiceName: serviceName,
+		RateLimit:   DefaultRateLimit,
+		TailLatency: DefaultTailLatency,
+		ServiceName: ServiceName,
+		Environment: "development",
 	}
 }
 
+// DefaultEndpoint returns the local collector's OTLP endpoint for protocol.
+func DefaultEndpoint(protocol string) string {
+	if protocol == ProtocolGRPC {
+		return "localhost:4317"
+	}
+	return "localhost:4318"
+}
+
 // TracerProvider wraps the OpenTelemetry trace provider.
 type TracerProvider struct {
 	provider *sdktrace.TracerProvider
@@ -83,11 +107,21 @@ func NewTracerProvider(cfg Config) (*TracerProvider, error) {
 
 	ctx := context.Background()
 
-	// Create OTLP HTTP exporter
-	client := otlptracehttp.NewClient(
-		otlptracehttp.WithEndpoint(cfg.Endpoint),
-		otlptracehttp.WithInsecure(),
-	)
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create OTLP exporter
+	var client otlptrace.Client
+	if cfg.Protocol == ProtocolGRPC {
+		client = newGRPCClient(cfg.Endpoint)
+	} else {
+		client = otlptracehttp.NewClient(
+			otlptracehttp.WithEndpoint(cfg.Endpoint),
+			otlptracehttp.WithInsecure(),
+		)
+	}
 
 	exporter, err := otlptrace.New(ctx, client)
 	if err != nil {
@@ -99,21 +133,26 @@ func NewTracerProvider(cfg Config) (*TracerProvider, error) {
 		resource.WithAttributes(
 			semconv.ServiceName(cfg.ServiceName),
 			semconv.ServiceVersion(ServiceVersion),
-			attribute.String("environment", getEnvironment()),
+			attribute.String("environment", cfg.Environment),
 		),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create trace provider with batch span processor
+	// Create trace provider with batch span processor, behind a tail sampler if enabled
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter,
+		sdktrace.WithBatchTimeout(5*time.Second),
+		sdktrace.WithMaxExportBatchSize(512),
+	)
+	if cfg.TailSampling {
+		processor = newTailProcessor(processor, cfg.TailLatency)
+		sampler = recordingSampler{sampler}
+	}
 	provider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter,
-			sdktrace.WithBatchTimeout(5*time.Second),
-			sdktrace.WithMaxExportBatchSize(512),
-		),
+		sdktrace.WithSpanProcessor(processor),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(cfg.SampleRate)),
+		sdktrace.WithSampler(sampler),
 	)
 
 	// Set global trace provider and propagator
@@ -125,7 +164,8 @@ func NewTracerProvider(cfg Config) (*TracerProvider, error) {
 
 	log.Info().
 		Str("endpoint", cfg.Endpoint).
-		Float64("sample_rate", cfg.SampleRate).
+		Str("protocol", cfg.Protocol).
+		Str("sampler", sampler.Description()).
 		Msg("OpenTelemetry tracing initialized")
 
 	return &TracerProvider{
@@ -187,6 +227,21 @@ func AddEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
 	span.AddEvent(name, trace.WithAttributes(attrs...))
 }
 
+// Start starts a child span of the span in ctx, with the tracer provider set by
+// NewTracerProvider. Spans are no-ops while tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, recording err on it and marking it failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 // Common attribute keys for MLRF API.
 var (
 	AttrStoreNbr    = attribute.Key("mlrf.store_nbr")
@@ -194,16 +249,9 @@ var (
 	AttrDate        = attribute.Key("mlrf.date")
 	AttrHorizon     = attribute.Key("mlrf.horizon")
 	AttrCacheHit    = attribute.Key("mlrf.cache_hit")
+	AttrCacheHits   = attribute.Key("mlrf.cache_hits")
 	AttrBatchSize   = attribute.Key("mlrf.batch_size")
 	AttrPrediction  = attribute.Key("mlrf.prediction")
 	AttrInferenceMs = attribute.Key("mlrf.inference_ms")
+	AttrFeatureSrc  = attribute.Key("mlrf.feature_source")
 )
-
-// getEnvironment returns the current environment name.
-func getEnvironment() string {
-	env := os.Getenv("ENVIRONMENT")
-	if env == "" {
-		env = "development"
-	}
-	return env
-}
@@ -31,35 +31,6 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
-func TestDefaultConfigWithEnv(t *testing.T) {
-	// Set environment variables
-	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "jaeger:4318")
-	os.Setenv("OTEL_SERVICE_NAME", "test-service")
-	defer os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	defer os.Unsetenv("OTEL_SERVICE_NAME")
-
-	cfg := DefaultConfig()
-
-	if cfg.Endpoint != "jaeger:4318" {
-		t.Errorf("expected endpoint jaeger:4318, got %s", cfg.Endpoint)
-	}
-
-	if cfg.ServiceName != "test-service" {
-		t.Errorf("expected service name test-service, got %s", cfg.ServiceName)
-	}
-}
-
-func TestDefaultConfigDisabled(t *testing.T) {
-	os.Setenv("OTEL_ENABLED", "false")
-	defer os.Unsetenv("OTEL_ENABLED")
-
-	cfg := DefaultConfig()
-
-	if cfg.Enabled {
-		t.Error("expected tracing to be disabled")
-	}
-}
-
 func TestNewTracerProviderDisabled(t *testing.T) {
 	cfg := Config{
 		Enabled:     false,
@@ -175,20 +146,12 @@ func TestAttributeKeys(t *testing.T) {
 	}
 }
 
-func TestGetEnvironment(t *testing.T) {
-	// Clear environment
-	os.Unsetenv("ENVIRONMENT")
-	env := getEnvironment()
-	if env != "development" {
-		t.Errorf("expected development, got %s", env)
+func TestDefaultEndpoint(t *testing.T) {
+	if got := DefaultEndpoint(ProtocolHTTP); got != "localhost:4318" {
+		t.Errorf("expected localhost:4318 over HTTP, got %s", got)
 	}
-
-	// Set environment
-	os.Setenv("ENVIRONMENT", "production")
-	defer os.Unsetenv("ENVIRONMENT")
-	env = getEnvironment()
-	if env != "production" {
-		t.Errorf("expected production, got %s", env)
+	if got := DefaultEndpoint(ProtocolGRPC); got != "localhost:4317" {
+		t.Errorf("expected localhost:4317 over gRPC, got %s", got)
 	}
 }
 
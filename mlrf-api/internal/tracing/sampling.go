@@ -0,0 +1,199 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Samplers, as named by OTEL_TRACES_SAMPLER.
+const (
+	SamplerAlwaysOn               = "always_on"
+	SamplerRatio                  = "traceidratio"
+	SamplerParentBasedRatio       = "parentbased_traceidratio"
+	SamplerRateLimited            = "ratelimited"
+	SamplerParentBasedRateLimited = "parentbased_ratelimited"
+)
+
+const (
+	// DefaultRateLimit is the traces per second sampled by the rate-limited samplers.
+	DefaultRateLimit = 10.0
+
+	// DefaultTailLatency is the root span duration from which tail sampling keeps a trace.
+	DefaultTailLatency = 500 * time.Millisecond
+
+	maxTailTraces        = 10000 // Traces buffered awaiting a tail decision
+	maxTailSpansPerTrace = 512
+)
+
+// newSampler returns the head sampler named by cfg.Sampler.
+func newSampler(cfg Config) (sdktrace.Sampler, error) {
+	switch cfg.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case "", SamplerRatio:
+		return sdktrace.TraceIDRatioBased(cfg.SampleRate), nil
+	case SamplerParentBasedRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate)), nil
+	case SamplerRateLimited:
+		return NewRateLimitSampler(cfg.RateLimit), nil
+	case SamplerParentBasedRateLimited:
+		return sdktrace.ParentBased(NewRateLimitSampler(cfg.RateLimit)), nil
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", cfg.Sampler)
+	}
+}
+
+// RateLimitSampler samples at most a number of traces per second, with bursts of up
+// to one second's worth. Spans with a local parent follow their parent's decision, so
+// traces are sampled whole.
+type RateLimitSampler struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimitSampler returns a sampler of at most perSecond traces per second.
+func NewRateLimitSampler(perSecond float64) *RateLimitSampler {
+	return &RateLimitSampler{rate: perSecond, tokens: perSecond, now: time.Now}
+}
+
+// ShouldSample samples the trace if the rate allows.
+func (s *RateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := trace.SpanContextFromContext(p.ParentContext)
+	result := sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: parent.TraceState()}
+	if parent.IsValid() && !parent.IsRemote() {
+		if parent.IsSampled() {
+			result.Decision = sdktrace.RecordAndSample
+		}
+		return result
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens = min(s.rate, s.tokens+now.Sub(s.last).Seconds()*s.rate)
+	}
+	s.last = now
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Decision = sdktrace.RecordAndSample
+	}
+	return result
+}
+
+// Description describes the sampler.
+func (s *RateLimitSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.rate)
+}
+
+// recordingSampler records the spans of every trace its sampler drops, so a tail
+// processor can still export the trace once it knows how it ended.
+type recordingSampler struct {
+	sdktrace.Sampler
+}
+
+func (s recordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.Sampler.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s recordingSampler) Description() string {
+	return "Tail{" + s.Sampler.Description() + "}"
+}
+
+// tailProcessor passes the spans of sampled traces to next, and buffers the spans of
+// traces the head sampler only recorded until their local root span ends. Those are
+// passed on too if any span failed or the root took at least latency: slow and failing
+// requests are traced whatever the sample rate. Spans ending after their root are
+// dropped with the rest of an unkept trace.
+type tailProcessor struct {
+	next    sdktrace.SpanProcessor
+	latency time.Duration
+
+	mu      sync.Mutex
+	pending map[trace.TraceID][]sdktrace.ReadOnlySpan
+}
+
+func newTailProcessor(next sdktrace.SpanProcessor, latency time.Duration) *tailProcessor {
+	return &tailProcessor{
+		next:    next,
+		latency: latency,
+		pending: make(map[trace.TraceID][]sdktrace.ReadOnlySpan),
+	}
+}
+
+func (p *tailProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *tailProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+
+	id := s.SpanContext().TraceID()
+	p.mu.Lock()
+	if s.Parent().IsValid() && !s.Parent().IsRemote() {
+		spans, ok := p.pending[id]
+		if (ok || len(p.pending) < maxTailTraces) && len(spans) < maxTailSpansPerTrace {
+			p.pending[id] = append(spans, s)
+		}
+		p.mu.Unlock()
+		return
+	}
+	spans := append(p.pending[id], s)
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	if !p.keep(s, spans) {
+		return
+	}
+	for _, span := range spans {
+		p.next.OnEnd(sampledSpan{span})
+	}
+}
+
+// keep reports whether a trace is exported: a span failed, or its root was slow.
+func (p *tailProcessor) keep(root sdktrace.ReadOnlySpan, spans []sdktrace.ReadOnlySpan) bool {
+	if root.EndTime().Sub(root.StartTime()) >= p.latency {
+		return true
+	}
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *tailProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *tailProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan is a recorded span marked sampled, so processors export it.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
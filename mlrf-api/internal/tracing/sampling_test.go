@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+func TestRateLimitSampler(t *testing.T) {
+	now := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+	s := NewRateLimitSampler(2)
+	s.now = func() time.Time { return now }
+
+	sampled := func() int {
+		n := 0
+		for i := 0; i < 5; i++ {
+			if s.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()}).Decision == sdktrace.RecordAndSample {
+				n++
+			}
+		}
+		return n
+	}
+	if n := sampled(); n != 2 {
+		t.Errorf("expected a burst of 2 traces, got %d", n)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if n := sampled(); n != 1 {
+		t.Errorf("expected 1 trace after half a second, got %d", n)
+	}
+
+	// Spans with a local parent follow it without taking from the rate
+	parent := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	if s.ShouldSample(sdktrace.SamplingParameters{ParentContext: parent}).Decision != sdktrace.RecordAndSample {
+		t.Error("expected the child of a sampled span to be sampled")
+	}
+}
+
+func TestTailSampling(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(newTailProcessor(rec, time.Second)),
+		sdktrace.WithSampler(recordingSampler{sdktrace.NeverSample()}),
+	)
+	tracer := tp.Tracer("test")
+	start := time.Date(2017, 8, 15, 0, 0, 0, 0, time.UTC)
+
+	request := func(name string, took time.Duration, failed bool) {
+		ctx, root := tracer.Start(context.Background(), name, trace.WithTimestamp(start))
+		_, child := tracer.Start(ctx, name+" child")
+		if failed {
+			child.SetStatus(codes.Error, "inference failed")
+		}
+		child.End()
+		root.End(trace.WithTimestamp(start.Add(took)))
+	}
+	request("fast", 10*time.Millisecond, false)
+	request("slow", 2*time.Second, false)
+	request("failed", 10*time.Millisecond, true)
+
+	kept := make(map[string]bool)
+	for _, span := range rec.Ended() {
+		if !span.SpanContext().IsSampled() {
+			t.Errorf("expected kept span %s to be marked sampled", span.Name())
+		}
+		kept[span.Name()] = true
+	}
+	for _, name := range []string{"slow", "slow child", "failed", "failed child"} {
+		if !kept[name] {
+			t.Errorf("expected span %s to be kept", name)
+		}
+	}
+	if kept["fast"] || kept["fast child"] {
+		t.Error("expected the fast, successful trace to be dropped")
+	}
+}
+
+type traceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	spans chan int
+}
+
+func (c *traceCollector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	n := 0
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			n += len(ss.Spans)
+		}
+	}
+	c.spans <- n
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestNewTracerProviderGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	collector := &traceCollector{spans: make(chan int, 1)}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, collector)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	tp, err := NewTracerProvider(Config{
+		Enabled:     true,
+		Endpoint:    lis.Addr().String(),
+		Protocol:    ProtocolGRPC,
+		Sampler:     SamplerAlwaysOn,
+		ServiceName: "test",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, span := tp.StartSpan(context.Background(), "test-span")
+	span.End()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error on shutdown: %v", err)
+	}
+
+	select {
+	case n := <-collector.spans:
+		if n != 1 {
+			t.Errorf("expected 1 span exported, got %d", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected spans to be exported over gRPC")
+	}
+}
@@ -0,0 +1,409 @@
+// Package treeshap computes SHAP values for LightGBM models natively, from the JSON
+// model dump written by Booster.dump_model(). It implements the path-dependent
+// TreeSHAP algorithm (Lundberg et al., "Consistent Individualized Feature Attribution
+// for Tree Ensembles", Algorithm 2), which is what shap.TreeExplainer uses with
+// feature_perturbation="tree_path_dependent", so its values match the SHAP service.
+package treeshap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// zeroThreshold is LightGBM's kZeroThreshold: smaller magnitudes count as zero.
+const zeroThreshold = 1e-35
+
+// node is a tree node. Children are indices into the tree's nodes.
+type node struct {
+	leaf  bool
+	value float64 // Leaf value
+	cover float64 // Training samples reaching the node
+
+	feature     int
+	threshold   float64
+	categories  map[int]bool // Categories going left; nil for numerical splits
+	defaultLeft bool
+	missing     string // None, Zero or NaN
+	left, right int
+}
+
+// tree is one regression tree; node 0 is the root.
+type tree struct {
+	nodes    []node
+	maxDepth int
+}
+
+// Model is a LightGBM tree ensemble. Safe for concurrent use.
+type Model struct {
+	trees        []tree
+	featureNames []string
+	numFeatures  int
+	scale        float64 // 1/len(trees) for averaged (random forest) output, else 1
+	base         float64
+}
+
+// dump is the part of the LightGBM model dump used here.
+type dump struct {
+	NumClass      int      `json:"num_class"`
+	MaxFeatureIdx int      `json:"max_feature_idx"`
+	AverageOutput bool     `json:"average_output"`
+	FeatureNames  []string `json:"feature_names"`
+	TreeInfo      []struct {
+		TreeIndex     int      `json:"tree_index"`
+		TreeStructure dumpNode `json:"tree_structure"`
+	} `json:"tree_info"`
+}
+
+// dumpNode is a split or leaf of the model dump.
+type dumpNode struct {
+	SplitFeature   *int            `json:"split_feature"`
+	Threshold      json.RawMessage `json:"threshold"` // Number, or "a||b||c" categories
+	DecisionType   string          `json:"decision_type"`
+	DefaultLeft    bool            `json:"default_left"`
+	MissingType    string          `json:"missing_type"`
+	InternalCount  float64         `json:"internal_count"`
+	InternalWeight float64         `json:"internal_weight"`
+	LeftChild      *dumpNode       `json:"left_child"`
+	RightChild     *dumpNode       `json:"right_child"`
+
+	LeafValue  float64 `json:"leaf_value"`
+	LeafCount  float64 `json:"leaf_count"`
+	LeafWeight float64 `json:"leaf_weight"`
+}
+
+// Load reads a LightGBM JSON model dump.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model dump: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse parses a LightGBM JSON model dump.
+func Parse(data []byte) (*Model, error) {
+	var d dump
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse model dump: %w", err)
+	}
+	if d.NumClass > 1 {
+		return nil, fmt.Errorf("multiclass models are not supported")
+	}
+	if len(d.TreeInfo) == 0 {
+		return nil, fmt.Errorf("model has no trees")
+	}
+
+	m := &Model{
+		featureNames: d.FeatureNames,
+		numFeatures:  d.MaxFeatureIdx + 1,
+		scale:        1,
+	}
+	if len(d.FeatureNames) > m.numFeatures {
+		m.numFeatures = len(d.FeatureNames)
+	}
+	if d.AverageOutput {
+		m.scale = 1 / float64(len(d.TreeInfo))
+	}
+
+	for _, ti := range d.TreeInfo {
+		var t tree
+		if _, err := t.add(&ti.TreeStructure, 0, m.numFeatures); err != nil {
+			return nil, fmt.Errorf("tree %d: %w", ti.TreeIndex, err)
+		}
+		m.trees = append(m.trees, t)
+		m.base += t.expectedValue(0) * m.scale
+	}
+	return m, nil
+}
+
+// add appends n and its subtree to the tree and returns its index.
+func (t *tree) add(n *dumpNode, depth, numFeatures int) (int, error) {
+	i := len(t.nodes)
+	t.nodes = append(t.nodes, node{})
+	if depth > t.maxDepth {
+		t.maxDepth = depth
+	}
+
+	if n.SplitFeature == nil {
+		cover := n.LeafCount
+		if cover == 0 {
+			cover = n.LeafWeight
+		}
+		t.nodes[i] = node{leaf: true, value: n.LeafValue, cover: cover}
+		return i, nil
+	}
+
+	if *n.SplitFeature < 0 || *n.SplitFeature >= numFeatures {
+		return 0, fmt.Errorf("split on unknown feature %d", *n.SplitFeature)
+	}
+	if n.LeftChild == nil || n.RightChild == nil {
+		return 0, fmt.Errorf("split on feature %d without two children", *n.SplitFeature)
+	}
+	nd := node{
+		feature:     *n.SplitFeature,
+		defaultLeft: n.DefaultLeft,
+		missing:     n.MissingType,
+		cover:       n.InternalCount,
+	}
+	if nd.cover == 0 {
+		nd.cover = n.InternalWeight
+	}
+
+	switch n.DecisionType {
+	case "<=", "":
+		if err := json.Unmarshal(n.Threshold, &nd.threshold); err != nil {
+			return 0, fmt.Errorf("invalid threshold %s", n.Threshold)
+		}
+	case "==":
+		var raw string
+		if err := json.Unmarshal(n.Threshold, &raw); err != nil {
+			// A single category may be dumped as a number
+			var f float64
+			if json.Unmarshal(n.Threshold, &f) != nil {
+				return 0, fmt.Errorf("invalid categorical threshold %s", n.Threshold)
+			}
+			raw = strconv.Itoa(int(f))
+		}
+		nd.categories = make(map[int]bool)
+		for _, c := range strings.Split(raw, "||") {
+			v, err := strconv.Atoi(c)
+			if err != nil {
+				return 0, fmt.Errorf("invalid category %q", c)
+			}
+			nd.categories[v] = true
+		}
+	default:
+		return 0, fmt.Errorf("unsupported decision type %q", n.DecisionType)
+	}
+
+	left, err := t.add(n.LeftChild, depth+1, numFeatures)
+	if err != nil {
+		return 0, err
+	}
+	right, err := t.add(n.RightChild, depth+1, numFeatures)
+	if err != nil {
+		return 0, err
+	}
+	nd.left, nd.right = left, right
+	t.nodes[i] = nd
+	return i, nil
+}
+
+// expectedValue returns the mean output of the subtree at j, weighting leaves by cover.
+func (t *tree) expectedValue(j int) float64 {
+	n := &t.nodes[j]
+	if n.leaf {
+		return n.value
+	}
+	l, r := &t.nodes[n.left], &t.nodes[n.right]
+	if l.cover+r.cover == 0 {
+		return (t.expectedValue(n.left) + t.expectedValue(n.right)) / 2
+	}
+	return (t.expectedValue(n.left)*l.cover + t.expectedValue(n.right)*r.cover) / (l.cover + r.cover)
+}
+
+// next returns the child of split n that x follows, using LightGBM's decision rules
+// for missing values and categories.
+func (n *node) next(x float64) int {
+	if n.categories != nil {
+		if math.IsNaN(x) {
+			if n.missing == "NaN" {
+				return n.right
+			}
+			x = 0
+		}
+		if c := int(x); c >= 0 && n.categories[c] {
+			return n.left
+		}
+		return n.right
+	}
+
+	if math.IsNaN(x) && n.missing != "NaN" {
+		x = 0
+	}
+	if (n.missing == "Zero" && math.Abs(x) <= zeroThreshold) || (n.missing == "NaN" && math.IsNaN(x)) {
+		if n.defaultLeft {
+			return n.left
+		}
+		return n.right
+	}
+	if x <= n.threshold {
+		return n.left
+	}
+	return n.right
+}
+
+// NumFeatures returns the number of input features.
+func (m *Model) NumFeatures() int {
+	return m.numFeatures
+}
+
+// FeatureNames returns the feature names stored in the dump.
+func (m *Model) FeatureNames() []string {
+	return append([]string(nil), m.featureNames...)
+}
+
+// ExpectedValue returns the mean model output over the training data, the base value
+// SHAP values are relative to.
+func (m *Model) ExpectedValue() float64 {
+	return m.base
+}
+
+// Predict returns the raw model output for features.
+func (m *Model) Predict(features []float32) (float64, error) {
+	x, err := m.input(features)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	for i := range m.trees {
+		t := &m.trees[i]
+		j := 0
+		for !t.nodes[j].leaf {
+			j = t.nodes[j].next(x[t.nodes[j].feature])
+		}
+		sum += t.nodes[j].value
+	}
+	return sum * m.scale, nil
+}
+
+// SHAP returns the SHAP value of each feature for features. The values add up to
+// Predict(features) - ExpectedValue().
+func (m *Model) SHAP(features []float32) ([]float64, error) {
+	x, err := m.input(features)
+	if err != nil {
+		return nil, err
+	}
+	phi := make([]float64, m.numFeatures)
+	for i := range m.trees {
+		t := &m.trees[i]
+		path := make([]pathElement, 0, (t.maxDepth+2)*(t.maxDepth+3)/2)
+		t.shap(x, phi, 0, path, 1, 1, -1)
+	}
+	for i := range phi {
+		phi[i] *= m.scale
+	}
+	return phi, nil
+}
+
+// input converts features to the float64 values LightGBM compares thresholds with.
+func (m *Model) input(features []float32) ([]float64, error) {
+	if len(features) != m.numFeatures {
+		return nil, fmt.Errorf("expected %d features, got %d", m.numFeatures, len(features))
+	}
+	x := make([]float64, len(features))
+	for i, f := range features {
+		x[i] = float64(f)
+	}
+	return x, nil
+}
+
+// pathElement is a feature on the path from the root to the current node.
+type pathElement struct {
+	feature int
+	zero    float64 // Fraction of zero paths (feature not in the coalition) flowing through
+	one     float64 // Fraction of one paths (feature in the coalition) flowing through
+	weight  float64 // Proportion of coalitions of each size
+}
+
+// shap recurses into node j, adding each leaf's contributions to phi. path holds the
+// features split on so far; every call works on its own copy.
+func (t *tree) shap(x, phi []float64, j int, path []pathElement, zero, one float64, feature int) {
+	path = extendPath(append(path[len(path):], path...), zero, one, feature)
+	n := &t.nodes[j]
+
+	if n.leaf {
+		for i := 1; i < len(path); i++ {
+			w := unwoundPathSum(path, i)
+			phi[path[i].feature] += w * (path[i].one - path[i].zero) * n.value
+		}
+		return
+	}
+
+	hot := n.next(x[n.feature])
+	cold := n.left
+	if hot == n.left {
+		cold = n.right
+	}
+
+	// A feature split on again is removed from the path and re-added with its fractions
+	incomingZero, incomingOne := 1.0, 1.0
+	for k := 1; k < len(path); k++ {
+		if path[k].feature == n.feature {
+			incomingZero, incomingOne = path[k].zero, path[k].one
+			path = unwindPath(path, k)
+			break
+		}
+	}
+
+	hotZero, coldZero := 0.0, 0.0
+	if n.cover > 0 {
+		hotZero = t.nodes[hot].cover / n.cover
+		coldZero = t.nodes[cold].cover / n.cover
+	}
+	t.shap(x, phi, hot, path, incomingZero*hotZero, incomingOne, n.feature)
+	t.shap(x, phi, cold, path, incomingZero*coldZero, 0, n.feature)
+}
+
+// extendPath appends a feature with the given zero and one fractions to path,
+// updating the coalition weights.
+func extendPath(path []pathElement, zero, one float64, feature int) []pathElement {
+	depth := len(path)
+	weight := 0.0
+	if depth == 0 {
+		weight = 1
+	}
+	path = append(path, pathElement{feature: feature, zero: zero, one: one, weight: weight})
+	for i := depth - 1; i >= 0; i-- {
+		path[i+1].weight += one * path[i].weight * float64(i+1) / float64(depth+1)
+		path[i].weight = zero * path[i].weight * float64(depth-i) / float64(depth+1)
+	}
+	return path
+}
+
+// unwindPath removes the feature at index k from path, undoing its extendPath.
+func unwindPath(path []pathElement, k int) []pathElement {
+	depth := len(path) - 1
+	one, zero := path[k].one, path[k].zero
+	next := path[depth].weight
+	for i := depth - 1; i >= 0; i-- {
+		if one != 0 {
+			tmp := path[i].weight
+			path[i].weight = next * float64(depth+1) / (float64(i+1) * one)
+			next = tmp - path[i].weight*zero*float64(depth-i)/float64(depth+1)
+		} else {
+			path[i].weight = path[i].weight * float64(depth+1) / (zero * float64(depth-i))
+		}
+	}
+	for i := k; i < depth; i++ {
+		path[i].feature, path[i].zero, path[i].one = path[i+1].feature, path[i+1].zero, path[i+1].one
+	}
+	return path[:depth]
+}
+
+// unwoundPathSum returns the total weight of path with the feature at index k removed,
+// without modifying path.
+func unwoundPathSum(path []pathElement, k int) float64 {
+	depth := len(path) - 1
+	one, zero := path[k].one, path[k].zero
+	next := path[depth].weight
+	var total float64
+	for i := depth - 1; i >= 0; i-- {
+		if one != 0 {
+			tmp := next * float64(depth+1) / (float64(i+1) * one)
+			total += tmp
+			next = path[i].weight - tmp*zero*float64(depth-i)/float64(depth+1)
+		} else if zero != 0 {
+			total += path[i].weight / zero / (float64(depth-i) / float64(depth+1))
+		}
+	}
+	return total
+}
@@ -0,0 +1,198 @@
+package treeshap
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDump is a two-tree model over 4 features, with a feature split on twice in one
+// path, missing value handling and a categorical split.
+const testDump = `{
+  "num_class": 1,
+  "max_feature_idx": 3,
+  "feature_names": ["a", "b", "c", "d"],
+  "tree_info": [
+    {"tree_index": 0, "tree_structure": {
+      "split_feature": 0, "threshold": 0.5, "decision_type": "<=", "default_left": true, "missing_type": "NaN", "internal_count": 100,
+      "left_child": {
+        "split_feature": 1, "threshold": 2.0, "decision_type": "<=", "default_left": false, "missing_type": "None", "internal_count": 60,
+        "left_child": {"leaf_value": 1.5, "leaf_count": 20},
+        "right_child": {
+          "split_feature": 0, "threshold": -1.0, "decision_type": "<=", "default_left": false, "missing_type": "None", "internal_count": 40,
+          "left_child": {"leaf_value": -2.0, "leaf_count": 10},
+          "right_child": {"leaf_value": 0.5, "leaf_count": 30}
+        }
+      },
+      "right_child": {
+        "split_feature": 2, "threshold": 10, "decision_type": "<=", "default_left": true, "missing_type": "Zero", "internal_count": 40,
+        "left_child": {"leaf_value": 3.0, "leaf_count": 25},
+        "right_child": {"leaf_value": 4.5, "leaf_count": 15}
+      }
+    }},
+    {"tree_index": 1, "tree_structure": {
+      "split_feature": 3, "threshold": "1||3", "decision_type": "==", "default_left": false, "missing_type": "None", "internal_count": 100,
+      "left_child": {"leaf_value": 0.25, "leaf_count": 30},
+      "right_child": {
+        "split_feature": 1, "threshold": 5.0, "decision_type": "<=", "default_left": true, "missing_type": "None", "internal_count": 70,
+        "left_child": {"leaf_value": -0.75, "leaf_count": 50},
+        "right_child": {"leaf_value": 1.0, "leaf_count": 20}
+      }
+    }},
+    {"tree_index": 2, "tree_structure": {"leaf_value": 0.1}}
+  ]
+}`
+
+// conditionalExpectation returns the path-dependent expectation of the tree output
+// with the features in coalition fixed to x: splits on other features average their
+// children by cover.
+func conditionalExpectation(t *tree, j int, x []float64, coalition map[int]bool) float64 {
+	n := &t.nodes[j]
+	if n.leaf {
+		return n.value
+	}
+	if coalition[n.feature] {
+		return conditionalExpectation(t, n.next(x[n.feature]), x, coalition)
+	}
+	l, r := &t.nodes[n.left], &t.nodes[n.right]
+	return (conditionalExpectation(t, n.left, x, coalition)*l.cover + conditionalExpectation(t, n.right, x, coalition)*r.cover) / n.cover
+}
+
+// bruteForceSHAP computes exact Shapley values of the path-dependent expectation by
+// enumerating every coalition.
+func bruteForceSHAP(m *Model, x []float64) []float64 {
+	n := m.numFeatures
+	value := func(mask int) float64 {
+		coalition := make(map[int]bool)
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				coalition[i] = true
+			}
+		}
+		var sum float64
+		for i := range m.trees {
+			sum += conditionalExpectation(&m.trees[i], 0, x, coalition)
+		}
+		return sum
+	}
+
+	fact := func(k int) float64 {
+		f := 1.0
+		for i := 2; i <= k; i++ {
+			f *= float64(i)
+		}
+		return f
+	}
+
+	phi := make([]float64, n)
+	for i := 0; i < n; i++ {
+		for mask := 0; mask < 1<<n; mask++ {
+			if mask&(1<<i) != 0 {
+				continue
+			}
+			size := 0
+			for k := 0; k < n; k++ {
+				if mask&(1<<k) != 0 {
+					size++
+				}
+			}
+			weight := fact(size) * fact(n-size-1) / fact(n)
+			phi[i] += weight * (value(mask|1<<i) - value(mask))
+		}
+	}
+	return phi
+}
+
+func TestSHAPMatchesBruteForce(t *testing.T) {
+	m, err := Parse([]byte(testDump))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, features := range [][]float32{
+		{0, 1, 0, 1},
+		{0, 3, 5, 2},
+		{-2, 3, 0, 3},
+		{1, 6, 0, 0},
+		{1, 6, 20, 4},
+		{float32(math.NaN()), 1, 11, 1},
+	} {
+		phi, err := m.SHAP(features)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x, _ := m.input(features)
+		want := bruteForceSHAP(m, x)
+		for i := range want {
+			if math.Abs(phi[i]-want[i]) > 1e-9 {
+				t.Errorf("features %v: phi[%d] = %v, want %v", features, i, phi[i], want[i])
+			}
+		}
+
+		// Local accuracy: the values explain the difference from the base value
+		pred, _ := m.Predict(features)
+		sum := m.ExpectedValue()
+		for _, v := range phi {
+			sum += v
+		}
+		if math.Abs(sum-pred) > 1e-9 {
+			t.Errorf("features %v: base + SHAP = %v, prediction %v", features, sum, pred)
+		}
+	}
+}
+
+func TestPredictDecisions(t *testing.T) {
+	m, err := Parse([]byte(testDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		features []float32
+		want     float64
+	}{
+		{[]float32{0, 1, 0, 1}, 1.5 + 0.25 + 0.1},
+		{[]float32{-2, 3, 0, 0}, -2.0 - 0.75 + 0.1},
+		{[]float32{float32(math.NaN()), 6, 0, 3}, 0.5 + 0.25 + 0.1}, // NaN goes left by default
+		{[]float32{1, 6, 0, 2}, 3.0 + 1.0 + 0.1},                    // Zero is missing and goes left
+		{[]float32{1, 1, 11, -1}, 4.5 - 0.75 + 0.1},                 // Negative categories go right
+	} {
+		got, err := m.Predict(tc.features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("Predict(%v) = %v, want %v", tc.features, got, tc.want)
+		}
+	}
+
+	if _, err := m.Predict([]float32{1, 2}); err == nil {
+		t.Error("expected an error for a short feature vector")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		dump, want string
+	}{
+		"no trees":     {`{"max_feature_idx": 1, "tree_info": []}`, "no trees"},
+		"multiclass":   {`{"num_class": 3, "tree_info": []}`, "multiclass"},
+		"bad feature":  {`{"max_feature_idx": 1, "tree_info": [{"tree_structure": {"split_feature": 5, "threshold": 1, "decision_type": "<=", "left_child": {"leaf_value": 1}, "right_child": {"leaf_value": 2}}}]}`, "unknown feature 5"},
+		"bad decision": {`{"max_feature_idx": 1, "tree_info": [{"tree_structure": {"split_feature": 0, "threshold": 1, "decision_type": ">", "left_child": {"leaf_value": 1}, "right_child": {"leaf_value": 2}}}]}`, "decision type"},
+		"bad category": {`{"max_feature_idx": 1, "tree_info": [{"tree_structure": {"split_feature": 0, "threshold": "1||x", "decision_type": "==", "left_child": {"leaf_value": 1}, "right_child": {"leaf_value": 2}}}]}`, "category"},
+		"one child":    {`{"max_feature_idx": 1, "tree_info": [{"tree_structure": {"split_feature": 0, "threshold": 1, "decision_type": "<=", "left_child": {"leaf_value": 1}}}]}`, "two children"},
+		"invalid JSON": {`{"tree_info": [`, "parse"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "model.json")
+			if err := os.WriteFile(path, []byte(tc.dump), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error mentioning %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
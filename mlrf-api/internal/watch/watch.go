@@ -0,0 +1,146 @@
+// Package watch reloads model, feature and interval files when they change on disk,
+// so pipeline outputs are picked up without calling the admin reload endpoints.
+package watch
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mlrf/mlrf-api/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds file watcher configuration.
+type Config struct {
+	Enabled  bool          // Whether files are watched at all
+	Interval time.Duration // How often watched files are checked
+	Debounce time.Duration // How long a changed file must stay unchanged before it is reloaded
+}
+
+// DefaultConfig returns the default watcher configuration, disabled.
+func DefaultConfig() Config {
+	return Config{
+		Interval: 5 * time.Second,
+		Debounce: 10 * time.Second,
+	}
+}
+
+// Target is a watched file and the reload it triggers.
+type Target struct {
+	Name   string // Label in logs and metrics, e.g. "features"
+	Path   string
+	Reload func(ctx context.Context) error
+}
+
+// stamp identifies a version of a file by modification time and size.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watched is the state of one target.
+type watched struct {
+	Target
+	loaded    stamp     // Version last reloaded (or present at start)
+	pending   stamp     // Changed version waiting for the debounce
+	changedAt time.Time // When pending was first seen; zero when nothing is pending
+}
+
+// Watcher polls target files and reloads each one once a change has settled. Files
+// are stat'ed rather than watched with inotify, which also covers network and
+// Kubernetes volume mounts where file events are unreliable.
+type Watcher struct {
+	cfg     Config
+	targets []*watched
+	now     func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a watcher over targets. The files as they are now count as loaded.
+// Call Start to begin polling.
+func New(cfg Config, targets ...Target) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{cfg: cfg, now: time.Now, ctx: ctx, cancel: cancel}
+	for _, t := range targets {
+		st, _ := statFile(t.Path)
+		w.targets = append(w.targets, &watched{Target: t, loaded: st})
+	}
+	return w
+}
+
+// Start polls the targets every Interval until Close.
+func (w *Watcher) Start() {
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Close stops polling and waits for an in-flight reload to finish.
+func (w *Watcher) Close() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Watcher) loop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(w.ctx)
+		}
+	}
+}
+
+// Check stats every target and reloads those whose change has been stable for the
+// debounce period. A file being rewritten keeps changing size or modification time,
+// which restarts its debounce, so a half-written file is not loaded. A failed reload
+// is not retried until the file changes again.
+func (w *Watcher) Check(ctx context.Context) {
+	now := w.now()
+	for _, t := range w.targets {
+		st, ok := statFile(t.Path)
+		if !ok || st == t.loaded {
+			t.changedAt = time.Time{}
+			continue
+		}
+		if t.changedAt.IsZero() || st != t.pending {
+			t.pending, t.changedAt = st, now
+		}
+		if now.Sub(t.changedAt) < w.cfg.Debounce {
+			continue
+		}
+
+		start := w.now()
+		err := t.Reload(ctx)
+		t.loaded, t.changedAt = st, time.Time{}
+		if err != nil {
+			metrics.RecordFileReload(t.Name, "failed")
+			log.Error().Err(err).Str("target", t.Name).Str("path", t.Path).Msg("File watch reload failed")
+			continue
+		}
+		metrics.RecordFileReload(t.Name, "reloaded")
+		log.Info().
+			Str("target", t.Name).
+			Str("path", t.Path).
+			Dur("duration", w.now().Sub(start)).
+			Msg("Reloaded changed file")
+	}
+}
+
+// statFile returns the stamp of a regular file.
+func statFile(path string) (stamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return stamp{}, false
+	}
+	return stamp{modTime: info.ModTime(), size: info.Size()}, true
+}
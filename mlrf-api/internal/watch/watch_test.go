@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckDebounces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.parquet")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := 0
+	var reloadErr error
+	w := New(Config{Interval: time.Second, Debounce: 10 * time.Second}, Target{
+		Name: "features",
+		Path: path,
+		Reload: func(context.Context) error {
+			reloads++
+			return reloadErr
+		},
+	})
+	clock := time.Date(2017, 8, 16, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return clock }
+	tick := func(d time.Duration) {
+		clock = clock.Add(d)
+		w.Check(context.Background())
+	}
+
+	tick(time.Second)
+	if reloads != 0 {
+		t.Fatalf("expected no reload for an unchanged file, got %d", reloads)
+	}
+
+	// A file still being written restarts the debounce
+	os.WriteFile(path, []byte("v2 partial"), 0o644)
+	tick(time.Second)
+	os.WriteFile(path, []byte("v2 partial, longer"), 0o644)
+	tick(9 * time.Second)
+	tick(9 * time.Second)
+	if reloads != 0 {
+		t.Fatalf("expected the rewrite to restart the debounce, got %d reloads", reloads)
+	}
+	tick(time.Second)
+	if reloads != 1 {
+		t.Fatalf("expected 1 reload once the file settled, got %d", reloads)
+	}
+	tick(time.Minute)
+	if reloads != 1 {
+		t.Errorf("expected no reload without a further change, got %d", reloads)
+	}
+
+	// A failed reload is not retried until the file changes again
+	reloadErr = errors.New("quality check failed")
+	os.WriteFile(path, []byte("v3"), 0o644)
+	tick(time.Second)
+	tick(10 * time.Second)
+	tick(time.Minute)
+	if reloads != 2 {
+		t.Errorf("expected 2 reloads, got %d", reloads)
+	}
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	reloads := 0
+	w := New(Config{Debounce: 0}, Target{Name: "model", Path: path, Reload: func(context.Context) error {
+		reloads++
+		return nil
+	}})
+
+	w.Check(context.Background())
+	if reloads != 0 {
+		t.Fatalf("expected no reload for a missing file, got %d", reloads)
+	}
+	if err := os.WriteFile(path, []byte("onnx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.Check(context.Background())
+	if reloads != 1 {
+		t.Errorf("expected a reload once the file appears, got %d", reloads)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Enabled {
+		t.Error("expected the watcher to be disabled")
+	}
+	if cfg.Interval != 5*time.Second {
+		t.Errorf("expected a 5s interval, got %s", cfg.Interval)
+	}
+	if cfg.Debounce != 10*time.Second {
+		t.Errorf("expected 10s debounce, got %s", cfg.Debounce)
+	}
+}
@@ -1,6 +1,5 @@
 import { useQuery } from '@tanstack/react-query';
-import { addDays, format } from 'date-fns';
-import { fetchSimplePrediction, fetchHistorical } from '../lib/api';
+import { fetchSeries } from '../lib/api';
 import type { ForecastDataPoint } from '../components/ForecastChart';
 
 interface UseForecastDataOptions {
@@ -18,12 +17,15 @@ interface ForecastDataResult {
   refetch: () => void;
 }
 
+// Days of actual sales shown before the forecast for chart context
+const HISTORY_DAYS = 28;
+
 /**
  * Hook to fetch forecast data from the API.
  *
- * Generates predictions for each day in the forecast horizon,
- * starting from the provided date. Also includes "historical" points
- * from the week before the start date for chart context.
+ * Fetches the daily forecast over the horizon from the provided date,
+ * with confidence intervals, and the four weeks of actual sales before
+ * it from /series in one request.
  *
  * @param options - Configuration for the forecast data fetch
  * @returns Forecast data points ready for the ForecastChart component
@@ -34,125 +36,50 @@ export function useForecastData(options: UseForecastDataOptions): ForecastDataRe
   const query = useQuery({
     queryKey: ['forecast', storeNbr, family, startDate, horizon],
     queryFn: async (): Promise<ForecastDataPoint[]> => {
-      const start = new Date(startDate);
-      const points: ForecastDataPoint[] = [];
-
-      // Generate forecast points for the horizon period
-      // We'll fetch predictions at weekly intervals to avoid too many API calls
-      const intervalDays = 7;
-      const numForecastPoints = Math.ceil(horizon / intervalDays);
-
-      // Create array of forecast dates
-      const forecastDates: Date[] = [];
-      for (let i = 0; i < numForecastPoints; i++) {
-        forecastDates.push(addDays(start, i * intervalDays));
-      }
-
-      // Fetch all predictions in parallel
-      const predictions = await Promise.all(
-        forecastDates.map(async (date) => {
-          const dateStr = format(date, 'yyyy-MM-dd');
-          try {
-            const response = await fetchSimplePrediction(
-              storeNbr,
-              family,
-              dateStr,
-              horizon
-            );
-            return {
-              date: dateStr,
-              prediction: response.prediction,
-              lower_80: response.lower_80,
-              upper_80: response.upper_80,
-              lower_95: response.lower_95,
-              upper_95: response.upper_95,
-              success: true,
-            };
-          } catch (error) {
-            // If API fails, return null prediction (error captured in return object)
-            return {
-              date: dateStr,
-              prediction: null as number | null,
-              lower_80: undefined as number | undefined,
-              upper_80: undefined as number | undefined,
-              lower_95: undefined as number | undefined,
-              upper_95: undefined as number | undefined,
-              success: false,
-            };
-          }
-        })
-      );
+      const response = await fetchSeries({
+        store_nbr: storeNbr,
+        family,
+        start_date: startDate,
+        horizon,
+        history_days: HISTORY_DAYS,
+        limit: HISTORY_DAYS + horizon,
+      });
 
-      // Build forecast data points
-      for (const pred of predictions) {
-        if (pred.success && pred.prediction !== null) {
-          const forecast = pred.prediction;
-
-          // Use API-provided confidence intervals if available
-          // Falls back to approximate intervals if API doesn't provide them
-          const hasApiIntervals = pred.lower_80 !== undefined && pred.lower_80 !== 0;
-
-          let lower_80: number, upper_80: number, lower_95: number, upper_95: number;
-          if (hasApiIntervals) {
-            // Use intervals from API (computed from model validation residuals)
-            lower_80 = pred.lower_80!;
-            upper_80 = pred.upper_80!;
-            lower_95 = pred.lower_95!;
-            upper_95 = pred.upper_95!;
-          } else {
-            // Fallback: approximate intervals based on typical forecast uncertainty
-            const ci80Spread = forecast * 0.10;
-            const ci95Spread = forecast * 0.15;
-            lower_80 = Math.max(0, forecast - ci80Spread);
-            upper_80 = forecast + ci80Spread;
-            lower_95 = Math.max(0, forecast - ci95Spread);
-            upper_95 = forecast + ci95Spread;
-          }
+      const points: ForecastDataPoint[] = [];
+      for (const point of response.points) {
+        if (point.actual !== undefined) {
+          points.push({ date: point.date, actual: point.actual });
+        }
+        if (point.prediction === undefined) {
+          continue;
+        }
 
+        const forecast = point.prediction;
+        if (point.upper_80 !== undefined) {
+          // Intervals from the API (validation residuals or the quantile models)
           points.push({
-            date: pred.date,
+            date: point.date,
             forecast,
-            lower_80,
-            upper_80,
-            lower_95,
-            upper_95,
+            lower_80: point.lower_80 ?? 0,
+            upper_80: point.upper_80,
+            lower_95: point.lower_95 ?? 0,
+            upper_95: point.upper_95,
           });
-        }
-      }
-
-      // Fetch real historical data from the API
-      const historicalDays = 28; // 4 weeks of history
-      try {
-        const historicalResponse = await fetchHistorical(
-          storeNbr,
-          family,
-          startDate,
-          historicalDays
-        );
-
-        // Add historical points to the beginning
-        for (const point of historicalResponse.data) {
-          points.unshift({
+        } else {
+          // Fallback: approximate intervals based on typical forecast uncertainty
+          const ci80Spread = forecast * 0.10;
+          const ci95Spread = forecast * 0.15;
+          points.push({
             date: point.date,
-            actual: point.actual,
-          });
-        }
-      } catch {
-        // If historical API fails, generate fallback data based on predictions
-        const basePrediction = predictions[0]?.prediction ?? 45000;
-        for (let i = historicalDays; i > 0; i -= 7) {
-          const histDate = new Date(startDate);
-          histDate.setDate(histDate.getDate() - i);
-          // Simple variation without sine wave
-          const variation = 1 + ((i % 14) - 7) * 0.02;
-          points.unshift({
-            date: format(histDate, 'yyyy-MM-dd'),
-            actual: basePrediction * variation,
+            forecast,
+            lower_80: Math.max(0, forecast - ci80Spread),
+            upper_80: forecast + ci80Spread,
+            lower_95: Math.max(0, forecast - ci95Spread),
+            upper_95: forecast + ci95Spread,
           });
         }
       }
-
-      return points.sort((a, b) => a.date.localeCompare(b.date));
+      return points;
     },
     enabled,
     staleTime: 1000 * 60 * 5, // 5 minutes
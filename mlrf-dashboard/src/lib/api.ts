@@ -14,6 +14,8 @@ export interface SimplePredictRequest {
   family: string;
   date: string;
   horizon: number;
+  strict_features?: boolean;
+  regressors?: Partial<Record<'oil_price' | 'onpromotion', number>>; // values on date, not cached
 }
 
 export interface PredictResponse {
@@ -27,6 +29,7 @@ export interface PredictResponse {
   upper_95?: number;
   cached: boolean;
   latency_ms: number;
+  feature_source?: 'exact' | 'rolled_forward' | 'aggregated' | 'zeros';
 }
 
 export interface BatchPredictRequest {
@@ -68,6 +71,18 @@ export interface ModelMetric {
   rmsle: number;
   mape: number;
   rmse: number;
+  mae?: number;
+  n_samples?: number;
+  horizon?: number;
+  family?: string;
+  horizons?: number[];
+  families?: string[];
+  is_mock?: boolean;
+}
+
+export interface ModelMetricsFilter {
+  horizon?: number;
+  family?: string;
 }
 
 export interface HealthResponse {
@@ -96,28 +111,179 @@ export interface AccuracyResponse {
   summary: AccuracySummary;
 }
 
+export type CalibrationStatus = 'ok' | 'too_narrow' | 'too_wide' | 'insufficient';
+
+export interface CalibrationRequest {
+  store_nbr?: number;
+  family?: string;
+  start_date?: string;
+  end_date?: string;
+  min_samples?: number;
+  tolerance?: number;
+}
+
+export interface CalibrationStats {
+  count: number;
+  coverage_80: number;
+  coverage_95: number;
+  status_80: CalibrationStatus;
+  status_95: CalibrationStatus;
+}
+
+export interface IntervalOffsets {
+  lower_80_offset: number;
+  upper_80_offset: number;
+  lower_95_offset: number;
+  upper_95_offset: number;
+  std: number;
+  mean_abs_error: number;
+  n_samples: number;
+}
+
+export interface FamilyCalibration extends CalibrationStats {
+  family: string;
+  recalibrated?: IntervalOffsets;
+}
+
+export interface CalibrationResponse {
+  start_date: string;
+  end_date: string;
+  min_samples: number;
+  tolerance: number;
+  overall: CalibrationStats;
+  families: FamilyCalibration[];
+  mis_calibrated: number;
+}
+
+export interface ModelComparisonRequest {
+  window?: string;
+  family?: string;
+}
+
+export interface ShadowDivergence {
+  champion: string;
+  challenger: string;
+  count: number;
+  errors: number;
+  mean_abs_delta: number;
+  p95_abs_delta: number;
+  max_abs_delta: number;
+  mean_rel_delta: number;
+  bias: number;
+}
+
+export interface ModelLatency {
+  count: number;
+  mean_ms: number;
+  p50_ms: number;
+  p95_ms: number;
+}
+
+export interface ModelAccuracy {
+  count: number;
+  mape: number;
+  rmsle: number;
+  bias: number;
+  bias_pct: number;
+}
+
+export interface OnlineModelEvaluation {
+  key: string;
+  name: string;
+  version?: string;
+  champion: boolean;
+  challenger: boolean;
+  predictions: number;
+  latency?: ModelLatency;
+  accuracy?: ModelAccuracy;
+}
+
+export interface ModelComparisonResponse {
+  window: string;
+  since: string;
+  champion: string;
+  challenger: string;
+  comparisons: number;
+  pairs: ShadowDivergence[];
+  models: OnlineModelEvaluation[];
+}
+
 export interface WhatIfRequest {
   store_nbr: number;
   family: string;
   date: string;
   horizon: number;
+  mode?: 'date' | 'horizon';
   adjustments: Record<string, number>;
+  strict?: boolean;
+}
+
+export interface WhatIfWarning {
+  adjustment: string;
+  reason: 'unknown_feature' | 'clamped' | 'rounded';
+  requested: number;
+  applied?: number;
+  message: string;
+}
+
+export interface WhatIfPoint {
+  date: string;
+  baseline: number;
+  adjusted: number;
+  delta: number;
+  cumulative_delta: number;
 }
 
 export interface WhatIfResponse {
+  mode?: 'date' | 'horizon';
   original: number;
   adjusted: number;
   delta: number;
   delta_pct: number;
+  series?: WhatIfPoint[];
   latency_ms: number;
   applied: Record<string, number>;
+  warnings?: WhatIfWarning[];
 }
 
-export interface HistoricalRequest {
+export interface WhatIfSweepRequest {
   store_nbr: number;
   family: string;
+  date: string;
+  feature: string;
+  from?: number;
+  to?: number;
+  steps?: number;
+}
+
+export interface WhatIfSweepPoint {
+  adjustment: number;
+  feature_value: number;
+  prediction: number;
+  delta: number;
+  delta_pct: number;
+  warning?: 'clamped' | 'rounded';
+}
+
+export interface WhatIfSweepResponse {
+  feature: string;
+  column: string;
+  base_value: number;
+  baseline: number;
+  points: WhatIfSweepPoint[];
+  range: number;
+  latency_ms: number;
+}
+
+export type HistoryLevel = 'series' | 'store' | 'family' | 'total';
+
+export interface HistoricalRequest {
+  store_nbr?: number;
+  family?: string;
+  level?: HistoryLevel;
+  start_date?: string;
   end_date: string;
-  days: number;
+  days?: number;
 }
 
 export interface HistoricalPoint {
@@ -126,7 +292,140 @@ export interface HistoricalPoint {
 }
 
 export interface HistoricalResponse {
+  level: HistoryLevel;
+  store_nbr?: number;
+  family?: string;
+  start_date: string;
+  end_date: string;
   data: HistoricalPoint[];
+  is_mock?: boolean;
+}
+
+export interface SeriesRequest {
+  level?: HistoryLevel;
+  store_nbr?: number;
+  family?: string;
+  start_date?: string;
+  horizon?: number;
+  history_days?: number;
+  offset?: number;
+  limit?: number;
+}
+
+export interface SeriesPoint {
+  date: string;
+  actual?: number;
+  prediction?: number;
+  lower_80?: number;
+  upper_80?: number;
+  lower_95?: number;
+  upper_95?: number;
+}
+
+export interface SeriesResponse {
+  level: HistoryLevel;
+  store_nbr?: number;
+  family?: string;
+  history_start: string;
+  forecast_start: string;
+  end_date: string;
+  horizon: number;
+  series_count?: number;
+  interval_set?: string;
+  points: SeriesPoint[];
+  total: number;
+  offset: number;
+  limit: number;
+  next_offset?: number;
+  latency_ms: number;
+}
+
+export type AnomalyMethod = 'auto' | 'interval' | 'zscore';
+export type AnomalySeverity = 'warning' | 'critical';
+
+export interface AnomaliesRequest {
+  end_date?: string;
+  days?: number;
+  store_nbr?: number;
+  family?: string;
+  method?: AnomalyMethod;
+  severity?: AnomalySeverity; // Minimum severity
+  limit?: number;
+}
+
+export interface Anomaly {
+  store_nbr: number;
+  family: string;
+  date: string;
+  actual: number;
+  prediction: number;
+  lower: number;
+  upper: number;
+  deviation: number;
+  deviation_pct?: number;
+  score: number;
+  direction: 'above' | 'below';
+  severity: AnomalySeverity;
+  method: 'interval' | 'zscore';
+}
+
+export interface AnomalyStoreSummary {
+  store_nbr: number;
+  anomalies: number;
+  critical: number;
+  deviation: number;
+  max_score: number;
+}
+
+export interface AnomaliesResponse {
+  method: AnomalyMethod;
+  window_start: string;
+  window_end: string;
+  checked: number;
+  unscored: number;
+  count: number;
+  critical: number;
+  anomalies: Anomaly[];
+  stores: AnomalyStoreSummary[];
+}
+
+export interface FamilyInfo {
+  family: string;
+  family_id: number;
+  stores: number;
+}
+
+export interface FamiliesResponse {
+  families: FamilyInfo[];
+  count: number;
+}
+
+export interface StoreInfo {
+  store_nbr: number;
+  cluster: number;
+  type_id: number;
+  families: number;
+}
+
+export interface StoresResponse {
+  stores: StoreInfo[];
+  count: number;
+}
+
+export interface Holiday {
+  date: string;
+  type?: string;
+  locale?: string; // National, Regional or Local; is_holiday flags National
+  locale_name?: string;
+  description?: string;
+  transferred?: boolean;
+}
+
+export interface HolidaysResponse {
+  holidays: Holiday[];
+  count: number;
+  first: string;
+  last: string;
 }
 
 class ApiClient {
@@ -208,7 +507,13 @@ class ApiClient {
     return this.fetch<HierarchyNode>(`/hierarchy?date=${encodeURIComponent(date)}`);
   }
 
-  async getMetrics(): Promise<ModelMetric[]> {
+  async getMetrics(filter: ModelMetricsFilter = {}): Promise<ModelMetric[]> {
+    if (filter.horizon !== undefined) {
+      return this.fetch<ModelMetric[]>(`/model-metrics?horizon=${filter.horizon}`);
+    }
+    if (filter.family !== undefined) {
+      return this.fetch<ModelMetric[]>(`/model-metrics?family=${encodeURIComponent(filter.family)}`);
+    }
     return this.fetch<ModelMetric[]>('/model-metrics');
   }
 
@@ -216,6 +521,18 @@ class ApiClient {
     return this.fetch<AccuracyResponse>('/accuracy');
   }
 
+  async getFamilies(): Promise<FamiliesResponse> {
+    return this.fetch<FamiliesResponse>('/families');
+  }
+
+  async getStores(): Promise<StoresResponse> {
+    return this.fetch<StoresResponse>('/stores');
+  }
+
+  async getHolidays(year?: number): Promise<HolidaysResponse> {
+    return this.fetch<HolidaysResponse>(year !== undefined ? `/calendar/holidays?year=${year}` : '/calendar/holidays');
+  }
+
   async whatIf(request: WhatIfRequest): Promise<WhatIfResponse> {
     return this.fetch<WhatIfResponse>('/whatif', {
       method: 'POST',
@@ -223,12 +540,59 @@ class ApiClient {
     });
   }
 
+  async whatIfSweep(request: WhatIfSweepRequest): Promise<WhatIfSweepResponse> {
+    return this.fetch<WhatIfSweepResponse>('/whatif/sweep', {
+      method: 'POST',
+      body: JSON.stringify(request),
+    });
+  }
+
   async getHistorical(request: HistoricalRequest): Promise<HistoricalResponse> {
     return this.fetch<HistoricalResponse>('/historical', {
       method: 'POST',
       body: JSON.stringify(request),
     });
   }
+
+  async getSeries(request: SeriesRequest): Promise<SeriesResponse> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(request)) {
+      if (value !== undefined) {
+        params.set(key, String(value));
+      }
+    }
+    return this.fetch<SeriesResponse>(`/series?${params}`);
+  }
+
+  async getCalibration(request: CalibrationRequest = {}): Promise<CalibrationResponse> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(request)) {
+      if (value !== undefined) {
+        params.set(key, String(value));
+      }
+    }
+    return this.fetch<CalibrationResponse>(`/accuracy/calibration?${params}`);
+  }
+
+  async getModelComparison(request: ModelComparisonRequest = {}): Promise<ModelComparisonResponse> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(request)) {
+      if (value !== undefined) {
+        params.set(key, String(value));
+      }
+    }
+    return this.fetch<ModelComparisonResponse>(`/models/comparison?${params}`);
+  }
+
+  async getAnomalies(request: AnomaliesRequest = {}): Promise<AnomaliesResponse> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(request)) {
+      if (value !== undefined) {
+        params.set(key, String(value));
+      }
+    }
+    return this.fetch<AnomaliesResponse>(`/monitoring/anomalies?${params}`);
+  }
 }
 
 export const apiClient = new ApiClient();
@@ -250,8 +614,8 @@ export async function fetchHierarchy(date: string): Promise<HierarchyNode> {
   return apiClient.getHierarchy(date);
 }
 
-export async function fetchMetrics(): Promise<ModelMetric[]> {
-  return apiClient.getMetrics();
+export async function fetchMetrics(filter?: ModelMetricsFilter): Promise<ModelMetric[]> {
+  return apiClient.getMetrics(filter);
 }
 
 export async function fetchSimplePrediction(
@@ -272,6 +636,26 @@ export async function fetchAccuracy(): Promise<AccuracyResponse> {
   return apiClient.getAccuracy();
 }
 
+export async function fetchCalibration(request?: CalibrationRequest): Promise<CalibrationResponse> {
+  return apiClient.getCalibration(request);
+}
+
+export async function fetchModelComparison(request?: ModelComparisonRequest): Promise<ModelComparisonResponse> {
+  return apiClient.getModelComparison(request);
+}
+
+export async function fetchFamilies(): Promise<FamiliesResponse> {
+  return apiClient.getFamilies();
+}
+
+export async function fetchStores(): Promise<StoresResponse> {
+  return apiClient.getStores();
+}
+
+export async function fetchHolidays(year?: number): Promise<HolidaysResponse> {
+  return apiClient.getHolidays(year);
+}
+
 export async function fetchWhatIf(
   storeNbr: number,
   family: string,
@@ -301,3 +685,11 @@ export async function fetchHistorical(
     days,
   });
 }
+
+export async function fetchSeries(request: SeriesRequest): Promise<SeriesResponse> {
+  return apiClient.getSeries(request);
+}
+
+export async function fetchAnomalies(request?: AnomaliesRequest): Promise<AnomaliesResponse> {
+  return apiClient.getAnomalies(request);
+}
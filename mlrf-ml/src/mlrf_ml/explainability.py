@@ -1,6 +1,7 @@
 """SHAP-based model explainability for LightGBM."""
 
 import json
+from datetime import datetime, timezone
 from pathlib import Path
 
 import lightgbm as lgb
@@ -280,6 +281,54 @@ def export_waterfall_data(
     print(f"Exported waterfall data for {len(waterfall_data)} combinations to {output_path}")
 
 
+def export_global_importance(
+    shap_values: shap.Explanation,
+    feature_names: list[str],
+    families: list[str],
+    output_path: Path,
+) -> None:
+    """
+    Export global mean |SHAP| feature importance, overall and per family.
+
+    The API serves this file from /explain/global (GLOBAL_IMPORTANCE_PATH).
+
+    Parameters
+    ----------
+    shap_values : shap.Explanation
+        SHAP explanation object
+    feature_names : list[str]
+        Feature names
+    families : list[str]
+        Product family of each explained row
+    output_path : Path
+        Output JSON file path
+    """
+    abs_values = np.abs(shap_values.values)
+    family_arr = np.asarray(families)
+
+    def importance(values: np.ndarray) -> list[dict]:
+        mean = values.mean(axis=0)
+        order = np.argsort(-mean, kind="stable")
+        return [
+            {"feature": feature_names[i], "mean_abs_shap": float(mean[i])} for i in order
+        ]
+
+    export_data = {
+        "generated_at": datetime.now(timezone.utc).isoformat(),
+        "sample_size": int(len(abs_values)),
+        "overall": importance(abs_values),
+        "families": {
+            str(family): importance(abs_values[family_arr == family])
+            for family in sorted(set(families))
+        },
+    }
+
+    with open(output_path, "w") as f:
+        json.dump(export_data, f, indent=2)
+
+    print(f"Exported global importance for {len(export_data['families'])} families to {output_path}")
+
+
 def save_shap_values(shap_values: shap.Explanation, output_path: Path) -> None:
     """
     Save SHAP values to numpy file.
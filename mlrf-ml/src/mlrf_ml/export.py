@@ -1,5 +1,6 @@
 """Export models to ONNX format for Go inference."""
 
+import json
 from pathlib import Path
 
 import lightgbm as lgb
@@ -112,6 +113,88 @@ def validate_onnx_model(
     return is_close
 
 
+def save_selftest(
+    sample_input: np.ndarray,
+    expected_output: np.ndarray,
+    output_path: Path,
+    rtol: float = 1e-3,
+    atol: float = 1e-5,
+) -> Path:
+    """
+    Save known inputs and their accepted prediction ranges for the API startup self-test.
+
+    The Go API predicts each case when it loads the model and refuses readiness
+    if a prediction falls outside [min, max], catching corrupted ONNX files.
+
+    Parameters
+    ----------
+    sample_input : np.ndarray
+        Sample input features, shape (n_samples, n_features)
+    expected_output : np.ndarray
+        Predictions from the original model
+    output_path : Path
+        Path for the self-test JSON file
+    rtol : float
+        Relative tolerance of each range
+    atol : float
+        Absolute tolerance of each range
+
+    Returns
+    -------
+    Path
+        Path to the saved file
+    """
+    output_path = Path(output_path)
+    output_path.parent.mkdir(parents=True, exist_ok=True)
+
+    cases = []
+    for features, expected in zip(sample_input.astype(np.float32), expected_output.flatten()):
+        tolerance = atol + rtol * abs(float(expected))
+        cases.append(
+            {
+                "features": [float(v) for v in features],
+                "expected": float(expected),
+                "min": float(expected) - tolerance,
+                "max": float(expected) + tolerance,
+            }
+        )
+
+    with open(output_path, "w") as f:
+        json.dump({"cases": cases}, f, indent=2)
+
+    print(f"Self-test with {len(cases)} cases saved to {output_path}")
+    return output_path
+
+
+def save_model_dump(model: lgb.Booster, output_path: Path) -> Path:
+    """
+    Save the LightGBM tree structure as JSON for native TreeSHAP in the Go API.
+
+    The API computes SHAP values from this dump when the SHAP service is
+    unavailable, so it must come from the same booster as the ONNX model.
+
+    Parameters
+    ----------
+    model : lgb.Booster
+        Trained LightGBM model
+    output_path : Path
+        Path for the model dump JSON file
+
+    Returns
+    -------
+    Path
+        Path to the saved file
+    """
+    output_path = Path(output_path)
+    output_path.parent.mkdir(parents=True, exist_ok=True)
+
+    with open(output_path, "w") as f:
+        json.dump(model.dump_model(), f)
+
+    print(f"Model dump with {model.num_trees()} trees saved to {output_path}")
+    return output_path
+
+
 def get_onnx_model_info(onnx_path: Path) -> dict:
     """
     Get information about an ONNX model.
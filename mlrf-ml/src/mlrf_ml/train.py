@@ -14,6 +14,7 @@ import argparse
 import json
 import logging
 import pickle
+from datetime import datetime, timezone
 from pathlib import Path
 
 import numpy as np
@@ -22,10 +23,16 @@ import polars as pl
 from mlrf_ml.explainability import (
     compute_shap_values,
     create_tree_explainer,
+    export_global_importance,
     export_waterfall_data,
     get_feature_importance,
 )
-from mlrf_ml.export import export_lightgbm_to_onnx, validate_onnx_model
+from mlrf_ml.export import (
+    export_lightgbm_to_onnx,
+    save_model_dump,
+    save_selftest,
+    validate_onnx_model,
+)
 from mlrf_ml.models.lightgbm_model import (
     CATEGORICAL_COLS,
     FEATURE_COLS,
@@ -150,6 +157,67 @@ def save_prediction_intervals(intervals: dict, output_path: Path) -> None:
     logger.info(f"  Saved prediction intervals to {output_path}")
 
 
+def save_feature_reference(
+    train_df: pl.DataFrame,
+    output_path: Path,
+    n_bins: int = 10,
+) -> int:
+    """
+    Save training feature distributions for API drift monitoring.
+
+    Each numeric feature is summarized as a histogram over its training quantiles:
+    ``edges`` are the interior cut points and ``proportions`` the share of rows in
+    each of the ``len(edges) + 1`` bins (below the first edge, between edges, and at
+    or above the last edge), matching /monitoring/feature-drift.
+
+    Parameters
+    ----------
+    train_df : pl.DataFrame
+        Training dataframe
+    output_path : Path
+        Path to save the reference JSON
+    n_bins : int
+        Target number of quantile bins (fewer for low-cardinality features)
+
+    Returns
+    -------
+    int
+        Number of features written
+    """
+    features = []
+    for col in FEATURE_COLS:
+        if col not in train_df.columns:
+            continue
+        values = train_df[col].drop_nulls().cast(pl.Float64).to_numpy()
+        values = values[np.isfinite(values)]
+        if values.size == 0:
+            continue
+
+        quantiles = np.quantile(values, np.linspace(0, 1, n_bins + 1)[1:-1])
+        edges = np.unique(quantiles)
+        bins = np.searchsorted(edges, values, side="right")
+        counts = np.bincount(bins, minlength=len(edges) + 1)
+
+        features.append({
+            "name": col,
+            "edges": [float(e) for e in edges],
+            "proportions": [float(c) / values.size for c in counts],
+            "mean": float(values.mean()),
+            "std": float(values.std()),
+        })
+
+    dates = train_df["date"]
+    output = {
+        "source": f"train {dates.min()} to {dates.max()}",
+        "features": features,
+    }
+    with open(output_path, "w") as f:
+        json.dump(output, f, indent=2)
+    logger.info(f"  Saved feature reference ({len(features)} features) to {output_path}")
+
+    return len(features)
+
+
 def generate_accuracy_data(
     valid_df: pl.DataFrame,
     predictions: np.ndarray,
@@ -248,6 +316,59 @@ def generate_accuracy_data(
     return summary
 
 
+def generate_model_metrics(
+    valid_df: pl.DataFrame,
+    model_predictions: dict[str, np.ndarray],
+    horizons: list[int],
+    output_path: Path,
+) -> None:
+    """
+    Save held-out evaluation metrics of each model for the API's /model-metrics.
+
+    Besides the overall metrics, each model gets a breakdown by forecast horizon
+    (the first h days of the validation window) and by product family, which the
+    dashboard filters on.
+
+    Parameters
+    ----------
+    valid_df : pl.DataFrame
+        Validation dataframe with date, family and sales columns
+    model_predictions : dict[str, np.ndarray]
+        Predictions for the validation set by model display name
+    horizons : list[int]
+        Forecast horizons to break the metrics down by
+    output_path : Path
+        Path to save the model metrics JSON
+    """
+    y_true = valid_df["sales"].to_numpy()
+    days = (valid_df["date"] - valid_df["date"].min()).dt.total_days().to_numpy()
+    families = valid_df["family"].to_numpy()
+
+    def summarize(y: np.ndarray, pred: np.ndarray) -> dict:
+        m = compute_all_metrics(y, pred)
+        return {k: m[k] for k in ("rmsle", "mape", "rmse", "mae", "n_samples")}
+
+    models = []
+    for name, predictions in model_predictions.items():
+        entry = {"model": name, **summarize(y_true, predictions)}
+        entry["by_horizon"] = {
+            str(h): summarize(y_true[days < h], predictions[days < h])
+            for h in sorted(horizons)
+            if (days < h).any()
+        }
+        entry["by_family"] = {
+            str(family): summarize(y_true[families == family], predictions[families == family])
+            for family in sorted(np.unique(families))
+        }
+        models.append(entry)
+
+    save_metrics(
+        {"generated_at": datetime.now(timezone.utc).isoformat(), "models": models},
+        output_path,
+    )
+    logger.info(f"  Saved model metrics for {len(models)} model(s) to {output_path}")
+
+
 def train_pipeline(
     features_dir: Path = Path("data/features"),
     models_dir: Path = Path("models"),
@@ -374,6 +495,9 @@ def train_pipeline(
     logger.info(f"  95% CI: [{prediction_intervals['lower_95_offset']:.2f}, "
                 f"{prediction_intervals['upper_95_offset']:.2f}]")
 
+    # Training feature distributions for drift monitoring
+    save_feature_reference(train_df, models_dir / "feature_reference.json")
+
     # Generate accuracy data for dashboard visualization
     logger.info("  Generating accuracy data for visualization...")
     accuracy_summary = generate_accuracy_data(
@@ -381,6 +505,14 @@ def train_pipeline(
     )
     metrics["accuracy_data"] = accuracy_summary
 
+    # Per-horizon and per-family evaluation for the model comparison
+    generate_model_metrics(
+        valid_df,
+        {"LightGBM + MinTrace": predictions},
+        horizons,
+        models_dir / "model_metrics.json",
+    )
+
     # Quality gate
     if metrics["final_rmsle"] >= rmsle_threshold:
         logger.warning(
@@ -393,6 +525,7 @@ def train_pipeline(
     lgb_path = models_dir / "lightgbm_model.pkl"
     save_lightgbm_model(model, lgb_path)
     model.save_model(str(models_dir / "lightgbm_model.txt"))
+    save_model_dump(model, models_dir / "lightgbm_model.json")
     metrics["model_path"] = str(lgb_path)
 
     # Step 5: SHAP explainability
@@ -417,11 +550,24 @@ def train_pipeline(
             for _, row in importance_df.head(5).iterrows():
                 logger.info(f"    {row['feature']}: {row['importance']:.4f}")
 
-            # Export waterfall data for API
+            # Export global importance per family for /explain/global
+            export_global_importance(
+                shap_values,
+                feature_names,
+                sample_df["family"].to_list(),
+                models_dir / "global_importance.json",
+            )
+
+            # Export waterfall data per series for the API's offline engine
+            sample_meta = sample_df.select(["store_nbr", "family"]).to_pandas()
             export_waterfall_data(
                 shap_values,
                 feature_names,
                 models_dir / "shap_waterfall.json",
+                store_family_pairs=list(
+                    sample_meta.drop_duplicates().itertuples(index=False, name=None)
+                ),
+                df_metadata=sample_meta,
                 max_display=10,
             )
 
@@ -466,8 +612,12 @@ def train_pipeline(
             logger.warning("  ONNX validation failed - outputs don't match exactly")
         else:
             logger.info("  ONNX validation passed")
+        # Same cases and tolerance, checked by the API before it reports ready
+        save_selftest(sample_input, expected_output, models_dir / "model_selftest.json")
         metrics["onnx_path"] = str(onnx_path)
         metrics["onnx_valid"] = onnx_valid
+        # Reported by the API's /model endpoint
+        metrics["exported_at"] = datetime.now(timezone.utc).isoformat()
     except Exception as e:
         logger.error(f"  ONNX export failed: {e}")
         metrics["onnx_valid"] = False
@@ -1,8 +1,14 @@
 """Tests for SHAP explainability module."""
 
+import json
+
 import numpy as np
 
-from mlrf_ml.explainability import create_waterfall_data, get_feature_importance
+from mlrf_ml.explainability import (
+    create_waterfall_data,
+    export_global_importance,
+    get_feature_importance,
+)
 
 
 class MockShapExplanation:
@@ -136,3 +142,31 @@ def test_get_feature_importance():
 
     # feat_b should be most important (highest mean absolute SHAP)
     assert importance_df.iloc[0]["feature"] == "feat_b"
+
+
+def test_export_global_importance(tmp_path):
+    """Test export_global_importance writes overall and per-family importance."""
+    values = np.array([
+        [0.1, -0.5, 0.2],
+        [0.3, 0.1, -0.2],
+        [-0.9, 0.2, 0.0],
+    ])
+    mock_shap = MockShapExplanation(values, np.zeros(3))
+    output_path = tmp_path / "global_importance.json"
+
+    export_global_importance(
+        mock_shap, ["feat_a", "feat_b", "feat_c"], ["GROCERY I", "GROCERY I", "BEVERAGES"], output_path
+    )
+
+    with open(output_path) as f:
+        result = json.load(f)
+
+    assert result["sample_size"] == 3
+    assert result["overall"][0] == {"feature": "feat_a", "mean_abs_shap": (0.1 + 0.3 + 0.9) / 3}
+    assert set(result["families"]) == {"GROCERY I", "BEVERAGES"}
+
+    # Per family, sorted by mean absolute SHAP
+    grocery = result["families"]["GROCERY I"]
+    assert [f["feature"] for f in grocery] == ["feat_b", "feat_a", "feat_c"]
+    assert abs(grocery[0]["mean_abs_shap"] - 0.3) < 1e-9
+    assert result["families"]["BEVERAGES"][0]["feature"] == "feat_a"
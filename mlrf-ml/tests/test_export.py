@@ -10,6 +10,8 @@ from mlrf_ml.export import (
     benchmark_onnx_inference,
     export_lightgbm_to_onnx,
     get_onnx_model_info,
+    save_model_dump,
+    save_selftest,
     validate_onnx_model,
 )
 
@@ -72,6 +74,52 @@ def test_validate_onnx_model():
         assert is_valid
 
 
+def test_save_selftest():
+    """Test save_selftest writes ranges the exported ONNX model satisfies."""
+    import json
+
+    import onnxruntime as ort
+
+    model, feature_names = create_simple_lgb_model(n_features=5)
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        onnx_path = Path(tmpdir) / "model.onnx"
+        export_lightgbm_to_onnx(model, feature_names, onnx_path)
+
+        sample_input = np.random.randn(4, 5).astype(np.float32)
+        expected_output = model.predict(sample_input)
+        selftest_path = save_selftest(
+            sample_input, expected_output, Path(tmpdir) / "model_selftest.json"
+        )
+
+        with open(selftest_path) as f:
+            cases = json.load(f)["cases"]
+        assert len(cases) == 4
+        assert all(len(c["features"]) == 5 and c["min"] <= c["expected"] <= c["max"] for c in cases)
+
+        session = ort.InferenceSession(str(onnx_path))
+        features = np.array([c["features"] for c in cases], dtype=np.float32)
+        preds = session.run(None, {session.get_inputs()[0].name: features})[0].flatten()
+        for pred, c in zip(preds, cases):
+            assert c["min"] <= pred <= c["max"]
+
+
+def test_save_model_dump():
+    """Test save_model_dump writes the tree structure of every tree."""
+    import json
+
+    model, feature_names = create_simple_lgb_model(n_features=5)
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        dump_path = save_model_dump(model, Path(tmpdir) / "lightgbm_model.json")
+
+        with open(dump_path) as f:
+            dump = json.load(f)
+        assert dump["max_feature_idx"] == 4
+        assert len(dump["tree_info"]) == model.num_trees()
+        assert "tree_structure" in dump["tree_info"][0]
+
+
 def test_get_onnx_model_info():
     """Test get_onnx_model_info returns correct information."""
     model, feature_names = create_simple_lgb_model(n_features=5)